@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"os"
+	"path/filepath"
+)
+
+// analyzeRequest is the JSON argument of AnalyzeDiffCoverage. Diff is the
+// path of a zero-context diff; DiffText the diff itself, used instead.
+type analyzeRequest struct {
+	CoverProfile string   `json:"coverProfile"`
+	Diff         string   `json:"diff"`
+	DiffText     string   `json:"diffText"`
+	SourceRoot   string   `json:"sourceRoot"`
+	Config       string   `json:"config"`
+	Preset       string   `json:"preset"`
+	MinCoverage  *float64 `json:"minCoverage"`
+}
+
+// analyzeResponse is the JSON result of AnalyzeDiffCoverage: the report, or
+// the error that prevented computing it.
+type analyzeResponse struct {
+	Report *diffcoverage.Report `json:"report,omitempty"`
+	Error  string               `json:"error,omitempty"`
+}
+
+// analyzeDiffCoverage is AnalyzeDiffCoverage of the C ABI (see cshared.go):
+// it analyzes the JSON request and returns the JSON response.
+func analyzeDiffCoverage(request []byte) []byte {
+	var resp analyzeResponse
+	r, err := analyzeJSON(request)
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Report = r
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	return data
+}
+
+// analyzeJSON runs the analysis of a JSON request with the configuration of
+// its source root, as the CLI does.
+func analyzeJSON(data []byte) (*diffcoverage.Report, error) {
+	var req analyzeRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %v", err)
+	}
+	if req.CoverProfile == "" || (req.Diff == "" && req.DiffText == "") {
+		return nil, fmt.Errorf("invalid request: coverProfile and diff or diffText are required")
+	}
+	if req.SourceRoot == "" {
+		req.SourceRoot = "."
+	}
+	cfg, err := config.Resolve(req.Config, req.SourceRoot, req.Preset)
+	if err != nil {
+		return nil, err
+	}
+	if req.MinCoverage != nil {
+		cfg.MinCoverage = *req.MinCoverage
+	}
+
+	diffPath := req.Diff
+	if req.DiffText != "" {
+		tmpDir, err := os.MkdirTemp("", "diffcoverage-cshared")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmpDir)
+		diffPath = filepath.Join(tmpDir, "diff.txt")
+		if err := os.WriteFile(diffPath, []byte(req.DiffText), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return evaluate(req.CoverProfile, diffPath, req.SourceRoot, cfg)
+}
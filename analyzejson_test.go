@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAnalyzeDiffCoverage returns the report of a request, or the error that
+// prevented computing it.
+func TestAnalyzeDiffCoverage(t *testing.T) {
+	dir := writeFixture(t)
+	diff := "+++ b/pkg/a.go\n@@ -3,0 +4,4 @@\n+\tif x > 0 {\n+\t\treturn 1\n+\t}\n+\treturn 0\n"
+	cases := []struct {
+		name, request string
+		wantCoverage  float64
+		wantErr       string
+	}{
+		{"diff file", `{"coverProfile":"` + filepath.Join(dir, "cover.out") + `","diff":"` + filepath.Join(dir, "diff.txt") + `","sourceRoot":"` + dir + `","minCoverage":90}`, 40, ""},
+		{"diff text", `{"coverProfile":"` + filepath.Join(dir, "cover.out") + `","diffText":` + quote(diff) + `,"sourceRoot":"` + dir + `","minCoverage":90}`, 50, ""},
+		{"invalid JSON", `{`, 0, "invalid request: "},
+		{"missing diff", `{"coverProfile":"cover.out"}`, 0, "invalid request: coverProfile and diff or diffText are required"},
+		{"missing profile", `{"coverProfile":"` + filepath.Join(dir, "nope.out") + `","diffText":"","diff":"d","sourceRoot":"` + dir + `"}`, 0, "nope.out"},
+	}
+	for _, c := range cases {
+		var resp struct {
+			Report *struct {
+				Coverage    float64 `json:"coverage"`
+				MinCoverage float64 `json:"minCoverage"`
+				Passed      bool    `json:"passed"`
+				Files       []struct {
+					Path string `json:"path"`
+				} `json:"files"`
+			} `json:"report"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(analyzeDiffCoverage([]byte(c.request)), &resp); err != nil {
+			t.Fatalf("%s: invalid response: %v", c.name, err)
+		}
+		if c.wantErr != "" {
+			if resp.Report != nil || !strings.Contains(resp.Error, c.wantErr) {
+				t.Errorf("%s: got report %v and error %q, want error %q", c.name, resp.Report, resp.Error, c.wantErr)
+			}
+			continue
+		}
+		if resp.Error != "" || resp.Report == nil {
+			t.Fatalf("%s: unexpected error %q", c.name, resp.Error)
+		}
+		if r := resp.Report; r.Coverage != c.wantCoverage || r.MinCoverage != 90 || r.Passed || len(r.Files) == 0 {
+			t.Errorf("%s: unexpected report %+v", c.name, r)
+		}
+	}
+}
+
+// quote returns s as a JSON string.
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
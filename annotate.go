@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"io"
+	"os"
+)
+
+// runAnnotateDiff re-emits the diff with |COVERED / |MISS markers on added lines.
+func runAnnotateDiff(args []string) int {
+	fs := flag.NewFlagSet("annotate-diff", flag.ExitOnError)
+	outFlag := fs.String("o", "", "Write the annotated diff to this file instead of stdout")
+	fs.Parse(args)
+
+	if fs.NArg() < 3 {
+		fmt.Println("Usage: diffcoverage annotate-diff [options] <cover.out> <diff.txt> <source_root>")
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		return 1
+	}
+
+	coverPath := fs.Arg(0)
+	diffPath := fs.Arg(1)
+	sourceRoot := fs.Arg(2)
+
+	a, err := diffcoverage.Analyze(coverPath, diffPath, sourceRoot)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *outFlag != "" {
+		f, err := os.Create(*outFlag)
+		if err != nil {
+			fmt.Printf("error creating output file: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := diffcoverage.AnnotateDiff(w, diffPath, a); err != nil {
+		fmt.Printf("error annotating diff: %v\n", err)
+		return 1
+	}
+	return 0
+}
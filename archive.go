@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/ci"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+	"github.com/JackShadow/go-new-code-coverage/internal/storage"
+	"os"
+	"time"
+)
+
+// runArchive fetches archived reports; they are uploaded with -publish=archive.
+func runArchive(args []string) int {
+	if len(args) < 1 || args[0] != "fetch" {
+		fmt.Println("Usage: diffcoverage archive fetch -url=s3://bucket/prefix [-repo=owner/name] [-branch=main] [-commit=sha] [-o=report.json]")
+		return 1
+	}
+
+	fs := flag.NewFlagSet("archive fetch", flag.ExitOnError)
+	archiveURL := fs.String("url", "", "Archive location: s3://bucket/prefix or gs://bucket/prefix")
+	repo := fs.String("repo", "", "Repository (owner/name); detected in CI")
+	branch := fs.String("branch", "", "Branch to fetch the report of (default: the pull/merge request's base branch)")
+	commit := fs.String("commit", "", "Commit to fetch the report of (default: the branch's latest report)")
+	output := fs.String("o", "", "Write the report to this file instead of stdout")
+	fs.Parse(args[1:])
+
+	env := ci.Detect(os.Getenv)
+	if env == nil {
+		env = &ci.Env{}
+	}
+	*repo = firstNonEmpty(*repo, env.Repo)
+	*branch = firstNonEmpty(*branch, env.BaseBranch, env.Branch)
+	if *archiveURL == "" || *repo == "" || *branch == "" {
+		fmt.Println("archive fetch: -url, -repo and -branch are required outside CI")
+		return 1
+	}
+
+	bucket, err := storage.Open(*archiveURL, os.Getenv)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	key := storage.LatestKey(*repo, *branch, reporter.ArchiveReportName)
+	if *commit != "" {
+		key = storage.Key(*repo, *branch, *commit, reporter.ArchiveReportName)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	data, err := bucket.Get(ctx, key)
+	if storage.IsNotFound(err) {
+		fmt.Fprintf(os.Stderr, "No archived report at %s\n", key)
+		return 1
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Printf("error writing %s: %v\n", *output, err)
+		return 1
+	}
+	return 0
+}
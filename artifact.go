@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/artifacts"
+	"github.com/JackShadow/go-new-code-coverage/internal/ci"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/credentials"
+	"os"
+	"time"
+)
+
+// runArtifact downloads the coverage profile a CI run of the base branch
+// uploaded as an artifact.
+func runArtifact(args []string) int {
+	if len(args) < 1 || args[0] != "fetch" {
+		fmt.Println("Usage: diffcoverage artifact fetch -name=coverage [-branch=main] [-commit=sha] [-file=cover.out] [-provider=github|gitlab] [-o=base.out]")
+		return 1
+	}
+
+	fs := flag.NewFlagSet("artifact fetch", flag.ExitOnError)
+	name := fs.String("name", "", "Artifact name on GitHub, job name on GitLab (default: artifact.name in the configuration, else coverage)")
+	file := fs.String("file", "", "File inside the artifact (default: artifact.file in the configuration, else cover.out)")
+	root := fs.String("root", ".", "Source root containing the configuration file")
+	configPath := fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present)")
+	repo := fs.String("repo", "", "Repository (owner/name); detected in CI")
+	branch := fs.String("branch", "", "Branch whose latest artifact to fetch (default: the pull/merge request's base branch)")
+	commit := fs.String("commit", "", "Fetch the artifact of the run of this commit instead of the branch's latest one")
+	apiURL := fs.String("api-url", "", "Provider API base URL; detected in CI")
+	token := fs.String("token", "", "API token; defaults to the provider's environment variables, ~/.netrc or git credential helpers")
+	provider := fs.String("provider", "", "Code host the artifacts are on: github or gitlab (default: the CI system's)")
+	output := fs.String("o", "", "Write the file to this path instead of stdout")
+	fs.Parse(args[1:])
+
+	cfg, err := config.Resolve(*configPath, *root, "")
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	*name = firstNonEmpty(*name, cfg.Artifact.Name, "coverage")
+	*file = firstNonEmpty(*file, cfg.Artifact.File, "cover.out")
+	env := ci.Detect(os.Getenv)
+	if env == nil {
+		env = &ci.Env{}
+	}
+	*repo = firstNonEmpty(*repo, env.Repo)
+	*branch = firstNonEmpty(*branch, env.BaseBranch, env.Branch)
+	if *repo == "" || (*branch == "" && *commit == "") {
+		fmt.Println("artifact fetch: -repo and -branch or -commit are required outside CI")
+		return 1
+	}
+	if *apiURL == "" {
+		*apiURL = env.APIURL
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	resolver := &credentials.Resolver{Token: *token, Getenv: os.Getenv}
+	resolve := func(p credentials.Provider) (credentials.Credential, error) {
+		return resolver.Resolve(p, *apiURL)
+	}
+	a, err := fetchArtifact(ctx, firstNonEmpty(*provider, env.Provider, "github"), *apiURL, *repo, resolve, *name, *branch, *commit, *file)
+	if errors.Is(err, artifacts.ErrNotFound) {
+		fmt.Fprintf(os.Stderr, "No artifact %s of %s\n", *name, firstNonEmpty(*commit, *branch))
+		return 1
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	if a.Commit != "" {
+		fmt.Fprintf(os.Stderr, "Fetched %s of commit %s from %s\n", a.Name, a.Commit, a.Source)
+	} else {
+		fmt.Fprintf(os.Stderr, "Fetched %s from %s\n", a.Name, a.Source)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(a.Data)
+		return 0
+	}
+	if err := os.WriteFile(*output, a.Data, 0644); err != nil {
+		fmt.Printf("error writing %s: %v\n", *output, err)
+		return 1
+	}
+	return 0
+}
+
+// fetchArtifact downloads file from the artifact name of the provider (github
+// or gitlab, or the corresponding CI system); see artifacts.GitHub.Fetch.
+func fetchArtifact(ctx context.Context, provider, apiURL, repo string, resolve func(credentials.Provider) (credentials.Credential, error), name, branch, commit, file string) (*artifacts.Artifact, error) {
+	switch provider {
+	case "github", ci.GitHubActions:
+		cred, err := resolve(credentials.GitHub)
+		if err != nil {
+			return nil, err
+		}
+		g := &artifacts.GitHub{APIURL: apiURL, Repo: repo, Token: cred.Token}
+		return g.Fetch(ctx, name, branch, commit, file)
+	case "gitlab", ci.GitLabCI:
+		g := &artifacts.GitLab{APIURL: apiURL, Project: repo}
+		cred, err := resolve(credentials.GitLab)
+		switch {
+		case err == nil:
+			g.Token = cred.Token
+		case os.Getenv("CI_JOB_TOKEN") != "":
+			g.Token, g.JobToken = os.Getenv("CI_JOB_TOKEN"), true
+		default:
+			return nil, err
+		}
+		return g.Fetch(ctx, name, branch, commit, file)
+	}
+	return nil, fmt.Errorf("artifact fetch: unknown provider %q", provider)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/blame"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/testrun"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// rangeFlags are the flags shared by the commands analyzing a commit range.
+type rangeFlags struct {
+	cover  *string
+	rng    *string
+	root   *string
+	config *string
+	preset *string
+	format *string
+}
+
+// addRangeFlags registers the range flags on fs.
+func addRangeFlags(fs *flag.FlagSet) *rangeFlags {
+	return &rangeFlags{
+		cover:  fs.String("cover", "cover.out", "Path to the coverage profile of the end of the range"),
+		rng:    fs.String("range", "origin/main..HEAD", "Commit range to analyze, as accepted by git diff (A..B or A...B)"),
+		root:   fs.String("root", ".", "Source root containing go.mod"),
+		config: fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present)"),
+		preset: fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", ")),
+		format: fs.String("format", "text", "Output format: text, markdown or json"),
+	}
+}
+
+// analyze diffs the range and analyzes it against the coverage profile. It
+// also returns the configuration, whose exclusions are already applied.
+func (f *rangeFlags) analyze() (*diffcoverage.Analysis, *config.Config, error) {
+	cfg, err := config.Resolve(*f.config, *f.root, *f.preset)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpDir, err := os.MkdirTemp("", "diffcoverage-range")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	diffPath := filepath.Join(tmpDir, "diff.txt")
+	if err := testrun.WriteDiff(*f.root, *f.rng, diffPath); err != nil {
+		return nil, nil, fmt.Errorf("error computing diff: %v", err)
+	}
+
+	a, err := diffcoverage.Analyze(*f.cover, diffPath, *f.root)
+	if err != nil {
+		return nil, nil, err
+	}
+	a.Exclude(cfg.Exclude)
+	return a, cfg, nil
+}
+
+// printJSON prints v as indented JSON.
+func printJSON(v any) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	return 0
+}
+
+// runAuthors summarizes the coverage of the lines changed in a commit range per author.
+func runAuthors(args []string) int {
+	fs := flag.NewFlagSet("authors", flag.ExitOnError)
+	flags := addRangeFlags(fs)
+	fs.Parse(args)
+
+	a, _, err := flags.analyze()
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	stats, err := blame.Stats(a, rangeEnd(*flags.rng))
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	switch format := *flags.format; format {
+	case "json":
+		return printJSON(stats)
+	case "markdown":
+		fmt.Printf("### Diff coverage by author (%s)\n\n", *flags.rng)
+		fmt.Println("| # | Author | Covered | Coverage | Commits |")
+		fmt.Println("|--:|--------|--------:|---------:|--------:|")
+		for i, s := range stats {
+			fmt.Printf("| %d | %s | %d/%d | %.2f%% | %d |\n", i+1, s.Author, s.CoveredLines, s.TotalLines, s.Coverage, len(s.Commits))
+		}
+	case "text":
+		if len(stats) == 0 {
+			fmt.Printf("No new or changed lines inside functions in %s\n", *flags.rng)
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "#\tAUTHOR\tLINES\tCOVERAGE\tCOMMITS")
+		for i, s := range stats {
+			fmt.Fprintf(tw, "%d\t%s <%s>\t%d/%d\t%.2f%%\t%d\n", i+1, s.Author, s.Email, s.CoveredLines, s.TotalLines, s.Coverage, len(s.Commits))
+		}
+		tw.Flush()
+	default:
+		fmt.Printf("unknown format %q\n", format)
+		return 1
+	}
+	return 0
+}
+
+// rangeEnd returns the revision at the end of a commit range, HEAD if it is
+// left out ("A.."); a single revision stands for the working tree.
+func rangeEnd(r string) string {
+	i := strings.Index(r, "..")
+	if i < 0 {
+		return ""
+	}
+	end := strings.TrimLeft(r[i+2:], ".")
+	if end == "" {
+		return "HEAD"
+	}
+	return end
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/ci"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+	"github.com/JackShadow/go-new-code-coverage/internal/profilecache"
+	"github.com/JackShadow/go-new-code-coverage/internal/storage"
+	"os"
+	"time"
+)
+
+// runBaseCache stores and fetches coverage profiles keyed by commit, so the
+// base of a pull request is tested once instead of on every run.
+func runBaseCache(args []string) int {
+	if len(args) < 1 || (args[0] != "get" && args[0] != "put") {
+		fmt.Println("Usage: diffcoverage base-cache get [-base=origin/main] [-o=base.out]")
+		fmt.Println("       diffcoverage base-cache put [-commit=HEAD] <cover.out>")
+		return 1
+	}
+	get := args[0] == "get"
+
+	fs := flag.NewFlagSet("base-cache "+args[0], flag.ExitOnError)
+	root := fs.String("root", ".", "Git checkout the commits are resolved in")
+	dir := fs.String("dir", profilecache.DefaultDir(), "Local cache directory; empty disables it")
+	cacheURL := fs.String("url", os.Getenv("DIFFCOVERAGE_CACHE_URL"), "s3://bucket/prefix or gs://bucket/prefix shared by CI jobs (default: $DIFFCOVERAGE_CACHE_URL)")
+	repo := fs.String("repo", "", "Repository (owner/name) the remote cache is keyed by; detected in CI")
+	commit := fs.String("commit", "", "Commit of the profile (default: put HEAD, get the merge base of HEAD and -base)")
+	base := fs.String("base", "", "Base branch to get the profile of the merge base with (default: the pull/merge request's base, else origin/main)")
+	output := fs.String("o", "", "Write the fetched profile to this file instead of stdout")
+	fs.Parse(args[1:])
+	if !get && fs.NArg() != 1 {
+		fmt.Println("base-cache put: expected the path of the coverage profile")
+		return 1
+	}
+
+	env := ci.Detect(os.Getenv)
+	if env == nil {
+		env = &ci.Env{}
+	}
+	c := &profilecache.Cache{Dir: *dir, Repo: firstNonEmpty(*repo, env.Repo)}
+	if *cacheURL != "" {
+		if c.Repo == "" {
+			fmt.Println("base-cache: -repo is required with -url outside CI")
+			return 1
+		}
+		bucket, err := storage.Open(*cacheURL, os.Getenv)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		c.Remote = bucket
+	}
+	if c.Dir == "" && c.Remote == nil {
+		fmt.Println("base-cache: neither -dir nor -url is set")
+		return 1
+	}
+
+	rev := *commit
+	if rev == "" {
+		rev = "HEAD"
+		if get {
+			mergeBase, err := gitutil.Run(*root, "merge-base", "HEAD", firstNonEmpty(*base, env.BaseRef(), "origin/main"))
+			if err != nil {
+				fmt.Println(err.Error())
+				return 1
+			}
+			rev = mergeBase
+		}
+	}
+	sha, err := resolveCommit(*root, rev)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if !get {
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		if err := c.Put(ctx, sha, data); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		fmt.Printf("Cached the coverage profile of %s\n", sha)
+		return 0
+	}
+
+	data, err := c.Get(ctx, sha)
+	if errors.Is(err, profilecache.ErrMiss) {
+		fmt.Fprintf(os.Stderr, "No cached coverage profile of %s\n", sha)
+		return 1
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Printf("error writing %s: %v\n", *output, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Using the cached coverage profile of %s\n", sha)
+	return 0
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/ci"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/credentials"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+	"github.com/JackShadow/go-new-code-coverage/internal/prdiff"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runCI detects the CI environment and runs the pipeline with its settings.
+func runCI(args []string) int {
+	fs := flag.NewFlagSet("ci", flag.ExitOnError)
+	flags := addRunFlags(fs, "")
+	cover := fs.String("cover", "", "Coverage profile of the checkout, e.g. from an earlier job, to report on instead of running the tests")
+	coverArtifact := fs.String("cover-artifact", "", "CI artifact (GitHub) or job (GitLab) whose coverage profile of the commit to report on instead of running the tests")
+	fs.Parse(args)
+
+	env := ci.Detect(os.Getenv)
+	if env == nil {
+		fmt.Println("No supported CI environment detected (GitHub Actions, GitLab CI, CircleCI, Buildkite, Bitbucket Pipelines, Jenkins); use the run command instead")
+		return 1
+	}
+	fmt.Printf("Detected %s\n", env)
+
+	if *flags.base == "" {
+		*flags.base = env.BaseRef()
+		flags.target = env.TargetBranch()
+		// The base commit of the event is exact, but shallow clones lack it.
+		if env.BaseSHA != "" {
+			if _, err := resolveCommit(*flags.root, env.BaseSHA); err == nil {
+				*flags.base = env.BaseSHA
+			} else {
+				fmt.Printf("Base commit %s not fetched; fetch more history (e.g. fetch-depth: 0) to diff against it\n", env.BaseSHA)
+			}
+		}
+	}
+	if *flags.base == "" {
+		// Branch builds have no target branch: check the commit being built.
+		*flags.base = "HEAD~1"
+		fmt.Println("No pull/merge request base branch found; diffing against HEAD~1")
+	}
+
+	opts, err := flags.options()
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	tmpDir, err := os.MkdirTemp("", "diffcoverage-ci")
+	if err != nil {
+		fmt.Printf("error creating temp dir: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(tmpDir)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	// The flags of the integrations, e.g. -repo and -token, apply to the API requests too.
+	penv := flags.publish.env()
+
+	if _, err := resolveCommit(opts.root, opts.base); err != nil && penv.PRNumber != "" {
+		// Shallow clones lack the base: ask the code host for the diff.
+		diffPath := filepath.Join(tmpDir, "diff.txt")
+		if err := fetchPRDiff(ctx, flags.publish, penv, opts.root, diffPath); err != nil {
+			fmt.Printf("%s is not fetched and the diff could not be downloaded: %v\n", opts.base, err)
+			return 1
+		}
+		fmt.Printf("%s is not fetched; using the diff of the pull/merge request from the API\n", opts.base)
+		opts.diff = diffPath
+	}
+
+	opts.cover = *cover
+	if *coverArtifact != "" {
+		apiURL := flags.publish.providerAPIURL(penv, penv.Provider)
+		resolve := func(p credentials.Provider) (credentials.Credential, error) {
+			return flags.publish.credential(p, apiURL, penv)
+		}
+		file := firstNonEmpty(opts.cfg.Artifact.File, "cover.out")
+		a, err := fetchArtifact(ctx, penv.Provider, apiURL, penv.Repo, resolve, *coverArtifact, "", penv.CommitSHA, file)
+		if err != nil {
+			fmt.Printf("error fetching the coverage profile of %s: %v\n", penv.CommitSHA, err)
+			return 1
+		}
+		opts.cover = filepath.Join(tmpDir, "cover.out")
+		if err := os.WriteFile(opts.cover, a.Data, 0644); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		fmt.Printf("Fetched %s of commit %s from %s\n", a.Name, a.Commit, a.Source)
+	}
+	return runPipeline(opts)
+}
+
+// fetchPRDiff writes the diff of the pull/merge request, relative to root,
+// downloaded from GitHub or GitLab.
+func fetchPRDiff(ctx context.Context, f *publishFlags, env *ci.Env, root, path string) error {
+	var diff []byte
+	var err error
+	switch env.Provider {
+	case ci.GitHubActions:
+		apiURL := f.providerAPIURL(env, ci.GitHubActions)
+		cred, credErr := f.credential(credentials.GitHub, apiURL, env)
+		if credErr != nil {
+			return credErr
+		}
+		diff, err = prdiff.GitHub(ctx, nil, apiURL, env.Repo, env.PRNumber, cred.Token)
+	case ci.GitLabCI:
+		apiURL := f.providerAPIURL(env, ci.GitLabCI)
+		cred, credErr := f.credential(credentials.GitLab, apiURL, env)
+		if credErr != nil {
+			return credErr
+		}
+		diff, err = prdiff.GitLab(ctx, nil, apiURL, env.Repo, env.PRNumber, cred.Token)
+	default:
+		return fmt.Errorf("downloading diffs from %s is not supported", env.Provider)
+	}
+	if err != nil {
+		return err
+	}
+	prefix, err := gitutil.Run(root, "rev-parse", "--show-prefix")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, prdiff.Relative(diff, prefix), 0644)
+}
+
+// fetchPRLabels returns the labels of the pull/merge request from GitHub or
+// GitLab.
+func fetchPRLabels(ctx context.Context, f *publishFlags, env *ci.Env) ([]string, error) {
+	switch env.Provider {
+	case ci.GitHubActions:
+		apiURL := f.providerAPIURL(env, ci.GitHubActions)
+		cred, err := f.credential(credentials.GitHub, apiURL, env)
+		if err != nil {
+			return nil, err
+		}
+		return prdiff.GitHubLabels(ctx, nil, apiURL, env.Repo, env.PRNumber, cred.Token)
+	case ci.GitLabCI:
+		apiURL := f.providerAPIURL(env, ci.GitLabCI)
+		cred, err := f.credential(credentials.GitLab, apiURL, env)
+		if err != nil {
+			return nil, err
+		}
+		return prdiff.GitLabLabels(ctx, nil, apiURL, env.Repo, env.PRNumber, cred.Token)
+	default:
+		return nil, fmt.Errorf("reading labels from %s is not supported", env.Provider)
+	}
+}
+
+// applyLabels applies the label overrides of cfg for the labels of the
+// pull/merge request being checked, if any. Labels that cannot be read leave
+// cfg unchanged. Messages go to log.
+func applyLabels(log io.Writer, f *publishFlags, cfg *config.Config) {
+	env := f.env()
+	if len(cfg.Labels) == 0 || env.Provider == "" || env.PRNumber == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	labels, err := fetchPRLabels(ctx, f, env)
+	if err != nil {
+		fmt.Fprintf(log, "Ignoring the label overrides: error reading the labels of #%s: %v\n", env.PRNumber, err)
+		return
+	}
+	for _, l := range cfg.ApplyLabels(labels) {
+		fmt.Fprintf(log, "Applying the overrides of label %s\n", l.Name)
+	}
+}
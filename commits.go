@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/blame"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runCommits breaks the coverage of the lines changed in a commit range down per commit.
+func runCommits(args []string) int {
+	fs := flag.NewFlagSet("commits", flag.ExitOnError)
+	flags := addRangeFlags(fs)
+	fs.Parse(args)
+
+	a, _, err := flags.analyze()
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	stats, err := blame.Commits(a, rangeEnd(*flags.rng))
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	switch format := *flags.format; format {
+	case "json":
+		return printJSON(stats)
+	case "markdown":
+		fmt.Printf("### Diff coverage by commit (%s)\n\n", *flags.rng)
+		fmt.Println("| Commit | Author | Summary | Covered | Coverage | Uncovered lines |")
+		fmt.Println("|--------|--------|---------|--------:|---------:|-----------------|")
+		for _, c := range stats {
+			uncovered := "-"
+			if len(c.Uncovered) > 0 {
+				uncovered = "`" + strings.Join(c.Uncovered, "`, `") + "`"
+			}
+			fmt.Printf("| %.8s | %s | %s | %d/%d | %.2f%% | %s |\n", c.Commit, c.Author, strings.ReplaceAll(c.Summary, "|", "\\|"), c.CoveredLines, c.TotalLines, c.Coverage, uncovered)
+		}
+	case "text":
+		if len(stats) == 0 {
+			fmt.Printf("No new or changed lines inside functions in %s\n", *flags.rng)
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "COMMIT\tDATE\tAUTHOR\tLINES\tCOVERAGE\tSUMMARY")
+		for _, c := range stats {
+			date := "-"
+			if c.Time > 0 {
+				date = time.Unix(c.Time, 0).UTC().Format(time.DateOnly)
+			}
+			fmt.Fprintf(tw, "%.8s\t%s\t%s\t%d/%d\t%.2f%%\t%s\n", c.Commit, date, c.Author, c.CoveredLines, c.TotalLines, c.Coverage, c.Summary)
+		}
+		tw.Flush()
+		for _, c := range stats {
+			if len(c.Uncovered) > 0 {
+				fmt.Printf("\nUncovered lines of %.8s:\n", c.Commit)
+				for _, loc := range c.Uncovered {
+					fmt.Printf("\t%s\n", loc)
+				}
+			}
+		}
+	default:
+		fmt.Printf("unknown format %q\n", format)
+		return 1
+	}
+	return 0
+}
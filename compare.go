@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/coverprofile"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// runCompare compares two coverage profiles of a module, independent of any diff.
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	root := fs.String("root", ".", "Module root containing go.mod; its files are the source of the new profile")
+	oldRef := fs.String("old-ref", "", "Git revision the old profile was built at, to locate its functions (default: the files in -root)")
+	format := fs.String("format", "text", "Output format: text, markdown or json")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fmt.Println("Usage: diffcoverage compare [options] <old.out> <new.out>")
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		return 1
+	}
+	module, err := diffcoverage.ModulePath(*root)
+	if err != nil {
+		fmt.Printf("error parsing go.mod: %v\n", err)
+		return 1
+	}
+	profiles := make([]*coverprofile.Merger, 2)
+	for i, path := range fs.Args() {
+		profiles[i] = &coverprofile.Merger{Module: module, Root: *root}
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		err = profiles[i].Add(path, f)
+		f.Close()
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+	}
+
+	newSrc := func(file string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(*root, filepath.FromSlash(file)))
+	}
+	oldSrc := newSrc
+	if *oldRef != "" {
+		oldSrc = func(file string) ([]byte, error) {
+			out, err := gitutil.Run(*root, "show", *oldRef+":./"+file)
+			return []byte(out), err
+		}
+	}
+	c := coverprofile.Compare(profiles[0], profiles[1], oldSrc, newSrc)
+
+	switch *format {
+	case "json":
+		return printJSON(c)
+	case "markdown":
+		printCompareMarkdown(c)
+	case "text":
+		printCompareText(c)
+	default:
+		fmt.Printf("unknown format %q\n", *format)
+		return 1
+	}
+	return 0
+}
+
+// printCompareText prints the comparison as plain text.
+func printCompareText(c *coverprofile.Comparison) {
+	fmt.Printf("Total coverage: %s\n\n", formatDelta(c.Total))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tOLD\tNEW\tCHANGE")
+	for _, p := range c.Packages {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Name, formatCoverage(p.OldCoverage, p.OldStatements), formatCoverage(p.NewCoverage, p.NewStatements), formatChange(p))
+	}
+	tw.Flush()
+
+	if len(c.Files) > 0 {
+		fmt.Println()
+		tw = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHANGED FILE\tOLD\tNEW\tCHANGE")
+		for _, f := range c.Files {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Name, formatCoverage(f.OldCoverage, f.OldStatements), formatCoverage(f.NewCoverage, f.NewStatements), formatChange(f))
+		}
+		tw.Flush()
+	}
+	if len(c.NewlyUncovered) > 0 {
+		fmt.Println("\nNewly uncovered functions:")
+		for _, fn := range c.NewlyUncovered {
+			fmt.Printf("\t%s: %s (was %.2f%%)\n", fn.File, fn.Func, fn.OldCoverage)
+		}
+	}
+}
+
+// printCompareMarkdown prints the comparison as Markdown.
+func printCompareMarkdown(c *coverprofile.Comparison) {
+	fmt.Printf("### Coverage drift\n\nTotal coverage: **%s**\n\n", formatDelta(c.Total))
+	fmt.Println("| Package | Old | New | Change |")
+	fmt.Println("|---------|----:|----:|-------:|")
+	for _, p := range c.Packages {
+		fmt.Printf("| `%s` | %s | %s | %s |\n", p.Name, formatCoverage(p.OldCoverage, p.OldStatements), formatCoverage(p.NewCoverage, p.NewStatements), formatChange(p))
+	}
+	if len(c.Files) > 0 {
+		fmt.Println("\n| Changed file | Old | New | Change |")
+		fmt.Println("|--------------|----:|----:|-------:|")
+		for _, f := range c.Files {
+			fmt.Printf("| `%s` | %s | %s | %s |\n", f.Name, formatCoverage(f.OldCoverage, f.OldStatements), formatCoverage(f.NewCoverage, f.NewStatements), formatChange(f))
+		}
+	}
+	if len(c.NewlyUncovered) > 0 {
+		fmt.Println("\n#### Newly uncovered functions")
+		fmt.Println()
+		for _, fn := range c.NewlyUncovered {
+			fmt.Printf("- `%s`: `%s` (was %.2f%%)\n", fn.File, fn.Func, fn.OldCoverage)
+		}
+	}
+}
+
+// formatDelta formats the coverage of both profiles and the change.
+func formatDelta(d coverprofile.Delta) string {
+	return fmt.Sprintf("%s -> %s (%s)", formatCoverage(d.OldCoverage, d.OldStatements), formatCoverage(d.NewCoverage, d.NewStatements), formatChange(d))
+}
+
+// formatCoverage formats a coverage percentage, "-" without statements.
+func formatCoverage(coverage float64, statements int) string {
+	if statements == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", coverage)
+}
+
+// formatChange formats the change of d, "new" or "removed" if only one
+// profile has statements.
+func formatChange(d coverprofile.Delta) string {
+	switch {
+	case d.OldStatements == 0 && d.NewStatements > 0:
+		return "new"
+	case d.NewStatements == 0 && d.OldStatements > 0:
+		return "removed"
+	}
+	return fmt.Sprintf("%+.2f", d.Change)
+}
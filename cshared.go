@@ -0,0 +1,32 @@
+//go:build cshared
+
+// The C ABI of the analysis, for embedding it without running the CLI:
+//
+//	go build -tags cshared -buildmode=c-shared -o libdiffcoverage.so .
+//
+// The build also writes libdiffcoverage.h declaring the exported functions.
+
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+// AnalyzeDiffCoverage analyzes the diff coverage described by the JSON
+// request and returns the JSON response. The caller must release the result
+// with FreeString.
+//
+//export AnalyzeDiffCoverage
+func AnalyzeDiffCoverage(request *C.char) *C.char {
+	return C.CString(string(analyzeDiffCoverage([]byte(C.GoString(request)))))
+}
+
+// FreeString releases a string returned by AnalyzeDiffCoverage.
+//
+//export FreeString
+func FreeString(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
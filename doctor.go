@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// runDoctor explains why the cover file, diff and source tree do not line up.
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() < 3 {
+		fmt.Println("Usage: diffcoverage doctor <cover.out> <diff.txt> <source_root>")
+		return 1
+	}
+
+	exitCode := 0
+	for _, f := range diffcoverage.Diagnose(fs.Arg(0), fs.Arg(1), fs.Arg(2)) {
+		fmt.Printf("[%s] %s\n", f.Severity, f.Message)
+		if f.Severity == diffcoverage.SeverityError {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
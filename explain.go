@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// runExplain prints how changed lines were classified, step by step, to
+// answer why a line does or does not count towards diff coverage.
+func runExplain(args []string) int {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	coverFlag := fs.String("cover", "cover.out", "Path to the coverage profile")
+	diffFlag := fs.String("diff", "diff.txt", "Path to the diff (git diff output, with or without context lines)")
+	rootFlag := fs.String("root", ".", "Source root containing go.mod")
+	configFlag := fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present)")
+	presetFlag := fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", "))
+	unreachable := fs.Bool("ignore-unreachable", false, "Do not count changed blocks that only panic, log.Fatal or os.Exit")
+	errReturns := fs.Bool("ignore-error-returns", false, "Do not count changed if err != nil { return ..., err } statements")
+	funcs := fs.String("exclude-functions", "", "Comma-separated patterns of function names whose changed lines are not counted")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println("Usage: diffcoverage explain [-cover=cover.out] [-diff=diff.txt] [-root=.] pkg/foo.go:128 ...")
+		return 1
+	}
+
+	cfg, err := config.Resolve(*configFlag, *rootFlag, *presetFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	cfg.IgnoreUnreachable = cfg.IgnoreUnreachable || *unreachable
+	cfg.IgnoreErrorReturns = cfg.IgnoreErrorReturns || *errReturns
+	if err := setExcludeFunctions(cfg, *funcs); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	for i, location := range fs.Args() {
+		file, line, err := diffcoverage.ParseLocation(location)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := explain(os.Stdout, *coverFlag, *diffFlag, *rootFlag, cfg, file, line); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+	}
+	return 0
+}
+
+// explain writes the classification of line of file, relative to root, by
+// the steps of analyze in order, up to the one deciding it.
+func explain(w io.Writer, coverPath, diffPath, root string, cfg *config.Config, file string, line int) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	step := func(name, format string, args ...any) {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, fmt.Sprintf(format, args...))
+	}
+	verdict := func(format string, args ...any) error {
+		fmt.Fprintf(w, "%s:%d: %s\n", file, line, fmt.Sprintf(format, args...))
+		return tw.Flush()
+	}
+
+	if reason := diffcoverage.SkipReason(file); reason != "" {
+		return verdict("not counted: %s", reason)
+	}
+	ignored, err := diffcoverage.NewCoverIgnore(root).Match(file)
+	if err != nil {
+		return err
+	}
+	if ignored {
+		return verdict("not counted: the file is ignored by a %s file", diffcoverage.CoverIgnoreFile)
+	}
+	a, err := diffcoverage.Analyze(coverPath, diffPath, root)
+	if err != nil {
+		return err
+	}
+	if !a.HasLine(file, line) {
+		for key := range a.Diff.Hunks {
+			if a.RelPath(key) == file {
+				return verdict("not counted: the line is not added or changed by the diff")
+			}
+		}
+		return verdict("not counted: the file is not changed by the diff (paths are relative to the source root %s)", root)
+	}
+	step("diff", "added or changed")
+
+	fn, err := a.FuncAt(file, line)
+	if err != nil {
+		return err
+	}
+	switch {
+	case fn == nil:
+		return verdict("not counted: the line is outside any function; only function bodies are counted")
+	case line < fn.BodyStart || line > fn.BodyEnd:
+		step("function", "%s, %s", fn.Name, lineSpan(fn.Start, fn.End))
+		return verdict("not counted: the line is the signature or closing brace of %s, whose counted body is %s", fn.Name, lineSpan(fn.BodyStart, fn.BodyEnd))
+	}
+	step("function", "body of %s, %s", fn.Name, lineSpan(fn.BodyStart, fn.BodyEnd))
+
+	for _, ex := range exclusions(cfg) {
+		if err := ex.apply(a); err != nil {
+			return err
+		}
+		if a.HasLine(file, line) {
+			continue
+		}
+		switch ex.setting {
+		case "exclude":
+			var patterns []string
+			for _, p := range cfg.Exclude {
+				if diffcoverage.MatchPattern(p, file) {
+					patterns = append(patterns, p)
+				}
+			}
+			return verdict("not counted: the file matches exclude %s", strings.Join(patterns, ", "))
+		case "exclude_functions":
+			return verdict("not counted: %s matches exclude_functions %s", fn.Name, strings.Join(cfg.ExcludeFunctions, ", "))
+		case "ignore_unreachable":
+			return verdict("not counted: the line is in a block that only panics or exits (ignore_unreachable)")
+		default:
+			return verdict("not counted: the line propagates an error (ignore_error_returns)")
+		}
+	}
+	step("exclusions", "none applies")
+
+	exempted, err := applyExceptions(a, root, cfg.Exceptions)
+	if err != nil {
+		return err
+	}
+	if !a.HasLine(file, line) {
+		for _, e := range exempted {
+			if !e.Expired && diffcoverage.MatchPattern(e.Path, file) && (e.Func == "" || e.Func == fn.Name) {
+				return verdict("not counted: exempted by the coverage exception %s (%s, until %s)", e, e.Owner, e.Expires)
+			}
+		}
+		return verdict("not counted: exempted by a coverage exception")
+	}
+	step("exceptions", "none applies")
+
+	blocks, err := a.Blocks(file, line)
+	if err != nil {
+		return err
+	}
+	for _, b := range blocks {
+		step("profile", "block %s (%d statements) ran %d times", b, b.Statements, b.Count)
+	}
+	if len(blocks) == 0 {
+		if len(a.Coverage.Lines[file]) == 0 {
+			step("profile", "no data for the file: its package was not instrumented; run the tests with -coverpkg=./... or include its tests")
+		} else {
+			step("profile", "no block spans the line")
+		}
+	}
+	if a.Status(file, line) == diffcoverage.LineCovered {
+		return verdict("counted, covered")
+	}
+	return verdict("counted, not covered")
+}
+
+// lineSpan formats the lines from start to end, e.g. "lines 4-5".
+func lineSpan(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("line %d", start)
+	}
+	return fmt.Sprintf("lines %d-%d", start, end)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes the files, by path relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeFixture writes a module whose diff changes pkg/a.go, covered in part,
+// other/b.go, which the profile does not instrument, and pkg/gen.go, ignored
+// by .coverignore. It returns the module root.
+func writeFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":          "module example.com/m\n\ngo 1.21\n",
+		"pkg/a.go":        "package pkg\n\nfunc A(x int) int {\n\tif x > 0 {\n\t\treturn 1\n\t}\n\treturn 0\n}\n\nvar V = 1\n",
+		"exceptions.yaml": "- path: pkg/a.go\n  func: A\n  owner: \"@team\"\n  expires: 2999-12-31\n",
+		"pkg/gen.go":      "package pkg\n\nfunc Gen() {\n\tprintln(1)\n}\n",
+		"other/b.go":      "package other\n\nfunc B() {\n\tprintln(1)\n}\n",
+		".coverignore":    "pkg/gen.go\n",
+		"cover.out":       "mode: set\nexample.com/m/pkg/a.go:3.19,4.11 1 1\nexample.com/m/pkg/a.go:4.11,6.3 1 0\nexample.com/m/pkg/a.go:7.2,7.10 1 1\n",
+		"diff.txt":        "+++ b/pkg/a.go\n@@ -3,0 +4,4 @@\n+\tif x > 0 {\n+\t\treturn 1\n+\t}\n+\treturn 0\n@@ -9,0 +10,1 @@\n+var V = 1\n+++ b/other/b.go\n@@ -3,0 +4,1 @@\n+\tprintln(1)\n+++ b/pkg/gen.go\n@@ -3,0 +4,1 @@\n+\tprintln(1)\n",
+	})
+	return dir
+}
+
+// TestExplain checks the verdict and steps of each classification.
+func TestExplain(t *testing.T) {
+	dir := writeFixture(t)
+	cover, diff := filepath.Join(dir, "cover.out"), filepath.Join(dir, "diff.txt")
+	cases := []struct {
+		name, file string
+		line       int
+		cfg        config.Config
+		want       []string
+	}{
+		{"covered", "pkg/a.go", 7, config.Config{}, []string{`pkg/a.go:7: counted, covered
+  diff        added or changed
+  function    body of A, lines 4-7
+  exclusions  none applies
+  exceptions  none applies
+  profile     block 7.2,7.10 (1 statements) ran 1 times
+`}},
+		{"uncovered", "pkg/a.go", 5, config.Config{}, []string{"ran 0 times", "pkg/a.go:5: counted, not covered"}},
+		{"not instrumented", "other/b.go", 4, config.Config{}, []string{
+			"no data for the file: its package was not instrumented", "other/b.go:4: counted, not covered"}},
+		{"line not in diff", "pkg/a.go", 3, config.Config{}, []string{"pkg/a.go:3: not counted: the line is not added or changed by the diff"}},
+		{"file not in diff", "pkg/c.go", 1, config.Config{}, []string{"pkg/c.go:1: not counted: the file is not changed by the diff"}},
+		{"ignored", "pkg/gen.go", 4, config.Config{}, []string{"pkg/gen.go:4: not counted: the file is ignored by a .coverignore file"}},
+		{"outside functions", "pkg/a.go", 10, config.Config{}, []string{"pkg/a.go:10: not counted: the line is outside any function"}},
+		{"excluded", "pkg/a.go", 5, config.Config{Exclude: []string{"pkg/*.go"}}, []string{"pkg/a.go:5: not counted: the file matches exclude pkg/*.go"}},
+		{"exempted", "pkg/a.go", 5, config.Config{Exceptions: "exceptions.yaml"}, []string{"pkg/a.go:5: not counted: exempted by the coverage exception", "@team, until 2999-12-31"}},
+		{"excluded function", "pkg/a.go", 5, config.Config{ExcludeFunctions: []string{"A"}}, []string{"pkg/a.go:5: not counted: A matches exclude_functions A"}},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		cfg := c.cfg
+		if err := explain(&buf, cover, diff, dir, &cfg, c.file, c.line); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		for _, want := range c.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s: output lacks %q:\n%s", c.name, want, buf.String())
+			}
+		}
+	}
+}
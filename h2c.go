@@ -0,0 +1,15 @@
+//go:build go1.24
+
+package main
+
+import "net/http"
+
+// enableH2C lets srv accept HTTP/2 without TLS, as gRPC clients use inside
+// build clusters.
+func enableH2C(srv *http.Server) bool {
+	var p http.Protocols
+	p.SetHTTP1(true)
+	p.SetUnencryptedHTTP2(true)
+	srv.Protocols = &p
+	return true
+}
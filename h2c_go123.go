@@ -0,0 +1,10 @@
+//go:build !go1.24
+
+package main
+
+import "net/http"
+
+// enableH2C reports that cleartext HTTP/2 needs Go 1.24.
+func enableH2C(srv *http.Server) bool {
+	return false
+}
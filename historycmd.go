@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runHistory lists and shows runs recorded with -publish=history.
+func runHistory(args []string) int {
+	if len(args) < 1 || (args[0] != "list" && args[0] != "show" && args[0] != "chart" && args[0] != "prune") {
+		fmt.Println("Usage: diffcoverage history list [-branch=main] [-n=20] | history show <id> | history chart -o coverage.svg | history prune [-keep=100] [-max-age=2160h]")
+		return 1
+	}
+
+	fs := flag.NewFlagSet("history "+args[0], flag.ExitOnError)
+	root := fs.String("root", ".", "Module root containing the history file")
+	file := fs.String("file", "", "History file (default: <root>/"+history.DefaultPath+")")
+	branch := fs.String("branch", "", "Only list runs of this branch")
+	limit := fs.Int("n", 20, "Number of runs to list; 0 lists all")
+	output := fs.String("o", "coverage.svg", "Chart file; .png writes a PNG image, anything else SVG")
+	title := fs.String("title", "", "Chart title (default: the branch)")
+	keep := fs.Int("keep", 0, "Prune all but the newest runs of each branch of each repository, this many; 0 keeps all")
+	maxAge := fs.Duration("max-age", 0, "Prune runs older than this, e.g. 2160h for 90 days; 0 keeps all")
+	dryRun := fs.Bool("dry-run", false, "Only report how many runs prune would remove")
+	fs.Parse(args[1:])
+
+	store := &history.Store{Path: *file}
+	if store.Path == "" {
+		store.Path = filepath.Join(*root, history.DefaultPath)
+	}
+
+	if args[0] == "prune" {
+		return pruneHistory(store, history.Retention{KeepRuns: *keep, MaxAge: *maxAge}, *dryRun)
+	}
+	if args[0] == "show" {
+		if fs.NArg() < 1 {
+			fmt.Println("Usage: diffcoverage history show <id>")
+			return 1
+		}
+		id, err := strconv.Atoi(fs.Arg(0))
+		if err != nil {
+			fmt.Printf("invalid run id %q\n", fs.Arg(0))
+			return 1
+		}
+		rec, err := store.Get(id)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		printRecord(rec)
+		return 0
+	}
+
+	records, err := store.List(*branch, *limit)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	if len(records) == 0 {
+		fmt.Printf("No runs recorded in %s\n", store.Path)
+		return 0
+	}
+	if args[0] == "chart" {
+		return writeChart(records, *output, firstNonEmpty(*title, *branch))
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTIME\tBRANCH\tCOMMIT\tCOVERAGE\tLINES\tRESULT")
+	for _, rec := range records {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%.2f%%\t%d/%d\t%s\n", rec.ID, rec.Time.Local().Format("2006-01-02 15:04"),
+			rec.Branch, shortSHA(rec.Commit), rec.Report.Coverage, rec.Report.CoveredLines, rec.Report.TotalLines, result(rec.Report.Passed))
+	}
+	tw.Flush()
+	return 0
+}
+
+// pruneHistory removes the runs of store outside ret.
+func pruneHistory(store *history.Store, ret history.Retention, dryRun bool) int {
+	if ret.KeepRuns < 0 || ret.MaxAge < 0 {
+		fmt.Println("-keep and -max-age must not be negative")
+		return 1
+	}
+	if ret.KeepRuns == 0 && ret.MaxAge == 0 {
+		fmt.Println("Usage: diffcoverage history prune [-keep=N] [-max-age=duration] [-dry-run]; set -keep or -max-age")
+		return 1
+	}
+	removed, err := store.Prune(ret, time.Now(), dryRun)
+	if err != nil {
+		fmt.Printf("error pruning %s: %v\n", store.Path, err)
+		return 1
+	}
+	if dryRun {
+		fmt.Printf("Would remove %d runs from %s\n", removed, store.Path)
+	} else {
+		fmt.Printf("Removed %d runs from %s\n", removed, store.Path)
+	}
+	return 0
+}
+
+// writeChart plots records to path in the format given by its extension.
+func writeChart(records []history.Record, path, title string) int {
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	chart := &history.Chart{Title: title}
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		err = chart.WritePNG(f, records)
+	} else {
+		err = chart.WriteSVG(f, records)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Printf("error writing chart: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote chart of %d runs to %s\n", len(records), path)
+	return 0
+}
+
+// printRecord prints a run with its per-file results.
+func printRecord(rec *history.Record) {
+	r := rec.Report
+	fmt.Printf("Run #%d at %s\n", rec.ID, rec.Time.Local().Format("2006-01-02 15:04:05"))
+	if rec.Repo != "" {
+		fmt.Printf("Repository: %s\n", rec.Repo)
+	}
+	fmt.Printf("Branch: %s\nCommit: %s\n", rec.Branch, rec.Commit)
+	fmt.Printf("Coverage: %.2f%% (minimum %.2f%%), %d of %d lines covered: %s\n",
+		r.Coverage, r.MinCoverage, r.CoveredLines, r.TotalLines, result(r.Passed))
+	if len(r.Files) == 0 {
+		return
+	}
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tCOVERED\tCOVERAGE\tUNCOVERED LINES")
+	for _, f := range r.Files {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%.2f%%\t%s\n", f.Path, f.CoveredLines, f.TotalLines, f.Coverage, reporter.FormatRanges(f.Uncovered))
+	}
+	tw.Flush()
+}
+
+func result(passed bool) string {
+	if passed {
+		return "pass"
+	}
+	return "fail"
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
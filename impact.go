@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/testrun"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runImpact prints the packages to test for coverage of the changed lines.
+func runImpact(args []string) int {
+	fs := flag.NewFlagSet("impact", flag.ExitOnError)
+	root := fs.String("root", ".", "Module root containing go.mod")
+	base := fs.String("base", "origin/main", "Git ref to diff the working tree against")
+	configPath := fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present)")
+	preset := fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", "))
+	format := fs.String("format", "text", "Output format: text (one package per line), args (go test arguments) or json")
+	fs.Parse(args)
+
+	cfg, err := config.Resolve(*configPath, *root, *preset)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	tmpDir, err := os.MkdirTemp("", "diffcoverage-impact")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating temp dir: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(tmpDir)
+	diffPath := filepath.Join(tmpDir, "diff.txt")
+	if err := testrun.WriteDiff(*root, *base, diffPath); err != nil {
+		fmt.Fprintf(os.Stderr, "error computing diff: %v\n", err)
+		return 1
+	}
+
+	changedPkgs, testPkgs, err := impactedPackages(*root, diffPath, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	switch *format {
+	case "text":
+		for _, p := range testPkgs {
+			fmt.Println(p)
+		}
+	case "args":
+		if len(testPkgs) > 0 {
+			fmt.Printf("-coverpkg=%s %s\n", strings.Join(changedPkgs, ","), strings.Join(testPkgs, " "))
+		}
+	case "json":
+		out := struct {
+			Changed []string `json:"changed"`
+			Test    []string `json:"test"`
+		}{append([]string{}, changedPkgs...), append([]string{}, testPkgs...)}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 1
+	}
+	return 0
+}
+
+// impactedPackages returns the packages with counted changes in the diff at
+// diffPath and the packages whose tests can cover them.
+func impactedPackages(root, diffPath string, cfg *config.Config) (changed, toTest []string, err error) {
+	files, err := diffcoverage.CountedFiles(diffPath, root)
+	if err != nil {
+		return nil, nil, err
+	}
+	var counted []string
+	for _, f := range files {
+		if !diffcoverage.MatchAny(cfg.Exclude, f) {
+			counted = append(counted, f)
+		}
+	}
+	if len(counted) == 0 {
+		return nil, nil, nil
+	}
+
+	pkgs, err := testrun.ListPackages(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	changed, toTest = testrun.SelectPackages(root, pkgs, counted)
+	if len(toTest) == 0 {
+		// Testing the changed packages anyway records their statements as
+		// uncovered, instead of missing from the profile.
+		toTest = changed
+	}
+	return changed, toTest, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runInit inspects the repository and writes a starter configuration file.
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	rootFlag := fs.String("root", ".", "Module root containing go.mod")
+	minCoverageFlag := fs.Float64("min", config.DefaultMinCoverage, "Minimum coverage percentage to configure")
+	forceFlag := fs.Bool("force", false, "Overwrite an existing configuration file")
+	fs.Parse(args)
+
+	path := filepath.Join(*rootFlag, config.FileName)
+	if _, err := os.Stat(path); err == nil && !*forceFlag {
+		fmt.Printf("%s already exists (use -force to overwrite it)\n", path)
+		return 1
+	}
+
+	insp, err := config.Inspect(*rootFlag)
+	if err != nil {
+		fmt.Printf("error inspecting %s: %v\n", *rootFlag, err)
+		return 1
+	}
+	if insp.ModuleName == "" {
+		fmt.Printf("warning: no go.mod found in %s; run init from the module root\n", *rootFlag)
+	}
+
+	if err := os.WriteFile(path, config.Scaffold(insp, *minCoverageFlag), 0644); err != nil {
+		fmt.Printf("error writing %s: %v\n", path, err)
+		return 1
+	}
+
+	fmt.Printf("Wrote %s\n", path)
+	if len(insp.Exclude) > 0 {
+		fmt.Printf("Excluding: %s\n", strings.Join(insp.Exclude, ", "))
+	}
+	if len(insp.CI) > 0 {
+		fmt.Printf("Detected CI: %s\n", strings.Join(insp.CI, ", "))
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/hook"
+	"github.com/JackShadow/go-new-code-coverage/internal/version"
+	"os"
+)
+
+// runInstallHook writes a git hook that enforces diff coverage locally.
+func runInstallHook(args []string) int {
+	fs := flag.NewFlagSet("install-hook", flag.ExitOnError)
+	kindFlag := fs.String("hook", "pre-push", "Hook to install: pre-push or pre-commit")
+	baseFlag := fs.String("base", "origin/main", "Ref to diff against (pre-push only)")
+	minCoverageFlag := fs.Float64("min", 0.0, "Minimum coverage percentage (e.g., 80.0)")
+	rootFlag := fs.String("root", ".", "Module root containing go.mod")
+	forceFlag := fs.Bool("force", false, "Replace an existing hook not installed by diffcoverage")
+	fs.Parse(args)
+
+	binary, err := os.Executable()
+	if err != nil {
+		binary = "go-new-code-coverage"
+	}
+
+	hookPath, err := hook.Install(*rootFlag, hook.Options{
+		Kind:        *kindFlag,
+		Base:        *baseFlag,
+		MinCoverage: *minCoverageFlag,
+		Binary:      binary,
+		Version:     version.Get().Short(),
+	}, *forceFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	fmt.Printf("Installed %s hook at %s\n", *kindFlag, hookPath)
+	return 0
+}
@@ -0,0 +1,85 @@
+// Package artifacts downloads coverage profiles that previous CI runs of the
+// base branch uploaded as build artifacts, to use them as the baseline.
+package artifacts
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// ErrNotFound is returned when no artifact matches.
+var ErrNotFound = errors.New("no matching artifact")
+
+// Artifact is a downloaded artifact.
+type Artifact struct {
+	Name   string
+	Commit string // commit the run that uploaded it built
+	Source string // the run or job that uploaded it, for messages
+	Data   []byte // the requested file
+}
+
+// get fetches url and returns its body, failing on non-2xx responses.
+func get(ctx context.Context, client *http.Client, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET %s: unexpected status %s: %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extract returns the file named name from a zip archive, matched by its path
+// or its base name; with an empty name the archive must hold a single file.
+func extract(data []byte, name string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading artifact archive: %v", err)
+	}
+	var files []*zip.File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if name == "" || f.Name == name || path.Base(f.Name) == name {
+			files = append(files, f)
+		}
+	}
+	switch {
+	case len(files) == 0:
+		return nil, fmt.Errorf("artifact has no file %s", name)
+	case len(files) > 1 && name == "":
+		return nil, fmt.Errorf("artifact has %d files; choose one with -file", len(files))
+	}
+	rc, err := files[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,92 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitHub finds artifacts uploaded by GitHub Actions workflow runs.
+type GitHub struct {
+	APIURL string // e.g. https://api.github.com
+	Repo   string // owner/name
+	Token  string
+	Client *http.Client
+}
+
+type githubArtifacts struct {
+	Artifacts []struct {
+		Name               string `json:"name"`
+		Expired            bool   `json:"expired"`
+		ArchiveDownloadURL string `json:"archive_download_url"`
+		WorkflowRun        struct {
+			ID         int64  `json:"id"`
+			HeadBranch string `json:"head_branch"`
+			HeadSHA    string `json:"head_sha"`
+		} `json:"workflow_run"`
+	} `json:"artifacts"`
+}
+
+// Fetch downloads file from the most recent unexpired artifact called name
+// uploaded by a run on branch, or by a run of commit if it is set.
+func (g *GitHub) Fetch(ctx context.Context, name, branch, commit, file string) (*Artifact, error) {
+	if g.Repo == "" || g.Token == "" {
+		return nil, fmt.Errorf("github artifact: repository and token are required")
+	}
+	const perPage = 100
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/repos/%s/actions/artifacts?name=%s&per_page=%d&page=%d", g.apiURL(), g.Repo, url.QueryEscape(name), perPage, page)
+		data, err := get(ctx, g.Client, u, g.header())
+		if err != nil {
+			return nil, fmt.Errorf("github artifact: %v", err)
+		}
+		var list githubArtifacts
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("github artifact: error decoding %s: %v", u, err)
+		}
+		// Artifacts are listed newest first.
+		for _, a := range list.Artifacts {
+			run := a.WorkflowRun
+			if a.Name != name || a.Expired || (commit != "" && run.HeadSHA != commit) || (commit == "" && run.HeadBranch != branch) {
+				continue
+			}
+			archive, err := get(ctx, g.Client, a.ArchiveDownloadURL, g.header())
+			if err != nil {
+				return nil, fmt.Errorf("github artifact: %v", err)
+			}
+			content, err := extract(archive, file)
+			if err != nil {
+				return nil, fmt.Errorf("github artifact %s: %v", name, err)
+			}
+			return &Artifact{
+				Name:   name,
+				Commit: run.HeadSHA,
+				Source: fmt.Sprintf("workflow run %d", run.ID),
+				Data:   content,
+			}, nil
+		}
+		if len(list.Artifacts) < perPage {
+			return nil, ErrNotFound
+		}
+	}
+}
+
+func (g *GitHub) apiURL() string {
+	if g.APIURL == "" {
+		return "https://api.github.com"
+	}
+	return strings.TrimSuffix(g.APIURL, "/")
+}
+
+// header authenticates the API requests. The download URL redirects to blob
+// storage, and Go drops the Authorization header on that cross-host redirect.
+func (g *GitHub) header() http.Header {
+	return http.Header{
+		"Authorization":        {"Bearer " + g.Token},
+		"Accept":               {"application/vnd.github+json"},
+		"X-Github-Api-Version": {"2022-11-28"},
+	}
+}
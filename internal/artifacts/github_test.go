@@ -0,0 +1,73 @@
+package artifacts
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestGitHub_Fetch picks the newest unexpired artifact of the branch.
+func TestGitHub_Fetch(t *testing.T) {
+	archive := zipFiles(t, map[string]string{"cover.out": "mode: set\n", "report.json": "{}"})
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "denied", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/repos/octo/repo/actions/artifacts":
+			if r.URL.Query().Get("name") != "coverage" {
+				t.Errorf("Unexpected query %s", r.URL.RawQuery)
+			}
+			fmt.Fprintf(w, `{"artifacts": [
+				{"name": "coverage", "expired": false, "archive_download_url": "%[1]s/zip/3", "workflow_run": {"id": 3, "head_branch": "feature", "head_sha": "c3"}},
+				{"name": "coverage", "expired": true, "archive_download_url": "%[1]s/zip/2", "workflow_run": {"id": 2, "head_branch": "main", "head_sha": "c2"}},
+				{"name": "coverage", "expired": false, "archive_download_url": "%[1]s/zip/1", "workflow_run": {"id": 1, "head_branch": "main", "head_sha": "c1"}}
+			]}`, srv.URL)
+		case "/zip/1":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := &GitHub{APIURL: srv.URL, Repo: "octo/repo", Token: "tok", Client: srv.Client()}
+	a, err := g.Fetch(context.Background(), "coverage", "main", "", "cover.out")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if a.Commit != "c1" || a.Source != "workflow run 1" || string(a.Data) != "mode: set\n" {
+		t.Errorf("Unexpected artifact %+v", a)
+	}
+
+	if _, err := g.Fetch(context.Background(), "coverage", "main", "c9", "cover.out"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown commit, got %v", err)
+	}
+	if _, err := g.Fetch(context.Background(), "coverage", "main", "", ""); err == nil {
+		t.Errorf("Expected an error choosing among several files")
+	}
+}
@@ -0,0 +1,126 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLab finds artifacts of jobs of successful GitLab CI pipelines.
+type GitLab struct {
+	APIURL  string // e.g. https://gitlab.com/api/v4
+	Project string // numeric ID or full path such as group/project
+	Token   string
+	// JobToken means Token is a CI_JOB_TOKEN, which cannot list pipelines:
+	// only the latest artifact of a branch can be fetched with it.
+	JobToken bool
+	Client   *http.Client
+}
+
+type gitlabPipeline struct {
+	ID  int64  `json:"id"`
+	SHA string `json:"sha"`
+}
+
+type gitlabJob struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Artifacts []struct {
+		FileType string `json:"file_type"`
+	} `json:"artifacts"`
+}
+
+// Fetch downloads file from the artifacts of the job called name in the
+// latest successful pipeline of branch, or of commit if it is set.
+func (g *GitLab) Fetch(ctx context.Context, name, branch, commit, file string) (*Artifact, error) {
+	if g.Project == "" || g.Token == "" {
+		return nil, fmt.Errorf("gitlab artifact: project and token are required")
+	}
+	project := g.apiURL() + "/projects/" + url.PathEscape(g.Project)
+	if g.JobToken {
+		if commit != "" {
+			return nil, fmt.Errorf("gitlab artifact: fetching the artifact of a commit requires an access token (GITLAB_TOKEN)")
+		}
+		u := fmt.Sprintf("%s/jobs/artifacts/%s/download?job=%s", project, url.PathEscape(branch), url.QueryEscape(name))
+		return g.download(ctx, u, name, "", "latest successful pipeline of "+branch, file)
+	}
+
+	query := url.Values{"status": {"success"}, "order_by": {"id"}, "sort": {"desc"}, "per_page": {"20"}}
+	if commit != "" {
+		query.Set("sha", commit)
+	} else {
+		query.Set("ref", branch)
+	}
+	var pipelines []gitlabPipeline
+	if err := g.getJSON(ctx, project+"/pipelines?"+query.Encode(), &pipelines); err != nil {
+		return nil, err
+	}
+	for _, p := range pipelines {
+		var jobs []gitlabJob
+		if err := g.getJSON(ctx, fmt.Sprintf("%s/pipelines/%d/jobs?scope[]=success&per_page=100", project, p.ID), &jobs); err != nil {
+			return nil, err
+		}
+		for _, j := range jobs {
+			if j.Name != name || !hasArchive(j) {
+				continue
+			}
+			u := fmt.Sprintf("%s/jobs/%d/artifacts", project, j.ID)
+			return g.download(ctx, u, name, p.SHA, fmt.Sprintf("pipeline %d", p.ID), file)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// hasArchive reports whether the job uploaded artifacts:paths, as opposed
+// to only reports or logs.
+func hasArchive(j gitlabJob) bool {
+	for _, a := range j.Artifacts {
+		if a.FileType == "archive" {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GitLab) download(ctx context.Context, u, name, commit, source, file string) (*Artifact, error) {
+	archive, err := get(ctx, g.Client, u, g.header())
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, err
+		}
+		return nil, fmt.Errorf("gitlab artifact: %v", err)
+	}
+	content, err := extract(archive, file)
+	if err != nil {
+		return nil, fmt.Errorf("gitlab artifact %s: %v", name, err)
+	}
+	return &Artifact{Name: name, Commit: commit, Source: source, Data: content}, nil
+}
+
+func (g *GitLab) getJSON(ctx context.Context, u string, out any) error {
+	data, err := get(ctx, g.Client, u, g.header())
+	if err != nil {
+		return fmt.Errorf("gitlab artifact: %v", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("gitlab artifact: error decoding %s: %v", u, err)
+	}
+	return nil
+}
+
+func (g *GitLab) apiURL() string {
+	if g.APIURL == "" {
+		return "https://gitlab.com/api/v4"
+	}
+	return strings.TrimSuffix(g.APIURL, "/")
+}
+
+func (g *GitLab) header() http.Header {
+	if g.JobToken {
+		return http.Header{"Job-Token": {g.Token}}
+	}
+	return http.Header{"Private-Token": {g.Token}}
+}
@@ -0,0 +1,53 @@
+package artifacts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGitLab_Fetch finds the job in the latest successful pipeline with artifacts.
+func TestGitLab_Fetch(t *testing.T) {
+	archive := zipFiles(t, map[string]string{"out/cover.out": "mode: atomic\n"})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.RawPath == "/projects/group%2Fproject/pipelines" || r.URL.Path == "/projects/group/project/pipelines":
+			if r.Header.Get("Private-Token") != "pat" || r.URL.Query().Get("ref") != "main" || r.URL.Query().Get("status") != "success" {
+				t.Errorf("Unexpected pipelines request %s", r.URL)
+			}
+			w.Write([]byte(`[{"id": 12, "sha": "c12"}, {"id": 11, "sha": "c11"}]`))
+		case r.URL.Path == "/projects/group/project/pipelines/12/jobs":
+			w.Write([]byte(`[{"id": 120, "name": "coverage", "artifacts": [{"file_type": "trace"}]}]`))
+		case r.URL.Path == "/projects/group/project/pipelines/11/jobs":
+			w.Write([]byte(`[{"id": 110, "name": "lint", "artifacts": [{"file_type": "archive"}]}, {"id": 111, "name": "coverage", "artifacts": [{"file_type": "archive"}]}]`))
+		case r.URL.Path == "/projects/group/project/jobs/111/artifacts":
+			w.Write(archive)
+		case r.URL.Path == "/projects/group/project/jobs/artifacts/main/download":
+			if r.Header.Get("Job-Token") != "job" || r.URL.Query().Get("job") != "coverage" {
+				t.Errorf("Unexpected download request %s", r.URL)
+			}
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := &GitLab{APIURL: srv.URL, Project: "group/project", Token: "pat", Client: srv.Client()}
+	a, err := g.Fetch(context.Background(), "coverage", "main", "", "cover.out")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if a.Commit != "c11" || a.Source != "pipeline 11" || string(a.Data) != "mode: atomic\n" {
+		t.Errorf("Unexpected artifact %+v", a)
+	}
+
+	g = &GitLab{APIURL: srv.URL, Project: "group/project", Token: "job", JobToken: true, Client: srv.Client()}
+	if a, err = g.Fetch(context.Background(), "coverage", "main", "", "cover.out"); err != nil || string(a.Data) != "mode: atomic\n" {
+		t.Errorf("Fetch with a job token = %+v, %v", a, err)
+	}
+	if _, err = g.Fetch(context.Background(), "coverage", "main", "c11", "cover.out"); err == nil {
+		t.Errorf("Expected an error fetching a commit's artifact with a job token")
+	}
+}
@@ -0,0 +1,147 @@
+// Package attest signs and verifies JSON reports, so tooling downstream of CI
+// can check that the published coverage numbers were not altered. Signatures
+// are detached and base64 encoded; those of ECDSA P-256 keys are over the
+// SHA-256 digest, as checked by `cosign verify-blob --key` and openssl.
+package attest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SignatureSuffix is appended to the path of a report to name its signature.
+const SignatureSuffix = ".sig"
+
+// ErrInvalid is returned by Verify when the signature does not match.
+var ErrInvalid = errors.New("invalid signature")
+
+// Key signs or verifies reports: an ed25519 or ECDSA key, or an HMAC-SHA256
+// secret shared by the signer and the verifiers.
+type Key struct {
+	private crypto.Signer
+	public  crypto.PublicKey
+	secret  []byte
+}
+
+// Secret returns an HMAC-SHA256 key.
+func Secret(secret string) *Key {
+	return &Key{secret: []byte(secret)}
+}
+
+// ParsePrivateKey parses a PEM encoded, unencrypted PKCS#8 ("PRIVATE KEY") or
+// SEC 1 ("EC PRIVATE KEY") ed25519 or ECDSA private key.
+func ParsePrivateKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported private key type %q; encrypted keys must be decrypted first", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %v", err)
+	}
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return &Key{private: k, public: k.Public()}, nil
+	case *ecdsa.PrivateKey:
+		return &Key{private: k, public: k.Public()}, nil
+	}
+	return nil, fmt.Errorf("unsupported private key %T: expected ed25519 or ECDSA", key)
+}
+
+// ParsePublicKey parses a PEM encoded ed25519 or ECDSA public key ("PUBLIC
+// KEY"); a private key is accepted too.
+func ParsePublicKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded public key found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		k, err := ParsePrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		return &Key{public: k.public}, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key: %v", err)
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return &Key{public: key}, nil
+	}
+	return nil, fmt.Errorf("unsupported public key %T: expected ed25519 or ECDSA", key)
+}
+
+// Sign returns the base64 encoded signature of data.
+func (k *Key) Sign(data []byte) (string, error) {
+	var sig []byte
+	var err error
+	switch p := k.private.(type) {
+	case nil:
+		if k.secret == nil {
+			return "", fmt.Errorf("no private key or secret to sign with")
+		}
+		sig = k.mac(data)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(p, data)
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		sig, err = ecdsa.SignASN1(rand.Reader, p, digest[:])
+	}
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// Verify checks the base64 encoded signature of data, returning ErrInvalid
+// if it does not match.
+func (k *Key) Verify(data []byte, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return fmt.Errorf("signature is not base64 encoded: %v", err)
+	}
+	valid := false
+	switch p := k.public.(type) {
+	case nil:
+		if k.secret == nil {
+			return fmt.Errorf("no public key or secret to verify with")
+		}
+		valid = hmac.Equal(sig, k.mac(data))
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(p, data, sig)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = ecdsa.VerifyASN1(p, digest[:], sig)
+	}
+	if !valid {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func (k *Key) mac(data []byte) []byte {
+	m := hmac.New(sha256.New, k.secret)
+	m.Write(data)
+	return m.Sum(nil)
+}
@@ -0,0 +1,76 @@
+package attest
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func pemKeys(t *testing.T, private any) (privatePEM, publicPEM []byte) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var public any
+	switch k := private.(type) {
+	case ed25519.PrivateKey:
+		public = k.Public()
+	case *ecdsa.PrivateKey:
+		public = k.Public()
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+}
+
+// TestKey_SignVerify round-trips signatures of every key type and rejects
+// altered reports.
+func TestKey_SignVerify(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	report := []byte(`{"coverage":85}`)
+
+	for name, private := range map[string]any{"ed25519": edKey, "ecdsa": ecKey} {
+		privatePEM, publicPEM := pemKeys(t, private)
+		signer, err := ParsePrivateKey(privatePEM)
+		if err != nil {
+			t.Fatalf("%s: ParsePrivateKey failed: %v", name, err)
+		}
+		verifier, err := ParsePublicKey(publicPEM)
+		if err != nil {
+			t.Fatalf("%s: ParsePublicKey failed: %v", name, err)
+		}
+		sig, err := signer.Sign(report)
+		if err != nil {
+			t.Fatalf("%s: Sign failed: %v", name, err)
+		}
+		if err := verifier.Verify(report, sig); err != nil {
+			t.Errorf("%s: Verify failed: %v", name, err)
+		}
+		if err := verifier.Verify([]byte(`{"coverage":95}`), sig); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid for an altered report, got %v", name, err)
+		}
+		if _, err := verifier.Sign(report); err == nil {
+			t.Errorf("%s: expected an error signing with a public key", name)
+		}
+	}
+
+	sig, err := Secret("s3cret").Sign(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Secret("s3cret").Verify(report, sig); err != nil {
+		t.Errorf("HMAC Verify failed: %v", err)
+	}
+	if err := Secret("other").Verify(report, sig); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for another secret, got %v", err)
+	}
+}
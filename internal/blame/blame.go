@@ -0,0 +1,153 @@
+// Package blame attributes uncovered lines to the authors and commits that
+// last changed them, using git blame.
+package blame
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+)
+
+// Line is the blame of one line.
+type Line struct {
+	Commit  string
+	Author  string
+	Email   string
+	Time    int64  // author time, Unix seconds
+	Summary string // first line of the commit message
+}
+
+// File blames the given line ranges of file, relative to dir, in the working
+// tree. Lines that are not committed yet have an all-zero commit.
+func File(dir, file string, ranges [][2]int) (map[int]Line, error) {
+	return FileAt(dir, "", file, ranges)
+}
+
+// FileAt is File at the revision rev, or the working tree if rev is empty.
+func FileAt(dir, rev, file string, ranges [][2]int) (map[int]Line, error) {
+	args := []string{"blame", "--line-porcelain"}
+	for _, r := range ranges {
+		args = append(args, "-L", fmt.Sprintf("%d,%d", r[0], r[1]))
+	}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	args = append(args, "--", file)
+
+	var out bytes.Buffer
+	if err := gitutil.RunTo(dir, &out, args...); err != nil {
+		return nil, err
+	}
+	return parsePorcelain(&out)
+}
+
+// Since returns the lines of files, relative to dir, in the working tree that
+// were last changed at or after t, by file. Uncommitted lines count as new.
+func Since(dir string, files []string, t time.Time) (map[string]map[int]bool, error) {
+	recent := map[string]map[int]bool{}
+	for _, file := range files {
+		lines, err := File(dir, file, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error blaming %s: %v", file, err)
+		}
+		recent[file] = map[int]bool{}
+		for n, l := range lines {
+			if l.Time >= t.Unix() {
+				recent[file][n] = true
+			}
+		}
+	}
+	return recent, nil
+}
+
+// parsePorcelain reads git blame --line-porcelain output, keyed by final line number.
+func parsePorcelain(r io.Reader) (map[int]Line, error) {
+	lines := map[int]Line{}
+	var cur Line
+	var lineNo int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	header := true
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case header:
+			// <sha> <original line> <final line> [<group size>]
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("unexpected blame header %q", text)
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected blame header %q", text)
+			}
+			cur, lineNo, header = Line{Commit: fields[0]}, n, false
+		case strings.HasPrefix(text, "\t"):
+			lines[lineNo] = cur
+			header = true
+		case strings.HasPrefix(text, "author "):
+			cur.Author = strings.TrimPrefix(text, "author ")
+		case strings.HasPrefix(text, "author-mail "):
+			cur.Email = strings.Trim(strings.TrimPrefix(text, "author-mail "), "<>")
+		case strings.HasPrefix(text, "author-time "):
+			cur.Time, _ = strconv.ParseInt(strings.TrimPrefix(text, "author-time "), 10, 64)
+		case strings.HasPrefix(text, "summary "):
+			cur.Summary = strings.TrimPrefix(text, "summary ")
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// Attribute blames the uncovered lines of r in its source root and sets
+// r.Authors, sorted by the number of uncovered lines.
+func Attribute(r *diffcoverage.Report) error {
+	byAuthor := map[string]*diffcoverage.AuthorReport{}
+	commits := map[string]map[string]bool{}
+	for _, f := range r.Files {
+		if len(f.Uncovered) == 0 {
+			continue
+		}
+		lines, err := File(r.SourceRoot, f.Path, f.Uncovered)
+		if err != nil {
+			return fmt.Errorf("error blaming %s: %v", f.Path, err)
+		}
+		for _, l := range lines {
+			key := l.Email
+			if key == "" {
+				key = l.Author
+			}
+			a := byAuthor[key]
+			if a == nil {
+				a = &diffcoverage.AuthorReport{Author: l.Author, Email: l.Email}
+				byAuthor[key] = a
+				commits[key] = map[string]bool{}
+			}
+			a.UncoveredLines++
+			if !commits[key][l.Commit] {
+				commits[key][l.Commit] = true
+				a.Commits = append(a.Commits, l.Commit)
+			}
+		}
+	}
+
+	r.Authors = make([]diffcoverage.AuthorReport, 0, len(byAuthor))
+	for _, a := range byAuthor {
+		sort.Strings(a.Commits)
+		r.Authors = append(r.Authors, *a)
+	}
+	sort.Slice(r.Authors, func(i, j int) bool {
+		if r.Authors[i].UncoveredLines != r.Authors[j].UncoveredLines {
+			return r.Authors[i].UncoveredLines > r.Authors[j].UncoveredLines
+		}
+		return r.Authors[i].Author < r.Authors[j].Author
+	})
+	return nil
+}
@@ -0,0 +1,147 @@
+package blame
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+)
+
+func mustGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := gitutil.Run(dir, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commitAs writes content to file and commits it as the given author.
+func commitAs(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mustGit(t, dir, "add", "-A")
+	mustGit(t, dir, "-c", "user.name="+name, "-c", "user.email="+name+"@example.com", "commit", "-q", "-m", name)
+	sha, err := gitutil.Run(dir, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+// TestAttribute counts uncovered lines per author, including uncommitted ones.
+func TestAttribute(t *testing.T) {
+	dir := t.TempDir()
+	mustGit(t, dir, "init", "-q")
+	alice := commitAs(t, dir, "alice", "package a\n\nfunc A() {\n\tx()\n}\n")
+	bob := commitAs(t, dir, "bob", "package a\n\nfunc A() {\n\tx()\n\ty()\n\tz()\n}\n")
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {\n\tx()\n\ty()\n\tz()\n\tw()\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &diffcoverage.Report{SourceRoot: dir, Files: []diffcoverage.FileReport{
+		{Path: "a.go", Uncovered: [][2]int{{4, 7}}},
+	}}
+	if err := Attribute(r); err != nil {
+		t.Fatalf("Attribute failed: %v", err)
+	}
+
+	if len(r.Authors) != 3 {
+		t.Fatalf("Expected 3 authors, got %+v", r.Authors)
+	}
+	if got, want := r.Authors[0], (diffcoverage.AuthorReport{Author: "bob", Email: "bob@example.com", UncoveredLines: 2, Commits: []string{bob}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Authors[0] = %+v, want %+v", got, want)
+	}
+	if got, want := r.Authors[1], (diffcoverage.AuthorReport{Author: "Not Committed Yet", Email: "not.committed.yet", UncoveredLines: 1, Commits: []string{strings.Repeat("0", len(alice))}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Authors[1] = %+v, want %+v", got, want)
+	}
+	if got := r.Authors[2]; got.Author != "alice" || got.UncoveredLines != 1 || got.Commits[0] != alice {
+		t.Errorf("Authors[2] = %+v", got)
+	}
+}
+
+// TestParsePorcelain_Error rejects malformed headers.
+func TestParsePorcelain_Error(t *testing.T) {
+	if _, err := parsePorcelain(strings.NewReader("garbage\n")); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
+
+// TestStats aggregates the coverage of the counted lines per author at a revision.
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	mustGit(t, dir, "init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	alice := commitAs(t, dir, "alice", "package a\n\nfunc A() {\n\tx()\n}\n")
+	bob := commitAs(t, dir, "bob", "package a\n\nfunc A() {\n\tx()\n\ty()\n\tz()\n}\n")
+	files := map[string]string{
+		"cover.out": "mode: set\nexample.com/m/a.go:3.10,5.5 2 1\nexample.com/m/a.go:6.2,6.5 1 0\n",
+		"diff.txt":  "+++ b/a.go\n@@ -3,0 +4,3 @@\n+\tx()\n+\ty()\n+\tz()\n",
+	}
+	tmp := t.TempDir()
+	for name, content := range files {
+		files[name] = filepath.Join(tmp, name)
+		if err := os.WriteFile(files[name], []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := diffcoverage.Analyze(files["cover.out"], files["diff.txt"], dir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	stats, err := Stats(a, "HEAD")
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	want := []AuthorStats{
+		{Author: "alice", Email: "alice@example.com", TotalLines: 1, CoveredLines: 1, Coverage: 100, Commits: []string{alice}},
+		{Author: "bob", Email: "bob@example.com", TotalLines: 2, CoveredLines: 1, Coverage: 50, Commits: []string{bob}},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Stats = %+v, want %+v", stats, want)
+	}
+
+	commits, err := Commits(a, "HEAD")
+	if err != nil {
+		t.Fatalf("Commits failed: %v", err)
+	}
+	byAuthor := map[string]CommitStats{}
+	for _, c := range commits {
+		c.Time = 0
+		byAuthor[c.Author] = c
+	}
+	wantCommits := map[string]CommitStats{
+		"alice": {Commit: alice, Author: "alice", Email: "alice@example.com", Summary: "alice", TotalLines: 1, CoveredLines: 1, Coverage: 100, Uncovered: []string{}},
+		"bob":   {Commit: bob, Author: "bob", Email: "bob@example.com", Summary: "bob", TotalLines: 2, CoveredLines: 1, Coverage: 50, Uncovered: []string{"a.go:6"}},
+	}
+	if !reflect.DeepEqual(byAuthor, wantCommits) {
+		t.Errorf("Commits = %+v, want %+v", byAuthor, wantCommits)
+	}
+}
+
+// TestSince keeps the lines changed after the cutoff and uncommitted ones.
+func TestSince(t *testing.T) {
+	dir := t.TempDir()
+	mustGit(t, dir, "init", "-q")
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {\n\tx()\n}\n"), 0644)
+	mustGit(t, dir, "add", "-A")
+	mustGit(t, dir, "-c", "user.name=old", "-c", "user.email=old@example.com", "commit", "-q", "-m", "old", "--date=2001-01-01T00:00:00Z")
+	commitAs(t, dir, "new", "package a\n\nfunc A() {\n\tx()\n\ty()\n}\n")
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {\n\tx()\n\ty()\n\tz()\n}\n"), 0644)
+
+	recent, err := Since(dir, []string{"a.go"}, time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Since failed: %v", err)
+	}
+	if want := map[int]bool{5: true, 6: true}; !reflect.DeepEqual(recent["a.go"], want) {
+		t.Errorf("Recent lines = %v, want %v", recent["a.go"], want)
+	}
+}
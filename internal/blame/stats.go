@@ -0,0 +1,173 @@
+package blame
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// AuthorStats is the coverage of the counted new/changed lines last changed
+// by one author.
+type AuthorStats struct {
+	Author       string   `json:"author"`
+	Email        string   `json:"email,omitempty"`
+	TotalLines   int      `json:"totalLines"`
+	CoveredLines int      `json:"coveredLines"`
+	Coverage     float64  `json:"coverage"`
+	Commits      []string `json:"commits"`
+}
+
+// Stats blames the counted new/changed lines of a at rev (the working tree if
+// empty) and aggregates their coverage per author, sorted by coverage and
+// then by the number of lines, both descending.
+func Stats(a *diffcoverage.Analysis, rev string) ([]AuthorStats, error) {
+	byAuthor := map[string]*AuthorStats{}
+	commits := map[string]map[string]bool{}
+	err := blameCounted(a, rev, func(_ string, _ int, l Line, covered bool) {
+		key := l.Email
+		if key == "" {
+			key = l.Author
+		}
+		s := byAuthor[key]
+		if s == nil {
+			s = &AuthorStats{Author: l.Author, Email: l.Email}
+			byAuthor[key] = s
+			commits[key] = map[string]bool{}
+		}
+		s.TotalLines++
+		if covered {
+			s.CoveredLines++
+		}
+		if !commits[key][l.Commit] {
+			commits[key][l.Commit] = true
+			s.Commits = append(s.Commits, l.Commit)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]AuthorStats, 0, len(byAuthor))
+	for _, s := range byAuthor {
+		s.Coverage = 100.0 * float64(s.CoveredLines) / float64(s.TotalLines)
+		sort.Strings(s.Commits)
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Coverage != stats[j].Coverage {
+			return stats[i].Coverage > stats[j].Coverage
+		}
+		if stats[i].TotalLines != stats[j].TotalLines {
+			return stats[i].TotalLines > stats[j].TotalLines
+		}
+		return stats[i].Author < stats[j].Author
+	})
+	return stats, nil
+}
+
+// CommitStats is the coverage of the counted new/changed lines last changed
+// by one commit. Uncovered lists its uncovered lines as "file:start-end".
+type CommitStats struct {
+	Commit       string   `json:"commit"`
+	Author       string   `json:"author"`
+	Email        string   `json:"email,omitempty"`
+	Time         int64    `json:"time"` // author time, Unix seconds
+	Summary      string   `json:"summary"`
+	TotalLines   int      `json:"totalLines"`
+	CoveredLines int      `json:"coveredLines"`
+	Coverage     float64  `json:"coverage"`
+	Uncovered    []string `json:"uncovered"`
+}
+
+// Commits is Stats per commit, sorted from the oldest commit; lines
+// that are not committed yet come last.
+func Commits(a *diffcoverage.Analysis, rev string) ([]CommitStats, error) {
+	byCommit := map[string]*CommitStats{}
+	uncovered := map[string]map[string][]int{}
+	err := blameCounted(a, rev, func(file string, line int, l Line, covered bool) {
+		c := byCommit[l.Commit]
+		if c == nil {
+			c = &CommitStats{Commit: l.Commit, Author: l.Author, Email: l.Email, Time: l.Time, Summary: l.Summary}
+			byCommit[l.Commit] = c
+			uncovered[l.Commit] = map[string][]int{}
+		}
+		c.TotalLines++
+		if covered {
+			c.CoveredLines++
+		} else {
+			uncovered[l.Commit][file] = append(uncovered[l.Commit][file], line)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]CommitStats, 0, len(byCommit))
+	for _, c := range byCommit {
+		c.Coverage = 100.0 * float64(c.CoveredLines) / float64(c.TotalLines)
+		c.Uncovered = []string{}
+		for file, lines := range uncovered[c.Commit] {
+			sort.Ints(lines)
+			for _, r := range diffcoverage.GroupLinesIntoRanges(lines) {
+				loc := fmt.Sprintf("%s:%d", file, r[0])
+				if r[1] != r[0] {
+					loc += fmt.Sprintf("-%d", r[1])
+				}
+				c.Uncovered = append(c.Uncovered, loc)
+			}
+		}
+		sort.Strings(c.Uncovered)
+		stats = append(stats, *c)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if ui, uj := uncommitted(stats[i].Commit), uncommitted(stats[j].Commit); ui != uj {
+			return uj
+		}
+		if stats[i].Time != stats[j].Time {
+			return stats[i].Time < stats[j].Time
+		}
+		return stats[i].Commit < stats[j].Commit
+	})
+	return stats, nil
+}
+
+// uncommitted reports whether commit is git blame's all-zero commit of lines
+// that are not committed yet.
+func uncommitted(commit string) bool {
+	return strings.Trim(commit, "0") == ""
+}
+
+// blameCounted blames the counted new/changed lines of a at rev and calls fn
+// for each line, with the file relative to the source root.
+func blameCounted(a *diffcoverage.Analysis, rev string, fn func(file string, line int, l Line, covered bool)) error {
+	files := make([]string, 0, len(a.Diff.NewLines))
+	for file := range a.Diff.NewLines {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		rel := a.RelPath(file)
+		var counted []int
+		for line := range a.Diff.NewLines[file] {
+			if a.Status(rel, line) != diffcoverage.LineIgnored {
+				counted = append(counted, line)
+			}
+		}
+		if len(counted) == 0 {
+			continue
+		}
+		sort.Ints(counted)
+		lines, err := FileAt(a.SourceRoot, rev, rel, diffcoverage.GroupLinesIntoRanges(counted))
+		if err != nil {
+			return fmt.Errorf("error blaming %s: %v", rel, err)
+		}
+		for _, n := range counted {
+			if l, ok := lines[n]; ok {
+				fn(rel, n, l, a.Status(rel, n) == diffcoverage.LineCovered)
+			}
+		}
+	}
+	return nil
+}
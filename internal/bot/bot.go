@@ -0,0 +1,502 @@
+// Package bot runs diffcoverage as a standalone service: it receives pull
+// request webhooks from code hosts, fetches the diff, the sources and the
+// coverage profile produced by CI, and publishes the report back.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+)
+
+// ErrNoProfile is returned by Source.Profile when CI has not produced the
+// coverage profile (yet); the pull request is analyzed on a later event.
+var ErrNoProfile = errors.New("no coverage profile found")
+
+// Event is a pull request revision to analyze.
+type Event struct {
+	Repo     string // owner/name, or the GitLab project path
+	PR       string
+	Branch   string // head branch, if known
+	HeadSHA  string
+	CloneURL string
+	// RunID identifies the CI run (GitHub) or job (GitLab) holding the
+	// coverage profile, if the event came from CI.
+	RunID string
+}
+
+func (e *Event) String() string {
+	return fmt.Sprintf("%s#%s@%.7s", e.Repo, e.PR, e.HeadSHA)
+}
+
+// Source is a code host.
+type Source interface {
+	// Event verifies a webhook delivery and returns the pull request to
+	// analyze, or nil if the event is not relevant.
+	Event(r *http.Request, body []byte) (*Event, error)
+	// Checkout fetches the head revision into dir.
+	Checkout(ctx context.Context, ev *Event, dir string) error
+	// Diff returns the unified diff of the pull request.
+	Diff(ctx context.Context, ev *Event) ([]byte, error)
+	// Profile writes the coverage profile of the head revision to the file
+	// dst; downloads go to files next to it.
+	Profile(ctx context.Context, ev *Event, dst string) error
+	// Reporters returns the integrations the report is published to.
+	Reporters(ctx context.Context, ev *Event) ([]reporter.Reporter, error)
+}
+
+// Analyzer computes the report of a checked-out revision.
+type Analyzer func(coverPath, diffPath, sourceRoot string) (*diffcoverage.Report, error)
+
+// Server receives webhooks on the paths of Sources and analyzes the pull
+// requests in Run, by default one at a time.
+type Server struct {
+	Sources map[string]Source // keyed by URL path, e.g. "/webhooks/github"
+	Analyze Analyzer
+	Workdir string // temporary directories are created here; os.TempDir if empty
+	// History, if set, records every analysis with its annotated diff, and
+	// Team, if set, the team of the checked-out repository.
+	History *history.Store
+	Team    func(sourceRoot string) string
+	Logf    func(format string, args ...any)
+	// Observe, if set, is called after every analysis with its result:
+	// published, skipped or error.
+	Observe func(result string, d time.Duration)
+
+	// Workers is the number of analyses run at once; 1 if 0.
+	Workers int
+	// QueueSize is the number of pending analyses before deliveries are
+	// rejected; DefaultQueueSize if 0.
+	QueueSize int
+	// MaxPendingPerRepo bounds the queued and running analyses of each
+	// repository, so that one repository cannot fill the queue; unlimited if 0.
+	MaxPendingPerRepo int
+	// Timeout bounds each analysis, including downloads and the checkout;
+	// unlimited if 0.
+	Timeout time.Duration
+
+	once    sync.Once
+	jobs    chan job
+	running atomic.Bool
+	mu      sync.Mutex
+	pending map[string]int // by repository
+}
+
+type job struct {
+	src Source
+	ev  *Event
+}
+
+// maxPayload bounds webhook bodies; GitHub caps them at 25 MB.
+const maxPayload = 25 << 20
+
+// DefaultMaxDownload bounds API responses, artifacts and the profiles in
+// them, unless the source sets MaxDownload.
+const DefaultMaxDownload = 128 << 20
+
+// DefaultQueueSize is the number of pending analyses before deliveries are
+// rejected, unless set in Server.QueueSize.
+const DefaultQueueSize = 64
+
+// ServeHTTP accepts a webhook delivery and queues its analysis.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	src, ok := s.Sources[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	ev, err := src.Event(r, body)
+	if err != nil {
+		s.logf("rejected delivery to %s: %v", r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ev == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if !s.acquire(ev.Repo) {
+		s.logf("rejected %s: too many pending analyses of %s", ev, ev.Repo)
+		http.Error(w, "too many pending analyses of "+ev.Repo, http.StatusTooManyRequests)
+		return
+	}
+	select {
+	case s.queue() <- job{src: src, ev: ev}:
+		s.logf("queued %s", ev)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		s.release(ev.Repo)
+		http.Error(w, "too many pending analyses", http.StatusServiceUnavailable)
+	}
+}
+
+// acquire counts a pending analysis of repo, unless it has MaxPendingPerRepo.
+func (s *Server) acquire(repo string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.MaxPendingPerRepo > 0 && s.pending[repo] >= s.MaxPendingPerRepo {
+		return false
+	}
+	if s.pending == nil {
+		s.pending = map[string]int{}
+	}
+	s.pending[repo]++
+	return true
+}
+
+// release uncounts a pending analysis of repo.
+func (s *Server) release(repo string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[repo]--; s.pending[repo] <= 0 {
+		delete(s.pending, repo)
+	}
+}
+
+// Run processes queued analyses with Workers workers until ctx is canceled.
+func (s *Server) Run(ctx context.Context) {
+	s.running.Store(true)
+	defer s.running.Store(false)
+	var wg sync.WaitGroup
+	for i := 0; i < max(s.Workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-s.queue():
+					s.run(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// run processes one queued analysis within Timeout.
+func (s *Server) run(ctx context.Context, j job) {
+	defer s.release(j.ev.Repo)
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	result := "published"
+	if err := s.Process(ctx, j.src, j.ev); errors.Is(err, ErrNoProfile) {
+		result = "skipped"
+		s.logf("skipped %s: %v", j.ev, err)
+	} else if err != nil {
+		result = "error"
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %v", s.Timeout, err)
+		}
+		s.logf("error analyzing %s: %v", j.ev, err)
+	} else {
+		s.logf("published %s", j.ev)
+	}
+	if s.Observe != nil {
+		s.Observe(result, time.Since(start))
+	}
+}
+
+// QueueDepth returns the number of queued analyses.
+func (s *Server) QueueDepth() int {
+	return len(s.queue())
+}
+
+// Ready returns an error unless Run is processing the queue and the queue
+// has room for another delivery.
+func (s *Server) Ready() error {
+	if !s.running.Load() {
+		return errors.New("not processing analyses")
+	}
+	if q := s.queue(); len(q) == cap(q) {
+		return errors.New("too many pending analyses")
+	}
+	return nil
+}
+
+// Process analyzes one pull request and publishes the report.
+func (s *Server) Process(ctx context.Context, src Source, ev *Event) error {
+	// Each analysis has its own directory, named after the repository.
+	dir, err := os.MkdirTemp(s.Workdir, "diffcoverage-bot-"+tempName(ev.Repo)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	coverPath, diffPath := filepath.Join(dir, "cover.out"), filepath.Join(dir, "diff.txt")
+	if err := src.Profile(ctx, ev, coverPath); err != nil {
+		return err
+	}
+	diff, err := src.Diff(ctx, ev)
+	if err != nil {
+		return fmt.Errorf("error fetching diff: %v", err)
+	}
+	if err := os.WriteFile(diffPath, diff, 0644); err != nil {
+		return err
+	}
+
+	root := filepath.Join(dir, "src")
+	if err := src.Checkout(ctx, ev, root); err != nil {
+		return fmt.Errorf("error checking out %s: %v", ev.HeadSHA, err)
+	}
+
+	r, err := s.Analyze(coverPath, diffPath, root)
+	if err != nil {
+		return err
+	}
+	if s.History != nil {
+		if err := s.record(ev, r, coverPath, diffPath, root); err != nil {
+			s.logf("error recording %s: %v", ev, err)
+		}
+	}
+	reporters, err := src.Reporters(ctx, ev)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, rep := range reporters {
+		if err := rep.Publish(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// record adds the analysis to the history, with the diff annotated for the
+// files of the report.
+func (s *Server) record(ev *Event, r *diffcoverage.Report, coverPath, diffPath, root string) error {
+	a, err := diffcoverage.Analyze(coverPath, diffPath, root)
+	if err != nil {
+		return err
+	}
+	reported := map[string]bool{}
+	for _, f := range r.Files {
+		reported[f.Path] = true
+	}
+	for file := range a.Diff.NewLines {
+		if !reported[a.RelPath(file)] {
+			delete(a.Diff.NewLines, file)
+		}
+	}
+	var diff strings.Builder
+	if err := diffcoverage.AnnotateDiff(&diff, diffPath, a); err != nil {
+		return err
+	}
+	var team string
+	if s.Team != nil {
+		team = s.Team(root)
+	}
+	return s.History.Add(&history.Record{
+		Time:   time.Now().UTC(),
+		Repo:   ev.Repo,
+		Team:   team,
+		Branch: ev.Branch,
+		Commit: ev.HeadSHA,
+		PR:     ev.PR,
+		Report: r,
+		Diff:   diff.String(),
+	})
+}
+
+func (s *Server) queue() chan job {
+	s.once.Do(func() {
+		size := s.QueueSize
+		if size == 0 {
+			size = DefaultQueueSize
+		}
+		s.jobs = make(chan job, size)
+	})
+	return s.jobs
+}
+
+// tempName makes repo usable in a temporary directory name.
+func tempName(repo string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' {
+			return r
+		}
+		return '-'
+	}, repo)
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// checkout fetches sha from cloneURL into dir. The authorization header is
+// passed through the environment so it never shows in process listings.
+func checkout(ctx context.Context, dir, cloneURL, sha, authorization string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if authorization != "" {
+		env = append(env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=Authorization: "+authorization)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"fetch", "-q", "--depth=1", "--", cloneURL, sha},
+		{"checkout", "-q", "FETCH_HEAD"},
+	} {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		cmd.Env = env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// checkCloneURL accepts https URLs of host without user info, so the token
+// the checkout sends goes nowhere else.
+func checkCloneURL(cloneURL, host string) error {
+	u, err := url.Parse(cloneURL)
+	if err != nil || u.Scheme != "https" || u.Host != host || u.User != nil {
+		return fmt.Errorf("clone URL %q is not an https URL of %s", cloneURL, host)
+	}
+	return nil
+}
+
+// get fetches url with the given headers.
+func get(ctx context.Context, client *http.Client, url string, header http.Header) ([]byte, error) {
+	body, err := open(ctx, client, url, header)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %v", url, err)
+	}
+	return data, nil
+}
+
+// download streams url, fetched with the given headers, to the file dst.
+func download(ctx context.Context, client *http.Client, url string, header http.Header, dst string) error {
+	body, err := open(ctx, client, url, header)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return fmt.Errorf("GET %s: %v", url, err)
+	}
+	return f.Close()
+}
+
+// open sends a GET request and returns the body of a successful response.
+func open(ctx context.Context, client *http.Client, url string, header http.Header) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET %s: unexpected status %s: %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}
+
+// limitClient returns a copy of client whose response bodies fail once they
+// exceed limit bytes, DefaultMaxDownload if 0.
+func limitClient(client *http.Client, limit int64) *http.Client {
+	if client == nil {
+		client = httpclient.Default
+	}
+	if limit == 0 {
+		limit = DefaultMaxDownload
+	}
+	c := *client
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := base.RoundTrip(req)
+		if err == nil {
+			resp.Body = &limitedBody{ReadCloser: resp.Body, r: newLimitedReader(resp.Body, limit)}
+		}
+		return resp, err
+	})
+	return &c
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// limitedBody reads a response body through a limitedReader.
+type limitedBody struct {
+	io.ReadCloser
+	r io.Reader
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) { return b.r.Read(p) }
+
+// limitedReader fails once more than limit bytes were read from r.
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func newLimitedReader(r io.Reader, limit int64) *limitedReader {
+	return &limitedReader{r: io.LimitReader(r, limit+1), limit: limit}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if l.read += int64(n); l.read > l.limit {
+		return n, fmt.Errorf("larger than the limit of %d bytes", l.limit)
+	}
+	return n, err
+}
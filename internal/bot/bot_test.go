@@ -0,0 +1,208 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+)
+
+// fakeSource serves a fixed event from a local repository.
+type fakeSource struct {
+	repo      string
+	sha       string
+	profile   error
+	published []*diffcoverage.Report
+}
+
+func (f *fakeSource) Event(r *http.Request, body []byte) (*Event, error) {
+	switch string(body) {
+	case "bad":
+		return nil, errors.New("invalid signature")
+	case "ignore":
+		return nil, nil
+	}
+	return &Event{Repo: "o/r", PR: "1", HeadSHA: f.sha, CloneURL: f.repo}, nil
+}
+
+func (f *fakeSource) Checkout(ctx context.Context, ev *Event, dir string) error {
+	return checkout(ctx, dir, ev.CloneURL, ev.HeadSHA, "")
+}
+
+func (f *fakeSource) Diff(ctx context.Context, ev *Event) ([]byte, error) {
+	return []byte("+++ b/a.go\n@@ -0,0 +3,3 @@\n+func A() {\n+\tprintln()\n+}\n"), nil
+}
+
+func (f *fakeSource) Profile(ctx context.Context, ev *Event, dst string) error {
+	if f.profile != nil {
+		return f.profile
+	}
+	return os.WriteFile(dst, []byte("mode: set\n"), 0644)
+}
+
+func (f *fakeSource) Reporters(ctx context.Context, ev *Event) ([]reporter.Reporter, error) {
+	return []reporter.Reporter{reporterFunc(func(ctx context.Context, r *diffcoverage.Report) error {
+		f.published = append(f.published, r)
+		return nil
+	})}, nil
+}
+
+type reporterFunc func(ctx context.Context, r *diffcoverage.Report) error
+
+func (f reporterFunc) Publish(ctx context.Context, r *diffcoverage.Report) error { return f(ctx, r) }
+
+// setupRepo commits a module and returns its path and head commit.
+func setupRepo(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"a.go":   "package m\n\nfunc A() {\n\tprintln()\n}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}, {"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "init"}} {
+		if _, err := gitutil.Run(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sha, err := gitutil.Run(dir, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, sha
+}
+
+// TestServer_ServeHTTP checks routing, rejected and ignored deliveries and queueing.
+func TestServer_ServeHTTP(t *testing.T) {
+	s := &Server{Sources: map[string]Source{"/webhooks/test": &fakeSource{}}}
+	cases := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/other", "", http.StatusNotFound},
+		{http.MethodGet, "/webhooks/test", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/webhooks/test", "bad", http.StatusBadRequest},
+		{http.MethodPost, "/webhooks/test", "ignore", http.StatusNoContent},
+		{http.MethodPost, "/webhooks/test", "{}", http.StatusAccepted},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+		if w.Code != c.want {
+			t.Errorf("%s %s %q: status %d, want %d", c.method, c.path, c.body, w.Code, c.want)
+		}
+	}
+	if len(s.queue()) != 1 {
+		t.Errorf("Expected one queued analysis, got %d", len(s.queue()))
+	}
+}
+
+// TestServer_Process checks out the head commit, analyzes it and publishes the report.
+func TestServer_Process(t *testing.T) {
+	repo, sha := setupRepo(t)
+	src := &fakeSource{repo: repo, sha: sha}
+	var gotRoot string
+	store := &history.Store{Path: filepath.Join(t.TempDir(), "history.jsonl")}
+	s := &Server{Workdir: t.TempDir(), History: store, Analyze: func(coverPath, diffPath, sourceRoot string) (*diffcoverage.Report, error) {
+		gotRoot = sourceRoot
+		if _, err := os.Stat(filepath.Join(sourceRoot, "a.go")); err != nil {
+			return nil, err
+		}
+		r := diffcoverage.NewReport(80)
+		r.Files = []diffcoverage.FileReport{{Path: "a.go", TotalLines: 1, Uncovered: [][2]int{{4, 4}}}}
+		return r, nil
+	}}
+
+	ev, _ := src.Event(nil, []byte("{}"))
+	if err := s.Process(context.Background(), src, ev); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(src.published) != 1 {
+		t.Errorf("Expected one published report, got %d", len(src.published))
+	}
+	if _, err := os.Stat(gotRoot); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkout to be removed, got %v", err)
+	}
+	rec, err := store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.PR != "1" || rec.Commit != sha || !strings.Contains(rec.Diff, "+\tprintln()"+diffcoverage.UncoveredMarker) {
+		t.Errorf("Unexpected history record %+v", rec)
+	}
+
+	src.profile = ErrNoProfile
+	if err := s.Process(context.Background(), src, ev); !errors.Is(err, ErrNoProfile) {
+		t.Errorf("Expected ErrNoProfile, got %v", err)
+	}
+}
+
+// TestServer_Ready reports readiness while Run processes the queue and
+// observes the result of each analysis.
+func TestServer_Ready(t *testing.T) {
+	results := make(chan string, 1)
+	s := &Server{
+		Sources: map[string]Source{"/webhooks/test": &fakeSource{profile: ErrNoProfile}},
+		Observe: func(result string, d time.Duration) { results <- result },
+	}
+	if s.Ready() == nil {
+		t.Errorf("Expected not ready before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	for deadline := time.Now().Add(5 * time.Second); s.Ready() != nil; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Not ready: %v", s.Ready())
+		}
+	}
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhooks/test", strings.NewReader("{}")))
+	if got := <-results; got != "skipped" {
+		t.Errorf("Observed %q, want skipped", got)
+	}
+
+	full := &Server{}
+	full.running.Store(true)
+	for i := 0; i < DefaultQueueSize; i++ {
+		full.queue() <- job{}
+	}
+	if err := full.Ready(); err == nil || full.QueueDepth() != DefaultQueueSize {
+		t.Errorf("Expected a full queue not to be ready, got %v with %d queued", err, full.QueueDepth())
+	}
+}
+
+// TestServer_MaxPendingPerRepo rejects deliveries of a repository with too
+// many pending analyses until one of them has run.
+func TestServer_MaxPendingPerRepo(t *testing.T) {
+	src := &fakeSource{profile: ErrNoProfile}
+	s := &Server{Sources: map[string]Source{"/webhooks/test": src}, MaxPendingPerRepo: 1, Timeout: time.Minute}
+	deliver := func() int {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhooks/test", strings.NewReader("{}")))
+		return w.Code
+	}
+	if code := deliver(); code != http.StatusAccepted {
+		t.Fatalf("First delivery: status %d", code)
+	}
+	if code := deliver(); code != http.StatusTooManyRequests {
+		t.Errorf("Second delivery: status %d, want %d", code, http.StatusTooManyRequests)
+	}
+	s.run(context.Background(), <-s.queue())
+	if code := deliver(); code != http.StatusAccepted {
+		t.Errorf("Delivery after the analysis: status %d", code)
+	}
+}
@@ -0,0 +1,289 @@
+package bot
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/prdiff"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+)
+
+// GitHub receives pull_request and workflow_run webhooks. The coverage
+// profile is the file ProfileName inside the Actions artifact Artifact of a
+// successful workflow run of the head commit.
+type GitHub struct {
+	APIURL      string // https://api.github.com if empty
+	Secret      string // webhook secret; required
+	Artifact    string
+	ProfileName string
+	// Token returns the API token for a repository, e.g. a GitHub App
+	// installation token.
+	Token  func(ctx context.Context, repo string) (string, error)
+	Client *http.Client
+	// MaxDownload bounds each API response, artifact and the profile in it,
+	// in bytes; DefaultMaxDownload if 0.
+	MaxDownload int64
+}
+
+type githubRepo struct {
+	FullName string `json:"full_name"`
+	CloneURL string `json:"clone_url"`
+}
+
+type githubPayload struct {
+	Action      string     `json:"action"`
+	Number      int        `json:"number"`
+	Repository  githubRepo `json:"repository"`
+	PullRequest struct {
+		Head struct {
+			Ref  string     `json:"ref"`
+			SHA  string     `json:"sha"`
+			Repo githubRepo `json:"repo"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	WorkflowRun struct {
+		ID             int64      `json:"id"`
+		HeadBranch     string     `json:"head_branch"`
+		HeadSHA        string     `json:"head_sha"`
+		Conclusion     string     `json:"conclusion"`
+		HeadRepository githubRepo `json:"head_repository"`
+		PullRequests   []struct {
+			Number int `json:"number"`
+		} `json:"pull_requests"`
+	} `json:"workflow_run"`
+}
+
+// Event handles opened, reopened and synchronized pull requests and
+// successfully completed workflow runs of pull requests. The clone URL must
+// be an https URL of the GitHub host, since the checkout sends it the token.
+func (g *GitHub) Event(r *http.Request, body []byte) (*Event, error) {
+	if g.Secret == "" || !reporter.Verify(body, g.Secret, r.Header.Get("X-Hub-Signature-256")) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	ev, err := g.event(r.Header.Get("X-GitHub-Event"), body)
+	if err != nil || ev == nil {
+		return nil, err
+	}
+	if err := g.checkCloneURL(ev.CloneURL); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// event parses the payload of a verified webhook.
+func (g *GitHub) event(name string, body []byte) (*Event, error) {
+	var p githubPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+
+	switch name {
+	case "pull_request":
+		if p.Action != "opened" && p.Action != "reopened" && p.Action != "synchronize" {
+			return nil, nil
+		}
+		return &Event{
+			Repo:     p.Repository.FullName,
+			PR:       strconv.Itoa(p.Number),
+			Branch:   p.PullRequest.Head.Ref,
+			HeadSHA:  p.PullRequest.Head.SHA,
+			CloneURL: p.PullRequest.Head.Repo.CloneURL,
+		}, nil
+	case "workflow_run":
+		run := p.WorkflowRun
+		if p.Action != "completed" || run.Conclusion != "success" || len(run.PullRequests) == 0 {
+			return nil, nil
+		}
+		return &Event{
+			Repo:     p.Repository.FullName,
+			PR:       strconv.Itoa(run.PullRequests[0].Number),
+			Branch:   run.HeadBranch,
+			HeadSHA:  run.HeadSHA,
+			CloneURL: run.HeadRepository.CloneURL,
+			RunID:    strconv.FormatInt(run.ID, 10),
+		}, nil
+	}
+	return nil, nil
+}
+
+// checkCloneURL accepts https URLs of the GitHub host only: github.com for
+// the public API, the API host for GitHub Enterprise Server.
+func (g *GitHub) checkCloneURL(cloneURL string) error {
+	api, err := url.Parse(g.APIURL)
+	if g.APIURL == "" {
+		api, err = url.Parse("https://api.github.com")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid API URL %q: %v", g.APIURL, err)
+	}
+	host := api.Host
+	if strings.Trim(api.Path, "/") == "" {
+		host = strings.TrimPrefix(host, "api.")
+	}
+	return checkCloneURL(cloneURL, host)
+}
+
+// Checkout fetches the head commit, authenticated as x-access-token.
+func (g *GitHub) Checkout(ctx context.Context, ev *Event, dir string) error {
+	token, err := g.Token(ctx, ev.Repo)
+	if err != nil {
+		return err
+	}
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("x-access-token:"+token))
+	return checkout(ctx, dir, ev.CloneURL, ev.HeadSHA, auth)
+}
+
+// Diff returns the pull request diff.
+func (g *GitHub) Diff(ctx context.Context, ev *Event) ([]byte, error) {
+	token, err := g.Token(ctx, ev.Repo)
+	if err != nil {
+		return nil, err
+	}
+	return prdiff.GitHub(ctx, limitClient(g.Client, g.MaxDownload), g.APIURL, ev.Repo, ev.PR, token)
+}
+
+// Profile downloads the artifact of the event's workflow run, or of the
+// newest successful run of the head commit that has one, and extracts the
+// profile from it.
+func (g *GitHub) Profile(ctx context.Context, ev *Event, dst string) error {
+	runIDs := []string{ev.RunID}
+	if ev.RunID == "" {
+		data, err := g.get(ctx, ev.Repo, fmt.Sprintf("/repos/%s/actions/runs?head_sha=%s&status=success", ev.Repo, ev.HeadSHA), "")
+		if err != nil {
+			return err
+		}
+		var runs struct {
+			WorkflowRuns []struct {
+				ID int64 `json:"id"`
+			} `json:"workflow_runs"`
+		}
+		if err := json.Unmarshal(data, &runs); err != nil {
+			return err
+		}
+		runIDs = runIDs[:0]
+		for _, run := range runs.WorkflowRuns {
+			runIDs = append(runIDs, strconv.FormatInt(run.ID, 10))
+		}
+	}
+
+	for _, id := range runIDs {
+		data, err := g.get(ctx, ev.Repo, fmt.Sprintf("/repos/%s/actions/runs/%s/artifacts", ev.Repo, id), "")
+		if err != nil {
+			return err
+		}
+		var list struct {
+			Artifacts []struct {
+				Name        string `json:"name"`
+				Expired     bool   `json:"expired"`
+				DownloadURL string `json:"archive_download_url"`
+			} `json:"artifacts"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		for _, a := range list.Artifacts {
+			if a.Name != g.Artifact || a.Expired {
+				continue
+			}
+			archive := dst + ".zip"
+			if err := g.download(ctx, ev.Repo, a.DownloadURL, archive); err != nil {
+				return err
+			}
+			defer os.Remove(archive)
+			return extractFromZip(archive, g.ProfileName, dst, g.MaxDownload)
+		}
+	}
+	return fmt.Errorf("%w: no artifact %q in the successful workflow runs of %s", ErrNoProfile, g.Artifact, ev.HeadSHA)
+}
+
+// Reporters posts the sticky comment and the commit status.
+func (g *GitHub) Reporters(ctx context.Context, ev *Event) ([]reporter.Reporter, error) {
+	token, err := g.Token(ctx, ev.Repo)
+	if err != nil {
+		return nil, err
+	}
+	return []reporter.Reporter{
+		&reporter.GitHub{APIURL: g.APIURL, Repo: ev.Repo, PR: ev.PR, Token: token, Client: g.Client},
+		&reporter.CommitStatus{Provider: reporter.StatusGitHub, APIURL: g.APIURL, Repo: ev.Repo, Commit: ev.HeadSHA, Token: token, Client: g.Client},
+	}, nil
+}
+
+// get fetches an API path or absolute URL.
+func (g *GitHub) get(ctx context.Context, repo, url, accept string) ([]byte, error) {
+	url, header, err := g.request(ctx, repo, url, accept)
+	if err != nil {
+		return nil, err
+	}
+	return get(ctx, limitClient(g.Client, g.MaxDownload), url, header)
+}
+
+// download streams an absolute URL to the file dst.
+func (g *GitHub) download(ctx context.Context, repo, url, dst string) error {
+	url, header, err := g.request(ctx, repo, url, "")
+	if err != nil {
+		return err
+	}
+	return download(ctx, limitClient(g.Client, g.MaxDownload), url, header, dst)
+}
+
+// request returns the URL of an API path or absolute URL and its headers.
+func (g *GitHub) request(ctx context.Context, repo, url, accept string) (string, http.Header, error) {
+	token, err := g.Token(ctx, repo)
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasPrefix(url, "http") {
+		base := strings.TrimSuffix(g.APIURL, "/")
+		if base == "" {
+			base = "https://api.github.com"
+		}
+		url = base + url
+	}
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+	return url, http.Header{"Authorization": {"Bearer " + token}, "Accept": {accept}}, nil
+}
+
+// extractFromZip writes the file named name, at any depth, in the zip
+// archive to dst, failing if it exceeds limit bytes (DefaultMaxDownload if 0).
+func extractFromZip(archive, name, dst string, limit int64) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("error reading artifact: %v", err)
+	}
+	defer zr.Close()
+	if limit == 0 {
+		limit = DefaultMaxDownload
+	}
+	for _, f := range zr.File {
+		if path.Base(f.Name) != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		out, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, newLimitedReader(rc, limit)); err != nil {
+			out.Close()
+			return fmt.Errorf("error reading %s from the artifact: %v", f.Name, err)
+		}
+		return out.Close()
+	}
+	return fmt.Errorf("%w: the artifact has no file %s", ErrNoProfile, name)
+}
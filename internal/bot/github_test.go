@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+)
+
+func staticToken(ctx context.Context, repo string) (string, error) { return "t0ken", nil }
+
+// TestGitHub_Event verifies the signature and keeps only relevant events.
+func TestGitHub_Event(t *testing.T) {
+	g := &GitHub{Secret: "s3cret", Token: staticToken}
+	cases := []struct {
+		name, event, body string
+		want              *Event
+	}{
+		{"opened", "pull_request", `{"action":"opened","number":7,"repository":{"full_name":"o/r"},"pull_request":{"head":{"ref":"feature","sha":"abc","repo":{"clone_url":"https://github.com/o/r.git"}}}}`,
+			&Event{Repo: "o/r", PR: "7", Branch: "feature", HeadSHA: "abc", CloneURL: "https://github.com/o/r.git"}},
+		{"closed", "pull_request", `{"action":"closed","number":7}`, nil},
+		{"run", "workflow_run", `{"action":"completed","repository":{"full_name":"o/r"},"workflow_run":{"id":42,"head_sha":"abc","conclusion":"success","head_repository":{"clone_url":"https://github.com/f/r.git"},"pull_requests":[{"number":7}]}}`,
+			&Event{Repo: "o/r", PR: "7", HeadSHA: "abc", CloneURL: "https://github.com/f/r.git", RunID: "42"}},
+		{"failed run", "workflow_run", `{"action":"completed","workflow_run":{"conclusion":"failure","pull_requests":[{"number":7}]}}`, nil},
+		{"push", "push", `{}`, nil},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("X-GitHub-Event", c.event)
+		r.Header.Set("X-Hub-Signature-256", reporter.Sign([]byte(c.body), "s3cret"))
+		ev, err := g.Event(r, []byte(c.body))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if (ev == nil) != (c.want == nil) || (ev != nil && *ev != *c.want) {
+			t.Errorf("%s: got %+v, want %+v", c.name, ev, c.want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("X-GitHub-Event", "pull_request")
+	r.Header.Set("X-Hub-Signature-256", reporter.Sign([]byte("{}"), "wrong"))
+	if _, err := g.Event(r, []byte("{}")); err == nil {
+		t.Error("Expected an error for an invalid signature")
+	}
+	if _, err := (&GitHub{Token: staticToken}).Event(r, []byte("{}")); err == nil {
+		t.Error("Expected an error without a webhook secret")
+	}
+}
+
+// TestGitHub_Event_CloneURL rejects clone URLs the token must not be sent to.
+func TestGitHub_Event_CloneURL(t *testing.T) {
+	cases := []struct {
+		apiURL, cloneURL string
+		ok               bool
+	}{
+		{"", "https://github.com/o/r.git", true},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/o/r.git", true},
+		{"", "https://evil.example.com/o/r.git", false},
+		{"", "http://github.com/o/r.git", false},
+		{"", "--upload-pack=touch /tmp/x", false},
+		{"", "https://user@github.com/o/r.git", false},
+	}
+	for _, c := range cases {
+		g := &GitHub{APIURL: c.apiURL, Secret: "s3cret", Token: staticToken}
+		body := fmt.Sprintf(`{"action":"opened","number":7,"repository":{"full_name":"o/r"},"pull_request":{"head":{"sha":"abc","repo":{"clone_url":%q}}}}`, c.cloneURL)
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("X-GitHub-Event", "pull_request")
+		r.Header.Set("X-Hub-Signature-256", reporter.Sign([]byte(body), "s3cret"))
+		if _, err := g.Event(r, []byte(body)); (err == nil) != c.ok {
+			t.Errorf("%s with API %q: error %v, want ok %v", c.cloneURL, c.apiURL, err, c.ok)
+		}
+	}
+}
+
+// TestGitHub_Profile finds the artifact of a successful run of the head commit.
+func TestGitHub_Profile(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, _ := zw.Create("out/cover.out")
+	w.Write([]byte("mode: set\n"))
+	zw.Close()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/repos/o/r/actions/runs":
+			fmt.Fprint(w, `{"workflow_runs":[{"id":1},{"id":2}]}`)
+		case "/repos/o/r/actions/runs/1/artifacts":
+			fmt.Fprint(w, `{"artifacts":[{"name":"other","archive_download_url":"x"}]}`)
+		case "/repos/o/r/actions/runs/2/artifacts":
+			fmt.Fprintf(w, `{"artifacts":[{"name":"coverage","archive_download_url":"%s/download"}]}`, srv.URL)
+		case "/download":
+			w.Write(archive.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "cover.out")
+	g := &GitHub{APIURL: srv.URL, Artifact: "coverage", ProfileName: "cover.out", Token: staticToken}
+	if err := g.Profile(context.Background(), &Event{Repo: "o/r", HeadSHA: "abc"}, dst); err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "mode: set\n" {
+		t.Errorf("Unexpected profile %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the profile to be left, got %v", entries)
+	}
+
+	g.MaxDownload = 16
+	if err := g.Profile(context.Background(), &Event{Repo: "o/r", HeadSHA: "abc"}, dst); err == nil || !strings.Contains(err.Error(), "limit of 16 bytes") {
+		t.Errorf("Expected the download limit to be exceeded, got %v", err)
+	}
+
+	g.MaxDownload = 0
+	g.Artifact = "missing"
+	if err := g.Profile(context.Background(), &Event{Repo: "o/r", HeadSHA: "abc"}, dst); !errors.Is(err, ErrNoProfile) {
+		t.Errorf("Expected ErrNoProfile, got %v", err)
+	}
+}
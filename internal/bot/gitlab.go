@@ -0,0 +1,221 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/prdiff"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+)
+
+// GitLab receives merge request and pipeline webhooks. The coverage profile
+// is the artifact file ProfileName of the job named Job in a successful pipeline
+// of the head commit.
+type GitLab struct {
+	APIURL      string // https://gitlab.com/api/v4 if empty
+	Secret      string // webhook secret token; required
+	Job         string
+	ProfileName string
+	Token       string
+	Client      *http.Client
+	// MaxDownload bounds each API response and the profile, in bytes;
+	// DefaultMaxDownload if 0.
+	MaxDownload int64
+}
+
+type gitlabPayload struct {
+	ObjectKind string `json:"object_kind"`
+	Project    struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		GitHTTPURL        string `json:"git_http_url"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		Action       string `json:"action"`
+		OldRev       string `json:"oldrev"`
+		SourceBranch string `json:"source_branch"`
+		LastCommit   struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+		// Pipeline events.
+		SHA    string `json:"sha"`
+		Ref    string `json:"ref"`
+		Status string `json:"status"`
+	} `json:"object_attributes"`
+	MergeRequest *struct {
+		IID int `json:"iid"`
+	} `json:"merge_request"`
+	Builds []struct {
+		ID     int64  `json:"id"`
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	} `json:"builds"`
+}
+
+// Event handles opened, reopened and pushed-to merge requests and successful
+// merge request pipelines. The clone URL must be an https URL of the GitLab
+// host, since the checkout sends it the token.
+func (g *GitLab) Event(r *http.Request, body []byte) (*Event, error) {
+	if g.Secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(g.Secret)) != 1 {
+		return nil, fmt.Errorf("invalid token")
+	}
+	ev, err := g.event(body)
+	if err != nil || ev == nil {
+		return nil, err
+	}
+	if err := g.checkCloneURL(ev.CloneURL); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// event parses the payload of a verified webhook.
+func (g *GitLab) event(body []byte) (*Event, error) {
+	var p gitlabPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+	attrs := p.ObjectAttributes
+
+	switch p.ObjectKind {
+	case "merge_request":
+		// Updates without oldrev change the title or labels, not the code.
+		if attrs.Action != "open" && attrs.Action != "reopen" && (attrs.Action != "update" || attrs.OldRev == "") {
+			return nil, nil
+		}
+		return &Event{
+			Repo:     p.Project.PathWithNamespace,
+			PR:       strconv.Itoa(attrs.IID),
+			Branch:   attrs.SourceBranch,
+			HeadSHA:  attrs.LastCommit.ID,
+			CloneURL: p.Project.GitHTTPURL,
+		}, nil
+	case "pipeline":
+		if attrs.Status != "success" || p.MergeRequest == nil {
+			return nil, nil
+		}
+		ev := &Event{
+			Repo:     p.Project.PathWithNamespace,
+			PR:       strconv.Itoa(p.MergeRequest.IID),
+			Branch:   attrs.Ref,
+			HeadSHA:  attrs.SHA,
+			CloneURL: p.Project.GitHTTPURL,
+		}
+		for _, b := range p.Builds {
+			if b.Name == g.Job && b.Status == "success" {
+				ev.RunID = strconv.FormatInt(b.ID, 10)
+			}
+		}
+		if ev.RunID == "" {
+			return nil, nil
+		}
+		return ev, nil
+	}
+	return nil, nil
+}
+
+// checkCloneURL accepts https URLs of the host of the API URL only.
+func (g *GitLab) checkCloneURL(cloneURL string) error {
+	api, err := url.Parse(g.APIURL)
+	if g.APIURL == "" {
+		api, err = url.Parse("https://gitlab.com/api/v4")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid API URL %q: %v", g.APIURL, err)
+	}
+	return checkCloneURL(cloneURL, api.Host)
+}
+
+// Checkout fetches the head commit, authenticated as oauth2.
+func (g *GitLab) Checkout(ctx context.Context, ev *Event, dir string) error {
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("oauth2:"+g.Token))
+	return checkout(ctx, dir, ev.CloneURL, ev.HeadSHA, auth)
+}
+
+// Diff assembles a unified diff from the merge request's file diffs.
+func (g *GitLab) Diff(ctx context.Context, ev *Event) ([]byte, error) {
+	return prdiff.GitLab(ctx, limitClient(g.Client, g.MaxDownload), g.APIURL, ev.Repo, ev.PR, g.Token)
+}
+
+// Profile downloads the artifact file of the event's job, or of the job of
+// the newest successful merge request pipeline of the head commit.
+func (g *GitLab) Profile(ctx context.Context, ev *Event, dst string) error {
+	jobID := ev.RunID
+	if jobID == "" {
+		data, err := g.get(ctx, fmt.Sprintf("%s/merge_requests/%s/pipelines", g.project(ev), ev.PR))
+		if err != nil {
+			return err
+		}
+		var pipelines []struct {
+			ID     int64  `json:"id"`
+			SHA    string `json:"sha"`
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(data, &pipelines); err != nil {
+			return err
+		}
+		for _, p := range pipelines {
+			if p.SHA != ev.HeadSHA || p.Status != "success" {
+				continue
+			}
+			data, err := g.get(ctx, fmt.Sprintf("%s/pipelines/%d/jobs?scope[]=success", g.project(ev), p.ID))
+			if err != nil {
+				return err
+			}
+			var jobs []struct {
+				ID   int64  `json:"id"`
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(data, &jobs); err != nil {
+				return err
+			}
+			for _, j := range jobs {
+				if j.Name == g.Job {
+					jobID = strconv.FormatInt(j.ID, 10)
+				}
+			}
+			break
+		}
+		if jobID == "" {
+			return fmt.Errorf("%w: no successful %s job in a pipeline of %s", ErrNoProfile, g.Job, ev.HeadSHA)
+		}
+	}
+
+	err := download(ctx, limitClient(g.Client, g.MaxDownload), fmt.Sprintf("%s/jobs/%s/artifacts/%s", g.project(ev), jobID, g.ProfileName), g.header(), dst)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNoProfile, err)
+	}
+	return nil
+}
+
+// Reporters posts the sticky note and the commit status.
+func (g *GitLab) Reporters(ctx context.Context, ev *Event) ([]reporter.Reporter, error) {
+	return []reporter.Reporter{
+		&reporter.GitLab{APIURL: g.APIURL, Project: ev.Repo, MR: ev.PR, Token: g.Token, Client: g.Client},
+		&reporter.CommitStatus{Provider: reporter.StatusGitLab, APIURL: g.APIURL, Repo: ev.Repo, Commit: ev.HeadSHA, Token: g.Token, Client: g.Client},
+	}, nil
+}
+
+// project returns the API URL of the event's project.
+func (g *GitLab) project(ev *Event) string {
+	base := strings.TrimSuffix(g.APIURL, "/")
+	if base == "" {
+		base = "https://gitlab.com/api/v4"
+	}
+	return base + "/projects/" + url.PathEscape(ev.Repo)
+}
+
+func (g *GitLab) get(ctx context.Context, endpoint string) ([]byte, error) {
+	return get(ctx, limitClient(g.Client, g.MaxDownload), endpoint, g.header())
+}
+
+func (g *GitLab) header() http.Header {
+	return http.Header{"Private-Token": {g.Token}}
+}
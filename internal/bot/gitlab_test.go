@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGitLab_Event checks the secret token and keeps only code changes.
+func TestGitLab_Event(t *testing.T) {
+	g := &GitLab{Secret: "s3cret", Job: "test"}
+	cases := []struct {
+		name, body string
+		want       *Event
+	}{
+		{"open", `{"object_kind":"merge_request","project":{"path_with_namespace":"g/p","git_http_url":"https://gitlab.com/g/p.git"},"object_attributes":{"iid":3,"action":"open","last_commit":{"id":"abc"}}}`,
+			&Event{Repo: "g/p", PR: "3", HeadSHA: "abc", CloneURL: "https://gitlab.com/g/p.git"}},
+		{"title edit", `{"object_kind":"merge_request","object_attributes":{"iid":3,"action":"update"}}`, nil},
+		{"pipeline", `{"object_kind":"pipeline","project":{"path_with_namespace":"g/p","git_http_url":"https://gitlab.com/g/p.git"},"object_attributes":{"sha":"abc","status":"success"},"merge_request":{"iid":3},"builds":[{"id":9,"name":"test","status":"success"},{"id":10,"name":"lint","status":"success"}]}`,
+			&Event{Repo: "g/p", PR: "3", HeadSHA: "abc", CloneURL: "https://gitlab.com/g/p.git", RunID: "9"}},
+		{"branch pipeline", `{"object_kind":"pipeline","object_attributes":{"sha":"abc","status":"success"},"builds":[{"id":9,"name":"test","status":"success"}]}`, nil},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("X-Gitlab-Token", "s3cret")
+		ev, err := g.Event(r, []byte(c.body))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if (ev == nil) != (c.want == nil) || (ev != nil && *ev != *c.want) {
+			t.Errorf("%s: got %+v, want %+v", c.name, ev, c.want)
+		}
+	}
+
+	if _, err := g.Event(httptest.NewRequest(http.MethodPost, "/", nil), []byte("{}")); err == nil {
+		t.Error("Expected an error without the secret token")
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	if _, err := (&GitLab{Job: "test"}).Event(r, []byte("{}")); err == nil {
+		t.Error("Expected an error without a webhook secret")
+	}
+}
+
+// TestGitLab_Event_CloneURL rejects clone URLs the token must not be sent to.
+func TestGitLab_Event_CloneURL(t *testing.T) {
+	cases := []struct {
+		apiURL, cloneURL string
+		ok               bool
+	}{
+		{"", "https://gitlab.com/g/p.git", true},
+		{"https://git.example.com/api/v4", "https://git.example.com/g/p.git", true},
+		{"https://git.example.com/api/v4", "https://gitlab.com/g/p.git", false},
+		{"", "https://evil.example.com/g/p.git", false},
+		{"", "http://gitlab.com/g/p.git", false},
+		{"", "--upload-pack=touch /tmp/x", false},
+		{"", "https://user@gitlab.com/g/p.git", false},
+	}
+	for _, c := range cases {
+		g := &GitLab{APIURL: c.apiURL, Secret: "s3cret"}
+		body := fmt.Sprintf(`{"object_kind":"merge_request","project":{"path_with_namespace":"g/p","git_http_url":%q},"object_attributes":{"iid":3,"action":"open","last_commit":{"id":"abc"}}}`, c.cloneURL)
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("X-Gitlab-Token", "s3cret")
+		if _, err := g.Event(r, []byte(body)); (err == nil) != c.ok {
+			t.Errorf("%s with API %q: error %v, want ok %v", c.cloneURL, c.apiURL, err, c.ok)
+		}
+	}
+}
+
+// TestGitLab_DiffAndProfile assembles the diff, with new files from
+// /dev/null, and finds the job artifact.
+func TestGitLab_DiffAndProfile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Private-Token") != "t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/projects/g%2Fp/merge_requests/3/diffs":
+			fmt.Fprint(w, `[{"old_path":"a.go","new_path":"a.go","diff":"@@ -1 +1 @@\n-x\n+y"},{"old_path":"b.go","new_path":"b.go","new_file":true,"diff":"@@ -0,0 +1 @@\n+z\n"}]`)
+		case "/projects/g%2Fp/merge_requests/3/pipelines":
+			fmt.Fprint(w, `[{"id":5,"sha":"abc","status":"success"}]`)
+		case "/projects/g%2Fp/pipelines/5/jobs":
+			fmt.Fprint(w, `[{"id":9,"name":"test"}]`)
+		case "/projects/g%2Fp/jobs/9/artifacts/cover.out":
+			fmt.Fprint(w, "mode: set\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := &GitLab{APIURL: srv.URL, Job: "test", ProfileName: "cover.out", Token: "t0ken"}
+	ev := &Event{Repo: "g/p", PR: "3", HeadSHA: "abc"}
+	diff, err := g.Diff(context.Background(), ev)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	want := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n" +
+		"diff --git a/b.go b/b.go\n--- /dev/null\n+++ b/b.go\n@@ -0,0 +1 @@\n+z\n"
+	if string(diff) != want {
+		t.Errorf("Diff = %q, want %q", diff, want)
+	}
+
+	dst := filepath.Join(t.TempDir(), "cover.out")
+	if err := g.Profile(context.Background(), ev, dst); err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "mode: set\n" {
+		t.Errorf("Unexpected profile %q", data)
+	}
+}
@@ -0,0 +1,178 @@
+package ci
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Provider names returned in Env.Provider.
+const (
+	GitHubActions = "github-actions"
+	GitLabCI      = "gitlab-ci"
+	CircleCI      = "circleci"
+	Buildkite     = "buildkite"
+	Jenkins       = "jenkins"
+	Bitbucket     = "bitbucket-pipelines"
+)
+
+// Env is the build context detected from CI environment variables.
+type Env struct {
+	Provider   string
+	Repo       string // owner/name (or group/project on GitLab)
+	Branch     string // branch being built (the source branch of a pull/merge request)
+	BaseBranch string // target branch of the pull/merge request, if any
+	PRNumber   string // pull/merge request number, if any
+	CommitSHA  string
+	BaseSHA    string // commit to diff against, if known: the pull request base or the commit before a push
+	Token      string // API token found in the environment; never print it
+	APIURL     string // provider API base URL, if known
+}
+
+// BaseRef returns the ref to diff against, e.g. "origin/main", or "" if unknown.
+func (e *Env) BaseRef() string {
+	if e.BaseBranch == "" {
+		return ""
+	}
+	return "origin/" + e.BaseBranch
+}
+
+// TargetBranch returns the branch the change lands on: the base branch of a
+// pull/merge request, or the branch being built.
+func (e *Env) TargetBranch() string {
+	return firstNonEmpty(e.BaseBranch, e.Branch)
+}
+
+// String summarizes the environment without exposing the token.
+func (e *Env) String() string {
+	parts := []string{e.Provider}
+	if e.Repo != "" {
+		parts = append(parts, "repo "+e.Repo)
+	}
+	if e.PRNumber != "" {
+		parts = append(parts, "PR #"+e.PRNumber)
+	}
+	if e.Branch != "" {
+		parts = append(parts, "branch "+e.Branch)
+	}
+	if e.BaseBranch != "" {
+		parts = append(parts, "base "+e.BaseBranch)
+	}
+	if e.CommitSHA != "" {
+		parts = append(parts, "commit "+e.CommitSHA)
+	}
+	if e.BaseSHA != "" {
+		parts = append(parts, "base commit "+e.BaseSHA)
+	}
+	if e.Token != "" {
+		parts = append(parts, "token set")
+	}
+	return strings.Join(parts, ", ")
+}
+
+var (
+	pullRefRegex = regexp.MustCompile(`^refs/pull/(\d+)/`)
+	pullURLRegex = regexp.MustCompile(`/pull/(\d+)$`)
+	repoURLRegex = regexp.MustCompile(`[:/]([^/:]+/[^/]+?)(?:\.git)?/?$`)
+)
+
+// Detect identifies the CI system from environment variables read through getenv
+// (usually os.Getenv). It returns nil outside a supported CI system.
+func Detect(getenv func(string) string) *Env {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		env := &Env{
+			Provider:   GitHubActions,
+			Repo:       getenv("GITHUB_REPOSITORY"),
+			Branch:     firstNonEmpty(getenv("GITHUB_HEAD_REF"), getenv("GITHUB_REF_NAME")),
+			BaseBranch: getenv("GITHUB_BASE_REF"),
+			CommitSHA:  getenv("GITHUB_SHA"),
+			Token:      getenv("GITHUB_TOKEN"),
+			APIURL:     firstNonEmpty(getenv("GITHUB_API_URL"), "https://api.github.com"),
+		}
+		if m := pullRefRegex.FindStringSubmatch(getenv("GITHUB_REF")); m != nil {
+			env.PRNumber = m[1]
+		}
+		if path := getenv("GITHUB_EVENT_PATH"); path != "" {
+			applyGitHubEvent(env, path)
+		}
+		return env
+	case getenv("GITLAB_CI") == "true":
+		return &Env{
+			Provider:   GitLabCI,
+			Repo:       getenv("CI_PROJECT_PATH"),
+			Branch:     firstNonEmpty(getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"), getenv("CI_COMMIT_REF_NAME")),
+			BaseBranch: getenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME"),
+			PRNumber:   getenv("CI_MERGE_REQUEST_IID"),
+			CommitSHA:  getenv("CI_COMMIT_SHA"),
+			Token:      firstNonEmpty(getenv("GITLAB_TOKEN"), getenv("CI_JOB_TOKEN")),
+			APIURL:     getenv("CI_API_V4_URL"),
+		}
+	case getenv("CIRCLECI") == "true":
+		env := &Env{
+			Provider:  CircleCI,
+			Branch:    getenv("CIRCLE_BRANCH"),
+			PRNumber:  getenv("CIRCLE_PR_NUMBER"),
+			CommitSHA: getenv("CIRCLE_SHA1"),
+			Token:     getenv("GITHUB_TOKEN"),
+		}
+		if owner, name := getenv("CIRCLE_PROJECT_USERNAME"), getenv("CIRCLE_PROJECT_REPONAME"); owner != "" && name != "" {
+			env.Repo = owner + "/" + name
+		}
+		if m := pullURLRegex.FindStringSubmatch(getenv("CIRCLE_PULL_REQUEST")); m != nil && env.PRNumber == "" {
+			env.PRNumber = m[1]
+		}
+		return env
+	case getenv("BUILDKITE") == "true":
+		env := &Env{
+			Provider:   Buildkite,
+			Repo:       repoFromURL(getenv("BUILDKITE_REPO")),
+			Branch:     getenv("BUILDKITE_BRANCH"),
+			BaseBranch: getenv("BUILDKITE_PULL_REQUEST_BASE_BRANCH"),
+			CommitSHA:  getenv("BUILDKITE_COMMIT"),
+			Token:      getenv("GITHUB_TOKEN"),
+		}
+		if pr := getenv("BUILDKITE_PULL_REQUEST"); pr != "" && pr != "false" {
+			env.PRNumber = pr
+		}
+		return env
+	case getenv("BITBUCKET_BUILD_NUMBER") != "":
+		return &Env{
+			Provider:   Bitbucket,
+			Repo:       getenv("BITBUCKET_REPO_FULL_NAME"),
+			Branch:     getenv("BITBUCKET_BRANCH"),
+			BaseBranch: getenv("BITBUCKET_PR_DESTINATION_BRANCH"),
+			PRNumber:   getenv("BITBUCKET_PR_ID"),
+			CommitSHA:  getenv("BITBUCKET_COMMIT"),
+			Token:      getenv("BITBUCKET_TOKEN"),
+			APIURL:     "https://api.bitbucket.org/2.0",
+		}
+	case getenv("JENKINS_URL") != "":
+		return &Env{
+			Provider:   Jenkins,
+			Repo:       repoFromURL(getenv("GIT_URL")),
+			Branch:     firstNonEmpty(getenv("CHANGE_BRANCH"), getenv("BRANCH_NAME"), getenv("GIT_BRANCH")),
+			BaseBranch: getenv("CHANGE_TARGET"),
+			PRNumber:   getenv("CHANGE_ID"),
+			CommitSHA:  getenv("GIT_COMMIT"),
+			Token:      getenv("GITHUB_TOKEN"),
+		}
+	}
+	return nil
+}
+
+// repoFromURL extracts owner/name from an https or ssh git remote URL.
+func repoFromURL(url string) string {
+	if m := repoURLRegex.FindStringSubmatch(url); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
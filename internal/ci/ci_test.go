@@ -0,0 +1,134 @@
+package ci
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func envFrom(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+// TestDetect covers every supported provider.
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		want Env
+	}{
+		{"github pull request", map[string]string{
+			"GITHUB_ACTIONS": "true", "GITHUB_REPOSITORY": "octo/repo", "GITHUB_BASE_REF": "main",
+			"GITHUB_REF": "refs/pull/42/merge", "GITHUB_SHA": "abc", "GITHUB_TOKEN": "secret",
+			"GITHUB_HEAD_REF": "feature", "GITHUB_REF_NAME": "42/merge",
+		}, Env{Provider: GitHubActions, Repo: "octo/repo", Branch: "feature", BaseBranch: "main", PRNumber: "42", CommitSHA: "abc", Token: "secret", APIURL: "https://api.github.com"}},
+		{"github push", map[string]string{
+			"GITHUB_ACTIONS": "true", "GITHUB_REPOSITORY": "octo/repo", "GITHUB_REF": "refs/heads/main", "GITHUB_REF_NAME": "main",
+			"GITHUB_SHA": "abc", "GITHUB_API_URL": "https://ghe.example.com/api/v3",
+		}, Env{Provider: GitHubActions, Repo: "octo/repo", Branch: "main", CommitSHA: "abc", APIURL: "https://ghe.example.com/api/v3"}},
+		{"gitlab merge request", map[string]string{
+			"GITLAB_CI": "true", "CI_PROJECT_PATH": "group/project", "CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "develop",
+			"CI_MERGE_REQUEST_IID": "7", "CI_COMMIT_SHA": "def", "CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "fix", "CI_JOB_TOKEN": "job", "CI_API_V4_URL": "https://gitlab.com/api/v4",
+		}, Env{Provider: GitLabCI, Repo: "group/project", Branch: "fix", BaseBranch: "develop", PRNumber: "7", CommitSHA: "def", Token: "job", APIURL: "https://gitlab.com/api/v4"}},
+		{"gitlab prefers personal token", map[string]string{
+			"GITLAB_CI": "true", "GITLAB_TOKEN": "pat", "CI_JOB_TOKEN": "job",
+		}, Env{Provider: GitLabCI, Token: "pat"}},
+		{"circleci", map[string]string{
+			"CIRCLECI": "true", "CIRCLE_PROJECT_USERNAME": "octo", "CIRCLE_PROJECT_REPONAME": "repo",
+			"CIRCLE_PULL_REQUEST": "https://github.com/octo/repo/pull/9", "CIRCLE_SHA1": "123",
+		}, Env{Provider: CircleCI, Repo: "octo/repo", PRNumber: "9", CommitSHA: "123"}},
+		{"buildkite", map[string]string{
+			"BUILDKITE": "true", "BUILDKITE_REPO": "git@github.com:octo/repo.git", "BUILDKITE_PULL_REQUEST": "5",
+			"BUILDKITE_PULL_REQUEST_BASE_BRANCH": "main", "BUILDKITE_COMMIT": "456",
+		}, Env{Provider: Buildkite, Repo: "octo/repo", BaseBranch: "main", PRNumber: "5", CommitSHA: "456"}},
+		{"buildkite branch build", map[string]string{
+			"BUILDKITE": "true", "BUILDKITE_PULL_REQUEST": "false",
+		}, Env{Provider: Buildkite}},
+		{"bitbucket pipelines", map[string]string{
+			"BITBUCKET_BUILD_NUMBER": "12", "BITBUCKET_REPO_FULL_NAME": "team/repo", "BITBUCKET_PR_ID": "4",
+			"BITBUCKET_PR_DESTINATION_BRANCH": "main", "BITBUCKET_COMMIT": "abc", "BITBUCKET_TOKEN": "t", "BITBUCKET_BRANCH": "topic",
+		}, Env{Provider: Bitbucket, Repo: "team/repo", Branch: "topic", BaseBranch: "main", PRNumber: "4", CommitSHA: "abc", Token: "t", APIURL: "https://api.bitbucket.org/2.0"}},
+		{"jenkins", map[string]string{
+			"JENKINS_URL": "https://ci.example.com/", "GIT_URL": "https://github.com/octo/repo.git",
+			"CHANGE_ID": "3", "CHANGE_TARGET": "main", "GIT_COMMIT": "789", "GITHUB_TOKEN": "t",
+		}, Env{Provider: Jenkins, Repo: "octo/repo", BaseBranch: "main", PRNumber: "3", CommitSHA: "789", Token: "t"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(envFrom(tt.vars))
+			if got == nil {
+				t.Fatalf("Detect returned nil")
+			}
+			if *got != tt.want {
+				t.Errorf("Detect = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if env := Detect(envFrom(nil)); env != nil {
+		t.Errorf("Expected nil outside CI, got %+v", env)
+	}
+}
+
+// TestDetect_GitHubEvent completes the environment from the event payload.
+func TestDetect_GitHubEvent(t *testing.T) {
+	dir := t.TempDir()
+	pr := filepath.Join(dir, "pr.json")
+	os.WriteFile(pr, []byte(`{"number": 42, "repository": {"full_name": "octo/repo"},
+		"pull_request": {"head": {"sha": "head1", "ref": "feature"}, "base": {"sha": "base1", "ref": "main"}}}`), 0o644)
+	push := filepath.Join(dir, "push.json")
+	os.WriteFile(push, []byte(`{"before": "prev1", "after": "abc", "repository": {"full_name": "octo/repo"}}`), 0o644)
+	newBranch := filepath.Join(dir, "new.json")
+	os.WriteFile(newBranch, []byte(`{"before": "0000000000000000000000000000000000000000"}`), 0o644)
+
+	// pull_request_target runs on the base branch: GITHUB_SHA is its head.
+	env := Detect(envFrom(map[string]string{
+		"GITHUB_ACTIONS": "true", "GITHUB_REF": "refs/heads/main", "GITHUB_REF_NAME": "main", "GITHUB_SHA": "mainhead", "GITHUB_EVENT_PATH": pr,
+	}))
+	want := Env{Provider: GitHubActions, Repo: "octo/repo", Branch: "feature", BaseBranch: "main", PRNumber: "42", CommitSHA: "head1", BaseSHA: "base1", APIURL: "https://api.github.com"}
+	if *env != want {
+		t.Errorf("pull request: got %+v, want %+v", *env, want)
+	}
+	env = Detect(envFrom(map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_SHA": "abc", "GITHUB_EVENT_PATH": push}))
+	if env.BaseSHA != "prev1" || env.Repo != "octo/repo" || env.PRNumber != "" {
+		t.Errorf("push: got %+v", *env)
+	}
+	env = Detect(envFrom(map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_EVENT_PATH": newBranch}))
+	if env.BaseSHA != "" {
+		t.Errorf("new branch: BaseSHA = %q, want none", env.BaseSHA)
+	}
+}
+
+// TestEnv_BaseRefAndString checks derived values never expose the token.
+func TestEnv_BaseRefAndString(t *testing.T) {
+	env := &Env{Provider: GitHubActions, Repo: "octo/repo", BaseBranch: "main", PRNumber: "1", CommitSHA: "abc", Token: "secret"}
+	if got := env.BaseRef(); got != "origin/main" {
+		t.Errorf("BaseRef = %q", got)
+	}
+	s := env.String()
+	if strings.Contains(s, "secret") || !strings.Contains(s, "token set") || !strings.Contains(s, "PR #1") {
+		t.Errorf("Unexpected String() %q", s)
+	}
+	if (&Env{}).BaseRef() != "" {
+		t.Errorf("Expected empty BaseRef without base branch")
+	}
+	if got := (&Env{Branch: "release/1.2"}).TargetBranch(); got != "release/1.2" {
+		t.Errorf("Expected the built branch as target without base branch, got %q", got)
+	}
+}
+
+// TestRepoFromURL covers https, ssh and unparseable remotes.
+func TestRepoFromURL(t *testing.T) {
+	cases := map[string]string{
+		"https://github.com/octo/repo.git": "octo/repo",
+		"https://github.com/octo/repo":     "octo/repo",
+		"git@github.com:octo/repo.git":     "octo/repo",
+		"":                                 "",
+	}
+	for url, want := range cases {
+		if got := repoFromURL(url); got != want {
+			t.Errorf("repoFromURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
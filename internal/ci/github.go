@@ -0,0 +1,60 @@
+package ci
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// githubEvent holds the fields of the GitHub Actions event payload used to
+// complete the environment: those of pull_request and pull_request_target
+// events, and of push events.
+type githubEvent struct {
+	Number      int `json:"number"`
+	PullRequest *struct {
+		Head struct {
+			SHA string `json:"sha"`
+			Ref string `json:"ref"`
+		} `json:"head"`
+		Base struct {
+			SHA string `json:"sha"`
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+	Before     string `json:"before"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// applyGitHubEvent completes env from the event payload at path
+// (GITHUB_EVENT_PATH). For pull requests the payload names the head commit,
+// where GITHUB_SHA is a merge commit or, for pull_request_target, the base
+// branch head; for pushes the commit before the push. A missing or
+// unreadable payload leaves env as is.
+func applyGitHubEvent(env *Env, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var event githubEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return
+	}
+	env.Repo = firstNonEmpty(env.Repo, event.Repository.FullName)
+	if pr := event.PullRequest; pr != nil {
+		if event.Number > 0 {
+			env.PRNumber = strconv.Itoa(event.Number)
+		}
+		env.CommitSHA = firstNonEmpty(pr.Head.SHA, env.CommitSHA)
+		env.Branch = firstNonEmpty(pr.Head.Ref, env.Branch)
+		env.BaseBranch = firstNonEmpty(pr.Base.Ref, env.BaseBranch)
+		env.BaseSHA = pr.Base.SHA
+		return
+	}
+	// The commit before the push is all zeros for a new branch.
+	if strings.Trim(event.Before, "0") != "" {
+		env.BaseSHA = event.Before
+	}
+}
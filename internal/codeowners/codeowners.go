@@ -0,0 +1,129 @@
+// Package codeowners parses CODEOWNERS files as used by GitHub, GitLab and
+// Gitea and looks up the owners of a path.
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// Locations lists where CODEOWNERS is looked up, relative to the repository root.
+var Locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// Rule assigns owners to the paths matching a pattern.
+type Rule struct {
+	Pattern string
+	Owners  []string
+	glob    string
+}
+
+// File is a parsed CODEOWNERS file.
+type File struct {
+	Rules []Rule
+}
+
+// Parse reads a CODEOWNERS file. GitLab section headers ("[Section]") are
+// skipped, so rules of all sections apply.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		pattern := strings.ReplaceAll(fields[0], `\ `, " ")
+		if strings.HasPrefix(pattern, "!") {
+			return nil, fmt.Errorf("line %d: negated patterns are not supported", n)
+		}
+		f.Rules = append(f.Rules, Rule{Pattern: pattern, Owners: fields[1:], glob: toGlob(pattern)})
+	}
+	return f, scanner.Err()
+}
+
+// Load parses the CODEOWNERS file at path.
+func Load(path string) (*File, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	f, err := Parse(fh)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// Find returns the CODEOWNERS file of the repository containing dir and the
+// repository root, or "" if there is none.
+func Find(dir string) (path, root string) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			for _, loc := range Locations {
+				path := filepath.Join(dir, filepath.FromSlash(loc))
+				if info, err := os.Stat(path); err == nil && !info.IsDir() {
+					return path, dir
+				}
+			}
+			return "", dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// Owners returns the owners of the slash-separated path relative to the
+// repository root. The last matching rule wins; a rule without owners
+// leaves the path unowned.
+func (f *File) Owners(path string) []string {
+	for i := len(f.Rules) - 1; i >= 0; i-- {
+		if diffcoverage.MatchPattern(f.Rules[i].glob, path) {
+			if len(f.Rules[i].Owners) == 0 {
+				return nil
+			}
+			return f.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// toGlob translates a gitignore-style CODEOWNERS pattern to a MatchPattern
+// glob. Patterns with a leading or inner slash are anchored at the root, and
+// a pattern matching a directory matches everything below it, except that
+// "dir/*" only matches the files directly in dir, as on GitHub.
+func toGlob(pattern string) string {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !anchored {
+		pattern = "**/" + pattern
+	}
+	switch {
+	case strings.HasSuffix(pattern, "/*") && pattern != "**/*":
+		return pattern
+	case dirOnly:
+		// At least one path segment below the directory.
+		return pattern + "/*/**"
+	}
+	return pattern + "/**"
+}
@@ -0,0 +1,77 @@
+package codeowners
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `# Default owners
+*                 @acme/everyone
+*.pb.go           @acme/api
+/internal/        @acme/platform   # platform code
+docs/*            @acme/docs
+cmd/tool/main.go  @alice @bob
+
+[Legacy]
+/legacy/
+`
+
+// TestFile_Owners checks anchoring, directory patterns and precedence.
+func TestFile_Owners(t *testing.T) {
+	f, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cases := map[string][]string{
+		"main.go":                   {"@acme/everyone"},
+		"api/v1/service.pb.go":      {"@acme/api"},
+		"internal/x/y.go":           {"@acme/platform"},
+		"internal/x/y.pb.go":        {"@acme/platform"},
+		"pkg/internal/z.go":         {"@acme/everyone"},
+		"docs/a.go":                 {"@acme/docs"},
+		"docs/nested/a.go":          {"@acme/everyone"},
+		"cmd/tool/main.go":          {"@alice", "@bob"},
+		"legacy/old.go":             nil,
+		"sub/cmd/tool/main.go":      {"@acme/everyone"},
+		"internal":                  {"@acme/everyone"},
+		"internal/platform_test.go": {"@acme/platform"},
+	}
+	for path, want := range cases {
+		if got := f.Owners(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("Owners(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// TestParse_Negation rejects patterns CODEOWNERS does not support.
+func TestParse_Negation(t *testing.T) {
+	if _, err := Parse(strings.NewReader("!*.go @x\n")); err == nil {
+		t.Errorf("Expected an error for a negated pattern")
+	}
+}
+
+// TestFind looks up .github/CODEOWNERS from a module below the repository root.
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	module := filepath.Join(root, "services", "api")
+	for _, dir := range []string{filepath.Join(root, ".git"), filepath.Join(root, ".github"), module} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if path, _ := Find(module); path != "" {
+		t.Errorf("Expected no CODEOWNERS, got %s", path)
+	}
+
+	want := filepath.Join(root, ".github", "CODEOWNERS")
+	if err := os.WriteFile(want, []byte("* @x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path, repoRoot := Find(module)
+	if path != want || repoRoot != root {
+		t.Errorf("Find = %s, %s; want %s, %s", path, repoRoot, want, root)
+	}
+}
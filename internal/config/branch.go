@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// Branch overrides the thresholds when the target branch matches Pattern, a
+// path.Match pattern such as "release/*". Unset thresholds keep their value.
+type Branch struct {
+	Pattern             string   `yaml:"pattern"`
+	MinCoverage         *float64 `yaml:"min_coverage"`
+	MinFunctionCoverage *float64 `yaml:"min_function_coverage"`
+}
+
+// validate checks the pattern and thresholds of b.
+func (b Branch) validate() error {
+	if b.Pattern == "" {
+		return fmt.Errorf("branches: pattern is required")
+	}
+	if _, err := path.Match(b.Pattern, ""); err != nil {
+		return fmt.Errorf("branches: invalid pattern %q: %v", b.Pattern, err)
+	}
+	for name, v := range map[string]*float64{"min_coverage": b.MinCoverage, "min_function_coverage": b.MinFunctionCoverage} {
+		if v != nil && (*v < 0 || *v > 100) {
+			return fmt.Errorf("branches: %s of %s must be between 0 and 100, got %v", name, b.Pattern, *v)
+		}
+	}
+	return nil
+}
+
+// ApplyBranch applies the thresholds of the first entry of Branches matching
+// the target branch and returns its pattern, or "" if none matches.
+func (cfg *Config) ApplyBranch(branch string) string {
+	if branch == "" {
+		return ""
+	}
+	for _, b := range cfg.Branches {
+		if ok, _ := path.Match(b.Pattern, branch); !ok {
+			continue
+		}
+		if b.MinCoverage != nil {
+			cfg.MinCoverage = *b.MinCoverage
+		}
+		if b.MinFunctionCoverage != nil {
+			cfg.MinFunctionCoverage = *b.MinFunctionCoverage
+		}
+		return b.Pattern
+	}
+	return ""
+}
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/policy"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the configuration file looked up in the source root.
+const FileName = ".diffcoverage.yaml"
+
+// Config is the content of .diffcoverage.yaml.
+type Config struct {
+	// Preset names a built-in policy (see Presets) supplying defaults.
+	Preset string `yaml:"preset"`
+	// MinCoverage is the minimum diff coverage percentage.
+	MinCoverage float64 `yaml:"min_coverage"`
+	// MinFunctionCoverage is the minimum percentage of changed functions
+	// that must be fully covered.
+	MinFunctionCoverage float64 `yaml:"min_function_coverage"`
+	// Exceptions is the exceptions file, relative to the source root
+	// (default: .diffcoverage-exceptions.yaml if present).
+	Exceptions string `yaml:"exceptions"`
+	// Branches override the thresholds per target branch (see ApplyBranch).
+	Branches []Branch `yaml:"branches"`
+	// Labels override the thresholds for pull/merge requests carrying a label
+	// (see ApplyLabels).
+	Labels []Label `yaml:"labels"`
+	// AppliedLabels are the entries of Labels ApplyLabels applied.
+	AppliedLabels []Label `yaml:"-"`
+	// Exclude lists glob patterns of changed files that are not counted.
+	Exclude []string `yaml:"exclude"`
+	// ExcludeFunctions lists patterns of function names whose changed lines
+	// are not counted, e.g. String or DeepCopy* (see diffcoverage.FuncMatcher).
+	ExcludeFunctions []string `yaml:"exclude_functions"`
+	// Publish lists the integrations the report is published to (see -publish).
+	Publish []string `yaml:"publish"`
+	// CommentTemplate is a Go text/template file, relative to the source
+	// root, rendering pull request comments (see -comment-template).
+	CommentTemplate string `yaml:"comment_template"`
+	// Webhooks lists the URLs the webhook integration posts the JSON report to.
+	Webhooks []string `yaml:"webhooks"`
+	// Archive is the s3:// or gs:// location the archive integration uploads to.
+	Archive string `yaml:"archive"`
+	// History is the file the history integration records runs in.
+	History string `yaml:"history"`
+	// Server is the URL of the serve command the server integration uploads
+	// runs to, and Team the team they are rolled up by there.
+	Server string `yaml:"server"`
+	Team   string `yaml:"team"`
+	// Trend enables regression alerts against the run history.
+	Trend Trend `yaml:"trend"`
+	// Email configures the email integration.
+	Email Email `yaml:"email"`
+	// Artifact locates the baseline profile fetched by artifact fetch.
+	Artifact Artifact `yaml:"artifact"`
+	// Sort orders the files of every output: file, coverage or uncovered
+	// (see diffcoverage.SortOrders and -sort).
+	Sort string `yaml:"sort"`
+	// Blame attributes uncovered lines to their authors with git blame (see -blame).
+	Blame bool `yaml:"blame"`
+	// TestSkeletons generates tests for new, uncovered functions, posted as
+	// GitHub review comments (see -test-skeletons).
+	TestSkeletons bool `yaml:"test_skeletons"`
+	// Mutation reruns the tests against mutants of the covered changed lines
+	// and reports the lines no test asserts on (see -mutate).
+	Mutation bool `yaml:"mutation"`
+	// RequireTestFiles fails the run when the diff adds files to a package
+	// without any test file (see -require-test-files).
+	RequireTestFiles bool `yaml:"require_test_files"`
+	// IgnoreUnreachable does not count blocks that only panic, log.Fatal or
+	// os.Exit (see -ignore-unreachable).
+	IgnoreUnreachable bool `yaml:"ignore_unreachable"`
+	// IgnoreErrorReturns does not count `if err != nil { return ..., err }`
+	// statements (see -ignore-error-returns).
+	IgnoreErrorReturns bool `yaml:"ignore_error_returns"`
+	// WholeFunctions holds every line of the touched functions, changed or
+	// not, to MinCoverage as well (see -whole-functions).
+	WholeFunctions bool `yaml:"whole_functions"`
+	// MaxLineAge, in days, leaves out the unchanged lines of the touched
+	// functions last changed longer ago in whole-function mode; 0 counts all
+	// (see -max-line-age).
+	MaxLineAge int `yaml:"max_line_age"`
+	// RequireCoverMode fails the run if the profile was generated with a
+	// weaker go test -covermode: set, count or atomic (see -require-covermode).
+	RequireCoverMode string `yaml:"require_covermode"`
+	// ProfileCheck is what an implausible profile does: warn (the default),
+	// fail or off (see diffcoverage.ProfileChecks and -profile-check).
+	ProfileCheck string `yaml:"profile_check"`
+	// MinProfileStatements is the percentage of the module's statements a
+	// plausible profile has; 0 skips the check (see -min-profile-statements).
+	MinProfileStatements float64 `yaml:"min_profile_statements"`
+	// RequireTestChanges lists glob patterns of files whose changed functions
+	// fail the run when the diff does not change their package tests (see
+	// -require-test-changes); elsewhere they are only reported.
+	RequireTestChanges []string `yaml:"require_test_changes"`
+	// Policies are expressions over the JSON report that must all hold (see
+	// the policy package).
+	Policies []Policy `yaml:"policies"`
+	// CodeOwners enables the coverage breakdown by CODEOWNERS owner.
+	CodeOwners *CodeOwners `yaml:"codeowners"`
+	// Grading enables the letter grade of the coverage (see -grade).
+	Grading *Grading `yaml:"grading"`
+}
+
+// Grading awards the grade of the band with the highest minimum the coverage
+// reaches; without bands A to F (see diffcoverage.DefaultGrades).
+type Grading struct {
+	Bands []GradeBand `yaml:"bands"`
+}
+
+// GradeBand awards Grade to coverages of at least Min percent.
+type GradeBand struct {
+	Grade string  `yaml:"grade"`
+	Min   float64 `yaml:"min"`
+}
+
+// GradeBands returns the bands of g, or the default ones.
+func (g *Grading) GradeBands() []diffcoverage.GradeBand {
+	if len(g.Bands) == 0 {
+		return diffcoverage.DefaultGrades
+	}
+	bands := make([]diffcoverage.GradeBand, len(g.Bands))
+	for i, b := range g.Bands {
+		bands[i] = diffcoverage.GradeBand{Grade: b.Grade, Min: b.Min}
+	}
+	return bands
+}
+
+// Policy is a named policy expression, e.g. `coverage >= 80 ||
+// files.all(f, !f.path.startsWith("internal/billing/"))`.
+type Policy struct {
+	Name string `yaml:"name"`
+	Expr string `yaml:"expr"`
+}
+
+// CodeOwners configures the per-owner report. File defaults to the
+// CODEOWNERS file of the repository; MinCoverage maps owners such as
+// "@org/team" to their own minimum diff coverage.
+type CodeOwners struct {
+	File        string             `yaml:"file"`
+	MinCoverage map[string]float64 `yaml:"min_coverage"`
+}
+
+// Email configures who the email integration notifies. SMTP credentials are
+// read from the environment.
+type Email struct {
+	SMTP   string   `yaml:"smtp"` // host:port
+	From   string   `yaml:"from"`
+	To     []string `yaml:"to"`
+	Author bool     `yaml:"author"` // also notify the commit author
+	Always bool     `yaml:"always"` // also send when the gate passes
+}
+
+// Artifact names the CI artifact holding the coverage profile of the base
+// branch: the artifact name on GitHub Actions, the job name on GitLab CI.
+type Artifact struct {
+	Name string `yaml:"name"`
+	File string `yaml:"file"` // path inside the artifact
+}
+
+// Trend configures regression alerts: a metric that did not increase over the
+// last Runs runs of a branch and dropped by more than Tolerance percentage
+// points is reported, and fails the run if Fail is set.
+type Trend struct {
+	Runs      int     `yaml:"runs"`
+	Tolerance float64 `yaml:"tolerance"`
+	Fail      bool    `yaml:"fail"`
+}
+
+// Load reads and validates the configuration file at path.
+func Load(path string) (*Config, error) {
+	return load(path, "")
+}
+
+// load reads the file at path; a non-empty preset replaces the file's own preset.
+func load(path, preset string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data, path, preset)
+}
+
+// parse decodes and validates a configuration; path names it in errors.
+func parse(data []byte, path, preset string) (*Config, error) {
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	if cfg.MinCoverage < 0 || cfg.MinCoverage > 100 {
+		return nil, fmt.Errorf("error parsing %s: min_coverage must be between 0 and 100, got %v", path, cfg.MinCoverage)
+	}
+	if cfg.MinFunctionCoverage < 0 || cfg.MinFunctionCoverage > 100 {
+		return nil, fmt.Errorf("error parsing %s: min_function_coverage must be between 0 and 100, got %v", path, cfg.MinFunctionCoverage)
+	}
+	if cfg.Trend.Runs < 0 || cfg.Trend.Runs == 1 || cfg.Trend.Tolerance < 0 {
+		return nil, fmt.Errorf("error parsing %s: trend.runs must be 0 (disabled) or at least 2 and trend.tolerance must not be negative", path)
+	}
+	if cfg.MaxLineAge < 0 {
+		return nil, fmt.Errorf("error parsing %s: max_line_age must not be negative, got %d", path, cfg.MaxLineAge)
+	}
+	if cfg.RequireCoverMode != "" && !diffcoverage.ValidCoverMode(cfg.RequireCoverMode) {
+		return nil, fmt.Errorf("error parsing %s: require_covermode must be set, count or atomic, got %q", path, cfg.RequireCoverMode)
+	}
+	if cfg.Grading != nil {
+		for _, b := range cfg.Grading.Bands {
+			if b.Grade == "" || b.Min < 0 || b.Min > 100 {
+				return nil, fmt.Errorf("error parsing %s: grading bands need a grade and a min between 0 and 100, got %q at %v", path, b.Grade, b.Min)
+			}
+		}
+	}
+	if !diffcoverage.ValidProfileCheck(cfg.ProfileCheck) {
+		return nil, fmt.Errorf("error parsing %s: profile_check must be warn, fail or off, got %q", path, cfg.ProfileCheck)
+	}
+	if cfg.MinProfileStatements < 0 || cfg.MinProfileStatements > 100 {
+		return nil, fmt.Errorf("error parsing %s: min_profile_statements must be between 0 and 100, got %v", path, cfg.MinProfileStatements)
+	}
+	if !diffcoverage.ValidSortOrder(cfg.Sort) {
+		return nil, fmt.Errorf("error parsing %s: sort must be file, coverage or uncovered, got %q", path, cfg.Sort)
+	}
+	if _, err := diffcoverage.NewFuncMatcher(cfg.ExcludeFunctions); err != nil {
+		return nil, fmt.Errorf("error parsing %s: exclude_functions: %v", path, err)
+	}
+	for _, b := range cfg.Branches {
+		if err := b.validate(); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", path, err)
+		}
+	}
+	for _, l := range cfg.Labels {
+		if err := l.validate(); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", path, err)
+		}
+	}
+	for i, p := range cfg.Policies {
+		if p.Name == "" {
+			cfg.Policies[i].Name = p.Expr
+		}
+		if _, err := policy.Parse(p.Expr); err != nil {
+			return nil, fmt.Errorf("error parsing %s: policy %q: %v", path, cfg.Policies[i].Name, err)
+		}
+	}
+	if cfg.CodeOwners != nil {
+		for owner, minimum := range cfg.CodeOwners.MinCoverage {
+			if minimum < 0 || minimum > 100 {
+				return nil, fmt.Errorf("error parsing %s: codeowners.min_coverage of %s must be between 0 and 100, got %v", path, owner, minimum)
+			}
+		}
+	}
+	if preset != "" {
+		cfg.Preset = preset
+	}
+	if err := cfg.ApplyPreset(cfg.Preset); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Find returns the configuration file in dir, or "" if there is none.
+func Find(dir string) string {
+	for _, name := range []string{FileName, ".diffcoverage.yml"} {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Resolve loads the file at path, or the one found in dir when path is empty.
+// A non-empty preset takes precedence over the file's preset. Without any
+// configuration file it returns the preset's settings (or an empty Config).
+func Resolve(path, dir, preset string) (*Config, error) {
+	if path == "" {
+		path = Find(dir)
+	}
+	if path == "" {
+		cfg := &Config{}
+		return cfg, cfg.ApplyPreset(preset)
+	}
+	return load(path, preset)
+}
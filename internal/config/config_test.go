@@ -0,0 +1,255 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustWriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directories for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file %s: %v", path, err)
+	}
+}
+
+// TestLoad_Success parses a complete configuration.
+func TestLoad_Success(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	mustWriteFile(t, path, "min_coverage: 85.5\nexclude:\n  - \"*.pb.go\"\n  - internal/gen/**\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := &Config{MinCoverage: 85.5, Exclude: []string{"*.pb.go", "internal/gen/**"}}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Load = %+v, want %+v", cfg, want)
+	}
+}
+
+// TestLoad_Errors covers missing files, unknown keys and invalid values.
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"unknown.yaml": "min_coverge: 80\n",
+		"range.yaml":   "min_coverage: 120\n",
+		"syntax.yaml":  "exclude: [\n",
+		"runs.yaml":    "trend:\n  runs: 1\n",
+		"tol.yaml":     "trend:\n  runs: 3\n  tolerance: -1\n",
+		"owner.yaml":   "codeowners:\n  min_coverage:\n    \"@org/team\": 101\n",
+		"policy.yaml":  "policies:\n  - expr: coverage >\n",
+		"funcs.yaml":   "min_function_coverage: -1\n",
+		"branch.yaml":  "branches:\n  - pattern: \"release/[\"\n",
+		"bmin.yaml":    "branches:\n  - pattern: main\n    min_coverage: 200\n",
+		"label.yaml":   "labels:\n  - min_coverage: 90\n",
+	}
+	for name, content := range cases {
+		mustWriteFile(t, filepath.Join(dir, name), content)
+		if _, err := Load(filepath.Join(dir, name)); err == nil {
+			t.Errorf("Expected error for %s, got nil", name)
+		}
+	}
+	if _, err := Load(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("Expected error for missing file, got nil")
+	}
+}
+
+// TestLoad_Empty accepts an empty file.
+func TestLoad_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	mustWriteFile(t, path, "")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.MinCoverage != 0 || cfg.Exclude != nil {
+		t.Errorf("Expected zero config, got %+v", cfg)
+	}
+}
+
+// TestFindAndResolve covers discovery in the source root.
+func TestFindAndResolve(t *testing.T) {
+	dir := t.TempDir()
+	if Find(dir) != "" {
+		t.Errorf("Expected no config in empty dir")
+	}
+	cfg, err := Resolve("", dir, "")
+	if err != nil || cfg.MinCoverage != 0 {
+		t.Errorf("Expected empty config, got %+v (err %v)", cfg, err)
+	}
+
+	mustWriteFile(t, filepath.Join(dir, ".diffcoverage.yml"), "min_coverage: 70\n")
+	if got := Find(dir); got != filepath.Join(dir, ".diffcoverage.yml") {
+		t.Errorf("Find = %q", got)
+	}
+	cfg, err = Resolve("", dir, "")
+	if err != nil || cfg.MinCoverage != 70 {
+		t.Errorf("Expected discovered config, got %+v (err %v)", cfg, err)
+	}
+
+	cfg, err = Resolve("", dir, "strict")
+	if err != nil || cfg.MinCoverage != 70 || cfg.Preset != "strict" {
+		t.Errorf("Expected flag preset to replace the file's, got %+v (err %v)", cfg, err)
+	}
+	cfg, err = Resolve("", t.TempDir(), "legacy")
+	if err != nil || cfg.MinCoverage != 60 {
+		t.Errorf("Expected preset without config file, got %+v (err %v)", cfg, err)
+	}
+	if _, err := Resolve("", t.TempDir(), "nope"); err == nil {
+		t.Errorf("Expected error for unknown preset, got nil")
+	}
+
+	explicit := filepath.Join(t.TempDir(), "custom.yaml")
+	mustWriteFile(t, explicit, "min_coverage: 90\n")
+	cfg, err = Resolve(explicit, dir, "")
+	if err != nil || cfg.MinCoverage != 90 {
+		t.Errorf("Expected explicit config, got %+v (err %v)", cfg, err)
+	}
+}
+
+// TestInspectAndScaffold checks detection and that the scaffold loads back.
+func TestInspectAndScaffold(t *testing.T) {
+	root := t.TempDir()
+	gen := "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n"
+	mustWriteFile(t, filepath.Join(root, "go.mod"), "module github.com/example/module\n\ngo 1.21\n")
+	mustWriteFile(t, filepath.Join(root, "api", "v1.pb.go"), gen)
+	mustWriteFile(t, filepath.Join(root, "api", "handwritten.go"), "package api\n")
+	mustWriteFile(t, filepath.Join(root, "gen", "a.go"), gen)
+	mustWriteFile(t, filepath.Join(root, "gen", "b.go"), gen)
+	mustWriteFile(t, filepath.Join(root, "pkg", "enum.go"), gen)
+	mustWriteFile(t, filepath.Join(root, "pkg", "logic.go"), "// Package pkg.\npackage pkg\n\n// Code generated by nothing. DO NOT EDIT.\n")
+	mustWriteFile(t, filepath.Join(root, "pkg", "logic_test.go"), gen)
+	mustWriteFile(t, filepath.Join(root, "vendor", "x", "x.go"), gen)
+	mustWriteFile(t, filepath.Join(root, "tools", "go.mod"), "module tools\n")
+	mustWriteFile(t, filepath.Join(root, ".github", "workflows", "ci.yml"), "on: push\n")
+
+	insp, err := Inspect(root)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if insp.ModuleName != "github.com/example/module" {
+		t.Errorf("ModuleName = %q", insp.ModuleName)
+	}
+	if want := []string{"*.pb.go", "gen/**", "pkg/enum.go", "vendor/**"}; !reflect.DeepEqual(insp.Exclude, want) {
+		t.Errorf("Exclude = %v, want %v", insp.Exclude, want)
+	}
+	if want := []string{"tools"}; !reflect.DeepEqual(insp.Modules, want) {
+		t.Errorf("Modules = %v, want %v", insp.Modules, want)
+	}
+	if want := []string{"GitHub Actions"}; !reflect.DeepEqual(insp.CI, want) {
+		t.Errorf("CI = %v, want %v", insp.CI, want)
+	}
+
+	content := Scaffold(insp, DefaultMinCoverage)
+	for _, want := range []string{"# Module: github.com/example/module", "# Detected CI: GitHub Actions", "tools"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected scaffold to contain %q, got:\n%s", want, content)
+		}
+	}
+	path := filepath.Join(root, FileName)
+	mustWriteFile(t, path, string(content))
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Scaffold does not load back: %v\n%s", err, content)
+	}
+	if cfg.MinCoverage != DefaultMinCoverage || !reflect.DeepEqual(cfg.Exclude, insp.Exclude) {
+		t.Errorf("Round trip mismatch: %+v", cfg)
+	}
+}
+
+// TestScaffold_Empty writes an empty exclusion list when nothing was detected.
+func TestScaffold_Empty(t *testing.T) {
+	insp, err := Inspect(t.TempDir())
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	content := string(Scaffold(insp, 75))
+	if !strings.Contains(content, "min_coverage: 75\n") || !strings.Contains(content, "exclude: []\n") {
+		t.Errorf("Unexpected scaffold:\n%s", content)
+	}
+}
+
+// TestConfig_ApplyBranch applies the first matching branch entry.
+func TestConfig_ApplyBranch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	mustWriteFile(t, path, `min_coverage: 80
+min_function_coverage: 50
+branches:
+  - pattern: release/*
+    min_coverage: 90
+    min_function_coverage: 100
+  - pattern: experimental/*
+    min_coverage: 0
+  - pattern: "*"
+    min_coverage: 75
+`)
+	cases := []struct {
+		branch    string
+		pattern   string
+		min, fmin float64
+	}{
+		{"release/1.2", "release/*", 90, 100},
+		{"experimental/x", "experimental/*", 0, 50},
+		{"main", "*", 75, 50},
+		{"feature/a/b", "", 80, 50},
+		{"", "", 80, 50},
+	}
+	for _, c := range cases {
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if got := cfg.ApplyBranch(c.branch); got != c.pattern || cfg.MinCoverage != c.min || cfg.MinFunctionCoverage != c.fmin {
+			t.Errorf("ApplyBranch(%q) = %q with %v/%v, want %q with %v/%v", c.branch, got, cfg.MinCoverage, cfg.MinFunctionCoverage, c.pattern, c.min, c.fmin)
+		}
+	}
+}
+
+// TestConfig_ApplyLabels applies the entries of the carried labels in order.
+func TestConfig_ApplyLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	mustWriteFile(t, path, `min_coverage: 80
+labels:
+  - name: skip-diff-coverage
+    warn: true
+  - name: strict-coverage
+    min_coverage: 95
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if applied := cfg.ApplyLabels([]string{"bug"}); applied != nil || cfg.MinCoverage != 80 {
+		t.Errorf("ApplyLabels(bug) = %+v with %v", applied, cfg.MinCoverage)
+	}
+	applied := cfg.ApplyLabels([]string{"strict-coverage", "skip-diff-coverage"})
+	if len(applied) != 2 || applied[0].Name != "skip-diff-coverage" || !applied[0].Warn || cfg.MinCoverage != 95 {
+		t.Errorf("ApplyLabels = %+v with %v", applied, cfg.MinCoverage)
+	}
+	if !reflect.DeepEqual(cfg.AppliedLabels, applied) {
+		t.Errorf("AppliedLabels = %+v", cfg.AppliedLabels)
+	}
+}
+
+// TestDiff lists the changed settings only.
+func TestDiff(t *testing.T) {
+	old := &Config{MinCoverage: 80, Exclude: []string{"gen/**"}, Trend: Trend{Runs: 5}}
+	next := &Config{MinCoverage: 85, Exclude: []string{"gen/**", "mock/**"}, Trend: Trend{Runs: 5}, Blame: true}
+	want := []string{
+		"blame: false -> true",
+		"exclude: [gen/**] -> [gen/**, mock/**]",
+		"min_coverage: 80 -> 85",
+	}
+	if got := Diff(old, next); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff = %q, want %q", got, want)
+	}
+	if got := Diff(old, old); len(got) != 0 {
+		t.Errorf("Expected no changes, got %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diff describes the settings that differ between prev and next, one
+// "key: prev -> next" entry per top-level key of the file, sorted by key.
+func Diff(prev, next *Config) []string {
+	a, b := settings(prev), settings(next)
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	var changes []string
+	for k := range keys {
+		if !reflect.DeepEqual(a[k], b[k]) {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", k, setting(a[k]), setting(b[k])))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// settings returns cfg keyed as in the file.
+func settings(cfg *Config) map[string]any {
+	m := map[string]any{}
+	if data, err := yaml.Marshal(cfg); err == nil {
+		yaml.Unmarshal(data, &m)
+	}
+	return m
+}
+
+// setting formats a value in YAML flow style.
+func setting(v any) string {
+	if v == nil {
+		return "null"
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	setFlow(node)
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data[:len(data)-1])
+}
+
+func setFlow(n *yaml.Node) {
+	n.Style |= yaml.FlowStyle
+	for _, c := range n.Content {
+		setFlow(c)
+	}
+}
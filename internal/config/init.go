@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMinCoverage is the threshold written by Scaffold.
+const DefaultMinCoverage = 80.0
+
+// generatedRegex is the marker defined by https://go.dev/s/generatedcode.
+var generatedRegex = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// generatedSuffixes are file name patterns used by common code generators.
+var generatedSuffixes = []string{"*.pb.go", "*.pb.gw.go", "*_string.go", "*_gen.go", "*.gen.go", "zz_generated*.go"}
+
+// ciProviders maps marker paths to the CI system they indicate.
+var ciProviders = []struct{ path, name string }{
+	{".github/workflows", "GitHub Actions"},
+	{".gitlab-ci.yml", "GitLab CI"},
+	{".circleci", "CircleCI"},
+	{".buildkite", "Buildkite"},
+	{"Jenkinsfile", "Jenkins"},
+	{"bitbucket-pipelines.yml", "Bitbucket Pipelines"},
+}
+
+// Inspection is what Inspect learns about a repository.
+type Inspection struct {
+	ModuleName string
+	Modules    []string // directories of nested go.mod files
+	Exclude    []string // suggested exclusion patterns
+	CI         []string // detected CI providers
+}
+
+// Inspect walks the module at root looking for nested modules, vendored and
+// generated code, and CI configuration.
+func Inspect(root string) (*Inspection, error) {
+	insp := &Inspection{}
+	if data, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "module" {
+				insp.ModuleName = fields[1]
+				break
+			}
+		}
+	}
+
+	for _, p := range ciProviders {
+		if _, err := os.Stat(filepath.Join(root, p.path)); err == nil {
+			insp.CI = append(insp.CI, p.name)
+		}
+	}
+
+	goFiles := make(map[string]int)        // dir -> non-test .go files
+	generated := make(map[string][]string) // dir -> generated files
+	suffixHits := make(map[string]bool)
+	exclude := make(map[string]bool)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "testdata", "node_modules":
+				return filepath.SkipDir
+			case "vendor":
+				exclude[rel+"/**"] = true
+				return filepath.SkipDir
+			}
+			if rel != "." {
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					insp.Modules = append(insp.Modules, rel)
+				}
+			}
+			return nil
+		}
+		if !strings.HasSuffix(rel, ".go") || strings.HasSuffix(rel, "_test.go") {
+			return nil
+		}
+		dir := filepath.ToSlash(filepath.Dir(rel))
+		goFiles[dir]++
+		if !isGenerated(path) {
+			return nil
+		}
+		for _, pattern := range generatedSuffixes {
+			if ok, _ := filepath.Match(pattern, d.Name()); ok {
+				suffixHits[pattern] = true
+				return nil
+			}
+		}
+		generated[dir] = append(generated[dir], rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for pattern := range suffixHits {
+		exclude[pattern] = true
+	}
+	for dir, files := range generated {
+		if len(files) == goFiles[dir] && dir != "." {
+			exclude[dir+"/**"] = true
+			continue
+		}
+		for _, f := range files {
+			exclude[f] = true
+		}
+	}
+	for pattern := range exclude {
+		insp.Exclude = append(insp.Exclude, pattern)
+	}
+	sort.Strings(insp.Exclude)
+	sort.Strings(insp.Modules)
+	return insp, nil
+}
+
+// Scaffold renders a commented starter configuration for an inspected repository.
+func Scaffold(insp *Inspection, minCoverage float64) []byte {
+	var sb strings.Builder
+	sb.WriteString("# diffcoverage configuration, generated by \"diffcoverage init\".\n")
+	if insp.ModuleName != "" {
+		fmt.Fprintf(&sb, "# Module: %s\n", insp.ModuleName)
+	}
+	if len(insp.CI) > 0 {
+		fmt.Fprintf(&sb, "# Detected CI: %s\n", strings.Join(insp.CI, ", "))
+	}
+	if len(insp.Modules) > 0 {
+		fmt.Fprintf(&sb, "# Nested modules (analyze each from its own root): %s\n", strings.Join(insp.Modules, ", "))
+	}
+
+	sb.WriteString("\n# Minimum percentage of new/changed lines inside functions that must be covered.\n")
+	fmt.Fprintf(&sb, "min_coverage: %s\n", strconv.FormatFloat(minCoverage, 'f', -1, 64))
+
+	sb.WriteString("\n# Changed files matching these glob patterns are not counted.\n")
+	sb.WriteString("# \"*.pb.go\" matches at any depth, \"dir/**\" matches everything below dir.\n")
+	if len(insp.Exclude) == 0 {
+		sb.WriteString("exclude: []\n")
+		return []byte(sb.String())
+	}
+	sb.WriteString("exclude:\n")
+	for _, pattern := range insp.Exclude {
+		fmt.Fprintf(&sb, "  - %s\n", strconv.Quote(pattern))
+	}
+	return []byte(sb.String())
+}
+
+// isGenerated reports whether the Go file carries the generated-code marker
+// before its package clause.
+func isGenerated(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if generatedRegex.MatchString(line) {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package config
+
+import "fmt"
+
+// Label overrides the settings for pull/merge requests carrying the label
+// Name. Unset thresholds keep their value; Warn reports a failing gate as a
+// warning instead of failing the run.
+type Label struct {
+	Name                string   `yaml:"name"`
+	MinCoverage         *float64 `yaml:"min_coverage"`
+	MinFunctionCoverage *float64 `yaml:"min_function_coverage"`
+	Warn                bool     `yaml:"warn"`
+}
+
+// validate checks the name and thresholds of l.
+func (l Label) validate() error {
+	if l.Name == "" {
+		return fmt.Errorf("labels: name is required")
+	}
+	for name, v := range map[string]*float64{"min_coverage": l.MinCoverage, "min_function_coverage": l.MinFunctionCoverage} {
+		if v != nil && (*v < 0 || *v > 100) {
+			return fmt.Errorf("labels: %s of %s must be between 0 and 100, got %v", name, l.Name, *v)
+		}
+	}
+	return nil
+}
+
+// ApplyLabels applies the entries of Labels whose label the pull/merge
+// request carries, in order, and returns them.
+func (cfg *Config) ApplyLabels(labels []string) []Label {
+	carried := map[string]bool{}
+	for _, l := range labels {
+		carried[l] = true
+	}
+	var applied []Label
+	for _, l := range cfg.Labels {
+		if !carried[l.Name] {
+			continue
+		}
+		if l.MinCoverage != nil {
+			cfg.MinCoverage = *l.MinCoverage
+		}
+		if l.MinFunctionCoverage != nil {
+			cfg.MinFunctionCoverage = *l.MinFunctionCoverage
+		}
+		applied = append(applied, l)
+	}
+	cfg.AppliedLabels = applied
+	return applied
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// generatedCode are exclusions shared by all presets.
+var generatedCode = []string{"*.pb.go", "*.pb.gw.go", "zz_generated*.go"}
+
+// Presets bundle thresholds and exclusions for common adoption stages.
+var Presets = map[string]Config{
+	// strict suits new services: high bar, only generated code excluded.
+	"strict": {
+		MinCoverage: 90,
+		Exclude:     generatedCode,
+	},
+	// balanced is the recommended default for established codebases.
+	"balanced": {
+		MinCoverage: 80,
+		Exclude:     append(append([]string{}, generatedCode...), "*_string.go", "*_gen.go", "*.gen.go"),
+	},
+	// legacy eases adoption in codebases with little existing test coverage.
+	"legacy": {
+		MinCoverage: 60,
+		Exclude:     append(append([]string{}, generatedCode...), "*_string.go", "*_gen.go", "*.gen.go", "cmd/**", "**/main.go"),
+	},
+}
+
+// PresetNames returns the available preset names, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyPreset fills the settings cfg leaves unset from the named preset and
+// adds the preset's exclusions to cfg's own.
+func (cfg *Config) ApplyPreset(name string) error {
+	if name == "" {
+		return nil
+	}
+	preset, ok := Presets[name]
+	if !ok {
+		return fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	cfg.Preset = name
+	if cfg.MinCoverage == 0 {
+		cfg.MinCoverage = preset.MinCoverage
+	}
+	seen := make(map[string]bool)
+	for _, p := range cfg.Exclude {
+		seen[p] = true
+	}
+	for _, p := range preset.Exclude {
+		if !seen[p] {
+			cfg.Exclude = append(cfg.Exclude, p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestApplyPreset checks preset defaults never override explicit settings.
+func TestApplyPreset(t *testing.T) {
+	cfg := &Config{Exclude: []string{"*.pb.go", "internal/legacy/**"}}
+	if err := cfg.ApplyPreset("strict"); err != nil {
+		t.Fatalf("ApplyPreset failed: %v", err)
+	}
+	if cfg.MinCoverage != 90 || cfg.Preset != "strict" {
+		t.Errorf("Expected strict threshold, got %+v", cfg)
+	}
+	want := []string{"*.pb.go", "internal/legacy/**", "*.pb.gw.go", "zz_generated*.go"}
+	if !reflect.DeepEqual(cfg.Exclude, want) {
+		t.Errorf("Exclude = %v, want %v", cfg.Exclude, want)
+	}
+
+	cfg = &Config{MinCoverage: 70}
+	if err := cfg.ApplyPreset("legacy"); err != nil {
+		t.Fatalf("ApplyPreset failed: %v", err)
+	}
+	if cfg.MinCoverage != 70 {
+		t.Errorf("Explicit threshold overridden: %v", cfg.MinCoverage)
+	}
+
+	if err := (&Config{}).ApplyPreset(""); err != nil {
+		t.Errorf("Empty preset should be a no-op, got %v", err)
+	}
+	err := (&Config{}).ApplyPreset("lenient")
+	if err == nil || !strings.Contains(err.Error(), "balanced, legacy, strict") {
+		t.Errorf("Expected unknown preset error listing presets, got %v", err)
+	}
+}
+
+// TestPresets_DoNotShareSlices guards against presets aliasing each other's exclusions.
+func TestPresets_DoNotShareSlices(t *testing.T) {
+	cfg := &Config{}
+	cfg.ApplyPreset("balanced")
+	cfg.Exclude[0] = "changed"
+	if Presets["balanced"].Exclude[0] == "changed" || Presets["strict"].Exclude[0] == "changed" {
+		t.Errorf("ApplyPreset must copy preset exclusions")
+	}
+}
+
+// TestLoad_Preset checks presets referenced from the configuration file.
+func TestLoad_Preset(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, "ok.yaml"), "preset: balanced\nexclude:\n  - gen/**\n")
+	cfg, err := Load(filepath.Join(dir, "ok.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.MinCoverage != 80 || cfg.Exclude[0] != "gen/**" || len(cfg.Exclude) != 7 {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	mustWriteFile(t, filepath.Join(dir, "bad.yaml"), "preset: nope\n")
+	if _, err := Load(filepath.Join(dir, "bad.yaml")); err == nil {
+		t.Errorf("Expected error for unknown preset, got nil")
+	}
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// Remote is a central configuration served over HTTP(S), e.g. by a platform
+// team for all repositories of an organization.
+type Remote struct {
+	URL string
+	// PublicKey, if set, requires URL+".sig" to hold an ed25519 signature of
+	// the configuration, raw or base64 encoded.
+	PublicKey ed25519.PublicKey
+	// CacheDir keeps the last fetched copy, revalidated with its ETag and used
+	// when the server cannot be reached. Empty disables caching.
+	CacheDir string
+	Client   *http.Client
+}
+
+// DefaultCacheDir returns the cache directory of remote configurations.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-new-code-coverage", "config")
+}
+
+// Load fetches, verifies and parses the configuration; a non-empty preset
+// replaces its own preset. The boolean reports whether the cached copy was
+// used because the server could not be reached.
+func (r *Remote) Load(ctx context.Context, preset string) (*Config, bool, error) {
+	data, stale, err := r.fetch(ctx, r.URL)
+	if err != nil {
+		return nil, false, err
+	}
+	if r.PublicKey != nil {
+		sig, _, err := r.fetch(ctx, r.URL+".sig")
+		if err != nil {
+			return nil, false, err
+		}
+		if !verify(r.PublicKey, data, sig) {
+			return nil, false, fmt.Errorf("signature verification of %s failed", r.URL)
+		}
+	}
+	cfg, err := parse(data, r.URL, preset)
+	return cfg, stale, err
+}
+
+// verify checks the raw or base64 encoded signature sig of data.
+func verify(key ed25519.PublicKey, data, sig []byte) bool {
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil {
+			return false
+		}
+		sig = decoded
+	}
+	return ed25519.Verify(key, data, sig)
+}
+
+// fetch returns the body at url, from the cache if the server reports it
+// unchanged or cannot be reached (stale).
+func (r *Remote) fetch(ctx context.Context, url string) (body []byte, stale bool, err error) {
+	var cached, etag []byte
+	var cachePath string
+	if r.CacheDir != "" {
+		sum := sha256.Sum256([]byte(url))
+		cachePath = filepath.Join(r.CacheDir, hex.EncodeToString(sum[:]))
+		cached, _ = os.ReadFile(cachePath)
+		etag, _ = os.ReadFile(cachePath + ".etag")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if cached != nil && len(etag) > 0 {
+		req.Header.Set("If-None-Match", string(etag))
+	}
+	client := r.Client
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if cached != nil {
+			return cached, true, nil
+		}
+		return nil, false, fmt.Errorf("error fetching %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return cached, false, nil
+	case resp.StatusCode != http.StatusOK:
+		if cached != nil && resp.StatusCode >= 500 {
+			return cached, true, nil
+		}
+		return nil, false, fmt.Errorf("error fetching %s: unexpected status %s", url, resp.Status)
+	}
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("error fetching %s: %v", url, err)
+	}
+	if cachePath != "" {
+		if err := os.MkdirAll(r.CacheDir, 0755); err == nil {
+			os.WriteFile(cachePath, body, 0644)
+			os.WriteFile(cachePath+".etag", []byte(resp.Header.Get("ETag")), 0644)
+		}
+	}
+	return body, false, nil
+}
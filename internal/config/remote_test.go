@@ -0,0 +1,61 @@
+package config
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRemote_Load verifies the signature and caches the configuration.
+func TestRemote_Load(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte("min_coverage: 85\n")
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, body))
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/coverage.yaml":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Write(body)
+		case "/coverage.yaml.sig":
+			w.Write([]byte(sig + "\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	remote := &Remote{URL: srv.URL + "/coverage.yaml", PublicKey: pub, CacheDir: t.TempDir(), Client: srv.Client()}
+	for i := 0; i < 2; i++ {
+		cfg, stale, err := remote.Load(context.Background(), "")
+		if err != nil || stale || cfg.MinCoverage != 85 {
+			t.Fatalf("Load = %+v, %v, %v", cfg, stale, err)
+		}
+	}
+	if notModified != 1 {
+		t.Errorf("Expected the second load to revalidate the cached copy, got %d 304s in %d requests", notModified, requests)
+	}
+
+	srv.Close()
+	if cfg, stale, err := remote.Load(context.Background(), ""); err != nil || !stale || cfg.MinCoverage != 85 {
+		t.Errorf("Expected the cached copy when the server is down, got %+v, %v, %v", cfg, stale, err)
+	}
+
+	other, _, _ := ed25519.GenerateKey(nil)
+	remote.PublicKey = other
+	if _, _, err := remote.Load(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "signature verification") {
+		t.Errorf("Expected signature verification error, got %v", err)
+	}
+}
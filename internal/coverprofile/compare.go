@@ -0,0 +1,200 @@
+package coverprofile
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// Delta is the statement coverage of a file, a package or the whole module
+// in the old and the new profile. Coverage is 0 without statements, and
+// Change only set if both profiles have statements.
+type Delta struct {
+	Name          string  `json:"name"`
+	OldStatements int     `json:"oldStatements"`
+	OldCovered    int     `json:"oldCovered"`
+	OldCoverage   float64 `json:"oldCoverage"`
+	NewStatements int     `json:"newStatements"`
+	NewCovered    int     `json:"newCovered"`
+	NewCoverage   float64 `json:"newCoverage"`
+	Change        float64 `json:"change"`
+}
+
+// UncoveredFunc is a function covered in the old profile but not in the new one.
+type UncoveredFunc struct {
+	File        string  `json:"file"`
+	Func        string  `json:"func"`
+	Statements  int     `json:"statements"`
+	OldCoverage float64 `json:"oldCoverage"`
+}
+
+// Comparison is the coverage drift between two profiles of a module.
+type Comparison struct {
+	Total    Delta   `json:"total"`
+	Packages []Delta `json:"packages"`
+	// Files lists the files whose statements or covered statements changed,
+	// the largest coverage drop first.
+	Files          []Delta         `json:"files"`
+	NewlyUncovered []UncoveredFunc `json:"newlyUncovered"`
+}
+
+// Source returns the content of a module-relative file as of one profile,
+// or an error if it is not available; its functions are then not compared.
+type Source func(file string) ([]byte, error)
+
+// Compare compares the profiles merged into old and new, whose paths are
+// relative to the module if its Module is set. The functions of both are
+// located with the source of the respective profile.
+func Compare(old, new *Merger, oldSrc, newSrc Source) *Comparison {
+	c := &Comparison{Total: Delta{Name: "total"}, Packages: []Delta{}, Files: []Delta{}, NewlyUncovered: []UncoveredFunc{}}
+	files := map[string]*Delta{}
+	pkgs := map[string]*Delta{}
+	add := func(m *Merger, isNew bool) {
+		for b, stmts := range m.stmts {
+			name := m.rel(b.file)
+			covered := 0
+			if m.counts[b] > 0 {
+				covered = stmts
+			}
+			if files[name] == nil {
+				files[name] = &Delta{Name: name}
+			}
+			if pkgs[path.Dir(name)] == nil {
+				pkgs[path.Dir(name)] = &Delta{Name: path.Dir(name)}
+			}
+			for _, d := range []*Delta{files[name], pkgs[path.Dir(name)], &c.Total} {
+				if isNew {
+					d.NewStatements += stmts
+					d.NewCovered += covered
+				} else {
+					d.OldStatements += stmts
+					d.OldCovered += covered
+				}
+			}
+		}
+	}
+	add(old, false)
+	add(new, true)
+
+	c.Total.finish()
+	for _, d := range pkgs {
+		d.finish()
+		c.Packages = append(c.Packages, *d)
+	}
+	sort.Slice(c.Packages, func(i, j int) bool { return c.Packages[i].Name < c.Packages[j].Name })
+	names := make([]string, 0, len(files))
+	for name, d := range files {
+		d.finish()
+		names = append(names, name)
+		if d.OldStatements != d.NewStatements || d.OldCovered != d.NewCovered {
+			c.Files = append(c.Files, *d)
+		}
+	}
+	sort.Slice(c.Files, func(i, j int) bool {
+		if c.Files[i].Change != c.Files[j].Change {
+			return c.Files[i].Change < c.Files[j].Change
+		}
+		return c.Files[i].Name < c.Files[j].Name
+	})
+
+	sort.Strings(names)
+	oldBlocks, newBlocks := old.byFile(), new.byFile()
+	for _, name := range names {
+		oldFuncs := map[string]funcCoverage{}
+		for _, fn := range old.funcs(name, oldBlocks[name], oldSrc) {
+			oldFuncs[fn.name] = fn
+		}
+		for _, fn := range new.funcs(name, newBlocks[name], newSrc) {
+			o, ok := oldFuncs[fn.name]
+			if ok && o.covered > 0 && fn.covered == 0 && fn.statements > 0 {
+				c.NewlyUncovered = append(c.NewlyUncovered, UncoveredFunc{
+					File:        name,
+					Func:        fn.name,
+					Statements:  fn.statements,
+					OldCoverage: percent(o.covered, o.statements),
+				})
+			}
+		}
+	}
+	return c
+}
+
+// finish computes the coverage and the change of d.
+func (d *Delta) finish() {
+	d.OldCoverage = percent(d.OldCovered, d.OldStatements)
+	d.NewCoverage = percent(d.NewCovered, d.NewStatements)
+	if d.OldStatements > 0 && d.NewStatements > 0 {
+		d.Change = d.NewCoverage - d.OldCoverage
+	}
+}
+
+// funcCoverage counts the statements of a function's blocks.
+type funcCoverage struct {
+	name                string
+	statements, covered int
+}
+
+// byFile groups the blocks by module-relative file.
+func (m *Merger) byFile() map[string][]block {
+	files := map[string][]block{}
+	for b := range m.stmts {
+		name := m.rel(b.file)
+		files[name] = append(files[name], b)
+	}
+	return files
+}
+
+// funcs returns the coverage of the functions of the module-relative file
+// name with the given blocks, in source order, or nil if src cannot provide
+// its source.
+func (m *Merger) funcs(name string, blocks []block, src Source) []funcCoverage {
+	data, err := src(name)
+	if err != nil {
+		return nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, data, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	var list []funcCoverage
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		fc := funcCoverage{name: diffcoverage.FuncName(fn)}
+		start, end := fset.Position(fn.Body.Lbrace).Line, fset.Position(fn.Body.Rbrace).Line
+		for _, b := range blocks {
+			if b.startLine >= start && b.endLine <= end {
+				fc.statements += m.stmts[b]
+				if m.counts[b] > 0 {
+					fc.covered += m.stmts[b]
+				}
+			}
+		}
+		list = append(list, fc)
+	}
+	return list
+}
+
+// rel returns a profile path relative to the module, if it is in it.
+func (m *Merger) rel(file string) string {
+	if m.Module == "" {
+		return file
+	}
+	return strings.TrimPrefix(file, m.Module+"/")
+}
+
+// percent returns covered/total as a percentage, 0 without statements.
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100.0 * float64(covered) / float64(total)
+}
@@ -0,0 +1,60 @@
+package coverprofile
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCompare reports per-package and per-file deltas and functions that lost
+// their coverage.
+func TestCompare(t *testing.T) {
+	src := "package a\n\nfunc F() {\n\tprintln()\n}\n\nfunc G() {\n\tprintln()\n\tprintln()\n}\n"
+	oldProfile := "mode: set\n" +
+		"example.com/m/a/a.go:3.10,5.2 1 1\n" +
+		"example.com/m/a/a.go:7.10,10.2 2 1\n" +
+		"example.com/m/b/b.go:1.1,2.2 2 1\n" +
+		"example.com/m/gone.go:1.1,2.2 1 1\n"
+	newProfile := "mode: set\n" +
+		"example.com/m/a/a.go:3.10,5.2 1 1\n" +
+		"example.com/m/a/a.go:7.10,10.2 2 0\n" +
+		"example.com/m/b/b.go:1.1,2.2 2 1\n" +
+		"example.com/m/b/new.go:1.1,2.2 1 0\n"
+	old, new := &Merger{Module: "example.com/m"}, &Merger{Module: "example.com/m"}
+	if err := old.Add("old.out", strings.NewReader(oldProfile)); err != nil {
+		t.Fatal(err)
+	}
+	if err := new.Add("new.out", strings.NewReader(newProfile)); err != nil {
+		t.Fatal(err)
+	}
+	source := func(file string) ([]byte, error) {
+		if file == "a/a.go" {
+			return []byte(src), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	c := Compare(old, new, source, source)
+
+	if c.Total.OldCoverage != 100 || c.Total.NewCoverage != 50 || c.Total.Change != -50 {
+		t.Errorf("Unexpected total %+v", c.Total)
+	}
+	var pkgs []string
+	for _, p := range c.Packages {
+		pkgs = append(pkgs, p.Name)
+	}
+	if want := []string{".", "a", "b"}; !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("Packages = %v, want %v", pkgs, want)
+	}
+	var files []string
+	for _, f := range c.Files {
+		files = append(files, f.Name)
+	}
+	if want := []string{"a/a.go", "b/new.go", "gone.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Files = %v, want %v", files, want)
+	}
+	want := []UncoveredFunc{{File: "a/a.go", Func: "G", Statements: 2, OldCoverage: 100}}
+	if !reflect.DeepEqual(c.NewlyUncovered, want) {
+		t.Errorf("NewlyUncovered = %+v, want %+v", c.NewlyUncovered, want)
+	}
+}
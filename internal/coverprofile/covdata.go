@@ -0,0 +1,60 @@
+package coverprofile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// AddCovData converts the binary coverage data written to GOCOVERDIR by
+// binaries built with `go build -cover` to a text profile with `go tool covdata
+// textfmt` and merges it.
+func (m *Merger) AddCovData(dirs []string) error {
+	tmpDir, err := os.MkdirTemp("", "diffcoverage-covdata")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	profile := filepath.Join(tmpDir, "covdata.out")
+
+	cmd := exec.Command("go", "tool", "covdata", "textfmt", "-i="+strings.Join(dirs, ","), "-o="+profile)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go tool covdata: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	f, err := os.Open(profile)
+	if err != nil {
+		return fmt.Errorf("go tool covdata: no profile written for %s: %v", strings.Join(dirs, ", "), err)
+	}
+	defer f.Close()
+	return m.Add(strings.Join(dirs, ","), f)
+}
+
+// Merge writes the merge of the text profiles and GOCOVERDIR directories to
+// w, with paths normalized to module (see Normalize).
+func Merge(w io.Writer, module, root string, profiles, covDirs []string) error {
+	m := &Merger{Module: module, Root: root}
+	for _, path := range profiles {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = m.Add(path, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if len(covDirs) > 0 {
+		if err := m.AddCovData(covDirs); err != nil {
+			return err
+		}
+	}
+	return m.Write(w)
+}
@@ -0,0 +1,62 @@
+package coverprofile
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMerge_CovData merges a unit test profile with the coverage written by a
+// binary built with go build -cover.
+func TestMerge_CovData(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {\n\tprintln(\"e2e\")\n}\n\nfunc unit() {\n\tprintln(\"unit\")\n}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bin := filepath.Join(t.TempDir(), "app")
+	build := exec.Command("go", "build", "-cover", "-o", bin, ".")
+	build.Dir = root
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build -cover: %v\n%s", err, out)
+	}
+	covDir := t.TempDir()
+	run := exec.Command(bin)
+	run.Env = append(os.Environ(), "GOCOVERDIR="+covDir)
+	if out, err := run.CombinedOutput(); err != nil {
+		t.Fatalf("running the binary: %v\n%s", err, out)
+	}
+	unit := filepath.Join(root, "unit.out")
+	if err := os.WriteFile(unit, []byte("mode: set\nexample.com/m/main.go:7.13,9.2 1 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := Merge(&out, "example.com/m", root, []string{unit}, []string{covDir}); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if lines[0] != "mode: set" {
+		t.Fatalf("Unexpected merged profile:\n%s", out.String())
+	}
+	covered := map[string]bool{}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		start := strings.Split(strings.TrimPrefix(fields[0], "example.com/m/main.go:"), ".")[0]
+		covered[start] = covered[start] || fields[2] == "1"
+	}
+	if !covered["3"] && !covered["4"] || !covered["7"] {
+		t.Errorf("Expected main (e2e) and unit (unit test) covered, got:\n%s", out.String())
+	}
+
+	if err := Merge(&out, "example.com/m", root, nil, []string{filepath.Join(root, "missing")}); err == nil {
+		t.Errorf("Expected error for a missing coverage directory, got nil")
+	}
+}
@@ -0,0 +1,183 @@
+// Package coverprofile merges Go coverage profiles produced by several builds
+// of one module, such as the shards of a linux/windows/darwin CI matrix.
+package coverprofile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// block is the position of a coverage block: file:startLine.startCol,endLine.endCol.
+type block struct {
+	file                                 string
+	startLine, startCol, endLine, endCol int
+}
+
+// Merger accumulates profiles. Blocks are merged by position: counts are
+// added in count and atomic mode, and a block is covered in set mode if it is
+// covered in any profile. Blocks seen in some profiles only, e.g. in files
+// built for one GOOS, are kept.
+type Merger struct {
+	// Module is the module path; file paths are rewritten to it.
+	Module string
+	// Root is the module root on this machine. Absolute file paths are mapped
+	// to the module by the longest path suffix that exists below Root.
+	Root string
+
+	mode   string
+	stmts  map[block]int
+	counts map[block]int
+}
+
+// blockRe matches a profile line: file:startLine.startCol,endLine.endCol numStmt count.
+// The file is matched greedily, so Windows drive letters are kept.
+var blockRe = regexp.MustCompile(`^(.+):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$`)
+
+// Add merges the profile read from r; name is used in errors.
+func (m *Merger) Add(name string, r io.Reader) error {
+	if m.stmts == nil {
+		m.stmts, m.counts = map[block]int{}, map[block]int{}
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if mode, ok := strings.CutPrefix(line, "mode: "); ok {
+			switch {
+			case m.mode == "":
+				m.mode = mode
+			case m.mode != mode:
+				// Counts of different modes cannot be added; only whether a
+				// block ran is kept.
+				m.mode = "set"
+			}
+			continue
+		}
+		match := blockRe.FindStringSubmatch(line)
+		if match == nil {
+			return fmt.Errorf("%s:%d: invalid profile line %q", name, n, line)
+		}
+		nums := make([]int, 6)
+		for i := range nums {
+			nums[i], _ = strconv.Atoi(match[i+2])
+		}
+		b := block{m.Normalize(match[1]), nums[0], nums[1], nums[2], nums[3]}
+		m.stmts[b] = nums[4]
+		m.counts[b] += nums[5]
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %v", name, err)
+	}
+	return nil
+}
+
+// Write writes the merged profile to w, sorted by file and position.
+func (m *Merger) Write(w io.Writer) error {
+	mode := m.mode
+	if mode == "" {
+		mode = "set"
+	}
+	blocks := make([]block, 0, len(m.stmts))
+	for b := range m.stmts {
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		a, b := blocks[i], blocks[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		if a.startLine != b.startLine {
+			return a.startLine < b.startLine
+		}
+		if a.startCol != b.startCol {
+			return a.startCol < b.startCol
+		}
+		if a.endLine != b.endLine {
+			return a.endLine < b.endLine
+		}
+		return a.endCol < b.endCol
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mode: %s\n", mode)
+	for _, b := range blocks {
+		count := m.counts[b]
+		if mode == "set" {
+			count = min(count, 1)
+		}
+		fmt.Fprintf(bw, "%s:%d.%d,%d.%d %d %d\n", b.file, b.startLine, b.startCol, b.endLine, b.endCol, m.stmts[b], count)
+	}
+	return bw.Flush()
+}
+
+// Normalize rewrites a profile file path to the module's import path form:
+// backslashes become slashes, module cache paths lose their prefix and
+// version, and absolute paths into a checkout of the module are mapped to it.
+// Paths of other modules are returned with slashes only.
+func (m *Merger) Normalize(path string) string {
+	p := strings.ReplaceAll(path, `\`, "/")
+	if m.Module == "" || strings.HasPrefix(p, m.Module+"/") {
+		return p
+	}
+
+	// <GOMODCACHE>/example.com/!some!user/m@v1.2.3/pkg/a.go
+	if _, rest, ok := strings.Cut(p, "/pkg/mod/"); ok {
+		if mod, file, ok := strings.Cut(rest, "@"); ok {
+			if _, file, ok := strings.Cut(file, "/"); ok {
+				if q := unescape(mod) + "/" + file; strings.HasPrefix(q, m.Module+"/") {
+					return q
+				}
+			}
+		}
+	}
+
+	// GOPATH mode: <GOPATH>/src/example.com/m/pkg/a.go
+	if i := strings.Index(p, "/"+m.Module+"/"); i >= 0 {
+		return p[i+1:]
+	}
+
+	if m.Root != "" && isAbs(p) {
+		parts := strings.Split(p, "/")
+		for i := 1; i < len(parts); i++ {
+			rel := strings.Join(parts[i:], "/")
+			if info, err := os.Stat(filepath.Join(m.Root, filepath.FromSlash(rel))); err == nil && !info.IsDir() {
+				return m.Module + "/" + rel
+			}
+		}
+	}
+	return p
+}
+
+// isAbs reports whether p, with slashes, is absolute on Unix or Windows.
+func isAbs(p string) bool {
+	return strings.HasPrefix(p, "/") || (len(p) > 2 && p[1] == ':' && p[2] == '/')
+}
+
+// unescape undoes the module cache's case encoding: "!a" stands for "A".
+func unescape(path string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range path {
+		switch {
+		case r == '!':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package coverprofile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMerger_Normalize covers the path forms of the different platforms.
+func TestMerger_Normalize(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "pkg", "a.go"), []byte("package pkg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &Merger{Module: "github.com/Org/m", Root: root}
+
+	cases := map[string]string{
+		"github.com/Org/m/pkg/a.go":                                "github.com/Org/m/pkg/a.go",
+		`github.com\Org\m\pkg\a.go`:                                "github.com/Org/m/pkg/a.go",
+		"/home/runner/go/pkg/mod/github.com/!org/m@v1.2.0/x.go":    "github.com/Org/m/x.go",
+		`C:\Users\runner\go\pkg\mod\github.com\!org\m@v1.2.0\x.go`: "github.com/Org/m/x.go",
+		"/go/src/github.com/Org/m/pkg/a.go":                        "github.com/Org/m/pkg/a.go",
+		`D:\a\m\m\pkg\a.go`:                                        "github.com/Org/m/pkg/a.go",
+		"/Users/runner/work/m/m/pkg/a.go":                          "github.com/Org/m/pkg/a.go",
+		"/Users/runner/work/m/m/pkg/missing.go":                    "/Users/runner/work/m/m/pkg/missing.go",
+		"golang.org/x/text/a.go":                                   "golang.org/x/text/a.go",
+	}
+	for in, want := range cases {
+		if got := m.Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestMerger checks shards are merged into one profile by block.
+func TestMerger(t *testing.T) {
+	m := &Merger{Module: "example.com/m"}
+	linux := "mode: count\nexample.com/m/a.go:3.14,5.2 1 2\nexample.com/m/a_linux.go:3.14,5.2 1 1\n"
+	windows := "mode: count\r\n" + `example.com\m\a.go:3.14,5.2 1 3` + "\r\n" + `example.com\m\a_windows.go:3.14,5.2 1 0` + "\r\n"
+	for name, content := range map[string]string{"linux.out": linux, "windows.out": windows} {
+		if err := m.Add(name, strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	if err := m.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "mode: count\nexample.com/m/a.go:3.14,5.2 1 5\nexample.com/m/a_linux.go:3.14,5.2 1 1\nexample.com/m/a_windows.go:3.14,5.2 1 0\n"
+	if out.String() != want {
+		t.Errorf("Merged profile:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	if err := m.Add("set.out", strings.NewReader("mode: set\nexample.com/m/b.go:1.1,2.2 1 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	m.Write(&out)
+	if !strings.HasPrefix(out.String(), "mode: set\nexample.com/m/a.go:3.14,5.2 1 1\n") {
+		t.Errorf("Expected mixed modes to merge as set, got:\n%s", out.String())
+	}
+
+	if err := m.Add("bad.out", strings.NewReader("mode: set\nnot a block\n")); err == nil || !strings.Contains(err.Error(), "bad.out:2") {
+		t.Errorf("Expected error at bad.out:2, got %v", err)
+	}
+}
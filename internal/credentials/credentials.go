@@ -0,0 +1,231 @@
+// Package credentials resolves the API credentials of code hosting providers
+// from the -token flag, environment variables, ~/.netrc and git credential
+// helpers. Credentials never format as their secret values, so they cannot
+// leak into logs by accident.
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Provider describes where the credentials of a provider are looked up.
+type Provider struct {
+	Name string
+	// TokenVars are the environment variables holding a token, in order.
+	TokenVars []string
+	// UserVar and PasswordVar hold basic authentication credentials.
+	UserVar, PasswordVar string
+	// Basic means netrc and credential helper entries are a username and
+	// password rather than a token in the password field.
+	Basic bool
+	// DefaultHost is the API host looked up when no API URL is configured.
+	DefaultHost string
+}
+
+// Known providers.
+var (
+	GitHub    = Provider{Name: "GitHub", TokenVars: []string{"GITHUB_TOKEN", "GH_TOKEN"}, DefaultHost: "api.github.com"}
+	GitLab    = Provider{Name: "GitLab", TokenVars: []string{"GITLAB_TOKEN"}, DefaultHost: "gitlab.com"}
+	Bitbucket = Provider{Name: "Bitbucket", TokenVars: []string{"BITBUCKET_TOKEN"}, UserVar: "BITBUCKET_USERNAME", PasswordVar: "BITBUCKET_APP_PASSWORD", Basic: true, DefaultHost: "api.bitbucket.org"}
+	Gerrit    = Provider{Name: "Gerrit", UserVar: "GERRIT_USERNAME", PasswordVar: "GERRIT_PASSWORD", Basic: true}
+	Gitea     = Provider{Name: "Gitea", TokenVars: []string{"GITEA_TOKEN"}}
+)
+
+// Credential is a token or a username and password.
+type Credential struct {
+	Token    string
+	Username string
+	Password string
+	// Source names where the credential was found, e.g. "GITHUB_TOKEN".
+	Source string
+}
+
+// String describes the credential without revealing it.
+func (c Credential) String() string {
+	if c.Token != "" {
+		return "token from " + c.Source
+	}
+	return fmt.Sprintf("password of %s from %s", c.Username, c.Source)
+}
+
+// GoString keeps %#v from printing the secret.
+func (c Credential) GoString() string {
+	return "credentials.Credential{" + c.String() + "}"
+}
+
+// Resolver looks up credentials.
+type Resolver struct {
+	// Token is the -token flag; it takes precedence over everything else.
+	Token  string
+	Getenv func(string) string
+	// Netrc is the netrc file; $NETRC or ~/.netrc if empty.
+	Netrc string
+	// Helper asks git credential helpers for the credentials of host; git
+	// credential fill if nil.
+	Helper func(host string) (username, password string, err error)
+}
+
+// Resolve returns the credentials of p for the API at apiURL (the provider's
+// default host if empty), trying in order the -token flag, the provider's
+// environment variables, the netrc file and git credential helpers.
+func (r *Resolver) Resolve(p Provider, apiURL string) (Credential, error) {
+	if r.Token != "" {
+		return Credential{Token: r.Token, Source: "-token"}, nil
+	}
+	for _, name := range p.TokenVars {
+		if v := r.Getenv(name); v != "" {
+			return Credential{Token: v, Source: name}, nil
+		}
+	}
+	if p.UserVar != "" {
+		if user, pass := r.Getenv(p.UserVar), r.Getenv(p.PasswordVar); user != "" && pass != "" {
+			return Credential{Username: user, Password: pass, Source: p.UserVar + "/" + p.PasswordVar}, nil
+		}
+	}
+
+	host := p.DefaultHost
+	if u, err := url.Parse(apiURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	if host == "" {
+		return Credential{}, r.missing(p, host)
+	}
+
+	netrc := r.netrcPath()
+	if login, password, ok := lookupNetrc(netrc, host); ok {
+		return p.credential(login, password, netrc), nil
+	}
+	helper := r.Helper
+	if helper == nil {
+		helper = gitCredentialFill
+	}
+	if user, pass, err := helper(host); err == nil && pass != "" {
+		return p.credential(user, pass, "git credential helper"), nil
+	}
+	return Credential{}, r.missing(p, host)
+}
+
+// credential interprets a login and password found for the provider.
+func (p Provider) credential(login, password, source string) Credential {
+	if p.Basic {
+		return Credential{Username: login, Password: password, Source: source}
+	}
+	return Credential{Token: password, Source: source}
+}
+
+// missing explains where credentials for p can be configured.
+func (r *Resolver) missing(p Provider, host string) error {
+	var where []string
+	where = append(where, "pass -token")
+	if len(p.TokenVars) > 0 {
+		where = append(where, "set "+strings.Join(p.TokenVars, " or "))
+	}
+	if p.UserVar != "" {
+		where = append(where, fmt.Sprintf("set %s and %s", p.UserVar, p.PasswordVar))
+	}
+	if host != "" {
+		where = append(where, fmt.Sprintf("add machine %s to %s", host, r.netrcPath()))
+	}
+	return fmt.Errorf("no %s credentials found: %s", p.Name, strings.Join(where, ", "))
+}
+
+func (r *Resolver) netrcPath() string {
+	if r.Netrc != "" {
+		return r.Netrc
+	}
+	if v := r.Getenv("NETRC"); v != "" {
+		return v
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".netrc"
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// lookupNetrc returns the login and password of host in the netrc file at
+// path, falling back to its default entry.
+func lookupNetrc(path, host string) (login, password string, ok bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+	type entry struct{ login, password string }
+	var def *entry
+	var cur *entry
+	var curHost string
+	found := func() bool { return cur != nil && curHost == host && cur.password != "" }
+
+	tokens := strings.Fields(string(data))
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			if found() {
+				return cur.login, cur.password, true
+			}
+			cur, curHost = &entry{}, ""
+			if i+1 < len(tokens) {
+				i++
+				curHost = tokens[i]
+			}
+		case "default":
+			if found() {
+				return cur.login, cur.password, true
+			}
+			def = &entry{}
+			cur, curHost = def, ""
+		case "login", "password", "account":
+			if i+1 >= len(tokens) || cur == nil {
+				continue
+			}
+			i++
+			if tokens[i-1] == "login" {
+				cur.login = tokens[i]
+			} else if tokens[i-1] == "password" {
+				cur.password = tokens[i]
+			}
+		case "macdef":
+			// Macro definitions run until an empty line; they cannot hold credentials.
+			cur, curHost = nil, ""
+		}
+	}
+	if found() {
+		return cur.login, cur.password, true
+	}
+	if def != nil && def.password != "" {
+		return def.login, def.password, true
+	}
+	return "", "", false
+}
+
+// gitCredentialFill asks the configured git credential helpers, without prompting.
+func gitCredentialFill(host string) (string, string, error) {
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=" + host + "\n\n")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never", "GIT_ASKPASS=true", "SSH_ASKPASS=true")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", "", err
+	}
+
+	var user, pass string
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			user = value
+		case "password":
+			pass = value
+		}
+	}
+	return user, pass, nil
+}
@@ -0,0 +1,83 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func noHelper(host string) (string, string, error) { return "", "", errors.New("no helper") }
+
+func envOf(vars map[string]string) func(string) string {
+	return func(k string) string { return vars[k] }
+}
+
+// TestResolve_Order checks the flag, environment, netrc and helper precedence.
+func TestResolve_Order(t *testing.T) {
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	content := "machine api.github.com login x password netrc-token\n" +
+		"machine bitbucket.example.com\n  login alice\n  password app-pass\n" +
+		"default login anon password fallback\n"
+	if err := os.WriteFile(netrc, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	helper := func(host string) (string, string, error) {
+		if host == "gitea.example.com" {
+			return "bob", "helper-token", nil
+		}
+		return "", "", errors.New("not found")
+	}
+
+	cases := []struct {
+		name     string
+		resolver Resolver
+		provider Provider
+		apiURL   string
+		want     Credential
+	}{
+		{"flag", Resolver{Token: "flag-token", Getenv: envOf(map[string]string{"GITHUB_TOKEN": "env"})}, GitHub, "",
+			Credential{Token: "flag-token", Source: "-token"}},
+		{"second env var", Resolver{Getenv: envOf(map[string]string{"GH_TOKEN": "gh"})}, GitHub, "",
+			Credential{Token: "gh", Source: "GH_TOKEN"}},
+		{"basic env", Resolver{Getenv: envOf(map[string]string{"GERRIT_USERNAME": "u", "GERRIT_PASSWORD": "p"})}, Gerrit, "https://review.example.com",
+			Credential{Username: "u", Password: "p", Source: "GERRIT_USERNAME/GERRIT_PASSWORD"}},
+		{"netrc token", Resolver{Getenv: envOf(nil), Netrc: netrc, Helper: noHelper}, GitHub, "",
+			Credential{Token: "netrc-token", Source: netrc}},
+		{"netrc basic", Resolver{Getenv: envOf(nil), Netrc: netrc, Helper: noHelper}, Bitbucket, "https://bitbucket.example.com/rest",
+			Credential{Username: "alice", Password: "app-pass", Source: netrc}},
+		{"netrc default", Resolver{Getenv: envOf(nil), Netrc: netrc, Helper: noHelper}, GitLab, "https://gitlab.example.com/api/v4",
+			Credential{Token: "fallback", Source: netrc}},
+		{"helper", Resolver{Getenv: envOf(nil), Netrc: filepath.Join(t.TempDir(), "missing"), Helper: helper}, Gitea, "https://gitea.example.com/api/v1",
+			Credential{Token: "helper-token", Source: "git credential helper"}},
+	}
+	for _, c := range cases {
+		got, err := c.resolver.Resolve(c.provider, c.apiURL)
+		if err != nil || got != c.want {
+			t.Errorf("%s: Resolve = %#v, %v; want %#v", c.name, got, err, c.want)
+		}
+	}
+}
+
+// TestResolve_Missing explains where to configure credentials.
+func TestResolve_Missing(t *testing.T) {
+	r := &Resolver{Getenv: envOf(nil), Netrc: "/nonexistent/netrc", Helper: noHelper}
+	_, err := r.Resolve(GitHub, "https://github.example.com/api/v3")
+	want := "no GitHub credentials found: pass -token, set GITHUB_TOKEN or GH_TOKEN, add machine github.example.com to /nonexistent/netrc"
+	if err == nil || err.Error() != want {
+		t.Errorf("Resolve error = %v, want %q", err, want)
+	}
+}
+
+// TestCredential_String never formats the secret.
+func TestCredential_String(t *testing.T) {
+	for _, c := range []Credential{{Token: "s3cret", Source: "GITHUB_TOKEN"}, {Username: "u", Password: "s3cret", Source: "netrc"}} {
+		for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+			if out := fmt.Sprintf(verb, c); strings.Contains(out, "s3cret") {
+				t.Errorf("%s printed the secret: %s", verb, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package credentials
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// GitHubApp exchanges a GitHub App's private key for an installation token.
+type GitHubApp struct {
+	AppID string
+	Key   *rsa.PrivateKey
+	// InstallationID is looked up from the repository if empty.
+	InstallationID string
+	APIURL         string // https://api.github.com if empty
+	Client         *http.Client
+	Now            func() time.Time
+}
+
+// GitHubAppFromEnv configures the app from GITHUB_APP_ID, the PEM key in
+// GITHUB_APP_PRIVATE_KEY or the file GITHUB_APP_PRIVATE_KEY_PATH, and the
+// optional GITHUB_APP_INSTALLATION_ID. It returns nil if GITHUB_APP_ID is unset.
+func GitHubAppFromEnv(getenv func(string) string) (*GitHubApp, error) {
+	id := getenv("GITHUB_APP_ID")
+	if id == "" {
+		return nil, nil
+	}
+	pemData := []byte(getenv("GITHUB_APP_PRIVATE_KEY"))
+	if len(pemData) == 0 {
+		path := getenv("GITHUB_APP_PRIVATE_KEY_PATH")
+		if path == "" {
+			return nil, fmt.Errorf("GITHUB_APP_ID is set but neither GITHUB_APP_PRIVATE_KEY nor GITHUB_APP_PRIVATE_KEY_PATH")
+		}
+		var err error
+		if pemData, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("error reading the GitHub App private key: %v", err)
+		}
+	}
+	key, err := ParseRSAKey(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the GitHub App private key: %v", err)
+	}
+	return &GitHubApp{AppID: id, Key: key, InstallationID: getenv("GITHUB_APP_INSTALLATION_ID")}, nil
+}
+
+// ParseRSAKey parses a PEM-encoded PKCS#1 or PKCS#8 RSA private key.
+func ParseRSAKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA key")
+	}
+	return key, nil
+}
+
+// JWT returns the RS256-signed token authenticating as the app, valid for
+// nine minutes and backdated by one to allow for clock drift.
+func (a *GitHubApp) JWT() (string, error) {
+	now := time.Now()
+	if a.Now != nil {
+		now = a.Now()
+	}
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.AppID,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// InstallationToken returns an installation access token for repo (owner/name).
+func (a *GitHubApp) InstallationToken(ctx context.Context, repo string) (string, error) {
+	jwt, err := a.JWT()
+	if err != nil {
+		return "", fmt.Errorf("github app: %v", err)
+	}
+
+	id := a.InstallationID
+	if id == "" {
+		if repo == "" {
+			return "", fmt.Errorf("github app: a repository or GITHUB_APP_INSTALLATION_ID is required")
+		}
+		var inst struct {
+			ID int64 `json:"id"`
+		}
+		if err := a.do(ctx, http.MethodGet, "/repos/"+repo+"/installation", jwt, &inst); err != nil {
+			return "", fmt.Errorf("github app: error finding the installation on %s: %v", repo, err)
+		}
+		id = strconv.FormatInt(inst.ID, 10)
+	}
+
+	var tok struct {
+		Token string `json:"token"`
+	}
+	if err := a.do(ctx, http.MethodPost, "/app/installations/"+id+"/access_tokens", jwt, &tok); err != nil {
+		return "", fmt.Errorf("github app: error creating an installation token: %v", err)
+	}
+	return tok.Token, nil
+}
+
+// do sends an app-authenticated request and decodes the JSON response.
+func (a *GitHubApp) do(ctx context.Context, method, path, jwt string, out any) error {
+	base := strings.TrimSuffix(a.APIURL, "/")
+	if base == "" {
+		base = "https://api.github.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, base+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	client := a.Client
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: unexpected status %s: %s", method, req.URL, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
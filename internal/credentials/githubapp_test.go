@@ -0,0 +1,89 @@
+package credentials
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGitHubApp_InstallationToken signs a JWT and exchanges it for a token.
+func TestGitHubApp_InstallationToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if len(parts) != 3 || rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig) != nil {
+			http.Error(w, "bad JWT", http.StatusUnauthorized)
+			return
+		}
+		var claims map[string]any
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		json.Unmarshal(payload, &claims)
+		if claims["iss"] != "42" || claims["iat"].(float64) != 940 || claims["exp"].(float64) != 1540 {
+			http.Error(w, "bad claims", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/repos/owner/repo/installation":
+			w.Write([]byte(`{"id": 7}`))
+		case "/app/installations/7/access_tokens":
+			w.Write([]byte(`{"token": "ghs_installation"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	app := &GitHubApp{AppID: "42", Key: key, APIURL: srv.URL, Now: func() time.Time { return time.Unix(1000, 0) }}
+	tok, err := app.InstallationToken(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatalf("InstallationToken failed: %v", err)
+	}
+	if tok != "ghs_installation" || len(paths) != 2 || paths[1] != "POST /app/installations/7/access_tokens" {
+		t.Errorf("Unexpected token %q after %v", tok, paths)
+	}
+
+	app.InstallationID = "8"
+	if _, err := app.InstallationToken(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a 404 for an unknown installation, got %v", err)
+	}
+}
+
+// TestGitHubAppFromEnv reads PKCS#8 keys and reports incomplete configuration.
+func TestGitHubAppFromEnv(t *testing.T) {
+	if app, err := GitHubAppFromEnv(envOf(nil)); app != nil || err != nil {
+		t.Errorf("Expected no app without GITHUB_APP_ID, got %v, %v", app, err)
+	}
+	if _, err := GitHubAppFromEnv(envOf(map[string]string{"GITHUB_APP_ID": "1"})); err == nil {
+		t.Errorf("Expected an error without a private key")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	app, err := GitHubAppFromEnv(envOf(map[string]string{"GITHUB_APP_ID": "1", "GITHUB_APP_PRIVATE_KEY": string(pemKey), "GITHUB_APP_INSTALLATION_ID": "9"}))
+	if err != nil || app.AppID != "1" || app.InstallationID != "9" || !app.Key.Equal(key) {
+		t.Errorf("Unexpected app %+v, %v", app, err)
+	}
+}
@@ -0,0 +1,385 @@
+// Package dashboard serves a small web UI over the run history: the analyzed
+// pull requests and commits with their diff coverage, and a drill-down view
+// of each run with its annotated diff.
+package dashboard
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+)
+
+// Handler serves the dashboard:
+//
+//	/             the most recent runs, filtered by ?repo= and ?branch=
+//	/runs/<id>    one run with its files and annotated diff
+//	/chart.svg    the coverage trend of the listed runs
+//	/rollup       the average diff coverage per ?by=team or repo and ?period=
+//	/api/rollup   the same as JSON
+type Handler struct {
+	Store *history.Store
+	Title string // "Diff coverage" if empty
+	Limit int    // runs listed; 100 if 0
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case r.URL.Path == "/":
+		h.index(w, r)
+	case r.URL.Path == "/chart.svg":
+		h.chart(w, r)
+	case r.URL.Path == "/rollup", r.URL.Path == "/api/rollup":
+		h.rollup(w, r)
+	case strings.HasPrefix(r.URL.Path, "/runs/"):
+		h.run(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// list returns the runs selected by the query, newest first.
+func (h *Handler) list(r *http.Request) ([]history.Record, error) {
+	limit := h.Limit
+	if limit == 0 {
+		limit = 100
+	}
+	repo := r.URL.Query().Get("repo")
+	records, err := h.Store.List(r.URL.Query().Get("branch"), 0)
+	if err != nil {
+		return nil, err
+	}
+	var list []history.Record
+	for _, rec := range records {
+		if repo != "" && rec.Repo != repo {
+			continue
+		}
+		list = append(list, rec)
+		if len(list) == limit {
+			break
+		}
+	}
+	return list, nil
+}
+
+func (h *Handler) index(w http.ResponseWriter, r *http.Request) {
+	records, err := h.list(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.render(w, "index", map[string]any{
+		"Records": records,
+		"Query":   r.URL.RawQuery,
+	})
+}
+
+func (h *Handler) chart(w http.ResponseWriter, r *http.Request) {
+	records, err := h.list(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	c := &history.Chart{Title: r.URL.Query().Get("branch")}
+	if err := c.WriteSVG(w, records); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) run(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/runs/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	rec, err := h.Store.Get(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	diff := ParseDiff(rec.Diff)
+	heatmap := rec.Report != nil && Heatmap(diff, rec.Report)
+	h.render(w, "run", map[string]any{
+		"Record":  rec,
+		"Diff":    diff,
+		"Heatmap": heatmap,
+	})
+}
+
+// rollupPeriods is the number of most recent periods the rollup page shows.
+const rollupPeriods = 12
+
+func (h *Handler) rollup(w http.ResponseWriter, r *http.Request) {
+	by, period := r.URL.Query().Get("by"), r.URL.Query().Get("period")
+	if by == "" {
+		by = "team"
+	}
+	if period == "" {
+		period = "week"
+	}
+	records, err := h.Store.Records()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rollups, err := history.RollUp(records, by, period)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Path == "/api/rollup" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rollups)
+		return
+	}
+
+	// Pivot into one row per key and one column per period, newest last.
+	var periods []time.Time
+	seen := map[time.Time]bool{}
+	for _, ru := range rollups {
+		if !seen[ru.Period] {
+			seen[ru.Period] = true
+			periods = append(periods, ru.Period)
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Before(periods[j]) })
+	periods = periods[max(0, len(periods)-rollupPeriods):]
+	column := map[time.Time]int{}
+	for i, p := range periods {
+		column[p] = i
+	}
+	var rows []rollupRow
+	for i := range rollups {
+		ru := &rollups[i]
+		if len(rows) == 0 || rows[len(rows)-1].Key != ru.Key {
+			rows = append(rows, rollupRow{Key: ru.Key, Cells: make([]*history.Rollup, len(periods))})
+		}
+		if c, ok := column[ru.Period]; ok {
+			rows[len(rows)-1].Cells[c] = ru
+		}
+	}
+	h.render(w, "rollup", map[string]any{
+		"By":      by,
+		"Period":  period,
+		"Periods": periods,
+		"Rows":    rows,
+	})
+}
+
+// rollupRow is one repository or team on the rollup page, with its rollup
+// of each shown period or nil.
+type rollupRow struct {
+	Key   string
+	Cells []*history.Rollup
+}
+
+func (h *Handler) render(w http.ResponseWriter, name string, data map[string]any) {
+	data["Title"] = h.Title
+	if h.Title == "" {
+		data["Title"] = "Diff coverage"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DiffFile is the annotated diff of one file.
+type DiffFile struct {
+	Path  string
+	Lines []DiffLine
+}
+
+// DiffLine is a line of an annotated diff. Class is one of "hunk", "add",
+// "del", "ctx", "covered" and "missed"; Line is the line number in the new
+// file, 0 for hunk headers and deleted lines.
+type DiffLine struct {
+	Class string
+	Text  string
+	Line  int
+	// Hits is the number of times a covered line ran and Heat its shade,
+	// 1 (once) to 5 (1000 times or more), if the profile has counts.
+	Hits int
+	Heat int
+}
+
+// ParseDiff splits a diff annotated by diffcoverage.AnnotateDiff into files
+// and classifies its lines.
+func ParseDiff(diff string) []DiffFile {
+	var files []DiffFile
+	newLine := 0
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "), strings.HasPrefix(line, "--- "),
+			strings.HasPrefix(line, "index "), strings.HasPrefix(line, "new file"), strings.HasPrefix(line, "deleted file"):
+			continue
+		case strings.HasPrefix(line, "+++ "):
+			path := strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			files = append(files, DiffFile{Path: path})
+			continue
+		}
+		if len(files) == 0 {
+			continue
+		}
+		l := DiffLine{Text: line, Class: "ctx", Line: newLine}
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			l.Class, l.Line = "hunk", 0
+			newLine = hunkStart(line)
+		case strings.HasSuffix(line, diffcoverage.CoveredMarker) && strings.HasPrefix(line, "+"):
+			l.Class, l.Text = "covered", strings.TrimSuffix(line, diffcoverage.CoveredMarker)
+		case strings.HasSuffix(line, diffcoverage.UncoveredMarker) && strings.HasPrefix(line, "+"):
+			l.Class, l.Text = "missed", strings.TrimSuffix(line, diffcoverage.UncoveredMarker)
+		case strings.HasPrefix(line, "+"):
+			l.Class = "add"
+		case strings.HasPrefix(line, "-"):
+			l.Class, l.Line = "del", 0
+		}
+		if l.Line > 0 {
+			newLine++
+		}
+		f := &files[len(files)-1]
+		f.Lines = append(f.Lines, l)
+	}
+	return files
+}
+
+// hunkStart returns the first new-file line of the hunk with the header
+// "@@ -a,b +c,d @@".
+func hunkStart(header string) int {
+	_, plus, _ := strings.Cut(header, " +")
+	plus, _, _ = strings.Cut(plus, " ")
+	plus, _, _ = strings.Cut(plus, ",")
+	n, _ := strconv.Atoi(plus)
+	return n
+}
+
+// Heatmap sets the hits and heat of the covered lines of files from the
+// counts in r, and reports whether r has any.
+func Heatmap(files []DiffFile, r *diffcoverage.Report) bool {
+	found := false
+	for _, fr := range r.Files {
+		if len(fr.Hits) == 0 {
+			continue
+		}
+		found = true
+		hits := map[int]int{}
+		for _, h := range fr.Hits {
+			for line := h[0]; line <= h[1]; line++ {
+				hits[line] = h[2]
+			}
+		}
+		for i := range files {
+			// Report paths are relative to the module, diff paths to the repository.
+			if files[i].Path != fr.Path && !strings.HasSuffix(files[i].Path, "/"+fr.Path) {
+				continue
+			}
+			for j := range files[i].Lines {
+				l := &files[i].Lines[j]
+				if n := hits[l.Line]; l.Class == "covered" && n > 0 {
+					l.Hits, l.Heat = n, heat(n)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// heat shades a line by the order of magnitude of its hits: 1 for a line
+// that ran once, up to 5 for 1000 times or more.
+func heat(hits int) int {
+	switch {
+	case hits >= 1000:
+		return 5
+	case hits >= 100:
+		return 4
+	case hits >= 10:
+		return 3
+	case hits > 1:
+		return 2
+	}
+	return 1
+}
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"short":  func(sha string) string { return sha[:min(len(sha), 7)] },
+	"ranges": reporter.FormatRanges,
+	"when":   func(rec history.Record) string { return rec.Time.Local().Format("2006-01-02 15:04") },
+	"date":   func(t time.Time) string { return t.Format(time.DateOnly) },
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title>
+<style>
+body{font-family:system-ui,sans-serif;margin:2em;color:#24292f}
+table{border-collapse:collapse}td,th{padding:.3em .8em;text-align:left;border-bottom:1px solid #d0d7de}
+.pass{color:#1a7f37}.fail{color:#cf222e}
+pre{margin:0;font-size:13px}.diff{border:1px solid #d0d7de;margin-bottom:1.5em}.diff h3{margin:0;padding:.4em;background:#f6f8fa;font-size:14px}
+.hunk{color:#57606a;background:#ddf4ff}.add{background:#f0fff4}.del{background:#ffebe9}
+.covered{background:#aceebb}.missed{background:#ffcecb}
+.heat1{background:#dafbe1}.heat2{background:#aceebb}.heat3{background:#6fdd8b}.heat4{background:#4ac26b}.heat5{background:#2da44e;color:#fff}
+.legend pre{display:inline-block;padding:0 .5em}
+</style></head><body>
+{{end}}
+
+{{define "index"}}{{template "head" .}}
+<h1>{{.Title}}</h1>
+<p><a href="/rollup">Rollup by team</a> &middot; <a href="/rollup?by=repo">by repository</a></p>
+{{if .Records}}
+<p><img src="/chart.svg{{if .Query}}?{{.Query}}{{end}}" alt="coverage trend"></p>
+<table>
+<tr><th>Run</th><th>Time</th><th>Repository</th><th>PR</th><th>Branch</th><th>Commit</th><th>Diff coverage</th><th>Lines</th><th>Result</th></tr>
+{{range .Records}}<tr>
+<td><a href="/runs/{{.ID}}">#{{.ID}}</a></td><td>{{when .}}</td>
+<td><a href="/?repo={{.Repo}}">{{.Repo}}</a></td><td>{{.PR}}</td>
+<td><a href="/?branch={{.Branch}}">{{.Branch}}</a></td><td><code>{{short .Commit}}</code></td>
+<td>{{printf "%.2f%%" .Report.Coverage}}</td><td>{{.Report.CoveredLines}}/{{.Report.TotalLines}}</td>
+<td>{{if .Report.Passed}}<span class="pass">pass</span>{{else}}<span class="fail">fail</span>{{end}}</td>
+</tr>{{end}}
+</table>
+{{else}}<p>No runs recorded yet.</p>{{end}}
+</body></html>
+{{end}}
+
+{{define "rollup"}}{{template "head" .}}
+<p><a href="/">&larr; all runs</a></p>
+<h1>Average diff coverage by {{.By}} and {{.Period}}</h1>
+<p>Group by <a href="/rollup?by=team&period={{.Period}}">team</a> or <a href="/rollup?by=repo&period={{.Period}}">repository</a>;
+per <a href="/rollup?by={{.By}}&period=day">day</a>, <a href="/rollup?by={{.By}}&period=week">week</a> or <a href="/rollup?by={{.By}}&period=month">month</a>.</p>
+{{if .Rows}}<table>
+<tr><th>{{if eq .By "team"}}Team{{else}}Repository{{end}}</th>{{range .Periods}}<th>{{date .}}</th>{{end}}</tr>
+{{range .Rows}}<tr><td>{{if .Key}}{{.Key}}{{else}}<em>none</em>{{end}}</td>
+{{range .Cells}}<td>{{if .}}<span title="{{.Runs}} runs, {{.CoveredLines}}/{{.TotalLines}} lines">{{printf "%.2f%%" .Coverage}}</span>{{else}}-{{end}}</td>{{end}}</tr>
+{{end}}</table>
+{{else}}<p>No runs with counted lines recorded yet.</p>{{end}}
+</body></html>
+{{end}}
+
+{{define "run"}}{{template "head" .}}{{with .Record}}
+<p><a href="/">&larr; all runs</a></p>
+<h1>Run #{{.ID}}</h1>
+<p>{{if .Repo}}{{.Repo}} {{end}}{{if .PR}}PR #{{.PR}} {{end}}{{if .Branch}}on {{.Branch}} {{end}}at <code>{{short .Commit}}</code>, {{when .}}</p>
+<p>Diff coverage <strong class="{{if .Report.Passed}}pass{{else}}fail{{end}}">{{printf "%.2f%%" .Report.Coverage}}</strong>
+(minimum {{printf "%.2f%%" .Report.MinCoverage}}), {{.Report.CoveredLines}} of {{.Report.TotalLines}} lines covered.</p>
+{{if .Report.Files}}<table>
+<tr><th>File</th><th>Covered</th><th>Coverage</th><th>Uncovered lines</th></tr>
+{{range .Report.Files}}<tr><td>{{.Path}}</td><td>{{.CoveredLines}}/{{.TotalLines}}</td><td>{{printf "%.2f%%" .Coverage}}</td><td>{{ranges .Uncovered}}</td></tr>{{end}}
+</table>{{end}}
+{{end}}
+{{if .Heatmap}}<p class="legend">Covered lines are shaded by the times they ran:
+<pre class="heat1">once</pre><pre class="heat2">2-9</pre><pre class="heat3">10-99</pre><pre class="heat4">100-999</pre><pre class="heat5">1000+</pre></p>{{end}}
+{{range .Diff}}<div class="diff"><h3>{{.Path}}</h3>{{range .Lines}}<pre class="{{.Class}}{{if .Heat}} heat{{.Heat}}{{end}}"{{if .Hits}} title="ran {{.Hits}} times"{{end}}>{{.Text}}</pre>{{end}}</div>{{end}}
+</body></html>
+{{end}}
+`))
@@ -0,0 +1,146 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+)
+
+const annotated = "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,3 @@\n func A() {\n+\tx()|COVERED\n+\ty()|MISS\n-\tz()\n"
+
+// TestParseDiff classifies the lines of an annotated diff per file.
+func TestParseDiff(t *testing.T) {
+	files := ParseDiff(annotated)
+	if len(files) != 1 || files[0].Path != "a.go" {
+		t.Fatalf("Unexpected files %+v", files)
+	}
+	var classes []string
+	for _, l := range files[0].Lines {
+		classes = append(classes, l.Class)
+	}
+	if got := strings.Join(classes, ","); got != "hunk,ctx,covered,missed,del" {
+		t.Errorf("Classes = %s", got)
+	}
+	if files[0].Lines[3].Text != "+\ty()" {
+		t.Errorf("Expected the marker to be stripped, got %q", files[0].Lines[3].Text)
+	}
+}
+
+// TestHeatmap shades covered lines by their order of magnitude of hits.
+func TestHeatmap(t *testing.T) {
+	diff := "+++ b/mod/a.go\n@@ -1,0 +10,3 @@\n+\tx()|COVERED\n+\ty()|COVERED\n+\tz()|MISS\n"
+	files := ParseDiff(diff)
+	r := &diffcoverage.Report{Files: []diffcoverage.FileReport{{Path: "a.go", Hits: [][3]int{{10, 10, 1}, {11, 11, 250}}}}}
+	if !Heatmap(files, r) {
+		t.Fatalf("Expected a heatmap")
+	}
+	var got []DiffLine
+	for _, l := range files[0].Lines[1:] {
+		got = append(got, DiffLine{Line: l.Line, Hits: l.Hits, Heat: l.Heat})
+	}
+	want := []DiffLine{{Line: 10, Hits: 1, Heat: 1}, {Line: 11, Hits: 250, Heat: 4}, {Line: 12}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %+v, want %+v", got, want)
+	}
+	if Heatmap(ParseDiff(diff), &diffcoverage.Report{Files: []diffcoverage.FileReport{{Path: "a.go"}}}) {
+		t.Errorf("Expected no heatmap without hits")
+	}
+}
+
+// TestHandler serves the run list, a run's drill-down view and the chart.
+func TestHandler(t *testing.T) {
+	store := &history.Store{Path: filepath.Join(t.TempDir(), "history.jsonl")}
+	for _, rec := range []*history.Record{
+		{Repo: "o/r", PR: "7", Branch: "feature", Commit: "0123456789", Report: &diffcoverage.Report{Coverage: 50, TotalLines: 2, CoveredLines: 1}, Diff: annotated},
+		{Repo: "o/other", Branch: "main", Commit: "abcdef0123", Report: &diffcoverage.Report{Coverage: 100, Passed: true}},
+	} {
+		if err := store.Add(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &Handler{Store: store}
+
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	code, body := get("/")
+	if code != http.StatusOK || !strings.Contains(body, `href="/runs/1"`) || !strings.Contains(body, `href="/runs/2"`) {
+		t.Errorf("Unexpected index (%d): %s", code, body)
+	}
+	if _, body := get("/?repo=o/r"); strings.Contains(body, `href="/runs/2"`) || !strings.Contains(body, "0123456") {
+		t.Errorf("Expected only o/r runs: %s", body)
+	}
+	code, body = get("/runs/1")
+	if code != http.StatusOK || !strings.Contains(body, `<pre class="missed">&#43;	y()</pre>`) || !strings.Contains(body, "PR #7") {
+		t.Errorf("Unexpected run view (%d): %s", code, body)
+	}
+	if code, body := get("/chart.svg"); code != http.StatusOK || !strings.HasPrefix(body, "<svg") {
+		t.Errorf("Unexpected chart (%d): %.100s", code, body)
+	}
+	if code, _ := get("/runs/9"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown run, got %d", code)
+	}
+}
+
+// TestIngest records runs uploaded by the server integration and rolls them up.
+func TestIngest(t *testing.T) {
+	store := &history.Store{Path: filepath.Join(t.TempDir(), "history.jsonl")}
+	mux := http.NewServeMux()
+	mux.Handle("/", &Handler{Store: store})
+	mux.Handle("/api/runs", &Ingest{Store: store, Secret: "s3cret"})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	report := &diffcoverage.Report{Coverage: 50, TotalLines: 4, CoveredLines: 2}
+	for _, repo := range []string{"o/a", "o/b"} {
+		s := &reporter.Server{URL: srv.URL + "/", Secret: "s3cret", Repo: repo, Team: "payments"}
+		if err := s.Publish(context.Background(), report); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bad := &reporter.Server{URL: srv.URL, Secret: "wrong", Repo: "o/c"}
+	if err := bad.Publish(context.Background(), report); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected an invalid signature to be rejected, got %v", err)
+	}
+
+	records, err := store.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].Repo != "o/b" || records[1].Team != "payments" || records[1].ID != 2 {
+		t.Fatalf("Unexpected records %+v", records)
+	}
+
+	resp, err := http.Get(srv.URL + "/rollup?by=repo&period=month")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "<td>o/b</td>") || !strings.Contains(string(body), "50.00%") {
+		t.Errorf("Unexpected rollup page (%d): %s", resp.StatusCode, body)
+	}
+	resp, err = http.Get(srv.URL + "/api/rollup?by=team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rollups []history.Rollup
+	json.NewDecoder(resp.Body).Decode(&rollups)
+	resp.Body.Close()
+	if len(rollups) != 1 || rollups[0].Key != "payments" || rollups[0].Runs != 2 {
+		t.Errorf("Unexpected rollups %+v", rollups)
+	}
+}
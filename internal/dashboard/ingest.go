@@ -0,0 +1,61 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+)
+
+// maxUpload bounds the size of an uploaded run.
+const maxUpload = 32 << 20
+
+// Ingest records runs uploaded by the server integration (see
+// reporter.Server) of many repositories: a POST of a JSON history.Record,
+// signed with Secret like the webhook integration.
+type Ingest struct {
+	Store  *history.Store
+	Secret string
+	Now    func() time.Time
+}
+
+func (h *Ingest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUpload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !reporter.Verify(body, h.Secret, r.Header.Get(reporter.SignatureHeader)) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	var rec history.Record
+	if err := json.Unmarshal(body, &rec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rec.Repo == "" || rec.Report == nil {
+		http.Error(w, "a run needs a repo and a report", http.StatusBadRequest)
+		return
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+		if h.Now != nil {
+			rec.Time = h.Now()
+		}
+	}
+	if err := h.Store.Add(&rec); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int{"id": rec.ID})
+}
@@ -0,0 +1,79 @@
+package diffcoverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Markers appended to added lines by AnnotateDiff.
+const (
+	CoveredMarker   = "|COVERED"
+	UncoveredMarker = "|MISS"
+	// HunkMarker precedes the coverage of a hunk appended to its header,
+	// e.g. "|COVERAGE 50.00% (1/2)".
+	HunkMarker = "|COVERAGE "
+)
+
+// AnnotateDiff re-emits the diff at diffPath to w, appending a coverage marker
+// to every added line that counts towards diff coverage and the coverage of
+// the hunk to the headers of hunks with counted lines. All other lines are
+// copied unchanged, so the result is still readable in any diff viewer.
+func AnnotateDiff(w io.Writer, diffPath string, a *Analysis) error {
+	f, err := os.Open(diffPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	out := bufio.NewWriter(w)
+
+	var currentFile string
+	var plusLine int
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		marker := ""
+
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			if file, ok := diffFileKey(line, a.ModuleName); ok {
+				currentFile = file
+			}
+		case hunkHeaderRegex.MatchString(line):
+			matches := hunkHeaderRegex.FindStringSubmatch(line)
+			plusLine, _ = strconv.Atoi(matches[2])
+			if start, end, ok := hunkRange(matches); ok {
+				if hr := a.hunkReport(currentFile, start, end); hr.TotalLines > 0 {
+					marker = fmt.Sprintf("%s%.2f%% (%d/%d)", HunkMarker, hr.Coverage, hr.CoveredLines, hr.TotalLines)
+				}
+			}
+		case strings.HasPrefix(line, "+"):
+			if a.Diff.NewLines[currentFile][plusLine] {
+				switch a.Status(a.RelPath(currentFile), plusLine) {
+				case LineCovered:
+					marker = CoveredMarker
+				case LineUncovered:
+					marker = UncoveredMarker
+				}
+			}
+			plusLine++
+		case line == "" || line[0] == ' ':
+			// Context lines of diffs with --unified > 0 advance the new line number too.
+			plusLine++
+		}
+
+		if _, err := out.WriteString(line + marker + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,140 @@
+package diffcoverage
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAnnotateDiff checks that only counted added lines receive markers.
+func TestAnnotateDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "foo.go"), `package foo
+
+func Foo() int {
+	a := 1
+	b := 2
+	return a + b
+}
+
+var x = 1
+`)
+	writeCoverFile(t, tmpDir, "cover.out", `mode: set
+github.com/example/module/pkg/foo.go:4.0,4.10 1 1
+github.com/example/module/pkg/foo.go:5.0,6.10 2 0
+`)
+	diffContent := strings.Join([]string{
+		"diff --git a/pkg/foo.go b/pkg/foo.go",
+		"--- a/pkg/foo.go",
+		"+++ b/pkg/foo.go",
+		"@@ -3,0 +4,2 @@",
+		"+\ta := 1",
+		"+\tb := 2",
+		"@@ -8,0 +9,1 @@",
+		"+var x = 1",
+		"+++ b/README.md",
+		"@@ -1,0 +1,1 @@",
+		"+docs",
+	}, "\n") + "\n"
+	writeDiffFile(t, tmpDir, "diff.diff", diffContent)
+
+	a, err := Analyze(filepath.Join(tmpDir, "cover.out"), filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := AnnotateDiff(&buf, filepath.Join(tmpDir, "diff.diff"), a); err != nil {
+		t.Fatalf("AnnotateDiff failed: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"diff --git a/pkg/foo.go b/pkg/foo.go",
+		"--- a/pkg/foo.go",
+		"+++ b/pkg/foo.go",
+		"@@ -3,0 +4,2 @@|COVERAGE 50.00% (1/2)",
+		"+\ta := 1|COVERED",
+		"+\tb := 2|MISS",
+		"@@ -8,0 +9,1 @@",
+		"+var x = 1",
+		"+++ b/README.md",
+		"@@ -1,0 +1,1 @@",
+		"+docs",
+	}, "\n") + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("AnnotateDiff output mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestAnnotateDiff_FileOpenError covers the missing diff file case.
+func TestAnnotateDiff_FileOpenError(t *testing.T) {
+	a := &Analysis{ModuleName: "github.com/example/module"}
+	var buf bytes.Buffer
+	if err := AnnotateDiff(&buf, "/this/path/does/not/exist.diff", a); err == nil {
+		t.Fatalf("Expected error for non-existent diff file, got nil")
+	}
+}
+
+// TestAnnotateDiff_ContextLines checks that context lines of a --unified=3
+// diff advance the line numbers the markers are placed by.
+func TestAnnotateDiff_ContextLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "foo.go"), `package foo
+
+func Foo() int {
+	x := 0
+	a := 1
+	y := 0
+	b := 2
+	return a + b + x + y
+}
+`)
+	writeCoverFile(t, tmpDir, "cover.out", `mode: set
+github.com/example/module/pkg/foo.go:4.0,5.10 2 1
+github.com/example/module/pkg/foo.go:6.0,8.10 3 0
+`)
+	diffContent := strings.Join([]string{
+		"diff --git a/pkg/foo.go b/pkg/foo.go",
+		"--- a/pkg/foo.go",
+		"+++ b/pkg/foo.go",
+		"@@ -3,5 +3,6 @@",
+		" func Foo() int {",
+		" \tx := 0",
+		"-\ta := 0",
+		"+\ta := 1",
+		" \ty := 0",
+		"+\tb := 2",
+		" \treturn a + b + x + y",
+	}, "\n") + "\n"
+	writeDiffFile(t, tmpDir, "diff.diff", diffContent)
+
+	a, err := Analyze(filepath.Join(tmpDir, "cover.out"), filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := AnnotateDiff(&buf, filepath.Join(tmpDir, "diff.diff"), a); err != nil {
+		t.Fatalf("AnnotateDiff failed: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"diff --git a/pkg/foo.go b/pkg/foo.go",
+		"--- a/pkg/foo.go",
+		"+++ b/pkg/foo.go",
+		"@@ -3,5 +3,6 @@|COVERAGE 50.00% (1/2)",
+		" func Foo() int {",
+		" \tx := 0",
+		"-\ta := 0",
+		"+\ta := 1|COVERED",
+		" \ty := 0",
+		"+\tb := 2|MISS",
+		" \treturn a + b + x + y",
+	}, "\n") + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("AnnotateDiff output mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
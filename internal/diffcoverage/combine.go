@@ -0,0 +1,173 @@
+package diffcoverage
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// TargetReport summarizes the counted lines of one target: a source root of
+// a combined report or a module of a split one.
+type TargetReport struct {
+	Name         string  `json:"name"`
+	Module       string  `json:"module,omitempty"`
+	TotalLines   int     `json:"totalLines"`
+	CoveredLines int     `json:"coveredLines"`
+	Coverage     float64 `json:"coverage"`
+	Passed       bool    `json:"passed"`
+	Grade        string  `json:"grade,omitempty"`
+}
+
+// Combine aggregates the reports of several targets, named by their source
+// root relative to a common root, into one report whose paths are relative
+// to that root. The combined report makes a single gate decision: it passes
+// if the aggregated coverage meets minCoverage and nothing else fails, such
+// as an expired exception or a failed policy of a target.
+func Combine(names []string, reports []*Report, minCoverage float64) *Report {
+	c := NewReport(minCoverage)
+	owners := map[string]*OwnerReport{}
+	for i, r := range reports {
+		name := names[i]
+		prefix := ""
+		if name != "." && name != "" {
+			prefix = strings.TrimSuffix(name, "/") + "/"
+		}
+		join := func(p string) string { return prefix + p }
+
+		c.Targets = append(c.Targets, TargetReport{
+			Name:         name,
+			Module:       r.Module,
+			TotalLines:   r.TotalLines,
+			CoveredLines: r.CoveredLines,
+			Coverage:     r.Coverage,
+			Passed:       r.Passed,
+		})
+		c.TotalLines += r.TotalLines
+		c.CoveredLines += r.CoveredLines
+		c.statements += r.statements
+		c.coveredStatements += r.coveredStatements
+		c.ChangedFunctions += r.ChangedFunctions
+		c.CoveredFunctions += r.CoveredFunctions
+		c.MinFunctionCoverage = max(c.MinFunctionCoverage, r.MinFunctionCoverage)
+		if s := r.FunctionScope; s != nil {
+			if c.FunctionScope == nil {
+				c.FunctionScope = &FunctionScope{}
+			}
+			c.FunctionScope.TotalLines += s.TotalLines
+			c.FunctionScope.CoveredLines += s.CoveredLines
+			c.FunctionScope.MinCoverage = max(c.FunctionScope.MinCoverage, s.MinCoverage)
+			c.FunctionScope.MaxAgeDays = max(c.FunctionScope.MaxAgeDays, s.MaxAgeDays)
+			c.FunctionScope.OldLines += s.OldLines
+			for _, fn := range s.Functions {
+				fn.File = join(fn.File)
+				c.FunctionScope.Functions = append(c.FunctionScope.Functions, fn)
+			}
+		}
+		c.TestFilesRequired = c.TestFilesRequired || r.TestFilesRequired
+		if c.Commit == "" {
+			c.Commit, c.ProfileCommit = r.Commit, r.ProfileCommit
+		}
+
+		for _, f := range r.Files {
+			f.Path = join(f.Path)
+			c.Files = append(c.Files, f)
+		}
+		for _, p := range r.Uninstrumented {
+			p.Package = join(p.Package)
+			c.Uninstrumented = append(c.Uninstrumented, p)
+		}
+		for _, o := range r.Owners {
+			if sum := owners[o.Owner]; sum != nil {
+				sum.TotalLines += o.TotalLines
+				sum.CoveredLines += o.CoveredLines
+				continue
+			}
+			o := o
+			owners[o.Owner] = &o
+		}
+		c.Authors = append(c.Authors, r.Authors...)
+		for _, s := range r.Skeletons {
+			s.File = join(s.File)
+			c.Skeletons = append(c.Skeletons, s)
+		}
+		for _, s := range r.Survivors {
+			s.File = join(s.File)
+			c.Survivors = append(c.Survivors, s)
+		}
+		for _, f := range r.FilesWithoutTests {
+			c.FilesWithoutTests = append(c.FilesWithoutTests, join(f))
+		}
+		for _, p := range r.ProfileProblems {
+			c.ProfileProblems = append(c.ProfileProblems, name+": "+p)
+		}
+		c.ProfileCheckFails = c.ProfileCheckFails || r.ProfileCheckFails
+		for _, u := range r.UntestedChanges {
+			u.File = join(u.File)
+			c.UntestedChanges = append(c.UntestedChanges, u)
+		}
+		for _, e := range r.Exceptions {
+			e.Path = join(e.Path)
+			c.Exceptions = append(c.Exceptions, e)
+		}
+		for _, p := range r.Policies {
+			p.Name = name + ": " + p.Name
+			c.Policies = append(c.Policies, p)
+		}
+		c.Phases = append(c.Phases, r.Phases...)
+	}
+
+	for _, o := range owners {
+		o.Coverage = percent(o.CoveredLines, o.TotalLines)
+		o.Passed = o.Coverage >= o.MinCoverage
+		c.Owners = append(c.Owners, *o)
+	}
+	sort.Slice(c.Owners, func(i, j int) bool { return c.Owners[i].Owner < c.Owners[j].Owner })
+	sort.Slice(c.Files, func(i, j int) bool { return c.Files[i].Path < c.Files[j].Path })
+	c.Coverage = percent(c.CoveredLines, c.TotalLines)
+	c.ProjectCoverage = percent(c.coveredStatements, c.statements)
+	c.FunctionCoverage = percent(c.CoveredFunctions, c.ChangedFunctions)
+	if s := c.FunctionScope; s != nil {
+		s.Coverage = percent(s.CoveredLines, s.TotalLines)
+		s.Passed = s.Coverage >= s.MinCoverage
+	}
+	c.Passed = len(c.failures()) == 0
+	if len(reports) > 0 && reports[0].grades != nil {
+		c.ApplyGrades(reports[0].grades)
+	}
+	if len(reports) > 0 && reports[0].Overrides != nil {
+		c.ApplyOverrides(reports[0].Overrides)
+	}
+	return c
+}
+
+// SplitModules breaks the report down by the module each file belongs to,
+// the one with the longest directory containing it. It does not change the
+// gate decision, which stays with the whole report.
+func (r *Report) SplitModules(modules []Module) {
+	r.Targets = nil
+	byDir := map[string]*TargetReport{}
+	for _, f := range r.Files {
+		var m Module
+		for _, candidate := range modules {
+			if (candidate.Dir == "" || strings.HasPrefix(f.Path, candidate.Dir+"/")) && len(candidate.Dir) >= len(m.Dir) {
+				m = candidate
+			}
+		}
+		t := byDir[m.Dir]
+		if t == nil {
+			t = &TargetReport{Name: path.Clean("./" + m.Dir), Module: m.Path}
+			byDir[m.Dir] = t
+		}
+		t.TotalLines += f.TotalLines
+		t.CoveredLines += f.CoveredLines
+	}
+	for _, t := range byDir {
+		t.Coverage = percent(t.CoveredLines, t.TotalLines)
+		t.Passed = t.Coverage >= r.MinCoverage
+		r.Targets = append(r.Targets, *t)
+	}
+	sort.Slice(r.Targets, func(i, j int) bool { return r.Targets[i].Name < r.Targets[j].Name })
+	if r.grades != nil {
+		r.ApplyGrades(r.grades)
+	}
+}
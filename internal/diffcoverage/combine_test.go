@@ -0,0 +1,64 @@
+package diffcoverage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCombine aggregates the targets and prefixes their paths.
+func TestCombine(t *testing.T) {
+	api := NewReport(80)
+	api.Module = "example.com/api"
+	api.Files = []FileReport{{Path: "a.go", TotalLines: 4, CoveredLines: 4, Coverage: 100}}
+	api.TotalLines, api.CoveredLines, api.Coverage = 4, 4, 100
+	api.Policies = []PolicyResult{{Name: "p", Passed: false}}
+	api.Passed = false
+
+	worker := NewReport(80)
+	worker.Files = []FileReport{{Path: "pkg/w.go", TotalLines: 6, CoveredLines: 3, Coverage: 50, Uncovered: [][2]int{{4, 6}}}}
+	worker.TotalLines, worker.CoveredLines, worker.Coverage = 6, 3, 50
+	worker.Passed = false
+
+	c := Combine([]string{"services/api", "services/worker"}, []*Report{api, worker}, 70)
+	if c.TotalLines != 10 || c.CoveredLines != 7 || c.Coverage != 70 {
+		t.Errorf("Unexpected totals %d/%d %.2f", c.CoveredLines, c.TotalLines, c.Coverage)
+	}
+	if c.Files[0].Path != "services/api/a.go" || c.Files[1].Path != "services/worker/pkg/w.go" {
+		t.Errorf("Unexpected files %+v", c.Files)
+	}
+	wantTargets := []TargetReport{
+		{Name: "services/api", Module: "example.com/api", TotalLines: 4, CoveredLines: 4, Coverage: 100},
+		{Name: "services/worker", TotalLines: 6, CoveredLines: 3, Coverage: 50},
+	}
+	if !reflect.DeepEqual(c.Targets, wantTargets) {
+		t.Errorf("Targets = %+v, want %+v", c.Targets, wantTargets)
+	}
+	// The aggregate meets the minimum, but the policy of a target still fails.
+	if c.Passed || c.Err() == nil || !strings.Contains(c.Err().Error(), `policy "services/api: p" failed`) || strings.Contains(c.Err().Error(), "below") {
+		t.Errorf("Unexpected gate %v: %v", c.Passed, c.Err())
+	}
+
+	api.Policies, api.Passed = nil, true
+	if c = Combine([]string{"services/api", "services/worker"}, []*Report{api, worker}, 70); !c.Passed {
+		t.Errorf("Expected the combined report to pass: %v", c.Err())
+	}
+}
+
+// TestReport_SplitModules assigns files to the innermost module.
+func TestReport_SplitModules(t *testing.T) {
+	r := NewReport(80)
+	r.Files = []FileReport{
+		{Path: "main.go", TotalLines: 2, CoveredLines: 2},
+		{Path: "tools/gen/gen.go", TotalLines: 2, CoveredLines: 1},
+		{Path: "toolsx/x.go", TotalLines: 1, CoveredLines: 1},
+	}
+	r.SplitModules([]Module{{Path: "example.com/m", Dir: ""}, {Path: "example.com/m/tools", Dir: "tools"}})
+	want := []TargetReport{
+		{Name: ".", Module: "example.com/m", TotalLines: 3, CoveredLines: 3, Coverage: 100, Passed: true},
+		{Name: "tools", Module: "example.com/m/tools", TotalLines: 2, CoveredLines: 1, Coverage: 50},
+	}
+	if !reflect.DeepEqual(r.Targets, want) {
+		t.Errorf("Targets = %+v, want %+v", r.Targets, want)
+	}
+}
@@ -0,0 +1,150 @@
+package diffcoverage
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// CoverIgnoreFile lists gitignore-style patterns of files that are not
+// counted. It is looked up in the source root and in every directory
+// between it and a changed file; patterns are relative to the directory of
+// their file.
+const CoverIgnoreFile = ".coverignore"
+
+// ignoreRule is one pattern of a CoverIgnoreFile.
+type ignoreRule struct {
+	segments []string // of an anchored pattern
+	pattern  string   // matched against the base name otherwise
+	anchored bool
+	negate   bool
+	dirOnly  bool
+}
+
+// CoverIgnore matches paths against the CoverIgnoreFiles of a source root,
+// reading each file once.
+type CoverIgnore struct {
+	root  string
+	rules map[string][]ignoreRule // by slash-separated directory, "" for the root
+}
+
+// NewCoverIgnore returns the matcher of the CoverIgnoreFiles below root.
+func NewCoverIgnore(root string) *CoverIgnore {
+	return &CoverIgnore{root: root, rules: map[string][]ignoreRule{}}
+}
+
+// Match reports whether the file, relative to the source root, is ignored.
+// As with .gitignore, the last matching pattern wins, patterns of deeper
+// files take precedence, and "!" cannot re-include a file whose directory
+// is ignored.
+func (c *CoverIgnore) Match(rel string) (bool, error) {
+	parts := strings.Split(path.Clean(rel), "/")
+	for i := 1; i <= len(parts); i++ {
+		ignored, err := c.matchPath(parts[:i], i < len(parts))
+		if err != nil || ignored {
+			return ignored, err
+		}
+	}
+	return false, nil
+}
+
+// matchPath applies the rules of every directory above parts to it.
+func (c *CoverIgnore) matchPath(parts []string, isDir bool) (bool, error) {
+	ignored := false
+	for d := 0; d < len(parts); d++ {
+		rules, err := c.load(strings.Join(parts[:d], "/"))
+		if err != nil {
+			return false, err
+		}
+		for _, r := range rules {
+			if r.match(parts[d:], isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+	return ignored, nil
+}
+
+// load returns the rules of the CoverIgnoreFile in dir, if any.
+func (c *CoverIgnore) load(dir string) ([]ignoreRule, error) {
+	if rules, ok := c.rules[dir]; ok {
+		return rules, nil
+	}
+	file := filepath.Join(c.root, filepath.FromSlash(dir), CoverIgnoreFile)
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error reading %s: %v", file, err)
+	}
+	rules := parseCoverIgnore(data)
+	c.rules[dir] = rules
+	return rules, nil
+}
+
+// parseCoverIgnore parses the lines of a CoverIgnoreFile: blank lines and
+// "#" comments are skipped, "!" negates, a trailing "/" matches directories
+// only and a pattern with a leading or inner "/" is anchored to the file's
+// directory, otherwise it matches the name at any depth.
+func parseCoverIgnore(data []byte) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var r ignoreRule
+		if line[0] == '!' {
+			r.negate, line = true, line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:] // escaped leading "#" or "!"
+		}
+		if trimmed, ok := strings.CutSuffix(line, "/"); ok {
+			r.dirOnly, line = true, trimmed
+		}
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, "/") {
+			r.anchored = true
+			r.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+		} else {
+			r.pattern = line
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// match reports whether the rule matches the path rel, split into segments
+// relative to the rule's directory.
+func (r ignoreRule) match(rel []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.anchored {
+		return matchSegments(r.segments, rel)
+	}
+	ok, _ := path.Match(r.pattern, rel[len(rel)-1])
+	return ok
+}
+
+// excludeCoverIgnored drops the changed files ignored by CoverIgnoreFiles.
+func (a *Analysis) excludeCoverIgnored() error {
+	ignore := NewCoverIgnore(a.SourceRoot)
+	for file := range a.Diff.NewLines {
+		ignored, err := ignore.Match(a.RelPath(file))
+		if err != nil {
+			return err
+		}
+		if ignored {
+			delete(a.Diff.NewLines, file)
+			delete(a.Diff.Added, file)
+		}
+	}
+	return nil
+}
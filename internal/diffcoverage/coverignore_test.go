@@ -0,0 +1,46 @@
+package diffcoverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCoverIgnore_Match checks gitignore semantics across nested files.
+func TestCoverIgnore_Match(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(CoverIgnoreFile, "# generated code\n*.pb.go\n/tools/\nmocks/\n!keep.pb.go\n")
+	write("api/"+CoverIgnoreFile, "gen/*.go\n!mocks/client.go\n")
+
+	ignore := NewCoverIgnore(root)
+	cases := map[string]bool{
+		"main.go":             false,
+		"api/v1/svc.pb.go":    true,
+		"api/v1/keep.pb.go":   false,
+		"tools/lint/main.go":  true,
+		"cmd/tools/main.go":   false,
+		"pkg/mocks/store.go":  true,
+		"api/gen/client.go":   true,
+		"api/gen/sub/x.go":    false,
+		"gen/client.go":       false,
+		"api/mocks/client.go": true, // its directory is ignored
+	}
+	for rel, want := range cases {
+		got, err := ignore.Match(rel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Match(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
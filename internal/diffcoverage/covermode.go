@@ -0,0 +1,26 @@
+package diffcoverage
+
+import "fmt"
+
+// coverModes ranks the go test -covermode values: count also tells how often
+// a block ran and atomic keeps the counts exact in parallel tests, where set
+// and count lose updates under -race.
+var coverModes = map[string]int{"set": 1, "count": 2, "atomic": 3}
+
+// ValidCoverMode reports whether mode is a go test -covermode value.
+func ValidCoverMode(mode string) bool {
+	return coverModes[mode] > 0
+}
+
+// RequireCoverMode fails if the profile was generated with a weaker mode than
+// required.
+func (a *Analysis) RequireCoverMode(required string) error {
+	if coverModes[a.Coverage.Mode] < coverModes[required] {
+		mode := a.Coverage.Mode
+		if mode == "" {
+			mode = "no mode"
+		}
+		return fmt.Errorf("the coverage profile was generated with %s but -covermode=%s is required; run go test -covermode=%s", mode, required, required)
+	}
+	return nil
+}
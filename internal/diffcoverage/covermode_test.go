@@ -0,0 +1,33 @@
+package diffcoverage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestAnalysis_RequireCoverMode compares the profile mode with the required one.
+func TestAnalysis_RequireCoverMode(t *testing.T) {
+	a := setupReportAnalysis(t)
+	if a.Coverage.Mode != "set" {
+		t.Fatalf("Mode = %q, want set", a.Coverage.Mode)
+	}
+	if err := a.RequireCoverMode("set"); err != nil {
+		t.Errorf("RequireCoverMode(set) = %v", err)
+	}
+	if err := a.RequireCoverMode("atomic"); err == nil {
+		t.Errorf("RequireCoverMode(atomic) succeeded for a set profile")
+	}
+}
+
+// TestParseCoverFile_MixedModes keeps the weakest mode of concatenated profiles.
+func TestParseCoverFile_MixedModes(t *testing.T) {
+	dir := t.TempDir()
+	writeCoverFile(t, dir, "cover.out", "mode: atomic\nm/a.go:1.1,2.2 1 1\nmode: count\nm/b.go:1.1,2.2 1 0\n")
+	c, err := parseCoverFile(filepath.Join(dir, "cover.out"), "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Mode != "count" {
+		t.Errorf("Mode = %q, want count", c.Mode)
+	}
+}
@@ -0,0 +1,257 @@
+package diffcoverage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severity grades a doctor Finding.
+type Severity int
+
+const (
+	SeverityOK Severity = iota
+	SeverityWarn
+	SeverityError
+)
+
+// String returns the label printed in front of a finding.
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarn:
+		return "WARN"
+	case SeverityError:
+		return "FAIL"
+	default:
+		return "OK"
+	}
+}
+
+// Finding is a single result of Diagnose.
+type Finding struct {
+	Severity Severity
+	Message  string
+}
+
+// coverSummary is what Diagnose learns from the raw cover file.
+type coverSummary struct {
+	mode     string
+	blocks   int
+	matching int
+	files    map[string]bool // module-relative paths present in the profile
+	foreign  map[string]int  // path prefix -> blocks not matching the module
+	lcov     bool            // an LCOV file, e.g. Bazel's coverage.dat
+}
+
+// diffSummary is what Diagnose learns from the raw diff file.
+type diffSummary struct {
+	files        []string // paths from "+++ " headers, without the b/ prefix
+	noPrefix     bool     // a header without the b/ prefix was seen
+	contextLines int      // lines starting with a space inside hunks
+	addedLines   int
+}
+
+// Diagnose cross-checks the module, coverage profile, diff and source tree and
+// explains why they do not line up. It never fails; problems are reported as findings.
+func Diagnose(coverPath, diffPath, sourceRoot string) []Finding {
+	var findings []Finding
+	add := func(sev Severity, format string, args ...any) {
+		findings = append(findings, Finding{Severity: sev, Message: fmt.Sprintf(format, args...)})
+	}
+
+	goModPath := filepath.Join(sourceRoot, "go.mod")
+	moduleName, err := parseGoMod(goModPath)
+	if err != nil {
+		add(SeverityError, "%v; the source root must be the directory containing go.mod", err)
+	} else {
+		add(SeverityOK, "module %s (from %s)", moduleName, goModPath)
+	}
+
+	cover, err := summarizeCoverFile(coverPath, moduleName)
+	if err != nil {
+		add(SeverityError, "cannot read cover file: %v", err)
+	} else if cover.lcov {
+		data, err := parseCoverFileIn(coverPath, &moduleResolver{sourceRoot: sourceRoot, root: moduleName})
+		switch {
+		case err != nil:
+			add(SeverityError, "cannot read cover file: %v", err)
+		case len(data.Lines) == 0:
+			add(SeverityError, "none of the LCOV source files (SF:) map to the source root; is it the Go module of the Bazel workspace?")
+		default:
+			for file := range data.Lines {
+				cover.files[file] = true
+			}
+			add(SeverityOK, "cover file: LCOV, %d lines in %d files", data.Statements, len(data.Lines))
+		}
+	} else {
+		switch {
+		case cover.mode == "":
+			add(SeverityWarn, "cover file has no \"mode:\" header; is it a go test -coverprofile output?")
+		case cover.blocks == 0:
+			add(SeverityError, "cover file contains no coverage blocks; did the tests run?")
+		case moduleName != "" && cover.matching == 0:
+			add(SeverityError, "none of the %d coverage blocks start with the module path %q; profile paths start with %s",
+				cover.blocks, moduleName+"/", strings.Join(sortedPrefixes(cover.foreign), ", "))
+		case cover.matching < cover.blocks:
+			add(SeverityWarn, "%d of %d coverage blocks belong to other modules and are ignored (%s)",
+				cover.blocks-cover.matching, cover.blocks, strings.Join(sortedPrefixes(cover.foreign), ", "))
+		default:
+			add(SeverityOK, "cover file: mode %s, %d blocks in %d files", cover.mode, cover.blocks, len(cover.files))
+		}
+	}
+
+	diff, err := summarizeDiffFile(diffPath)
+	if err != nil {
+		add(SeverityError, "cannot read diff file: %v", err)
+		return findings
+	}
+	if len(diff.files) == 0 {
+		add(SeverityError, "diff contains no \"+++ \" file headers; generate it with git diff --unified=0")
+		return findings
+	}
+	if diff.contextLines > 0 {
+		add(SeverityOK, "diff contains %d context lines; line numbers follow the hunk headers", diff.contextLines)
+	}
+	if diff.noPrefix {
+		add(SeverityWarn, "diff file headers lack the b/ prefix (--no-prefix or diff.noprefix); paths may not resolve")
+	}
+
+	var goFiles, missing, untested []string
+	for _, file := range diff.files {
+		if !strings.HasSuffix(file, ".go") || strings.Contains(file, "_test.go") || strings.Contains(file, "mock") {
+			continue
+		}
+		goFiles = append(goFiles, file)
+		if _, err := os.Stat(filepath.Join(sourceRoot, file)); err != nil {
+			missing = append(missing, file)
+			continue
+		}
+		if cover != nil && !cover.files[file] {
+			untested = append(untested, file)
+		}
+	}
+
+	if len(goFiles) == 0 {
+		add(SeverityWarn, "diff touches %d files but none are counted Go sources (tests, mocks and non-.go files are skipped); the result is always 100%%", len(diff.files))
+		return findings
+	}
+	add(SeverityOK, "diff: %d added lines, %d counted Go files", diff.addedLines, len(goFiles))
+
+	if len(missing) == len(goFiles) {
+		add(SeverityError, "none of the changed Go files exist under %s (e.g. %s); generate the diff from the module root or with git diff --relative",
+			sourceRoot, missing[0])
+	} else if len(missing) > 0 {
+		add(SeverityWarn, "%d changed Go files are missing under %s (deleted or outside the module): %s",
+			len(missing), sourceRoot, strings.Join(missing, ", "))
+	}
+
+	if len(untested) > 0 {
+		add(SeverityWarn, "%d changed Go files have no coverage blocks (package not tested or not instrumented): %s",
+			len(untested), strings.Join(untested, ", "))
+	}
+
+	return findings
+}
+
+// summarizeCoverFile scans a cover file without skipping anything silently.
+func summarizeCoverFile(coverPath, moduleName string) (*coverSummary, error) {
+	f, err := os.Open(coverPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &coverSummary{files: make(map[string]bool), foreign: make(map[string]int)}
+	br := bufio.NewReader(f)
+	if s.lcov = isLCOV(br); s.lcov {
+		return s, nil
+	}
+	scanner := bufio.NewScanner(br)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "mode:") {
+			s.mode = strings.TrimSpace(strings.TrimPrefix(line, "mode:"))
+			continue
+		}
+		idx := strings.LastIndex(line, ":")
+		if line == "" || idx < 0 {
+			continue
+		}
+		s.blocks++
+		path := line[:idx]
+		if moduleName != "" && strings.HasPrefix(path, moduleName+"/") {
+			s.matching++
+			s.files[strings.TrimPrefix(path, moduleName+"/")] = true
+			continue
+		}
+		s.foreign[pathPrefix(path)]++
+	}
+	return s, scanner.Err()
+}
+
+// summarizeDiffFile scans a diff file, recording headers and context lines.
+func summarizeDiffFile(diffPath string) (*diffSummary, error) {
+	f, err := os.Open(diffPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &diffSummary{}
+	inHunk := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			inHunk = false
+			fields := strings.Fields(line)
+			if len(fields) < 2 || fields[1] == "/dev/null" {
+				continue
+			}
+			if !strings.HasPrefix(fields[1], "b/") {
+				s.noPrefix = true
+			}
+			s.files = append(s.files, strings.TrimPrefix(fields[1], "b/"))
+		case strings.HasPrefix(line, "diff "):
+			inHunk = false
+		case hunkHeaderRegex.MatchString(line):
+			inHunk = true
+		case inHunk && strings.HasPrefix(line, " "):
+			s.contextLines++
+		case inHunk && strings.HasPrefix(line, "+"):
+			s.addedLines++
+		}
+	}
+	return s, scanner.Err()
+}
+
+// pathPrefix returns the first three path segments, enough to tell module paths apart.
+func pathPrefix(path string) string {
+	parts := strings.SplitN(filepath.ToSlash(path), "/", 4)
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	return strings.Join(parts, "/") + "/"
+}
+
+// sortedPrefixes lists prefixes by descending block count.
+func sortedPrefixes(prefixes map[string]int) []string {
+	var list []string
+	for p := range prefixes {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if prefixes[list[i]] != prefixes[list[j]] {
+			return prefixes[list[i]] > prefixes[list[j]]
+		}
+		return list[i] < list[j]
+	})
+	if len(list) > 3 {
+		list = append(list[:3], "...")
+	}
+	return list
+}
@@ -0,0 +1,125 @@
+package diffcoverage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupDoctorInputs writes a consistent module, cover file and diff and returns their paths.
+func setupDoctorInputs(t *testing.T) (coverPath, diffPath, root string) {
+	t.Helper()
+	root = t.TempDir()
+	writeGoMod(t, root, "github.com/example/module")
+	mustWriteFile(t, filepath.Join(root, "pkg", "foo.go"), "package foo\n\nfunc Foo() {\n\tprintln(1)\n}\n")
+	writeCoverFile(t, root, "cover.out", "mode: set\ngithub.com/example/module/pkg/foo.go:4.0,4.10 1 1\n")
+	writeDiffFile(t, root, "diff.diff", "diff --git a/pkg/foo.go b/pkg/foo.go\n+++ b/pkg/foo.go\n@@ -3,0 +4,1 @@\n+\tprintln(1)\n")
+	return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+}
+
+// findFinding returns the first finding of severity sev containing substr.
+func findFinding(findings []Finding, sev Severity, substr string) bool {
+	for _, f := range findings {
+		if f.Severity == sev && strings.Contains(f.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestDiagnose_Healthy expects only OK findings for consistent inputs.
+func TestDiagnose_Healthy(t *testing.T) {
+	coverPath, diffPath, root := setupDoctorInputs(t)
+	findings := Diagnose(coverPath, diffPath, root)
+	for _, f := range findings {
+		if f.Severity != SeverityOK {
+			t.Errorf("Unexpected finding: %s %s", f.Severity, f.Message)
+		}
+	}
+	if !findFinding(findings, SeverityOK, "module github.com/example/module") {
+		t.Errorf("Expected module finding, got %+v", findings)
+	}
+}
+
+// TestDiagnose_Problems covers each mismatch Diagnose explains.
+func TestDiagnose_Problems(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(t *testing.T, root string) (cover, diff, srcRoot string)
+		sev    Severity
+		substr string
+	}{
+		{"missing go.mod", func(t *testing.T, root string) (string, string, string) {
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), filepath.Join(root, "pkg")
+		}, SeverityError, "directory containing go.mod"},
+		{"missing cover file", func(t *testing.T, root string) (string, string, string) {
+			return filepath.Join(root, "nope.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityError, "cannot read cover file"},
+		{"cover without mode", func(t *testing.T, root string) (string, string, string) {
+			writeCoverFile(t, root, "cover.out", "github.com/example/module/pkg/foo.go:4.0,4.10 1 1\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityWarn, "no \"mode:\" header"},
+		{"empty cover", func(t *testing.T, root string) (string, string, string) {
+			writeCoverFile(t, root, "cover.out", "mode: set\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityError, "no coverage blocks"},
+		{"wrong prefix", func(t *testing.T, root string) (string, string, string) {
+			writeCoverFile(t, root, "cover.out", "mode: set\ngithub.com/other/thing/pkg/foo.go:4.0,4.10 1 1\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityError, "github.com/other/thing/"},
+		{"foreign blocks", func(t *testing.T, root string) (string, string, string) {
+			writeCoverFile(t, root, "cover.out", "mode: set\ngithub.com/example/module/pkg/foo.go:4.0,4.10 1 1\ngithub.com/other/thing/x.go:1.0,2.0 1 1\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityWarn, "1 of 2 coverage blocks"},
+		{"missing diff", func(t *testing.T, root string) (string, string, string) {
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "nope.diff"), root
+		}, SeverityError, "cannot read diff file"},
+		{"no headers", func(t *testing.T, root string) (string, string, string) {
+			writeDiffFile(t, root, "diff.diff", "just text\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityError, "no \"+++ \" file headers"},
+		{"context lines", func(t *testing.T, root string) (string, string, string) {
+			writeDiffFile(t, root, "diff.diff", "+++ b/pkg/foo.go\n@@ -3,2 +3,3 @@\n func Foo() {\n+\tprintln(1)\n }\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityOK, "2 context lines"},
+		{"no prefix", func(t *testing.T, root string) (string, string, string) {
+			writeDiffFile(t, root, "diff.diff", "+++ pkg/foo.go\n@@ -3,0 +4,1 @@\n+\tprintln(1)\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityWarn, "b/ prefix"},
+		{"only tests", func(t *testing.T, root string) (string, string, string) {
+			writeDiffFile(t, root, "diff.diff", "+++ b/pkg/foo_test.go\n@@ -3,0 +4,1 @@\n+\tprintln(1)\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityWarn, "always 100%"},
+		{"diff from wrong directory", func(t *testing.T, root string) (string, string, string) {
+			writeDiffFile(t, root, "diff.diff", "+++ b/svc/pkg/foo.go\n@@ -3,0 +4,1 @@\n+\tprintln(1)\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityError, "--relative"},
+		{"some files missing", func(t *testing.T, root string) (string, string, string) {
+			writeDiffFile(t, root, "diff.diff", "+++ b/pkg/foo.go\n@@ -3,0 +4,1 @@\n+\tprintln(1)\n+++ b/pkg/gone.go\n@@ -1,0 +1,1 @@\n+x\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityWarn, "pkg/gone.go"},
+		{"untested file", func(t *testing.T, root string) (string, string, string) {
+			writeCoverFile(t, root, "cover.out", "mode: set\ngithub.com/example/module/pkg/other.go:4.0,4.10 1 1\n")
+			return filepath.Join(root, "cover.out"), filepath.Join(root, "diff.diff"), root
+		}, SeverityWarn, "no coverage blocks (package not tested"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, root := setupDoctorInputs(t)
+			coverPath, diffPath, srcRoot := tt.mutate(t, root)
+			findings := Diagnose(coverPath, diffPath, srcRoot)
+			if !findFinding(findings, tt.sev, tt.substr) {
+				t.Errorf("Expected %s finding containing %q, got %+v", tt.sev, tt.substr, findings)
+			}
+		})
+	}
+}
+
+// TestSortedPrefixes checks ordering and truncation.
+func TestSortedPrefixes(t *testing.T) {
+	got := sortedPrefixes(map[string]int{"a/": 1, "b/": 5, "c/": 5, "d/": 2})
+	want := []string{"b/", "c/", "d/", "..."}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("sortedPrefixes = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package diffcoverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+)
+
+// ExcludeErrorReturns drops the new/changed lines of `if err != nil { return
+// ..., err }` statements that only propagate an error, wrapped or not:
+// covering each of them requires injecting a failure into the callee. A
+// statement such as `if err := f(); err != nil` keeps its first line, which
+// calls f.
+func (a *Analysis) ExcludeErrorReturns() error {
+	for file, lines := range a.Diff.NewLines {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, filepath.Join(a.SourceRoot, a.RelPath(file)), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %v", a.RelPath(file), err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			stmt, ok := n.(*ast.IfStmt)
+			if !ok || !isErrorReturn(stmt) {
+				return true
+			}
+			from := stmt.Pos()
+			if stmt.Init != nil {
+				from = stmt.Body.List[0].Pos()
+			}
+			for line := fset.Position(from).Line; line <= fset.Position(stmt.End()).Line; line++ {
+				delete(lines, line)
+			}
+			return false
+		})
+	}
+	return nil
+}
+
+// isErrorReturn reports whether stmt is `if x != nil { return ... }`, without
+// else, whose return values use x.
+func isErrorReturn(stmt *ast.IfStmt) bool {
+	cond, ok := stmt.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ || stmt.Else != nil || len(stmt.Body.List) != 1 {
+		return false
+	}
+	x, ok := cond.X.(*ast.Ident)
+	if nilIdent, isIdent := cond.Y.(*ast.Ident); !ok || !isIdent || nilIdent.Name != "nil" {
+		return false
+	}
+	ret, ok := stmt.Body.List[0].(*ast.ReturnStmt)
+	if !ok {
+		return false
+	}
+	uses := false
+	for _, result := range ret.Results {
+		ast.Inspect(result, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Name == x.Name {
+				uses = true
+			}
+			return !uses
+		})
+	}
+	return uses
+}
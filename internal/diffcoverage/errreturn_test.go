@@ -0,0 +1,52 @@
+package diffcoverage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestAnalysis_ExcludeErrorReturns drops error propagation only.
+func TestAnalysis_ExcludeErrorReturns(t *testing.T) {
+	root := t.TempDir()
+	src := `package p
+
+import "fmt"
+
+func f() (int, error) {
+	n, err := g()
+	if err != nil {
+		return 0, fmt.Errorf("error calling g: %w", err)
+	}
+	if err := h(); err != nil {
+		return 0, err
+	}
+	if err != nil {
+		return 0, nil
+	}
+	return n, nil
+}
+`
+	if err := os.WriteFile(filepath.Join(root, "p.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lines := map[int]bool{}
+	for line := 5; line <= 17; line++ {
+		lines[line] = true
+	}
+	a := &Analysis{ModuleName: "example.com/m", SourceRoot: root, Diff: &DiffData{NewLines: map[string]map[int]bool{"example.com/m/p.go": lines}}}
+	if err := a.ExcludeErrorReturns(); err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for line := range lines {
+		got = append(got, line)
+	}
+	sort.Ints(got)
+	want := []int{5, 6, 10, 13, 14, 15, 16, 17}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("remaining lines = %v, want %v", got, want)
+	}
+}
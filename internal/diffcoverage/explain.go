@@ -0,0 +1,114 @@
+package diffcoverage
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ProfileBlock is a block of the coverage profile: a span of source and the
+// number of times its statements ran.
+type ProfileBlock struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	Statements          int
+	Count               int
+}
+
+func (b ProfileBlock) String() string {
+	return fmt.Sprintf("%d.%d,%d.%d", b.StartLine, b.StartCol, b.EndLine, b.EndCol)
+}
+
+// FuncSpan locates a function declaration: Start and End span all of it,
+// BodyStart and BodyEnd the lines of it that are counted.
+type FuncSpan struct {
+	Name               string
+	Start, End         int
+	BodyStart, BodyEnd int
+}
+
+// HasLine reports whether the changed line of file, relative to the source
+// root, is still in the analysis.
+func (a *Analysis) HasLine(file string, line int) bool {
+	for key, lines := range a.Diff.NewLines {
+		if a.RelPath(key) == file {
+			return lines[line]
+		}
+	}
+	return false
+}
+
+// FuncAt returns the function declaration spanning line of file, relative to
+// the source root, or nil if there is none.
+func (a *Analysis) FuncAt(file string, line int) (*FuncSpan, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filepath.Join(a.SourceRoot, file), nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", file, err)
+	}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		span := &FuncSpan{Name: FuncName(fn), Start: fset.Position(fn.Pos()).Line, End: fset.Position(fn.End()).Line}
+		if line < span.Start || line > span.End {
+			continue
+		}
+		span.BodyStart, span.BodyEnd = bodyLines(fset, fn)
+		return span, nil
+	}
+	return nil, nil
+}
+
+// Blocks returns the blocks of the coverage profile spanning line of file,
+// relative to the source root, in the order of the profile.
+func (a *Analysis) Blocks(file string, line int) ([]ProfileBlock, error) {
+	f, err := os.Open(a.CoverPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	modules := &moduleResolver{sourceRoot: a.SourceRoot, root: a.ModuleName}
+	var blocks []ProfileBlock
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Format: filepath.go:startLine.startCol,endLine.endCol numStatements count
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.HasPrefix(scanner.Text(), "mode:") {
+			continue
+		}
+		if rel, ok := modules.rel(name); !ok || filepath.ToSlash(rel) != file {
+			continue
+		}
+		var b ProfileBlock
+		if _, err := fmt.Sscanf(rest, "%d.%d,%d.%d %d %d", &b.StartLine, &b.StartCol, &b.EndLine, &b.EndCol, &b.Statements, &b.Count); err != nil {
+			continue
+		}
+		if b.StartLine <= line && line <= b.EndLine {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks, scanner.Err()
+}
+
+// ParseLocation splits "pkg/foo.go:128" into a slash-separated path and a line.
+func ParseLocation(location string) (string, int, error) {
+	i := strings.LastIndex(location, ":")
+	if i < 0 {
+		i = len(location)
+	}
+	file := location[:i]
+	line, err := strconv.Atoi(location[min(i+1, len(location)):])
+	if err != nil || line < 1 || file == "" {
+		return "", 0, fmt.Errorf("invalid location %q: expected file:line, e.g. pkg/foo.go:128", location)
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(file)), "./"), line, nil
+}
@@ -0,0 +1,93 @@
+package diffcoverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FuncMatcher matches function names ("Func" or "Type.Method") against
+// patterns: globs such as `DeepCopy*` or `*.String`, or regular expressions
+// between slashes such as `/^Marshal.*JSON$/`. A pattern without a dot
+// matches the name of a method of any type too.
+type FuncMatcher struct {
+	globs   []string
+	regexps []*regexp.Regexp
+}
+
+// NewFuncMatcher compiles the patterns.
+func NewFuncMatcher(patterns []string) (*FuncMatcher, error) {
+	m := &FuncMatcher{}
+	for _, p := range patterns {
+		if expr, ok := strings.CutPrefix(p, "/"); ok && len(expr) > 0 && strings.HasSuffix(expr, "/") {
+			re, err := regexp.Compile(strings.TrimSuffix(expr, "/"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid function pattern %s: %v", p, err)
+			}
+			m.regexps = append(m.regexps, re)
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid function pattern %q: %v", p, err)
+		}
+		m.globs = append(m.globs, p)
+	}
+	return m, nil
+}
+
+// Match reports whether name matches any pattern.
+func (m *FuncMatcher) Match(name string) bool {
+	_, method, isMethod := strings.Cut(name, ".")
+	for _, g := range m.globs {
+		if ok, _ := path.Match(g, name); ok {
+			return true
+		}
+		if isMethod && !strings.Contains(g, ".") {
+			if ok, _ := path.Match(g, method); ok {
+				return true
+			}
+		}
+	}
+	for _, re := range m.regexps {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeFunctionNames drops the new/changed lines of the functions whose
+// names match the patterns (see FuncMatcher), such as String, Error or
+// generated DeepCopy methods: changes to these mechanical functions add
+// little risk.
+func (a *Analysis) ExcludeFunctionNames(patterns []string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+	m, err := NewFuncMatcher(patterns)
+	if err != nil {
+		return err
+	}
+	for file, lines := range a.Diff.NewLines {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, filepath.Join(a.SourceRoot, a.RelPath(file)), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %v", a.RelPath(file), err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || !m.Match(FuncName(fn)) {
+				continue
+			}
+			for line := fset.Position(fn.Pos()).Line; line <= fset.Position(fn.End()).Line; line++ {
+				delete(lines, line)
+			}
+		}
+	}
+	return nil
+}
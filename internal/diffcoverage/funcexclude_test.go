@@ -0,0 +1,72 @@
+package diffcoverage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestFuncMatcher_Match covers globs, method names and regular expressions.
+func TestFuncMatcher_Match(t *testing.T) {
+	m, err := NewFuncMatcher([]string{"String", "DeepCopy*", "*.Error", "/^Marshal.*JSON$/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"String":           true,
+		"T.String":         true,
+		"T.DeepCopyObject": true,
+		"myErr.Error":      true,
+		"Error":            false,
+		"T.MarshalJSON":    false,
+		"MarshalJSON":      true,
+		"Stringer":         false,
+		"T.Handle":         false,
+	} {
+		if got := m.Match(name); got != want {
+			t.Errorf("Match(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if _, err := NewFuncMatcher([]string{"/(/"}); err == nil {
+		t.Errorf("Expected an error for an invalid regular expression")
+	}
+}
+
+// TestAnalysis_ExcludeFunctionNames drops the lines of matching functions only.
+func TestAnalysis_ExcludeFunctionNames(t *testing.T) {
+	root := t.TempDir()
+	src := `package p
+
+type T int
+
+func (t T) String() string {
+	return "t"
+}
+
+func f() int {
+	return 1
+}
+`
+	if err := os.WriteFile(filepath.Join(root, "p.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lines := map[int]bool{}
+	for line := 3; line <= 11; line++ {
+		lines[line] = true
+	}
+	a := &Analysis{ModuleName: "example.com/m", SourceRoot: root, Diff: &DiffData{NewLines: map[string]map[int]bool{"example.com/m/p.go": lines}}}
+	if err := a.ExcludeFunctionNames([]string{"String"}); err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for line := range lines {
+		got = append(got, line)
+	}
+	sort.Ints(got)
+	want := []int{3, 4, 8, 9, 10, 11}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("remaining lines = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+package diffcoverage
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"sort"
+)
+
+// FuncReport is the coverage of a function touched by the diff, over all
+// lines of its body that the profile instruments, changed or not.
+type FuncReport struct {
+	File         string  `json:"file"`
+	Line         int     `json:"line"`
+	Name         string  `json:"name"` // "Func" or "Type.Method"
+	Signature    string  `json:"signature"`
+	TotalLines   int     `json:"totalLines"`
+	CoveredLines int     `json:"coveredLines"`
+	Coverage     float64 `json:"coverage"`
+}
+
+// Functions returns the functions with a counted new/changed line, sorted by
+// file and line. When the profile has no block of a file, e.g. because its
+// package was not instrumented, every line of the function bodies counts.
+func (a *Analysis) Functions() ([]FuncReport, error) {
+	return a.FunctionsWhere(nil)
+}
+
+// FunctionsWhere is Functions counting only the unchanged lines for which
+// keep, if not nil, returns true; changed lines always count.
+func (a *Analysis) FunctionsWhere(keep func(file string, line int) bool) ([]FuncReport, error) {
+	var funcs []FuncReport
+	for file, newLines := range a.Diff.NewLines {
+		rel := a.RelPath(file)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, filepath.Join(a.SourceRoot, rel), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", rel, err)
+		}
+		instrumented := a.Coverage.Lines[rel]
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			start, end := bodyLines(fset, fn)
+			touched := false
+			for line := start; line <= end; line++ {
+				touched = touched || (newLines[line] && a.Status(rel, line) != LineIgnored)
+			}
+			if !touched {
+				continue
+			}
+
+			fr := FuncReport{
+				File:      rel,
+				Line:      fset.Position(fn.Pos()).Line,
+				Name:      FuncName(fn),
+				Signature: signature(fset, fn),
+			}
+			for line := start; line <= end; line++ {
+				if instrumented != nil && !instrumented[line] {
+					continue
+				}
+				if keep != nil && !newLines[line] && !keep(rel, line) {
+					continue
+				}
+				fr.TotalLines++
+				if a.Coverage.CoveredLines[rel][line] {
+					fr.CoveredLines++
+				}
+			}
+			fr.Coverage = percent(fr.CoveredLines, fr.TotalLines)
+			funcs = append(funcs, fr)
+		}
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		if funcs[i].File != funcs[j].File {
+			return funcs[i].File < funcs[j].File
+		}
+		return funcs[i].Line < funcs[j].Line
+	})
+	return funcs, nil
+}
+
+// ExcludeFunctions drops the new/changed lines of the functions ("Func" or
+// "Type.Method") that funcs lists for changed files, given relative to the
+// source root.
+func (a *Analysis) ExcludeFunctions(funcs map[string][]string) error {
+	for file, lines := range a.Diff.NewLines {
+		names := funcs[a.RelPath(file)]
+		if len(names) == 0 {
+			continue
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, filepath.Join(a.SourceRoot, a.RelPath(file)), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %v", a.RelPath(file), err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || !containsString(names, FuncName(fn)) {
+				continue
+			}
+			for line := fset.Position(fn.Pos()).Line; line <= fset.Position(fn.End()).Line; line++ {
+				delete(lines, line)
+			}
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyLines returns the counted line range of fn: from the first statement of
+// its body to the line before the closing brace.
+func bodyLines(fset *token.FileSet, fn *ast.FuncDecl) (start, end int) {
+	start = fset.Position(fn.Pos()).Line
+	end = fset.Position(fn.End()).Line
+	if fn.Body != nil && len(fn.Body.List) > 0 {
+		start = fset.Position(fn.Body.List[0].Pos()).Line
+	}
+	if end > start {
+		end--
+	}
+	return start, end
+}
+
+// FuncName returns "Func" or "Type.Method" for fn.
+func FuncName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	expr := fn.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		expr = t.X
+	case *ast.IndexListExpr:
+		expr = t.X
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// signature returns fn's declaration without its body or doc comment.
+func signature(fset *token.FileSet, fn *ast.FuncDecl) string {
+	decl := *fn
+	decl.Body, decl.Doc = nil, nil
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, &decl)
+	return buf.String()
+}
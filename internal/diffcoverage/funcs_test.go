@@ -0,0 +1,76 @@
+package diffcoverage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestAnalysis_Functions checks touched functions are measured over all their
+// instrumented lines.
+func TestAnalysis_Functions(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "foo.go"), `package foo
+
+// Foo has a changed, covered line and an unchanged, uncovered one.
+func Foo(n int) int {
+	if n > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func (b *Bar) Baz() {
+	println()
+}
+
+func Untouched() {
+	println()
+}
+
+type Bar struct{}
+`)
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "other.go"), "package foo\n\nfunc Other() {\n\tprintln()\n}\n")
+	writeCoverFile(t, tmpDir, "cover.out", `mode: set
+github.com/example/module/pkg/foo.go:4.21,5.11 1 1
+github.com/example/module/pkg/foo.go:5.11,7.3 1 1
+github.com/example/module/pkg/foo.go:9.2,9.10 1 0
+github.com/example/module/pkg/foo.go:12.21,14.2 1 0
+`)
+	writeDiffFile(t, tmpDir, "diff.diff", `+++ b/pkg/foo.go
+@@ -5,0 +6,1 @@
++		return 1
+@@ -12,0 +13,1 @@
++	println()
+@@ -19,0 +20,1 @@
++type Bar struct{}
++++ b/pkg/other.go
+@@ -3,0 +4,1 @@
++	println()
+`)
+
+	a, err := Analyze(filepath.Join(tmpDir, "cover.out"), filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	funcs, err := a.Functions()
+	if err != nil {
+		t.Fatalf("Functions failed: %v", err)
+	}
+
+	want := []FuncReport{
+		{File: "pkg/foo.go", Line: 4, Name: "Foo", Signature: "func Foo(n int) int", TotalLines: 4, CoveredLines: 3, Coverage: 75},
+		{File: "pkg/foo.go", Line: 12, Name: "Bar.Baz", Signature: "func (b *Bar) Baz()", TotalLines: 1, CoveredLines: 0, Coverage: 0},
+		// Not in the profile: every body line counts.
+		{File: "pkg/other.go", Line: 3, Name: "Other", Signature: "func Other()", TotalLines: 1, CoveredLines: 0, Coverage: 0},
+	}
+	if len(funcs) != len(want) {
+		t.Fatalf("Functions = %+v, want %+v", funcs, want)
+	}
+	for i := range want {
+		if funcs[i] != want[i] {
+			t.Errorf("Functions[%d] = %+v, want %+v", i, funcs[i], want[i])
+		}
+	}
+}
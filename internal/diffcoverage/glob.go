@@ -0,0 +1,51 @@
+package diffcoverage
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchPattern reports whether the slash-separated path matches a glob pattern.
+// Patterns follow path.Match per segment, "**" matches any number of segments,
+// and a pattern without a slash matches the base name at any depth (e.g. "*.pb.go").
+func MatchPattern(pattern, file string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	file = strings.TrimPrefix(file, "./")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(file))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+// MatchAny reports whether file matches any of the patterns.
+func MatchAny(patterns []string, file string) bool {
+	for _, p := range patterns {
+		if MatchPattern(p, file) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
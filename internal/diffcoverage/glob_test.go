@@ -0,0 +1,39 @@
+package diffcoverage
+
+import "testing"
+
+// TestMatchPattern covers base-name, anchored and ** patterns.
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"*.pb.go", "api/v1/foo.pb.go", true},
+		{"*.pb.go", "api/v1/foo.go", false},
+		{"internal/gen/**", "internal/gen/a/b.go", true},
+		{"internal/gen/**", "internal/other/b.go", false},
+		{"**/zz_generated*.go", "zz_generated.deepcopy.go", true},
+		{"**/zz_generated*.go", "pkg/apis/zz_generated.deepcopy.go", true},
+		{"cmd/*/main.go", "cmd/tool/main.go", true},
+		{"cmd/*/main.go", "cmd/tool/sub/main.go", false},
+		{"./vendor/**", "vendor/x/y.go", true},
+		{"pkg/foo.go", "pkg/foo.go", true},
+		{"pkg/foo.go", "pkg/foo.go/extra", false},
+		{"pkg/**/mock.go", "pkg/mock.go", true},
+	}
+	for _, c := range cases {
+		if got := MatchPattern(c.pattern, c.file); got != c.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", c.pattern, c.file, got, c.want)
+		}
+	}
+}
+
+// TestMatchAny checks that any matching pattern wins.
+func TestMatchAny(t *testing.T) {
+	if !MatchAny([]string{"*.txt", "*.pb.go"}, "a/b.pb.go") {
+		t.Errorf("Expected a/b.pb.go to match")
+	}
+	if MatchAny(nil, "a.go") {
+		t.Errorf("Expected no match for empty pattern list")
+	}
+}
@@ -0,0 +1,35 @@
+package diffcoverage
+
+import "sort"
+
+// GradeBand awards Grade to coverages of at least Min percent.
+type GradeBand struct {
+	Grade string  `json:"grade"`
+	Min   float64 `json:"min"`
+}
+
+// DefaultGrades are the bands of the A to F grading.
+var DefaultGrades = []GradeBand{{"A", 90}, {"B", 80}, {"C", 70}, {"D", 60}, {"F", 0}}
+
+// Grade returns the grade of the band with the highest minimum that coverage
+// reaches, or "" if it reaches none.
+func Grade(bands []GradeBand, coverage float64) string {
+	sorted := append([]GradeBand(nil), bands...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Min > sorted[j].Min })
+	for _, b := range sorted {
+		if coverage >= b.Min {
+			return b.Grade
+		}
+	}
+	return ""
+}
+
+// ApplyGrades grades the coverage of the report and of its targets. It does
+// not change the gate decision.
+func (r *Report) ApplyGrades(bands []GradeBand) {
+	r.grades = bands
+	r.Grade = Grade(bands, r.Coverage)
+	for i := range r.Targets {
+		r.Targets[i].Grade = Grade(bands, r.Targets[i].Coverage)
+	}
+}
@@ -0,0 +1,35 @@
+package diffcoverage
+
+import "testing"
+
+// TestGrade checks the band with the highest reached minimum wins, in any order.
+func TestGrade(t *testing.T) {
+	for _, tt := range []struct {
+		coverage float64
+		want     string
+	}{{100, "A"}, {90, "A"}, {89.99, "B"}, {60, "D"}, {0, "F"}} {
+		if got := Grade(DefaultGrades, tt.coverage); got != tt.want {
+			t.Errorf("Grade(%v) = %q, want %q", tt.coverage, got, tt.want)
+		}
+	}
+	custom := []GradeBand{{"bronze", 50}, {"gold", 95}, {"silver", 80}}
+	if got := Grade(custom, 85); got != "silver" {
+		t.Errorf("Grade(custom, 85) = %q, want silver", got)
+	}
+	if got := Grade(custom, 10); got != "" {
+		t.Errorf("Grade(custom, 10) = %q, want none", got)
+	}
+}
+
+// TestCombine_Grades checks combined reports and their targets are graded.
+func TestCombine_Grades(t *testing.T) {
+	a, b := NewReport(0), NewReport(0)
+	a.TotalLines, a.CoveredLines, a.Coverage = 10, 10, 100
+	b.TotalLines, b.CoveredLines, b.Coverage = 10, 5, 50
+	a.ApplyGrades(DefaultGrades)
+	b.ApplyGrades(DefaultGrades)
+	c := Combine([]string{"a", "b"}, []*Report{a, b}, 0)
+	if c.Grade != "C" || c.Targets[0].Grade != "A" || c.Targets[1].Grade != "F" {
+		t.Errorf("grades = %q, targets %+v", c.Grade, c.Targets)
+	}
+}
@@ -0,0 +1,161 @@
+package diffcoverage
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// bazelWorkspaceFiles mark the root of a Bazel workspace.
+var bazelWorkspaceFiles = []string{"MODULE.bazel", "WORKSPACE.bazel", "WORKSPACE"}
+
+// isLCOV reports whether the cover file read by r is an LCOV tracefile, such
+// as the coverage.dat of bazel coverage, rather than a Go cover profile.
+func isLCOV(r *bufio.Reader) bool {
+	for n := 64; ; n *= 2 {
+		data, err := r.Peek(n)
+		text := strings.TrimLeft(string(data), " \t\r\n")
+		if len(text) >= 3 || err != nil {
+			return strings.HasPrefix(text, "TN:") || strings.HasPrefix(text, "SF:")
+		}
+	}
+}
+
+// parseLCOV parses an LCOV tracefile. Every DA line is a statement of one
+// line; a line covered by any record is covered and its hits are summed, so
+// the per-test coverage.dat files of bazel coverage can be concatenated.
+func parseLCOV(r io.Reader, modules *moduleResolver) (*CoverageData, error) {
+	coverage := &CoverageData{
+		CoveredLines: make(map[string]map[int]bool),
+		Lines:        make(map[string]map[int]bool),
+		Hits:         make(map[string]map[int]int),
+		Mode:         "count",
+	}
+	file := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if sf, ok := strings.CutPrefix(line, "SF:"); ok {
+			file = ""
+			if rel, ok := modules.bazelRel(sf); ok {
+				file = rel
+			}
+			continue
+		}
+		if line == "end_of_record" {
+			file = ""
+			continue
+		}
+		da, ok := strings.CutPrefix(line, "DA:")
+		if !ok || file == "" {
+			continue
+		}
+		// DA:<line>,<count>[,<checksum>]
+		fields := strings.Split(da, ",")
+		if len(fields) < 2 {
+			continue
+		}
+		ln, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+
+		if coverage.Lines[file] == nil {
+			coverage.Lines[file] = make(map[int]bool)
+			coverage.CoveredLines[file] = make(map[int]bool)
+			coverage.Hits[file] = make(map[int]int)
+		}
+		if !coverage.Lines[file][ln] {
+			coverage.Lines[file][ln] = true
+			coverage.Statements++
+		}
+		if count > 0 && !coverage.CoveredLines[file][ln] {
+			coverage.CoveredLines[file][ln] = true
+			coverage.CoveredStatements++
+		}
+		coverage.Hits[file][ln] += count
+	}
+	return coverage, scanner.Err()
+}
+
+// bazelRel returns the path of an LCOV source file relative to the source
+// root, or false if it is outside of it. Bazel writes paths relative to the
+// workspace root, possibly below the execroot or an output tree, and rules_go
+// may write import paths instead; the source root can be a directory of the
+// workspace.
+func (r *moduleResolver) bazelRel(file string) (string, bool) {
+	file = filepath.ToSlash(file)
+	if rest, ok := strings.CutPrefix(file, "//"); ok {
+		// A label, //pkg:file.go.
+		file = strings.Replace(rest, ":", "/", 1)
+	}
+	if r.sourceRoot != "" && path.IsAbs(file) {
+		if root, err := filepath.Abs(r.sourceRoot); err == nil {
+			if rel, err := filepath.Rel(root, filepath.FromSlash(file)); err == nil && filepath.IsLocal(rel) {
+				return filepath.ToSlash(rel), true
+			}
+		}
+	}
+	file = strings.TrimPrefix(file, "/proc/self/cwd/")
+	if _, rest, ok := strings.Cut(file, "/execroot/"); ok {
+		// execroot/<workspace name>/...
+		_, file, _ = strings.Cut(rest, "/")
+	}
+	if rest, ok := strings.CutPrefix(file, "bazel-out/"); ok {
+		// bazel-out/<configuration>/bin/... holds generated sources.
+		if _, after, ok := strings.Cut(rest, "/bin/"); ok {
+			file = after
+		}
+	}
+	if strings.HasPrefix(file, "external/") || path.IsAbs(file) {
+		return "", false
+	}
+	if rel, ok := r.rel(file); ok {
+		return rel, true
+	}
+
+	prefix := r.workspacePrefix()
+	if prefix == "" {
+		return file, true
+	}
+	return strings.CutPrefix(file, prefix+"/")
+}
+
+// workspacePrefix returns the slash-separated path of the source root
+// relative to the enclosing Bazel workspace, or "" if they are the same or
+// there is none.
+func (r *moduleResolver) workspacePrefix() string {
+	if r.workspaceLoaded {
+		return r.workspace
+	}
+	r.workspaceLoaded = true
+	if r.sourceRoot == "" {
+		return ""
+	}
+	root, err := filepath.Abs(r.sourceRoot)
+	if err != nil {
+		return ""
+	}
+	for dir := root; ; dir = filepath.Dir(dir) {
+		for _, name := range bazelWorkspaceFiles {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				if rel, err := filepath.Rel(dir, root); err == nil && rel != "." {
+					r.workspace = filepath.ToSlash(rel)
+				}
+				return r.workspace
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package diffcoverage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestParseCoverFile_BazelLCOV checks coverage.dat paths are mapped to a
+// source root below the Bazel workspace.
+func TestParseCoverFile_BazelLCOV(t *testing.T) {
+	ws := t.TempDir()
+	mustWriteFile(t, filepath.Join(ws, "MODULE.bazel"), "")
+	root := filepath.Join(ws, "go")
+	mustWriteFile(t, filepath.Join(root, "go.mod"), "module example.com/m\n")
+	coverPath := filepath.Join(ws, "coverage.dat")
+	mustWriteFile(t, coverPath, `TN:
+SF:go/pkg/a.go
+FN:3,A
+DA:3,2
+DA:4,0
+end_of_record
+SF:external/rules_go/x.go
+DA:1,1
+end_of_record
+SF:/proc/self/cwd/bazel-out/k8-fastbuild/bin/go/pkg/gen.go
+DA:7,1
+end_of_record
+SF://go/pkg:a.go
+DA:4,0
+DA:5,3
+end_of_record
+SF:example.com/m/pkg/b.go
+DA:1,1
+end_of_record
+SF:other/c.go
+DA:1,1
+end_of_record
+`)
+
+	cd, err := parseCoverFileIn(coverPath, &moduleResolver{sourceRoot: root, root: "example.com/m"})
+	if err != nil {
+		t.Fatalf("parseCoverFileIn: %v", err)
+	}
+	if len(cd.Lines) != 3 || cd.Lines["pkg/gen.go"] == nil || cd.Lines["pkg/b.go"] == nil {
+		t.Errorf("files = %v, want pkg/a.go, pkg/b.go and pkg/gen.go", cd.Lines)
+	}
+	a := cd.CoveredLines["pkg/a.go"]
+	if !a[3] || a[4] || !a[5] {
+		t.Errorf("covered lines of pkg/a.go = %v, want 3 and 5", a)
+	}
+	if cd.Statements != 5 || cd.CoveredStatements != 4 {
+		t.Errorf("statements = %d/%d, want 4/5", cd.CoveredStatements, cd.Statements)
+	}
+	if cd.Mode != "count" || cd.Hits["pkg/a.go"][5] != 3 {
+		t.Errorf("mode %q, hits %v", cd.Mode, cd.Hits)
+	}
+}
@@ -11,16 +11,30 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/lineranges"
 )
 
 // CoverageData holds coverage information: for each file, a set of covered lines.
 type CoverageData struct {
 	CoveredLines map[string]map[int]bool // file -> set of covered lines
+	Lines        map[string]map[int]bool // file -> set of lines of any block
+	Mode         string                  // set, count or atomic
+	// Hits maps files to the times each line ran, the most of the blocks
+	// on it; only in count and atomic mode.
+	Hits map[string]map[int]int
+	// Statements and CoveredStatements count the module's statements, each
+	// profile block once, as go tool cover does.
+	Statements        int
+	CoveredStatements int
 }
 
 // DiffData holds information about new/changed lines from the diff.
 type DiffData struct {
 	NewLines map[string]map[int]bool // file -> set of new/changed lines
+	Added    map[string]bool         // files the diff adds
+	Tests    map[string]bool         // test files the diff changes
+	Hunks    map[string][][2]int     // file -> new-side line ranges of its hunks
 }
 
 // FuncLines holds ranges of function lines for each file.
@@ -28,6 +42,11 @@ type FuncLines struct {
 	Functions map[string][][2]int // file -> slice of [start, end] function lines
 }
 
+// ModulePath returns the module path declared in sourceRoot's go.mod.
+func ModulePath(sourceRoot string) (string, error) {
+	return parseGoMod(filepath.Join(sourceRoot, "go.mod"))
+}
+
 // parseGoMod reads the go.mod file and returns the module name.
 func parseGoMod(goModPath string) (string, error) {
 	file, err := os.Open(goModPath)
@@ -54,23 +73,49 @@ func parseGoMod(goModPath string) (string, error) {
 	return "", fmt.Errorf("module name not found in go.mod")
 }
 
-// parseCoverFile parses the cover.out file and returns CoverageData.
+// parseCoverFile parses the cover.out file of the module moduleName and
+// returns CoverageData.
 func parseCoverFile(coverFilePath, moduleName string) (*CoverageData, error) {
+	return parseCoverFileIn(coverFilePath, &moduleResolver{root: moduleName})
+}
+
+// parseCoverFileIn parses the cover.out file, keeping the entries of modules
+// under the source root, and returns CoverageData. LCOV files, e.g. Bazel's
+// coverage.dat, are accepted too.
+func parseCoverFileIn(coverFilePath string, modules *moduleResolver) (*CoverageData, error) {
 	f, err := os.Open(coverFilePath)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
+	br := bufio.NewReader(f)
+	if isLCOV(br) {
+		return parseLCOV(br, modules)
+	}
+
 	coverage := &CoverageData{
 		CoveredLines: make(map[string]map[int]bool),
+		Lines:        make(map[string]map[int]bool),
+	}
+	// Merged profiles repeat blocks; a block counts as covered if any copy is.
+	blocks := make(map[string]bool)
+	// hits sums the counts of the copies of each block.
+	type block struct {
+		file       string
+		start, end int
 	}
+	hits := make(map[block]int)
 
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(br)
 	for scanner.Scan() {
 		line := scanner.Text()
-		// Skip the line starting with "mode:"
-		if strings.HasPrefix(line, "mode:") {
+		// Concatenated profiles have a mode line each; keep the weakest.
+		if mode, ok := strings.CutPrefix(line, "mode:"); ok {
+			mode = strings.TrimSpace(mode)
+			if coverage.Mode == "" || coverModes[mode] < coverModes[coverage.Mode] {
+				coverage.Mode = mode
+			}
 			continue
 		}
 
@@ -80,7 +125,7 @@ func parseCoverFile(coverFilePath, moduleName string) (*CoverageData, error) {
 			continue
 		}
 		fileRange := parts[0]
-		_, coverageCountStr := parts[1], parts[2]
+		numStatementsStr, coverageCountStr := parts[1], parts[2]
 
 		pathAndRange := strings.Split(fileRange, ":")
 		if len(pathAndRange) != 2 {
@@ -89,11 +134,10 @@ func parseCoverFile(coverFilePath, moduleName string) (*CoverageData, error) {
 		absPath := pathAndRange[0]
 		rangePart := pathAndRange[1]
 
-		// Check if path starts with the module name
-		if !strings.HasPrefix(absPath, moduleName+"/") {
+		relPath, ok := modules.rel(absPath)
+		if !ok {
 			continue
 		}
-		relPath := strings.TrimPrefix(absPath, moduleName+"/")
 
 		coverageCount, err := strconv.Atoi(coverageCountStr)
 		if err != nil {
@@ -120,9 +164,28 @@ func parseCoverFile(coverFilePath, moduleName string) (*CoverageData, error) {
 			continue
 		}
 
+		if numStatements, err := strconv.Atoi(numStatementsStr); err == nil {
+			covered, seen := blocks[fileRange]
+			if !seen {
+				coverage.Statements += numStatements
+			}
+			if coverageCount > 0 && !covered {
+				coverage.CoveredStatements += numStatements
+			}
+			blocks[fileRange] = covered || coverageCount > 0
+		}
+
+		normalizedPath := filepath.ToSlash(relPath)
+		hits[block{normalizedPath, startLine, endLine}] += coverageCount
+		if coverage.Lines[normalizedPath] == nil {
+			coverage.Lines[normalizedPath] = make(map[int]bool)
+		}
+		for ln := startLine; ln <= endLine; ln++ {
+			coverage.Lines[normalizedPath][ln] = true
+		}
+
 		// If coverageCount > 0, mark ALL lines in the range as covered
 		if coverageCount > 0 {
-			normalizedPath := filepath.ToSlash(relPath)
 			if coverage.CoveredLines[normalizedPath] == nil {
 				coverage.CoveredLines[normalizedPath] = make(map[int]bool)
 			}
@@ -132,10 +195,35 @@ func parseCoverFile(coverFilePath, moduleName string) (*CoverageData, error) {
 		}
 	}
 
+	if coverage.Mode == "count" || coverage.Mode == "atomic" {
+		coverage.Hits = make(map[string]map[int]int)
+		for b, count := range hits {
+			if coverage.Hits[b.file] == nil {
+				coverage.Hits[b.file] = make(map[int]int)
+			}
+			for ln := b.start; ln <= b.end; ln++ {
+				coverage.Hits[b.file][ln] = max(coverage.Hits[b.file][ln], count)
+			}
+		}
+	}
 	return coverage, scanner.Err()
 }
 
-// parseDiffFile parses the diff with --unified=0 and returns DiffData with new/changed lines.
+// hunkHeaderRegex matches hunk headers: @@ -start,len +start,len @@
+var hunkHeaderRegex = regexp.MustCompile(`@@ -(\d+)(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// hunkRange returns the new-side lines of a hunk header matched by
+// hunkHeaderRegex, or false for a hunk that only deletes lines.
+func hunkRange(matches []string) (start, end int, ok bool) {
+	start, _ = strconv.Atoi(matches[2])
+	count := 1
+	if matches[3] != "" {
+		count, _ = strconv.Atoi(matches[3])
+	}
+	return start, start + count - 1, count > 0
+}
+
+// parseDiffFile parses the diff, with or without context lines, and returns DiffData with new/changed lines.
 func parseDiffFile(diffFilePath, moduleName string) (*DiffData, error) {
 	f, err := os.Open(diffFilePath)
 	if err != nil {
@@ -145,28 +233,35 @@ func parseDiffFile(diffFilePath, moduleName string) (*DiffData, error) {
 
 	diffData := &DiffData{
 		NewLines: make(map[string]map[int]bool),
+		Added:    make(map[string]bool),
+		Tests:    make(map[string]bool),
+		Hunks:    make(map[string][][2]int),
 	}
 
-	// Regex for @@ -start,len +start,len @@
-	hunkHeaderRegex := regexp.MustCompile(`@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
-
 	var currentFile string
 	var plusStartLine int
+	added := false
 
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		line := scanner.Text()
 
+		// New files have "--- /dev/null" before "+++ b/pkg/foo.go"
+		if strings.HasPrefix(line, "--- ") {
+			added = strings.TrimSpace(strings.TrimPrefix(line, "--- ")) == "/dev/null"
+			continue
+		}
+
 		// Example: "+++ b/pkg/foo.go"
 		if strings.HasPrefix(line, "+++ ") {
-			fields := strings.Fields(line)
-			if len(fields) >= 2 {
-				path := fields[1] // e.g. b/pkg/foo.go
-				path = strings.TrimPrefix(path, "b/")
-				// Prepend the module name
-				fullPath := filepath.Join(moduleName, path)
-				normalizedPath := filepath.ToSlash(fullPath)
-				currentFile = normalizedPath
+			if file, ok := diffFileKey(line, moduleName); ok {
+				currentFile = file
+				if added {
+					diffData.Added[file] = true
+				}
+				if strings.HasSuffix(file, "_test.go") {
+					diffData.Tests[file] = true
+				}
 			}
 			continue
 		}
@@ -177,6 +272,9 @@ func parseDiffFile(diffFilePath, moduleName string) (*DiffData, error) {
 			if len(matches) >= 3 {
 				newStart, _ := strconv.Atoi(matches[2])
 				plusStartLine = newStart
+				if start, end, ok := hunkRange(matches); ok && currentFile != "" {
+					diffData.Hunks[currentFile] = append(diffData.Hunks[currentFile], [2]int{start, end})
+				}
 			}
 			continue
 		}
@@ -186,19 +284,7 @@ func parseDiffFile(diffFilePath, moduleName string) (*DiffData, error) {
 			if currentFile == "" {
 				continue
 			}
-			// Skip test files
-			if strings.Contains(currentFile, "_test.go") {
-				continue
-			}
-
-			// Skip mock files
-			// @todo consider moving this to config
-			if strings.Contains(currentFile, "mock") {
-				continue
-			}
-
-			// Only handle .go files
-			if !strings.HasSuffix(currentFile, ".go") {
+			if SkipReason(currentFile) != "" {
 				continue
 			}
 
@@ -208,12 +294,48 @@ func parseDiffFile(diffFilePath, moduleName string) (*DiffData, error) {
 			}
 			diffData.NewLines[currentFile][plusStartLine] = true
 			plusStartLine++
+			continue
+		}
+
+		// Context lines of diffs with --unified > 0, e.g. fetched from a
+		// code host, advance the new line number too.
+		if line == "" || line[0] == ' ' {
+			plusStartLine++
 		}
 	}
 
 	return diffData, scanner.Err()
 }
 
+// SkipReason returns why the changed lines of file are never counted, or ""
+// if they can be.
+func SkipReason(file string) string {
+	switch {
+	case strings.Contains(file, "_test.go"):
+		return "test files are not counted"
+	case strings.Contains(file, "mock"):
+		// @todo consider moving this to config
+		return `files with "mock" in their path are not counted`
+	case !strings.HasSuffix(file, ".go"):
+		return "only Go files are counted"
+	}
+	return ""
+}
+
+// diffFileKey converts a "+++ b/pkg/foo.go" header into the module-prefixed
+// file key used by DiffData.
+func diffFileKey(line, moduleName string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "", false
+	}
+	path := fields[1] // e.g. b/pkg/foo.go
+	path = strings.TrimPrefix(path, "b/")
+	// Prepend the module name
+	fullPath := filepath.Join(moduleName, path)
+	return filepath.ToSlash(fullPath), true
+}
+
 // parseGoFiles parses only the given .go files and extracts the ranges of function lines.
 // Excludes the last line of each function from the range.
 func parseGoFiles(rootDir string, files []string) (*FuncLines, error) {
@@ -243,18 +365,7 @@ func parseGoFiles(rootDir string, files []string) (*FuncLines, error) {
 		normalizedPath := filepath.ToSlash(relPath)
 		for _, decl := range astFile.Decls {
 			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-				start := fset.Position(funcDecl.Pos()).Line
-				end := fset.Position(funcDecl.End()).Line
-
-				if funcDecl.Body != nil && len(funcDecl.Body.List) > 0 {
-					//more precise function start line
-					start = fset.Position(funcDecl.Body.List[0].Pos()).Line
-				}
-
-				// Exclude the last line
-				if end > start {
-					end--
-				}
+				start, end := bodyLines(fset, funcDecl)
 				funcLines.Functions[normalizedPath] = append(funcLines.Functions[normalizedPath], [2]int{start, end})
 			}
 		}
@@ -277,25 +388,7 @@ func isLineInFunctions(file string, line int, funcLines *FuncLines) bool {
 	return false
 }
 
-// GroupLinesIntoRanges groups a sorted list of lines into contiguous ranges.
+// GroupLinesIntoRanges groups a list of lines into contiguous ranges.
 func GroupLinesIntoRanges(lines []int) [][2]int {
-	if len(lines) == 0 {
-		return nil
-	}
-
-	var ranges [][2]int
-	start := lines[0]
-	end := lines[0]
-
-	for _, line := range lines[1:] {
-		if line == end+1 {
-			end = line
-		} else {
-			ranges = append(ranges, [2]int{start, end})
-			start = line
-			end = line
-		}
-	}
-	ranges = append(ranges, [2]int{start, end})
-	return ranges
+	return lineranges.Group(lines)
 }
@@ -95,6 +95,26 @@ github.com/example/module/internal/bar.go:5.0,6.0 1 2
 	}
 }
 
+// TestParseCoverFile_Statements checks statement totals count merged blocks once.
+func TestParseCoverFile_Statements(t *testing.T) {
+	tmpDir := t.TempDir()
+	coverFilePath := filepath.Join(tmpDir, "cover.out")
+	mustWriteFile(t, coverFilePath, `mode: set
+github.com/example/module/pkg/foo.go:10.0,12.0 2 0
+github.com/example/module/pkg/foo.go:15.0,15.10 1 0
+github.com/example/module/pkg/foo.go:10.0,12.0 2 1
+github.com/other/module/x.go:1.0,2.0 5 1
+`)
+
+	cd, err := parseCoverFile(coverFilePath, "github.com/example/module")
+	if err != nil {
+		t.Fatalf("parseCoverFile failed unexpectedly: %v", err)
+	}
+	if cd.Statements != 3 || cd.CoveredStatements != 2 {
+		t.Errorf("statements = %d/%d, want 2/3", cd.CoveredStatements, cd.Statements)
+	}
+}
+
 // TestParseCoverFile_FileOpenError tests when cover file cannot be opened.
 func TestParseCoverFile_FileOpenError(t *testing.T) {
 	moduleName := "github.com/example/module"
@@ -365,6 +385,23 @@ func Foo() {
 	}
 }
 
+// TestParseDiffFile_Context checks that context lines advance the line numbers.
+func TestParseDiffFile_Context(t *testing.T) {
+	diffFilePath := filepath.Join(t.TempDir(), "diff.txt")
+	diffFileContent := "--- a/pkg/foo.go\n+++ b/pkg/foo.go\n@@ -3,4 +3,5 @@ func f() {\n \tctx\n-\told\n+\tnew\n\n+\tadded\n \tctx\n"
+	if err := os.WriteFile(diffFilePath, []byte(diffFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write diff.txt: %v", err)
+	}
+	dd, err := parseDiffFile(diffFilePath, "example.com/m")
+	if err != nil {
+		t.Fatalf("parseDiffFile failed: %v", err)
+	}
+	lines := dd.NewLines["example.com/m/pkg/foo.go"]
+	if len(lines) != 2 || !lines[4] || !lines[6] {
+		t.Errorf("Expected lines 4 and 6, got %v", lines)
+	}
+}
+
 // TestParseDiffFile_FileOpenError covers the "os.Open(diffFilePath)" error case.
 func TestParseDiffFile_FileOpenError(t *testing.T) {
 	moduleName := "github.com/example/module"
@@ -560,3 +597,27 @@ func TestParseCoverFile_OpenError(t *testing.T) {
 		t.Fatalf("Expected an error for non-existent file, got nil")
 	}
 }
+
+// TestParseCoverFile_Hits sums the counts of repeated blocks and keeps the
+// largest count of the blocks on a line; set mode has none.
+func TestParseCoverFile_Hits(t *testing.T) {
+	tmpDir := t.TempDir()
+	coverFilePath := filepath.Join(tmpDir, "cover.out")
+	mustWriteFile(t, coverFilePath, `mode: count
+github.com/example/module/pkg/foo.go:10.0,12.5 2 3
+github.com/example/module/pkg/foo.go:12.5,13.0 1 7
+github.com/example/module/pkg/foo.go:10.0,12.5 2 4
+`)
+	cd, err := parseCoverFile(coverFilePath, "github.com/example/module")
+	if err != nil {
+		t.Fatalf("parseCoverFile failed unexpectedly: %v", err)
+	}
+	if want := map[int]int{10: 7, 11: 7, 12: 7, 13: 7}; !reflect.DeepEqual(cd.Hits["pkg/foo.go"], want) {
+		t.Errorf("Hits = %v, want %v", cd.Hits["pkg/foo.go"], want)
+	}
+
+	mustWriteFile(t, coverFilePath, "mode: set\ngithub.com/example/module/pkg/foo.go:10.0,12.5 2 1\n")
+	if cd, err = parseCoverFile(coverFilePath, "github.com/example/module"); err != nil || cd.Hits != nil {
+		t.Errorf("Expected no hits in set mode, got %v, %v", cd.Hits, err)
+	}
+}
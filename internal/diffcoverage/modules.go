@@ -0,0 +1,118 @@
+package diffcoverage
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Module is a Go module of the source tree; Dir is its directory relative to
+// the source root, "" for the root.
+type Module struct {
+	Path string
+	Dir  string
+}
+
+// FindModules returns the modules of the go.mod files under sourceRoot,
+// the root's first, skipping vendor, testdata and hidden directories.
+func FindModules(sourceRoot string) ([]Module, error) {
+	var modules []Module
+	err := filepath.WalkDir(sourceRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != sourceRoot && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+		modPath, err := parseGoMod(p)
+		if err != nil {
+			return nil // not a module the profile can refer to
+		}
+		dir, err := filepath.Rel(sourceRoot, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		if dir == "." {
+			dir = ""
+		}
+		modules = append(modules, Module{Path: modPath, Dir: filepath.ToSlash(dir)})
+		return nil
+	})
+	sort.SliceStable(modules, func(i, j int) bool { return modules[i].Dir == "" && modules[j].Dir != "" })
+	return modules, err
+}
+
+// rootModule returns the module path of sourceRoot's go.mod, or "" for a
+// workspace root with a go.work file but no go.mod.
+func rootModule(sourceRoot string) (string, error) {
+	name, err := parseGoMod(filepath.Join(sourceRoot, "go.mod"))
+	if err != nil {
+		if _, statErr := os.Stat(filepath.Join(sourceRoot, "go.work")); statErr == nil {
+			return "", nil
+		}
+		return "", err
+	}
+	return name, nil
+}
+
+// moduleResolver maps the import paths of profile entries to paths relative
+// to the source root. Nested modules are looked up before the root module,
+// whose path may be a prefix of theirs, as in a profile concatenated from the
+// tests of several modules of a workspace.
+type moduleResolver struct {
+	sourceRoot string
+	root       string // module path of the source root, if any
+	nested     []Module
+	loaded     bool
+
+	workspace       string // source root relative to the Bazel workspace
+	workspaceLoaded bool
+}
+
+// rel returns the path of file, e.g. example.com/m/pkg/a.go, relative to the
+// source root, or false if it is not in any module under it.
+func (r *moduleResolver) rel(file string) (string, bool) {
+	r.loadNested()
+	for _, m := range r.nested {
+		if rel, ok := strings.CutPrefix(file, m.Path+"/"); ok {
+			return path.Join(m.Dir, rel), true
+		}
+	}
+	if r.root != "" {
+		if rel, ok := strings.CutPrefix(file, r.root+"/"); ok {
+			return rel, true
+		}
+	}
+	return "", false
+}
+
+// loadNested finds the modules nested under the source root once.
+func (r *moduleResolver) loadNested() {
+	if r.loaded || r.sourceRoot == "" {
+		return
+	}
+	r.loaded = true
+	modules, err := FindModules(r.sourceRoot)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	for _, m := range modules {
+		if m.Dir != "" {
+			r.nested = append(r.nested, m)
+		}
+	}
+	// The longest module path wins, e.g. example.com/m/tools over
+	// example.com/m.
+	sort.Slice(r.nested, func(i, j int) bool { return len(r.nested[i].Path) > len(r.nested[j].Path) })
+}
@@ -0,0 +1,63 @@
+package diffcoverage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestAnalyze_MultiModuleProfile resolves the entries of nested modules.
+func TestAnalyze_MultiModuleProfile(t *testing.T) {
+	for _, rootMod := range []bool{true, false} {
+		dir := t.TempDir()
+		if rootMod {
+			writeGoMod(t, dir, "example.com/root")
+		} else {
+			mustWriteFile(t, filepath.Join(dir, "go.work"), "go 1.21\n\nuse ./services/api\n")
+		}
+		writeGoMod(t, filepath.Join(dir, "services", "api"), "example.com/api")
+		src := "package p\n\nfunc F() {\n\tprintln(1)\n\tprintln(2)\n}\n"
+		mustWriteFile(t, filepath.Join(dir, "services", "api", "p", "p.go"), src)
+		writeCoverFile(t, dir, "cover.out", `mode: set
+example.com/api/p/p.go:4.2,4.12 1 1
+example.com/api/p/p.go:5.2,5.12 1 0
+example.com/elsewhere/x.go:1.1,2.2 1 1
+`)
+		writeDiffFile(t, dir, "diff.diff", `+++ b/services/api/p/p.go
+@@ -3,0 +4,2 @@
++	println(1)
++	println(2)
+`)
+		a, err := Analyze(filepath.Join(dir, "cover.out"), filepath.Join(dir, "diff.diff"), dir)
+		if err != nil {
+			t.Fatalf("Analyze (root module %v): %v", rootMod, err)
+		}
+		r := a.Report(0)
+		if r.TotalLines != 2 || r.CoveredLines != 1 || len(r.Files) != 1 || r.Files[0].Path != "services/api/p/p.go" {
+			t.Errorf("root module %v: report %+v", rootMod, r)
+		}
+		if r.Uninstrumented != nil {
+			t.Errorf("root module %v: Uninstrumented = %+v", rootMod, r.Uninstrumented)
+		}
+	}
+}
+
+// TestModuleResolver_NestedUnderRootPath prefers a nested module whose path
+// extends the root module's path but differs from its directory.
+func TestModuleResolver_NestedUnderRootPath(t *testing.T) {
+	dir := t.TempDir()
+	writeGoMod(t, dir, "example.com/m")
+	writeGoMod(t, filepath.Join(dir, "apis"), "example.com/m/api")
+	r := &moduleResolver{sourceRoot: dir, root: "example.com/m"}
+	for file, want := range map[string]string{
+		"example.com/m/api/v1/a.go": "apis/v1/a.go",
+		"example.com/m/pkg/b.go":    "pkg/b.go",
+		"example.com/m/apis/c.go":   "apis/c.go",
+	} {
+		if got, ok := r.rel(file); !ok || got != want {
+			t.Errorf("rel(%s) = %q, %v, want %q", file, got, ok, want)
+		}
+	}
+	if _, ok := r.rel("example.com/other/d.go"); ok {
+		t.Errorf("rel resolved a file of another module")
+	}
+}
@@ -0,0 +1,635 @@
+package diffcoverage
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/version"
+	"github.com/JackShadow/go-new-code-coverage/lineranges"
+)
+
+// Report is the structured result of a diff coverage run, shared by all output
+// formats and integrations.
+type Report struct {
+	ToolVersion     string  `json:"toolVersion"`
+	Module          string  `json:"module,omitempty"`
+	Coverage        float64 `json:"coverage"`
+	MinCoverage     float64 `json:"minCoverage"`
+	Passed          bool    `json:"passed"`
+	Grade           string  `json:"grade,omitempty"` // of the coverage, if graded (see ApplyGrades)
+	TotalLines      int     `json:"totalLines"`
+	CoveredLines    int     `json:"coveredLines"`
+	ProjectCoverage float64 `json:"projectCoverage"` // statement coverage of the whole module
+	// Commit is the head commit of the diff and ProfileCommit the commit the
+	// coverage profile was generated at, when known.
+	Commit        string `json:"commit,omitempty"`
+	ProfileCommit string `json:"profileCommit,omitempty"`
+	// ChangedFunctions counts the functions with counted lines and
+	// CoveredFunctions those of them that are fully covered (see
+	// ApplyFunctions); FunctionCoverage is their ratio.
+	ChangedFunctions    int     `json:"changedFunctions"`
+	CoveredFunctions    int     `json:"coveredFunctions"`
+	FunctionCoverage    float64 `json:"functionCoverage"`
+	MinFunctionCoverage float64 `json:"minFunctionCoverage,omitempty"`
+	// FunctionScope is the coverage of the whole touched functions, in
+	// whole-function mode (see ApplyWholeFunctions).
+	FunctionScope *FunctionScope `json:"functionScope,omitempty"`
+	Files         []FileReport   `json:"files"`
+	// Uninstrumented are the packages of counted files without any block in
+	// the profile: their lines are uncovered because the tests did not
+	// instrument them, e.g. for lack of -coverpkg, not because tests miss them.
+	Uninstrumented []PackageReport `json:"uninstrumented,omitempty"`
+	// Targets break a report combined from several source roots, or split
+	// by module, down by target (see Combine and SplitModules).
+	Targets []TargetReport `json:"targets,omitempty"`
+	// Owners breaks the coverage down by CODEOWNERS owner (see ApplyOwners).
+	Owners []OwnerReport `json:"owners,omitempty"`
+	// Authors attributes the uncovered lines with git blame (see the blame package).
+	Authors []AuthorReport `json:"authors,omitempty"`
+	// Skeletons are generated tests for new, uncovered functions (see the
+	// testgen package).
+	Skeletons []TestSkeleton `json:"skeletons,omitempty"`
+	// Survivors are covered changed lines whose mutants all passed the tests
+	// (see the mutation package).
+	Survivors []Survivor `json:"survivors,omitempty"`
+	// Tests are the results of the go test run that produced the profile
+	// (see ApplyTests).
+	Tests *TestRun `json:"tests,omitempty"`
+	// FilesWithoutTests are new files in packages without any test file (see
+	// Analysis.FilesWithoutTests); they fail the report if TestFilesRequired.
+	FilesWithoutTests []string `json:"filesWithoutTests,omitempty"`
+	TestFilesRequired bool     `json:"testFilesRequired,omitempty"`
+	// ProfileProblems explain why the profile looks implausible (see
+	// Analysis.ProfileProblems); they fail the report if ProfileCheckFails.
+	ProfileProblems   []string `json:"profileProblems,omitempty"`
+	ProfileCheckFails bool     `json:"profileCheckFails,omitempty"`
+	// UntestedChanges are changed functions of packages whose tests the diff
+	// does not change (see ApplyUntestedChanges).
+	UntestedChanges []UntestedChange `json:"untestedChanges,omitempty"`
+	// Exceptions are the exemptions that applied to the diff and those that
+	// have expired, which fail the report (see the exceptions package).
+	Exceptions []Exception `json:"exceptions,omitempty"`
+	// Policies are the results of the configured policy expressions.
+	Policies []PolicyResult `json:"policies,omitempty"`
+	// Regressions lists declining coverage trends found in the run history.
+	Regressions []Regression `json:"regressions,omitempty"`
+	// Overrides are the pull request labels that changed the gate (see
+	// ApplyOverrides), and Warnings the failures their warn downgraded.
+	Overrides []LabelOverride `json:"overrides,omitempty"`
+	Warnings  []string        `json:"warnings,omitempty"`
+	// Profile is the coverage profile the report was computed from, SourceRoot
+	// the module root and Phases the analysis timings; none of them is part
+	// of the JSON report.
+	Profile    string  `json:"-"`
+	SourceRoot string  `json:"-"`
+	Phases     []Phase `json:"-"`
+
+	// statements and coveredStatements make up ProjectCoverage.
+	statements, coveredStatements int
+	// grades are the bands of ApplyGrades, kept to grade combined reports.
+	grades []GradeBand
+}
+
+// FunctionScope is the coverage of every instrumented line of the functions
+// touched by the diff, changed or not. Functions lists those not fully covered.
+// With MaxAgeDays set, unchanged lines last changed longer ago are not
+// counted; OldLines counts them.
+type FunctionScope struct {
+	TotalLines   int          `json:"totalLines"`
+	CoveredLines int          `json:"coveredLines"`
+	Coverage     float64      `json:"coverage"`
+	MinCoverage  float64      `json:"minCoverage"`
+	Passed       bool         `json:"passed"`
+	MaxAgeDays   int          `json:"maxAgeDays,omitempty"`
+	OldLines     int          `json:"oldLines,omitempty"`
+	Functions    []FuncReport `json:"functions,omitempty"`
+}
+
+// TestRun summarizes the go test run that produced the profile. The coverage
+// of failing packages is left out of the profile, so any fails the report.
+type TestRun struct {
+	Packages []TestPackage `json:"packages"`
+	Failed   int           `json:"failed"`
+	Elapsed  float64       `json:"elapsed"` // seconds, summed over the packages
+}
+
+// TestPackage is the result of the tests of a package: pass, fail or skip.
+type TestPackage struct {
+	Package     string   `json:"package"`
+	Result      string   `json:"result"`
+	Elapsed     float64  `json:"elapsed"` // seconds
+	FailedTests []string `json:"failedTests,omitempty"`
+}
+
+// FailedPackages returns the packages whose tests failed.
+func (t *TestRun) FailedPackages() []string {
+	var failed []string
+	for _, p := range t.Packages {
+		if p.Result == "fail" {
+			failed = append(failed, p.Package)
+		}
+	}
+	return failed
+}
+
+// TestSkeleton is a generated table-driven test for the function Func
+// declared at File:Line.
+type TestSkeleton struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Func     string   `json:"func"`
+	TestFile string   `json:"testFile"`
+	Code     string   `json:"code"`
+	Imports  []string `json:"imports"`
+	// TestLine is the last line of TestFile if the diff contains it, so the
+	// test can be suggested as a change appending to TestLineText; otherwise 0.
+	TestLine     int    `json:"testLine,omitempty"`
+	TestLineText string `json:"-"`
+}
+
+// Survivor is a covered changed line no test asserts on: every mutation of
+// it compiled and passed the tests.
+type Survivor struct {
+	File      string   `json:"file"`
+	Line      int      `json:"line"`
+	Mutations []string `json:"mutations"`
+}
+
+// UntestedChange is a changed function, declared at File:Line, whose package
+// tests were not changed. Required changes fail the report.
+type UntestedChange struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Func     string `json:"func"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// Exception is an exemption of the files matching Path, or of the function
+// Func in them, from diff coverage until Expires (YYYY-MM-DD).
+type Exception struct {
+	Path    string `json:"path"`
+	Func    string `json:"func,omitempty"`
+	Owner   string `json:"owner"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason,omitempty"`
+	Expired bool   `json:"expired,omitempty"`
+}
+
+// String describes the exempted code.
+func (e Exception) String() string {
+	if e.Func == "" {
+		return e.Path
+	}
+	return e.Path + ": " + e.Func
+}
+
+// PolicyResult is the outcome of a policy expression; Error is set if it
+// could not be evaluated, which fails it too.
+type PolicyResult struct {
+	Name   string `json:"name"`
+	Expr   string `json:"expr"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Regression is a coverage metric that declined over consecutive runs.
+type Regression struct {
+	Metric string  `json:"metric"` // "diff" or "project"
+	From   float64 `json:"from"`
+	To     float64 `json:"to"`
+	Runs   int     `json:"runs"`
+}
+
+// String describes the regression in one line.
+func (g Regression) String() string {
+	return fmt.Sprintf("%s coverage declined from %.2f%% to %.2f%% over the last %d runs", g.Metric, g.From, g.To, g.Runs)
+}
+
+// FileReport holds the counted new/changed lines of a single file.
+type FileReport struct {
+	Path         string   `json:"path"`
+	TotalLines   int      `json:"totalLines"`
+	CoveredLines int      `json:"coveredLines"`
+	Coverage     float64  `json:"coverage"`
+	Uncovered    [][2]int `json:"uncovered"`
+	// Covered are the ranges of covered counted lines, for exporters; they
+	// are not part of the JSON report.
+	Covered [][2]int `json:"-"`
+	// Hunks are the diff hunks of the file with counted lines, in order.
+	Hunks []HunkReport `json:"hunks,omitempty"`
+	// Hits are the covered lines as [start, end, times run] ranges of lines
+	// that ran equally often, if the profile has counts (count or atomic mode).
+	Hits [][3]int `json:"hits,omitempty"`
+}
+
+// HunkReport holds the counted lines of one diff hunk, spanning StartLine to
+// EndLine of the new file.
+type HunkReport struct {
+	StartLine    int     `json:"startLine"`
+	EndLine      int     `json:"endLine"`
+	TotalLines   int     `json:"totalLines"`
+	CoveredLines int     `json:"coveredLines"`
+	Coverage     float64 `json:"coverage"`
+}
+
+// OwnerReport holds the counted lines of the files owned by one owner. An
+// owner without its own minimum always passes.
+type OwnerReport struct {
+	Owner        string  `json:"owner"`
+	TotalLines   int     `json:"totalLines"`
+	CoveredLines int     `json:"coveredLines"`
+	Coverage     float64 `json:"coverage"`
+	MinCoverage  float64 `json:"minCoverage,omitempty"`
+	Passed       bool    `json:"passed"`
+}
+
+// PackageReport holds the counted lines of the files in one directory.
+type PackageReport struct {
+	Package      string  `json:"package"`
+	Files        int     `json:"files"`
+	TotalLines   int     `json:"totalLines"`
+	CoveredLines int     `json:"coveredLines"`
+	Coverage     float64 `json:"coverage"`
+}
+
+// AuthorReport counts the uncovered lines last changed by one author.
+type AuthorReport struct {
+	Author         string   `json:"author"`
+	Email          string   `json:"email,omitempty"`
+	UncoveredLines int      `json:"uncoveredLines"`
+	Commits        []string `json:"commits"`
+}
+
+// NewReport returns a passing report without any counted lines.
+func NewReport(minCoverage float64) *Report {
+	return &Report{
+		ToolVersion:      version.Get().Short(),
+		Coverage:         100.0,
+		MinCoverage:      minCoverage,
+		FunctionCoverage: 100.0,
+		Passed:           true,
+		Files:            []FileReport{},
+	}
+}
+
+// Report summarizes the counted new/changed lines per file, sorted by path.
+// Files without counted lines are left out.
+func (a *Analysis) Report(minCoverage float64) *Report {
+	r := NewReport(minCoverage)
+	r.Module = a.ModuleName
+	r.Profile = a.CoverPath
+	r.SourceRoot = a.SourceRoot
+	r.Phases = a.Phases
+	if a.Coverage != nil {
+		r.statements, r.coveredStatements = a.Coverage.Statements, a.Coverage.CoveredStatements
+		r.ProjectCoverage = percent(r.coveredStatements, r.statements)
+	}
+
+	for file, newLinesSet := range a.Diff.NewLines {
+		relFile := a.RelPath(file)
+		fr := FileReport{Path: relFile}
+		var covered, uncovered []int
+
+		for line := range newLinesSet {
+			switch a.Status(relFile, line) {
+			case LineCovered:
+				fr.TotalLines++
+				fr.CoveredLines++
+				covered = append(covered, line)
+			case LineUncovered:
+				fr.TotalLines++
+				uncovered = append(uncovered, line)
+			}
+		}
+		if fr.TotalLines == 0 {
+			continue
+		}
+
+		sort.Ints(covered)
+		sort.Ints(uncovered)
+		fr.Covered = GroupLinesIntoRanges(covered)
+		fr.Uncovered = GroupLinesIntoRanges(uncovered)
+		if hits := a.Coverage.Hits[relFile]; hits != nil {
+			fr.Hits = hitRanges(covered, hits)
+		}
+		for _, h := range a.Diff.Hunks[file] {
+			if hr := a.hunkReport(file, h[0], h[1]); hr.TotalLines > 0 {
+				fr.Hunks = append(fr.Hunks, hr)
+			}
+		}
+		fr.Coverage = percent(fr.CoveredLines, fr.TotalLines)
+		r.Files = append(r.Files, fr)
+		r.TotalLines += fr.TotalLines
+		r.CoveredLines += fr.CoveredLines
+	}
+
+	sort.Slice(r.Files, func(i, j int) bool { return r.Files[i].Path < r.Files[j].Path })
+	r.Coverage = percent(r.CoveredLines, r.TotalLines)
+	r.Passed = r.Coverage >= minCoverage
+	r.Uninstrumented = a.uninstrumented(r.Packages())
+	return r
+}
+
+// hitRanges groups the sorted lines into ranges of consecutive lines with the
+// same count.
+func hitRanges(lines []int, hits map[int]int) [][3]int {
+	var ranges [][3]int
+	for _, line := range lines {
+		if n := len(ranges); n > 0 && ranges[n-1][1] == line-1 && ranges[n-1][2] == hits[line] {
+			ranges[n-1][1] = line
+			continue
+		}
+		ranges = append(ranges, [3]int{line, line, hits[line]})
+	}
+	return ranges
+}
+
+// uninstrumented returns the packages without any file in the profile.
+func (a *Analysis) uninstrumented(pkgs []PackageReport) []PackageReport {
+	if a.Coverage == nil {
+		return nil
+	}
+	instrumented := map[string]bool{}
+	for file := range a.Coverage.Lines {
+		instrumented[path.Dir(file)] = true
+	}
+	var missing []PackageReport
+	for _, p := range pkgs {
+		if !instrumented[p.Package] {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// hunkReport counts the counted lines of file between start and end.
+func (a *Analysis) hunkReport(file string, start, end int) HunkReport {
+	hr := HunkReport{StartLine: start, EndLine: end}
+	for line := start; line <= end; line++ {
+		if !a.Diff.NewLines[file][line] {
+			continue
+		}
+		switch a.Status(a.RelPath(file), line) {
+		case LineCovered:
+			hr.TotalLines++
+			hr.CoveredLines++
+		case LineUncovered:
+			hr.TotalLines++
+		}
+	}
+	hr.Coverage = percent(hr.CoveredLines, hr.TotalLines)
+	return hr
+}
+
+// ApplyOwners attributes each file to the owners returned by owners, sorted
+// by owner, and fails the report if an owner's coverage is below its entry in
+// minCoverage. Files without owners are not attributed.
+func (r *Report) ApplyOwners(owners func(path string) []string, minCoverage map[string]float64) {
+	byOwner := map[string]*OwnerReport{}
+	for _, f := range r.Files {
+		for _, owner := range owners(f.Path) {
+			o := byOwner[owner]
+			if o == nil {
+				o = &OwnerReport{Owner: owner, MinCoverage: minCoverage[owner]}
+				byOwner[owner] = o
+			}
+			o.TotalLines += f.TotalLines
+			o.CoveredLines += f.CoveredLines
+		}
+	}
+
+	r.Owners = make([]OwnerReport, 0, len(byOwner))
+	for _, o := range byOwner {
+		o.Coverage = percent(o.CoveredLines, o.TotalLines)
+		o.Passed = o.Coverage >= o.MinCoverage
+		r.Owners = append(r.Owners, *o)
+		r.Passed = r.Passed && o.Passed
+	}
+	sort.Slice(r.Owners, func(i, j int) bool { return r.Owners[i].Owner < r.Owners[j].Owner })
+}
+
+// Packages aggregates the files per directory relative to the source root
+// ("." for the root), sorted by directory.
+func (r *Report) Packages() []PackageReport {
+	byDir := map[string]*PackageReport{}
+	for _, f := range r.Files {
+		dir := path.Dir(f.Path)
+		p := byDir[dir]
+		if p == nil {
+			p = &PackageReport{Package: dir}
+			byDir[dir] = p
+		}
+		p.Files++
+		p.TotalLines += f.TotalLines
+		p.CoveredLines += f.CoveredLines
+	}
+
+	pkgs := make([]PackageReport, 0, len(byDir))
+	for _, p := range byDir {
+		p.Coverage = percent(p.CoveredLines, p.TotalLines)
+		pkgs = append(pkgs, *p)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Package < pkgs[j].Package })
+	return pkgs
+}
+
+// ApplyFunctions counts the changed functions that are fully covered and
+// fails the report if their share is below minCoverage.
+func (r *Report) ApplyFunctions(funcs []FuncReport, minCoverage float64) {
+	r.ChangedFunctions, r.CoveredFunctions = len(funcs), 0
+	for _, fn := range funcs {
+		if fn.CoveredLines == fn.TotalLines {
+			r.CoveredFunctions++
+		}
+	}
+	r.FunctionCoverage = percent(r.CoveredFunctions, r.ChangedFunctions)
+	r.MinFunctionCoverage = minCoverage
+	r.Passed = r.Passed && r.FunctionCoverage >= minCoverage
+}
+
+// ApplyWholeFunctions holds the touched functions funcs (see
+// Analysis.Functions) to minCoverage as a whole, failing the report if the
+// coverage of all their lines is below it.
+func (r *Report) ApplyWholeFunctions(funcs []FuncReport, minCoverage float64) {
+	scope := &FunctionScope{MinCoverage: minCoverage}
+	for _, fn := range funcs {
+		scope.TotalLines += fn.TotalLines
+		scope.CoveredLines += fn.CoveredLines
+		if fn.CoveredLines < fn.TotalLines {
+			scope.Functions = append(scope.Functions, fn)
+		}
+	}
+	scope.Coverage = percent(scope.CoveredLines, scope.TotalLines)
+	scope.Passed = scope.Coverage >= minCoverage
+	r.FunctionScope = scope
+	r.Passed = r.Passed && scope.Passed
+}
+
+// ApplyTests records the results of the tested packages and fails the report
+// if any failed.
+func (r *Report) ApplyTests(pkgs []TestPackage) {
+	t := &TestRun{Packages: pkgs}
+	for _, p := range pkgs {
+		t.Elapsed += p.Elapsed
+		if p.Result == "fail" {
+			t.Failed++
+		}
+	}
+	r.Tests = t
+	r.Passed = r.Passed && t.Failed == 0
+}
+
+// ApplyExceptions records the exceptions and fails the report if any of
+// them has expired.
+func (r *Report) ApplyExceptions(exceptions []Exception) {
+	r.Exceptions = exceptions
+	for _, e := range exceptions {
+		r.Passed = r.Passed && !e.Expired
+	}
+}
+
+// ApplyPolicies records the policy results and fails the report if any
+// policy failed.
+func (r *Report) ApplyPolicies(results []PolicyResult) {
+	r.Policies = results
+	for _, p := range results {
+		r.Passed = r.Passed && p.Passed
+	}
+}
+
+// ApplyTestFiles records the new files without tests and, if required, fails
+// the report when there are any.
+func (r *Report) ApplyTestFiles(files []string, required bool) {
+	r.FilesWithoutTests = files
+	r.TestFilesRequired = required
+	if required && len(files) > 0 {
+		r.Passed = false
+	}
+}
+
+// ApplyProfileProblems records the problems of the profile and, if fail,
+// fails the report when there are any.
+func (r *Report) ApplyProfileProblems(problems []string, fail bool) {
+	r.ProfileProblems = problems
+	r.ProfileCheckFails = fail
+	if fail && len(problems) > 0 {
+		r.Passed = false
+	}
+}
+
+// LabelOverride is a pull request label whose configured overrides applied:
+// the thresholds it set, if any, and whether it turns failures into warnings.
+type LabelOverride struct {
+	Label               string   `json:"label"`
+	MinCoverage         *float64 `json:"minCoverage,omitempty"`
+	MinFunctionCoverage *float64 `json:"minFunctionCoverage,omitempty"`
+	Warn                bool     `json:"warn,omitempty"`
+}
+
+// ApplyOverrides records the label overrides, whose thresholds are already in
+// the report. If one of them warns, a failing report passes and its failures
+// become Warnings. It should be applied last.
+func (r *Report) ApplyOverrides(overrides []LabelOverride) {
+	r.Overrides = overrides
+	r.Warnings = nil
+	warn := false
+	for _, o := range overrides {
+		warn = warn || o.Warn
+	}
+	if !warn || r.Passed {
+		return
+	}
+	for _, err := range r.failures() {
+		r.Warnings = append(r.Warnings, err.Error())
+	}
+	r.Passed = true
+}
+
+// ApplyUntestedChanges records the changed functions without test changes
+// and fails the report if any of their files matches one of the required
+// glob patterns (see MatchPattern).
+func (r *Report) ApplyUntestedChanges(changes []UntestedChange, required []string) {
+	r.UntestedChanges = changes
+	for i := range r.UntestedChanges {
+		c := &r.UntestedChanges[i]
+		c.Required = MatchAny(required, c.File)
+		r.Passed = r.Passed && !c.Required
+	}
+}
+
+// Err returns the gate failure as an error, or nil if the report passed.
+func (r *Report) Err() error {
+	if r.Passed {
+		return nil
+	}
+	return errors.Join(r.failures()...)
+}
+
+// failures lists the reasons the report fails, regardless of Passed.
+func (r *Report) failures() []error {
+	var errs []error
+	if r.Coverage < r.MinCoverage {
+		errs = append(errs, fmt.Errorf("coverage %.2f%% is below the minimum required %.2f%%", r.Coverage, r.MinCoverage))
+	}
+	if r.FunctionCoverage < r.MinFunctionCoverage {
+		errs = append(errs, fmt.Errorf("%d of %d changed functions (%.2f%%) are fully covered, below the minimum required %.2f%%", r.CoveredFunctions, r.ChangedFunctions, r.FunctionCoverage, r.MinFunctionCoverage))
+	}
+	if s := r.FunctionScope; s != nil && !s.Passed {
+		errs = append(errs, fmt.Errorf("coverage %.2f%% of the touched functions is below the minimum required %.2f%%", s.Coverage, s.MinCoverage))
+	}
+	if t := r.Tests; t != nil && t.Failed > 0 {
+		errs = append(errs, fmt.Errorf("%d of %d test packages failed and their coverage is ignored: %s", t.Failed, len(t.Packages), strings.Join(t.FailedPackages(), ", ")))
+	}
+	for _, o := range r.Owners {
+		if !o.Passed {
+			errs = append(errs, fmt.Errorf("coverage %.2f%% of files owned by %s is below the minimum required %.2f%%", o.Coverage, o.Owner, o.MinCoverage))
+		}
+	}
+	if r.TestFilesRequired && len(r.FilesWithoutTests) > 0 {
+		errs = append(errs, fmt.Errorf("new files without tests in their package: %s", strings.Join(r.FilesWithoutTests, ", ")))
+	}
+	if r.ProfileCheckFails {
+		for _, p := range r.ProfileProblems {
+			errs = append(errs, errors.New(p))
+		}
+	}
+	var untested []string
+	for _, c := range r.UntestedChanges {
+		if c.Required {
+			untested = append(untested, c.File+": "+c.Func)
+		}
+	}
+	if len(untested) > 0 {
+		errs = append(errs, fmt.Errorf("changed functions whose package tests were not changed: %s", strings.Join(untested, ", ")))
+	}
+	for _, e := range r.Exceptions {
+		if e.Expired {
+			errs = append(errs, fmt.Errorf("coverage exception for %s owned by %s expired on %s", e, e.Owner, e.Expires))
+		}
+	}
+	for _, p := range r.Policies {
+		switch {
+		case p.Error != "":
+			errs = append(errs, fmt.Errorf("policy %q could not be evaluated: %s", p.Name, p.Error))
+		case !p.Passed:
+			errs = append(errs, fmt.Errorf("policy %q failed", p.Name))
+		}
+	}
+	return errs
+}
+
+// UncoveredLines expands the uncovered ranges into individual line numbers.
+func (f *FileReport) UncoveredLines() []int {
+	return lineranges.Lines(f.Uncovered)
+}
+
+// percent returns covered/total as a percentage; no lines count as fully covered.
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 100.0
+	}
+	return 100.0 * float64(covered) / float64(total)
+}
@@ -0,0 +1,250 @@
+package diffcoverage
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupReportAnalysis builds an Analysis over two files with mixed coverage.
+func setupReportAnalysis(t *testing.T) *Analysis {
+	t.Helper()
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	src := "package foo\n\nfunc Foo() {\n\tprintln(1)\n\tprintln(2)\n\tprintln(3)\n\tprintln(4)\n}\n"
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "b.go"), src)
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "a.go"), src)
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "c.go"), src)
+	writeCoverFile(t, tmpDir, "cover.out", `mode: set
+github.com/example/module/pkg/a.go:4.0,5.10 2 1
+github.com/example/module/pkg/b.go:4.0,7.10 4 1
+`)
+	writeDiffFile(t, tmpDir, "diff.diff", `+++ b/pkg/b.go
+@@ -3,0 +4,2 @@
++	println(1)
++	println(2)
++++ b/pkg/a.go
+@@ -3,0 +4,4 @@
++	println(1)
++	println(2)
++	println(3)
++	println(4)
++++ b/pkg/c.go
+@@ -1,0 +1,1 @@
++package foo
+`)
+	a, err := Analyze(filepath.Join(tmpDir, "cover.out"), filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	return a
+}
+
+// TestAnalysis_Report checks totals, per-file entries and ordering.
+func TestAnalysis_Report(t *testing.T) {
+	a := setupReportAnalysis(t)
+	r := a.Report(80)
+
+	if r.TotalLines != 6 || r.CoveredLines != 4 {
+		t.Errorf("totals = %d/%d, want 4/6", r.CoveredLines, r.TotalLines)
+	}
+	if r.Passed || r.MinCoverage != 80 || r.ToolVersion == "" {
+		t.Errorf("Unexpected report header %+v", r)
+	}
+	want := []FileReport{
+		{Path: "pkg/a.go", TotalLines: 4, CoveredLines: 2, Coverage: 50, Uncovered: [][2]int{{6, 7}}, Covered: [][2]int{{4, 5}},
+			Hunks: []HunkReport{{StartLine: 4, EndLine: 7, TotalLines: 4, CoveredLines: 2, Coverage: 50}}},
+		{Path: "pkg/b.go", TotalLines: 2, CoveredLines: 2, Coverage: 100, Covered: [][2]int{{4, 5}},
+			Hunks: []HunkReport{{StartLine: 4, EndLine: 5, TotalLines: 2, CoveredLines: 2, Coverage: 100}}},
+	}
+	if !reflect.DeepEqual(r.Files, want) {
+		t.Errorf("Files = %+v, want %+v", r.Files, want)
+	}
+
+	err := r.Err()
+	if err == nil || !strings.Contains(err.Error(), "66.67% is below the minimum required 80.00%") {
+		t.Errorf("Unexpected Err() %v", err)
+	}
+	if a.Report(50).Err() != nil {
+		t.Errorf("Expected report to pass at 50%%")
+	}
+}
+
+// TestAnalysis_Report_Uninstrumented lists packages missing from the profile.
+func TestAnalysis_Report_Uninstrumented(t *testing.T) {
+	a := setupReportAnalysis(t)
+	if r := a.Report(0); r.Uninstrumented != nil {
+		t.Errorf("Uninstrumented = %+v, want none", r.Uninstrumented)
+	}
+	a.Diff.NewLines["github.com/example/module/other/d.go"] = map[int]bool{4: true}
+	a.Funcs.Functions["other/d.go"] = [][2]int{{3, 8}}
+	want := []PackageReport{{Package: "other", Files: 1, TotalLines: 1}}
+	if r := a.Report(0); !reflect.DeepEqual(r.Uninstrumented, want) {
+		t.Errorf("Uninstrumented = %+v, want %+v", r.Uninstrumented, want)
+	}
+}
+
+// TestAnalysis_Report_Empty treats no counted lines as fully covered.
+func TestAnalysis_Report_Empty(t *testing.T) {
+	a := &Analysis{Diff: &DiffData{NewLines: map[string]map[int]bool{}}}
+	r := a.Report(90)
+	if r.Coverage != 100 || !r.Passed || r.Files == nil || len(r.Files) != 0 {
+		t.Errorf("Unexpected empty report %+v", r)
+	}
+}
+
+// TestFileReport_UncoveredLines expands ranges back into lines.
+func TestFileReport_UncoveredLines(t *testing.T) {
+	f := FileReport{Uncovered: [][2]int{{3, 5}, {9, 9}}}
+	if got, want := f.UncoveredLines(), []int{3, 4, 5, 9}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UncoveredLines = %v, want %v", got, want)
+	}
+}
+
+// TestReport_ApplyFunctions counts fully covered functions and gates on their share.
+func TestReport_ApplyFunctions(t *testing.T) {
+	funcs := []FuncReport{
+		{Name: "A", TotalLines: 3, CoveredLines: 3},
+		{Name: "B", TotalLines: 4, CoveredLines: 2},
+		{Name: "C", TotalLines: 1, CoveredLines: 1},
+		{Name: "D", TotalLines: 2, CoveredLines: 0},
+	}
+	r := NewReport(0)
+	r.ApplyFunctions(funcs, 50)
+	if r.ChangedFunctions != 4 || r.CoveredFunctions != 2 || r.FunctionCoverage != 50 || !r.Passed {
+		t.Errorf("Unexpected report %+v", r)
+	}
+
+	r.ApplyFunctions(funcs, 75)
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "2 of 4 changed functions (50.00%) are fully covered, below the minimum required 75.00%") {
+		t.Errorf("Unexpected Err() %v", err)
+	}
+}
+
+// TestReport_ApplyWholeFunctions gates on all lines of the touched functions
+// and lists those not fully covered.
+func TestReport_ApplyWholeFunctions(t *testing.T) {
+	funcs := []FuncReport{
+		{Name: "A", TotalLines: 6, CoveredLines: 6},
+		{Name: "B", TotalLines: 4, CoveredLines: 1},
+	}
+	r := NewReport(70)
+	r.ApplyWholeFunctions(funcs, 70)
+	s := r.FunctionScope
+	if s == nil || s.TotalLines != 10 || s.CoveredLines != 7 || s.Coverage != 70 || !s.Passed || !r.Passed {
+		t.Fatalf("Unexpected function scope %+v", s)
+	}
+	if len(s.Functions) != 1 || s.Functions[0].Name != "B" {
+		t.Errorf("Expected only B to be listed, got %+v", s.Functions)
+	}
+
+	r.ApplyWholeFunctions(funcs, 80)
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "coverage 70.00% of the touched functions is below the minimum required 80.00%") {
+		t.Errorf("Unexpected Err() %v", err)
+	}
+}
+
+// TestReport_ApplyTests checks failing test packages fail the report.
+func TestReport_ApplyTests(t *testing.T) {
+	r := NewReport(0)
+	r.ApplyTests([]TestPackage{
+		{Package: "example.com/m/a", Result: "pass", Elapsed: 1.5},
+		{Package: "example.com/m/b", Result: "fail", Elapsed: 0.5, FailedTests: []string{"TestB"}},
+	})
+	if r.Passed || r.Tests.Failed != 1 || r.Tests.Elapsed != 2 {
+		t.Fatalf("Unexpected tests %+v, passed %v", r.Tests, r.Passed)
+	}
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "1 of 2 test packages failed and their coverage is ignored: example.com/m/b") {
+		t.Errorf("Unexpected Err() %v", err)
+	}
+}
+
+// TestReport_Packages aggregates files per directory.
+func TestReport_Packages(t *testing.T) {
+	r := &Report{Files: []FileReport{
+		{Path: "a/x.go", TotalLines: 4, CoveredLines: 2},
+		{Path: "a/y.go", TotalLines: 4, CoveredLines: 4},
+		{Path: "main.go", TotalLines: 1, CoveredLines: 0},
+	}}
+	want := []PackageReport{
+		{Package: ".", Files: 1, TotalLines: 1, CoveredLines: 0, Coverage: 0},
+		{Package: "a", Files: 2, TotalLines: 8, CoveredLines: 6, Coverage: 75},
+	}
+	if got := r.Packages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Packages = %+v, want %+v", got, want)
+	}
+}
+
+// TestReport_ApplyOwners aggregates files per owner and applies owner minimums.
+func TestReport_ApplyOwners(t *testing.T) {
+	r := &Report{Coverage: 60, MinCoverage: 50, Passed: true, Files: []FileReport{
+		{Path: "platform/a.go", TotalLines: 4, CoveredLines: 2},
+		{Path: "shared/b.go", TotalLines: 6, CoveredLines: 4},
+		{Path: "tools/c.go", TotalLines: 1, CoveredLines: 0},
+	}}
+	owners := map[string][]string{
+		"platform/a.go": {"@platform"},
+		"shared/b.go":   {"@platform", "@app"},
+	}
+	r.ApplyOwners(func(path string) []string { return owners[path] }, map[string]float64{"@platform": 70})
+
+	want := []OwnerReport{
+		{Owner: "@app", TotalLines: 6, CoveredLines: 4, Coverage: 100.0 * 4 / 6, Passed: true},
+		{Owner: "@platform", TotalLines: 10, CoveredLines: 6, Coverage: 60, MinCoverage: 70},
+	}
+	if !reflect.DeepEqual(r.Owners, want) {
+		t.Errorf("Owners = %+v, want %+v", r.Owners, want)
+	}
+	if r.Passed {
+		t.Errorf("Expected the report to fail on the @platform minimum")
+	}
+	if err := r.Err(); err == nil || err.Error() != "coverage 60.00% of files owned by @platform is below the minimum required 70.00%" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+// TestReport_ApplyPolicies fails the report on failed or erroneous policies.
+func TestReport_ApplyPolicies(t *testing.T) {
+	r := NewReport(0)
+	r.ApplyPolicies([]PolicyResult{{Name: "ok", Passed: true}})
+	if !r.Passed {
+		t.Errorf("Expected passing policies to pass the report")
+	}
+	r.ApplyPolicies([]PolicyResult{{Name: "billing"}, {Name: "typo", Error: "undeclared reference to x"}})
+	err := r.Err()
+	if r.Passed || err == nil || !strings.Contains(err.Error(), `policy "billing" failed`) || !strings.Contains(err.Error(), `policy "typo" could not be evaluated: undeclared reference to x`) {
+		t.Errorf("Unexpected Err() %v", err)
+	}
+}
+
+// TestReport_ApplyOverrides turns failures into warnings only for a warning label.
+func TestReport_ApplyOverrides(t *testing.T) {
+	strict := 95.0
+	r := &Report{TotalLines: 10, CoveredLines: 9, Coverage: 90, MinCoverage: strict}
+	r.ApplyOverrides([]LabelOverride{{Label: "strict-coverage", MinCoverage: &strict}})
+	if r.Passed || r.Warnings != nil || len(r.Overrides) != 1 {
+		t.Errorf("Unexpected report %+v", r)
+	}
+	r.ApplyOverrides([]LabelOverride{{Label: "skip-diff-coverage", Warn: true}})
+	if !r.Passed || r.Err() != nil || !reflect.DeepEqual(r.Warnings, []string{"coverage 90.00% is below the minimum required 95.00%"}) {
+		t.Errorf("Unexpected report %+v", r)
+	}
+	c := Combine([]string{"a"}, []*Report{r}, strict)
+	if !c.Passed || len(c.Warnings) != 1 || c.Overrides[0].Label != "skip-diff-coverage" {
+		t.Errorf("Unexpected combined report %+v", c)
+	}
+}
+
+// TestAnalysis_Report_Hits groups covered lines run equally often.
+func TestAnalysis_Report_Hits(t *testing.T) {
+	a := setupReportAnalysis(t)
+	a.Coverage.Hits = map[string]map[int]int{"pkg/a.go": {4: 1, 5: 1}, "pkg/b.go": {4: 2, 5: 30}}
+	r := a.Report(0)
+	if want := [][3]int{{4, 5, 1}}; !reflect.DeepEqual(r.Files[0].Hits, want) {
+		t.Errorf("pkg/a.go hits = %v, want %v", r.Files[0].Hits, want)
+	}
+	if want := [][3]int{{4, 4, 2}, {5, 5, 30}}; !reflect.DeepEqual(r.Files[1].Hits, want) {
+		t.Errorf("pkg/b.go hits = %v, want %v", r.Files[1].Hits, want)
+	}
+}
@@ -2,94 +2,193 @@ package diffcoverage
 
 import (
 	"fmt"
-	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
-// RunDiffCoverage runs the main diff-coverage logic and returns:
-//   - coveragePercent (float64)
-//   - uncovered map[file][]lines
-//   - error if coverage below minCoverage or parse failures
-func RunDiffCoverage(coverPath, diffPath, sourceRoot string, minCoverage float64) (float64, map[string][]int, error) {
+// Analysis holds the parsed inputs of a diff coverage run.
+type Analysis struct {
+	ModuleName string
+	CoverPath  string
+	SourceRoot string
+	Coverage   *CoverageData
+	Diff       *DiffData
+	Funcs      *FuncLines
+	// Phases records how long each parsing step of Analyze took.
+	Phases []Phase
+}
+
+// Phase is a timed step of the analysis.
+type Phase struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
 
-	moduleName, err := parseGoMod(filepath.Join(sourceRoot, "go.mod"))
+// LineStatus describes how a new/changed line counts towards diff coverage.
+type LineStatus int
+
+const (
+	// LineIgnored marks lines that are not counted (e.g. outside function bodies).
+	LineIgnored LineStatus = iota
+	// LineCovered marks counted lines hit by at least one test.
+	LineCovered
+	// LineUncovered marks counted lines no test executed.
+	LineUncovered
+)
+
+// Analyze parses go.mod, the cover file, the diff file and the changed Go files under sourceRoot.
+func Analyze(coverPath, diffPath, sourceRoot string) (*Analysis, error) {
+	var phases []Phase
+	timed := func(name string, start time.Time) {
+		phases = append(phases, Phase{Name: name, Start: start, End: time.Now()})
+	}
+
+	start := time.Now()
+	moduleName, err := rootModule(sourceRoot)
 	if err != nil {
-		return 0, nil, fmt.Errorf("error parsing go.mod: %v", err)
+		return nil, fmt.Errorf("error parsing go.mod: %v", err)
 	}
 
-	coverageData, err := parseCoverFile(coverPath, moduleName)
+	coverageData, err := parseCoverFileIn(coverPath, &moduleResolver{sourceRoot: sourceRoot, root: moduleName})
 	if err != nil {
-		return 0, nil, fmt.Errorf("error parsing cover file: %v", err)
+		return nil, fmt.Errorf("error parsing cover file: %v", err)
 	}
+	timed("parse_cover", start)
 
+	start = time.Now()
 	diffData, err := parseDiffFile(diffPath, moduleName)
 	if err != nil {
-		return 0, nil, fmt.Errorf("error parsing diff file: %v", err)
+		return nil, fmt.Errorf("error parsing diff file: %v", err)
+	}
+	timed("parse_diff", start)
+
+	a := &Analysis{
+		ModuleName: moduleName,
+		CoverPath:  coverPath,
+		SourceRoot: sourceRoot,
+		Coverage:   coverageData,
+		Diff:       diffData,
+	}
+	if err := a.excludeCoverIgnored(); err != nil {
+		return nil, err
 	}
 
+	start = time.Now()
 	var filesToAnalyze []string
 	for file := range diffData.NewLines {
-		if strings.HasPrefix(file, moduleName+"/") {
-			relFile := strings.TrimPrefix(file, moduleName+"/")
-			filesToAnalyze = append(filesToAnalyze, relFile)
-		} else {
-			filesToAnalyze = append(filesToAnalyze, file)
-		}
-	}
-
-	if len(filesToAnalyze) == 0 {
-		// No new/changed Go files found
-		return 100.0, nil, nil
+		filesToAnalyze = append(filesToAnalyze, a.RelPath(file))
 	}
 
 	funcLines, err := parseGoFiles(sourceRoot, filesToAnalyze)
 	if err != nil {
-		return 0, nil, fmt.Errorf("error parsing go files: %v", err)
+		return nil, fmt.Errorf("error parsing go files: %v", err)
 	}
+	a.Funcs = funcLines
+	timed("analyze", start)
+	a.Phases = phases
 
-	totalNewLines := 0
-	coveredNewLines := 0
-	uncoveredLinesMap := make(map[string][]int)
+	return a, nil
+}
 
-	for file, newLinesSet := range diffData.NewLines {
-		var relFile string
-		if strings.HasPrefix(file, moduleName+"/") {
-			relFile = strings.TrimPrefix(file, moduleName+"/")
-		} else {
-			relFile = file
-		}
+// CountedFiles returns the files of the diff, relative to sourceRoot, with at
+// least one new/changed line inside a function body: only their packages can
+// produce coverage that counts.
+func CountedFiles(diffPath, sourceRoot string) ([]string, error) {
+	moduleName, err := rootModule(sourceRoot)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing go.mod: %v", err)
+	}
+	diffData, err := parseDiffFile(diffPath, moduleName)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing diff file: %v", err)
+	}
+	a := &Analysis{ModuleName: moduleName, SourceRoot: sourceRoot, Diff: diffData}
+	if err := a.excludeCoverIgnored(); err != nil {
+		return nil, err
+	}
+	var files []string
+	for file := range diffData.NewLines {
+		files = append(files, a.RelPath(file))
+	}
+	funcLines, err := parseGoFiles(sourceRoot, files)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing go files: %v", err)
+	}
 
-		for line := range newLinesSet {
-			// Only consider lines inside functions
-			if !isLineInFunctions(relFile, line, funcLines) {
-				continue
-			}
-			totalNewLines++
-			if coverageData.CoveredLines[relFile] != nil && coverageData.CoveredLines[relFile][line] {
-				coveredNewLines++
-			} else {
-				uncoveredLinesMap[relFile] = append(uncoveredLinesMap[relFile], line)
+	var counted []string
+	for file, lines := range a.Diff.NewLines {
+		for line := range lines {
+			if isLineInFunctions(a.RelPath(file), line, funcLines) {
+				counted = append(counted, a.RelPath(file))
+				break
 			}
 		}
 	}
+	sort.Strings(counted)
+	return counted, nil
+}
+
+// RelPath strips the module prefix from a DiffData file key.
+func (a *Analysis) RelPath(file string) string {
+	return strings.TrimPrefix(file, a.ModuleName+"/")
+}
 
-	// Sort the uncovered lines
-	for file := range uncoveredLinesMap {
-		sort.Ints(uncoveredLinesMap[file])
+// Status classifies a line of a file given relative to the source root.
+func (a *Analysis) Status(relFile string, line int) LineStatus {
+	// Only consider lines inside functions
+	if !isLineInFunctions(relFile, line, a.Funcs) {
+		return LineIgnored
 	}
+	if a.Coverage.CoveredLines[relFile] != nil && a.Coverage.CoveredLines[relFile][line] {
+		return LineCovered
+	}
+	return LineUncovered
+}
 
-	if totalNewLines == 0 {
-		// Means we found changed go files, but no lines inside function bodies
-		return 100.0, uncoveredLinesMap, nil
+// Exclude drops changed files matching any of the glob patterns (see MatchPattern).
+func (a *Analysis) Exclude(patterns []string) {
+	if len(patterns) == 0 {
+		return
+	}
+	for file := range a.Diff.NewLines {
+		if MatchAny(patterns, a.RelPath(file)) {
+			delete(a.Diff.NewLines, file)
+			delete(a.Diff.Added, file)
+		}
 	}
+}
 
-	coveragePercent := 100.0 * float64(coveredNewLines) / float64(totalNewLines)
+// RunDiffCoverage runs the main diff-coverage logic and returns:
+//   - coveragePercent (float64)
+//   - uncovered map[file][]lines
+//   - error if coverage below minCoverage or parse failures
+func RunDiffCoverage(coverPath, diffPath, sourceRoot string, minCoverage float64) (float64, map[string][]int, error) {
+	a, err := Analyze(coverPath, diffPath, sourceRoot)
+	if err != nil {
+		return 0, nil, err
+	}
+	return a.Evaluate(minCoverage)
+}
+
+// Evaluate computes the coverage of the counted new/changed lines and returns:
+//   - coveragePercent (float64)
+//   - uncovered map[file][]lines
+//   - error if coverage below minCoverage
+func (a *Analysis) Evaluate(minCoverage float64) (float64, map[string][]int, error) {
+	if len(a.Diff.NewLines) == 0 {
+		// No new/changed Go files found
+		return 100.0, nil, nil
+	}
 
-	if coveragePercent < minCoverage {
-		return coveragePercent, uncoveredLinesMap,
-			fmt.Errorf("coverage %.2f%% is below the minimum required %.2f%%", coveragePercent, minCoverage)
+	r := a.Report(minCoverage)
+	uncoveredLinesMap := make(map[string][]int)
+	for i := range r.Files {
+		if lines := r.Files[i].UncoveredLines(); len(lines) > 0 {
+			uncoveredLinesMap[r.Files[i].Path] = lines
+		}
 	}
 
-	return coveragePercent, uncoveredLinesMap, nil
+	return r.Coverage, uncoveredLinesMap, r.Err()
 }
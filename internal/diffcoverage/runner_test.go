@@ -192,6 +192,131 @@ func Foo() {
 
 }
 
+// TestAnalyze_Status checks line classification on a parsed Analysis.
+func TestAnalyze_Status(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	writeCoverFile(t, tmpDir, "cover.out", `mode: set
+github.com/example/module/pkg/foo.go:4.0,4.10 1 1
+`)
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "foo.go"), `package foo
+
+func Foo() {
+	println(1)
+	println(2)
+}
+`)
+	writeDiffFile(t, tmpDir, "diff.diff", `+++ b/pkg/foo.go
+@@ -3,0 +4,2 @@
++	println(1)
++	println(2)
+`)
+
+	a, err := Analyze(filepath.Join(tmpDir, "cover.out"), filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if a.ModuleName != "github.com/example/module" {
+		t.Errorf("ModuleName = %q", a.ModuleName)
+	}
+	if got := a.RelPath("github.com/example/module/pkg/foo.go"); got != "pkg/foo.go" {
+		t.Errorf("RelPath = %q, want pkg/foo.go", got)
+	}
+	if len(a.Phases) != 3 || a.Phases[0].Name != "parse_cover" || a.Phases[2].End.Before(a.Phases[2].Start) {
+		t.Errorf("Unexpected phases %+v", a.Phases)
+	}
+
+	cases := []struct {
+		line int
+		want LineStatus
+	}{
+		{1, LineIgnored},
+		{4, LineCovered},
+		{5, LineUncovered},
+		{6, LineIgnored},
+	}
+	for _, c := range cases {
+		if got := a.Status("pkg/foo.go", c.line); got != c.want {
+			t.Errorf("Status(pkg/foo.go, %d) = %v, want %v", c.line, got, c.want)
+		}
+	}
+}
+
+// TestAnalysis_Exclude checks excluded files no longer count.
+func TestAnalysis_Exclude(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	writeCoverFile(t, tmpDir, "cover.out", "mode: set\n")
+	src := "package foo\n\nfunc Foo() {\n\tprintln(1)\n}\n"
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "foo.go"), src)
+	mustWriteFile(t, filepath.Join(tmpDir, "api", "foo.pb.go"), src)
+	writeDiffFile(t, tmpDir, "diff.diff", `+++ b/pkg/foo.go
+@@ -3,0 +4,1 @@
++	println(1)
++++ b/api/foo.pb.go
+@@ -3,0 +4,1 @@
++	println(1)
+`)
+
+	a, err := Analyze(filepath.Join(tmpDir, "cover.out"), filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	a.Exclude(nil)
+	if len(a.Diff.NewLines) != 2 {
+		t.Fatalf("Expected 2 files before excluding, got %d", len(a.Diff.NewLines))
+	}
+
+	a.Exclude([]string{"*.pb.go"})
+	_, uncovered, err := a.Evaluate(0)
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if _, ok := uncovered["api/foo.pb.go"]; ok {
+		t.Errorf("Excluded file still reported: %#v", uncovered)
+	}
+	if len(uncovered["pkg/foo.go"]) != 1 {
+		t.Errorf("Expected pkg/foo.go line 4 uncovered, got %#v", uncovered)
+	}
+
+	a.Exclude([]string{"pkg/**"})
+	coverPercent, uncovered, err := a.Evaluate(90)
+	if err != nil || coverPercent != 100.0 || uncovered != nil {
+		t.Errorf("Expected (100, nil, nil) with everything excluded, got (%.2f, %#v, %v)", coverPercent, uncovered, err)
+	}
+}
+
+// TestCountedFiles checks files changed only outside function bodies are dropped.
+func TestCountedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	src := "package foo\n\n// Foo prints.\nfunc Foo() {\n\tprintln(1)\n}\n"
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "foo.go"), src)
+	mustWriteFile(t, filepath.Join(tmpDir, "doc", "foo.go"), src)
+	writeDiffFile(t, tmpDir, "diff.diff", `+++ b/pkg/foo.go
+@@ -4,0 +5,1 @@
++	println(1)
++++ b/doc/foo.go
+@@ -2,0 +3,1 @@
++// Foo prints.
++++ b/pkg/foo_test.go
+@@ -0,0 +1,1 @@
++package foo
+`)
+
+	files, err := CountedFiles(filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("CountedFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "pkg/foo.go" {
+		t.Errorf("CountedFiles = %v, want [pkg/foo.go]", files)
+	}
+
+	if _, err := CountedFiles(filepath.Join(tmpDir, "missing.diff"), tmpDir); err == nil {
+		t.Errorf("Expected error for a missing diff, got nil")
+	}
+}
+
 // ---------------------------------------------------------------
 // Helper functions to keep test code DRY
 // ---------------------------------------------------------------
@@ -0,0 +1,89 @@
+package diffcoverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// ProfileChecks lists what an implausible profile does: warn, fail the run,
+// or nothing.
+var ProfileChecks = []string{"warn", "fail", "off"}
+
+// ValidProfileCheck reports whether check is one of ProfileChecks, or empty.
+func ValidProfileCheck(check string) bool {
+	for _, c := range ProfileChecks {
+		if check == c {
+			return true
+		}
+	}
+	return check == ""
+}
+
+// ProfileProblems explains why the profile looks wrong for the diff of the
+// report r of a: it has no statements of the module, it instruments none of
+// the changed packages, or it has fewer than minStatements percent of the
+// statements of the Go files under the source root (not checked if 0). Such a
+// profile comes from a misconfigured pipeline, and its 0% or 100% means
+// nothing.
+func (a *Analysis) ProfileProblems(r *Report, minStatements float64) ([]string, error) {
+	if a.Coverage == nil {
+		return nil, nil
+	}
+	if a.Coverage.Statements == 0 {
+		return []string{"the coverage profile has no statements of the module; did the tests run, for this module?"}, nil
+	}
+	var problems []string
+	if pkgs := r.Packages(); len(pkgs) > 0 && len(r.Uninstrumented) == len(pkgs) {
+		problems = append(problems, fmt.Sprintf("the coverage profile instruments none of the %d changed packages; was it generated for other packages, or without -coverpkg?", len(pkgs)))
+	}
+	if minStatements > 0 {
+		total, err := moduleStatements(a.SourceRoot)
+		if err != nil {
+			return nil, err
+		}
+		if share := percent(a.Coverage.Statements, total); total > 0 && share < minStatements {
+			problems = append(problems, fmt.Sprintf("the coverage profile has %d statements, %.2f%% of the %d statements of the module, fewer than the expected %.2f%%", a.Coverage.Statements, share, total, minStatements))
+		}
+	}
+	return problems, nil
+}
+
+// moduleStatements counts the statements of the non-test Go files under
+// root, roughly as go test -cover does.
+func moduleStatements(root string) (int, error) {
+	total := 0
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil // go test would not build it either
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n.(type) {
+			case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause, *ast.LabeledStmt, *ast.EmptyStmt:
+			case ast.Stmt:
+				total++
+			}
+			return true
+		})
+		return nil
+	})
+	return total, err
+}
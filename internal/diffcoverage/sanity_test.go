@@ -0,0 +1,36 @@
+package diffcoverage
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestAnalysis_ProfileProblems covers the empty, misdirected and too small profiles.
+func TestAnalysis_ProfileProblems(t *testing.T) {
+	a := setupReportAnalysis(t)
+	if problems, err := a.ProfileProblems(a.Report(0), 40); err != nil || len(problems) != 0 {
+		t.Fatalf("ProfileProblems = %v, %v; want none", problems, err)
+	}
+	problems, err := a.ProfileProblems(a.Report(0), 60)
+	if err != nil || len(problems) != 1 || !strings.Contains(problems[0], "6 statements, 50.00% of the 12 statements") {
+		t.Errorf("ProfileProblems(60) = %v, %v", problems, err)
+	}
+
+	a.Coverage.Lines = map[string]map[int]bool{"other/x.go": {1: true}}
+	problems, _ = a.ProfileProblems(a.Report(0), 0)
+	if len(problems) != 1 || !strings.Contains(problems[0], "none of the 1 changed packages") {
+		t.Errorf("ProfileProblems of another package = %v", problems)
+	}
+
+	a.Coverage.Statements = 0
+	problems, _ = a.ProfileProblems(a.Report(0), 0)
+	if len(problems) != 1 || !strings.Contains(problems[0], "no statements of the module") {
+		t.Errorf("ProfileProblems of an empty profile = %v", problems)
+	}
+
+	r := a.Report(0)
+	r.ApplyProfileProblems(problems, true)
+	if r.Passed || r.Err() == nil {
+		t.Errorf("Expected the profile problems to fail the report")
+	}
+}
@@ -0,0 +1,63 @@
+package diffcoverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Gutter markers used by ShowFile.
+const (
+	gutterCovered   = "+" // changed and covered
+	gutterUncovered = "!" // changed and not covered
+	gutterIgnored   = "~" // changed but not counted (outside function bodies)
+	gutterUnchanged = " "
+)
+
+// DiffKey converts a file relative to the source root into its DiffData key.
+func (a *Analysis) DiffKey(relFile string) string {
+	return filepath.ToSlash(filepath.Join(a.ModuleName, relFile))
+}
+
+// ShowFile prints relFile with a gutter marking changed+covered, changed+uncovered
+// and unchanged lines.
+func ShowFile(w io.Writer, sourceRoot, relFile string, a *Analysis) error {
+	relFile = filepath.ToSlash(filepath.Clean(relFile))
+
+	f, err := os.Open(filepath.Join(sourceRoot, relFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "File: %s (%s covered, %s uncovered, %s not counted)\n",
+		relFile, gutterCovered, gutterUncovered, gutterIgnored)
+
+	changed := a.Diff.NewLines[a.DiffKey(relFile)]
+
+	lineNum := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineNum++
+		gutter := gutterUnchanged
+		if changed[lineNum] {
+			switch a.Status(relFile, lineNum) {
+			case LineCovered:
+				gutter = gutterCovered
+			case LineUncovered:
+				gutter = gutterUncovered
+			default:
+				gutter = gutterIgnored
+			}
+		}
+		fmt.Fprintf(out, "%s %5d | %s\n", gutter, lineNum, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
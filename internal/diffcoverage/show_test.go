@@ -0,0 +1,70 @@
+package diffcoverage
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestShowFile checks the gutter for each kind of line.
+func TestShowFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	mustWriteFile(t, filepath.Join(tmpDir, "pkg", "foo.go"), `package foo
+
+func Foo() int {
+	a := 1
+	b := 2
+	return a + b
+}
+
+var x = 1
+`)
+	writeCoverFile(t, tmpDir, "cover.out", `mode: set
+github.com/example/module/pkg/foo.go:4.0,4.10 1 1
+`)
+	writeDiffFile(t, tmpDir, "diff.diff", `+++ b/pkg/foo.go
+@@ -3,0 +4,2 @@
++	a := 1
++	b := 2
+@@ -8,0 +9,1 @@
++var x = 1
+`)
+
+	a, err := Analyze(filepath.Join(tmpDir, "cover.out"), filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ShowFile(&buf, tmpDir, "./pkg/foo.go", a); err != nil {
+		t.Fatalf("ShowFile failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("Expected header + 9 source lines, got %d:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "File: pkg/foo.go") {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	wantGutters := map[int]string{1: " ", 3: " ", 4: "+", 5: "!", 6: " ", 9: "~"}
+	for ln, gutter := range wantGutters {
+		if got := lines[ln][:1]; got != gutter {
+			t.Errorf("line %d gutter = %q, want %q (%q)", ln, got, gutter, lines[ln])
+		}
+	}
+	if want := "+     4 | \ta := 1"; lines[4] != want {
+		t.Errorf("line 4 = %q, want %q", lines[4], want)
+	}
+}
+
+// TestShowFile_FileOpenError covers a file missing from the source root.
+func TestShowFile_FileOpenError(t *testing.T) {
+	a := &Analysis{ModuleName: "github.com/example/module", Diff: &DiffData{}}
+	var buf bytes.Buffer
+	if err := ShowFile(&buf, t.TempDir(), "missing.go", a); err == nil {
+		t.Fatalf("Expected error for missing file, got nil")
+	}
+}
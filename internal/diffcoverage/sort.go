@@ -0,0 +1,50 @@
+package diffcoverage
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SortOrders lists the orders of SortFiles: by path, by coverage (lowest
+// first) and by uncovered lines (most first).
+var SortOrders = []string{"file", "coverage", "uncovered"}
+
+// ValidSortOrder reports whether order is one of SortOrders, or empty.
+func ValidSortOrder(order string) bool {
+	for _, o := range SortOrders {
+		if order == o {
+			return true
+		}
+	}
+	return order == ""
+}
+
+// SortFiles orders the files of the report, which every output format lists
+// in this order. Ties are broken by path, so the order is stable across runs;
+// "" is "file", the order of NewReport.
+func (r *Report) SortFiles(order string) error {
+	var less func(a, b *FileReport) bool
+	switch order {
+	case "", "file":
+		less = func(a, b *FileReport) bool { return false }
+	case "coverage":
+		less = func(a, b *FileReport) bool { return a.Coverage < b.Coverage }
+	case "uncovered":
+		less = func(a, b *FileReport) bool {
+			return a.TotalLines-a.CoveredLines > b.TotalLines-b.CoveredLines
+		}
+	default:
+		return fmt.Errorf("unknown sort order %q: must be file, coverage or uncovered", order)
+	}
+	sort.Slice(r.Files, func(i, j int) bool {
+		a, b := &r.Files[i], &r.Files[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Path < b.Path
+	})
+	return nil
+}
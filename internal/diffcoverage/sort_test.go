@@ -0,0 +1,38 @@
+package diffcoverage
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestReport_SortFiles checks each order and the tie-break by path.
+func TestReport_SortFiles(t *testing.T) {
+	files := []FileReport{
+		{Path: "c.go", TotalLines: 10, CoveredLines: 5, Coverage: 50},
+		{Path: "a.go", TotalLines: 2, CoveredLines: 1, Coverage: 50},
+		{Path: "b.go", TotalLines: 4, CoveredLines: 4, Coverage: 100},
+		{Path: "d.go", TotalLines: 5, CoveredLines: 0, Coverage: 0},
+	}
+	for order, want := range map[string][]string{
+		"":          {"a.go", "b.go", "c.go", "d.go"},
+		"file":      {"a.go", "b.go", "c.go", "d.go"},
+		"coverage":  {"d.go", "a.go", "c.go", "b.go"},
+		"uncovered": {"c.go", "d.go", "a.go", "b.go"},
+	} {
+		r := &Report{Files: append([]FileReport(nil), files...)}
+		if err := r.SortFiles(order); err != nil {
+			t.Fatalf("%q: SortFiles failed: %v", order, err)
+		}
+		var got []string
+		for _, f := range r.Files {
+			got = append(got, f.Path)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: expected %v, got %v", order, want, got)
+		}
+	}
+
+	if err := (&Report{}).SortFiles("size"); err == nil {
+		t.Errorf("Expected error for an unknown order, got nil")
+	}
+}
@@ -0,0 +1,75 @@
+package diffcoverage
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// generatedRe matches the comment marking generated files (see go help generate).
+var generatedRe = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// FilesWithoutTests returns the files the diff adds that declare functions
+// in a package directory without any _test.go file, sorted. Generated files
+// are left out.
+func (a *Analysis) FilesWithoutTests() []string {
+	var files []string
+	for file := range a.Diff.Added {
+		rel := a.RelPath(file)
+		if _, ok := a.Diff.NewLines[file]; !ok || len(a.Funcs.Functions[rel]) == 0 {
+			continue
+		}
+		abs := filepath.Join(a.SourceRoot, rel)
+		if tests, _ := filepath.Glob(filepath.Join(filepath.Dir(abs), "*_test.go")); len(tests) > 0 || isGenerated(abs) {
+			continue
+		}
+		files = append(files, rel)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// UntestedChanges returns the touched functions (see Functions) of packages
+// whose test files the diff does not change.
+func (a *Analysis) UntestedChanges() ([]UntestedChange, error) {
+	tested := map[string]bool{}
+	for file := range a.Diff.Tests {
+		tested[path.Dir(a.RelPath(file))] = true
+	}
+	funcs, err := a.Functions()
+	if err != nil {
+		return nil, err
+	}
+	var changes []UntestedChange
+	for _, fn := range funcs {
+		if !tested[path.Dir(fn.File)] {
+			changes = append(changes, UntestedChange{File: fn.File, Line: fn.Line, Func: fn.Name})
+		}
+	}
+	return changes, nil
+}
+
+// isGenerated reports whether the file at path has the generated code comment
+// before its package clause.
+func isGenerated(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if generatedRe.MatchString(line) {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package diffcoverage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestAnalysis_FilesWithoutTests flags only added, non-generated files with
+// functions in packages without test files.
+func TestAnalysis_FilesWithoutTests(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	body := "\nfunc F() {\n\tprintln()\n}\n"
+	mustWriteFile(t, filepath.Join(tmpDir, "a", "new.go"), "package a\n"+body)
+	mustWriteFile(t, filepath.Join(tmpDir, "a", "gen.go"), "// Code generated by stringer. DO NOT EDIT.\n\npackage a\n"+body)
+	mustWriteFile(t, filepath.Join(tmpDir, "a", "old.go"), "package a\n"+body)
+	mustWriteFile(t, filepath.Join(tmpDir, "b", "new.go"), "package b\n"+body)
+	mustWriteFile(t, filepath.Join(tmpDir, "b", "b_test.go"), "package b\n")
+	writeCoverFile(t, tmpDir, "cover.out", "mode: set\n")
+
+	var diff string
+	for _, file := range []string{"a/new.go", "a/gen.go", "b/new.go"} {
+		diff += "--- /dev/null\n+++ b/" + file + "\n@@ -0,0 +3,1 @@\n+\tprintln()\n"
+	}
+	diff += "--- a/a/old.go\n+++ b/a/old.go\n@@ -3,0 +4,1 @@\n+\tprintln()\n"
+	writeDiffFile(t, tmpDir, "diff.diff", diff)
+
+	a, err := Analyze(filepath.Join(tmpDir, "cover.out"), filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if got, want := a.FilesWithoutTests(), []string{"a/new.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilesWithoutTests = %v, want %v", got, want)
+	}
+}
+
+// TestAnalysis_UntestedChanges reports changed functions of packages whose
+// test files are not in the diff.
+func TestAnalysis_UntestedChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir, "github.com/example/module")
+	mustWriteFile(t, filepath.Join(tmpDir, "a", "a.go"), "package a\n\nfunc A() {\n\tprintln()\n}\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "b", "b.go"), "package b\n\nfunc B() {\n\tprintln()\n}\n")
+	writeCoverFile(t, tmpDir, "cover.out", "mode: set\n")
+	writeDiffFile(t, tmpDir, "diff.diff", `--- a/a/a.go
++++ b/a/a.go
+@@ -3,0 +4,1 @@
++	println()
+--- a/b/b.go
++++ b/b/b.go
+@@ -3,0 +4,1 @@
++	println()
+--- a/b/b_test.go
++++ b/b/b_test.go
+@@ -5,1 +4,0 @@
+-	t.Skip()
+`)
+
+	a, err := Analyze(filepath.Join(tmpDir, "cover.out"), filepath.Join(tmpDir, "diff.diff"), tmpDir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	changes, err := a.UntestedChanges()
+	if err != nil {
+		t.Fatalf("UntestedChanges failed: %v", err)
+	}
+	if want := []UntestedChange{{File: "a/a.go", Line: 3, Func: "A"}}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("UntestedChanges = %+v, want %+v", changes, want)
+	}
+
+	r := a.Report(0)
+	r.ApplyUntestedChanges(changes, []string{"b/**"})
+	if !r.Passed {
+		t.Errorf("Expected a change outside the required paths to pass, got %v", r.Err())
+	}
+	r.ApplyUntestedChanges(changes, []string{"a/**"})
+	if r.Passed || !r.UntestedChanges[0].Required {
+		t.Errorf("Expected a required change to fail the report")
+	}
+}
@@ -0,0 +1,87 @@
+package diffcoverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// ExcludeUnreachable drops the new/changed lines of blocks and case clauses
+// whose statements all end the program, e.g. `panic("unreachable")`,
+// log.Fatal or os.Exit: deliberate guards against states that cannot occur
+// are not worth a test. The line opening a block still counts, as its
+// condition is executed.
+func (a *Analysis) ExcludeUnreachable() error {
+	for file, lines := range a.Diff.NewLines {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, filepath.Join(a.SourceRoot, a.RelPath(file)), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %v", a.RelPath(file), err)
+		}
+		drop := func(from, to token.Pos) {
+			for line := fset.Position(from).Line; line <= fset.Position(to).Line; line++ {
+				delete(lines, line)
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.BlockStmt:
+				if onlyExits(n.List) {
+					drop(n.List[0].Pos(), n.Rbrace)
+				}
+			case *ast.CaseClause:
+				if onlyExits(n.Body) {
+					drop(n.Pos(), n.End())
+				}
+			case *ast.CommClause:
+				if onlyExits(n.Body) {
+					drop(n.Pos(), n.End())
+				}
+			}
+			return true
+		})
+	}
+	return nil
+}
+
+// onlyExits reports whether list is made of calls that end the program only.
+func onlyExits(list []ast.Stmt) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, stmt := range list {
+		expr, ok := stmt.(*ast.ExprStmt)
+		if !ok {
+			return false
+		}
+		call, ok := expr.X.(*ast.CallExpr)
+		if !ok || !isExit(call.Fun) {
+			return false
+		}
+	}
+	return true
+}
+
+// isExit reports whether fun is panic, a log.Fatal or log.Panic function or
+// os.Exit.
+func isExit(fun ast.Expr) bool {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun.Name == "panic"
+	case *ast.SelectorExpr:
+		pkg, ok := fun.X.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		switch pkg.Name {
+		case "log":
+			return strings.HasPrefix(fun.Sel.Name, "Fatal") || strings.HasPrefix(fun.Sel.Name, "Panic")
+		case "os":
+			return fun.Sel.Name == "Exit"
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package diffcoverage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestAnalysis_ExcludeUnreachable drops panic-only blocks and clauses.
+func TestAnalysis_ExcludeUnreachable(t *testing.T) {
+	root := t.TempDir()
+	src := `package p
+
+import "log"
+
+func f(x int) int {
+	if x < 0 {
+		panic("negative")
+	}
+	switch x {
+	case 1:
+		return 1
+	default:
+		log.Fatalf("unexpected %d", x)
+	}
+	if x > 10 {
+		log.Print("large")
+		panic("large")
+	}
+	return 0
+}
+`
+	if err := os.WriteFile(filepath.Join(root, "p.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lines := map[int]bool{}
+	for line := 5; line <= 20; line++ {
+		lines[line] = true
+	}
+	a := &Analysis{ModuleName: "example.com/m", SourceRoot: root, Diff: &DiffData{NewLines: map[string]map[int]bool{"example.com/m/p.go": lines}}}
+	if err := a.ExcludeUnreachable(); err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for line := range lines {
+		got = append(got, line)
+	}
+	sort.Ints(got)
+	want := []int{5, 6, 9, 10, 11, 14, 15, 16, 17, 18, 19, 20}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("remaining lines = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+// Package exceptions exempts files and functions from diff coverage until an
+// expiry date. Each exception names an owner; once it expires the run fails
+// until the exception is removed or renewed.
+package exceptions
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the exceptions file looked up in the source root.
+const FileName = ".diffcoverage-exceptions.yaml"
+
+// Exception exempts the changed lines of the files matching Path (see
+// diffcoverage.MatchPattern) or, if Func is set, of the function Func ("Func"
+// or "Type.Method") in them, through the end of the day Expires.
+type Exception struct {
+	Path    string    `yaml:"path"`
+	Func    string    `yaml:"func"`
+	Owner   string    `yaml:"owner"`
+	Expires time.Time `yaml:"expires"`
+	Reason  string    `yaml:"reason"`
+}
+
+// Expired reports whether the exception no longer applies at now.
+func (e Exception) Expired(now time.Time) bool {
+	return !now.Before(e.Expires.AddDate(0, 0, 1))
+}
+
+// Find returns the exceptions file in dir, or "" if there is none.
+func Find(dir string) string {
+	path := filepath.Join(dir, FileName)
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return path
+	}
+	return ""
+}
+
+// Load reads and validates the exceptions file at path, a YAML list of
+// exceptions.
+func Load(path string) ([]Exception, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []Exception
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&list); err != nil && len(bytes.TrimSpace(data)) > 0 {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	for i, e := range list {
+		if e.Path == "" || e.Owner == "" || e.Expires.IsZero() {
+			return nil, fmt.Errorf("error parsing %s: exception %d needs a path, an owner and an expires date (YYYY-MM-DD)", path, i+1)
+		}
+	}
+	return list, nil
+}
+
+// Apply exempts the changed lines covered by the exceptions that have not
+// expired at now. It returns those that matched a changed file and all
+// expired ones, for diffcoverage.Report.ApplyExceptions.
+func Apply(a *diffcoverage.Analysis, list []Exception, now time.Time) ([]diffcoverage.Exception, error) {
+	var result []diffcoverage.Exception
+	var files []string
+	funcs := map[string][]string{}
+	for _, e := range list {
+		r := diffcoverage.Exception{Path: e.Path, Func: e.Func, Owner: e.Owner, Expires: e.Expires.Format(time.DateOnly), Reason: e.Reason}
+		if e.Expired(now) {
+			r.Expired = true
+			result = append(result, r)
+			continue
+		}
+		matched := false
+		for file := range a.Diff.NewLines {
+			rel := a.RelPath(file)
+			if !diffcoverage.MatchPattern(e.Path, rel) {
+				continue
+			}
+			matched = true
+			if e.Func != "" {
+				funcs[rel] = append(funcs[rel], e.Func)
+			}
+		}
+		if e.Func == "" {
+			files = append(files, e.Path)
+		}
+		if matched {
+			result = append(result, r)
+		}
+	}
+	if err := a.ExcludeFunctions(funcs); err != nil {
+		return nil, err
+	}
+	a.Exclude(files)
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package exceptions
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+func mustWriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directories for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file %s: %v", path, err)
+	}
+}
+
+// TestLoad parses dates and requires a path, an owner and an expiry date.
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, FileName), `- path: legacy/**
+  owner: "@org/data"
+  expires: 2026-12-31
+  reason: rewrite planned
+`)
+	list, err := Load(Find(dir))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := []Exception{{Path: "legacy/**", Owner: "@org/data", Expires: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), Reason: "rewrite planned"}}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("Load = %+v, want %+v", list, want)
+	}
+	if list[0].Expired(time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC)) || !list[0].Expired(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the exception to expire at the end of its day")
+	}
+
+	mustWriteFile(t, filepath.Join(dir, "bad.yaml"), "- path: a.go\n  expires: 2026-12-31\n")
+	if _, err := Load(filepath.Join(dir, "bad.yaml")); err == nil || !strings.Contains(err.Error(), "needs a path, an owner") {
+		t.Errorf("Expected missing owner error, got %v", err)
+	}
+}
+
+// TestApply exempts files and functions until their exceptions expire.
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n")
+	mustWriteFile(t, filepath.Join(dir, "a", "a.go"), "package a\n\nfunc A() {\n\tprintln()\n}\n\nfunc B() {\n\tprintln()\n}\n")
+	mustWriteFile(t, filepath.Join(dir, "legacy", "l.go"), "package legacy\n\nfunc L() {\n\tprintln()\n}\n")
+	mustWriteFile(t, filepath.Join(dir, "old", "o.go"), "package old\n\nfunc O() {\n\tprintln()\n}\n")
+	mustWriteFile(t, filepath.Join(dir, "cover.out"), "mode: set\n")
+	mustWriteFile(t, filepath.Join(dir, "diff.diff"), `+++ b/a/a.go
+@@ -3,0 +4,1 @@
++	println()
+@@ -7,0 +8,1 @@
++	println()
++++ b/legacy/l.go
+@@ -3,0 +4,1 @@
++	println()
++++ b/old/o.go
+@@ -3,0 +4,1 @@
++	println()
+`)
+
+	a, err := diffcoverage.Analyze(filepath.Join(dir, "cover.out"), filepath.Join(dir, "diff.diff"), dir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	expires := time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)
+	list := []Exception{
+		{Path: "a/a.go", Func: "B", Owner: "@app", Expires: expires},
+		{Path: "legacy/**", Owner: "@data", Expires: expires},
+		{Path: "old/o.go", Owner: "@ops", Expires: expires.AddDate(0, -1, 0)},
+		{Path: "unchanged.go", Owner: "@app", Expires: expires},
+	}
+	got, err := Apply(a, list, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := []diffcoverage.Exception{
+		{Path: "a/a.go", Func: "B", Owner: "@app", Expires: "2026-06-30"},
+		{Path: "legacy/**", Owner: "@data", Expires: "2026-06-30"},
+		{Path: "old/o.go", Owner: "@ops", Expires: "2026-05-30", Expired: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply = %+v, want %+v", got, want)
+	}
+
+	counted := map[string]int{}
+	for _, f := range a.Report(0).Files {
+		counted[f.Path] = f.TotalLines
+	}
+	if want := map[string]int{"a/a.go": 1, "old/o.go": 1}; !reflect.DeepEqual(counted, want) {
+		t.Errorf("Counted lines = %v, want %v", counted, want)
+	}
+}
@@ -0,0 +1,43 @@
+package gitutil
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestRun_Success checks stdout is returned trimmed.
+func TestRun_Success(t *testing.T) {
+	out, err := Run(t.TempDir(), "--version")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.HasPrefix(out, "git version") || strings.HasSuffix(out, "\n") {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
+
+// TestRun_Error checks that git failures include the command and stderr.
+func TestRun_Error(t *testing.T) {
+	_, err := Run(t.TempDir(), "rev-parse", "--show-toplevel")
+	if err == nil {
+		t.Fatalf("Expected error outside a git repository, got nil")
+	}
+	if !strings.Contains(err.Error(), "git rev-parse --show-toplevel") {
+		t.Errorf("Expected error to mention the command, got %v", err)
+	}
+}
+
+// TestRunTo checks stdout is streamed without trimming and errors are reported.
+func TestRunTo(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RunTo(t.TempDir(), &buf, "--version"); err != nil {
+		t.Fatalf("RunTo failed: %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "\n") {
+		t.Errorf("Expected untrimmed output, got %q", buf.String())
+	}
+	if err := RunTo(t.TempDir(), &buf, "rev-parse", "HEAD"); err == nil {
+		t.Fatalf("Expected error outside a git repository, got nil")
+	}
+}
@@ -0,0 +1,223 @@
+package grpcapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The messages of api/diffcoverage.proto with a hand-written protobuf
+// encoding; only the field types the API uses are supported.
+
+// AnalyzeRequest is one part of the analysis inputs; exactly one field is set.
+type AnalyzeRequest struct {
+	ProfileChunk []byte
+	DiffChunk    []byte
+	SourceFile   *SourceFile
+	Config       []byte
+}
+
+// SourceFile is (a chunk of) a file below the module root.
+type SourceFile struct {
+	Path    string
+	Content []byte
+}
+
+// AnalyzeResponse is a Progress acknowledgement or the final Report.
+type AnalyzeResponse struct {
+	Progress *Progress
+	Report   *Report
+}
+
+// Progress counts the request bytes written to disk so far.
+type Progress struct {
+	ReceivedBytes int64
+}
+
+// Report summarizes the analysis; JSON holds the full report.
+type Report struct {
+	Coverage     float64
+	Passed       bool
+	TotalLines   int32
+	CoveredLines int32
+	JSON         []byte
+}
+
+// Marshal encodes the request.
+func (m *AnalyzeRequest) Marshal() []byte {
+	var b []byte
+	switch {
+	case m.ProfileChunk != nil:
+		b = appendBytes(b, 1, m.ProfileChunk)
+	case m.DiffChunk != nil:
+		b = appendBytes(b, 2, m.DiffChunk)
+	case m.SourceFile != nil:
+		var f []byte
+		f = appendBytes(f, 1, []byte(m.SourceFile.Path))
+		f = appendBytes(f, 2, m.SourceFile.Content)
+		b = appendBytes(b, 3, f)
+	case m.Config != nil:
+		b = appendBytes(b, 4, m.Config)
+	}
+	return b
+}
+
+// Unmarshal decodes a request. Byte fields alias data.
+func (m *AnalyzeRequest) Unmarshal(data []byte) error {
+	*m = AnalyzeRequest{}
+	return walk(data, func(num int, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			m.ProfileChunk = nonNil(b)
+		case 2:
+			m.DiffChunk = nonNil(b)
+		case 3:
+			m.SourceFile = &SourceFile{}
+			return walk(b, func(num int, v uint64, b []byte) error {
+				switch num {
+				case 1:
+					m.SourceFile.Path = string(b)
+				case 2:
+					m.SourceFile.Content = b
+				}
+				return nil
+			})
+		case 4:
+			m.Config = nonNil(b)
+		}
+		return nil
+	})
+}
+
+// Marshal encodes the response.
+func (m *AnalyzeResponse) Marshal() []byte {
+	var b []byte
+	switch {
+	case m.Progress != nil:
+		b = appendBytes(b, 1, appendVarintField(nil, 1, uint64(m.Progress.ReceivedBytes)))
+	case m.Report != nil:
+		var r []byte
+		r = appendTag(r, 1, wireFixed64)
+		r = binary.LittleEndian.AppendUint64(r, math.Float64bits(m.Report.Coverage))
+		if m.Report.Passed {
+			r = appendVarintField(r, 2, 1)
+		}
+		r = appendVarintField(r, 3, uint64(m.Report.TotalLines))
+		r = appendVarintField(r, 4, uint64(m.Report.CoveredLines))
+		r = appendBytes(r, 5, m.Report.JSON)
+		b = appendBytes(b, 2, r)
+	}
+	return b
+}
+
+// Unmarshal decodes a response.
+func (m *AnalyzeResponse) Unmarshal(data []byte) error {
+	*m = AnalyzeResponse{}
+	return walk(data, func(num int, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			m.Progress = &Progress{}
+			return walk(b, func(num int, v uint64, b []byte) error {
+				if num == 1 {
+					m.Progress.ReceivedBytes = int64(v)
+				}
+				return nil
+			})
+		case 2:
+			m.Report = &Report{}
+			return walk(b, func(num int, v uint64, b []byte) error {
+				switch num {
+				case 1:
+					m.Report.Coverage = math.Float64frombits(v)
+				case 2:
+					m.Report.Passed = v != 0
+				case 3:
+					m.Report.TotalLines = int32(v)
+				case 4:
+					m.Report.CoveredLines = int32(v)
+				case 5:
+					m.Report.JSON = b
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+func appendTag(b []byte, num, typ int) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(typ))
+}
+
+func appendVarintField(b []byte, num int, v uint64) []byte {
+	return binary.AppendUvarint(appendTag(b, num, wireVarint), v)
+}
+
+func appendBytes(b []byte, num int, v []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, num, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// walk calls fn for each field of a message with the value of varint and
+// fixed fields or the contents of length-delimited ones. Unknown fields are
+// passed on too and ignored by the callers.
+func walk(data []byte, fn func(num int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		num, typ := int(tag>>3), int(tag&7)
+		var v uint64
+		var b []byte
+		switch typ {
+		case wireVarint:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			v, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return errTruncated
+			}
+			b, data = data[n:n+int(size)], data[n+int(size):]
+		default:
+			return fmt.Errorf("unsupported wire type %d", typ)
+		}
+		if err := fn(num, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nonNil keeps empty chunks distinguishable from unset fields.
+func nonNil(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
@@ -0,0 +1,344 @@
+// Package grpcapi serves the DiffCoverage gRPC service of
+// api/diffcoverage.proto on net/http's HTTP/2 support, so build orchestrators
+// can stream large profiles to a long-lived analysis service.
+package grpcapi
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// Method is the HTTP/2 path of the AnalyzeDiffCoverage method.
+const Method = "/diffcoverage.v1.DiffCoverage/AnalyzeDiffCoverage"
+
+// Default limits.
+const (
+	DefaultMaxMessageSize = 4 << 20 // as grpc-go
+	DefaultMaxRequestSize = 1 << 30
+	DefaultProgressEvery  = 1 << 20
+)
+
+// gRPC status codes.
+const (
+	codeOK                = 0
+	codeCanceled          = 1
+	codeInvalidArgument   = 3
+	codeDeadlineExceeded  = 4
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+	codeInternal          = 13
+)
+
+// Analyzer computes the report of the received inputs. The config file, if
+// sent, is sourceRoot/.diffcoverage.yaml.
+type Analyzer func(coverPath, diffPath, sourceRoot string) (*diffcoverage.Report, error)
+
+// Server implements the DiffCoverage service. Request messages are read one
+// at a time and written to disk before the next one is read, so HTTP/2 flow
+// control slows down clients sending faster than the disk absorbs; analyses
+// beyond MaxConcurrent wait without reading their streams.
+type Server struct {
+	Analyze        Analyzer
+	Workdir        string // os.TempDir if empty
+	MaxConcurrent  int    // unlimited if 0
+	MaxMessageSize int    // DefaultMaxMessageSize if 0
+	MaxRequestSize int64  // total bytes of a stream's messages; DefaultMaxRequestSize if 0
+	ProgressEvery  int64  // bytes between Progress messages; DefaultProgressEvery if 0
+	// Timeout bounds each analysis from the time it starts, receiving the
+	// inputs included; unlimited if 0.
+	Timeout time.Duration
+	// Observe, if set, is called after every analysis with its result, ok or
+	// error, and duration, not counting the wait for MaxConcurrent.
+	Observe func(result string, d time.Duration)
+
+	once    sync.Once
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// statusError is an error with a gRPC status code.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
+func errorf(code int, format string, args ...any) error {
+	return &statusError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// ServeHTTP handles gRPC requests; the connection must be HTTP/2.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != Method {
+		// gRPC expects unknown methods as a status, not an HTTP error.
+		w.Header().Set("Content-Type", "application/grpc")
+		writeStatus(w, errorf(codeUnimplemented, "unknown method %s", r.URL.Path))
+		return
+	}
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+
+	writeStatus(w, s.analyze(r.Context(), r.Body, func(resp *AnalyzeResponse) error {
+		if err := writeMessage(w, resp.Marshal()); err != nil {
+			return err
+		}
+		flush(w)
+		return nil
+	}))
+}
+
+// Waiting returns the number of streams waiting for one of MaxConcurrent.
+func (s *Server) Waiting() int {
+	return int(s.waiting.Load())
+}
+
+// analyze receives the inputs from body, runs the analysis and sends the
+// progress and the report.
+func (s *Server) analyze(ctx context.Context, body io.Reader, send func(*AnalyzeResponse) error) (err error) {
+	if s.MaxConcurrent > 0 {
+		s.once.Do(func() { s.slots = make(chan struct{}, s.MaxConcurrent) })
+		s.waiting.Add(1)
+		select {
+		case s.slots <- struct{}{}:
+			s.waiting.Add(-1)
+			defer func() { <-s.slots }()
+		case <-ctx.Done():
+			s.waiting.Add(-1)
+			return errorf(codeCanceled, "%v", ctx.Err())
+		}
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+		// Closing the body unblocks a read waiting for a slow client.
+		if c, ok := body.(io.Closer); ok {
+			defer context.AfterFunc(ctx, func() { c.Close() })()
+		}
+		defer func() {
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = errorf(codeDeadlineExceeded, "analysis exceeded the timeout of %s", s.Timeout)
+			}
+		}()
+	}
+	if s.Observe != nil {
+		start := time.Now()
+		defer func() {
+			result := "ok"
+			if err != nil {
+				result = "error"
+			}
+			s.Observe(result, time.Since(start))
+		}()
+	}
+
+	dir, err := os.MkdirTemp(s.Workdir, "diffcoverage-grpc")
+	if err != nil {
+		return errorf(codeInternal, "%v", err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "src")
+	coverPath, diffPath := filepath.Join(dir, "cover.out"), filepath.Join(dir, "diff.txt")
+	for _, p := range []string{coverPath, diffPath} {
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			return errorf(codeInternal, "%v", err)
+		}
+	}
+
+	maxSize := s.MaxMessageSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+	every := s.ProgressEvery
+	if every == 0 {
+		every = DefaultProgressEvery
+	}
+	maxTotal := s.MaxRequestSize
+	if maxTotal == 0 {
+		maxTotal = DefaultMaxRequestSize
+	}
+	var received, acked int64
+	for {
+		msg, err := readMessage(body, maxSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if received += int64(len(msg)); received > maxTotal {
+			return errorf(codeResourceExhausted, "request exceeds the limit of %d bytes", maxTotal)
+		}
+		var req AnalyzeRequest
+		if err := req.Unmarshal(msg); err != nil {
+			return errorf(codeInvalidArgument, "invalid AnalyzeRequest: %v", err)
+		}
+		if err := store(&req, root, coverPath, diffPath); err != nil {
+			return err
+		}
+		if received-acked >= every {
+			acked = received
+			if err := send(&AnalyzeResponse{Progress: &Progress{ReceivedBytes: received}}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return errorf(codeCanceled, "%v", err)
+	}
+	r, err := s.Analyze(coverPath, diffPath, root)
+	if err != nil {
+		return errorf(codeInvalidArgument, "%v", err)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return errorf(codeInternal, "%v", err)
+	}
+	return send(&AnalyzeResponse{Report: &Report{
+		Coverage:     r.Coverage,
+		Passed:       r.Passed,
+		TotalLines:   int32(r.TotalLines),
+		CoveredLines: int32(r.CoveredLines),
+		JSON:         data,
+	}})
+}
+
+// store appends a request part to its file.
+func store(req *AnalyzeRequest, root, coverPath, diffPath string) error {
+	path, data := "", []byte(nil)
+	switch {
+	case req.ProfileChunk != nil:
+		path, data = coverPath, req.ProfileChunk
+	case req.DiffChunk != nil:
+		path, data = diffPath, req.DiffChunk
+	case req.SourceFile != nil:
+		rel := filepath.FromSlash(req.SourceFile.Path)
+		if !filepath.IsLocal(rel) {
+			return errorf(codeInvalidArgument, "source file path %q is not below the module root", req.SourceFile.Path)
+		}
+		path, data = filepath.Join(root, rel), req.SourceFile.Content
+	case req.Config != nil:
+		path, data = filepath.Join(root, config.FileName), req.Config
+	default:
+		return errorf(codeInvalidArgument, "empty AnalyzeRequest")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errorf(codeInternal, "%v", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return errorf(codeInternal, "%v", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return errorf(codeInternal, "%v", err)
+	}
+	if err := f.Close(); err != nil {
+		return errorf(codeInternal, "%v", err)
+	}
+	return nil
+}
+
+// readMessage reads one length-prefixed gRPC message; io.EOF marks the end
+// of the stream.
+func readMessage(r io.Reader, maxSize int) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errorf(codeCanceled, "error reading request: %v", err)
+	}
+	if prefix[0] != 0 {
+		return nil, errorf(codeUnimplemented, "compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if int64(size) > int64(maxSize) {
+		return nil, errorf(codeResourceExhausted, "message of %d bytes exceeds the limit of %d", size, maxSize)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, errorf(codeCanceled, "error reading request: %v", err)
+	}
+	return msg, nil
+}
+
+// writeMessage writes one uncompressed length-prefixed gRPC message.
+func writeMessage(w io.Writer, msg []byte) error {
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(msg)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// writeStatus sets the grpc-status and grpc-message trailers.
+func writeStatus(w http.ResponseWriter, err error) {
+	code, msg := codeOK, ""
+	var se *statusError
+	if errors.As(err, &se) {
+		code, msg = se.code, se.msg
+	} else if err != nil {
+		code, msg = codeInternal, err.Error()
+	}
+	// Before the response started this is a trailers-only response and
+	// the status goes into the headers.
+	h := w.Header()
+	h.Set("Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		h.Set("Grpc-Message", encodeMessage(msg))
+	}
+	if _, started := h["Trailer"]; !started {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// encodeMessage percent-encodes a status message as the gRPC spec requires.
+func encodeMessage(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,188 @@
+package grpcapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestMessages_RoundTrip encodes and decodes every message variant.
+func TestMessages_RoundTrip(t *testing.T) {
+	reqs := []AnalyzeRequest{
+		{ProfileChunk: []byte("mode: set\n")},
+		{DiffChunk: []byte{}},
+		{SourceFile: &SourceFile{Path: "pkg/a.go", Content: []byte("package pkg\n")}},
+		{Config: []byte("min_coverage: 80\n")},
+	}
+	for _, want := range reqs {
+		var got AnalyzeRequest
+		if err := got.Unmarshal(want.Marshal()); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Marshal(), want.Marshal()) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+
+	want := AnalyzeResponse{Report: &Report{Coverage: 87.5, Passed: true, TotalLines: 8, CoveredLines: 7, JSON: []byte("{}")}}
+	var got AnalyzeResponse
+	if err := got.Unmarshal(want.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Report == nil || got.Report.Coverage != 87.5 || !got.Report.Passed || got.Report.CoveredLines != 7 || string(got.Report.JSON) != "{}" {
+		t.Errorf("got %+v", got.Report)
+	}
+
+	if err := got.Unmarshal([]byte{0x12, 0x05, 0x01}); err == nil {
+		t.Error("Expected an error for a truncated message")
+	}
+}
+
+// call sends the requests over HTTP/2 and returns the responses and grpc-status.
+func call(t *testing.T, s *Server, reqs []AnalyzeRequest) ([]AnalyzeResponse, string, string) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(s)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	var body bytes.Buffer
+	for _, req := range reqs {
+		writeMessage(&body, req.Marshal())
+	}
+	hreq, _ := http.NewRequest(http.MethodPost, srv.URL+Method, &body)
+	hreq.Header.Set("Content-Type", "application/grpc")
+	resp, err := srv.Client().Do(hreq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("Expected HTTP/2, got %s", resp.Proto)
+	}
+
+	var out []AnalyzeResponse
+	for {
+		msg, err := readMessage(resp.Body, DefaultMaxMessageSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r AnalyzeResponse
+		if err := r.Unmarshal(msg); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, r)
+	}
+	return out, resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+}
+
+// TestServer_Analyze streams the inputs and receives progress and the report.
+func TestServer_Analyze(t *testing.T) {
+	var gotConfig string
+	s := &Server{ProgressEvery: 10, Analyze: func(coverPath, diffPath, sourceRoot string) (*diffcoverage.Report, error) {
+		cover, _ := os.ReadFile(coverPath)
+		if string(cover) != "mode: set\nexample.com/m/a.go:3.12,5.2 1 1\n" {
+			t.Errorf("Unexpected profile %q", cover)
+		}
+		if _, err := os.Stat(filepath.Join(sourceRoot, "pkg", "a.go")); err != nil {
+			t.Error(err)
+		}
+		data, _ := os.ReadFile(filepath.Join(sourceRoot, ".diffcoverage.yaml"))
+		gotConfig = string(data)
+		r := diffcoverage.NewReport(80)
+		r.Coverage, r.TotalLines, r.CoveredLines = 50, 2, 1
+		return r, nil
+	}}
+
+	out, status, msg := call(t, s, []AnalyzeRequest{
+		{Config: []byte("min_coverage: 80\n")},
+		{ProfileChunk: []byte("mode: set\n")},
+		{ProfileChunk: []byte("example.com/m/a.go:3.12,5.2 1 1\n")},
+		{DiffChunk: []byte("+++ b/pkg/a.go\n")},
+		{SourceFile: &SourceFile{Path: "pkg/a.go", Content: []byte("package pkg\n")}},
+	})
+	if status != "0" {
+		t.Fatalf("grpc-status %s: %s", status, msg)
+	}
+	if len(out) < 2 || out[0].Progress == nil || out[len(out)-1].Report == nil {
+		t.Fatalf("Expected progress then a report, got %+v", out)
+	}
+	rep := out[len(out)-1].Report
+	if rep.Coverage != 50 || rep.TotalLines != 2 {
+		t.Errorf("Unexpected report %+v", rep)
+	}
+	var full diffcoverage.Report
+	if err := json.Unmarshal(rep.JSON, &full); err != nil || full.MinCoverage != 80 {
+		t.Errorf("Unexpected JSON report %s: %v", rep.JSON, err)
+	}
+	if gotConfig != "min_coverage: 80\n" {
+		t.Errorf("Unexpected config %q", gotConfig)
+	}
+}
+
+// TestServer_Errors reports invalid inputs and oversized messages as gRPC statuses.
+func TestServer_Errors(t *testing.T) {
+	s := &Server{MaxMessageSize: 16, Analyze: func(coverPath, diffPath, sourceRoot string) (*diffcoverage.Report, error) {
+		return diffcoverage.NewReport(0), nil
+	}}
+	cases := []struct {
+		name string
+		req  AnalyzeRequest
+		want string
+	}{
+		{"escaping path", AnalyzeRequest{SourceFile: &SourceFile{Path: "../x.go"}}, "3"},
+		{"too large", AnalyzeRequest{ProfileChunk: bytes.Repeat([]byte("x"), 32)}, "8"},
+	}
+	for _, c := range cases {
+		if _, status, _ := call(t, s, []AnalyzeRequest{c.req}); status != c.want {
+			t.Errorf("%s: grpc-status %q, want %q", c.name, status, c.want)
+		}
+	}
+}
+
+// TestServer_Limits rejects streams over MaxRequestSize and stops streams
+// that exceed Timeout.
+func TestServer_Limits(t *testing.T) {
+	s := &Server{MaxRequestSize: 40, Analyze: func(coverPath, diffPath, sourceRoot string) (*diffcoverage.Report, error) {
+		return diffcoverage.NewReport(0), nil
+	}}
+	chunk := AnalyzeRequest{ProfileChunk: bytes.Repeat([]byte("x"), 16)}
+	if _, status, _ := call(t, s, []AnalyzeRequest{chunk, chunk}); status != "0" {
+		t.Errorf("Expected two chunks to fit, got grpc-status %q", status)
+	}
+	if _, status, _ := call(t, s, []AnalyzeRequest{chunk, chunk, chunk}); status != "8" {
+		t.Errorf("Expected RESOURCE_EXHAUSTED for three chunks, got grpc-status %q", status)
+	}
+
+	// A client that never closes its stream.
+	s.Timeout = 100 * time.Millisecond
+	srv := httptest.NewUnstartedServer(s)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go writeMessage(pw, chunk.Marshal())
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+Method, pr)
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if status := resp.Trailer.Get("Grpc-Status"); status != "4" {
+		t.Errorf("Expected DEADLINE_EXCEEDED, got grpc-status %q: %s", status, resp.Trailer.Get("Grpc-Message"))
+	}
+}
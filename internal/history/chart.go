@@ -0,0 +1,191 @@
+package history
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Chart plots diff and project coverage of recorded runs on a 0-100% scale.
+type Chart struct {
+	Title  string
+	Width  int // 800 if zero
+	Height int // 300 if zero
+}
+
+// Chart margins around the plot area, in pixels.
+const (
+	chartLeft   = 50
+	chartRight  = 20
+	chartTop    = 40
+	chartBottom = 40
+)
+
+var (
+	diffColor    = color.RGBA{0x1f, 0x77, 0xb4, 0xff}
+	projectColor = color.RGBA{0x2c, 0xa0, 0x2c, 0xff}
+	minColor     = color.RGBA{0xd6, 0x27, 0x28, 0xff}
+	gridColor    = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+)
+
+// series is a line of the chart: one value per run, oldest first.
+type series struct {
+	name   string
+	color  color.RGBA
+	values []float64
+	dashed bool
+}
+
+func (c *Chart) size() (int, int) {
+	w, h := c.Width, c.Height
+	if w == 0 {
+		w = 800
+	}
+	if h == 0 {
+		h = 300
+	}
+	return w, h
+}
+
+// chartSeries returns the plotted series of records, which are newest first as
+// returned by List.
+func chartSeries(records []Record) []series {
+	diff := series{name: "Diff coverage", color: diffColor}
+	project := series{name: "Project coverage", color: projectColor}
+	minimum := series{name: "Minimum", color: minColor, dashed: true}
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i].Report
+		diff.values = append(diff.values, r.Coverage)
+		project.values = append(project.values, r.ProjectCoverage)
+		minimum.values = append(minimum.values, r.MinCoverage)
+	}
+	return []series{diff, project, minimum}
+}
+
+// point maps run i of n and a percentage to image coordinates.
+func (c *Chart) point(i, n int, value float64) (float64, float64) {
+	w, h := c.size()
+	plotW := float64(w - chartLeft - chartRight)
+	plotH := float64(h - chartTop - chartBottom)
+	x := float64(chartLeft) + plotW/2
+	if n > 1 {
+		x = float64(chartLeft) + plotW*float64(i)/float64(n-1)
+	}
+	y := float64(chartTop) + plotH*(100-value)/100
+	return x, y
+}
+
+// WriteSVG renders the chart of records (newest first) as SVG.
+func (c *Chart) WriteSVG(w io.Writer, records []Record) error {
+	if len(records) == 0 {
+		return fmt.Errorf("no runs to chart")
+	}
+	width, height := c.size()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	if c.Title != "" {
+		fmt.Fprintf(&sb, `<text x="%d" y="20" font-size="14" font-weight="bold">%s</text>`+"\n", chartLeft, html.EscapeString(c.Title))
+	}
+
+	for pct := 0; pct <= 100; pct += 25 {
+		x0, y := c.point(0, 2, float64(pct))
+		x1, _ := c.point(1, 2, float64(pct))
+		fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", x0, y, x1, y, hexColor(gridColor))
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="end">%d%%</text>`+"\n", x0-6, y+4, pct)
+	}
+
+	// Dates of the first and last run below the axis.
+	first, last := records[len(records)-1], records[0]
+	fmt.Fprintf(&sb, `<text x="%d" y="%d">%s</text>`+"\n", chartLeft, height-chartBottom+18, first.Time.Format("2006-01-02"))
+	if len(records) > 1 {
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", width-chartRight, height-chartBottom+18, last.Time.Format("2006-01-02"))
+	}
+
+	for i, s := range chartSeries(records) {
+		var points []string
+		for j, v := range s.values {
+			x, y := c.point(j, len(s.values), v)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+			if !s.dashed {
+				fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`+"\n", x, y, hexColor(s.color))
+			}
+		}
+		dash := ""
+		if s.dashed {
+			dash = ` stroke-dasharray="6,4"`
+		}
+		fmt.Fprintf(&sb, `<polyline fill="none" stroke="%s" stroke-width="2"%s points="%s"/>`+"\n", hexColor(s.color), dash, strings.Join(points, " "))
+
+		lx := chartLeft + i*150
+		ly := height - 10
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="12" height="3" fill="%s"/>`+"\n", lx, ly-4, hexColor(s.color))
+		fmt.Fprintf(&sb, `<text x="%d" y="%d">%s</text>`+"\n", lx+16, ly, s.name)
+	}
+	sb.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WritePNG renders the chart of records (newest first) as PNG. The image has
+// the grid and lines of the SVG chart but no text.
+func (c *Chart) WritePNG(w io.Writer, records []Record) error {
+	if len(records) == 0 {
+		return fmt.Errorf("no runs to chart")
+	}
+	width, height := c.size()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	for pct := 0; pct <= 100; pct += 25 {
+		x0, y := c.point(0, 2, float64(pct))
+		x1, _ := c.point(1, 2, float64(pct))
+		drawLine(img, x0, y, x1, y, gridColor, false)
+	}
+	for _, s := range chartSeries(records) {
+		for j := range s.values {
+			x1, y1 := c.point(j, len(s.values), s.values[j])
+			if j == 0 {
+				drawLine(img, x1, y1, x1, y1, s.color, false)
+				continue
+			}
+			x0, y0 := c.point(j-1, len(s.values), s.values[j-1])
+			drawLine(img, x0, y0, x1, y1, s.color, s.dashed)
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// drawLine draws a 2px line from (x0, y0) to (x1, y1).
+func drawLine(img *image.RGBA, x0, y0, x1, y1 float64, c color.RGBA, dashed bool) {
+	dx, dy := x1-x0, y1-y0
+	steps := int(max(abs(dx), abs(dy))) + 1
+	for i := 0; i <= steps; i++ {
+		if dashed && (i/6)%2 == 1 {
+			continue
+		}
+		t := float64(i) / float64(steps)
+		x, y := int(x0+dx*t), int(y0+dy*t)
+		for _, d := range [][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+			img.SetRGBA(x+d[0], y+d[1], c)
+		}
+	}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
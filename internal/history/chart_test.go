@@ -0,0 +1,65 @@
+package history
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+func chartRecords() []Record {
+	// Newest first, as returned by List.
+	return []Record{
+		{ID: 2, Time: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Report: &diffcoverage.Report{Coverage: 50, ProjectCoverage: 70, MinCoverage: 80}},
+		{ID: 1, Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Report: &diffcoverage.Report{Coverage: 100, ProjectCoverage: 75, MinCoverage: 80}},
+	}
+}
+
+// TestChart_WriteSVG plots the runs oldest to newest with axis labels.
+func TestChart_WriteSVG(t *testing.T) {
+	c := &Chart{Title: "main <coverage>", Width: 250, Height: 140}
+	var buf bytes.Buffer
+	if err := c.WriteSVG(&buf, chartRecords()); err != nil {
+		t.Fatalf("WriteSVG failed: %v", err)
+	}
+	svg := buf.String()
+	for _, want := range []string{
+		`width="250" height="140"`,
+		"main &lt;coverage&gt;",
+		">2024-03-01<", ">2024-03-02<", ">100%<",
+		// Diff coverage from 100% at the left edge to 50% at the right edge.
+		`points="50.0,40.0 230.0,70.0"`,
+		`stroke-dasharray="6,4" points="50.0,52.0 230.0,52.0"`,
+		"Project coverage",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("Expected SVG to contain %q, got:\n%s", want, svg)
+		}
+	}
+
+	if err := c.WriteSVG(&buf, nil); err == nil {
+		t.Errorf("Expected an error without runs")
+	}
+}
+
+// TestChart_WritePNG renders an image of the requested size.
+func TestChart_WritePNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&Chart{Width: 200, Height: 100}).WritePNG(&buf, chartRecords()); err != nil {
+		t.Fatalf("WritePNG failed: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Invalid PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Errorf("Unexpected size %v", b)
+	}
+	// The diff coverage line starts at the top-left corner of the plot area.
+	if r, g, b, _ := img.At(50, 40).RGBA(); r>>8 != 0x1f || g>>8 != 0x77 || b>>8 != 0xb4 {
+		t.Errorf("Unexpected color at the first point: %x %x %x", r>>8, g>>8, b>>8)
+	}
+}
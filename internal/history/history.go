@@ -0,0 +1,210 @@
+// Package history keeps a local record of diff coverage runs in an
+// append-only JSON Lines file, one run per line.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// DefaultPath is the history file, relative to the source root.
+const DefaultPath = ".diffcoverage/history.jsonl"
+
+// Record is one recorded run.
+type Record struct {
+	ID     int                  `json:"id"`
+	Time   time.Time            `json:"time"`
+	Repo   string               `json:"repo,omitempty"`
+	Team   string               `json:"team,omitempty"`
+	Branch string               `json:"branch,omitempty"`
+	Commit string               `json:"commit,omitempty"`
+	PR     string               `json:"pr,omitempty"`
+	Report *diffcoverage.Report `json:"report"`
+	// Diff is the diff annotated with coverage markers (see
+	// diffcoverage.AnnotateDiff), if recorded.
+	Diff string `json:"diff,omitempty"`
+}
+
+// Store is a history file.
+type Store struct {
+	Path string
+
+	mu sync.Mutex // serializes Add, e.g. of a server's webhooks and uploads
+}
+
+// Add appends rec, assigning the next ID.
+func (s *Store) Add(rec *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.Records()
+	if err != nil {
+		return err
+	}
+	rec.ID = 1
+	if len(records) > 0 {
+		rec.ID = records[len(records)-1].ID + 1
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Records returns all records, oldest first. A missing file holds no records.
+func (s *Store) Records() ([]Record, error) {
+	f, err := os.Open(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", s.Path, lineNum, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// List returns up to limit records of branch (all branches if empty), newest
+// first. A limit of 0 or less returns all of them.
+func (s *Store) List(branch string, limit int) ([]Record, error) {
+	records, err := s.Records()
+	if err != nil {
+		return nil, err
+	}
+	var list []Record
+	for i := len(records) - 1; i >= 0; i-- {
+		if branch != "" && records[i].Branch != branch {
+			continue
+		}
+		list = append(list, records[i])
+		if limit > 0 && len(list) == limit {
+			break
+		}
+	}
+	return list, nil
+}
+
+// Get returns the record with the given ID.
+func (s *Store) Get(id int) (*Record, error) {
+	records, err := s.Records()
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].ID == id {
+			return &records[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no run #%d in %s", id, s.Path)
+}
+
+// Retention limits the runs kept by Prune: the newest KeepRuns runs of each
+// branch of each repository, and only those younger than MaxAge. Zero values
+// do not limit.
+type Retention struct {
+	KeepRuns int
+	MaxAge   time.Duration
+}
+
+// Prune removes the runs outside ret as of now and returns how many it
+// removed. Unless dryRun is set the file is rewritten in place; the remaining
+// runs keep their IDs, and new runs continue from the newest one kept.
+func (s *Store) Prune(ret Retention, now time.Time, dryRun bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.Records()
+	if err != nil {
+		return 0, err
+	}
+
+	var keep []Record
+	type branchKey struct{ repo, branch string }
+	perBranch := map[branchKey]int{}
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		if ret.MaxAge > 0 && now.Sub(rec.Time) > ret.MaxAge {
+			continue
+		}
+		key := branchKey{rec.Repo, rec.Branch}
+		if perBranch[key]++; ret.KeepRuns > 0 && perBranch[key] > ret.KeepRuns {
+			continue
+		}
+		keep = append(keep, rec)
+	}
+	removed := len(records) - len(keep)
+	if removed == 0 || dryRun {
+		return removed, nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	w := bufio.NewWriter(f)
+	for i := len(keep) - 1; i >= 0; i-- {
+		data, err := json.Marshal(&keep[i])
+		if err != nil {
+			f.Close()
+			return 0, err
+		}
+		w.Write(append(data, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return 0, err
+	}
+	return removed, os.Rename(f.Name(), s.Path)
+}
+
+// ResolvePath returns the history file for path, which is relative to the
+// source root and defaults to DefaultPath.
+func ResolvePath(sourceRoot, path string) string {
+	if path == "" {
+		path = DefaultPath
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(sourceRoot, path)
+}
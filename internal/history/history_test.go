@@ -0,0 +1,116 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestStore_AddListGet records runs on two branches and reads them back.
+func TestStore_AddListGet(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "nested", "history.jsonl")}
+
+	if records, err := s.Records(); err != nil || len(records) != 0 {
+		t.Fatalf("Expected an empty history, got %v, %v", records, err)
+	}
+
+	for i, branch := range []string{"main", "feature", "main"} {
+		rec := &Record{Time: time.Unix(int64(i), 0), Branch: branch, Commit: string(rune('a' + i)),
+			Report: &diffcoverage.Report{Coverage: float64(50 + i)}}
+		if err := s.Add(rec); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if rec.ID != i+1 {
+			t.Errorf("ID = %d, want %d", rec.ID, i+1)
+		}
+	}
+
+	list, err := s.List("main", 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != 3 || list[1].ID != 1 {
+		t.Errorf("Unexpected main runs %+v", list)
+	}
+	if list, _ := s.List("", 1); len(list) != 1 || list[0].ID != 3 {
+		t.Errorf("Expected only the newest run, got %+v", list)
+	}
+
+	rec, err := s.Get(2)
+	if err != nil || rec.Branch != "feature" || rec.Report.Coverage != 51 {
+		t.Errorf("Get(2) = %+v, %v", rec, err)
+	}
+	if _, err := s.Get(9); err == nil {
+		t.Errorf("Expected error for unknown run, got nil")
+	}
+}
+
+// TestStore_Corrupt reports the offending line.
+func TestStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	os.WriteFile(path, []byte(`{"id":1}`+"\nnot json\n"), 0644)
+
+	_, err := (&Store{Path: path}).Records()
+	if err == nil || !strings.Contains(err.Error(), "history.jsonl:2") {
+		t.Errorf("Expected an error pointing at line 2, got %v", err)
+	}
+}
+
+// TestStore_Prune keeps the newest runs per branch that are not too old.
+func TestStore_Prune(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "history.jsonl")}
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i, branch := range []string{"main", "feature", "main", "main", "feature"} {
+		rec := &Record{Time: now.Add(time.Duration(i-5) * 24 * time.Hour), Branch: branch, Report: &diffcoverage.Report{}}
+		if err := s.Add(rec); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	if removed, err := s.Prune(Retention{KeepRuns: 1}, now, true); err != nil || removed != 3 {
+		t.Errorf("Dry run = %d, %v, want 3", removed, err)
+	}
+	if records, _ := s.Records(); len(records) != 5 {
+		t.Fatalf("Dry run removed runs, %d left", len(records))
+	}
+
+	// Runs 1 and 2 are older than 3.5 days, and only two main runs are kept.
+	removed, err := s.Prune(Retention{KeepRuns: 2, MaxAge: 84 * time.Hour}, now, false)
+	if err != nil || removed != 2 {
+		t.Fatalf("Prune = %d, %v, want 2", removed, err)
+	}
+	records, err := s.Records()
+	if err != nil || len(records) != 3 || records[0].ID != 3 || records[2].ID != 5 {
+		t.Fatalf("Unexpected runs after pruning: %+v, %v", records, err)
+	}
+
+	rec := &Record{Time: now, Branch: "main", Report: &diffcoverage.Report{}}
+	if err := s.Add(rec); err != nil || rec.ID != 6 {
+		t.Errorf("Add after pruning = #%d, %v, want #6", rec.ID, err)
+	}
+}
+
+// TestStore_Prune_Repos counts the kept runs of a branch per repository.
+func TestStore_Prune_Repos(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "history.jsonl")}
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i, repo := range []string{"o/a", "o/b", "o/a", "o/a", "o/a"} {
+		rec := &Record{Time: now.Add(time.Duration(i-5) * time.Hour), Repo: repo, Branch: "main", Report: &diffcoverage.Report{}}
+		if err := s.Add(rec); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	removed, err := s.Prune(Retention{KeepRuns: 2}, now, false)
+	if err != nil || removed != 2 {
+		t.Fatalf("Prune = %d, %v, want 2", removed, err)
+	}
+	records, err := s.Records()
+	if err != nil || len(records) != 3 || records[0].ID != 2 || records[1].ID != 4 || records[2].ID != 5 {
+		t.Fatalf("Unexpected runs after pruning: %+v, %v", records, err)
+	}
+}
@@ -0,0 +1,90 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rollup aggregates the runs of one repository or team in one period.
+// Coverage is the average diff coverage of the runs, LineCoverage the share
+// of all their counted lines that is covered.
+type Rollup struct {
+	Key          string    `json:"key"`
+	Period       time.Time `json:"period"`
+	Runs         int       `json:"runs"`
+	Coverage     float64   `json:"coverage"`
+	TotalLines   int       `json:"totalLines"`
+	CoveredLines int       `json:"coveredLines"`
+	LineCoverage float64   `json:"lineCoverage"`
+}
+
+// Periods lists the periods accepted by RollUp.
+var Periods = []string{"day", "week", "month"}
+
+// RollUp aggregates the records by "repo" or "team" and by the period each
+// started in, in UTC with weeks starting on Monday. Runs without counted
+// lines are left out, as they count as fully covered. Records without a
+// team are rolled up under "". The result is sorted by key and period.
+func RollUp(records []Record, by, period string) ([]Rollup, error) {
+	if by != "repo" && by != "team" {
+		return nil, fmt.Errorf("cannot roll up by %q (available: repo, team)", by)
+	}
+	type group struct {
+		key    string
+		period time.Time
+	}
+	groups := map[group]*Rollup{}
+	for _, rec := range records {
+		if rec.Report == nil || rec.Report.TotalLines == 0 {
+			continue
+		}
+		start, err := periodStart(rec.Time, period)
+		if err != nil {
+			return nil, err
+		}
+		g := group{rec.Repo, start}
+		if by == "team" {
+			g.key = rec.Team
+		}
+		r := groups[g]
+		if r == nil {
+			r = &Rollup{Key: g.key, Period: start}
+			groups[g] = r
+		}
+		r.Runs++
+		r.Coverage += rec.Report.Coverage
+		r.TotalLines += rec.Report.TotalLines
+		r.CoveredLines += rec.Report.CoveredLines
+	}
+
+	rollups := make([]Rollup, 0, len(groups))
+	for _, r := range groups {
+		r.Coverage /= float64(r.Runs)
+		r.LineCoverage = 100.0 * float64(r.CoveredLines) / float64(r.TotalLines)
+		rollups = append(rollups, *r)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		if rollups[i].Key != rollups[j].Key {
+			return rollups[i].Key < rollups[j].Key
+		}
+		return rollups[i].Period.Before(rollups[j].Period)
+	})
+	return rollups, nil
+}
+
+// periodStart returns the start of the day, week or month t is in.
+func periodStart(t time.Time, period string) (time.Time, error) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case "day":
+		return day, nil
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7), nil
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown period %q (available: %s)", period, strings.Join(Periods, ", "))
+}
@@ -0,0 +1,50 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestRollUp aggregates runs per team and week, skipping runs without lines.
+func TestRollUp(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 12, 0, 0, 0, time.UTC) }
+	run := func(repo, team string, d int, covered, total int) Record {
+		r := &diffcoverage.Report{TotalLines: total, CoveredLines: covered, Coverage: 100}
+		if total > 0 {
+			r.Coverage = 100.0 * float64(covered) / float64(total)
+		}
+		return Record{Repo: repo, Team: team, Time: day(d), Report: r}
+	}
+	records := []Record{
+		run("o/a", "payments", 6, 1, 2),  // Monday
+		run("o/b", "payments", 12, 9, 9), // Sunday, same week
+		run("o/a", "payments", 13, 0, 4),
+		run("o/c", "", 7, 0, 0),
+	}
+
+	got, err := RollUp(records, "team", "week")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rollup{
+		{Key: "payments", Period: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), Runs: 2, Coverage: 75, TotalLines: 11, CoveredLines: 10, LineCoverage: 100.0 * 10 / 11},
+		{Key: "payments", Period: time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), Runs: 1, Coverage: 0, TotalLines: 4, CoveredLines: 0, LineCoverage: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RollUp = %+v, want %+v", got, want)
+	}
+
+	got, err = RollUp(records, "repo", "month")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Key != "o/a" || got[0].Runs != 2 || got[1].Key != "o/b" {
+		t.Errorf("Unexpected rollup by repo %+v", got)
+	}
+	if _, err := RollUp(records, "owner", "week"); err == nil {
+		t.Errorf("Expected an error for an unknown grouping")
+	}
+}
@@ -0,0 +1,46 @@
+package history
+
+import "github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+
+// DetectRegressions compares the current report with the previous runs of
+// its branch (newest first, as returned by List). A metric regresses when it
+// did not increase across the last runs (the current one included) and
+// dropped by more than tolerance percentage points in total.
+func DetectRegressions(current *diffcoverage.Report, previous []Record, runs int, tolerance float64) []diffcoverage.Regression {
+	if runs < 2 || len(previous) < runs-1 {
+		return nil
+	}
+	previous = previous[:runs-1]
+
+	metrics := []struct {
+		name  string
+		value func(*diffcoverage.Report) float64
+	}{
+		{"diff", func(r *diffcoverage.Report) float64 { return r.Coverage }},
+		{"project", func(r *diffcoverage.Report) float64 { return r.ProjectCoverage }},
+	}
+
+	var regressions []diffcoverage.Regression
+	for _, m := range metrics {
+		// Oldest to newest.
+		values := make([]float64, 0, runs)
+		for i := len(previous) - 1; i >= 0; i-- {
+			values = append(values, m.value(previous[i].Report))
+		}
+		values = append(values, m.value(current))
+
+		declining := true
+		for i := 1; i < len(values); i++ {
+			if values[i] > values[i-1] {
+				declining = false
+				break
+			}
+		}
+		if declining && values[0]-values[len(values)-1] > tolerance {
+			regressions = append(regressions, diffcoverage.Regression{
+				Metric: m.name, From: values[0], To: values[len(values)-1], Runs: runs,
+			})
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,49 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// records builds a newest-first history from oldest-first diff and project coverage pairs.
+func records(values ...[2]float64) []Record {
+	var list []Record
+	for i := len(values) - 1; i >= 0; i-- {
+		list = append(list, Record{Report: &diffcoverage.Report{Coverage: values[i][0], ProjectCoverage: values[i][1]}})
+	}
+	return list
+}
+
+// TestDetectRegressions covers declining, recovering and short histories.
+func TestDetectRegressions(t *testing.T) {
+	current := &diffcoverage.Report{Coverage: 70, ProjectCoverage: 80}
+
+	tests := []struct {
+		name     string
+		previous []Record
+		runs     int
+		want     []string
+	}{
+		{"both declining", records([2]float64{90, 82}, [2]float64{80, 81}), 3,
+			[]string{"diff coverage declined from 90.00% to 70.00% over the last 3 runs", "project coverage declined from 82.00% to 80.00% over the last 3 runs"}},
+		{"recovered in between", records([2]float64{90, 80}, [2]float64{60, 81}), 3, nil},
+		{"drop within tolerance", records([2]float64{70.5, 80.5}, [2]float64{70, 80}), 3, nil},
+		{"only recent runs count", records([2]float64{50, 70}, [2]float64{90, 85}, [2]float64{80, 82}), 3,
+			[]string{"diff coverage declined from 90.00% to 70.00% over the last 3 runs", "project coverage declined from 85.00% to 80.00% over the last 3 runs"}},
+		{"not enough history", records([2]float64{90, 90}), 3, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectRegressions(current, tt.previous, tt.runs, 1)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].String() != tt.want[i] {
+					t.Errorf("got %q, want %q", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+)
+
+// Marker identifies hooks written by this package, so they can be safely replaced.
+const Marker = "# diffcoverage hook"
+
+// Options configures the generated hook script.
+type Options struct {
+	Kind        string  // "pre-push" or "pre-commit"
+	Base        string  // ref to diff against for pre-push hooks
+	MinCoverage float64 // minimum diff coverage percentage
+	Binary      string  // diffcoverage binary invoked by the hook
+	SourceRoot  string  // module root relative to the repository top level
+	Version     string  // diffcoverage version that installed the hook
+}
+
+var scriptTemplate = template.Must(template.New("hook").Funcs(template.FuncMap{
+	"quote": shellQuote,
+	"float": func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) },
+}).Parse(`#!/bin/sh
+` + Marker + `: blocks {{.Kind}} when diff coverage is below {{float .MinCoverage}}%.
+# Installed by "diffcoverage install-hook"{{with .Version}} ({{.}}){{end}}; delete this file to disable it.
+# Bypass once with --no-verify.
+set -e
+
+cd {{quote .SourceRoot}}
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+{{if eq .Kind "pre-commit" -}}
+git diff --cached --unified=0 --relative > "$tmp/diff.txt"
+changed=$(git diff --cached --name-only --relative -- '*.go')
+{{- else -}}
+git diff {{quote .Base}} --unified=0 --relative > "$tmp/diff.txt"
+changed=$(git diff --name-only --relative {{quote .Base}} -- '*.go')
+{{- end}}
+
+pkgs=""
+for f in $changed; do
+	d=$(dirname "$f")
+	if [ -d "$d" ]; then
+		pkgs="$pkgs ./$d"
+	fi
+done
+pkgs=$(echo $pkgs | tr ' ' '\n' | sort -u)
+if [ -z "$pkgs" ]; then
+	exit 0
+fi
+
+go test -coverprofile="$tmp/cover.out" $pkgs
+{{quote .Binary}} -vvv -min={{float .MinCoverage}} "$tmp/cover.out" "$tmp/diff.txt" .
+`))
+
+// Script renders the hook script for opts.
+func Script(opts Options) (string, error) {
+	if opts.Kind != "pre-push" && opts.Kind != "pre-commit" {
+		return "", fmt.Errorf("unsupported hook kind %q (want pre-push or pre-commit)", opts.Kind)
+	}
+	var sb strings.Builder
+	if err := scriptTemplate.Execute(&sb, opts); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Install writes the hook into the repository containing dir and returns its path.
+// dir is the module root; an existing hook not written by diffcoverage is only
+// replaced when force is set.
+func Install(dir string, opts Options, force bool) (string, error) {
+	prefix, err := gitutil.Run(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", err
+	}
+	opts.SourceRoot = strings.TrimSuffix(prefix, "/")
+	if opts.SourceRoot == "" {
+		opts.SourceRoot = "."
+	}
+
+	script, err := Script(opts)
+	if err != nil {
+		return "", err
+	}
+
+	hooksDir, err := gitutil.Run(dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(dir, hooksDir)
+	}
+	hookPath := filepath.Join(hooksDir, opts.Kind)
+
+	if existing, err := os.ReadFile(hookPath); err == nil && !force && !strings.Contains(string(existing), Marker) {
+		return "", fmt.Errorf("%s already exists and was not installed by diffcoverage (use -force to replace it)", hookPath)
+	}
+
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %v", err)
+	}
+	if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		return "", fmt.Errorf("failed to write hook: %v", err)
+	}
+	// WriteFile keeps the mode of an existing file, so make sure it is executable.
+	if err := os.Chmod(hookPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to make hook executable: %v", err)
+	}
+	return hookPath, nil
+}
+
+// shellQuote wraps s in single quotes for safe use in a POSIX shell script.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
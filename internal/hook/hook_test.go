@@ -0,0 +1,119 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+)
+
+// TestScript_PrePush checks the pre-push script diffs against the base ref.
+func TestScript_PrePush(t *testing.T) {
+	script, err := Script(Options{Kind: "pre-push", Base: "origin/main", MinCoverage: 85, Binary: "/usr/bin/diffcov", SourceRoot: ".", Version: "v1.2.3"})
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	for _, want := range []string{
+		"#!/bin/sh\n",
+		Marker,
+		`"diffcoverage install-hook" (v1.2.3)`,
+		"git diff 'origin/main' --unified=0 --relative",
+		"go test -coverprofile=",
+		"'/usr/bin/diffcov' -vvv -min=85 ",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "--cached") {
+		t.Errorf("pre-push script should not diff the index:\n%s", script)
+	}
+}
+
+// TestScript_PreCommit checks the pre-commit script diffs the index.
+func TestScript_PreCommit(t *testing.T) {
+	script, err := Script(Options{Kind: "pre-commit", MinCoverage: 72.5, Binary: "diffcov", SourceRoot: "svc/api"})
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	for _, want := range []string{"cd 'svc/api'", "git diff --cached --unified=0 --relative", "-min=72.5"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+}
+
+// TestScript_UnsupportedKind covers the kind validation.
+func TestScript_UnsupportedKind(t *testing.T) {
+	if _, err := Script(Options{Kind: "post-merge"}); err == nil {
+		t.Fatalf("Expected error for unsupported hook kind, got nil")
+	}
+}
+
+// TestShellQuote checks embedded single quotes are escaped.
+func TestShellQuote(t *testing.T) {
+	if got, want := shellQuote("it's"), `'it'\''s'`; got != want {
+		t.Errorf("shellQuote = %q, want %q", got, want)
+	}
+}
+
+// TestInstall covers writing, replacing and refusing to overwrite hooks.
+func TestInstall(t *testing.T) {
+	repo := t.TempDir()
+	if _, err := gitutil.Run(repo, "init", "-q"); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+	moduleDir := filepath.Join(repo, "svc")
+	if err := os.MkdirAll(moduleDir, 0755); err != nil {
+		t.Fatalf("Failed to create module dir: %v", err)
+	}
+	opts := Options{Kind: "pre-push", Base: "origin/main", MinCoverage: 80, Binary: "diffcov"}
+
+	hookPath, err := Install(moduleDir, opts, false)
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if want := filepath.Join(repo, ".git", "hooks", "pre-push"); hookPath != want {
+		t.Errorf("hook path = %q, want %q", hookPath, want)
+	}
+	info, err := os.Stat(hookPath)
+	if err != nil {
+		t.Fatalf("Hook not written: %v", err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Hook is not executable: %v", info.Mode())
+	}
+	content, _ := os.ReadFile(hookPath)
+	if !strings.Contains(string(content), "cd 'svc'") {
+		t.Errorf("Expected hook to cd into the module root, got:\n%s", content)
+	}
+
+	// Re-installing over our own hook is allowed.
+	if _, err := Install(moduleDir, opts, false); err != nil {
+		t.Fatalf("Re-install failed: %v", err)
+	}
+
+	// A foreign hook is preserved unless forced.
+	if err := os.WriteFile(hookPath, []byte("#!/bin/sh\necho custom\n"), 0644); err != nil {
+		t.Fatalf("Failed to write foreign hook: %v", err)
+	}
+	if _, err := Install(moduleDir, opts, false); err == nil {
+		t.Fatalf("Expected error when a foreign hook exists, got nil")
+	}
+	if _, err := Install(moduleDir, opts, true); err != nil {
+		t.Fatalf("Forced install failed: %v", err)
+	}
+	info, _ = os.Stat(hookPath)
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Forced hook is not executable: %v", info.Mode())
+	}
+}
+
+// TestInstall_NotARepo covers running outside a git repository.
+func TestInstall_NotARepo(t *testing.T) {
+	if _, err := Install(t.TempDir(), Options{Kind: "pre-push"}, false); err == nil {
+		t.Fatalf("Expected error outside a git repository, got nil")
+	}
+}
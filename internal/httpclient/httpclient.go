@@ -0,0 +1,146 @@
+// Package httpclient provides the HTTP client shared by all integrations: it
+// honors HTTP(S)_PROXY, times out unresponsive servers and retries transient
+// failures and rate limits with exponential backoff.
+package httpclient
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defaults of Options.
+const (
+	DefaultTimeout = 30 * time.Second
+	DefaultRetries = 3
+)
+
+// Options configure New.
+type Options struct {
+	Timeout    time.Duration // per attempt, until the response headers arrive; DefaultTimeout if zero
+	MaxRetries int           // retries after the first attempt
+	MaxWait    time.Duration // longest wait before a retry; a longer Retry-After is not waited for; 1 minute if zero
+}
+
+// Default is the client integrations use when none is configured.
+var Default = New(Options{MaxRetries: DefaultRetries})
+
+// New returns a client with the given options.
+func New(opts Options) *http.Client {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxWait == 0 {
+		opts.MaxWait = time.Minute
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = http.ProxyFromEnvironment
+	base.ResponseHeaderTimeout = opts.Timeout
+	return &http.Client{Transport: &Transport{
+		Base:       base,
+		MaxRetries: max(opts.MaxRetries, 0),
+		MinBackoff: time.Second,
+		MaxWait:    opts.MaxWait,
+	}}
+}
+
+// Transport retries requests that failed with a network error, a 429, 502,
+// 503 or 504 status, or a 403 caused by a rate limit (GitHub's secondary rate
+// limits). Requests whose body cannot be replayed are sent once.
+type Transport struct {
+	Base       http.RoundTripper
+	MaxRetries int
+	MinBackoff time.Duration // backoff before the first retry, doubled for each further one
+	MaxWait    time.Duration
+}
+
+// RoundTrip sends req, retrying as described on Transport.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.Base.RoundTrip(req)
+		if attempt >= t.MaxRetries || !retryable(resp, err) || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		wait, ok := t.backoff(resp, attempt)
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryable reports whether the attempt failed transiently.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+	return false
+}
+
+// backoff returns the wait before the next attempt: the server's Retry-After
+// or rate limit reset if given, exponential backoff with jitter otherwise. It
+// reports false if the server asks to wait longer than MaxWait.
+func (t *Transport) backoff(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp != nil {
+		if wait, ok := serverWait(resp.Header, time.Now()); ok {
+			return wait, wait <= t.MaxWait
+		}
+	}
+	wait := t.MinBackoff << attempt
+	wait += time.Duration(rand.Int63n(int64(wait)/2 + 1))
+	return min(wait, t.MaxWait), true
+}
+
+// serverWait reads Retry-After (seconds or an HTTP date) or, for exhausted
+// rate limits, X-RateLimit-Reset (Unix seconds).
+func serverWait(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,101 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestClient retries quickly.
+func newTestClient(retries int) *http.Client {
+	return &http.Client{Transport: &Transport{Base: http.DefaultTransport, MaxRetries: retries, MinBackoff: time.Millisecond, MaxWait: time.Second}}
+}
+
+// TestTransport_Retry replays the body after transient failures.
+func TestTransport_Retry(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		switch len(bodies) {
+		case 1:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "secondary rate limit", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := newTestClient(3).Post(srv.URL, "text/plain", bytes.NewReader([]byte("payload")))
+	if err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(bodies) != 3 || bodies[2] != "payload" {
+		t.Errorf("Unexpected result %s after %q", resp.Status, bodies)
+	}
+}
+
+// TestTransport_NoRetry returns permanent failures and exhausted retries as is.
+func TestTransport_NoRetry(t *testing.T) {
+	calls := 0
+	status := http.StatusForbidden
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	client := newTestClient(2)
+	resp, err := client.Get(srv.URL)
+	if err != nil || resp.StatusCode != http.StatusForbidden || calls != 1 {
+		t.Errorf("Expected a single 403, got %v, %v after %d calls", resp, err, calls)
+	}
+
+	calls, status = 0, http.StatusBadGateway
+	resp, err = client.Get(srv.URL)
+	if err != nil || resp.StatusCode != http.StatusBadGateway || calls != 3 {
+		t.Errorf("Expected 3 attempts, got %v, %v after %d calls", resp, err, calls)
+	}
+}
+
+// TestServerWait parses Retry-After and GitHub rate limit headers.
+func TestServerWait(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cases := []struct {
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{http.Header{"Retry-After": {"7"}}, 7 * time.Second, true},
+		{http.Header{"Retry-After": {now.Add(time.Minute).UTC().Format(http.TimeFormat)}}, time.Minute, true},
+		{http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"1030"}}, 30 * time.Second, true},
+		{http.Header{"X-Ratelimit-Remaining": {"5"}, "X-Ratelimit-Reset": {"1030"}}, 0, false},
+		{http.Header{}, 0, false},
+	}
+	for _, c := range cases {
+		if got, ok := serverWait(c.header, now); got != c.want || ok != c.ok {
+			t.Errorf("serverWait(%v) = %v, %v; want %v, %v", c.header, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+// TestTransport_LongRetryAfter does not wait longer than MaxWait.
+func TestTransport_LongRetryAfter(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	resp, err := newTestClient(3).Get(srv.URL)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests || calls != 1 {
+		t.Errorf("Expected the 429 without retrying, got %v, %v after %d calls", resp, err, calls)
+	}
+}
@@ -0,0 +1,354 @@
+// Package lsp implements a Language Server Protocol server on the stdio
+// transport that only publishes diagnostics: Content-Length framed JSON-RPC
+// 2.0 messages, with the diagnostics of open documents refreshed on save and
+// on a polling interval.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+// Diagnostic severities.
+const (
+	SeverityError       Severity = 1
+	SeverityWarning     Severity = 2
+	SeverityInformation Severity = 3
+	SeverityHint        Severity = 4
+)
+
+// Position is a zero-based line and UTF-16 character offset.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range in a document, End exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Diagnostic is a message about a range of a document.
+type Diagnostic struct {
+	Range    Range    `json:"range"`
+	Severity Severity `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// Server publishes the diagnostics returned by Diagnostics for the documents
+// the client has open.
+type Server struct {
+	Name    string
+	Version string
+	// Diagnostics returns the diagnostics per absolute file path. It is
+	// called after initialization, when a document is saved and every
+	// Interval, and should be cheap when nothing changed.
+	Diagnostics func(ctx context.Context) (map[string][]Diagnostic, error)
+	Interval    time.Duration // 2s if 0
+	Logf        func(format string, args ...any)
+
+	mu        sync.Mutex
+	w         *bufio.Writer
+	open      map[string]bool         // open documents by path
+	latest    map[string][]Diagnostic // the latest result of Diagnostics
+	published map[string][]Diagnostic // what the client has, per open document
+}
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a successful response; its result may be null.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes.
+const (
+	codeInvalidRequest       = -32600
+	codeMethodNotFound       = -32601
+	codeServerNotInitialized = -32002
+)
+
+// Serve handles messages from r, writing to w, until the client sends exit,
+// r ends or ctx is canceled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.w = bufio.NewWriter(w)
+	s.open = map[string]bool{}
+	interval := s.Interval
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+
+	msgs := make(chan *message)
+	errs := make(chan error, 1)
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			msg, err := readMessage(br)
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	initialized, shutdown := false, false
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-ticker.C:
+			if initialized && !shutdown {
+				s.refresh(ctx)
+			}
+		case msg := <-msgs:
+			switch msg.Method {
+			case "exit":
+				return nil
+			case "initialize":
+				initialized = true
+				s.reply(msg, map[string]any{
+					"capabilities": map[string]any{
+						"textDocumentSync": map[string]any{"openClose": true, "change": 0, "save": true},
+					},
+					"serverInfo": map[string]string{"name": s.Name, "version": s.Version},
+				}, nil)
+			case "initialized":
+				s.refresh(ctx)
+			case "shutdown":
+				shutdown = true
+				s.reply(msg, nil, nil)
+			case "textDocument/didOpen", "textDocument/didClose", "textDocument/didSave":
+				if !initialized || shutdown {
+					continue
+				}
+				var params struct {
+					TextDocument struct {
+						URI string `json:"uri"`
+					} `json:"textDocument"`
+				}
+				if err := json.Unmarshal(msg.Params, &params); err != nil {
+					continue
+				}
+				path, ok := uriPath(params.TextDocument.URI)
+				if !ok {
+					continue
+				}
+				switch msg.Method {
+				case "textDocument/didOpen":
+					s.setOpen(path, true)
+				case "textDocument/didClose":
+					s.setOpen(path, false)
+				default:
+					s.refresh(ctx)
+				}
+			default:
+				switch {
+				case msg.ID == nil:
+					// Other notifications, e.g. didChange, need no reply.
+				case !initialized:
+					s.reply(msg, nil, &rpcError{codeServerNotInitialized, "server not initialized"})
+				case shutdown:
+					s.reply(msg, nil, &rpcError{codeInvalidRequest, "server is shutting down"})
+				default:
+					s.reply(msg, nil, &rpcError{codeMethodNotFound, fmt.Sprintf("method %q not found", msg.Method)})
+				}
+			}
+		}
+	}
+}
+
+// refresh recomputes the diagnostics and publishes those that changed.
+func (s *Server) refresh(ctx context.Context) {
+	diags, err := s.Diagnostics(ctx)
+	if err != nil {
+		s.logf("%v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest = diags
+	for path := range s.open {
+		s.publish(path)
+	}
+}
+
+// setOpen records whether the client has path open, publishing its
+// diagnostics on open and clearing them on close.
+func (s *Server) setOpen(path string, open bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if open {
+		s.open[path] = true
+		s.publish(path)
+		return
+	}
+	delete(s.open, path)
+	if len(s.published[path]) > 0 {
+		s.notify("textDocument/publishDiagnostics", map[string]any{"uri": pathURI(path), "diagnostics": []Diagnostic{}})
+	}
+	delete(s.published, path)
+}
+
+// publish sends the latest diagnostics of path unless the client has them.
+// The caller holds mu.
+func (s *Server) publish(path string) {
+	diags := s.latest[path]
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	if prev, ok := s.published[path]; ok && reflect.DeepEqual(prev, diags) {
+		return
+	}
+	if s.published == nil {
+		s.published = map[string][]Diagnostic{}
+	}
+	s.published[path] = diags
+	s.notify("textDocument/publishDiagnostics", map[string]any{"uri": pathURI(path), "diagnostics": diags})
+}
+
+func (s *Server) reply(req *message, result any, rpcErr *rpcError) {
+	if req.ID == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rpcErr != nil {
+		s.write(&errorResponse{JSONRPC: "2.0", ID: req.ID, Error: rpcErr})
+		return
+	}
+	s.write(&response{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+// notify sends a notification. The caller holds mu.
+func (s *Server) notify(method string, params any) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		s.logf("%v", err)
+		return
+	}
+	s.write(&message{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+// write sends one framed message. The caller holds mu.
+func (s *Server) write(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logf("%v", err)
+		return
+	}
+	fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(data))
+	s.w.Write(data)
+	if err := s.w.Flush(); err != nil {
+		s.logf("%v", err)
+	}
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// readMessage reads one Content-Length framed message.
+func readMessage(r *bufio.Reader) (*message, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("error reading message header: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("error reading message: %v", err)
+	}
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("error parsing message: %v", err)
+	}
+	return &msg, nil
+}
+
+// uriPath returns the file path of a file:// URI.
+func uriPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	path := u.Path
+	// file:///C:/src/a.go
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path)), true
+}
+
+// pathURI returns the file:// URI of an absolute path.
+func pathURI(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
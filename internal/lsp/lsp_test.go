@@ -0,0 +1,130 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func frame(msgs ...string) string {
+	var sb strings.Builder
+	for _, m := range msgs {
+		fmt.Fprintf(&sb, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	return sb.String()
+}
+
+// TestServer_Serve publishes diagnostics of opened documents, skipping
+// unchanged ones, and clears them on close.
+func TestServer_Serve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.go")
+	uri := pathURI(path)
+	calls := 0
+	s := &Server{
+		Name:     "test",
+		Interval: time.Hour,
+		Diagnostics: func(ctx context.Context) (map[string][]Diagnostic, error) {
+			calls++
+			return map[string][]Diagnostic{path: {{Range: Range{Start: Position{Line: 2}, End: Position{Line: 3}}, Severity: SeverityWarning, Source: "test", Message: "uncovered"}}}, nil
+		},
+	}
+	in := frame(
+		`{"jsonrpc":"2.0","id":0,"method":"textDocument/hover"}`,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"`+uri+`","text":""}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didSave","params":{"textDocument":{"uri":"`+uri+`"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didClose","params":{"textDocument":{"uri":"`+uri+`"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	r := bufio.NewReader(&out)
+	for {
+		data, err := readRaw(r)
+		if err != nil {
+			break
+		}
+		var msg struct {
+			ID     *int
+			Method string
+			Error  *struct{ Code int }
+			Params struct {
+				URI         string
+				Diagnostics []Diagnostic
+			}
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case msg.Error != nil:
+			got = append(got, fmt.Sprintf("error %d", msg.Error.Code))
+		case msg.ID != nil:
+			got = append(got, fmt.Sprintf("response %d", *msg.ID))
+		default:
+			if msg.Params.URI != uri {
+				t.Errorf("Unexpected URI %s", msg.Params.URI)
+			}
+			got = append(got, fmt.Sprintf("%s %d", msg.Method, len(msg.Params.Diagnostics)))
+		}
+	}
+	want := []string{
+		"error -32002",
+		"response 1",
+		"textDocument/publishDiagnostics 1",
+		"textDocument/publishDiagnostics 0",
+		"response 2",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Messages:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if calls != 2 {
+		t.Errorf("Expected diagnostics after initialized and on save, got %d calls", calls)
+	}
+}
+
+// readRaw reads the body of one framed message.
+func readRaw(r *bufio.Reader) ([]byte, error) {
+	var length int
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == "\r\n" {
+			break
+		}
+		fmt.Sscanf(line, "Content-Length: %d", &length)
+	}
+	data := make([]byte, length)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+// TestURIPath converts file URIs to paths and back.
+func TestURIPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir with space", "a.go")
+	uri := pathURI(path)
+	if !strings.HasPrefix(uri, "file:///") || !strings.Contains(uri, "dir%20with%20space") {
+		t.Errorf("pathURI = %s", uri)
+	}
+	if got, ok := uriPath(uri); !ok || got != path {
+		t.Errorf("uriPath(%s) = %s, %v", uri, got, ok)
+	}
+	if _, ok := uriPath("untitled:Untitled-1"); ok {
+		t.Errorf("Expected non-file URIs to be rejected")
+	}
+}
@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testResponse struct {
+	ID     json.RawMessage
+	Result struct {
+		ProtocolVersion string
+		Tools           []struct{ Name string }
+		Content         []struct{ Text string }
+		IsError         bool
+	}
+	Error *struct{ Code int }
+}
+
+// TestServer_Serve runs a session: initialize, list and call tools.
+func TestServer_Serve(t *testing.T) {
+	s := &Server{Name: "test", Version: "1.0", Tools: []Tool{
+		{
+			Name:        "echo",
+			InputSchema: map[string]any{"type": "object"},
+			Call: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var a struct{ Text string }
+				json.Unmarshal(args, &a)
+				return a.Text, nil
+			},
+		},
+		{
+			Name:        "fail",
+			InputSchema: map[string]any{"type": "object"},
+			Call: func(ctx context.Context, args json.RawMessage) (string, error) {
+				return "", errors.New("boom")
+			},
+		},
+	}}
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"fail"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+		`not json`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("Expected 7 responses (none for the notification), got %d:\n%s", len(lines), out.String())
+	}
+	var resps []testResponse
+	for _, l := range lines {
+		var resp testResponse
+		if err := json.Unmarshal([]byte(l), &resp); err != nil {
+			t.Fatal(err)
+		}
+		resps = append(resps, resp)
+	}
+
+	if resps[0].Result.ProtocolVersion != ProtocolVersion {
+		t.Errorf("initialize: %s", lines[0])
+	}
+	if len(resps[1].Result.Tools) != 2 || resps[1].Result.Tools[0].Name != "echo" {
+		t.Errorf("tools/list: %s", lines[1])
+	}
+	if resps[2].Result.IsError || resps[2].Result.Content[0].Text != "hi" {
+		t.Errorf("echo: %s", lines[2])
+	}
+	if !resps[3].Result.IsError || resps[3].Result.Content[0].Text != "boom" {
+		t.Errorf("fail: %s", lines[3])
+	}
+	if resps[4].Error == nil || resps[4].Error.Code != codeInvalidParams {
+		t.Errorf("unknown tool: %s", lines[4])
+	}
+	if resps[5].Error == nil || resps[5].Error.Code != codeMethodNotFound {
+		t.Errorf("unknown method: %s", lines[5])
+	}
+	if resps[6].Error == nil || resps[6].Error.Code != codeParseError {
+		t.Errorf("parse error: %s", lines[6])
+	}
+}
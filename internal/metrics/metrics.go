@@ -0,0 +1,171 @@
+// Package metrics serves the health, readiness and Prometheus metrics
+// endpoints of the long-running services, for Kubernetes probes and scraping.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Buckets are the upper bounds, in seconds, of the analysis duration histogram.
+var Buckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Metrics counts the requests and analyses of a service.
+type Metrics struct {
+	// Ready reports whether the service accepts work; /readyz fails with its
+	// error. Always ready if nil.
+	Ready func() error
+	// QueueDepth returns the number of analyses waiting to start, if set.
+	QueueDepth func() int
+
+	mu       sync.Mutex
+	requests map[[2]string]int // handler, status code
+	analyses map[string]*histogram
+}
+
+type histogram struct {
+	counts []int // per bucket, not cumulative; the last one is +Inf
+	sum    float64
+}
+
+// Register adds /healthz, /readyz and /metrics to mux.
+func (m *Metrics) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if m.Ready != nil {
+			if err := m.Ready(); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(m.Expose())
+	})
+}
+
+// Instrument counts the requests served by h under the handler label.
+func (m *Metrics) Instrument(handler string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.requests == nil {
+			m.requests = map[[2]string]int{}
+		}
+		m.requests[[2]string{handler, fmt.Sprint(sw.status)}]++
+	})
+}
+
+// ObserveAnalysis records an analysis that took d and ended with result,
+// e.g. "published" or "error".
+func (m *Metrics) ObserveAnalysis(result string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.analyses == nil {
+		m.analyses = map[string]*histogram{}
+	}
+	h := m.analyses[result]
+	if h == nil {
+		h = &histogram{counts: make([]int, len(Buckets)+1)}
+		m.analyses[result] = h
+	}
+	seconds := d.Seconds()
+	h.counts[sort.SearchFloat64s(Buckets, seconds)]++
+	h.sum += seconds
+}
+
+// Expose renders the metrics in the Prometheus text exposition format.
+func (m *Metrics) Expose() []byte {
+	var buf bytes.Buffer
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buf.WriteString("# HELP diffcoverage_http_requests_total HTTP requests served, by handler and status code.\n")
+	buf.WriteString("# TYPE diffcoverage_http_requests_total counter\n")
+	for _, k := range sortedKeys(m.requests, func(a, b [2]string) bool { return a[0] < b[0] || a[0] == b[0] && a[1] < b[1] }) {
+		fmt.Fprintf(&buf, "diffcoverage_http_requests_total{handler=\"%s\",code=\"%s\"} %d\n", escapeLabel(k[0]), k[1], m.requests[k])
+	}
+
+	buf.WriteString("# HELP diffcoverage_analysis_duration_seconds Duration of the analyses, by result.\n")
+	buf.WriteString("# TYPE diffcoverage_analysis_duration_seconds histogram\n")
+	for _, result := range sortedKeys(m.analyses, func(a, b string) bool { return a < b }) {
+		h, label := m.analyses[result], escapeLabel(result)
+		count := 0
+		for i, n := range h.counts {
+			count += n
+			le := "+Inf"
+			if i < len(Buckets) {
+				le = fmt.Sprint(Buckets[i])
+			}
+			fmt.Fprintf(&buf, "diffcoverage_analysis_duration_seconds_bucket{result=\"%s\",le=\"%s\"} %d\n", label, le, count)
+		}
+		fmt.Fprintf(&buf, "diffcoverage_analysis_duration_seconds_sum{result=\"%s\"} %g\n", label, h.sum)
+		fmt.Fprintf(&buf, "diffcoverage_analysis_duration_seconds_count{result=\"%s\"} %d\n", label, count)
+	}
+
+	if m.QueueDepth != nil {
+		buf.WriteString("# HELP diffcoverage_queue_depth Analyses waiting to start.\n")
+		buf.WriteString("# TYPE diffcoverage_queue_depth gauge\n")
+		fmt.Fprintf(&buf, "diffcoverage_queue_depth %d\n", m.QueueDepth())
+	}
+	return buf.Bytes()
+}
+
+// statusWriter records the status code of a response. It forwards Flush, which
+// streaming gRPC responses rely on.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func sortedKeys[K comparable, V any](m map[K]V, less func(a, b K) bool) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return keys
+}
+
+// escapeLabel escapes a Prometheus label value.
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMetrics_Endpoints serves the probes and the counted requests and analyses.
+func TestMetrics_Endpoints(t *testing.T) {
+	var notReady error
+	m := &Metrics{Ready: func() error { return notReady }, QueueDepth: func() int { return 3 }}
+	mux := http.NewServeMux()
+	mux.Handle("/hook", m.Instrument("hook", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	m.Register(mux)
+	get := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := get(http.MethodGet, "/healthz"); w.Code != http.StatusOK {
+		t.Errorf("/healthz: status %d", w.Code)
+	}
+	if w := get(http.MethodGet, "/readyz"); w.Code != http.StatusOK {
+		t.Errorf("/readyz: status %d", w.Code)
+	}
+	notReady = errors.New("queue full")
+	if w := get(http.MethodGet, "/readyz"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "queue full") {
+		t.Errorf("/readyz when not ready: status %d, %q", w.Code, w.Body.String())
+	}
+
+	get(http.MethodPost, "/hook")
+	get(http.MethodPost, "/hook")
+	get(http.MethodGet, "/hook")
+	m.ObserveAnalysis("published", 700*time.Millisecond)
+	m.ObserveAnalysis("published", 20*time.Second)
+
+	body := get(http.MethodGet, "/metrics").Body.String()
+	for _, want := range []string{
+		`diffcoverage_http_requests_total{handler="hook",code="200"} 2`,
+		`diffcoverage_http_requests_total{handler="hook",code="405"} 1`,
+		`diffcoverage_analysis_duration_seconds_bucket{result="published",le="0.5"} 0`,
+		`diffcoverage_analysis_duration_seconds_bucket{result="published",le="1"} 1`,
+		`diffcoverage_analysis_duration_seconds_bucket{result="published",le="+Inf"} 2`,
+		`diffcoverage_analysis_duration_seconds_sum{result="published"} 20.7`,
+		`diffcoverage_analysis_duration_seconds_count{result="published"} 2`,
+		"diffcoverage_queue_depth 3",
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("Expected %s in:\n%s", want, body)
+		}
+	}
+}
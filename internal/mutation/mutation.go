@@ -0,0 +1,268 @@
+// Package mutation checks that tests assert on the changed lines they cover:
+// it applies small mutations to those lines and reruns the tests, which
+// should fail for at least one mutant of each line.
+package mutation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// Mutant is a single mutation of a line: the source bytes [start, end) are
+// replaced with replacement.
+type Mutant struct {
+	File        string // relative to the source root
+	Line        int
+	Description string
+
+	start, end  int
+	replacement string
+}
+
+// Result is the outcome of running the tests against a mutant.
+type Result int
+
+const (
+	// Killed means a test failed (or timed out): the mutation was detected.
+	Killed Result = iota
+	// Survived means all tests passed despite the mutation.
+	Survived
+	// Invalid means the mutant did not compile and says nothing about the tests.
+	Invalid
+)
+
+// String returns the lowercase name of the result.
+func (r Result) String() string {
+	switch r {
+	case Killed:
+		return "killed"
+	case Survived:
+		return "survived"
+	}
+	return "invalid"
+}
+
+// negated maps operators to the operator of the opposite condition.
+var negated = map[token.Token]token.Token{
+	token.EQL: token.NEQ, token.NEQ: token.EQL,
+	token.LSS: token.GEQ, token.GEQ: token.LSS,
+	token.GTR: token.LEQ, token.LEQ: token.GTR,
+	token.LAND: token.LOR, token.LOR: token.LAND,
+}
+
+// Generate returns the mutants of the given lines of file, relative to root:
+// comparison and logical operators and boolean literals are flipped,
+// conditions that are not comparisons are negated and expression, increment
+// and assignment statements are dropped.
+func Generate(root, file string, lines map[int]bool) ([]Mutant, error) {
+	src, err := os.ReadFile(filepath.Join(root, file))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", file, err)
+	}
+
+	var mutants []Mutant
+	add := func(pos, end token.Pos, replacement, description string) {
+		line := fset.Position(pos).Line
+		if !lines[line] {
+			return
+		}
+		mutants = append(mutants, Mutant{
+			File:        file,
+			Line:        line,
+			Description: description,
+			start:       fset.Position(pos).Offset,
+			end:         fset.Position(end).Offset,
+			replacement: replacement,
+		})
+	}
+	text := func(n ast.Node) string {
+		return string(src[fset.Position(n.Pos()).Offset:fset.Position(n.End()).Offset])
+	}
+	negate := func(cond ast.Expr) {
+		switch c := cond.(type) {
+		case *ast.BinaryExpr:
+			if _, ok := negated[c.Op]; ok {
+				return // flipped as an operator
+			}
+		case *ast.UnaryExpr:
+			if c.Op == token.NOT {
+				add(c.Pos(), c.End(), text(c.X), "removed !")
+				return
+			}
+		}
+		if cond != nil {
+			add(cond.Pos(), cond.End(), "!("+text(cond)+")", "negated condition")
+		}
+	}
+
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.BinaryExpr:
+				if op, ok := negated[n.Op]; ok {
+					add(n.OpPos, n.OpPos+token.Pos(len(n.Op.String())), op.String(), fmt.Sprintf("replaced %s with %s", n.Op, op))
+				}
+			case *ast.Ident:
+				if n.Name == "true" || n.Name == "false" {
+					flipped := "true"
+					if n.Name == "true" {
+						flipped = "false"
+					}
+					add(n.Pos(), n.End(), flipped, fmt.Sprintf("replaced %s with %s", n.Name, flipped))
+				}
+			case *ast.IfStmt:
+				negate(n.Cond)
+			case *ast.ForStmt:
+				negate(n.Cond)
+			case *ast.ExprStmt:
+				add(n.Pos(), n.End(), "", "removed statement")
+			case *ast.IncDecStmt:
+				add(n.Pos(), n.End(), "", "removed statement")
+			case *ast.AssignStmt:
+				if n.Tok != token.DEFINE {
+					add(n.Pos(), n.End(), "", "removed statement")
+				}
+			}
+			return true
+		})
+	}
+	sort.SliceStable(mutants, func(i, j int) bool { return mutants[i].Line < mutants[j].Line })
+	return mutants, nil
+}
+
+// Runner runs tests against mutants without modifying the source tree, using
+// go test -overlay.
+type Runner struct {
+	Root string // module root
+	// Timeout bounds each test run; mutants that hang the tests are killed.
+	Timeout time.Duration
+}
+
+// Run tests pkgs with m applied.
+func (r *Runner) Run(m Mutant, pkgs []string) (Result, error) {
+	path, err := filepath.Abs(filepath.Join(r.Root, m.File))
+	if err != nil {
+		return Invalid, err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return Invalid, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "diffcoverage-mutant")
+	if err != nil {
+		return Invalid, err
+	}
+	defer os.RemoveAll(tmpDir)
+	mutated := append(append(append([]byte{}, src[:m.start]...), m.replacement...), src[m.end:]...)
+	mutatedPath := filepath.Join(tmpDir, filepath.Base(path))
+	if err := os.WriteFile(mutatedPath, mutated, 0644); err != nil {
+		return Invalid, err
+	}
+	overlay, err := json.Marshal(map[string]map[string]string{"Replace": {path: mutatedPath}})
+	if err != nil {
+		return Invalid, err
+	}
+	overlayPath := filepath.Join(tmpDir, "overlay.json")
+	if err := os.WriteFile(overlayPath, overlay, 0644); err != nil {
+		return Invalid, err
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	args := append([]string{"test", "-count=1", "-failfast", "-overlay=" + overlayPath, "-timeout=" + timeout.String()}, pkgs...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = r.Root
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+	switch {
+	case err == nil:
+		return Survived, nil
+	case bytes.Contains(out.Bytes(), []byte("[build failed]")) || bytes.Contains(out.Bytes(), []byte("[setup failed]")):
+		return Invalid, nil
+	case cmd.ProcessState == nil:
+		return Invalid, fmt.Errorf("go test: %v", err)
+	}
+	return Killed, nil
+}
+
+// Attach mutates the covered changed lines of r and sets r.Survivors to the
+// lines whose valid mutants all survived. tests returns the packages to test
+// for a changed file; progress, if not nil, receives a line per mutant.
+func Attach(a *diffcoverage.Analysis, r *diffcoverage.Report, runner *Runner, tests func(file string) []string, progress io.Writer) error {
+	r.Survivors = nil
+	for _, f := range r.Files {
+		covered := map[int]bool{}
+		for line := range a.Diff.NewLines[a.DiffKey(f.Path)] {
+			if a.Status(f.Path, line) == diffcoverage.LineCovered {
+				covered[line] = true
+			}
+		}
+		if len(covered) == 0 {
+			continue
+		}
+		mutants, err := Generate(r.SourceRoot, f.Path, covered)
+		if err != nil {
+			return err
+		}
+		pkgs := tests(f.Path)
+
+		killed := map[int]bool{}
+		survivors := map[int][]string{}
+		for _, m := range mutants {
+			if killed[m.Line] {
+				continue
+			}
+			res, err := runner.Run(m, pkgs)
+			if err != nil {
+				return err
+			}
+			if progress != nil {
+				fmt.Fprintf(progress, "%s:%d: %s: %s\n", m.File, m.Line, m.Description, res)
+			}
+			switch res {
+			case Killed:
+				killed[m.Line] = true
+			case Survived:
+				survivors[m.Line] = append(survivors[m.Line], m.Description)
+			}
+		}
+
+		var lines []int
+		for line := range survivors {
+			if !killed[line] {
+				lines = append(lines, line)
+			}
+		}
+		sort.Ints(lines)
+		for _, line := range lines {
+			r.Survivors = append(r.Survivors, diffcoverage.Survivor{File: f.Path, Line: line, Mutations: survivors[line]})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package mutation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const source = `package m
+
+func Check(n int, ok bool) bool {
+	if !ok {
+		return false
+	}
+	k := n
+	for n > 0 && ok {
+		println(k)
+		n = 0
+	}
+	return n == 0
+}
+`
+
+// setupModule writes a module with source in m.go and the given test.
+func setupModule(t *testing.T, test string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":    "module example.com/m\n\ngo 1.21\n",
+		"m.go":      source,
+		"m_test.go": test,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestGenerate checks the mutations of each kind on the requested lines only.
+func TestGenerate(t *testing.T) {
+	dir := setupModule(t, "package m\n")
+	lines := map[int]bool{}
+	for line := 4; line <= 12; line++ {
+		lines[line] = true
+	}
+	mutants, err := Generate(dir, "m.go", lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, m := range mutants {
+		got = append(got, m.Description)
+	}
+	want := []string{
+		"removed !",
+		"replaced false with true",
+		"replaced && with ||",
+		"replaced > with <=",
+		"removed statement",
+		"removed statement",
+		"replaced == with !=",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Generate = %q, want %q", got, want)
+	}
+	if mutants[0].Line != 4 || mutants[len(mutants)-1].Line != 12 {
+		t.Errorf("Unexpected lines %d and %d", mutants[0].Line, mutants[len(mutants)-1].Line)
+	}
+
+	if _, err := Generate(dir, "missing.go", lines); err == nil {
+		t.Errorf("Expected error for a missing file, got nil")
+	}
+}
+
+// TestRunner_Run checks each result without modifying the source file.
+func TestRunner_Run(t *testing.T) {
+	dir := setupModule(t, "package m\n\nimport \"testing\"\n\nfunc TestCheck(t *testing.T) {\n\tif Check(1, false) {\n\t\tt.Fatal()\n\t}\n\tCheck(1, true)\n}\n")
+	mutants, err := Generate(dir, "m.go", map[int]bool{5: true, 9: true, 12: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mutants) != 3 {
+		t.Fatalf("Expected 3 mutants, got %+v", mutants)
+	}
+
+	r := &Runner{Root: dir}
+	for i, want := range []Result{Killed, Invalid, Survived} {
+		got, err := r.Run(mutants[i], []string{"./..."})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s on line %d: got %s, want %s", mutants[i].Description, mutants[i].Line, got, want)
+		}
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "m.go"))
+	if string(content) != source {
+		t.Errorf("Source file was modified:\n%s", content)
+	}
+}
@@ -0,0 +1,258 @@
+// Package parquet writes flat tables as Apache Parquet files: a single row
+// group of required columns, each in one uncompressed, PLAIN-encoded data
+// page, which every Parquet reader supports.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const magic = "PAR1"
+
+// Physical types, converted types, encodings and compact protocol types of
+// the Parquet format (see parquet.thrift).
+const (
+	typeInt32     = 1
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// Column is a named column of values of one type.
+type Column struct {
+	Name      string
+	typ       int32
+	converted int32 // -1 if none
+	n         int
+	data      []byte // PLAIN encoded values
+}
+
+// String returns a UTF-8 string column.
+func String(name string, values []string) Column {
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(v)))
+		data = append(data, v...)
+	}
+	return Column{Name: name, typ: typeByteArray, converted: convertedUTF8, n: len(values), data: data}
+}
+
+// Int32 returns a 32-bit integer column.
+func Int32(name string, values []int32) Column {
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, uint32(v))
+	}
+	return Column{Name: name, typ: typeInt32, converted: -1, n: len(values), data: data}
+}
+
+// Int64 returns a 64-bit integer column.
+func Int64(name string, values []int64) Column {
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint64(data, uint64(v))
+	}
+	return Column{Name: name, typ: typeInt64, converted: -1, n: len(values), data: data}
+}
+
+// Double returns a float64 column.
+func Double(name string, values []float64) Column {
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+	}
+	return Column{Name: name, typ: typeDouble, converted: -1, n: len(values), data: data}
+}
+
+// Timestamp returns a column of timestamps with millisecond precision.
+func Timestamp(name string, values []time.Time) Column {
+	millis := make([]int64, len(values))
+	for i, v := range values {
+		millis[i] = v.UnixMilli()
+	}
+	c := Int64(name, millis)
+	c.converted = convertedTimestampMillis
+	return c
+}
+
+// chunk locates a written column.
+type chunk struct {
+	offset, size int64
+}
+
+// Write writes the columns, which must have the same number of values, as a
+// Parquet file; createdBy names the writing application.
+func Write(w io.Writer, createdBy string, columns ...Column) error {
+	rows := 0
+	for i, c := range columns {
+		if i > 0 && c.n != rows {
+			return fmt.Errorf("parquet: column %s has %d values, want %d", c.Name, c.n, rows)
+		}
+		rows = c.n
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+	chunks := make([]chunk, len(columns))
+	for i, c := range columns {
+		var header compact
+		header.begin()
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(len(c.data)))
+		header.i32(3, int32(len(c.data)))
+		header.beginStruct(5)
+		header.i32(1, int32(c.n))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.end()
+		header.end()
+
+		chunks[i].offset = int64(buf.Len())
+		buf.Write(header.buf.Bytes())
+		buf.Write(c.data)
+		chunks[i].size = int64(buf.Len()) - chunks[i].offset
+	}
+
+	var meta compact
+	meta.begin()
+	meta.i32(1, 1) // version
+	meta.list(2, compactStruct, len(columns)+1)
+	meta.begin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.end()
+	for _, c := range columns {
+		meta.begin()
+		meta.i32(1, c.typ)
+		meta.i32(3, 0) // REQUIRED
+		meta.binary(4, c.Name)
+		if c.converted >= 0 {
+			meta.i32(6, c.converted)
+		}
+		meta.end()
+	}
+	meta.i64(3, int64(rows))
+	meta.list(4, compactStruct, 1)
+	meta.begin()
+	meta.list(1, compactStruct, len(columns))
+	var total int64
+	for i, c := range columns {
+		meta.begin()
+		meta.i64(2, chunks[i].offset)
+		meta.beginStruct(3)
+		meta.i32(1, c.typ)
+		meta.list(2, compactI32, 1)
+		meta.varint(encodingPlain)
+		meta.list(3, compactBinary, 1)
+		meta.str(c.Name)
+		meta.i32(4, 0) // UNCOMPRESSED
+		meta.i64(5, int64(c.n))
+		meta.i64(6, chunks[i].size)
+		meta.i64(7, chunks[i].size)
+		meta.i64(9, chunks[i].offset)
+		meta.end()
+		meta.end()
+		total += chunks[i].size
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(rows))
+	meta.end()
+	meta.binary(6, createdBy)
+	meta.end()
+
+	buf.Write(meta.buf.Bytes())
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(meta.buf.Len())))
+	buf.WriteString(magic)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// compact encodes Thrift structs with the compact protocol. Structs are
+// opened with begin or beginStruct and closed with end.
+type compact struct {
+	buf  bytes.Buffer
+	last []int16 // the last field id of each open struct
+}
+
+func (c *compact) begin() {
+	c.last = append(c.last, 0)
+}
+
+func (c *compact) end() {
+	c.buf.WriteByte(0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compact) field(id int16, typ byte) {
+	top := len(c.last) - 1
+	if delta := id - c.last[top]; delta > 0 && delta <= 15 {
+		c.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		c.buf.WriteByte(typ)
+		c.varint(int64(id))
+	}
+	c.last[top] = id
+}
+
+func (c *compact) beginStruct(id int16) {
+	c.field(id, compactStruct)
+	c.begin()
+}
+
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, compactI32)
+	c.varint(int64(v))
+}
+
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, compactI64)
+	c.varint(v)
+}
+
+func (c *compact) binary(id int16, s string) {
+	c.field(id, compactBinary)
+	c.str(s)
+}
+
+// list starts a list field of n elements, which follow.
+func (c *compact) list(id int16, elem byte, n int) {
+	c.field(id, compactList)
+	if n < 15 {
+		c.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	c.buf.WriteByte(0xF0 | elem)
+	c.uvarint(uint64(n))
+}
+
+// varint writes a zigzag-encoded integer, as for i16, i32 and i64 values.
+func (c *compact) varint(v int64) {
+	c.uvarint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (c *compact) uvarint(v uint64) {
+	c.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (c *compact) str(s string) {
+	c.uvarint(uint64(len(s)))
+	c.buf.WriteString(s)
+}
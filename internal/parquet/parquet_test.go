@@ -0,0 +1,136 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// reader decodes Thrift compact structs into maps of field id to value.
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *reader) varint() int64 {
+	u := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *reader) value(typ byte) any {
+	switch typ {
+	case compactI32, compactI64:
+		return r.varint()
+	case compactBinary:
+		n := int(r.uvarint())
+		s := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case compactList:
+		head := r.data[r.pos]
+		r.pos++
+		n, elem := int(head>>4), head&0x0F
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case compactStruct:
+		fields := map[int64]any{}
+		var last int64
+		for {
+			head := r.data[r.pos]
+			r.pos++
+			if head == 0 {
+				return fields
+			}
+			id := last + int64(head>>4)
+			if head>>4 == 0 {
+				id = r.varint()
+			}
+			fields[id] = r.value(head & 0x0F)
+			last = id
+		}
+	}
+	panic("unsupported type")
+}
+
+// TestWrite reads the written file back through its metadata.
+func TestWrite(t *testing.T) {
+	names := make([]string, 20)
+	for i := range names {
+		names[i] = string(rune('a' + i))
+	}
+	lines := make([]int32, 20)
+	counts := make([]int64, 20)
+	ratios := make([]float64, 20)
+	times := make([]time.Time, 20)
+	for i := range lines {
+		lines[i], counts[i], ratios[i], times[i] = int32(i), int64(i)*1e10, float64(i)/4, time.UnixMilli(int64(i))
+	}
+	var buf bytes.Buffer
+	err := Write(&buf, "test", String("name", names), Int32("line", lines), Int64("count", counts), Double("ratio", ratios), Timestamp("time", times))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatalf("missing magic")
+	}
+	metaLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &reader{data: data[:len(data)-8], pos: len(data) - 8 - metaLen}
+	meta := r.value(compactStruct).(map[int64]any)
+	if r.pos != len(data)-8 {
+		t.Fatalf("metadata ends at %d, want %d", r.pos, len(data)-8)
+	}
+	if meta[3] != int64(20) || meta[6] != "test" {
+		t.Errorf("num_rows = %v, created_by = %v", meta[3], meta[6])
+	}
+	schema := meta[2].([]any)
+	if len(schema) != 6 || schema[0].(map[int64]any)[5] != int64(5) || schema[5].(map[int64]any)[6] != int64(convertedTimestampMillis) {
+		t.Errorf("schema = %v", schema)
+	}
+
+	group := meta[4].([]any)[0].(map[int64]any)
+	columns := group[1].([]any)
+	decode := func(i int) []byte {
+		md := columns[i].(map[int64]any)[3].(map[int64]any)
+		pr := &reader{data: data, pos: int(md[9].(int64))}
+		page := pr.value(compactStruct).(map[int64]any)
+		if page[5].(map[int64]any)[1] != int64(20) {
+			t.Errorf("column %d: page has %v values", i, page[5].(map[int64]any)[1])
+		}
+		return data[pr.pos : pr.pos+int(page[3].(int64))]
+	}
+	if got := decode(0); string(got[4:5]) != "a" || binary.LittleEndian.Uint32(got[5:]) != 1 || string(got[9:10]) != "b" {
+		t.Errorf("name column = %q", got)
+	}
+	if got := decode(1); binary.LittleEndian.Uint32(got[4*19:]) != 19 {
+		t.Errorf("line column = %v", got)
+	}
+	if got := decode(2); binary.LittleEndian.Uint64(got[8*19:]) != 19e10 {
+		t.Errorf("count column = %v", got)
+	}
+	if got := decode(3); math.Float64frombits(binary.LittleEndian.Uint64(got[8*2:])) != 0.5 {
+		t.Errorf("ratio column = %v", got)
+	}
+}
+
+// TestWrite_Mismatch rejects columns of different lengths.
+func TestWrite_Mismatch(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "test", Int32("a", []int32{1}), Int32("b", nil)); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,446 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Vars returns the fields of the JSON encoding of v, e.g. a report, as
+// variables. Numbers are float64, objects map[string]any and arrays []any.
+// Fields of a struct v that the encoding omits are null.
+func Vars(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var vars map[string]any
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, err
+	}
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if _, ok := vars[name]; !ok && name != "" && name != "-" && t.Field(i).IsExported() {
+				vars[name] = nil
+			}
+		}
+	}
+	return vars, nil
+}
+
+// Eval evaluates the expression with the given variables. Fields missing
+// from an object are null, so fields the JSON encoding omits when empty can
+// be used without has.
+func (p *Program) Eval(vars map[string]any) (any, error) {
+	return p.root.eval(&scope{vars: vars})
+}
+
+// Check evaluates the expression, which must result in a bool.
+func (p *Program) Check(vars map[string]any) (bool, error) {
+	v, err := p.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression is %s, not bool", typeName(v))
+	}
+	return b, nil
+}
+
+// scope holds the variables: the root scope those passed to Eval, each
+// nested scope the variable of a macro.
+type scope struct {
+	vars   map[string]any
+	name   string
+	value  any
+	parent *scope
+}
+
+func (s *scope) lookup(name string) (any, bool) {
+	for ; s.parent != nil; s = s.parent {
+		if s.name == name {
+			return s.value, true
+		}
+	}
+	v, ok := s.vars[name]
+	return v, ok
+}
+
+type node interface {
+	eval(s *scope) (any, error)
+}
+
+type (
+	literal struct{ v any }
+	ident   struct{ name string }
+	list    struct{ elems []node }
+	unary   struct {
+		op string
+		x  node
+	}
+	binary struct {
+		op   string
+		x, y node
+	}
+	cond   struct{ c, t, f node }
+	member struct {
+		x    node
+		name string
+	}
+	index struct{ x, i node }
+	call  struct {
+		recv node // nil for functions
+		name string
+		args []node
+	}
+)
+
+func (n *literal) eval(*scope) (any, error) { return n.v, nil }
+
+func (n *ident) eval(s *scope) (any, error) {
+	v, ok := s.lookup(n.name)
+	if !ok {
+		return nil, fmt.Errorf("undeclared reference to %s", n.name)
+	}
+	return v, nil
+}
+
+func (n *list) eval(s *scope) (any, error) {
+	l := make([]any, 0, len(n.elems))
+	for _, e := range n.elems {
+		v, err := e.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, v)
+	}
+	return l, nil
+}
+
+func (n *unary) eval(s *scope) (any, error) {
+	v, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case bool:
+		if n.op == "!" {
+			return !v, nil
+		}
+	case float64:
+		if n.op == "-" {
+			return -v, nil
+		}
+	}
+	return nil, fmt.Errorf("no such operator %s%s", n.op, typeName(v))
+}
+
+func (n *binary) eval(s *scope) (any, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		b, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("no such operator %s %s", typeName(x), n.op)
+		}
+		if b == (n.op == "||") {
+			return b, nil
+		}
+		y, err := n.y.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := y.(bool); !ok {
+			return nil, fmt.Errorf("no such operator bool %s %s", n.op, typeName(y))
+		}
+		return y, nil
+	}
+
+	y, err := n.y.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return reflect.DeepEqual(x, y), nil
+	case "!=":
+		return !reflect.DeepEqual(x, y), nil
+	case "in":
+		switch y := y.(type) {
+		case []any:
+			for _, e := range y {
+				if reflect.DeepEqual(x, e) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]any:
+			if k, ok := x.(string); ok {
+				_, found := y[k]
+				return found, nil
+			}
+		}
+	}
+
+	switch x := x.(type) {
+	case float64:
+		if y, ok := y.(float64); ok {
+			return arith(n.op, x, y)
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			switch n.op {
+			case "+":
+				return x + y, nil
+			case "<":
+				return x < y, nil
+			case "<=":
+				return x <= y, nil
+			case ">":
+				return x > y, nil
+			case ">=":
+				return x >= y, nil
+			}
+		}
+	case []any:
+		if y, ok := y.([]any); ok && n.op == "+" {
+			return append(append([]any{}, x...), y...), nil
+		}
+	}
+	return nil, fmt.Errorf("no such operator %s %s %s", typeName(x), n.op, typeName(y))
+}
+
+// arith applies the arithmetic or comparison operator op to numbers.
+func arith(op string, x, y float64) (any, error) {
+	switch op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/", "%":
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "%" {
+			return math.Mod(x, y), nil
+		}
+		return x / y, nil
+	case "<":
+		return x < y, nil
+	case "<=":
+		return x <= y, nil
+	case ">":
+		return x > y, nil
+	case ">=":
+		return x >= y, nil
+	}
+	return nil, fmt.Errorf("no such operator number %s number", op)
+}
+
+func (n *cond) eval(s *scope) (any, error) {
+	c, err := n.c.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := c.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition is %s, not bool", typeName(c))
+	}
+	if b {
+		return n.t.eval(s)
+	}
+	return n.f.eval(s)
+}
+
+func (n *member) eval(s *scope) (any, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := x.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("no field %s on %s", n.name, typeName(x))
+	}
+	return m[n.name], nil
+}
+
+func (n *index) eval(s *scope) (any, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	i, err := n.i.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case []any:
+		if f, ok := i.(float64); ok && f == math.Trunc(f) {
+			if f < 0 || int(f) >= len(x) {
+				return nil, fmt.Errorf("index %v out of range [0, %d)", f, len(x))
+			}
+			return x[int(f)], nil
+		}
+	case map[string]any:
+		if k, ok := i.(string); ok {
+			return x[k], nil
+		}
+	}
+	return nil, fmt.Errorf("cannot index %s with %s", typeName(x), typeName(i))
+}
+
+func (n *call) eval(s *scope) (any, error) {
+	if n.name == "has" {
+		m := n.args[0].(*member)
+		x, err := m.x.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := x.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("has: no field %s on %s", m.name, typeName(x))
+		}
+		_, found := obj[m.name]
+		return found, nil
+	}
+
+	var recv any
+	if n.recv != nil {
+		var err error
+		if recv, err = n.recv.eval(s); err != nil {
+			return nil, err
+		}
+	}
+	switch n.name {
+	case "all", "exists", "exists_one", "filter", "map":
+		return n.macro(s, recv)
+	}
+
+	args := make([]any, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	if n.name == "size" {
+		if n.recv == nil {
+			recv = args[0]
+		}
+		switch v := recv.(type) {
+		case nil:
+			return 0.0, nil
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("no size of %s", typeName(recv))
+	}
+
+	str, ok1 := recv.(string)
+	arg, ok2 := args[0].(string)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("no such method %s.%s(%s)", typeName(recv), n.name, typeName(args[0]))
+	}
+	switch n.name {
+	case "startsWith":
+		return strings.HasPrefix(str, arg), nil
+	case "endsWith":
+		return strings.HasSuffix(str, arg), nil
+	case "contains":
+		return strings.Contains(str, arg), nil
+	default: // matches
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("matches: %v", err)
+		}
+		return re.MatchString(str), nil
+	}
+}
+
+// macro evaluates the second argument for each element of the list recv
+// bound to the variable named by the first. Null is an empty list.
+func (n *call) macro(s *scope, recv any) (any, error) {
+	var elems []any
+	switch v := recv.(type) {
+	case nil:
+	case []any:
+		elems = v
+	case map[string]any:
+		for k := range v {
+			elems = append(elems, k)
+		}
+	default:
+		return nil, fmt.Errorf("%s over %s", n.name, typeName(recv))
+	}
+
+	name := n.args[0].(*ident).name
+	matched := 0
+	out := []any{}
+	for _, e := range elems {
+		v, err := n.args[1].eval(&scope{name: name, value: e, parent: s})
+		if err != nil {
+			return nil, err
+		}
+		if n.name == "map" {
+			out = append(out, v)
+			continue
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s predicate is %s, not bool", n.name, typeName(v))
+		}
+		if b {
+			matched++
+			if n.name == "filter" {
+				out = append(out, e)
+			}
+		}
+	}
+	switch n.name {
+	case "all":
+		return matched == len(elems), nil
+	case "exists":
+		return matched > 0, nil
+	case "exists_one":
+		return matched == 1, nil
+	}
+	return out, nil
+}
+
+// typeName names the type of a value in error messages.
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
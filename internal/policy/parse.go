@@ -0,0 +1,392 @@
+// Package policy evaluates policy expressions against the JSON form of a
+// report. Expressions use a subset of CEL: literals, lists, field access and
+// indexing, arithmetic, comparisons, in, &&, ||, !, ?: and the functions
+// size, has, startsWith, endsWith, contains, matches and the all, exists,
+// exists_one, filter and map macros.
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string // the unquoted value of strings
+	pos  int
+}
+
+// Program is a parsed policy expression.
+type Program struct {
+	src  string
+	root node
+}
+
+// String returns the source of the expression.
+func (p *Program) String() string { return p.src }
+
+// Parse parses a policy expression.
+func Parse(src string) (*Program, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+	}
+	return &Program{src: src, root: root}, nil
+}
+
+// lex splits src into tokens, ending with tokEOF.
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c):
+			j := i
+			for j < len(src) && isDigit(src[j]) {
+				j++
+			}
+			if j+1 < len(src) && src[j] == '.' && isDigit(src[j+1]) {
+				for j++; j < len(src) && isDigit(src[j]); j++ {
+				}
+			}
+			toks = append(toks, token{kind: tokNumber, text: src[i:j], pos: i})
+			i = j
+		case c == '"' || c == '\'':
+			s, n, err := unquote(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%v at offset %d", err, i)
+			}
+			toks = append(toks, token{kind: tokString, text: s, pos: i})
+			i += n
+		case isLetter(c):
+			j := i
+			for j < len(src) && (isLetter(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			kind := tokIdent
+			if src[i:j] == "in" {
+				kind = tokPunct
+			}
+			toks = append(toks, token{kind: kind, text: src[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+				}
+			}
+			if op == "" && strings.IndexByte("()[].,?:!<>+-*/%", c) >= 0 {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			toks = append(toks, token{kind: tokPunct, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, text: "end of expression", pos: len(src)}), nil
+}
+
+// unquote decodes the string literal at the start of s and returns it with
+// the length of the literal.
+func unquote(s string) (string, int, error) {
+	quote := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case quote:
+			return sb.String(), i + 1, nil
+		case '\\':
+			i++
+			if i == len(s) {
+				break
+			}
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case '\\', '"', '\'':
+				sb.WriteByte(s[i])
+			default:
+				return "", 0, fmt.Errorf("unknown escape sequence \\%c", s[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func isDigit(c byte) bool  { return '0' <= c && c <= '9' }
+func isLetter(c byte) bool { return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+// accept consumes the next token if it is the punctuation op.
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokPunct && t.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q, got %q at offset %d", op, t.text, t.pos)
+	}
+	return nil
+}
+
+// expr parses a conditional expression, the lowest precedence level.
+func (p *parser) expr() (node, error) {
+	c, err := p.binary(0)
+	if err != nil || !p.accept("?") {
+		return c, err
+	}
+	t, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	f, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &cond{c: c, t: t, f: f}, nil
+}
+
+// precedence lists the binary operators from the lowest precedence level.
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// binary parses the left-associative binary operators of level and above.
+func (p *parser) binary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokPunct || !contains(precedence[level], t.text) {
+			return x, nil
+		}
+		p.i++
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binary{op: t.text, x: x, y: y}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &unary{op: op, x: x}, nil
+		}
+	}
+	return p.postfix()
+}
+
+// postfix parses field accesses, method calls and indexing.
+func (p *parser) postfix() (node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.peek()
+			if t.kind != tokIdent {
+				return nil, fmt.Errorf("expected a field name, got %q at offset %d", t.text, t.pos)
+			}
+			p.i++
+			if !p.accept("(") {
+				x = &member{x: x, name: t.text}
+				continue
+			}
+			args, err := p.list(")")
+			if err != nil {
+				return nil, err
+			}
+			if x, err = newCall(x, t, args); err != nil {
+				return nil, err
+			}
+		case p.accept("["):
+			i, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &index{x: x, i: i}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	p.i++
+	switch t.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &literal{v: v}, nil
+	case tokString:
+		return &literal{v: t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return &literal{v: t.text == "true"}, nil
+		case "null":
+			return &literal{}, nil
+		}
+		if !p.accept("(") {
+			return &ident{name: t.text}, nil
+		}
+		args, err := p.list(")")
+		if err != nil {
+			return nil, err
+		}
+		return newCall(nil, t, args)
+	case tokPunct:
+		switch t.text {
+		case "(":
+			x, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			elems, err := p.list("]")
+			if err != nil {
+				return nil, err
+			}
+			return &list{elems: elems}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+// list parses comma-separated expressions up to the closing punctuation end.
+func (p *parser) list(end string) ([]node, error) {
+	var elems []node
+	if p.accept(end) {
+		return elems, nil
+	}
+	for {
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, x)
+		if p.accept(end) {
+			return elems, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newCall checks the arguments of a call of the function or method (recv
+// not nil) named by t.
+func newCall(recv node, t token, args []node) (node, error) {
+	c := &call{recv: recv, name: t.text, args: args}
+	var want int
+	switch t.text {
+	case "size":
+		want = 1
+		if recv != nil {
+			want = 0
+		}
+	case "has":
+		if _, ok := firstArg(args).(*member); recv == nil && len(args) == 1 && ok {
+			return c, nil
+		}
+		return nil, fmt.Errorf("has at offset %d takes a single field access, e.g. has(r.owners)", t.pos)
+	case "startsWith", "endsWith", "contains", "matches":
+		if recv == nil {
+			return nil, fmt.Errorf("%s at offset %d is a method, e.g. path.%s(\"x\")", t.text, t.pos, t.text)
+		}
+		want = 1
+	case "all", "exists", "exists_one", "filter", "map":
+		if _, ok := firstArg(args).(*ident); recv != nil && len(args) == 2 && ok {
+			return c, nil
+		}
+		return nil, fmt.Errorf("%s at offset %d takes a variable name and an expression, e.g. files.%s(f, f.coverage > 80)", t.text, t.pos, t.text)
+	default:
+		return nil, fmt.Errorf("unknown function %s at offset %d", t.text, t.pos)
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("wrong number of arguments to %s at offset %d", t.text, t.pos)
+	}
+	return c, nil
+}
+
+func firstArg(args []node) node {
+	if len(args) == 0 {
+		return nil
+	}
+	return args[0]
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// report is the JSON form of a small report.
+func report(t *testing.T) map[string]any {
+	t.Helper()
+	vars, err := Vars(&struct {
+		Coverage        float64          `json:"coverage"`
+		ProjectCoverage float64          `json:"projectCoverage"`
+		Passed          bool             `json:"passed"`
+		Files           []map[string]any `json:"files"`
+		Owners          []map[string]any `json:"owners,omitempty"`
+	}{
+		Coverage:        75.5,
+		ProjectCoverage: 81,
+		Passed:          true,
+		Files: []map[string]any{
+			{"path": "internal/billing/invoice.go", "totalLines": 4, "coveredLines": 4, "uncovered": [][2]int{}},
+			{"path": "internal/api/handler.go", "totalLines": 8, "coveredLines": 5, "uncovered": [][2]int{{3, 5}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vars
+}
+
+// TestProgram_Eval covers the operators, functions and macros.
+func TestProgram_Eval(t *testing.T) {
+	cases := map[string]any{
+		`coverage >= 75 && projectCoverage - coverage < 10`:                                      true,
+		`!passed || coverage > 80`:                                                               false,
+		`files.filter(f, f.path.startsWith("internal/billing/")).all(f, size(f.uncovered) == 0)`: true,
+		`files.exists(f, f.coveredLines < f.totalLines)`:                                         true,
+		`files.exists_one(f, f.path.matches("^internal/(api|billing)/"))`:                        false,
+		`files.map(f, f.totalLines - f.coveredLines)`:                                            []any{0.0, 3.0},
+		`files[1].uncovered[0][1]`:                                                               5.0,
+		`size(files) * 2 + 1`:                                                                    5.0,
+		`"billing" in ["api", 'billing'] ? "yes" : "no"`:                                         "yes",
+		`files[0].path.endsWith(".go") && "handler" + ".go" == "handler.go"`:                     true,
+		`owners.all(o, o.passed) && size(owners) == 0`:                                           true,
+		`has(files[0].path) && !has(files[0].owner)`:                                             true,
+		`-coverage % 10`: -5.5,
+	}
+	vars := report(t)
+	for src, want := range cases {
+		p, err := Parse(src)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", src, err)
+			continue
+		}
+		got, err := p.Eval(vars)
+		if err != nil {
+			t.Errorf("Eval(%s): %v", src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Eval(%s) = %#v, want %#v", src, got, want)
+		}
+	}
+}
+
+// TestParse_Errors reports syntax errors with their offset.
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]string{
+		`coverage >`:           "unexpected \"end of expression\" at offset 10",
+		`files.all(f.passed)`:  "all at offset 6 takes a variable name",
+		`startsWith(path, "")`: "is a method",
+		`size(a, b)`:           "wrong number of arguments to size",
+		`"open`:                "unterminated string",
+		`coverage # 1`:         "unexpected character '#' at offset 9",
+		`len(files)`:           "unknown function len",
+	}
+	for src, want := range cases {
+		if _, err := Parse(src); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%s) error = %v, want %q", src, err, want)
+		}
+	}
+}
+
+// TestProgram_Check requires a bool and reports type errors.
+func TestProgram_Check(t *testing.T) {
+	vars := report(t)
+	cases := map[string]string{
+		`coverage`:           "expression is number, not bool",
+		`coverage > "80"`:    "no such operator number > string",
+		`missing > 1`:        "undeclared reference to missing",
+		`coverage / 0 == 1`:  "division by zero",
+		`coverage && passed`: "no such operator number &&",
+	}
+	for src, want := range cases {
+		p, err := Parse(src)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", src, err)
+		}
+		if _, err := p.Check(vars); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Check(%s) error = %v, want %q", src, err, want)
+		}
+	}
+}
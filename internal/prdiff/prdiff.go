@@ -0,0 +1,170 @@
+// Package prdiff fetches the diff of a pull/merge request from the code host,
+// for checkouts that lack the history to compute it with git, and its labels.
+package prdiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// GitHub returns the diff of pull request pr of repo (owner/name).
+func GitHub(ctx context.Context, client *http.Client, apiURL, repo, pr, token string) ([]byte, error) {
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	u := fmt.Sprintf("%s/repos/%s/pulls/%s", strings.TrimSuffix(apiURL, "/"), repo, pr)
+	return get(ctx, client, u, http.Header{
+		"Authorization":        {"Bearer " + token},
+		"Accept":               {"application/vnd.github.diff"},
+		"X-Github-Api-Version": {"2022-11-28"},
+	})
+}
+
+type gitlabDiff struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	NewFile     bool   `json:"new_file"`
+	DeletedFile bool   `json:"deleted_file"`
+	Diff        string `json:"diff"`
+}
+
+// GitLab returns the diff of merge request mr of project (numeric ID or
+// group/project), assembled from its per-file diffs.
+func GitLab(ctx context.Context, client *http.Client, apiURL, project, mr, token string) ([]byte, error) {
+	if apiURL == "" {
+		apiURL = "https://gitlab.com/api/v4"
+	}
+	header := http.Header{"Private-Token": {token}}
+	const perPage = 100
+	var buf bytes.Buffer
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/projects/%s/merge_requests/%s/diffs?per_page=%d&page=%d", strings.TrimSuffix(apiURL, "/"), url.PathEscape(project), mr, perPage, page)
+		data, err := get(ctx, client, u, header)
+		if err != nil {
+			return nil, err
+		}
+		var diffs []gitlabDiff
+		if err := json.Unmarshal(data, &diffs); err != nil {
+			return nil, fmt.Errorf("error decoding %s: %v", u, err)
+		}
+		for _, d := range diffs {
+			oldPath, newPath := "a/"+d.OldPath, "b/"+d.NewPath
+			if d.NewFile {
+				oldPath = "/dev/null"
+			}
+			if d.DeletedFile {
+				newPath = "/dev/null"
+			}
+			fmt.Fprintf(&buf, "diff --git a/%s b/%s\n--- %s\n+++ %s\n%s", d.OldPath, d.NewPath, oldPath, newPath, d.Diff)
+			if d.Diff != "" && !strings.HasSuffix(d.Diff, "\n") {
+				buf.WriteByte('\n')
+			}
+		}
+		if len(diffs) < perPage {
+			return buf.Bytes(), nil
+		}
+	}
+}
+
+// GitHubLabels returns the labels of pull request pr of repo (owner/name).
+func GitHubLabels(ctx context.Context, client *http.Client, apiURL, repo, pr, token string) ([]string, error) {
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	// Labels are those of the pull request's issue; 100 is the most per page
+	// and more than any pull request carries.
+	u := fmt.Sprintf("%s/repos/%s/issues/%s/labels?per_page=100", strings.TrimSuffix(apiURL, "/"), repo, pr)
+	data, err := get(ctx, client, u, http.Header{
+		"Authorization":        {"Bearer " + token},
+		"Accept":               {"application/vnd.github+json"},
+		"X-Github-Api-Version": {"2022-11-28"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var labels []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %v", u, err)
+	}
+	names := make([]string, len(labels))
+	for i, l := range labels {
+		names[i] = l.Name
+	}
+	return names, nil
+}
+
+// GitLabLabels returns the labels of merge request mr of project (numeric ID
+// or group/project).
+func GitLabLabels(ctx context.Context, client *http.Client, apiURL, project, mr, token string) ([]string, error) {
+	if apiURL == "" {
+		apiURL = "https://gitlab.com/api/v4"
+	}
+	u := fmt.Sprintf("%s/projects/%s/merge_requests/%s", strings.TrimSuffix(apiURL, "/"), url.PathEscape(project), mr)
+	data, err := get(ctx, client, u, http.Header{"Private-Token": {token}})
+	if err != nil {
+		return nil, err
+	}
+	var m struct {
+		Labels []string `json:"labels"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %v", u, err)
+	}
+	return m.Labels, nil
+}
+
+// Relative keeps the files of diff below the directory prefix (e.g.
+// "services/api/"), with paths relative to it, like git diff --relative.
+func Relative(diff []byte, prefix string) []byte {
+	if prefix == "" {
+		return diff
+	}
+	var out bytes.Buffer
+	keep := false
+	for _, line := range strings.SplitAfter(string(diff), "\n") {
+		if rest, ok := strings.CutPrefix(line, "diff --git a/"); ok {
+			oldPath, newPath, _ := strings.Cut(strings.TrimSuffix(rest, "\n"), " b/")
+			keep = strings.HasPrefix(newPath, prefix) || strings.HasPrefix(oldPath, prefix)
+			line = "diff --git a/" + strings.TrimPrefix(oldPath, prefix) + " b/" + strings.TrimPrefix(newPath, prefix) + "\n"
+		} else if keep && (strings.HasPrefix(line, "--- a/") || strings.HasPrefix(line, "+++ b/")) {
+			line = line[:6] + strings.TrimPrefix(line[6:], prefix)
+		}
+		if keep {
+			out.WriteString(line)
+		}
+	}
+	return out.Bytes()
+}
+
+func get(ctx context.Context, client *http.Client, u string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET %s: unexpected status %s: %s", u, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(resp.Body)
+}
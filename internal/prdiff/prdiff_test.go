@@ -0,0 +1,78 @@
+package prdiff
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestGitHub requests the diff media type of the pull request.
+func TestGitHub(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/octo/repo/pulls/7" || r.Header.Get("Accept") != "application/vnd.github.diff" || r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("Unexpected request %s %v", r.URL, r.Header)
+		}
+		w.Write([]byte("diff --git a/a.go b/a.go\n"))
+	}))
+	defer srv.Close()
+
+	diff, err := GitHub(context.Background(), srv.Client(), srv.URL, "octo/repo", "7", "tok")
+	if err != nil || string(diff) != "diff --git a/a.go b/a.go\n" {
+		t.Errorf("GitHub = %q, %v", diff, err)
+	}
+}
+
+// TestGitLab assembles the per-file diffs into a unified diff.
+func TestGitLab(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/group/project/merge_requests/3/diffs" || r.Header.Get("Private-Token") != "pat" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`[{"old_path": "a.go", "new_path": "a.go", "diff": "@@ -1 +1 @@\n-x\n+y\n"},
+			{"old_path": "b.go", "new_path": "b.go", "new_file": true, "diff": "@@ -0,0 +1 @@\n+z"}]`))
+	}))
+	defer srv.Close()
+
+	diff, err := GitLab(context.Background(), srv.Client(), srv.URL, "group/project", "3", "pat")
+	want := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n" +
+		"diff --git a/b.go b/b.go\n--- /dev/null\n+++ b/b.go\n@@ -0,0 +1 @@\n+z\n"
+	if err != nil || string(diff) != want {
+		t.Errorf("GitLab = %q, %v", diff, err)
+	}
+}
+
+// TestLabels reads the label names of a GitHub pull request and a GitLab merge request.
+func TestLabels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/octo/repo/issues/7/labels":
+			w.Write([]byte(`[{"name": "strict-coverage"}, {"name": "bug"}]`))
+		case "/projects/group/project/merge_requests/3":
+			w.Write([]byte(`{"iid": 3, "labels": ["skip-diff-coverage"]}`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	labels, err := GitHubLabels(context.Background(), srv.Client(), srv.URL, "octo/repo", "7", "tok")
+	if err != nil || !reflect.DeepEqual(labels, []string{"strict-coverage", "bug"}) {
+		t.Errorf("GitHubLabels = %q, %v", labels, err)
+	}
+	labels, err = GitLabLabels(context.Background(), srv.Client(), srv.URL, "group/project", "3", "pat")
+	if err != nil || !reflect.DeepEqual(labels, []string{"skip-diff-coverage"}) {
+		t.Errorf("GitLabLabels = %q, %v", labels, err)
+	}
+}
+
+// TestRelative keeps the files below the prefix, relative to it.
+func TestRelative(t *testing.T) {
+	diff := "diff --git a/api/a.go b/api/a.go\n--- a/api/a.go\n+++ b/api/a.go\n@@ -1 +1 @@\n-x\n+y\n" +
+		"diff --git a/web/b.go b/web/b.go\n--- /dev/null\n+++ b/web/b.go\n@@ -0,0 +1 @@\n+z\n"
+	want := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n"
+	if got := string(Relative([]byte(diff), "api/")); got != want {
+		t.Errorf("Relative = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+// Package profilecache keeps coverage profiles keyed by commit SHA, in a local
+// directory and optionally in object storage, so the profile of a base commit
+// is computed once and reused by every pull request built on top of it.
+package profilecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/storage"
+)
+
+// ErrMiss is returned by Get when no cache has the commit's profile.
+var ErrMiss = errors.New("no cached coverage profile")
+
+// ObjectStore is the subset of storage.Bucket used by the remote cache.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Cache stores profiles in Dir, if set, and in Remote under Repo, if set.
+type Cache struct {
+	Dir    string
+	Remote ObjectStore
+	Repo   string
+}
+
+// DefaultDir returns the local cache directory of coverage profiles.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-new-code-coverage", "profiles")
+}
+
+// Key returns the object key of a commit's profile in the remote cache.
+func Key(repo, commit string) string {
+	return strings.Join([]string{repo, "profiles", commit, "cover.out"}, "/")
+}
+
+// Get returns the profile of commit, trying the local directory first. A
+// profile found only remotely is copied to the local directory.
+func (c *Cache) Get(ctx context.Context, commit string) ([]byte, error) {
+	if err := validCommit(commit); err != nil {
+		return nil, err
+	}
+	if c.Dir != "" {
+		data, err := os.ReadFile(c.path(commit))
+		if err == nil {
+			return data, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if c.Remote == nil {
+		return nil, ErrMiss
+	}
+	data, err := c.Remote.Get(ctx, Key(c.Repo, commit))
+	if storage.IsNotFound(err) {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the profile of %s: %v", commit, err)
+	}
+	if c.Dir != "" {
+		if err := c.writeLocal(commit, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// Put stores the profile of commit in the local directory and remotely.
+func (c *Cache) Put(ctx context.Context, commit string, data []byte) error {
+	if err := validCommit(commit); err != nil {
+		return err
+	}
+	if c.Dir != "" {
+		if err := c.writeLocal(commit, data); err != nil {
+			return err
+		}
+	}
+	if c.Remote != nil {
+		if err := c.Remote.Put(ctx, Key(c.Repo, commit), data, "text/plain"); err != nil {
+			return fmt.Errorf("error uploading the profile of %s: %v", commit, err)
+		}
+	}
+	return nil
+}
+
+func (c *Cache) path(commit string) string {
+	return filepath.Join(c.Dir, commit+".out")
+}
+
+// writeLocal writes atomically, so concurrent jobs never read a partial profile.
+func (c *Cache) writeLocal(commit string, data []byte) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("error creating cache directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(c.Dir, commit+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+	return os.Rename(tmp.Name(), c.path(commit))
+}
+
+// validCommit accepts full hexadecimal SHAs only: abbreviations and ref names
+// could alias different commits over time.
+func validCommit(commit string) error {
+	if len(commit) != 40 && len(commit) != 64 {
+		return fmt.Errorf("%q is not a full commit SHA", commit)
+	}
+	for _, r := range commit {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return fmt.Errorf("%q is not a full commit SHA", commit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package profilecache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/storage"
+)
+
+type fakeStore map[string]string
+
+func (s fakeStore) Put(_ context.Context, key string, data []byte, _ string) error {
+	s[key] = string(data)
+	return nil
+}
+
+func (s fakeStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := s[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return []byte(data), nil
+}
+
+const sha = "0123456789abcdef0123456789abcdef01234567"
+
+// TestCache_PutGet round-trips a profile and fills the local cache from the remote one.
+func TestCache_PutGet(t *testing.T) {
+	ctx := context.Background()
+	remote := fakeStore{}
+	c := &Cache{Dir: t.TempDir(), Remote: remote, Repo: "octo/repo"}
+	if _, err := c.Get(ctx, sha); !errors.Is(err, ErrMiss) {
+		t.Fatalf("Expected ErrMiss, got %v", err)
+	}
+	if err := c.Put(ctx, sha, []byte("mode: set\n")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if remote["octo/repo/profiles/"+sha+"/cover.out"] != "mode: set\n" {
+		t.Errorf("Remote cache = %v", remote)
+	}
+
+	other := &Cache{Dir: t.TempDir(), Remote: remote, Repo: "octo/repo"}
+	data, err := other.Get(ctx, sha)
+	if err != nil || string(data) != "mode: set\n" {
+		t.Fatalf("Get = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(other.Dir, sha+".out")); err != nil {
+		t.Errorf("Remote hit was not cached locally: %v", err)
+	}
+}
+
+// TestCache_InvalidCommit rejects abbreviated SHAs and ref names.
+func TestCache_InvalidCommit(t *testing.T) {
+	c := &Cache{Dir: t.TempDir()}
+	for _, commit := range []string{"0123456", "main", "../" + sha[3:]} {
+		if _, err := c.Get(context.Background(), commit); err == nil || !strings.Contains(err.Error(), "not a full commit SHA") {
+			t.Errorf("Get(%q) error = %v", commit, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/attest"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/storage"
+)
+
+// ArchiveReportName is the object name of the JSON report.
+const ArchiveReportName = "report.json"
+
+// ObjectStore is the subset of storage.Bucket used by Archive.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+}
+
+// Archive stores the JSON report under repo/branch/commit and as the
+// branch's latest report, so later runs can fetch it as a baseline. With a
+// Key, the detached signature of the report is uploaded next to it.
+type Archive struct {
+	Store  ObjectStore
+	Repo   string
+	Branch string
+	Commit string
+	Key    *attest.Key
+}
+
+// Publish uploads the report.
+func (a *Archive) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if a.Repo == "" || a.Branch == "" || a.Commit == "" {
+		return fmt.Errorf("archive: repository, branch and commit are required")
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("archive: %v", err)
+	}
+	if err := a.put(ctx, ArchiveReportName, data, "application/json"); err != nil {
+		return err
+	}
+	if a.Key == nil {
+		return nil
+	}
+	sig, err := a.Key.Sign(data)
+	if err != nil {
+		return fmt.Errorf("archive: error signing: %v", err)
+	}
+	return a.put(ctx, ArchiveReportName+attest.SignatureSuffix, []byte(sig+"\n"), "text/plain")
+}
+
+// put uploads the named object under the commit and latest keys.
+func (a *Archive) put(ctx context.Context, name string, data []byte, contentType string) error {
+	for _, key := range []string{
+		storage.Key(a.Repo, a.Branch, a.Commit, name),
+		storage.LatestKey(a.Repo, a.Branch, name),
+	} {
+		if err := a.Store.Put(ctx, key, data, contentType); err != nil {
+			return fmt.Errorf("archive: %v", err)
+		}
+	}
+	return nil
+}
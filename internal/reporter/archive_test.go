@@ -0,0 +1,60 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/attest"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// memoryStore is an in-memory ObjectStore.
+type memoryStore map[string][]byte
+
+func (m memoryStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if key == "fail/main/abc/report.json" {
+		return fmt.Errorf("denied")
+	}
+	m[key] = data
+	return nil
+}
+
+// TestArchive_Publish checks the commit and latest keys.
+func TestArchive_Publish(t *testing.T) {
+	store := memoryStore{}
+	a := &Archive{Store: store, Repo: "octo/repo", Branch: "feature/x", Commit: "abc"}
+	if err := a.Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	for _, key := range []string{"octo/repo/feature/x/abc/report.json", "octo/repo/feature/x/latest/report.json"} {
+		var r diffcoverage.Report
+		if err := json.Unmarshal(store[key], &r); err != nil || r.Coverage != 50 {
+			t.Errorf("%s: unexpected report %+v, %v", key, r, err)
+		}
+	}
+
+	if err := (&Archive{Store: store, Repo: "octo/repo"}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error without branch and commit, got nil")
+	}
+	if err := (&Archive{Store: store, Repo: "fail", Branch: "main", Commit: "abc"}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected upload error, got nil")
+	}
+}
+
+// TestArchive_PublishSigned uploads a signature verifying the uploaded report.
+func TestArchive_PublishSigned(t *testing.T) {
+	store := memoryStore{}
+	key := attest.Secret("s3cret")
+	a := &Archive{Store: store, Repo: "octo/repo", Branch: "main", Commit: "abc", Key: key}
+	if err := a.Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	for _, dir := range []string{"abc", "latest"} {
+		report, sig := store["octo/repo/main/"+dir+"/report.json"], store["octo/repo/main/"+dir+"/report.json.sig"]
+		if err := key.Verify(report, string(sig)); err != nil {
+			t.Errorf("%s: signature does not verify: %v", dir, err)
+		}
+	}
+}
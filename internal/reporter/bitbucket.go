@@ -0,0 +1,163 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// BitbucketReportID identifies the Code Insights report on a commit.
+const BitbucketReportID = "diffcoverage"
+
+const (
+	// bitbucketAnnotationBatch is the most annotations accepted per request.
+	bitbucketAnnotationBatch = 100
+	// bitbucketMaxAnnotations is the most annotations a report may hold.
+	bitbucketMaxAnnotations = 1000
+)
+
+// Bitbucket publishes the report as a Bitbucket Cloud Code Insights report on
+// the commit, with one annotation per uncovered line range.
+type Bitbucket struct {
+	APIURL string // e.g. https://api.bitbucket.org/2.0
+	Repo   string // workspace/repo_slug
+	Commit string
+	// Token is a repository or workspace access token. Username and
+	// Password (an app password) are used instead when set.
+	Token     string
+	Username  string
+	Password  string
+	ReportURL string             // linked from the report, if set
+	Template  *template.Template // renders the report details, if set
+	MaxBytes  int                // caps the size of the details (default: BitbucketDetailsLimit)
+	Client    *http.Client
+}
+
+type bitbucketReport struct {
+	Title      string          `json:"title"`
+	Details    string          `json:"details"`
+	ReportType string          `json:"report_type"`
+	Reporter   string          `json:"reporter"`
+	Result     string          `json:"result"`
+	Link       string          `json:"link,omitempty"`
+	Data       []bitbucketData `json:"data"`
+}
+
+type bitbucketData struct {
+	Title string `json:"title"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+type bitbucketAnnotation struct {
+	ExternalID     string `json:"external_id"`
+	AnnotationType string `json:"annotation_type"`
+	Summary        string `json:"summary"`
+	Path           string `json:"path"`
+	Line           int    `json:"line"`
+	Severity       string `json:"severity"`
+}
+
+// Publish replaces the commit's report and uploads its annotations.
+func (b *Bitbucket) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if b.Repo == "" || b.Commit == "" || (b.Token == "" && b.Username == "") {
+		return fmt.Errorf("bitbucket insights: repository, commit and credentials are required")
+	}
+
+	// Deleting first also drops the annotations of a previous run.
+	if err := doJSON(ctx, b.Client, http.MethodDelete, b.reportURL(), b.header(), nil, nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("bitbucket insights: %v", err)
+	}
+	report := bitbucketReportFor(r, b.ReportURL)
+	if b.Template != nil {
+		details, err := fitDetails(r, b.ReportURL, b.Template, limit(b.MaxBytes, BitbucketDetailsLimit))
+		if err != nil {
+			return fmt.Errorf("bitbucket insights: %v", err)
+		}
+		report.Details = details
+	}
+	if err := doJSON(ctx, b.Client, http.MethodPut, b.reportURL(), b.header(), report, nil); err != nil {
+		return fmt.Errorf("bitbucket insights: %v", err)
+	}
+
+	annotations := bitbucketAnnotations(r)
+	for start := 0; start < len(annotations); start += bitbucketAnnotationBatch {
+		end := start + bitbucketAnnotationBatch
+		if end > len(annotations) {
+			end = len(annotations)
+		}
+		if err := doJSON(ctx, b.Client, http.MethodPost, b.reportURL()+"/annotations", b.header(), annotations[start:end], nil); err != nil {
+			return fmt.Errorf("bitbucket insights: %v", err)
+		}
+	}
+	return nil
+}
+
+// bitbucketReportFor builds the report summary.
+func bitbucketReportFor(r *diffcoverage.Report, link string) bitbucketReport {
+	result := "PASSED"
+	if !r.Passed {
+		result = "FAILED"
+	}
+	return bitbucketReport{
+		Title:      "Diff coverage",
+		Details:    fmt.Sprintf("%d of %d new/changed lines in functions are covered (minimum %.2f%%%s).", r.CoveredLines, r.TotalLines, r.MinCoverage, gradeNote(r)),
+		ReportType: "COVERAGE",
+		Reporter:   "go-new-code-coverage " + r.ToolVersion,
+		Result:     result,
+		Link:       link,
+		Data: []bitbucketData{
+			{Title: "Coverage", Type: "PERCENTAGE", Value: r.Coverage},
+			{Title: "Minimum", Type: "PERCENTAGE", Value: r.MinCoverage},
+			{Title: "Uncovered lines", Type: "NUMBER", Value: r.TotalLines - r.CoveredLines},
+		},
+	}
+}
+
+// bitbucketAnnotations returns one annotation per uncovered range, up to the
+// per-report limit.
+func bitbucketAnnotations(r *diffcoverage.Report) []bitbucketAnnotation {
+	var annotations []bitbucketAnnotation
+	for _, f := range r.Files {
+		for _, rng := range f.Uncovered {
+			if len(annotations) == bitbucketMaxAnnotations {
+				return annotations
+			}
+			summary := fmt.Sprintf("Line %d is not covered by tests", rng[0])
+			if rng[0] != rng[1] {
+				summary = fmt.Sprintf("Lines %d-%d are not covered by tests", rng[0], rng[1])
+			}
+			annotations = append(annotations, bitbucketAnnotation{
+				ExternalID:     fmt.Sprintf("%s-%d", f.Path, rng[0]),
+				AnnotationType: "CODE_SMELL",
+				Summary:        summary,
+				Path:           f.Path,
+				Line:           rng[0],
+				Severity:       "MEDIUM",
+			})
+		}
+	}
+	return annotations
+}
+
+func (b *Bitbucket) reportURL() string {
+	apiURL := strings.TrimSuffix(b.APIURL, "/")
+	if apiURL == "" {
+		apiURL = "https://api.bitbucket.org/2.0"
+	}
+	return fmt.Sprintf("%s/repositories/%s/commit/%s/reports/%s", apiURL, b.Repo, b.Commit, BitbucketReportID)
+}
+
+func (b *Bitbucket) header() http.Header {
+	req := &http.Request{Header: http.Header{}}
+	if b.Username != "" {
+		req.SetBasicAuth(b.Username, b.Password)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+b.Token)
+	}
+	return req.Header
+}
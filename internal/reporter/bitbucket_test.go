@@ -0,0 +1,88 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestBitbucket_Publish checks the report is replaced and annotations are uploaded in batches.
+func TestBitbucket_Publish(t *testing.T) {
+	const reportPath = "/2.0/repositories/team/repo/commit/abc/reports/" + BitbucketReportID
+	var (
+		calls       []string
+		report      bitbucketReport
+		annotations []bitbucketAnnotation
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bot" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == reportPath:
+			http.NotFound(w, r)
+		case r.Method == http.MethodPut && r.URL.Path == reportPath:
+			json.NewDecoder(r.Body).Decode(&report)
+		case r.Method == http.MethodPost && r.URL.Path == reportPath+"/annotations":
+			var batch []bitbucketAnnotation
+			json.NewDecoder(r.Body).Decode(&batch)
+			if len(batch) > bitbucketAnnotationBatch {
+				http.Error(w, "too many annotations", http.StatusBadRequest)
+				return
+			}
+			annotations = append(annotations, batch...)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	r := sampleReport()
+	for i := 0; i < 150; i++ {
+		r.Files = append(r.Files, diffcoverage.FileReport{Path: fmt.Sprintf("pkg/f%d.go", i), Uncovered: [][2]int{{3, 3}}})
+	}
+
+	b := &Bitbucket{APIURL: srv.URL + "/2.0", Repo: "team/repo", Commit: "abc", Username: "bot", Password: "secret"}
+	if err := b.Publish(context.Background(), r); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	if report.Result != "FAILED" || report.ReportType != "COVERAGE" || report.Data[0].Value != 50.0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(annotations) != 152 {
+		t.Fatalf("Expected 152 annotations, got %d", len(annotations))
+	}
+	if a := annotations[0]; a.Path != "pkg/a.go" || a.Line != 6 || a.Summary != "Lines 6-7 are not covered by tests" {
+		t.Errorf("Unexpected first annotation: %+v", a)
+	}
+	want := []string{"DELETE " + reportPath, "PUT " + reportPath, "POST " + reportPath + "/annotations", "POST " + reportPath + "/annotations"}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected calls:\n%s", strings.Join(calls, "\n"))
+	}
+}
+
+// TestBitbucket_PublishErrors covers missing settings and API failures.
+func TestBitbucket_PublishErrors(t *testing.T) {
+	if err := (&Bitbucket{Repo: "team/repo"}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error for missing settings, got nil")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	b := &Bitbucket{APIURL: srv.URL, Repo: "team/repo", Commit: "abc", Token: "wrong"}
+	err := b.Publish(context.Background(), sampleReport())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// CircleCI writes the report for CircleCI to pick up: a JUnit test result
+// below Dir/test-results, for the store_test_results step, and a Markdown
+// summary in Dir/artifacts, for store_artifacts.
+type CircleCI struct {
+	Dir string
+}
+
+// Publish writes the files.
+func (c *CircleCI) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	var junit bytes.Buffer
+	if err := WriteJUnit(&junit, r); err != nil {
+		return fmt.Errorf("circleci: %v", err)
+	}
+	files := map[string][]byte{
+		filepath.Join(c.Dir, "test-results", "diffcoverage", "results.xml"): junit.Bytes(),
+		filepath.Join(c.Dir, "artifacts", "diffcoverage-summary.md"):        []byte(Markdown(r)),
+	}
+	for path, data := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("circleci: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("circleci: %v", err)
+		}
+	}
+	return nil
+}
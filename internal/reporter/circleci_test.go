@@ -0,0 +1,34 @@
+package reporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCircleCI_Publish writes the test result and the summary artifact.
+func TestCircleCI_Publish(t *testing.T) {
+	dir := t.TempDir()
+	if err := (&CircleCI{Dir: dir}).Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	junit, err := os.ReadFile(filepath.Join(dir, "test-results", "diffcoverage", "results.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<testsuite name="diffcoverage" tests="2" failures="2">`,
+		`<testcase name="pkg/a.go" classname="diffcoverage.files" file="pkg/a.go">`,
+		`<failure message="2 of 4 changed lines uncovered (50.00% covered)">Uncovered lines: 6-7, 9</failure>`,
+	} {
+		if !strings.Contains(string(junit), want) {
+			t.Errorf("results.xml lacks %q:\n%s", want, junit)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "artifacts", "diffcoverage-summary.md")); err != nil {
+		t.Errorf("summary: %v", err)
+	}
+}
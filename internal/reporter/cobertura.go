@@ -0,0 +1,122 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+const coberturaDoctype = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">` + "\n"
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        float64            `xml:"line-rate,attr"`
+	BranchRate      float64            `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      int                `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity int              `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   float64         `xml:"line-rate,attr"`
+	BranchRate float64         `xml:"branch-rate,attr"`
+	Complexity int             `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int    `xml:"number,attr"`
+	Hits   int    `xml:"hits,attr"`
+	Branch string `xml:"branch,attr"`
+}
+
+// WriteCobertura writes the report as Cobertura XML, which Jenkins, GitLab and
+// Azure DevOps display as coverage: a class per counted file, with its
+// new/changed lines only, in a package per directory. Covered lines have the
+// hits of a count profile, or 1.
+func WriteCobertura(w io.Writer, r *diffcoverage.Report) error {
+	cov := coberturaCoverage{
+		LineRate:     rate(r.CoveredLines, r.TotalLines),
+		LinesCovered: r.CoveredLines,
+		LinesValid:   r.TotalLines,
+		Version:      r.ToolVersion,
+		Timestamp:    time.Now().UnixMilli(),
+		Sources:      []string{"."},
+	}
+	if r.SourceRoot != "" {
+		cov.Sources[0] = r.SourceRoot
+	}
+	pkgs := r.Packages()
+	byDir := map[string]*coberturaPackage{}
+	for _, p := range pkgs {
+		byDir[p.Package] = &coberturaPackage{Name: p.Package, LineRate: rate(p.CoveredLines, p.TotalLines)}
+	}
+	for _, f := range r.Files {
+		if f.TotalLines == 0 {
+			continue
+		}
+		c := coberturaClass{Name: path.Base(f.Path), Filename: f.Path, LineRate: rate(f.CoveredLines, f.TotalLines)}
+		hits := map[int]int{}
+		for _, h := range f.Hits {
+			for line := h[0]; line <= h[1]; line++ {
+				hits[line] = h[2]
+			}
+		}
+		for _, rng := range f.Covered {
+			for line := rng[0]; line <= rng[1]; line++ {
+				c.Lines = append(c.Lines, coberturaLine{Number: line, Hits: max(hits[line], 1), Branch: "false"})
+			}
+		}
+		for _, line := range f.UncoveredLines() {
+			c.Lines = append(c.Lines, coberturaLine{Number: line, Branch: "false"})
+		}
+		sort.Slice(c.Lines, func(i, j int) bool { return c.Lines[i].Number < c.Lines[j].Number })
+		p := byDir[path.Dir(f.Path)]
+		p.Classes = append(p.Classes, c)
+	}
+	for _, p := range pkgs {
+		if pkg := byDir[p.Package]; len(pkg.Classes) > 0 {
+			cov.Packages = append(cov.Packages, *pkg)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header+coberturaDoctype); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(cov); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// rate returns covered/total as a fraction; no lines count as fully covered.
+func rate(covered, total int) float64 {
+	if total == 0 {
+		return 1
+	}
+	return float64(covered) / float64(total)
+}
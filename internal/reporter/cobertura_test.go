@@ -0,0 +1,39 @@
+package reporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestWriteCobertura lists the new/changed lines of each file with their hits.
+func TestWriteCobertura(t *testing.T) {
+	r := sampleReport()
+	r.Files[0].Covered = [][2]int{{4, 5}}
+	r.Files[0].Hits = [][3]int{{4, 4, 3}, {5, 5, 1}}
+	r.Files = append(r.Files, diffcoverage.FileReport{Path: "main.go", TotalLines: 1, CoveredLines: 1, Coverage: 100, Covered: [][2]int{{2, 2}}})
+	var buf bytes.Buffer
+	if err := WriteCobertura(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<coverage line-rate="0.5" branch-rate="0" lines-covered="2" lines-valid="4"`,
+		`<source>.</source>`,
+		`<package name="." line-rate="1"`,
+		`<package name="pkg" line-rate="0.5"`,
+		`<class name="a.go" filename="pkg/a.go" line-rate="0.5"`,
+		`<line number="4" hits="3" branch="false"></line>`,
+		`<line number="6" hits="0" branch="false"></line>`,
+		`<line number="2" hits="1" branch="false"></line>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, `number="5"`) > strings.Index(out, `number="6"`) {
+		t.Errorf("lines are not sorted:\n%s", out)
+	}
+}
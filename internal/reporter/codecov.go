@@ -0,0 +1,140 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// Codecov uploads the coverage profile the report was computed from to
+// Codecov, so a single pipeline step feeds both tools.
+type Codecov struct {
+	URL     string // e.g. https://codecov.io
+	Token   string // repository upload token; optional for public repositories on some CI systems
+	Service string // CI service name, e.g. github-actions
+	Slug    string // owner/name
+	Commit  string
+	Branch  string
+	PR      string
+	Build   string
+	Flags   string // Codecov flags attached to the upload
+	Client  *http.Client
+}
+
+// Publish uploads the report's coverage profile through the v4 upload API.
+func (c *Codecov) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if r.Profile == "" || c.Commit == "" {
+		return fmt.Errorf("codecov: a coverage profile and commit are required")
+	}
+
+	body, err := codecovUpload(r.Profile, r.Module)
+	if err != nil {
+		return fmt.Errorf("codecov: %v", err)
+	}
+
+	putURL, err := c.requestUpload(ctx)
+	if err != nil {
+		return fmt.Errorf("codecov: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, putURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("codecov: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	if _, err := c.do(req); err != nil {
+		return fmt.Errorf("codecov: uploading report: %v", err)
+	}
+	return nil
+}
+
+// requestUpload registers the upload and returns the storage URL to PUT it to.
+func (c *Codecov) requestUpload(ctx context.Context) (string, error) {
+	q := url.Values{}
+	q.Set("package", "go-new-code-coverage")
+	q.Set("commit", c.Commit)
+	for k, v := range map[string]string{"token": c.Token, "service": c.Service, "slug": c.Slug,
+		"branch": c.Branch, "pr": c.PR, "build": c.Build, "flags": c.Flags} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	base := strings.TrimSuffix(c.URL, "/")
+	if base == "" {
+		base = "https://codecov.io"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/upload/v4?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/plain")
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("requesting upload: %v", err)
+	}
+
+	// The response holds the result page URL and the storage URL on separate lines.
+	lines := strings.Split(strings.TrimSpace(string(resp)), "\n")
+	if len(lines) < 2 {
+		return "", fmt.Errorf("requesting upload: unexpected response %q", string(resp))
+	}
+	return strings.TrimSpace(lines[1]), nil
+}
+
+// do sends req and returns the response body, failing on non-2xx statuses.
+func (c *Codecov) do(req *http.Request) ([]byte, error) {
+	client := c.Client
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// codecovUpload wraps the profile in Codecov's upload format, rewriting import
+// paths to paths relative to the module root so Codecov can match them to files.
+func codecovUpload(profile, module string) ([]byte, error) {
+	f, err := os.Open(profile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	buf.WriteString("<<<<<< network\n# path=coverage.out\n")
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if module != "" {
+			line = strings.TrimPrefix(line, module+"/")
+		}
+		buf.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("<<<<<< EOF\n")
+	return buf.Bytes(), nil
+}
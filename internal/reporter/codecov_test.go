@@ -0,0 +1,73 @@
+package reporter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCodecov_Publish checks the two-step upload and the rewritten profile paths.
+func TestCodecov_Publish(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	if err := os.WriteFile(profile, []byte("mode: set\nexample.com/m/pkg/a.go:3.1,4.2 1 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var query, uploaded string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/v4":
+			query = r.URL.RawQuery
+			io.WriteString(w, "https://codecov.io/result\n"+srv.URL+"/storage?sig=1\n")
+		case r.Method == http.MethodPut && r.URL.Path == "/storage":
+			data, _ := io.ReadAll(r.Body)
+			uploaded = string(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	r := sampleReport()
+	r.Profile, r.Module = profile, "example.com/m"
+	c := &Codecov{URL: srv.URL, Token: "tok", Service: "github-actions", Slug: "octo/repo", Commit: "abc", PR: "5"}
+	if err := c.Publish(context.Background(), r); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	for _, want := range []string{"commit=abc", "token=tok", "slug=octo%2Frepo", "pr=5", "service=github-actions"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Expected %q in query %q", want, query)
+		}
+	}
+	want := "<<<<<< network\n# path=coverage.out\nmode: set\npkg/a.go:3.1,4.2 1 1\n<<<<<< EOF\n"
+	if uploaded != want {
+		t.Errorf("uploaded:\n%s\nwant:\n%s", uploaded, want)
+	}
+}
+
+// TestCodecov_PublishErrors covers a missing profile and a rejected upload.
+func TestCodecov_PublishErrors(t *testing.T) {
+	if err := (&Codecov{Commit: "abc"}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error without a profile, got nil")
+	}
+
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	os.WriteFile(profile, []byte("mode: set\n"), 0644)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid token", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	r := sampleReport()
+	r.Profile = profile
+	err := (&Codecov{URL: srv.URL, Commit: "abc"}).Publish(context.Background(), r)
+	if err == nil || !strings.Contains(err.Error(), "invalid token") {
+		t.Errorf("Expected upload error, got %v", err)
+	}
+}
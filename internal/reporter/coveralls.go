@@ -0,0 +1,186 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// Coveralls submits the line coverage of the changed files, taken from the
+// report's coverage profile, as a Coveralls job.
+type Coveralls struct {
+	URL         string // e.g. https://coveralls.io
+	RepoToken   string
+	ServiceName string // e.g. github
+	PR          string
+	Commit      string
+	Branch      string
+	Client      *http.Client
+}
+
+type coverallsJob struct {
+	RepoToken   string                `json:"repo_token"`
+	ServiceName string                `json:"service_name,omitempty"`
+	PullRequest string                `json:"service_pull_request,omitempty"`
+	Git         *coverallsGit         `json:"git,omitempty"`
+	SourceFiles []coverallsSourceFile `json:"source_files"`
+}
+
+type coverallsGit struct {
+	Head   map[string]string `json:"head"`
+	Branch string            `json:"branch,omitempty"`
+}
+
+type coverallsSourceFile struct {
+	Name         string `json:"name"`
+	SourceDigest string `json:"source_digest"`
+	// Coverage holds the hit count per line; nil marks lines without statements.
+	Coverage []*int `json:"coverage"`
+}
+
+// Publish submits the job.
+func (c *Coveralls) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if r.Profile == "" || c.RepoToken == "" {
+		return fmt.Errorf("coveralls: a coverage profile and repo token are required")
+	}
+
+	job, err := c.job(r)
+	if err != nil {
+		return fmt.Errorf("coveralls: %v", err)
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("coveralls: %v", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("json_file", "coverage.json")
+	if err != nil {
+		return fmt.Errorf("coveralls: %v", err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	base := strings.TrimSuffix(c.URL, "/")
+	if base == "" {
+		base = "https://coveralls.io"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/api/v1/jobs", &body)
+	if err != nil {
+		return fmt.Errorf("coveralls: %v", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	client := c.Client
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("coveralls: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("coveralls: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// job builds the Coveralls job for the files of the report.
+func (c *Coveralls) job(r *diffcoverage.Report) (*coverallsJob, error) {
+	hits, err := profileHits(r.Profile, r.Module)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &coverallsJob{RepoToken: c.RepoToken, ServiceName: c.ServiceName, PullRequest: c.PR, SourceFiles: []coverallsSourceFile{}}
+	if c.Commit != "" {
+		job.Git = &coverallsGit{Head: map[string]string{"id": c.Commit}, Branch: c.Branch}
+	}
+
+	for _, f := range r.Files {
+		src, err := os.ReadFile(filepath.Join(r.SourceRoot, f.Path))
+		if err != nil {
+			return nil, err
+		}
+		digest := md5.Sum(src)
+		lines := bytes.Count(src, []byte("\n"))
+		if len(src) > 0 && src[len(src)-1] != '\n' {
+			lines++
+		}
+		coverage := make([]*int, lines)
+		for line, count := range hits[f.Path] {
+			if line >= 1 && line <= len(coverage) {
+				count := count
+				coverage[line-1] = &count
+			}
+		}
+		job.SourceFiles = append(job.SourceFiles, coverallsSourceFile{
+			Name:         f.Path,
+			SourceDigest: hex.EncodeToString(digest[:]),
+			Coverage:     coverage,
+		})
+	}
+	return job, nil
+}
+
+// profileHits reads a coverage profile into per-file, per-line hit counts,
+// keyed by module-relative path. Lines in several blocks keep the highest count.
+func profileHits(profile, module string) (map[string]map[int]int, error) {
+	f, err := os.Open(profile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hits := map[string]map[int]int{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// path/file.go:startLine.startCol,endLine.endCol numStmts count
+		idx := strings.LastIndex(line, ":")
+		fields := strings.Fields(line[idx+1:])
+		if idx < 0 || len(fields) != 3 {
+			continue
+		}
+		file := strings.TrimPrefix(line[:idx], module+"/")
+		start, end, ok := strings.Cut(fields[0], ",")
+		if !ok {
+			continue
+		}
+		startLine, err1 := strconv.Atoi(strings.Split(start, ".")[0])
+		endLine, err2 := strconv.Atoi(strings.Split(end, ".")[0])
+		count, err3 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		if hits[file] == nil {
+			hits[file] = map[int]int{}
+		}
+		for ln := startLine; ln <= endLine; ln++ {
+			if prev, seen := hits[file][ln]; !seen || count > prev {
+				hits[file][ln] = count
+			}
+		}
+	}
+	return hits, scanner.Err()
+}
@@ -0,0 +1,77 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestCoveralls_Publish checks the submitted job for the changed files.
+func TestCoveralls_Publish(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "pkg"), 0755)
+	os.WriteFile(filepath.Join(root, "pkg", "a.go"), []byte("package pkg\n\nfunc A() {\n\tprintln(1)\n\tprintln(2)\n}\n"), 0644)
+	profile := filepath.Join(root, "cover.out")
+	os.WriteFile(profile, []byte("mode: count\nexample.com/m/pkg/a.go:3.10,4.12 1 3\nexample.com/m/pkg/a.go:5.2,5.12 1 0\nexample.com/m/pkg/b.go:1.1,1.2 1 1\n"), 0644)
+
+	var job coverallsJob
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/jobs" {
+			http.NotFound(w, r)
+			return
+		}
+		file, _, err := r.FormFile("json_file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(file).Decode(&job)
+	}))
+	defer srv.Close()
+
+	r := &diffcoverage.Report{Module: "example.com/m", Profile: profile, SourceRoot: root,
+		Files: []diffcoverage.FileReport{{Path: "pkg/a.go"}}}
+	c := &Coveralls{URL: srv.URL, RepoToken: "tok", ServiceName: "github", PR: "5", Commit: "abc", Branch: "feature"}
+	if err := c.Publish(context.Background(), r); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	if job.RepoToken != "tok" || job.PullRequest != "5" || job.Git.Head["id"] != "abc" || job.Git.Branch != "feature" {
+		t.Errorf("Unexpected job metadata %+v", job)
+	}
+	if len(job.SourceFiles) != 1 || job.SourceFiles[0].Name != "pkg/a.go" || len(job.SourceFiles[0].SourceDigest) != 32 {
+		t.Fatalf("Unexpected source files %+v", job.SourceFiles)
+	}
+	var got []string
+	for _, c := range job.SourceFiles[0].Coverage {
+		if c == nil {
+			got = append(got, "-")
+		} else {
+			got = append(got, string(rune('0'+*c)))
+		}
+	}
+	if strings.Join(got, "") != "--330-" {
+		t.Errorf("coverage = %s, want --330-", strings.Join(got, ""))
+	}
+}
+
+// TestCoveralls_PublishErrors covers missing settings and unreadable sources.
+func TestCoveralls_PublishErrors(t *testing.T) {
+	if err := (&Coveralls{}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error without profile and token, got nil")
+	}
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	os.WriteFile(profile, []byte("mode: set\n"), 0644)
+	r := sampleReport()
+	r.Profile, r.SourceRoot = profile, t.TempDir()
+	if err := (&Coveralls{RepoToken: "tok"}).Publish(context.Background(), r); err == nil {
+		t.Errorf("Expected error for missing source file, got nil")
+	}
+}
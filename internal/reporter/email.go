@@ -0,0 +1,115 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+)
+
+// Email sends a plain-text summary with the uncovered ranges over SMTP when
+// the gate fails, or on every run if Always is set. Recipients are To and, if
+// Author is set, the author of Commit (HEAD if empty).
+type Email struct {
+	Addr      string // SMTP server host:port
+	Username  string // PLAIN authentication if set
+	Password  string
+	From      string
+	To        []string
+	Author    bool
+	Always    bool
+	Repo      string
+	Branch    string
+	Commit    string
+	ReportURL string
+	// SendMail delivers the message; smtp.SendMail if nil.
+	SendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Publish sends the email.
+func (e *Email) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if r.Passed && !e.Always {
+		return nil
+	}
+	if e.Addr == "" || e.From == "" {
+		return fmt.Errorf("email: SMTP server and sender address are required")
+	}
+
+	to := append([]string(nil), e.To...)
+	if e.Author {
+		commit := e.Commit
+		if commit == "" {
+			commit = "HEAD"
+		}
+		author, err := gitutil.Run(r.SourceRoot, "log", "-1", "--format=%ae", commit)
+		if err != nil {
+			return fmt.Errorf("email: error looking up the commit author: %v", err)
+		}
+		if author != "" {
+			to = append(to, author)
+		}
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("email: no recipients")
+	}
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		host, _, _ := net.SplitHostPort(e.Addr)
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+	send := e.SendMail
+	if send == nil {
+		send = smtp.SendMail
+	}
+	if err := send(e.Addr, auth, e.From, to, e.message(r, to)); err != nil {
+		return fmt.Errorf("email: %v", err)
+	}
+	return nil
+}
+
+// message renders the headers and body of the email.
+func (e *Email) message(r *diffcoverage.Report, to []string) []byte {
+	subject := fmt.Sprintf("Diff coverage %.2f%% is below the minimum %.2f%%", r.Coverage, r.MinCoverage)
+	if r.Passed {
+		subject = fmt.Sprintf("Diff coverage %.2f%% meets the minimum %.2f%%", r.Coverage, r.MinCoverage)
+	}
+	if where := strings.TrimSpace(e.Repo + " " + e.Branch); where != "" {
+		subject += " (" + where + ")"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", e.From)
+	fmt.Fprintf(&sb, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&sb, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&sb, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	for _, line := range []struct{ name, value string }{{"Repository", e.Repo}, {"Branch", e.Branch}, {"Commit", e.Commit}} {
+		if line.value != "" {
+			fmt.Fprintf(&sb, "%s: %s\r\n", line.name, line.value)
+		}
+	}
+	fmt.Fprintf(&sb, "%d of %d new/changed lines in functions are covered (%.2f%%, minimum %.2f%%%s).\r\n",
+		r.CoveredLines, r.TotalLines, r.Coverage, r.MinCoverage, gradeNote(r))
+	for _, f := range r.Files {
+		if len(f.Uncovered) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\r\n%s (%.2f%%)\r\n  Uncovered lines: %s\r\n", f.Path, f.Coverage, FormatRanges(f.Uncovered))
+	}
+	for _, g := range r.Regressions {
+		fmt.Fprintf(&sb, "\r\nCoverage trend: %s\r\n", g)
+	}
+	if e.ReportURL != "" {
+		fmt.Fprintf(&sb, "\r\nFull report: %s\r\n", e.ReportURL)
+	}
+	fmt.Fprintf(&sb, "\r\n-- \r\ngo-new-code-coverage %s\r\n", r.ToolVersion)
+	return []byte(sb.String())
+}
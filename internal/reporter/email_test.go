@@ -0,0 +1,78 @@
+package reporter
+
+import (
+	"context"
+	"net/smtp"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+)
+
+// TestEmail_Publish sends the failing report to the team list and the commit author.
+func TestEmail_Publish(t *testing.T) {
+	root := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=dev", "-c", "user.email=dev@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if _, err := gitutil.Run(root, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	r := sampleReport()
+	r.SourceRoot = root
+
+	var gotAddr, gotFrom, msg string
+	var gotTo []string
+	var gotAuth smtp.Auth
+	e := &Email{
+		Addr: "smtp.example.com:587", Username: "bot", Password: "pw", From: "ci@example.com",
+		To: []string{"team@example.com"}, Author: true, Branch: "main", ReportURL: "https://ci/report",
+		SendMail: func(addr string, a smtp.Auth, from string, to []string, body []byte) error {
+			gotAddr, gotAuth, gotFrom, gotTo, msg = addr, a, from, to, string(body)
+			return nil
+		},
+	}
+	if err := e.Publish(context.Background(), r); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if gotAddr != "smtp.example.com:587" || gotAuth == nil || gotFrom != "ci@example.com" {
+		t.Errorf("Unexpected envelope %s %v %s", gotAddr, gotAuth, gotFrom)
+	}
+	if want := []string{"team@example.com", "dev@example.com"}; !reflect.DeepEqual(gotTo, want) {
+		t.Errorf("To = %v, want %v", gotTo, want)
+	}
+	for _, want := range []string{
+		"Subject: Diff coverage 50.00% is below the minimum 80.00% (main)\r\n",
+		"pkg/a.go (50.00%)\r\n  Uncovered lines: 6-7, 9\r\n",
+		"Full report: https://ci/report",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, msg)
+		}
+	}
+}
+
+// TestEmail_Passed sends nothing for a passing report unless Always is set.
+func TestEmail_Passed(t *testing.T) {
+	sent := 0
+	e := &Email{Addr: "localhost:25", From: "ci@example.com", To: []string{"team@example.com"},
+		SendMail: func(string, smtp.Auth, string, []string, []byte) error { sent++; return nil }}
+	r := sampleReport()
+	r.Passed = true
+
+	if err := e.Publish(context.Background(), r); err != nil || sent != 0 {
+		t.Errorf("Expected no email, got %d sent, %v", sent, err)
+	}
+	e.Always = true
+	if err := e.Publish(context.Background(), r); err != nil || sent != 1 {
+		t.Errorf("Expected one email, got %d sent, %v", sent, err)
+	}
+
+	e.To = nil
+	if err := e.Publish(context.Background(), r); err == nil {
+		t.Errorf("Expected an error without recipients")
+	}
+}
@@ -0,0 +1,133 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// GerritRobotID identifies the robot comments posted by diffcoverage.
+const GerritRobotID = "diffcoverage"
+
+// Gerrit posts a review on a patchset with a comment on each uncovered line
+// range and, if Label is set, votes +1 or -1 on it depending on the gate.
+type Gerrit struct {
+	URL      string // e.g. https://review.example.com
+	Change   string // change number or ID
+	Revision string // patchset number or commit SHA; "current" if empty
+	Username string
+	Password string // HTTP password
+	Label    string // e.g. Verified
+	// Robot posts robot comments instead of regular, unresolved review comments.
+	Robot bool
+	// RunID identifies the CI run for robot comments, e.g. the build URL.
+	RunID     string
+	ReportURL string // linked from robot comments and the review message, if set
+	Client    *http.Client
+}
+
+type gerritReview struct {
+	Message       string                          `json:"message"`
+	Tag           string                          `json:"tag"`
+	Labels        map[string]int                  `json:"labels,omitempty"`
+	Comments      map[string][]gerritComment      `json:"comments,omitempty"`
+	RobotComments map[string][]gerritRobotComment `json:"robot_comments,omitempty"`
+}
+
+type gerritComment struct {
+	Line       int          `json:"line"`
+	Range      *gerritRange `json:"range,omitempty"`
+	Message    string       `json:"message"`
+	Unresolved bool         `json:"unresolved"`
+}
+
+type gerritRobotComment struct {
+	gerritComment
+	RobotID    string `json:"robot_id"`
+	RobotRunID string `json:"robot_run_id"`
+	URL        string `json:"url,omitempty"`
+}
+
+type gerritRange struct {
+	StartLine      int `json:"start_line"`
+	StartCharacter int `json:"start_character"`
+	EndLine        int `json:"end_line"`
+	EndCharacter   int `json:"end_character"`
+}
+
+// Publish posts the review.
+func (g *Gerrit) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if g.URL == "" || g.Change == "" || g.Username == "" {
+		return fmt.Errorf("gerrit review: server URL, change and credentials are required")
+	}
+
+	review := gerritReview{
+		Message: fmt.Sprintf("Diff coverage: %.2f%% (minimum %.2f%%%s), %d of %d new/changed lines in functions covered.",
+			r.Coverage, r.MinCoverage, gradeNote(r), r.CoveredLines, r.TotalLines),
+		Tag: "autogenerated:" + GerritRobotID,
+	}
+	if g.ReportURL != "" {
+		review.Message += "\n\nFull report: " + g.ReportURL
+	}
+	if g.Label != "" {
+		vote := 1
+		if !r.Passed {
+			vote = -1
+		}
+		review.Labels = map[string]int{g.Label: vote}
+	}
+
+	for _, f := range r.Files {
+		for _, rng := range f.Uncovered {
+			c := gerritComment{Line: rng[1], Message: "Not covered by tests.", Unresolved: true}
+			if rng[0] != rng[1] {
+				// The range ends at the start of the line after the last uncovered one.
+				c.Range = &gerritRange{StartLine: rng[0], EndLine: rng[1] + 1}
+			}
+			if g.Robot {
+				if review.RobotComments == nil {
+					review.RobotComments = map[string][]gerritRobotComment{}
+				}
+				review.RobotComments[f.Path] = append(review.RobotComments[f.Path],
+					gerritRobotComment{gerritComment: c, RobotID: GerritRobotID, RobotRunID: g.runID(), URL: g.ReportURL})
+			} else {
+				if review.Comments == nil {
+					review.Comments = map[string][]gerritComment{}
+				}
+				review.Comments[f.Path] = append(review.Comments[f.Path], c)
+			}
+		}
+	}
+
+	if err := doJSON(ctx, g.Client, http.MethodPost, g.reviewURL(), g.header(), review, nil); err != nil {
+		return fmt.Errorf("gerrit review: %v", err)
+	}
+	return nil
+}
+
+// reviewURL is the authenticated set-review endpoint of the revision.
+func (g *Gerrit) reviewURL() string {
+	revision := g.Revision
+	if revision == "" {
+		revision = "current"
+	}
+	return fmt.Sprintf("%s/a/changes/%s/revisions/%s/review",
+		strings.TrimSuffix(g.URL, "/"), url.PathEscape(g.Change), url.PathEscape(revision))
+}
+
+func (g *Gerrit) runID() string {
+	if g.RunID == "" {
+		return "unknown"
+	}
+	return g.RunID
+}
+
+func (g *Gerrit) header() http.Header {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(g.Username, g.Password)
+	return req.Header
+}
@@ -0,0 +1,81 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestGerrit_Publish covers review comments, robot comments and the label vote.
+func TestGerrit_Publish(t *testing.T) {
+	var (
+		path   string
+		review gerritReview
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "ci" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		path = r.URL.Path
+		review = gerritReview{}
+		json.NewDecoder(r.Body).Decode(&review)
+		w.Write([]byte(")]}'\n{}"))
+	}))
+	defer srv.Close()
+
+	g := &Gerrit{URL: srv.URL + "/", Change: "1234", Username: "ci", Password: "secret", Label: "Verified"}
+	if err := g.Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if path != "/a/changes/1234/revisions/current/review" {
+		t.Errorf("Unexpected path %q", path)
+	}
+	if review.Labels["Verified"] != -1 {
+		t.Errorf("Expected a -1 vote on the failing gate, got %v", review.Labels)
+	}
+	comments := review.Comments["pkg/a.go"]
+	if len(comments) != 2 || review.RobotComments != nil {
+		t.Fatalf("Expected 2 review comments, got %+v", review)
+	}
+	if c := comments[0]; c.Line != 7 || c.Range == nil || c.Range.StartLine != 6 || c.Range.EndLine != 8 || !c.Unresolved {
+		t.Errorf("Unexpected range comment: %+v", c)
+	}
+	if c := comments[1]; c.Line != 9 || c.Range != nil {
+		t.Errorf("Unexpected single-line comment: %+v", c)
+	}
+
+	report := sampleReport()
+	report.Passed = true
+	g.Robot, g.Revision, g.RunID = true, "abc", "build-7"
+	if err := g.Publish(context.Background(), report); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if path != "/a/changes/1234/revisions/abc/review" || review.Labels["Verified"] != 1 {
+		t.Errorf("Unexpected path %q or labels %v", path, review.Labels)
+	}
+	robot := review.RobotComments["pkg/a.go"]
+	if len(robot) != 2 || review.Comments != nil || robot[0].RobotID != GerritRobotID || robot[0].RobotRunID != "build-7" {
+		t.Errorf("Expected 2 robot comments, got %+v", review)
+	}
+}
+
+// TestGerrit_PublishErrors covers missing settings and API failures.
+func TestGerrit_PublishErrors(t *testing.T) {
+	if err := (&Gerrit{Change: "1"}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error for missing settings, got nil")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	g := &Gerrit{URL: srv.URL, Change: "1", Username: "ci", Password: "wrong"}
+	err := g.Publish(context.Background(), sampleReport())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// GiteaStatusContext is the commit status context set by Gitea.
+const GiteaStatusContext = "diffcoverage"
+
+// Gitea posts the report as a sticky pull request comment and sets a commit
+// status on Gitea and Forgejo instances. The comment is skipped without PR,
+// the status without Commit.
+type Gitea struct {
+	APIURL    string // e.g. https://gitea.example.com/api/v1
+	Repo      string // owner/name
+	PR        string
+	Commit    string
+	Token     string
+	ReportURL string             // target of the commit status and linked from the comment, if set
+	Template  *template.Template // renders the comment instead of the built-in summary, if set
+	MaxBytes  int                // caps the size of the comment (default: GiteaCommentLimit)
+	Client    *http.Client
+}
+
+type giteaStatus struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+// Publish updates the comment and the commit status.
+func (g *Gitea) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if g.APIURL == "" || g.Repo == "" || g.Token == "" || (g.PR == "" && g.Commit == "") {
+		return fmt.Errorf("gitea: API URL, repository, token and a pull request or commit are required")
+	}
+
+	if g.PR != "" {
+		if err := g.publishComment(ctx, r); err != nil {
+			return fmt.Errorf("gitea comment: %v", err)
+		}
+	}
+	if g.Commit != "" {
+		state := "success"
+		if !r.Passed {
+			state = "failure"
+		}
+		status := giteaStatus{
+			State:       state,
+			TargetURL:   g.ReportURL,
+			Description: fmt.Sprintf("%.2f%% of new/changed lines covered (minimum %.2f%%%s)", r.Coverage, r.MinCoverage, gradeNote(r)),
+			Context:     GiteaStatusContext,
+		}
+		url := fmt.Sprintf("%s/repos/%s/statuses/%s", g.apiURL(), g.Repo, g.Commit)
+		if err := doJSON(ctx, g.Client, http.MethodPost, url, g.header(), status, nil); err != nil {
+			return fmt.Errorf("gitea status: %v", err)
+		}
+	}
+	return nil
+}
+
+// publishComment creates or updates the sticky comment.
+func (g *Gitea) publishComment(ctx context.Context, r *diffcoverage.Report) error {
+	body, err := FitComment(r, g.ReportURL, g.Template, limit(g.MaxBytes, GiteaCommentLimit))
+	if err != nil {
+		return err
+	}
+	comment := githubComment{Body: body}
+
+	// Gitea returns all comments of an issue at once.
+	var comments []githubComment
+	url := fmt.Sprintf("%s/repos/%s/issues/%s/comments", g.apiURL(), g.Repo, g.PR)
+	if err := doJSON(ctx, g.Client, http.MethodGet, url, g.header(), nil, &comments); err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if strings.Contains(c.Body, Marker) {
+			url := fmt.Sprintf("%s/repos/%s/issues/comments/%d", g.apiURL(), g.Repo, c.ID)
+			return doJSON(ctx, g.Client, http.MethodPatch, url, g.header(), comment, nil)
+		}
+	}
+	return doJSON(ctx, g.Client, http.MethodPost, url, g.header(), comment, nil)
+}
+
+func (g *Gitea) apiURL() string {
+	return strings.TrimSuffix(g.APIURL, "/")
+}
+
+func (g *Gitea) header() http.Header {
+	return http.Header{"Authorization": {"token " + g.Token}}
+}
@@ -0,0 +1,83 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestGitea_Publish checks the sticky comment and the commit status.
+func TestGitea_Publish(t *testing.T) {
+	var (
+		calls    []string
+		comments []githubComment
+		status   giteaStatus
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "token secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/repos/owner/repo/issues/3/comments":
+			json.NewEncoder(w).Encode(comments)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/owner/repo/issues/3/comments":
+			var c githubComment
+			json.NewDecoder(r.Body).Decode(&c)
+			c.ID = int64(len(comments) + 1)
+			comments = append(comments, c)
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/repos/owner/repo/issues/comments/1":
+			json.NewDecoder(r.Body).Decode(&comments[0])
+			comments[0].ID = 1
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/owner/repo/statuses/abc":
+			json.NewDecoder(r.Body).Decode(&status)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := &Gitea{APIURL: srv.URL + "/api/v1/", Repo: "owner/repo", PR: "3", Commit: "abc", Token: "secret"}
+	if err := g.Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("first Publish failed: %v", err)
+	}
+	if status.State != "failure" || status.Context != GiteaStatusContext {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	report := sampleReport()
+	report.Coverage, report.Passed = 100, true
+	if err := g.Publish(context.Background(), report); err != nil {
+		t.Fatalf("second Publish failed: %v", err)
+	}
+	if len(comments) != 1 || !strings.HasPrefix(comments[0].Body, Marker) || !strings.Contains(comments[0].Body, "100.00%") {
+		t.Errorf("Expected one updated comment, got %+v", comments)
+	}
+	if status.State != "success" {
+		t.Errorf("Expected success status, got %+v", status)
+	}
+	if calls[len(calls)-2] != "PATCH /api/v1/repos/owner/repo/issues/comments/1" {
+		t.Errorf("Expected the comment to be patched, calls: %v", calls)
+	}
+}
+
+// TestGitea_PublishErrors covers missing settings and API failures.
+func TestGitea_PublishErrors(t *testing.T) {
+	if err := (&Gitea{APIURL: "http://x", Repo: "owner/repo", Token: "t"}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error without pull request or commit, got nil")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	g := &Gitea{APIURL: srv.URL, Repo: "owner/repo", Commit: "abc", Token: "wrong"}
+	err := g.Publish(context.Background(), sampleReport())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// GitHub posts the report as a pull request comment and updates that same
+// comment on subsequent runs. Test skeletons of the report are posted as
+// review comments, once per function.
+type GitHub struct {
+	APIURL string // e.g. https://api.github.com
+	Repo   string // owner/name
+	PR     string
+	Token  string
+	// ReportURL, when set, is linked from the comment.
+	ReportURL string
+	// Template, if set, renders the comment instead of the built-in summary.
+	Template *template.Template
+	// MaxBytes caps the size of the comment (default: GitHubCommentLimit); the files with
+	// the fewest uncovered lines are left out to fit.
+	MaxBytes int
+	Client   *http.Client
+}
+
+type githubComment struct {
+	ID   int64  `json:"id,omitempty"`
+	Body string `json:"body"`
+}
+
+// Publish creates or updates the sticky comment.
+func (g *GitHub) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if g.Repo == "" || g.PR == "" || g.Token == "" {
+		return fmt.Errorf("github comment: repository, pull request number and token are required")
+	}
+
+	body, err := FitComment(r, g.ReportURL, g.Template, limit(g.MaxBytes, GitHubCommentLimit))
+	if err != nil {
+		return fmt.Errorf("github comment: %v", err)
+	}
+	comment := githubComment{Body: body}
+
+	id, err := g.findComment(ctx)
+	if err != nil {
+		return fmt.Errorf("github comment: %v", err)
+	}
+	if id != 0 {
+		url := fmt.Sprintf("%s/repos/%s/issues/comments/%d", g.apiURL(), g.Repo, id)
+		err = doJSON(ctx, g.Client, http.MethodPatch, url, g.header(), comment, nil)
+	} else {
+		url := fmt.Sprintf("%s/repos/%s/issues/%s/comments", g.apiURL(), g.Repo, g.PR)
+		err = doJSON(ctx, g.Client, http.MethodPost, url, g.header(), comment, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("github comment: %v", err)
+	}
+	if len(r.Skeletons) > 0 {
+		if err := g.postSkeletons(ctx, r.Skeletons); err != nil {
+			return fmt.Errorf("github review: %v", err)
+		}
+	}
+	return nil
+}
+
+type githubReview struct {
+	Event    string                `json:"event"`
+	Comments []githubReviewComment `json:"comments"`
+}
+
+type githubReviewComment struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Side string `json:"side"`
+	Body string `json:"body"`
+}
+
+// postSkeletons posts one review with a comment per skeleton not posted
+// before. Skeletons whose test file ends in the diff are suggested as a
+// change appending the test; the others are shown on the function.
+func (g *GitHub) postSkeletons(ctx context.Context, skeletons []diffcoverage.TestSkeleton) error {
+	posted, err := g.reviewComments(ctx)
+	if err != nil {
+		return err
+	}
+
+	var review githubReview
+	for _, s := range skeletons {
+		marker := fmt.Sprintf("<!-- diffcoverage-test:%s:%s -->", s.File, s.Func)
+		if strings.Contains(posted, marker) {
+			continue
+		}
+		imports := ""
+		if len(s.Imports) > 1 {
+			imports = fmt.Sprintf(" It needs the imports %s.", strings.Join(s.Imports, ", "))
+		}
+		c := githubReviewComment{Side: "RIGHT"}
+		if s.TestLine > 0 {
+			c.Path, c.Line = s.TestFile, s.TestLine
+			c.Body = fmt.Sprintf("%s\n`%s` in `%s` is new and not covered by tests. A table-driven test to start from:%s\n\n```suggestion\n%s\n\n%s```\n",
+				marker, s.Func, s.File, imports, s.TestLineText, s.Code)
+		} else {
+			c.Path, c.Line = s.File, s.Line
+			c.Body = fmt.Sprintf("%s\n`%s` is new and not covered by tests. A table-driven test to start from, for `%s`:%s\n\n```go\n%s```\n",
+				marker, s.Func, s.TestFile, imports, s.Code)
+		}
+		review.Comments = append(review.Comments, c)
+	}
+	if len(review.Comments) == 0 {
+		return nil
+	}
+	review.Event = "COMMENT"
+	url := fmt.Sprintf("%s/repos/%s/pulls/%s/reviews", g.apiURL(), g.Repo, g.PR)
+	return doJSON(ctx, g.Client, http.MethodPost, url, g.header(), review, nil)
+}
+
+// reviewComments returns the bodies of the pull request's review comments.
+func (g *GitHub) reviewComments(ctx context.Context) (string, error) {
+	const perPage = 100
+	var sb strings.Builder
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/pulls/%s/comments?per_page=%d&page=%d", g.apiURL(), g.Repo, g.PR, perPage, page)
+		var comments []githubComment
+		if err := doJSON(ctx, g.Client, http.MethodGet, url, g.header(), nil, &comments); err != nil {
+			return "", err
+		}
+		for _, c := range comments {
+			sb.WriteString(c.Body)
+		}
+		if len(comments) < perPage {
+			return sb.String(), nil
+		}
+	}
+}
+
+// findComment returns the ID of the previous diffcoverage comment, or 0.
+func (g *GitHub) findComment(ctx context.Context) (int64, error) {
+	const perPage = 100
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/issues/%s/comments?per_page=%d&page=%d", g.apiURL(), g.Repo, g.PR, perPage, page)
+		var comments []githubComment
+		if err := doJSON(ctx, g.Client, http.MethodGet, url, g.header(), nil, &comments); err != nil {
+			return 0, err
+		}
+		for _, c := range comments {
+			if strings.Contains(c.Body, Marker) {
+				return c.ID, nil
+			}
+		}
+		if len(comments) < perPage {
+			return 0, nil
+		}
+	}
+}
+
+func (g *GitHub) apiURL() string {
+	if g.APIURL == "" {
+		return "https://api.github.com"
+	}
+	return strings.TrimSuffix(g.APIURL, "/")
+}
+
+func (g *GitHub) header() http.Header {
+	return http.Header{
+		"Authorization":        {"Bearer " + g.Token},
+		"X-Github-Api-Version": {"2022-11-28"},
+	}
+}
@@ -0,0 +1,152 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// fakeGitHub records comment API calls for one pull request.
+type fakeGitHub struct {
+	mu       sync.Mutex
+	comments []githubComment
+	reviews  []githubReviewComment
+	calls    []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/octo/repo/issues/5/comments":
+		page := r.URL.Query().Get("page")
+		start := 0
+		if page == "2" {
+			start = 100
+		}
+		end := start + 100
+		if end > len(f.comments) {
+			end = len(f.comments)
+		}
+		if start > end {
+			start = end
+		}
+		json.NewEncoder(w).Encode(f.comments[start:end])
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/octo/repo/issues/5/comments":
+		var c githubComment
+		json.NewDecoder(r.Body).Decode(&c)
+		c.ID = int64(len(f.comments) + 1)
+		f.comments = append(f.comments, c)
+		json.NewEncoder(w).Encode(c)
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/octo/repo/pulls/5/comments":
+		json.NewEncoder(w).Encode(f.reviews)
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/octo/repo/pulls/5/reviews":
+		var review githubReview
+		json.NewDecoder(r.Body).Decode(&review)
+		f.reviews = append(f.reviews, review.Comments...)
+		w.Write([]byte("{}"))
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/repos/octo/repo/issues/comments/"):
+		var c githubComment
+		json.NewDecoder(r.Body).Decode(&c)
+		var id int64
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/repos/octo/repo/issues/comments/"), "%d", &id)
+		f.comments[id-1].Body = c.Body
+		json.NewEncoder(w).Encode(f.comments[id-1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// TestGitHub_PublishCreatesThenUpdates checks the comment is sticky.
+func TestGitHub_PublishCreatesThenUpdates(t *testing.T) {
+	fake := &fakeGitHub{}
+	// Fill the first page with unrelated comments so the lookup paginates.
+	for i := 0; i < 100; i++ {
+		fake.comments = append(fake.comments, githubComment{ID: int64(i + 1), Body: "lgtm"})
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	g := &GitHub{APIURL: srv.URL + "/", Repo: "octo/repo", PR: "5", Token: "token"}
+	if err := g.Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("first Publish failed: %v", err)
+	}
+	if len(fake.comments) != 101 || !strings.HasPrefix(fake.comments[100].Body, Marker) {
+		t.Fatalf("Expected a new marked comment, got %d comments", len(fake.comments))
+	}
+
+	report := sampleReport()
+	report.Coverage, report.Passed = 100, true
+	if err := g.Publish(context.Background(), report); err != nil {
+		t.Fatalf("second Publish failed: %v", err)
+	}
+	if len(fake.comments) != 101 {
+		t.Fatalf("Expected the comment to be updated, got %d comments", len(fake.comments))
+	}
+	if !strings.Contains(fake.comments[100].Body, "100.00%") {
+		t.Errorf("Comment not updated:\n%s", fake.comments[100].Body)
+	}
+	if last := fake.calls[len(fake.calls)-1]; last != "PATCH /repos/octo/repo/issues/comments/101" {
+		t.Errorf("Expected final call to PATCH the comment, got %q", last)
+	}
+}
+
+// TestGitHub_PublishErrors covers missing settings and API failures.
+func TestGitHub_PublishErrors(t *testing.T) {
+	if err := (&GitHub{Repo: "octo/repo"}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error for missing settings, got nil")
+	}
+
+	srv := httptest.NewServer(&fakeGitHub{})
+	defer srv.Close()
+	g := &GitHub{APIURL: srv.URL, Repo: "octo/repo", PR: "5", Token: "wrong"}
+	err := g.Publish(context.Background(), sampleReport())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
+
+// TestGitHub_PublishSkeletons posts each test skeleton once, as a suggestion
+// when the test file ends in the diff.
+func TestGitHub_PublishSkeletons(t *testing.T) {
+	fake := &fakeGitHub{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	report := sampleReport()
+	report.Skeletons = []diffcoverage.TestSkeleton{
+		{File: "a.go", Line: 3, Func: "A", TestFile: "a_test.go", Code: "func TestA(t *testing.T) {}\n", Imports: []string{"testing"}},
+		{File: "b.go", Line: 7, Func: "B", TestFile: "b_test.go", Code: "func TestB(t *testing.T) {}\n", Imports: []string{"testing"}, TestLine: 12, TestLineText: "}"},
+	}
+	g := &GitHub{APIURL: srv.URL, Repo: "octo/repo", PR: "5", Token: "token"}
+	for i := 0; i < 2; i++ {
+		if err := g.Publish(context.Background(), report); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	if len(fake.reviews) != 2 {
+		t.Fatalf("Expected two review comments in total, got %+v", fake.reviews)
+	}
+	a, b := fake.reviews[0], fake.reviews[1]
+	if a.Path != "a.go" || a.Line != 3 || !strings.Contains(a.Body, "```go\nfunc TestA") {
+		t.Errorf("Unexpected comment on the function: %+v", a)
+	}
+	if b.Path != "b_test.go" || b.Line != 12 || !strings.Contains(b.Body, "```suggestion\n}\n\nfunc TestB") {
+		t.Errorf("Unexpected suggestion: %+v", b)
+	}
+}
@@ -0,0 +1,93 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// GitLab posts the report as a merge request note and updates that same note
+// on subsequent runs.
+type GitLab struct {
+	APIURL  string // e.g. https://gitlab.com/api/v4
+	Project string // numeric ID or full path such as group/project
+	MR      string // merge request IID
+	Token   string // personal, project or group access token with api scope
+	// ReportURL, when set, is linked from the note (e.g. the job's HTML artifact).
+	ReportURL string
+	// Template, if set, renders the comment instead of the built-in summary.
+	Template *template.Template
+	// MaxBytes caps the size of the comment (default: GitLabNoteLimit); the files with
+	// the fewest uncovered lines are left out to fit.
+	MaxBytes int
+	Client   *http.Client
+}
+
+type gitlabNote struct {
+	ID   int64  `json:"id,omitempty"`
+	Body string `json:"body"`
+}
+
+// Publish creates or updates the sticky note.
+func (g *GitLab) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if g.Project == "" || g.MR == "" || g.Token == "" {
+		return fmt.Errorf("gitlab note: project, merge request IID and token are required")
+	}
+
+	body, err := FitComment(r, g.ReportURL, g.Template, limit(g.MaxBytes, GitLabNoteLimit))
+	if err != nil {
+		return fmt.Errorf("gitlab note: %v", err)
+	}
+	note := gitlabNote{Body: body}
+
+	id, err := g.findNote(ctx)
+	if err != nil {
+		return fmt.Errorf("gitlab note: %v", err)
+	}
+	if id != 0 {
+		err = doJSON(ctx, g.Client, http.MethodPut, fmt.Sprintf("%s/%d", g.notesURL(), id), g.header(), note, nil)
+	} else {
+		err = doJSON(ctx, g.Client, http.MethodPost, g.notesURL(), g.header(), note, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("gitlab note: %v", err)
+	}
+	return nil
+}
+
+// findNote returns the ID of the previous diffcoverage note, or 0.
+func (g *GitLab) findNote(ctx context.Context) (int64, error) {
+	const perPage = 100
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s?per_page=%d&page=%d", g.notesURL(), perPage, page)
+		var notes []gitlabNote
+		if err := doJSON(ctx, g.Client, http.MethodGet, url, g.header(), nil, &notes); err != nil {
+			return 0, err
+		}
+		for _, n := range notes {
+			if strings.Contains(n.Body, Marker) {
+				return n.ID, nil
+			}
+		}
+		if len(notes) < perPage {
+			return 0, nil
+		}
+	}
+}
+
+func (g *GitLab) notesURL() string {
+	apiURL := strings.TrimSuffix(g.APIURL, "/")
+	if apiURL == "" {
+		apiURL = "https://gitlab.com/api/v4"
+	}
+	return fmt.Sprintf("%s/projects/%s/merge_requests/%s/notes", apiURL, url.PathEscape(g.Project), g.MR)
+}
+
+func (g *GitLab) header() http.Header {
+	return http.Header{"Private-Token": {g.Token}}
+}
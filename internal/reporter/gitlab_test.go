@@ -0,0 +1,103 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGitLab records note API calls for one merge request.
+type fakeGitLab struct {
+	mu    sync.Mutex
+	notes []gitlabNote
+	calls []string
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := r.URL.EscapedPath()
+	f.calls = append(f.calls, r.Method+" "+path)
+
+	if r.Header.Get("Private-Token") != "token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	const notes = "/api/v4/projects/group%2Fproject/merge_requests/7/notes"
+	switch {
+	case r.Method == http.MethodGet && path == notes:
+		json.NewEncoder(w).Encode(f.notes)
+	case r.Method == http.MethodPost && path == notes:
+		var n gitlabNote
+		json.NewDecoder(r.Body).Decode(&n)
+		n.ID = int64(len(f.notes) + 1)
+		f.notes = append(f.notes, n)
+		json.NewEncoder(w).Encode(n)
+	case r.Method == http.MethodPut && strings.HasPrefix(path, notes+"/"):
+		var n gitlabNote
+		json.NewDecoder(r.Body).Decode(&n)
+		var id int64
+		fmt.Sscanf(strings.TrimPrefix(path, notes+"/"), "%d", &id)
+		f.notes[id-1].Body = n.Body
+		json.NewEncoder(w).Encode(f.notes[id-1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// TestGitLab_PublishCreatesThenUpdates checks the note is sticky and links the report.
+func TestGitLab_PublishCreatesThenUpdates(t *testing.T) {
+	fake := &fakeGitLab{notes: []gitlabNote{{ID: 1, Body: "looks good"}}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	g := &GitLab{
+		APIURL:    srv.URL + "/api/v4",
+		Project:   "group/project",
+		MR:        "7",
+		Token:     "token",
+		ReportURL: "https://gitlab.example.com/job/1/artifacts/file/coverage.html",
+	}
+	if err := g.Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("first Publish failed: %v", err)
+	}
+	if len(fake.notes) != 2 || !strings.HasPrefix(fake.notes[1].Body, Marker) {
+		t.Fatalf("Expected a new marked note, got %d notes", len(fake.notes))
+	}
+	if !strings.Contains(fake.notes[1].Body, "[Full report](https://gitlab.example.com/job/1/artifacts/file/coverage.html)") {
+		t.Errorf("Expected a link to the report:\n%s", fake.notes[1].Body)
+	}
+
+	report := sampleReport()
+	report.Coverage, report.Passed = 100, true
+	if err := g.Publish(context.Background(), report); err != nil {
+		t.Fatalf("second Publish failed: %v", err)
+	}
+	if len(fake.notes) != 2 || !strings.Contains(fake.notes[1].Body, "100.00%") {
+		t.Errorf("Expected the note to be updated, got %d notes:\n%s", len(fake.notes), fake.notes[1].Body)
+	}
+	if last := fake.calls[len(fake.calls)-1]; last != "PUT /api/v4/projects/group%2Fproject/merge_requests/7/notes/2" {
+		t.Errorf("Expected final call to PUT the note, got %q", last)
+	}
+}
+
+// TestGitLab_PublishErrors covers missing settings and API failures.
+func TestGitLab_PublishErrors(t *testing.T) {
+	if err := (&GitLab{Project: "group/project"}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error for missing settings, got nil")
+	}
+
+	srv := httptest.NewServer(&fakeGitLab{})
+	defer srv.Close()
+	g := &GitLab{APIURL: srv.URL + "/api/v4", Project: "group/project", MR: "7", Token: "wrong"}
+	err := g.Publish(context.Background(), sampleReport())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+)
+
+// History records the run in the local history file. Branch and commit
+// default to the checkout of the report's source root.
+type History struct {
+	Path   string // relative to the source root; history.DefaultPath if empty
+	Repo   string
+	Branch string
+	Commit string
+	PR     string
+	Now    func() time.Time
+}
+
+// Publish appends the run.
+func (h *History) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	path := history.ResolvePath(r.SourceRoot, h.Path)
+	rec := &history.Record{Repo: h.Repo, Branch: h.Branch, Commit: h.Commit, PR: h.PR, Report: r}
+	if rec.Branch == "" {
+		rec.Branch, _ = gitutil.Run(r.SourceRoot, "rev-parse", "--abbrev-ref", "HEAD")
+	}
+	if rec.Commit == "" {
+		rec.Commit, _ = gitutil.Run(r.SourceRoot, "rev-parse", "HEAD")
+	}
+	rec.Time = time.Now().UTC()
+	if h.Now != nil {
+		rec.Time = h.Now()
+	}
+
+	if err := (&history.Store{Path: path}).Add(rec); err != nil {
+		return fmt.Errorf("history: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package reporter
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+)
+
+// TestHistory_Publish records the run below the source root.
+func TestHistory_Publish(t *testing.T) {
+	root := t.TempDir()
+	r := sampleReport()
+	r.SourceRoot = root
+
+	h := &History{Branch: "main", Commit: "abc", Now: func() time.Time { return time.Unix(10, 0) }}
+	if err := h.Publish(context.Background(), r); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	records, err := (&history.Store{Path: filepath.Join(root, history.DefaultPath)}).Records()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Records = %+v, %v", records, err)
+	}
+	if rec := records[0]; rec.ID != 1 || rec.Branch != "main" || rec.Commit != "abc" || rec.Report.Coverage != 50 || !rec.Time.Equal(time.Unix(10, 0)) {
+		t.Errorf("Unexpected record %+v", rec)
+	}
+}
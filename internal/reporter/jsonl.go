@@ -0,0 +1,66 @@
+package reporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// The records of the JSON Lines output, told apart by their type: a "file"
+// record per counted file, followed by a "line" record per uncovered line of
+// it, and a final "summary" record.
+type (
+	jsonlFile struct {
+		Type         string  `json:"type"`
+		Path         string  `json:"path"`
+		TotalLines   int     `json:"totalLines"`
+		CoveredLines int     `json:"coveredLines"`
+		Coverage     float64 `json:"coverage"`
+	}
+	jsonlLine struct {
+		Type   string `json:"type"`
+		Path   string `json:"path"`
+		Line   int    `json:"line"`
+		Status string `json:"status"`
+	}
+	jsonlSummary struct {
+		Type         string  `json:"type"`
+		TotalLines   int     `json:"totalLines"`
+		CoveredLines int     `json:"coveredLines"`
+		Coverage     float64 `json:"coverage"`
+		MinCoverage  float64 `json:"minCoverage"`
+		Passed       bool    `json:"passed"`
+		Grade        string  `json:"grade,omitempty"`
+		Error        string  `json:"error,omitempty"`
+	}
+)
+
+// WriteJSONL writes the report as JSON Lines, flushing after each file so
+// consumers can process large reports as they arrive.
+func WriteJSONL(w io.Writer, r *diffcoverage.Report) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, f := range r.Files {
+		if err := enc.Encode(jsonlFile{"file", f.Path, f.TotalLines, f.CoveredLines, f.Coverage}); err != nil {
+			return err
+		}
+		for _, line := range f.UncoveredLines() {
+			if err := enc.Encode(jsonlLine{"line", f.Path, line, "uncovered"}); err != nil {
+				return err
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+	}
+	summary := jsonlSummary{Type: "summary", TotalLines: r.TotalLines, CoveredLines: r.CoveredLines, Coverage: r.Coverage, MinCoverage: r.MinCoverage, Passed: r.Passed, Grade: r.Grade}
+	if err := r.Err(); err != nil {
+		summary.Error = err.Error()
+	}
+	if err := enc.Encode(summary); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
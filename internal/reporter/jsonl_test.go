@@ -0,0 +1,25 @@
+package reporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestWriteJSONL emits file, line and summary records.
+func TestWriteJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, sampleReport()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`{"type":"file","path":"pkg/a.go","totalLines":4,"coveredLines":2,"coverage":50}`,
+		`{"type":"line","path":"pkg/a.go","line":6,"status":"uncovered"}`,
+		`{"type":"line","path":"pkg/a.go","line":7,"status":"uncovered"}`,
+		`{"type":"line","path":"pkg/a.go","line":9,"status":"uncovered"}`,
+		`{"type":"summary","totalLines":4,"coveredLines":2,"coverage":50,"minCoverage":80,"passed":false,"error":"coverage 50.00% is below the minimum required 80.00%"}`,
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("WriteJSONL =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
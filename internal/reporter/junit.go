@@ -0,0 +1,78 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as a JUnit XML test suite, which CI systems
+// display as test results: the gate is one test case, failing with the
+// reason the report failed, and every counted file another one, failing
+// with its uncovered lines if its coverage is below the minimum. Files above
+// it list their uncovered lines as output.
+func WriteJUnit(w io.Writer, r *diffcoverage.Report) error {
+	suite := junitSuite{Name: "diffcoverage"}
+	gate := junitCase{Name: "diff coverage", Classname: "diffcoverage"}
+	if err := r.Err(); err != nil {
+		gate.Failure = &junitFailure{Message: err.Error(), Text: err.Error()}
+	}
+	suite.Cases = append(suite.Cases, gate)
+	for _, f := range r.Files {
+		c := junitCase{Name: f.Path, Classname: "diffcoverage.files", File: f.Path}
+		switch uncovered := "Uncovered lines: " + FormatRanges(f.Uncovered); {
+		case f.Coverage < r.MinCoverage:
+			c.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d of %d changed lines uncovered (%.2f%% covered)", f.TotalLines-f.CoveredLines, f.TotalLines, f.Coverage),
+				Text:    uncovered,
+			}
+		case len(f.Uncovered) > 0:
+			c.SystemOut = uncovered
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	for _, c := range suite.Cases {
+		suite.Tests++
+		if c.Failure != nil {
+			suite.Failures++
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,29 @@
+package reporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestWriteJUnit fails the files below the minimum coverage only.
+func TestWriteJUnit(t *testing.T) {
+	r := sampleReport()
+	r.Files = append(r.Files, diffcoverage.FileReport{Path: "pkg/b.go", TotalLines: 10, CoveredLines: 9, Coverage: 90, Uncovered: [][2]int{{12, 12}}})
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<testsuite name="diffcoverage" tests="3" failures="2">`,
+		`<failure message="2 of 4 changed lines uncovered (50.00% covered)">Uncovered lines: 6-7, 9</failure>`,
+		"<testcase name=\"pkg/b.go\" classname=\"diffcoverage.files\" file=\"pkg/b.go\">\n      <system-out>Uncovered lines: 12</system-out>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// Marker is a hidden HTML comment identifying comments posted by diffcoverage,
+// so later runs update them instead of adding new ones.
+const Marker = "<!-- diffcoverage -->"
+
+// Markdown renders the report as a summary, a per-file table and uncovered ranges.
+func Markdown(r *diffcoverage.Report) string {
+	return markdown(r, 0)
+}
+
+// markdown is Markdown noting that omitted more files were left out of the
+// table (see FitComment).
+func markdown(r *diffcoverage.Report, omitted int) string {
+	var sb strings.Builder
+
+	icon := "✅"
+	if !r.Passed {
+		icon = "❌"
+	}
+	fmt.Fprintf(&sb, "### %s Diff coverage: %.2f%% (minimum %.2f%%%s)\n\n", icon, r.Coverage, r.MinCoverage, gradeNote(r))
+
+	if len(r.ProfileProblems) > 0 {
+		sb.WriteString("⚠️ The coverage profile looks implausible, so the coverage may mean nothing:\n\n")
+		for _, p := range r.ProfileProblems {
+			fmt.Fprintf(&sb, "- %s\n", p)
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(r.Warnings) > 0 {
+		var labels []string
+		for _, o := range r.Overrides {
+			if o.Warn {
+				labels = append(labels, "`"+o.Label+"`")
+			}
+		}
+		fmt.Fprintf(&sb, "⚠️ Failures reported as warnings because of the label %s:\n\n", strings.Join(labels, ", "))
+		for _, w := range r.Warnings {
+			fmt.Fprintf(&sb, "- %s\n", w)
+		}
+		sb.WriteString("\n")
+	}
+
+	if r.TotalLines == 0 {
+		sb.WriteString("No new or changed lines inside functions.\n")
+	} else {
+		fmt.Fprintf(&sb, "%d of %d new/changed lines in functions are covered", r.CoveredLines, r.TotalLines)
+		if r.ChangedFunctions > 0 {
+			fmt.Fprintf(&sb, "; %d of %d changed functions are 100%% covered", r.CoveredFunctions, r.ChangedFunctions)
+		}
+		sb.WriteString(".\n\n")
+		if s := r.FunctionScope; s != nil {
+			icon := ""
+			if !s.Passed {
+				icon = " ❌"
+			}
+			fmt.Fprintf(&sb, "Touched functions as a whole: %.2f%%%s (%d of %d lines covered, minimum %.2f%%", s.Coverage, icon, s.CoveredLines, s.TotalLines, s.MinCoverage)
+			if s.MaxAgeDays > 0 {
+				fmt.Fprintf(&sb, "; %d lines older than %d days not counted", s.OldLines, s.MaxAgeDays)
+			}
+			sb.WriteString(").\n\n")
+		}
+		sb.WriteString("| File | Covered | Coverage | Uncovered lines |\n")
+		sb.WriteString("|------|--------:|---------:|-----------------|\n")
+		for _, f := range r.Files {
+			fmt.Fprintf(&sb, "| `%s` | %d/%d | %.2f%% | %s |\n",
+				f.Path, f.CoveredLines, f.TotalLines, f.Coverage, FormatRanges(f.Uncovered))
+		}
+		if omitted > 0 {
+			fmt.Fprintf(&sb, "\n%d more files with fewer uncovered lines are not shown, to fit the size limit of comments.\n", omitted)
+		}
+	}
+
+	if t := r.Tests; t != nil && t.Failed > 0 {
+		fmt.Fprintf(&sb, "\n❌ The coverage produced by %d failing test packages is ignored: %s.\n", t.Failed, "`"+strings.Join(t.FailedPackages(), "`, `")+"`")
+	}
+
+	if len(r.Targets) > 0 {
+		sb.WriteString("\n| Target | Covered | Coverage |\n")
+		sb.WriteString("|--------|--------:|---------:|\n")
+		for _, t := range r.Targets {
+			icon := ""
+			if !t.Passed {
+				icon = " ❌"
+			}
+			grade := ""
+			if t.Grade != "" {
+				grade = " (" + t.Grade + ")"
+			}
+			fmt.Fprintf(&sb, "| `%s` | %d/%d | %.2f%%%s%s |\n", t.Name, t.CoveredLines, t.TotalLines, t.Coverage, grade, icon)
+		}
+	}
+
+	if len(r.Owners) > 0 {
+		sb.WriteString("\n| Owner | Covered | Coverage | Minimum |\n")
+		sb.WriteString("|-------|--------:|---------:|--------:|\n")
+		for _, o := range r.Owners {
+			minimum := "-"
+			if o.MinCoverage > 0 {
+				minimum = fmt.Sprintf("%.2f%%", o.MinCoverage)
+			}
+			icon := ""
+			if !o.Passed {
+				icon = " ❌"
+			}
+			fmt.Fprintf(&sb, "| %s | %d/%d | %.2f%%%s | %s |\n", o.Owner, o.CoveredLines, o.TotalLines, o.Coverage, icon, minimum)
+		}
+	}
+
+	if len(r.Exceptions) > 0 {
+		sb.WriteString("\n| Exception | Owner | Expires |\n")
+		sb.WriteString("|-----------|-------|---------|\n")
+		for _, e := range r.Exceptions {
+			expires := e.Expires
+			if e.Expired {
+				expires += " ❌ expired"
+			}
+			fmt.Fprintf(&sb, "| `%s` | %s | %s |\n", e, e.Owner, expires)
+		}
+	}
+
+	if len(r.Policies) > 0 {
+		sb.WriteString("\n| Policy | Result |\n")
+		sb.WriteString("|--------|--------|\n")
+		for _, p := range r.Policies {
+			result := "✅"
+			switch {
+			case p.Error != "":
+				result = "❌ " + p.Error
+			case !p.Passed:
+				result = "❌"
+			}
+			fmt.Fprintf(&sb, "| %s | %s |\n", strings.ReplaceAll(p.Name, "|", "\\|"), strings.ReplaceAll(result, "|", "\\|"))
+		}
+	}
+
+	if len(r.Uninstrumented) > 0 {
+		sb.WriteString("\n⚠️ **Packages not instrumented**\n\nThe profile has no coverage data for these packages, so their changed lines count as uncovered. Run the tests with `-coverpkg=./...` or include the packages' tests:\n\n")
+		for _, p := range r.Uninstrumented {
+			fmt.Fprintf(&sb, "- `./%s` (%d changed lines)\n", p.Package, p.TotalLines)
+		}
+	}
+
+	if len(r.FilesWithoutTests) > 0 {
+		icon := "⚠️"
+		if r.TestFilesRequired {
+			icon = "❌"
+		}
+		fmt.Fprintf(&sb, "\n%s **New files without tests**\n\nThese new files are in packages without any `_test.go` file:\n\n", icon)
+		for _, f := range r.FilesWithoutTests {
+			fmt.Fprintf(&sb, "- `%s`\n", f)
+		}
+	}
+
+	if len(r.UntestedChanges) > 0 {
+		sb.WriteString("\n⚠️ **Tests untouched for changed code**\n\nThe tests of the packages of these changed functions were not changed:\n\n")
+		for _, c := range r.UntestedChanges {
+			icon := ""
+			if c.Required {
+				icon = " ❌"
+			}
+			fmt.Fprintf(&sb, "- `%s` (`%s:%d`)%s\n", c.Func, c.File, c.Line, icon)
+		}
+	}
+
+	if len(r.Regressions) > 0 {
+		sb.WriteString("\n⚠️ **Coverage trend**\n\n")
+		for _, g := range r.Regressions {
+			fmt.Fprintf(&sb, "- %s\n", g)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\n<sub>go-new-code-coverage %s</sub>\n", r.ToolVersion)
+	return sb.String()
+}
+
+// Comment renders the body of a sticky review comment: the marker, the
+// summary and, when reportURL is set, a link to the full report.
+func Comment(r *diffcoverage.Report, reportURL string) string {
+	body, _ := TemplateComment(r, reportURL, nil)
+	return body
+}
+
+// TemplateComment is Comment with the summary rendered by t, if not nil;
+// the link to the full report is then up to the template.
+func TemplateComment(r *diffcoverage.Report, reportURL string, t *template.Template) (string, error) {
+	summary, err := render(t, r, reportURL, 0)
+	if err != nil {
+		return "", err
+	}
+	return commentBody(summary, reportURL, t), nil
+}
+
+// commentBody adds the marker and the link to the full report to summary.
+func commentBody(summary, reportURL string, t *template.Template) string {
+	body := Marker + "\n" + summary
+	if reportURL != "" && t == nil {
+		body += fmt.Sprintf("\n[Full report](%s)\n", reportURL)
+	}
+	return body
+}
@@ -0,0 +1,174 @@
+package reporter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// OTel exports the report as OTLP/HTTP JSON: a trace with a span per analysis
+// phase and gauges with the coverage results. It needs no SDK; any collector
+// accepting OTLP over HTTP works.
+type OTel struct {
+	Endpoint    string            // OTLP/HTTP base URL, e.g. http://collector:4318
+	Headers     map[string]string // e.g. authentication headers of a vendor backend
+	ServiceName string
+	// Attributes are added to every data point and the root span (repo, branch, commit).
+	Attributes map[string]string
+	Client     *http.Client
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// Publish sends the trace and the metrics.
+func (o *OTel) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if o.Endpoint == "" {
+		return fmt.Errorf("otel: endpoint is required")
+	}
+
+	header := http.Header{}
+	for k, v := range o.Headers {
+		header.Set(k, v)
+	}
+	base := strings.TrimSuffix(o.Endpoint, "/")
+
+	if len(r.Phases) > 0 {
+		if err := doJSON(ctx, o.Client, http.MethodPost, base+"/v1/traces", header, o.traces(r), nil); err != nil {
+			return fmt.Errorf("otel traces: %v", err)
+		}
+	}
+	if err := doJSON(ctx, o.Client, http.MethodPost, base+"/v1/metrics", header, o.metrics(r, time.Now()), nil); err != nil {
+		return fmt.Errorf("otel metrics: %v", err)
+	}
+	return nil
+}
+
+// traces builds an ExportTraceServiceRequest with a root span covering all phases.
+func (o *OTel) traces(r *diffcoverage.Report) map[string]any {
+	traceID, rootID := randomHex(16), randomHex(8)
+	attrs := append(o.attributes(),
+		otlpAttribute{Key: "diffcoverage.coverage", Value: map[string]any{"doubleValue": r.Coverage}},
+		otlpAttribute{Key: "diffcoverage.passed", Value: map[string]any{"boolValue": r.Passed}})
+
+	spans := []map[string]any{{
+		"traceId":           traceID,
+		"spanId":            rootID,
+		"name":              "diffcoverage",
+		"kind":              1,
+		"startTimeUnixNano": unixNano(r.Phases[0].Start),
+		"endTimeUnixNano":   unixNano(r.Phases[len(r.Phases)-1].End),
+		"attributes":        attrs,
+	}}
+	for _, p := range r.Phases {
+		spans = append(spans, map[string]any{
+			"traceId":           traceID,
+			"spanId":            randomHex(8),
+			"parentSpanId":      rootID,
+			"name":              "diffcoverage." + p.Name,
+			"kind":              1,
+			"startTimeUnixNano": unixNano(p.Start),
+			"endTimeUnixNano":   unixNano(p.End),
+		})
+	}
+
+	return map[string]any{"resourceSpans": []map[string]any{{
+		"resource":   o.resource(),
+		"scopeSpans": []map[string]any{{"scope": o.scope(r), "spans": spans}},
+	}}}
+}
+
+// metrics builds an ExportMetricsServiceRequest with the report's gauges.
+func (o *OTel) metrics(r *diffcoverage.Report, now time.Time) map[string]any {
+	passed := 0.0
+	if r.Passed {
+		passed = 1
+	}
+	gauge := func(name, unit, description string, value float64) map[string]any {
+		return map[string]any{
+			"name":        name,
+			"unit":        unit,
+			"description": description,
+			"gauge": map[string]any{"dataPoints": []map[string]any{{
+				"asDouble":     value,
+				"timeUnixNano": unixNano(now),
+				"attributes":   o.attributes(),
+			}}},
+		}
+	}
+	metrics := []map[string]any{
+		gauge("diffcoverage.coverage", "%", "Coverage of new/changed lines in functions.", r.Coverage),
+		gauge("diffcoverage.min_coverage", "%", "Minimum required diff coverage.", r.MinCoverage),
+		gauge("diffcoverage.changed_lines", "{line}", "New/changed lines in functions.", float64(r.TotalLines)),
+		gauge("diffcoverage.uncovered_lines", "{line}", "New/changed lines in functions not covered by tests.", float64(r.TotalLines-r.CoveredLines)),
+		gauge("diffcoverage.passed", "1", "1 if the diff coverage gate passed.", passed),
+	}
+
+	return map[string]any{"resourceMetrics": []map[string]any{{
+		"resource":     o.resource(),
+		"scopeMetrics": []map[string]any{{"scope": o.scope(r), "metrics": metrics}},
+	}}}
+}
+
+func (o *OTel) resource() map[string]any {
+	name := o.ServiceName
+	if name == "" {
+		name = "go-new-code-coverage"
+	}
+	return map[string]any{"attributes": []otlpAttribute{{Key: "service.name", Value: map[string]any{"stringValue": name}}}}
+}
+
+func (o *OTel) scope(r *diffcoverage.Report) map[string]any {
+	return map[string]any{"name": "github.com/JackShadow/go-new-code-coverage", "version": r.ToolVersion}
+}
+
+// attributes returns the configured attributes sorted by key, skipping empty values.
+func (o *OTel) attributes() []otlpAttribute {
+	attrs := []otlpAttribute{}
+	for k, v := range o.Attributes {
+		if v != "" {
+			attrs = append(attrs, otlpAttribute{Key: k, Value: map[string]any{"stringValue": v}})
+		}
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
+
+// ParseOTLPHeaders parses the OTEL_EXPORTER_OTLP_HEADERS format: comma-separated
+// key=value pairs with URL-encoded values.
+func ParseOTLPHeaders(s string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = unescaped
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// randomHex returns n random bytes hex-encoded, as used for trace and span IDs.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,105 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestOTel_Publish checks the trace and metrics requests.
+func TestOTel_Publish(t *testing.T) {
+	bodies := map[string]map[string]any{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Api-Key") != "k" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies[r.URL.Path] = body
+	}))
+	defer srv.Close()
+
+	start := time.Unix(100, 0)
+	r := sampleReport()
+	r.Phases = []diffcoverage.Phase{
+		{Name: "parse_cover", Start: start, End: start.Add(time.Millisecond)},
+		{Name: "parse_diff", Start: start.Add(time.Millisecond), End: start.Add(2 * time.Millisecond)},
+	}
+	o := &OTel{Endpoint: srv.URL + "/", Headers: map[string]string{"api-key": "k"}, Attributes: map[string]string{"repo": "octo/repo", "branch": ""}}
+	if err := o.Publish(context.Background(), r); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	var traces struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID, SpanID, ParentSpanID, Name string
+					StartTimeUnixNano, EndTimeUnixNano  string
+				}
+			}
+		}
+	}
+	remarshal(t, bodies["/v1/traces"], &traces)
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 || spans[0].Name != "diffcoverage" || spans[2].Name != "diffcoverage.parse_diff" {
+		t.Fatalf("Unexpected spans %+v", spans)
+	}
+	if spans[1].ParentSpanID != spans[0].SpanID || spans[1].TraceID != spans[0].TraceID || len(spans[0].TraceID) != 32 {
+		t.Errorf("Phase spans are not children of the root span: %+v", spans)
+	}
+	if spans[0].StartTimeUnixNano != "100000000000" || spans[0].EndTimeUnixNano != "100002000000" {
+		t.Errorf("Unexpected root span times %+v", spans[0])
+	}
+
+	var metrics struct {
+		ResourceMetrics []struct {
+			ScopeMetrics []struct {
+				Metrics []struct {
+					Name  string
+					Gauge struct {
+						DataPoints []struct {
+							AsDouble   float64
+							Attributes []otlpAttribute
+						}
+					}
+				}
+			}
+		}
+	}
+	remarshal(t, bodies["/v1/metrics"], &metrics)
+	m := metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(m) != 5 || m[0].Name != "diffcoverage.coverage" || m[0].Gauge.DataPoints[0].AsDouble != 50 {
+		t.Fatalf("Unexpected metrics %+v", m)
+	}
+	if attrs := m[0].Gauge.DataPoints[0].Attributes; len(attrs) != 1 || attrs[0].Key != "repo" {
+		t.Errorf("Expected only the non-empty repo attribute, got %+v", attrs)
+	}
+}
+
+// TestParseOTLPHeaders covers the environment variable format.
+func TestParseOTLPHeaders(t *testing.T) {
+	got := ParseOTLPHeaders("api-key=abc, authorization=Basic%20dXNlcg==,broken")
+	want := map[string]string{"api-key": "abc", "authorization": "Basic dXNlcg=="}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseOTLPHeaders = %v, want %v", got, want)
+	}
+}
+
+func remarshal(t *testing.T, in, out any) {
+	t.Helper()
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,89 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/parquet"
+)
+
+// Parquet writes the per-file and per-line results as Parquet files,
+// files.parquet and lines.parquet in Dir, for loading into a data warehouse.
+// Every row carries the repository, branch, commit and time of the run.
+type Parquet struct {
+	Dir    string
+	Repo   string
+	Branch string
+	Commit string
+	Now    func() time.Time
+}
+
+// Publish writes the files.
+func (p *Parquet) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	now := time.Now()
+	if p.Now != nil {
+		now = p.Now()
+	}
+	run := func(cols []parquet.Column, n int) []parquet.Column {
+		repo, branch, commit, times := make([]string, n), make([]string, n), make([]string, n), make([]time.Time, n)
+		for i := 0; i < n; i++ {
+			repo[i], branch[i], commit[i], times[i] = p.Repo, p.Branch, p.Commit, now
+		}
+		return append([]parquet.Column{
+			parquet.String("repo", repo), parquet.String("branch", branch),
+			parquet.String("commit", commit), parquet.Timestamp("run_time", times),
+		}, cols...)
+	}
+
+	var (
+		paths               []string
+		total, covered      []int32
+		coverage            []float64
+		linePaths, statuses []string
+		lines               []int32
+	)
+	for _, f := range r.Files {
+		paths = append(paths, f.Path)
+		total = append(total, int32(f.TotalLines))
+		covered = append(covered, int32(f.CoveredLines))
+		coverage = append(coverage, f.Coverage)
+		for _, status := range []struct {
+			name   string
+			ranges [][2]int
+		}{{"covered", f.Covered}, {"uncovered", f.Uncovered}} {
+			for _, rng := range status.ranges {
+				for line := rng[0]; line <= rng[1]; line++ {
+					linePaths, lines, statuses = append(linePaths, f.Path), append(lines, int32(line)), append(statuses, status.name)
+				}
+			}
+		}
+	}
+
+	tables := map[string][]parquet.Column{
+		"files.parquet": run([]parquet.Column{
+			parquet.String("path", paths), parquet.Int32("total_lines", total),
+			parquet.Int32("covered_lines", covered), parquet.Double("coverage", coverage),
+		}, len(paths)),
+		"lines.parquet": run([]parquet.Column{
+			parquet.String("path", linePaths), parquet.Int32("line", lines), parquet.String("status", statuses),
+		}, len(lines)),
+	}
+	if err := os.MkdirAll(p.Dir, 0o755); err != nil {
+		return fmt.Errorf("parquet: %v", err)
+	}
+	for name, cols := range tables {
+		var buf bytes.Buffer
+		if err := parquet.Write(&buf, "go-new-code-coverage "+r.ToolVersion, cols...); err != nil {
+			return fmt.Errorf("parquet: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(p.Dir, name), buf.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("parquet: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParquet_Publish writes both tables.
+func TestParquet_Publish(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	r := sampleReport()
+	r.Files[0].Covered = [][2]int{{4, 5}}
+	if err := (&Parquet{Dir: dir, Repo: "octo/repo", Branch: "main", Commit: "abc"}).Publish(context.Background(), r); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	for _, name := range []string{"files.parquet", "lines.parquet"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) || !bytes.Contains(data, []byte("pkg/a.go")) || !bytes.Contains(data, []byte("octo/repo")) {
+			t.Errorf("%s is not the expected Parquet file", name)
+		}
+	}
+	lines, _ := os.ReadFile(filepath.Join(dir, "lines.parquet"))
+	if !bytes.Contains(lines, []byte("uncovered")) || !bytes.Contains(lines, []byte("status")) {
+		t.Errorf("lines.parquet lacks the status column")
+	}
+}
@@ -0,0 +1,146 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// PushgatewayJob is the job label of the pushed metric group.
+const PushgatewayJob = "diffcoverage"
+
+// Pushgateway pushes the report as Prometheus metrics. The group is keyed by
+// repository and branch, so each push replaces the branch's previous metrics.
+type Pushgateway struct {
+	URL    string // e.g. http://pushgateway:9091
+	Repo   string
+	Branch string
+	Commit string // added as a label on every metric
+	Client *http.Client
+}
+
+// Publish replaces the metric group with the report's metrics.
+func (p *Pushgateway) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if p.URL == "" {
+		return fmt.Errorf("pushgateway: URL is required")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.groupURL(), bytes.NewReader(p.metrics(r)))
+	if err != nil {
+		return fmt.Errorf("pushgateway: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := p.Client
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushgateway: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// groupURL builds the grouping key path; values use the base64 form so that
+// slashes in repository and branch names survive.
+func (p *Pushgateway) groupURL() string {
+	u := strings.TrimSuffix(p.URL, "/") + "/metrics/job/" + PushgatewayJob
+	for _, l := range [][2]string{{"repo", p.Repo}, {"branch", p.Branch}} {
+		if l[1] == "" {
+			continue
+		}
+		u += "/" + l[0] + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(l[1]))
+	}
+	return u
+}
+
+// metrics renders the report in the Prometheus text exposition format.
+func (p *Pushgateway) metrics(r *diffcoverage.Report) []byte {
+	var buf bytes.Buffer
+	labels := ""
+	if p.Commit != "" {
+		labels = fmt.Sprintf(`commit="%s"`, escapeLabel(p.Commit))
+	}
+	gauge := func(name, help, labels string, value float64) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		writeSample(&buf, name, labels, value)
+	}
+
+	passed := 0.0
+	if r.Passed {
+		passed = 1
+	}
+	gauge("diffcoverage_coverage_percent", "Coverage of new/changed lines in functions.", labels, r.Coverage)
+	gauge("diffcoverage_min_coverage_percent", "Minimum required diff coverage.", labels, r.MinCoverage)
+	gauge("diffcoverage_changed_lines", "New/changed lines in functions.", labels, float64(r.TotalLines))
+	gauge("diffcoverage_uncovered_lines", "New/changed lines in functions not covered by tests.", labels, float64(r.TotalLines-r.CoveredLines))
+	gauge("diffcoverage_passed", "1 if the diff coverage gate passed.", labels, passed)
+
+	pkgs := packageCoverage(r)
+	if len(pkgs) > 0 {
+		const name = "diffcoverage_package_coverage_percent"
+		fmt.Fprintf(&buf, "# HELP %s Coverage of new/changed lines in functions per package directory.\n# TYPE %s gauge\n", name, name)
+		for _, pkg := range pkgs {
+			l := fmt.Sprintf(`package="%s"`, escapeLabel(pkg.dir))
+			if labels != "" {
+				l = labels + "," + l
+			}
+			writeSample(&buf, name, l, pkg.coverage)
+		}
+	}
+	return buf.Bytes()
+}
+
+// pkgCoverage is the diff coverage of one package directory.
+type pkgCoverage struct {
+	dir      string
+	coverage float64
+}
+
+// packageCoverage aggregates the file reports by directory, sorted by directory.
+func packageCoverage(r *diffcoverage.Report) []pkgCoverage {
+	totals := map[string][2]int{}
+	for _, f := range r.Files {
+		t := totals[path.Dir(f.Path)]
+		totals[path.Dir(f.Path)] = [2]int{t[0] + f.CoveredLines, t[1] + f.TotalLines}
+	}
+	pkgs := make([]pkgCoverage, 0, len(totals))
+	for dir, t := range totals {
+		coverage := 100.0
+		if t[1] > 0 {
+			coverage = float64(t[0]) / float64(t[1]) * 100
+		}
+		pkgs = append(pkgs, pkgCoverage{dir: dir, coverage: coverage})
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].dir < pkgs[j].dir })
+	return pkgs
+}
+
+func writeSample(buf *bytes.Buffer, name, labels string, value float64) {
+	if labels != "" {
+		fmt.Fprintf(buf, "%s{%s} %g\n", name, labels, value)
+	} else {
+		fmt.Fprintf(buf, "%s %g\n", name, value)
+	}
+}
+
+// escapeLabel escapes a Prometheus label value.
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
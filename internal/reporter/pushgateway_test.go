@@ -0,0 +1,62 @@
+package reporter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestPushgateway_Publish checks the grouping key and the exposition format.
+func TestPushgateway_Publish(t *testing.T) {
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(data)
+	}))
+	defer srv.Close()
+
+	r := sampleReport()
+	r.Files = append(r.Files, diffcoverage.FileReport{Path: "pkg/b.go", TotalLines: 4, CoveredLines: 4, Coverage: 100},
+		diffcoverage.FileReport{Path: "cmd/main.go", TotalLines: 1, CoveredLines: 1, Coverage: 100})
+
+	p := &Pushgateway{URL: srv.URL + "/", Repo: "octo/repo", Branch: "feature/x", Commit: "abc"}
+	if err := p.Publish(context.Background(), r); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/diffcoverage/repo@base64/b2N0by9yZXBv/branch@base64/ZmVhdHVyZS94" {
+		t.Errorf("Unexpected request %s %s", method, path)
+	}
+	for _, want := range []string{
+		"# TYPE diffcoverage_coverage_percent gauge\n",
+		`diffcoverage_coverage_percent{commit="abc"} 50` + "\n",
+		`diffcoverage_uncovered_lines{commit="abc"} 2` + "\n",
+		`diffcoverage_passed{commit="abc"} 0` + "\n",
+		`diffcoverage_package_coverage_percent{commit="abc",package="cmd"} 100` + "\n" +
+			`diffcoverage_package_coverage_percent{commit="abc",package="pkg"} 75` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in body:\n%s", want, body)
+		}
+	}
+}
+
+// TestPushgateway_PublishError covers a missing URL and a rejected push.
+func TestPushgateway_PublishError(t *testing.T) {
+	if err := (&Pushgateway{}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error without URL, got nil")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metrics", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	err := (&Pushgateway{URL: srv.URL}).Publish(context.Background(), sampleReport())
+	if err == nil || !strings.Contains(err.Error(), "bad metrics") {
+		t.Errorf("Expected push error, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+	"github.com/JackShadow/go-new-code-coverage/lineranges"
+)
+
+// Reporter publishes a report to an external system.
+type Reporter interface {
+	Publish(ctx context.Context, r *diffcoverage.Report) error
+}
+
+// FormatRanges renders line ranges as "3, 7-9".
+func FormatRanges(ranges [][2]int) string {
+	return lineranges.Format(ranges)
+}
+
+// gradeNote returns ", grade X" for a graded report, or "".
+func gradeNote(r *diffcoverage.Report) string {
+	if r.Grade == "" {
+		return ""
+	}
+	return ", grade " + r.Grade
+}
+
+// doJSON sends in (if non-nil) as JSON and decodes the response into out (if non-nil).
+func doJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{method: method, url: url, code: resp.StatusCode, status: resp.Status, body: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: error decoding response: %v", method, url, err)
+	}
+	return nil
+}
+
+// statusError is returned by doJSON for non-2xx responses.
+type statusError struct {
+	method, url  string
+	code         int
+	status, body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %s: %s", e.method, e.url, e.status, e.body)
+}
+
+// isNotFound reports whether err is a 404 response from doJSON.
+func isNotFound(err error) bool {
+	se, ok := err.(*statusError)
+	return ok && se.code == http.StatusNotFound
+}
@@ -0,0 +1,160 @@
+package reporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// sampleReport returns a failing report with one partially covered file.
+func sampleReport() *diffcoverage.Report {
+	return &diffcoverage.Report{
+		ToolVersion:  "v1.0.0",
+		Coverage:     50,
+		MinCoverage:  80,
+		TotalLines:   4,
+		CoveredLines: 2,
+		Files: []diffcoverage.FileReport{
+			{Path: "pkg/a.go", TotalLines: 4, CoveredLines: 2, Coverage: 50, Uncovered: [][2]int{{6, 7}, {9, 9}}},
+		},
+	}
+}
+
+// TestFormatRanges covers single lines and spans.
+func TestFormatRanges(t *testing.T) {
+	if got := FormatRanges([][2]int{{3, 3}, {7, 9}}); got != "3, 7-9" {
+		t.Errorf("FormatRanges = %q", got)
+	}
+	if got := FormatRanges(nil); got != "" {
+		t.Errorf("FormatRanges(nil) = %q", got)
+	}
+}
+
+// TestMarkdown checks the summary, table and footer.
+func TestMarkdown(t *testing.T) {
+	md := Markdown(sampleReport())
+	for _, want := range []string{
+		"### ❌ Diff coverage: 50.00% (minimum 80.00%)",
+		"2 of 4 new/changed lines in functions are covered.",
+		"| `pkg/a.go` | 2/4 | 50.00% | 6-7, 9 |",
+		"go-new-code-coverage v1.0.0",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+
+	md = Markdown(&diffcoverage.Report{Coverage: 100, Passed: true})
+	if !strings.Contains(md, "✅") || !strings.Contains(md, "No new or changed lines") {
+		t.Errorf("Unexpected markdown for empty report:\n%s", md)
+	}
+	if strings.Contains(md, "Coverage trend") {
+		t.Errorf("Unexpected trend section without regressions:\n%s", md)
+	}
+}
+
+// TestMarkdown_Owners adds a table of the coverage per owner.
+func TestMarkdown_Owners(t *testing.T) {
+	r := sampleReport()
+	r.Owners = []diffcoverage.OwnerReport{
+		{Owner: "@org/app", TotalLines: 2, CoveredLines: 2, Coverage: 100, Passed: true},
+		{Owner: "@org/platform", TotalLines: 4, CoveredLines: 2, Coverage: 50, MinCoverage: 90},
+	}
+	md := Markdown(r)
+	for _, want := range []string{"| @org/app | 2/2 | 100.00% | - |", "| @org/platform | 2/4 | 50.00% ❌ | 90.00% |"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+// TestMarkdown_Regressions lists declining trends in a warning section.
+func TestMarkdown_Regressions(t *testing.T) {
+	r := sampleReport()
+	r.Regressions = []diffcoverage.Regression{{Metric: "project", From: 80, To: 75.5, Runs: 3}}
+	md := Markdown(r)
+	for _, want := range []string{"**Coverage trend**", "- project coverage declined from 80.00% to 75.50% over the last 3 runs"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+// TestDoJSON_Error checks non-2xx responses include the status and body.
+func TestDoJSON_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad credentials", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	err := doJSON(context.Background(), nil, http.MethodGet, srv.URL, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "bad credentials") {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+// TestMarkdown_FilesWithoutTests lists new files without tests, failing when required.
+func TestMarkdown_FilesWithoutTests(t *testing.T) {
+	r := sampleReport()
+	r.ApplyTestFiles([]string{"pkg/new.go"}, false)
+	md := Markdown(r)
+	for _, want := range []string{"⚠️ **New files without tests**", "- `pkg/new.go`"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+
+	r.ApplyTestFiles([]string{"pkg/new.go"}, true)
+	if r.Passed || r.Err() == nil || !strings.Contains(Markdown(r), "❌ **New files without tests**") {
+		t.Errorf("Expected required test files to fail the report, got %v", r.Err())
+	}
+}
+
+// TestMarkdown_UntestedChanges lists changed functions without test changes.
+func TestMarkdown_UntestedChanges(t *testing.T) {
+	r := sampleReport()
+	r.UntestedChanges = []diffcoverage.UntestedChange{{File: "pkg/a.go", Line: 3, Func: "A", Required: true}}
+	md := Markdown(r)
+	for _, want := range []string{"**Tests untouched for changed code**", "- `A` (`pkg/a.go:3`) ❌"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+// TestMarkdown_Policies adds a table of the policy results.
+func TestMarkdown_Policies(t *testing.T) {
+	r := sampleReport()
+	r.Policies = []diffcoverage.PolicyResult{
+		{Name: "billing", Passed: true},
+		{Name: "a || b", Error: "undeclared reference to a"},
+	}
+	md := Markdown(r)
+	for _, want := range []string{"| billing | ✅ |", "| a \\|\\| b | ❌ undeclared reference to a |"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+// TestMarkdown_Exceptions lists applied and expired exceptions.
+func TestMarkdown_Exceptions(t *testing.T) {
+	r := sampleReport()
+	r.ApplyExceptions([]diffcoverage.Exception{
+		{Path: "legacy/**", Owner: "@org/data", Expires: "2030-01-31"},
+		{Path: "pkg/a.go", Func: "Parse", Owner: "@org/app", Expires: "2020-01-31", Expired: true},
+	})
+	md := Markdown(r)
+	for _, want := range []string{"| `legacy/**` | @org/data | 2030-01-31 |", "| `pkg/a.go: Parse` | @org/app | 2020-01-31 ❌ expired |"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "coverage exception for pkg/a.go: Parse owned by @org/app expired on 2020-01-31") {
+		t.Errorf("Unexpected Err() %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/attest"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// ReportFile writes the JSON report to Path and, when Key is set, its
+// detached signature to Path + attest.SignatureSuffix, to be checked with the
+// verify command.
+type ReportFile struct {
+	Path string
+	Key  *attest.Key
+}
+
+// Publish writes the report.
+func (f *ReportFile) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("report-file: %v", err)
+	}
+	if err := os.WriteFile(f.Path, data, 0644); err != nil {
+		return fmt.Errorf("report-file: %v", err)
+	}
+	if f.Key == nil {
+		return nil
+	}
+	sig, err := f.Key.Sign(data)
+	if err != nil {
+		return fmt.Errorf("report-file: error signing: %v", err)
+	}
+	if err := os.WriteFile(f.Path+attest.SignatureSuffix, []byte(sig+"\n"), 0644); err != nil {
+		return fmt.Errorf("report-file: %v", err)
+	}
+	return nil
+}
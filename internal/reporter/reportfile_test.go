@@ -0,0 +1,32 @@
+package reporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/attest"
+)
+
+// TestReportFile_Publish writes the report and a signature only with a key.
+func TestReportFile_Publish(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.json")
+	if err := (&ReportFile{Path: path}).Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if _, err := os.Stat(path + ".sig"); !os.IsNotExist(err) {
+		t.Errorf("Expected no signature without a key, got %v", err)
+	}
+
+	key := attest.Secret("s3cret")
+	if err := (&ReportFile{Path: path, Key: key}).Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	sig, _ := os.ReadFile(path + ".sig")
+	if err := key.Verify(data, string(sig)); err != nil {
+		t.Errorf("Signature does not verify: %v", err)
+	}
+}
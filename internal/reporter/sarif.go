@@ -0,0 +1,106 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// sarifRuleID identifies the results of uncovered lines.
+const sarifRuleID = "uncovered-line"
+
+// The subset of SARIF 2.1.0 written by WriteSARIF.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version,omitempty"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+		FullDescription  sarifMessage `json:"fullDescription"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+	sarifArtifactLocation struct {
+		URI       string `json:"uri"`
+		URIBaseID string `json:"uriBaseId"`
+	}
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+)
+
+// WriteSARIF writes the report as a SARIF 2.1.0 log, which GitHub code
+// scanning shows as alerts: a result per uncovered new/changed line, at
+// level error if the report failed and warning otherwise. Paths are relative
+// to the source root (%SRCROOT%).
+func WriteSARIF(w io.Writer, r *diffcoverage.Report) error {
+	level := "warning"
+	if !r.Passed {
+		level = "error"
+	}
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "diffcoverage",
+			Version:        r.ToolVersion,
+			InformationURI: "https://github.com/JackShadow/go-new-code-coverage",
+			Rules: []sarifRule{{
+				ID:               sarifRuleID,
+				ShortDescription: sarifMessage{"New/changed line not covered by tests"},
+				FullDescription:  sarifMessage{"The line is new or changed in the diff and the tests do not run it."},
+			}},
+		}},
+		Results: []sarifResult{},
+	}
+	for _, f := range r.Files {
+		for _, line := range f.UncoveredLines() {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  sarifRuleID,
+				Level:   level,
+				Message: sarifMessage{fmt.Sprintf("Line %d is not covered by tests (%s: %d of %d changed lines covered)", line, f.Path, f.CoveredLines, f.TotalLines)},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: f.Path, URIBaseID: "%SRCROOT%"},
+					Region:           sarifRegion{StartLine: line},
+				}}},
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
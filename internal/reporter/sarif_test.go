@@ -0,0 +1,30 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestWriteSARIF reports each uncovered line as a result at its region.
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, sampleReport()); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected log %+v", log)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3: %+v", len(results), results)
+	}
+	loc := results[2].Locations[0].PhysicalLocation
+	if results[2].Level != "error" || results[2].RuleID != sarifRuleID || loc.ArtifactLocation.URI != "pkg/a.go" || loc.Region.StartLine != 9 {
+		t.Errorf("Unexpected result %+v", results[2])
+	}
+}
@@ -0,0 +1,71 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// Server uploads the run to the history of a serve instance aggregating
+// the runs of many repositories, signed with Secret (see SignatureHeader).
+type Server struct {
+	URL    string // base URL of the server; runs are posted to URL/api/runs
+	Secret string
+	Repo   string
+	Team   string
+	Branch string
+	Commit string
+	PR     string
+	Client *http.Client
+	Now    func() time.Time
+}
+
+// Publish uploads the run.
+func (s *Server) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if s.URL == "" || s.Secret == "" {
+		return fmt.Errorf("server: a URL and a secret are required")
+	}
+	if s.Repo == "" {
+		return fmt.Errorf("server: the repository is required")
+	}
+	rec := &history.Record{Repo: s.Repo, Team: s.Team, Branch: s.Branch, Commit: s.Commit, PR: s.PR, Report: r}
+	rec.Time = time.Now().UTC()
+	if s.Now != nil {
+		rec.Time = s.Now()
+	}
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("server: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.URL, "/")+"/api/runs", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("server: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-new-code-coverage")
+	req.Header.Set(SignatureHeader, Sign(body, s.Secret))
+	client := s.Client
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("server: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("server: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// Commit status providers supported by CommitStatus.
+const (
+	StatusGitHub    = "github"
+	StatusGitLab    = "gitlab"
+	StatusBitbucket = "bitbucket"
+)
+
+// StatusContext is the default name of the commit status.
+const StatusContext = "diffcoverage"
+
+// CommitStatus sets a pass/fail commit status with the coverage in its
+// description, without commenting on the pull request.
+type CommitStatus struct {
+	Provider string // StatusGitHub, StatusGitLab or StatusBitbucket
+	APIURL   string // defaults to the provider's cloud API
+	Repo     string // owner/name, group/project or workspace/repo_slug
+	Commit   string
+	Token    string
+	// Username and Password (an app password) authenticate to Bitbucket
+	// instead of Token when set.
+	Username  string
+	Password  string
+	Context   string // status name; StatusContext if empty
+	TargetURL string // linked from the status, if set
+	Client    *http.Client
+}
+
+// Publish posts the status.
+func (s *CommitStatus) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if s.Repo == "" || s.Commit == "" || (s.Token == "" && s.Username == "") {
+		return fmt.Errorf("%s status: repository, commit and credentials are required", s.Provider)
+	}
+
+	name := s.Context
+	if name == "" {
+		name = StatusContext
+	}
+	description := fmt.Sprintf("%.2f%% of new/changed lines covered (minimum %.2f%%%s)", r.Coverage, r.MinCoverage, gradeNote(r))
+
+	var (
+		endpoint string
+		body     any
+		header   = http.Header{"Authorization": {"Bearer " + s.Token}}
+	)
+	switch s.Provider {
+	case StatusGitHub:
+		endpoint = fmt.Sprintf("%s/repos/%s/statuses/%s", s.apiURL("https://api.github.com"), s.Repo, s.Commit)
+		body = map[string]string{
+			"state":       passFail(r, "success", "failure"),
+			"description": description,
+			"context":     name,
+			"target_url":  s.TargetURL,
+		}
+	case StatusGitLab:
+		endpoint = fmt.Sprintf("%s/projects/%s/statuses/%s", s.apiURL("https://gitlab.com/api/v4"), url.PathEscape(s.Repo), s.Commit)
+		body = map[string]string{
+			"state":       passFail(r, "success", "failed"),
+			"description": description,
+			"name":        name,
+			"target_url":  s.TargetURL,
+		}
+		header = http.Header{"Private-Token": {s.Token}}
+	case StatusBitbucket:
+		endpoint = fmt.Sprintf("%s/repositories/%s/commit/%s/statuses/build", s.apiURL("https://api.bitbucket.org/2.0"), s.Repo, s.Commit)
+		target := s.TargetURL
+		if target == "" {
+			// Bitbucket requires a URL; fall back to the commit page.
+			target = fmt.Sprintf("https://bitbucket.org/%s/commits/%s", s.Repo, s.Commit)
+		}
+		body = map[string]string{
+			"key":         name,
+			"name":        name,
+			"state":       passFail(r, "SUCCESSFUL", "FAILED"),
+			"description": description,
+			"url":         target,
+		}
+		if s.Username != "" {
+			req := &http.Request{Header: http.Header{}}
+			req.SetBasicAuth(s.Username, s.Password)
+			header = req.Header
+		}
+	default:
+		return fmt.Errorf("commit status: unknown provider %q", s.Provider)
+	}
+
+	if err := doJSON(ctx, s.Client, http.MethodPost, endpoint, header, body, nil); err != nil {
+		return fmt.Errorf("%s status: %v", s.Provider, err)
+	}
+	return nil
+}
+
+func (s *CommitStatus) apiURL(def string) string {
+	if s.APIURL == "" {
+		return def
+	}
+	return strings.TrimSuffix(s.APIURL, "/")
+}
+
+// passFail returns pass or fail depending on the gate result.
+func passFail(r *diffcoverage.Report, pass, fail string) string {
+	if r.Passed {
+		return pass
+	}
+	return fail
+}
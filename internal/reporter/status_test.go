@@ -0,0 +1,76 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCommitStatus_Publish checks the endpoint, authentication and state per provider.
+func TestCommitStatus_Publish(t *testing.T) {
+	tests := []struct {
+		provider string
+		status   CommitStatus
+		path     string
+		auth     string
+		want     map[string]string
+	}{
+		{StatusGitHub, CommitStatus{Repo: "octo/repo", Token: "t"}, "/repos/octo/repo/statuses/abc", "Bearer t",
+			map[string]string{"state": "failure", "context": "diffcoverage"}},
+		{StatusGitLab, CommitStatus{Repo: "group/project", Token: "t", Context: "coverage"}, "/projects/group%2Fproject/statuses/abc", "",
+			map[string]string{"state": "failed", "name": "coverage"}},
+		{StatusBitbucket, CommitStatus{Repo: "team/repo", Username: "bot", Password: "pw"}, "/repositories/team/repo/commit/abc/statuses/build", "Basic Ym90OnB3",
+			map[string]string{"state": "FAILED", "key": "diffcoverage", "url": "https://bitbucket.org/team/repo/commits/abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			var (
+				path, auth, token string
+				body              map[string]string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, auth, token = r.URL.EscapedPath(), r.Header.Get("Authorization"), r.Header.Get("Private-Token")
+				json.NewDecoder(r.Body).Decode(&body)
+			}))
+			defer srv.Close()
+
+			s := tt.status
+			s.Provider, s.APIURL, s.Commit = tt.provider, srv.URL, "abc"
+			if err := s.Publish(context.Background(), sampleReport()); err != nil {
+				t.Fatalf("Publish failed: %v", err)
+			}
+			if path != tt.path {
+				t.Errorf("path = %q, want %q", path, tt.path)
+			}
+			if tt.provider == StatusGitLab {
+				if token != "t" {
+					t.Errorf("Expected Private-Token header, got %q", token)
+				}
+			} else if auth != tt.auth {
+				t.Errorf("Authorization = %q, want %q", auth, tt.auth)
+			}
+			for k, v := range tt.want {
+				if body[k] != v {
+					t.Errorf("%s = %q, want %q", k, body[k], v)
+				}
+			}
+			if !strings.Contains(body["description"], "50.00%") {
+				t.Errorf("Expected the coverage in the description, got %q", body["description"])
+			}
+		})
+	}
+}
+
+// TestCommitStatus_PublishErrors covers missing settings and unknown providers.
+func TestCommitStatus_PublishErrors(t *testing.T) {
+	if err := (&CommitStatus{Provider: StatusGitHub, Repo: "octo/repo"}).Publish(context.Background(), sampleReport()); err == nil {
+		t.Errorf("Expected error for missing settings, got nil")
+	}
+	s := &CommitStatus{Provider: "svn", Repo: "octo/repo", Commit: "abc", Token: "t"}
+	if err := s.Publish(context.Background(), sampleReport()); err == nil || !strings.Contains(err.Error(), "unknown provider") {
+		t.Errorf("Expected unknown provider error, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TemplateData is what comment templates are executed with.
+type TemplateData struct {
+	Report    *diffcoverage.Report
+	ReportURL string
+	// Summary is the built-in Markdown summary, for templates that only add
+	// to it.
+	Summary string
+	// Omitted is the number of files left out of Report.Files to fit the size
+	// limit of comments (see FitComment).
+	Omitted int
+}
+
+// templateFuncs are the functions available to comment templates besides
+// the text/template built-ins.
+var templateFuncs = template.FuncMap{
+	"ranges":  FormatRanges,
+	"percent": func(v float64) string { return fmt.Sprintf("%.2f%%", v) },
+	"join":    strings.Join,
+}
+
+// ParseTemplate parses the Go text/template of comments at path.
+func ParseTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading comment template: %v", err)
+	}
+	t, err := template.New(path).Funcs(templateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing comment template: %v", err)
+	}
+	return t, nil
+}
+
+// render executes t, or returns the built-in summary if t is nil. omitted is
+// the number of files left out of r.
+func render(t *template.Template, r *diffcoverage.Report, reportURL string, omitted int) (string, error) {
+	if t == nil {
+		return markdown(r, omitted), nil
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, TemplateData{Report: r, ReportURL: reportURL, Summary: markdown(r, omitted), Omitted: omitted}); err != nil {
+		return "", fmt.Errorf("error executing comment template: %v", err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,44 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTemplateComment renders a custom template after the marker.
+func TestTemplateComment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "comment.tmpl")
+	tmpl := `Couverture : {{percent .Report.Coverage}}
+{{range .Report.Files}}{{if .Uncovered}}- {{.Path}} : {{ranges .Uncovered}}
+{{end}}{{end}}[Rapport]({{.ReportURL}})`
+	if err := os.WriteFile(path, []byte(tmpl), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := TemplateComment(sampleReport(), "https://ci/report", parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Marker + "\nCouverture : 50.00%\n- pkg/a.go : 6-7, 9\n[Rapport](https://ci/report)"
+	if got != want {
+		t.Errorf("TemplateComment = %q, want %q", got, want)
+	}
+
+	if def, _ := TemplateComment(sampleReport(), "https://ci/report", nil); def != Comment(sampleReport(), "https://ci/report") || !strings.Contains(def, "[Full report]") {
+		t.Errorf("default comment = %q", def)
+	}
+	if err := os.WriteFile(path, []byte("{{.Nope}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if parsed, err = ParseTemplate(path); err == nil {
+		_, err = TemplateComment(sampleReport(), "", parsed)
+	}
+	if err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+}
@@ -0,0 +1,129 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// The size limits of the comments and report details the code hosts accept,
+// in bytes; GitHub and Gitea count characters, which are never more.
+const (
+	GitHubCommentLimit    = 65536
+	GitLabNoteLimit       = 1000000
+	GiteaCommentLimit     = 65535
+	BitbucketDetailsLimit = 2000
+)
+
+// limit returns maxBytes, or def if it is 0.
+func limit(maxBytes, def int) int {
+	if maxBytes == 0 {
+		return def
+	}
+	return maxBytes
+}
+
+// FitComment is TemplateComment at most maxBytes long; 0 is no limit. When
+// the comment is too long, the files with the fewest uncovered lines are left
+// out of the report it renders, and if the summary alone is still too long,
+// it is cut off with a note linking reportURL.
+func FitComment(r *diffcoverage.Report, reportURL string, t *template.Template, maxBytes int) (string, error) {
+	return fit(r, reportURL, t, maxBytes, func(summary string) string {
+		return commentBody(summary, reportURL, t)
+	})
+}
+
+// fitDetails renders the summary by t, if set, at most maxBytes long, like
+// FitComment without the marker and link.
+func fitDetails(r *diffcoverage.Report, reportURL string, t *template.Template, maxBytes int) (string, error) {
+	return fit(r, reportURL, t, maxBytes, func(summary string) string { return summary })
+}
+
+// fit renders the summary of r, wrapped by body, at most maxBytes long.
+func fit(r *diffcoverage.Report, reportURL string, t *template.Template, maxBytes int, body func(summary string) string) (string, error) {
+	// sized renders r with only the worst n files.
+	worst := worstFiles(r.Files)
+	sized := func(n int) (string, error) {
+		keep := make(map[int]bool, n)
+		for _, i := range worst[:n] {
+			keep[i] = true
+		}
+		kept := *r
+		kept.Files = nil
+		for i, f := range r.Files {
+			if keep[i] {
+				kept.Files = append(kept.Files, f)
+			}
+		}
+		summary, err := render(t, &kept, reportURL, len(r.Files)-n)
+		if err != nil {
+			return "", err
+		}
+		return body(summary), nil
+	}
+
+	full, err := sized(len(r.Files))
+	if err != nil || maxBytes <= 0 || len(full) <= maxBytes {
+		return full, err
+	}
+	// The largest number of files that fits, by binary search.
+	var fitErr error
+	n := sort.Search(len(r.Files), func(n int) bool {
+		s, err := sized(n + 1)
+		if err != nil {
+			fitErr = err
+		}
+		return len(s) > maxBytes
+	})
+	if fitErr != nil {
+		return "", fitErr
+	}
+	s, err := sized(n)
+	if err != nil || len(s) <= maxBytes {
+		return s, err
+	}
+	return truncate(s, reportURL, maxBytes), nil
+}
+
+// worstFiles returns the indexes of files from the most uncovered lines,
+// then the lowest coverage, then by path.
+func worstFiles(files []diffcoverage.FileReport) []int {
+	idx := make([]int, len(files))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		a, b := &files[idx[i]], &files[idx[j]]
+		if ua, ub := a.TotalLines-a.CoveredLines, b.TotalLines-b.CoveredLines; ua != ub {
+			return ua > ub
+		}
+		if a.Coverage != b.Coverage {
+			return a.Coverage < b.Coverage
+		}
+		return a.Path < b.Path
+	})
+	return idx
+}
+
+// truncate cuts s at a line boundary so that it and a note on the cut are at
+// most maxBytes long.
+func truncate(s, reportURL string, maxBytes int) string {
+	note := "\n… cut off to fit the size limit of comments"
+	if reportURL != "" {
+		note += fmt.Sprintf("; see the [full report](%s)", reportURL)
+	}
+	note += ".\n"
+	cut := max(maxBytes-len(note), 0)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	s = s[:cut]
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return s + note
+}
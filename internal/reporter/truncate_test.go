@@ -0,0 +1,44 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestFitComment keeps the worst files within the limit and cuts off
+// summaries that do not fit even without files.
+func TestFitComment(t *testing.T) {
+	r := &diffcoverage.Report{ToolVersion: "v1.0.0", TotalLines: 1000, CoveredLines: 500, Coverage: 50}
+	for i := 0; i < 100; i++ {
+		r.Files = append(r.Files, diffcoverage.FileReport{
+			Path: fmt.Sprintf("pkg/f%02d.go", i), TotalLines: 10, CoveredLines: 10 - i%10, Coverage: float64(100 - 10*(i%10)),
+			Uncovered: [][2]int{{1, i % 10}},
+		})
+	}
+	full := Comment(r, "https://ci/report")
+	if got, _ := FitComment(r, "https://ci/report", nil, 0); got != full {
+		t.Errorf("Expected the full comment without a limit")
+	}
+
+	got, err := FitComment(r, "https://ci/report", nil, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) > 2000 || !strings.HasPrefix(got, Marker) || !strings.Contains(got, "[Full report](https://ci/report)") {
+		t.Fatalf("Unexpected comment of %d bytes:\n%s", len(got), got)
+	}
+	if !strings.Contains(got, "`pkg/f09.go`") || strings.Contains(got, "`pkg/f00.go`") {
+		t.Errorf("Expected the files with the most uncovered lines to be kept:\n%s", got)
+	}
+	if !strings.Contains(got, "more files with fewer uncovered lines are not shown") {
+		t.Errorf("Expected a note on the omitted files:\n%s", got)
+	}
+
+	got, _ = FitComment(r, "https://ci/report", nil, 150)
+	if len(got) > 150 || !strings.HasSuffix(got, "see the [full report](https://ci/report).\n") {
+		t.Errorf("Expected a cut-off comment of at most 150 bytes, got %d:\n%s", len(got), got)
+	}
+}
@@ -0,0 +1,90 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the request body, formatted as
+// "sha256=<hex>" like GitHub webhooks.
+const SignatureHeader = "X-Diffcoverage-Signature-256"
+
+// Webhook POSTs the JSON report to each URL, signing the body when Secret is set.
+type Webhook struct {
+	URLs   []string
+	Secret string
+	Client *http.Client
+}
+
+// Sign returns the SignatureHeader value of body for secret.
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the valid SignatureHeader value of body.
+func Verify(body []byte, secret, signature string) bool {
+	return hmac.Equal([]byte(Sign(body, secret)), []byte(signature))
+}
+
+// Publish delivers the report to every URL, even if some deliveries fail.
+func (w *Webhook) Publish(ctx context.Context, r *diffcoverage.Report) error {
+	if len(w.URLs) == 0 {
+		return fmt.Errorf("webhook: at least one URL is required")
+	}
+
+	body, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("webhook: %v", err)
+	}
+
+	var errs []error
+	for _, url := range w.URLs {
+		if err := w.deliver(ctx, url, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %v", url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver POSTs body to one URL.
+func (w *Webhook) deliver(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-new-code-coverage")
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(body, w.Secret))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
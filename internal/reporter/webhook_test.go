@@ -0,0 +1,56 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestWebhook_Publish checks every URL receives the signed JSON report.
+func TestWebhook_Publish(t *testing.T) {
+	var received []diffcoverage.Report
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify(body, "secret", r.Header.Get(SignatureHeader)) {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		var rep diffcoverage.Report
+		json.Unmarshal(body, &rep)
+		received = append(received, rep)
+	})
+	srv1, srv2 := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer srv1.Close()
+	defer srv2.Close()
+
+	w := &Webhook{URLs: []string{srv1.URL, srv2.URL}, Secret: "secret"}
+	if err := w.Publish(context.Background(), sampleReport()); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if len(received) != 2 || received[1].Coverage != 50 || received[1].Files[0].Path != "pkg/a.go" {
+		t.Errorf("Unexpected deliveries: %+v", received)
+	}
+
+	w.Secret = "wrong"
+	err := w.Publish(context.Background(), sampleReport())
+	if err == nil || !strings.Contains(err.Error(), srv1.URL) || !strings.Contains(err.Error(), srv2.URL) {
+		t.Errorf("Expected both deliveries to fail, got %v", err)
+	}
+}
+
+// TestSign checks the signature format and Verify.
+func TestSign(t *testing.T) {
+	sig := Sign([]byte("body"), "key")
+	if !strings.HasPrefix(sig, "sha256=") || len(sig) != len("sha256=")+64 {
+		t.Errorf("Unexpected signature %q", sig)
+	}
+	if !Verify([]byte("body"), "key", sig) || Verify([]byte("body2"), "key", sig) {
+		t.Errorf("Verify does not match Sign")
+	}
+}
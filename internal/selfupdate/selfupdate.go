@@ -0,0 +1,248 @@
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// DefaultAPIURL is the GitHub API endpoint of this repository.
+const DefaultAPIURL = "https://api.github.com/repos/JackShadow/go-new-code-coverage"
+
+// Release assets are expected to be named go-new-code-coverage_<goos>_<goarch>[.exe],
+// accompanied by a sha256sum-style checksums.txt and, optionally, its ed25519
+// signature checksums.txt.sig.
+const (
+	binaryPrefix   = "go-new-code-coverage"
+	checksumsAsset = "checksums.txt"
+	signatureAsset = "checksums.txt.sig"
+)
+
+// Asset is a downloadable file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Release is the subset of the GitHub release payload used for updating.
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Updater fetches and verifies release binaries.
+type Updater struct {
+	APIURL    string
+	Client    *http.Client
+	PublicKey ed25519.PublicKey // when set, checksums.txt must carry a valid signature
+}
+
+// Latest returns the latest published release.
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	body, err := u.get(ctx, strings.TrimSuffix(u.APIURL, "/")+"/releases/latest")
+	if err != nil {
+		return nil, err
+	}
+	var rel Release
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return nil, fmt.Errorf("error decoding release: %v", err)
+	}
+	if rel.TagName == "" {
+		return nil, fmt.Errorf("release has no tag name")
+	}
+	return &rel, nil
+}
+
+// Download fetches the binary for goos/goarch from rel and verifies it against
+// the release checksums (and signature, if a public key is configured).
+func (u *Updater) Download(ctx context.Context, rel *Release, goos, goarch string) ([]byte, error) {
+	name := AssetName(goos, goarch)
+	binAsset := rel.asset(name)
+	if binAsset == nil {
+		return nil, fmt.Errorf("release %s has no asset %s", rel.TagName, name)
+	}
+	sumsAsset := rel.asset(checksumsAsset)
+	if sumsAsset == nil {
+		return nil, fmt.Errorf("release %s has no %s; refusing to install an unverified binary", rel.TagName, checksumsAsset)
+	}
+
+	sums, err := u.get(ctx, sumsAsset.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.PublicKey != nil {
+		sigAsset := rel.asset(signatureAsset)
+		if sigAsset == nil {
+			return nil, fmt.Errorf("release %s has no %s", rel.TagName, signatureAsset)
+		}
+		sig, err := u.get(ctx, sigAsset.URL)
+		if err != nil {
+			return nil, err
+		}
+		if !ed25519.Verify(u.PublicKey, sums, bytes.TrimSpace(sig)) {
+			return nil, fmt.Errorf("signature verification of %s failed", checksumsAsset)
+		}
+	}
+
+	want, err := checksumFor(sums, name)
+	if err != nil {
+		return nil, err
+	}
+
+	bin, err := u.get(ctx, binAsset.URL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(bin)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	return bin, nil
+}
+
+// AssetName returns the release asset name for a platform.
+func AssetName(goos, goarch string) string {
+	name := fmt.Sprintf("%s_%s_%s", binaryPrefix, goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Replace atomically swaps the executable at exePath for data, keeping its mode.
+func Replace(exePath string, data []byte) error {
+	exePath, err := filepath.EvalSymlinks(exePath)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exePath), ".diffcoverage-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write next to %s: %v", exePath, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// Windows cannot overwrite a running executable, but it can rename it.
+	oldPath := exePath + ".old"
+	os.Remove(oldPath)
+	if err := os.Rename(exePath, oldPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, exePath); err != nil {
+		os.Rename(oldPath, exePath)
+		return err
+	}
+	os.Remove(oldPath)
+	return nil
+}
+
+// NewerThan reports whether release version latest is newer than current.
+// Non-semver versions (e.g. "devel") are never considered up to date.
+func NewerThan(latest, current string) bool {
+	l, okL := parseSemver(latest)
+	c, okC := parseSemver(current)
+	if !okL {
+		return false
+	}
+	if !okC {
+		return true
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseSemver parses vMAJOR.MINOR.PATCH, ignoring pre-release and build suffixes.
+func parseSemver(v string) ([3]int, bool) {
+	var out [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+// checksumFor finds the sha256 of name in a sha256sum-formatted file.
+func checksumFor(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", name, checksumsAsset)
+}
+
+func (r *Release) asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+func (u *Updater) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	client := u.Client
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
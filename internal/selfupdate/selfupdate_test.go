@@ -0,0 +1,192 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// releaseServer serves a fake release with the given assets.
+func releaseServer(t *testing.T, assets map[string][]byte) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		rel := Release{TagName: "v1.3.0"}
+		for name := range assets {
+			rel.Assets = append(rel.Assets, Asset{Name: name, URL: srv.URL + "/download/" + name})
+		}
+		json.NewEncoder(w).Encode(rel)
+	})
+	mux.HandleFunc("/download/", func(w http.ResponseWriter, r *http.Request) {
+		data, ok := assets[strings.TrimPrefix(r.URL.Path, "/download/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sha(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// TestDownload_Verified covers the happy path with checksum and signature.
+func TestDownload_Verified(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	bin := []byte("new binary")
+	name := AssetName("linux", "amd64")
+	sums := []byte(fmt.Sprintf("%s  %s\n%s  other\n", sha(bin), name, sha([]byte("x"))))
+	srv := releaseServer(t, map[string][]byte{
+		name:           bin,
+		checksumsAsset: sums,
+		signatureAsset: ed25519.Sign(priv, sums),
+	})
+
+	u := &Updater{APIURL: srv.URL, PublicKey: pub}
+	rel, err := u.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if rel.TagName != "v1.3.0" {
+		t.Errorf("TagName = %q", rel.TagName)
+	}
+	got, err := u.Download(context.Background(), rel, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if string(got) != string(bin) {
+		t.Errorf("Download returned %q", got)
+	}
+}
+
+// TestDownload_Failures covers each verification failure.
+func TestDownload_Failures(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	bin := []byte("new binary")
+	name := AssetName("linux", "amd64")
+	goodSums := []byte(sha(bin) + "  " + name + "\n")
+
+	tests := []struct {
+		name   string
+		assets map[string][]byte
+		key    ed25519.PublicKey
+		substr string
+	}{
+		{"missing binary", map[string][]byte{checksumsAsset: goodSums}, nil, "has no asset"},
+		{"missing checksums", map[string][]byte{name: bin}, nil, "unverified"},
+		{"checksum mismatch", map[string][]byte{name: []byte("tampered"), checksumsAsset: goodSums}, nil, "checksum mismatch"},
+		{"no checksum line", map[string][]byte{name: bin, checksumsAsset: []byte("abc  other\n")}, nil, "no checksum for"},
+		{"missing signature", map[string][]byte{name: bin, checksumsAsset: goodSums}, pub, "has no checksums.txt.sig"},
+		{"bad signature", map[string][]byte{name: bin, checksumsAsset: goodSums, signatureAsset: ed25519.Sign(priv, []byte("other"))}, pub, "signature verification"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := releaseServer(t, tt.assets)
+			u := &Updater{APIURL: srv.URL, PublicKey: tt.key}
+			rel, err := u.Latest(context.Background())
+			if err != nil {
+				t.Fatalf("Latest failed: %v", err)
+			}
+			_, err = u.Download(context.Background(), rel, "linux", "amd64")
+			if err == nil || !strings.Contains(err.Error(), tt.substr) {
+				t.Errorf("Expected error containing %q, got %v", tt.substr, err)
+			}
+		})
+	}
+}
+
+// TestLatest_Errors covers HTTP and payload errors.
+func TestLatest_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bad/releases/latest":
+			w.Write([]byte("not json"))
+		case "/empty/releases/latest":
+			w.Write([]byte("{}"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/missing", "/bad", "/empty"} {
+		u := &Updater{APIURL: srv.URL + path}
+		if _, err := u.Latest(context.Background()); err == nil {
+			t.Errorf("Expected error for %s, got nil", path)
+		}
+	}
+}
+
+// TestAssetName checks the Windows suffix.
+func TestAssetName(t *testing.T) {
+	if got := AssetName("windows", "amd64"); got != "go-new-code-coverage_windows_amd64.exe" {
+		t.Errorf("AssetName = %q", got)
+	}
+	if got := AssetName("darwin", "arm64"); got != "go-new-code-coverage_darwin_arm64" {
+		t.Errorf("AssetName = %q", got)
+	}
+}
+
+// TestNewerThan covers semver comparison and non-semver versions.
+func TestNewerThan(t *testing.T) {
+	cases := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.3.0", "v1.2.9", true},
+		{"v1.3.0", "v1.3.0", false},
+		{"v1.3.0", "v1.10.0", false},
+		{"v2.0.0", "v1.99.99", true},
+		{"v1.3.0", "devel", true},
+		{"v1.3.0-rc.1", "v1.2.0", true},
+		{"nightly", "v1.0.0", false},
+	}
+	for _, c := range cases {
+		if got := NewerThan(c.latest, c.current); got != c.want {
+			t.Errorf("NewerThan(%q, %q) = %v, want %v", c.latest, c.current, got, c.want)
+		}
+	}
+}
+
+// TestReplace swaps a file in place and keeps its mode.
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "tool")
+	if err := os.WriteFile(exe, []byte("old"), 0750); err != nil {
+		t.Fatalf("Failed to write exe: %v", err)
+	}
+	if err := Replace(exe, []byte("new")); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	content, _ := os.ReadFile(exe)
+	if string(content) != "new" {
+		t.Errorf("content = %q, want new", content)
+	}
+	info, _ := os.Stat(exe)
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("mode = %v, want 0750", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected leftovers to be cleaned up, found %d entries", len(entries))
+	}
+
+	if err := Replace(filepath.Join(dir, "missing"), []byte("x")); err == nil {
+		t.Errorf("Expected error for missing executable, got nil")
+	}
+}
@@ -0,0 +1,187 @@
+// Package storage reads and writes report objects in S3-compatible object
+// storage (Amazon S3, Google Cloud Storage, MinIO) without a cloud SDK.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+)
+
+// ErrNotFound is returned by Get when the object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Bucket is a bucket and key prefix in S3-compatible storage.
+type Bucket struct {
+	Endpoint string // e.g. https://s3.eu-west-1.amazonaws.com or https://storage.googleapis.com
+	Region   string
+	Name     string
+	Prefix   string // prepended to every key, without a trailing slash
+	// PathStyle addresses objects as Endpoint/Name/key instead of Name.Endpoint/key.
+	PathStyle bool
+
+	// AccessKey and SecretKey sign requests with AWS Signature Version 4
+	// (S3 access keys or GCS HMAC keys); Token is the session token, if any.
+	AccessKey string
+	SecretKey string
+	Token     string
+	// BearerToken authenticates with an OAuth access token instead (GCS only).
+	BearerToken string
+
+	Client *http.Client
+	now    func() time.Time
+}
+
+// Open parses an s3://bucket/prefix or gs://bucket/prefix URL and takes the
+// credentials from the usual environment variables read through getenv.
+func Open(rawURL string, getenv func(string) string) (*Bucket, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("storage URL %q has no bucket", rawURL)
+	}
+	b := &Bucket{Name: u.Host, Prefix: strings.Trim(u.Path, "/")}
+
+	switch u.Scheme {
+	case "s3":
+		b.Region = firstNonEmpty(getenv("AWS_REGION"), getenv("AWS_DEFAULT_REGION"), "us-east-1")
+		b.Endpoint = firstNonEmpty(getenv("AWS_ENDPOINT_URL_S3"), getenv("AWS_ENDPOINT_URL"))
+		if b.Endpoint != "" {
+			// Custom endpoints (MinIO and friends) rarely support virtual hosts.
+			b.PathStyle = true
+		} else {
+			b.Endpoint = "https://s3." + b.Region + ".amazonaws.com"
+		}
+		b.AccessKey = getenv("AWS_ACCESS_KEY_ID")
+		b.SecretKey = getenv("AWS_SECRET_ACCESS_KEY")
+		b.Token = getenv("AWS_SESSION_TOKEN")
+	case "gs":
+		b.Endpoint = "https://storage.googleapis.com"
+		b.Region = "auto"
+		b.PathStyle = true
+		b.AccessKey = getenv("GCS_HMAC_ACCESS_ID")
+		b.SecretKey = getenv("GCS_HMAC_SECRET")
+		b.BearerToken = getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	default:
+		return nil, fmt.Errorf("unsupported storage URL %q (use s3://bucket/prefix or gs://bucket/prefix)", rawURL)
+	}
+
+	if (b.AccessKey == "" || b.SecretKey == "") && b.BearerToken == "" {
+		return nil, fmt.Errorf("no credentials for %s (set AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY, GCS_HMAC_ACCESS_ID/GCS_HMAC_SECRET or GOOGLE_OAUTH_ACCESS_TOKEN)", rawURL)
+	}
+	return b, nil
+}
+
+// Put stores data under key.
+func (b *Bucket) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	_, err = b.do(req, data)
+	return err
+}
+
+// Get returns the object stored under key, or ErrNotFound.
+func (b *Bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	return b.do(req, nil)
+}
+
+// do authenticates and sends req, returning the response body.
+func (b *Bucket) do(req *http.Request, payload []byte) ([]byte, error) {
+	if b.BearerToken != "" && b.AccessKey == "" {
+		req.Header.Set("Authorization", "Bearer "+b.BearerToken)
+	} else {
+		now := time.Now
+		if b.now != nil {
+			now = b.now
+		}
+		if b.Token != "" {
+			req.Header.Set("X-Amz-Security-Token", b.Token)
+		}
+		signV4(req, payload, b.AccessKey, b.SecretKey, b.Region, now())
+	}
+
+	client := b.Client
+	if client == nil {
+		client = httpclient.Default
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s: unexpected status %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// objectURL returns the URL of key below the prefix.
+func (b *Bucket) objectURL(key string) string {
+	if b.Prefix != "" {
+		key = b.Prefix + "/" + key
+	}
+	endpoint := strings.TrimSuffix(b.Endpoint, "/")
+	if b.PathStyle {
+		return endpoint + "/" + b.Name + "/" + escapePath(key)
+	}
+	scheme, host, _ := strings.Cut(endpoint, "://")
+	return scheme + "://" + b.Name + "." + host + "/" + escapePath(key)
+}
+
+// escapePath URI-encodes each segment of key as S3 expects.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = uriEncode(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Key builds the object key for a commit's report.
+func Key(repo, branch, commit, name string) string {
+	return strings.Join([]string{repo, branch, commit, name}, "/")
+}
+
+// LatestKey builds the object key of a branch's most recent report.
+func LatestKey(repo, branch, name string) string {
+	return strings.Join([]string{repo, branch, "latest", name}, "/")
+}
+
+// IsNotFound reports whether err is ErrNotFound.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,227 @@
+package testgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestFile is a _test.go file to create or extend with test skeletons.
+type TestFile struct {
+	Path    string   // relative to the source root
+	Exists  bool     // Content extends an existing file
+	Funcs   []string // tested functions, as "Func" or "Type.Method"
+	Tests   string   // the generated test functions
+	Content []byte   // the whole file with the tests added, gofmt-ed
+}
+
+// Scaffold returns test skeletons for the changed functions of a that no test
+// covers at all, grouped by the test file next to their source file. Functions
+// whose test name is already declared in the package are skipped. Skeletons
+// go to <name>_internal_test.go when <name>_test.go is an external test
+// package, since they call the functions unqualified.
+func Scaffold(a *diffcoverage.Analysis) ([]TestFile, error) {
+	var rels []string
+	for file := range a.Diff.NewLines {
+		rels = append(rels, a.RelPath(file))
+	}
+	sort.Strings(rels)
+
+	type pending struct {
+		pkg     string
+		funcs   []string
+		tests   []string
+		imports []string
+	}
+	files := map[string]*pending{}
+	var order []string
+	declared := map[string]map[string]bool{}
+
+	for _, rel := range rels {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, filepath.Join(a.SourceRoot, rel), nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", rel, err)
+		}
+		dir := filepath.Dir(rel)
+		if declared[dir] == nil {
+			declared[dir] = declaredTests(filepath.Join(a.SourceRoot, dir))
+		}
+		testPath := strings.TrimSuffix(rel, ".go") + "_test.go"
+		if pkg, ok := packageName(filepath.Join(a.SourceRoot, testPath)); ok && pkg != file.Name.Name {
+			testPath = strings.TrimSuffix(rel, ".go") + "_internal_test.go"
+		}
+
+		for _, decl := range file.Decls {
+			fn, ok := testable(decl)
+			if !ok || declared[dir][testName(fn)] || !untested(a, rel, fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line) {
+				continue
+			}
+			declared[dir][testName(fn)] = true
+
+			p := files[testPath]
+			if p == nil {
+				p = &pending{pkg: file.Name.Name}
+				files[testPath] = p
+				order = append(order, testPath)
+			}
+			code := Skeleton(fset, fn)
+			p.funcs = append(p.funcs, diffcoverage.FuncName(fn))
+			p.tests = append(p.tests, code)
+			for _, imp := range imports(code) {
+				if !contains(p.imports, imp) {
+					p.imports = append(p.imports, imp)
+				}
+			}
+		}
+	}
+
+	var out []TestFile
+	for _, testPath := range order {
+		p := files[testPath]
+		tf := TestFile{Path: filepath.ToSlash(testPath), Funcs: p.funcs, Tests: strings.Join(p.tests, "\n")}
+
+		var src []byte
+		existing, err := os.ReadFile(filepath.Join(a.SourceRoot, testPath))
+		switch {
+		case err == nil:
+			tf.Exists = true
+			if src, err = addImports(existing, p.imports); err != nil {
+				return nil, fmt.Errorf("error parsing %s: %v", testPath, err)
+			}
+			src = append(src, '\n')
+		case os.IsNotExist(err):
+			var b strings.Builder
+			fmt.Fprintf(&b, "package %s\n\nimport (\n", p.pkg)
+			for _, imp := range p.imports {
+				fmt.Fprintf(&b, "\t%s\n", strconv.Quote(imp))
+			}
+			b.WriteString(")\n\n")
+			src = []byte(b.String())
+		default:
+			return nil, err
+		}
+		src = append(src, tf.Tests...)
+
+		if tf.Content, err = format.Source(src); err != nil {
+			return nil, fmt.Errorf("error formatting %s: %v", testPath, err)
+		}
+		out = append(out, tf)
+	}
+	return out, nil
+}
+
+// untested reports whether lines start-end of rel contain a counted changed
+// line and no covered line.
+func untested(a *diffcoverage.Analysis, rel string, start, end int) bool {
+	newLines := a.Diff.NewLines[a.DiffKey(rel)]
+	changed := false
+	for line := start; line <= end; line++ {
+		if a.Coverage.CoveredLines[rel][line] {
+			return false
+		}
+		if newLines[line] && a.Status(rel, line) != diffcoverage.LineIgnored {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// declaredTests returns the names of the functions declared in the test files of dir.
+func declaredTests(dir string) map[string]bool {
+	names := map[string]bool{}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	for _, path := range paths {
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				names[fn.Name.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+// packageName returns the package clause of the Go file at path, if it exists.
+func packageName(path string) (string, bool) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return "", false
+	}
+	return file.Name.Name, true
+}
+
+// addImports returns src with the import paths it lacks added.
+func addImports(src []byte, paths []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, imp := range paths {
+		found := false
+		for _, spec := range file.Imports {
+			if spec.Path.Value == strconv.Quote(imp) {
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, imp)
+		}
+	}
+	if len(missing) == 0 {
+		return src, nil
+	}
+
+	var specs bytes.Buffer
+	for _, imp := range missing {
+		fmt.Fprintf(&specs, "\t%s\n", strconv.Quote(imp))
+	}
+	var last *ast.GenDecl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			last = gen
+		}
+	}
+
+	// Extend the last import declaration, turning it into a block if needed,
+	// or add a block after the package clause. gofmt sorts the block.
+	var out bytes.Buffer
+	switch {
+	case last == nil:
+		at := fset.Position(file.Name.End()).Offset
+		fmt.Fprintf(&out, "%s\n\nimport (\n%s)%s", src[:at], specs.Bytes(), src[at:])
+	case last.Lparen.IsValid():
+		at := fset.Position(last.Rparen).Offset
+		fmt.Fprintf(&out, "%s%s%s", src[:at], specs.Bytes(), src[at:])
+	default:
+		start, end := fset.Position(last.Pos()).Offset, fset.Position(last.End()).Offset
+		spec := last.Specs[0]
+		old := src[fset.Position(spec.Pos()).Offset:fset.Position(spec.End()).Offset]
+		fmt.Fprintf(&out, "%simport (\n\t%s\n%s)%s", src[:start], old, specs.Bytes(), src[end:])
+	}
+	return out.Bytes(), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package testgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// TestScaffold extends the existing test file with skeletons for changed,
+// entirely uncovered functions only.
+func TestScaffold(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":        "module example.com/m\n\ngo 1.21\n",
+		"pkg/a.go":      "package pkg\n\nfunc Covered() int {\n\treturn 1\n}\n\nfunc Keys(m map[string]int) []string {\n\treturn nil\n}\n\nfunc Old() {\n\tprintln()\n}\n\nfunc Tested() {\n\tprintln()\n}\n",
+		"pkg/a_test.go": "package pkg\n\nimport \"testing\"\n\nfunc TestTested(t *testing.T) {}\n",
+		"pkg/b.go":      "package pkg\n\nfunc Double(n int) int {\n\treturn n * 2\n}\n",
+		"ext/e.go":      "package ext\n\nfunc E() bool {\n\treturn true\n}\n",
+		"ext/e_test.go": "package ext_test\n",
+		"cover.out":     "mode: set\nexample.com/m/pkg/a.go:3.20,5.2 1 1\nexample.com/m/pkg/a.go:7.36,9.2 1 0\n",
+		"diff.txt":      "+++ b/pkg/a.go\n@@ -0,0 +3,3 @@\n+func Covered() int {\n+\treturn 1\n+}\n@@ -0,0 +7,3 @@\n+func Keys(m map[string]int) []string {\n+\treturn nil\n+}\n@@ -0,0 +16,1 @@\n+\tprintln()\n+++ b/pkg/b.go\n@@ -0,0 +4,1 @@\n+\treturn n * 2\n+++ b/ext/e.go\n@@ -0,0 +4,1 @@\n+\treturn true\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, err := diffcoverage.Analyze(filepath.Join(root, "cover.out"), filepath.Join(root, "diff.txt"), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Scaffold(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 3 {
+		t.Fatalf("Expected 3 test files, got %+v", out)
+	}
+	ext, pa, pb := out[0], out[1], out[2]
+
+	if ext.Path != "ext/e_internal_test.go" || ext.Exists || !strings.HasPrefix(string(ext.Content), "package ext\n") {
+		t.Errorf("Expected a new internal test file for the external test package, got %+v", ext)
+	}
+	if pa.Path != "pkg/a_test.go" || !pa.Exists || strings.Join(pa.Funcs, ",") != "Keys" {
+		t.Errorf("Expected only Keys scaffolded into pkg/a_test.go, got %+v", pa)
+	}
+	want := "package pkg\n\nimport (\n\t\"reflect\"\n\t\"testing\"\n)\n\nfunc TestTested(t *testing.T) {}\n\nfunc TestKeys(t *testing.T) {"
+	if !strings.HasPrefix(string(pa.Content), want) {
+		t.Errorf("Unexpected pkg/a_test.go content:\n%s", pa.Content)
+	}
+	if pb.Path != "pkg/b_test.go" || pb.Exists || !strings.Contains(string(pb.Content), "func TestDouble(t *testing.T) {") {
+		t.Errorf("Expected a new pkg/b_test.go with TestDouble, got %+v\n%s", pb, pb.Content)
+	}
+}
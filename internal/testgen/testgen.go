@@ -0,0 +1,316 @@
+// Package testgen generates table-driven test skeletons for new functions
+// that tests do not cover.
+package testgen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+// Attach sets r.Skeletons to a test skeleton for every function declared on
+// a new line of the diff at diffPath with uncovered lines. Generic functions
+// are skipped.
+func Attach(a *diffcoverage.Analysis, r *diffcoverage.Report, diffPath string) error {
+	testHunks, err := testFileHunks(diffPath)
+	if err != nil {
+		return err
+	}
+
+	r.Skeletons = nil
+	for _, f := range r.Files {
+		if len(f.Uncovered) == 0 {
+			continue
+		}
+		newLines := a.Diff.NewLines[a.ModuleName+"/"+f.Path]
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, filepath.Join(r.SourceRoot, f.Path), nil, 0)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %v", f.Path, err)
+		}
+		for _, decl := range file.Decls {
+			fn, ok := testable(decl)
+			if !ok {
+				continue
+			}
+			line := fset.Position(fn.Pos()).Line
+			if !newLines[line] || !overlaps(f.Uncovered, line, fset.Position(fn.End()).Line) {
+				continue
+			}
+			s := diffcoverage.TestSkeleton{
+				File:     f.Path,
+				Line:     line,
+				Func:     diffcoverage.FuncName(fn),
+				TestFile: strings.TrimSuffix(f.Path, ".go") + "_test.go",
+				Code:     Skeleton(fset, fn),
+			}
+			s.Imports = imports(s.Code)
+			if last, ok := testHunks[s.TestFile]; ok {
+				s.TestLine, s.TestLineText = lastLine(filepath.Join(r.SourceRoot, s.TestFile), last)
+			}
+			r.Skeletons = append(r.Skeletons, s)
+		}
+	}
+	return nil
+}
+
+// testable returns decl if it is a function a skeleton can be generated for:
+// not generic, not init or main, and with a body.
+func testable(decl ast.Decl) (*ast.FuncDecl, bool) {
+	fn, ok := decl.(*ast.FuncDecl)
+	if !ok || fn.Body == nil || fn.Type.TypeParams != nil || fn.Name.Name == "init" || fn.Name.Name == "main" {
+		return nil, false
+	}
+	return fn, true
+}
+
+func overlaps(ranges [][2]int, start, end int) bool {
+	for _, r := range ranges {
+		if r[0] <= end && r[1] >= start {
+			return true
+		}
+	}
+	return false
+}
+
+// recvType returns the receiver's type name without pointer or type arguments.
+func recvType(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return fmt.Sprint(t.X)
+	case *ast.IndexListExpr:
+		return fmt.Sprint(t.X)
+	}
+	return ""
+}
+
+// testName returns the name of fn's test: TestFunc, Test_func or TestType_Method.
+func testName(fn *ast.FuncDecl) string {
+	if recv := recvType(fn); recv != "" {
+		return "Test" + recv + "_" + fn.Name.Name
+	}
+	if !fn.Name.IsExported() {
+		return "Test_" + fn.Name.Name
+	}
+	return "Test" + fn.Name.Name
+}
+
+// field is a column of the test table.
+type field struct {
+	name, typ string
+	variadic  bool
+}
+
+// Skeleton returns a table-driven test for fn, in the style of gotests: one
+// field per argument and result, a wantErr flag for a trailing error result.
+func Skeleton(fset *token.FileSet, fn *ast.FuncDecl) string {
+	expr := func(e ast.Expr) string {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, fset, e)
+		return buf.String()
+	}
+
+	var args []field
+	for i, p := range fn.Type.Params.List {
+		typ, variadic := p.Type, false
+		if ell, ok := typ.(*ast.Ellipsis); ok {
+			typ, variadic = &ast.ArrayType{Elt: ell.Elt}, true
+		}
+		names := p.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{{Name: "_"}}
+		}
+		for j, n := range names {
+			name := n.Name
+			switch name {
+			case "_":
+				name = "arg" + strconv.Itoa(i+j)
+			case "name", "recv", "want", "wantErr", "tt", "t":
+				// Keep clear of the table's own fields and variables.
+				name += "Arg"
+			}
+			args = append(args, field{name: name, typ: expr(typ), variadic: variadic})
+		}
+	}
+
+	var results []field
+	wantErr := false
+	if fn.Type.Results != nil {
+		var types []string
+		for _, r := range fn.Type.Results.List {
+			n := max(len(r.Names), 1)
+			for i := 0; i < n; i++ {
+				types = append(types, expr(r.Type))
+			}
+		}
+		if len(types) > 0 && types[len(types)-1] == "error" {
+			types, wantErr = types[:len(types)-1], true
+		}
+		for i, typ := range types {
+			name := "want"
+			if len(types) > 1 {
+				name += strconv.Itoa(i)
+			}
+			results = append(results, field{name: name, typ: typ})
+		}
+	}
+
+	recv := recvType(fn)
+	var b strings.Builder
+	fmt.Fprintf(&b, "func %s(t *testing.T) {\n\ttests := []struct {\n\t\tname string\n", testName(fn))
+	if recv != "" {
+		fmt.Fprintf(&b, "\t\trecv %s\n", expr(fn.Recv.List[0].Type))
+	}
+	for _, f := range args {
+		fmt.Fprintf(&b, "\t\t%s %s\n", f.name, f.typ)
+	}
+	for _, f := range results {
+		fmt.Fprintf(&b, "\t\t%s %s\n", f.name, f.typ)
+	}
+	if wantErr {
+		b.WriteString("\t\twantErr bool\n")
+	}
+	b.WriteString("\t}{\n\t\t// TODO: add test cases.\n\t}\n")
+	b.WriteString("\tfor _, tt := range tests {\n\t\tt.Run(tt.name, func(t *testing.T) {\n")
+
+	var gots []string
+	for i := range results {
+		if len(results) > 1 {
+			gots = append(gots, "got"+strconv.Itoa(i))
+		} else {
+			gots = append(gots, "got")
+		}
+	}
+	if wantErr {
+		gots = append(gots, "err")
+	}
+	var callArgs []string
+	for _, f := range args {
+		a := "tt." + f.name
+		if f.variadic {
+			a += "..."
+		}
+		callArgs = append(callArgs, a)
+	}
+	call := fn.Name.Name + "(" + strings.Join(callArgs, ", ") + ")"
+	if recv != "" {
+		call = "tt.recv." + call
+	}
+	b.WriteString("\t\t\t")
+	if len(gots) > 0 {
+		b.WriteString(strings.Join(gots, ", ") + " := ")
+	}
+	b.WriteString(call + "\n")
+	if wantErr {
+		fmt.Fprintf(&b, "\t\t\tif (err != nil) != tt.wantErr {\n\t\t\t\tt.Fatalf(\"%s() error = %%v, wantErr %%v\", err, tt.wantErr)\n\t\t\t}\n", fn.Name.Name)
+	}
+	for i, f := range results {
+		cond := fmt.Sprintf("%s != tt.%s", gots[i], f.name)
+		if !isComparable(f.typ) {
+			cond = fmt.Sprintf("!reflect.DeepEqual(%s, tt.%s)", gots[i], f.name)
+		}
+		fmt.Fprintf(&b, "\t\t\tif %s {\n\t\t\t\tt.Errorf(\"%s() %s = %%v, want %%v\", %s, tt.%s)\n\t\t\t}\n", cond, fn.Name.Name, gots[i], gots[i], f.name)
+	}
+	b.WriteString("\t\t})\n\t}\n}\n")
+	if code, err := format.Source([]byte(b.String())); err == nil {
+		return string(code)
+	}
+	return b.String()
+}
+
+var basicTypes = map[string]bool{
+	"bool": true, "string": true, "error": true, "byte": true, "rune": true, "uintptr": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true,
+}
+
+// isComparable reports whether values of typ can be compared with != without
+// type information: basic types and pointers.
+func isComparable(typ string) bool {
+	return basicTypes[typ] || strings.HasPrefix(typ, "*")
+}
+
+// imports returns the packages the skeleton needs besides the tested package.
+func imports(code string) []string {
+	list := []string{"testing"}
+	if strings.Contains(code, "reflect.DeepEqual") {
+		list = append(list, "reflect")
+	}
+	return list
+}
+
+// lastLine returns line and its text if it is the last line of the file, so
+// a suggestion replacing it appends to the file.
+func lastLine(file string, line int) (int, string) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, ""
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != line {
+		return 0, ""
+	}
+	return line, lines[line-1]
+}
+
+var hunkRe = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// testFileHunks returns, for each _test.go file of the diff, the last line of
+// its last hunk: review comments can only be placed on lines of the diff.
+// Changed tests are not counted, so the analysis has no record of them.
+func testFileHunks(diffPath string) (map[string]int, error) {
+	f, err := os.Open(diffPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	last := map[string]int{}
+	current := ""
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "+++ ") {
+			current = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if !strings.HasSuffix(current, "_test.go") {
+				current = ""
+			}
+			continue
+		}
+		if m := hunkRe.FindStringSubmatch(line); m != nil && current != "" {
+			start, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			if count > 0 {
+				last[path.Clean(current)] = start + count - 1
+			}
+		}
+	}
+	return last, scanner.Err()
+}
@@ -0,0 +1,93 @@
+package testgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+)
+
+const source = `package pkg
+
+func Parse(s string, opts ...int) (int, error) {
+	return len(s), nil
+}
+
+func (c *Client) Get(name string) []byte {
+	return nil
+}
+
+func old() {
+	println()
+}
+`
+
+const wantParse = `func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		opts    []int
+		want    int
+		wantErr bool
+	}{
+		// TODO: add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.s, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+`
+
+// TestAttach generates skeletons for new uncovered functions only.
+func TestAttach(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a_test.go"), []byte("package pkg\n\nimport \"testing\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	diffPath := filepath.Join(root, "diff.txt")
+	diff := "+++ b/a_test.go\n@@ -0,0 +1,3 @@\n+package pkg\n+\n+import \"testing\"\n"
+	if err := os.WriteFile(diffPath, []byte(diff), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &diffcoverage.Analysis{
+		ModuleName: "example.com/m",
+		Diff: &diffcoverage.DiffData{NewLines: map[string]map[int]bool{
+			"example.com/m/a.go": {3: true, 4: true, 7: true, 8: true, 12: true},
+		}},
+	}
+	r := &diffcoverage.Report{SourceRoot: root, Files: []diffcoverage.FileReport{
+		{Path: "a.go", Uncovered: [][2]int{{4, 4}, {8, 8}, {12, 12}}},
+	}}
+	if err := Attach(a, r, diffPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Skeletons) != 2 {
+		t.Fatalf("Expected skeletons for Parse and Client.Get, got %+v", r.Skeletons)
+	}
+	parse, get := r.Skeletons[0], r.Skeletons[1]
+	if parse.Func != "Parse" || parse.Line != 3 || parse.TestFile != "a_test.go" || parse.Code != wantParse {
+		t.Errorf("Unexpected Parse skeleton %+v\n%s", parse, parse.Code)
+	}
+	if parse.TestLine != 3 || parse.TestLineText != `import "testing"` {
+		t.Errorf("Expected a suggestion after a_test.go:3, got %d %q", parse.TestLine, parse.TestLineText)
+	}
+	if get.Func != "Client.Get" || !strings.Contains(get.Code, "got := tt.recv.Get(tt.nameArg)") ||
+		!strings.Contains(get.Code, "!reflect.DeepEqual(got, tt.want)") || strings.Join(get.Imports, ",") != "testing,reflect" {
+		t.Errorf("Unexpected Client.Get skeleton %+v\n%s", get, get.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package testrun
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event is an event of go test -json, see go doc test2json.
+type Event struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64 // seconds
+	Output  string
+}
+
+// PackageResult is the outcome of a tested package.
+type PackageResult struct {
+	Package     string
+	Action      string // pass, fail or skip
+	Elapsed     time.Duration
+	FailedTests []string
+}
+
+// Failed returns the packages of results that failed.
+func Failed(results []PackageResult) []string {
+	var failed []string
+	for _, r := range results {
+		if r.Action == "fail" {
+			failed = append(failed, r.Package)
+		}
+	}
+	return failed
+}
+
+// ReadEvents reads the go test -json events of r and returns the results of
+// the packages in the order they finished. The output is written to w as
+// go test prints it without -v: the output of passing tests is dropped.
+func ReadEvents(r io.Reader, w io.Writer) ([]PackageResult, error) {
+	var results []PackageResult
+	failedTests := make(map[string][]string)
+	// testOutput holds the output of running tests until they fail.
+	testOutput := make(map[[2]string]*strings.Builder)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var ev Event
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &ev) != nil {
+			w.Write(append(line, '\n'))
+			continue
+		}
+		key := [2]string{ev.Package, ev.Test}
+		switch {
+		case ev.Action == "build-output":
+			io.WriteString(w, ev.Output)
+		case ev.Action == "output" && ev.Test != "":
+			if testOutput[key] == nil {
+				testOutput[key] = &strings.Builder{}
+			}
+			testOutput[key].WriteString(ev.Output)
+		case ev.Action == "output":
+			if ev.Output != "PASS\n" && !strings.HasPrefix(ev.Output, "coverage: ") {
+				io.WriteString(w, ev.Output)
+			}
+		case ev.Test != "" && (ev.Action == "pass" || ev.Action == "fail" || ev.Action == "skip"):
+			if ev.Action == "fail" {
+				failedTests[ev.Package] = append(failedTests[ev.Package], ev.Test)
+				if out := testOutput[key]; out != nil {
+					io.WriteString(w, out.String())
+				}
+			}
+			delete(testOutput, key)
+		case ev.Action == "pass" || ev.Action == "fail" || ev.Action == "skip":
+			results = append(results, PackageResult{
+				Package:     ev.Package,
+				Action:      ev.Action,
+				Elapsed:     time.Duration(ev.Elapsed * float64(time.Second)),
+				FailedTests: failedTests[ev.Package],
+			})
+		}
+	}
+	return results, scanner.Err()
+}
@@ -0,0 +1,71 @@
+package testrun
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReadEvents checks the results and that only failing tests' output is kept.
+func TestReadEvents(t *testing.T) {
+	events := `{"Action":"start","Package":"example.com/m/a"}
+{"Action":"output","Package":"example.com/m/a","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"pass","Package":"example.com/m/a","Test":"TestA"}
+{"Action":"output","Package":"example.com/m/a","Output":"PASS\n"}
+{"Action":"output","Package":"example.com/m/a","Output":"coverage: 100.0% of statements\n"}
+{"Action":"output","Package":"example.com/m/a","Output":"ok  \texample.com/m/a\t0.002s\n"}
+{"Action":"pass","Package":"example.com/m/a","Elapsed":0.25}
+{"Action":"output","Package":"example.com/m/b","Test":"TestB","Output":"    b_test.go:5: boom\n"}
+{"Action":"fail","Package":"example.com/m/b","Test":"TestB"}
+not json
+{"Action":"fail","Package":"example.com/m/b","Elapsed":1}
+`
+	var out bytes.Buffer
+	results, err := ReadEvents(strings.NewReader(events), &out)
+	if err != nil {
+		t.Fatalf("ReadEvents: %v", err)
+	}
+	want := []PackageResult{
+		{Package: "example.com/m/a", Action: "pass", Elapsed: 250 * time.Millisecond},
+		{Package: "example.com/m/b", Action: "fail", Elapsed: time.Second, FailedTests: []string{"TestB"}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %+v, want %+v", results, want)
+	}
+	if got := out.String(); got != "ok  \texample.com/m/a\t0.002s\n    b_test.go:5: boom\nnot json\n" {
+		t.Errorf("Unexpected output %q", got)
+	}
+	if failed := Failed(results); !reflect.DeepEqual(failed, []string{"example.com/m/b"}) {
+		t.Errorf("Failed = %v", failed)
+	}
+}
+
+// TestTestPackages checks the coverage of failing packages is left out, in
+// one go test process and in one per package.
+func TestTestPackages(t *testing.T) {
+	dir := setupRepo(t)
+	mustWriteFile(t, filepath.Join(dir, "b", "b_test.go"), "package b\n\nimport \"testing\"\n\nfunc TestB(t *testing.T) {\n\tB()\n\tt.Fatal(\"fail\")\n}\n")
+	pkgs := []string{"example.com/m/a", "example.com/m/b"}
+	for _, parallel := range []int{1, 2} {
+		profile := filepath.Join(t.TempDir(), "cover.out")
+		var stdout bytes.Buffer
+		results, err := TestPackages(dir, pkgs, []string{"example.com/m/b"}, profile, parallel, &stdout, io.Discard)
+		if err != nil {
+			t.Fatalf("TestPackages(%d): %v", parallel, err)
+		}
+		if failed := Failed(results); len(results) != 2 || !reflect.DeepEqual(failed, []string{"example.com/m/b"}) {
+			t.Errorf("TestPackages(%d) results = %+v\n%s", parallel, results, stdout.String())
+		}
+		if content, _ := os.ReadFile(profile); string(content) != "mode: set\n" {
+			t.Errorf("TestPackages(%d): expected no coverage of b, got:\n%s", parallel, content)
+		}
+		if !strings.Contains(stdout.String(), "fail") {
+			t.Errorf("TestPackages(%d): expected the failing test's output, got:\n%s", parallel, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package testrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/coverprofile"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+)
+
+// Package describes the subset of `go list -json` output used to select tests.
+type Package struct {
+	ImportPath   string
+	Dir          string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+}
+
+// listFields are the Package fields requested from go list; go list skips
+// the work for the others, which matters in large modules.
+const listFields = "ImportPath,Dir,Imports,TestImports,XTestImports,TestGoFiles,XTestGoFiles"
+
+// ListPackages returns all packages of the module rooted at dir.
+func ListPackages(dir string) ([]Package, error) {
+	cmd := exec.Command("go", "list", "-json="+listFields, "./...")
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var pkgs []Package
+	dec := json.NewDecoder(&stdout)
+	for dec.More() {
+		var p Package
+		if err := dec.Decode(&p); err != nil {
+			return nil, fmt.Errorf("error decoding go list output: %v", err)
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// WriteDiff writes the zero-context diff of dir against base to path.
+func WriteDiff(dir, base, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gitutil.RunTo(dir, f, "diff", base, "--unified=0", "--relative")
+}
+
+// SelectPackages returns the import paths of packages containing changedFiles
+// and, separately, the packages whose tests can cover them: the changed
+// packages and every package with tests that depends on one, directly or
+// through other packages of the module. Packages without test files are left
+// out, as running them produces no coverage.
+func SelectPackages(dir string, pkgs []Package, changedFiles []string) (changed, toTest []string) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		absDir = dir
+	}
+
+	changedDirs := make(map[string]bool)
+	for _, file := range changedFiles {
+		changedDirs[filepath.Join(absDir, filepath.Dir(file))] = true
+	}
+
+	changedSet := make(map[string]bool)
+	for _, p := range pkgs {
+		if changedDirs[p.Dir] {
+			changedSet[p.ImportPath] = true
+		}
+	}
+
+	// reaches[path] records whether the package depends on a changed package.
+	byPath := make(map[string]Package, len(pkgs))
+	for _, p := range pkgs {
+		byPath[p.ImportPath] = p
+	}
+	reaches := make(map[string]bool)
+	var visit func(path string) bool
+	visit = func(path string) bool {
+		if r, ok := reaches[path]; ok {
+			return r
+		}
+		reaches[path] = false // breaks import cycles of broken trees
+		p, ok := byPath[path]
+		if !ok {
+			return false
+		}
+		r := changedSet[path]
+		for _, imp := range p.Imports {
+			r = visit(imp) || r
+		}
+		reaches[path] = r
+		return r
+	}
+
+	testSet := make(map[string]bool)
+	for _, p := range pkgs {
+		if len(p.TestGoFiles) == 0 && len(p.XTestGoFiles) == 0 {
+			continue
+		}
+		r := visit(p.ImportPath)
+		for _, imports := range [][]string{p.TestImports, p.XTestImports} {
+			for _, imp := range imports {
+				r = visit(imp) || r
+			}
+		}
+		if r {
+			testSet[p.ImportPath] = true
+		}
+	}
+
+	return sortedKeys(changedSet), sortedKeys(testSet)
+}
+
+// RunTests runs `go test` for pkgs in dir, instrumenting coverPkgs and writing the profile.
+func RunTests(dir string, pkgs, coverPkgs []string, profile string, stdout, stderr io.Writer) error {
+	args := []string{"test", "-coverprofile=" + profile}
+	if len(coverPkgs) > 0 {
+		args = append(args, "-coverpkg="+strings.Join(coverPkgs, ","))
+	}
+	args = append(args, pkgs...)
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go test: %v", err)
+	}
+	return nil
+}
+
+// TestPackages is RunTests with go test -json and one go test process per
+// package, up to parallel at a time, each package's output written once its
+// tests finish. It returns the results of the packages and leaves the
+// coverage of the failing ones out of profile.
+// When a single go test process tests all packages and some fail, the passing
+// ones are tested again for their coverage alone. An error means the tests
+// could not run, not that they failed.
+func TestPackages(dir string, pkgs, coverPkgs []string, profile string, parallel int, stdout, stderr io.Writer) ([]PackageResult, error) {
+	if parallel <= 1 || len(pkgs) <= 1 {
+		results, err := runTestsJSON(dir, pkgs, coverPkgs, profile, stdout, stderr)
+		if err != nil || len(Failed(results)) == 0 {
+			return results, err
+		}
+		var passed []string
+		for _, r := range results {
+			if r.Action == "pass" {
+				passed = append(passed, r.Package)
+			}
+		}
+		if len(passed) == 0 {
+			return results, mergeProfiles(profile, nil, nil)
+		}
+		fmt.Fprintf(stdout, "Testing the %d passing packages again for their coverage alone\n", len(passed))
+		if _, err := runTestsJSON(dir, passed, coverPkgs, profile, io.Discard, stderr); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "diffcoverage-test")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	results := make([][]PackageResult, len(pkgs))
+	errs := make([]error, len(pkgs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for i, pkg := range pkgs {
+		wg.Add(1)
+		go func(i int, pkg string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var out, errOut bytes.Buffer
+			results[i], errs[i] = runTestsJSON(dir, []string{pkg}, coverPkgs, filepath.Join(tmpDir, fmt.Sprintf("%d.out", i)), &out, &errOut)
+			mu.Lock()
+			defer mu.Unlock()
+			stdout.Write(out.Bytes())
+			stderr.Write(errOut.Bytes())
+		}(i, pkg)
+	}
+	wg.Wait()
+
+	var all []PackageResult
+	profiles := make(map[string]string)
+	for i, pkg := range pkgs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		all = append(all, results[i]...)
+		if len(Failed(results[i])) == 0 {
+			profiles[pkg] = filepath.Join(tmpDir, fmt.Sprintf("%d.out", i))
+		}
+	}
+	return all, mergeProfiles(profile, pkgs, profiles)
+}
+
+// runTestsJSON is RunTests with go test -json, returning the package results;
+// failing tests are not an error.
+func runTestsJSON(dir string, pkgs, coverPkgs []string, profile string, stdout, stderr io.Writer) ([]PackageResult, error) {
+	args := []string{"test", "-json", "-coverprofile=" + profile}
+	if len(coverPkgs) > 0 {
+		args = append(args, "-coverpkg="+strings.Join(coverPkgs, ","))
+	}
+	args = append(args, pkgs...)
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Stderr = stderr
+	events, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("go test: %v", err)
+	}
+	results, readErr := ReadEvents(events, stdout)
+	io.Copy(io.Discard, events)
+	err = cmd.Wait()
+	if readErr != nil {
+		return nil, fmt.Errorf("error reading go test output: %v", readErr)
+	}
+	if err != nil && len(Failed(results)) == 0 {
+		return nil, fmt.Errorf("go test: %v", err)
+	}
+	return results, nil
+}
+
+// mergeProfiles writes to profile the merged profiles of pkgs, given by
+// package; packages without one, e.g. because they did not build, are
+// skipped.
+func mergeProfiles(profile string, pkgs []string, profiles map[string]string) error {
+	m := &coverprofile.Merger{}
+	for _, pkg := range pkgs {
+		path, ok := profiles[pkg]
+		if !ok {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			continue // the package did not build
+		}
+		err = m.Add(pkg, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	out, err := os.Create(profile)
+	if err != nil {
+		return err
+	}
+	if err := m.Write(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
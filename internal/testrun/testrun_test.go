@@ -0,0 +1,150 @@
+package testrun
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+)
+
+// setupRepo creates a committed module with packages a, b (imports a) and c,
+// then modifies a/a.go in the working tree.
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n\ngo 1.21\n")
+	mustWriteFile(t, filepath.Join(dir, "a", "a.go"), "package a\n\nfunc A() int {\n\treturn 1\n}\n")
+	mustWriteFile(t, filepath.Join(dir, "a", "a_test.go"), "package a\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {\n\tif A() == 0 {\n\t\tt.Fatal(\"zero\")\n\t}\n}\n")
+	mustWriteFile(t, filepath.Join(dir, "b", "b.go"), "package b\n\nimport \"example.com/m/a\"\n\nfunc B() int {\n\treturn a.A()\n}\n")
+	mustWriteFile(t, filepath.Join(dir, "c", "c.go"), "package c\n\nfunc C() int {\n\treturn 3\n}\n")
+
+	mustGit(t, dir, "init", "-q")
+	mustGit(t, dir, "add", "-A")
+	mustGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+
+	mustWriteFile(t, filepath.Join(dir, "a", "a.go"), "package a\n\nfunc A() int {\n\tx := 1\n\treturn x\n}\n")
+	return dir
+}
+
+// TestWriteDiff checks the diff is written with zero context.
+func TestWriteDiff(t *testing.T) {
+	dir := setupRepo(t)
+	path := filepath.Join(t.TempDir(), "diff.txt")
+	if err := WriteDiff(dir, "HEAD", path); err != nil {
+		t.Fatalf("WriteDiff failed: %v", err)
+	}
+	content, _ := os.ReadFile(path)
+	for _, want := range []string{"+++ b/a/a.go", "@@ -4 +4,2 @@", "+\tx := 1"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected diff to contain %q, got:\n%s", want, content)
+		}
+	}
+
+	if err := WriteDiff(dir, "HEAD", filepath.Join(dir, "missing", "diff.txt")); err == nil {
+		t.Errorf("Expected error for unwritable path, got nil")
+	}
+}
+
+// TestListAndSelectPackages checks changed packages and the packages whose
+// tests depend on them, directly or not, are selected.
+func TestListAndSelectPackages(t *testing.T) {
+	dir := setupRepo(t)
+	mustWriteFile(t, filepath.Join(dir, "d", "d.go"), "package d\n")
+	mustWriteFile(t, filepath.Join(dir, "d", "d_test.go"), "package d\n\nimport (\n\t\"testing\"\n\n\t\"example.com/m/b\"\n)\n\nfunc TestD(t *testing.T) {\n\t_ = b.B()\n}\n")
+	mustWriteFile(t, filepath.Join(dir, "e", "e_test.go"), "package e\n\nimport \"testing\"\n\nfunc TestE(t *testing.T) {}\n")
+	pkgs, err := ListPackages(dir)
+	if err != nil {
+		t.Fatalf("ListPackages failed: %v", err)
+	}
+	if len(pkgs) != 5 {
+		t.Fatalf("Expected 5 packages, got %d: %+v", len(pkgs), pkgs)
+	}
+
+	changed, toTest := SelectPackages(dir, pkgs, []string{"a/a.go"})
+	if want := []string{"example.com/m/a"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	// b imports a but has no tests; d tests b.
+	if want := []string{"example.com/m/a", "example.com/m/d"}; !reflect.DeepEqual(toTest, want) {
+		t.Errorf("toTest = %v, want %v", toTest, want)
+	}
+
+	if changed, toTest := SelectPackages(dir, pkgs, nil); len(changed)+len(toTest) != 0 {
+		t.Errorf("Expected nothing selected without changes, got %v %v", changed, toTest)
+	}
+}
+
+// TestListPackages_Error covers running outside a module.
+func TestListPackages_Error(t *testing.T) {
+	if _, err := ListPackages(t.TempDir()); err == nil {
+		t.Fatalf("Expected error outside a module, got nil")
+	}
+}
+
+// TestRunTests checks a profile is produced for the instrumented packages.
+func TestRunTests(t *testing.T) {
+	dir := setupRepo(t)
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	pkgs := []string{"example.com/m/a", "example.com/m/b"}
+	if err := RunTests(dir, pkgs, []string{"example.com/m/a"}, profile, io.Discard, io.Discard); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+	content, err := os.ReadFile(profile)
+	if err != nil {
+		t.Fatalf("Profile not written: %v", err)
+	}
+	if !strings.Contains(string(content), "example.com/m/a/a.go:") {
+		t.Errorf("Expected profile to cover a/a.go, got:\n%s", content)
+	}
+
+	var stderr bytes.Buffer
+	if err := RunTests(dir, []string{"example.com/m/nope"}, nil, profile, io.Discard, &stderr); err == nil {
+		t.Errorf("Expected error for unknown package, got nil")
+	}
+}
+
+func mustGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := gitutil.Run(dir, args...); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+func mustWriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directories for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file %s: %v", path, err)
+	}
+}
+
+// TestTestPackages_Merge checks the per-package profiles of parallel runs
+// are merged.
+func TestTestPackages_Merge(t *testing.T) {
+	dir := setupRepo(t)
+	mustWriteFile(t, filepath.Join(dir, "b", "b_test.go"), "package b\n\nimport \"testing\"\n\nfunc TestB(t *testing.T) {\n\tif B() != 1 {\n\t\tt.Fatal(\"want 1\")\n\t}\n}\n")
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	pkgs := []string{"example.com/m/a", "example.com/m/b"}
+	var stdout bytes.Buffer
+	results, err := TestPackages(dir, pkgs, []string{"example.com/m/a"}, profile, 2, &stdout, io.Discard)
+	if err != nil || len(Failed(results)) > 0 {
+		t.Fatalf("TestPackages failed: %v, %+v", err, results)
+	}
+	content, err := os.ReadFile(profile)
+	if err != nil {
+		t.Fatalf("Profile not written: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "mode: set\nexample.com/m/a/a.go:") || strings.Count(string(content), "\n") != 2 {
+		t.Errorf("Expected one merged block of a/a.go, got:\n%s", content)
+	}
+	if !strings.Contains(stdout.String(), "example.com/m/a") || !strings.Contains(stdout.String(), "example.com/m/b") {
+		t.Errorf("Expected the output of both packages, got:\n%s", stdout.String())
+	}
+}
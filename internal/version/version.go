@@ -0,0 +1,83 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Build metadata, optionally set at link time:
+//
+//	go build -ldflags "-X github.com/JackShadow/go-new-code-coverage/internal/version.Version=v1.2.3"
+//
+// Anything left empty is filled in from the module build info.
+var (
+	Version = ""
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build metadata of the running binary.
+func Get() Info {
+	bi, ok := debug.ReadBuildInfo()
+	return fromBuildInfo(bi, ok)
+}
+
+// fromBuildInfo merges the link-time variables with the module build info.
+func fromBuildInfo(bi *debug.BuildInfo, ok bool) Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+	if ok && bi != nil {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		modified := false
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if modified && Commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+	if info.Version == "" {
+		info.Version = "devel"
+	}
+	return info
+}
+
+// Short returns just the version, e.g. "v1.2.3" or "devel".
+func (i Info) Short() string {
+	return i.Version
+}
+
+// String renders the full metadata on one line.
+func (i Info) String() string {
+	details := []string{}
+	if i.Commit != "" {
+		details = append(details, "commit "+i.Commit)
+	}
+	if i.Date != "" {
+		details = append(details, "built "+i.Date)
+	}
+	details = append(details, i.GoVersion)
+	return fmt.Sprintf("%s (%s)", i.Version, strings.Join(details, ", "))
+}
@@ -0,0 +1,62 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+// TestFromBuildInfo_Module checks metadata is taken from the build info.
+func TestFromBuildInfo_Module(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Main: debug.Module{Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "abc123"},
+			{Key: "vcs.time", Value: "2024-05-01T10:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	info := fromBuildInfo(bi, true)
+	if info.Version != "v1.4.0" || info.Commit != "abc123-dirty" || info.Date != "2024-05-01T10:00:00Z" {
+		t.Errorf("Unexpected info %+v", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("GoVersion = %q, want %q", info.GoVersion, runtime.Version())
+	}
+	want := "v1.4.0 (commit abc123-dirty, built 2024-05-01T10:00:00Z, " + runtime.Version() + ")"
+	if got := info.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// TestFromBuildInfo_Ldflags checks link-time variables take precedence.
+func TestFromBuildInfo_Ldflags(t *testing.T) {
+	Version, Commit, Date = "v2.0.0", "fff", "today"
+	defer func() { Version, Commit, Date = "", "", "" }()
+
+	bi := &debug.BuildInfo{
+		Main:     debug.Module{Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc123"}, {Key: "vcs.modified", Value: "true"}},
+	}
+	info := fromBuildInfo(bi, true)
+	if info.Version != "v2.0.0" || info.Commit != "fff" || info.Date != "today" {
+		t.Errorf("Unexpected info %+v", info)
+	}
+}
+
+// TestFromBuildInfo_Devel covers local builds without any metadata.
+func TestFromBuildInfo_Devel(t *testing.T) {
+	info := fromBuildInfo(&debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}, true)
+	if info.Short() != "devel" || info.Commit != "" {
+		t.Errorf("Unexpected info %+v", info)
+	}
+	if got, want := info.String(), "devel ("+runtime.Version()+")"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if info := fromBuildInfo(nil, false); info.Version != "devel" {
+		t.Errorf("Expected devel without build info, got %+v", info)
+	}
+	if Get().GoVersion == "" {
+		t.Errorf("Get() returned no Go version")
+	}
+}
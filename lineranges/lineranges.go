@@ -0,0 +1,172 @@
+// Package lineranges handles sets of line numbers stored as inclusive
+// [start, end] ranges, the form of the covered and uncovered lines in the
+// JSON report, for reporters and tools built on it.
+//
+// Functions returning ranges return them normalized: sorted, without
+// overlapping or adjacent ranges, and without empty ones (start > end). Their
+// inputs need not be normalized.
+package lineranges
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Group groups lines into contiguous ranges, e.g. 3, 4, 5, 9 into 3-5 and 9.
+func Group(lines []int) [][2]int {
+	if len(lines) == 0 {
+		return nil
+	}
+	sorted := append([]int(nil), lines...)
+	sort.Ints(sorted)
+
+	var ranges [][2]int
+	start, end := sorted[0], sorted[0]
+	for _, line := range sorted[1:] {
+		switch {
+		case line <= end:
+			// A repeated line.
+		case line == end+1:
+			end = line
+		default:
+			ranges = append(ranges, [2]int{start, end})
+			start, end = line, line
+		}
+	}
+	return append(ranges, [2]int{start, end})
+}
+
+// Lines returns the lines of ranges in order, each once.
+func Lines(ranges [][2]int) []int {
+	var lines []int
+	for _, r := range Normalize(ranges) {
+		for line := r[0]; line <= r[1]; line++ {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Count returns the number of lines in ranges, each counted once.
+func Count(ranges [][2]int) int {
+	n := 0
+	for _, r := range Normalize(ranges) {
+		n += r[1] - r[0] + 1
+	}
+	return n
+}
+
+// Contains reports whether line is in any of the ranges.
+func Contains(ranges [][2]int, line int) bool {
+	for _, r := range ranges {
+		if r[0] <= line && line <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize sorts ranges and merges those that overlap or touch.
+func Normalize(ranges [][2]int) [][2]int {
+	var sorted [][2]int
+	for _, r := range ranges {
+		if r[0] <= r[1] {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+
+	var merged [][2]int
+	for _, r := range sorted {
+		if n := len(merged); n > 0 && r[0] <= merged[n-1][1]+1 {
+			merged[n-1][1] = max(merged[n-1][1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Union returns the lines in a or b.
+func Union(a, b [][2]int) [][2]int {
+	return Normalize(append(append([][2]int(nil), a...), b...))
+}
+
+// Intersect returns the lines in both a and b.
+func Intersect(a, b [][2]int) [][2]int {
+	a, b = Normalize(a), Normalize(b)
+	var out [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start, end := max(a[i][0], b[j][0]), min(a[i][1], b[j][1])
+		if start <= end {
+			out = append(out, [2]int{start, end})
+		}
+		if a[i][1] < b[j][1] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// Subtract returns the lines in a that are not in b.
+func Subtract(a, b [][2]int) [][2]int {
+	a, b = Normalize(a), Normalize(b)
+	var out [][2]int
+	j := 0
+	for _, r := range a {
+		start := r[0]
+		for ; j < len(b) && b[j][1] < start; j++ {
+		}
+		for k := j; k < len(b) && b[k][0] <= r[1]; k++ {
+			if b[k][0] > start {
+				out = append(out, [2]int{start, b[k][0] - 1})
+			}
+			start = max(start, b[k][1]+1)
+		}
+		if start <= r[1] {
+			out = append(out, [2]int{start, r[1]})
+		}
+	}
+	return out
+}
+
+// Format formats ranges as in the uncovered lines of comments: single lines
+// as "9", longer ranges as "3-5", separated by ", ". Ranges are formatted as
+// given; normalize them first to merge them.
+func Format(ranges [][2]int) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r[0] == r[1] {
+			parts = append(parts, strconv.Itoa(r[0]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", r[0], r[1]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Parse parses ranges formatted by Format, e.g. "3-5, 9", and normalizes them.
+func Parse(s string) ([][2]int, error) {
+	var ranges [][2]int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		end := start
+		if err == nil && isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(to))
+		}
+		if err != nil || start < 1 || end < start {
+			return nil, fmt.Errorf("invalid line range %q", part)
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return Normalize(ranges), nil
+}
@@ -0,0 +1,72 @@
+package lineranges
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestGroup groups unsorted and repeated lines.
+func TestGroup(t *testing.T) {
+	tests := []struct {
+		lines []int
+		want  [][2]int
+	}{
+		{nil, nil},
+		{[]int{5}, [][2]int{{5, 5}}},
+		{[]int{3, 4, 5, 9}, [][2]int{{3, 5}, {9, 9}}},
+		{[]int{9, 3, 5, 4, 4}, [][2]int{{3, 5}, {9, 9}}},
+	}
+	for _, tt := range tests {
+		if got := Group(tt.lines); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Group(%v) = %v, want %v", tt.lines, got, tt.want)
+		}
+	}
+}
+
+// TestSetOperations covers overlapping, adjacent and disjoint ranges.
+func TestSetOperations(t *testing.T) {
+	a := [][2]int{{10, 20}, {1, 3}, {4, 5}, {30, 30}}
+	b := [][2]int{{5, 12}, {15, 15}, {19, 31}}
+
+	if got, want := Normalize(a), [][2]int{{1, 5}, {10, 20}, {30, 30}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Normalize = %v, want %v", got, want)
+	}
+	if got, want := Union(a, b), [][2]int{{1, 31}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Union = %v, want %v", got, want)
+	}
+	if got, want := Intersect(a, b), [][2]int{{5, 5}, {10, 12}, {15, 15}, {19, 20}, {30, 30}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Intersect = %v, want %v", got, want)
+	}
+	if got, want := Subtract(a, b), [][2]int{{1, 4}, {13, 14}, {16, 18}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Subtract = %v, want %v", got, want)
+	}
+	if got := Subtract(b, [][2]int{{1, 100}}); got != nil {
+		t.Errorf("Subtract of everything = %v, want nil", got)
+	}
+	if Count(a) != 17 || !Contains(a, 30) || Contains(a, 25) {
+		t.Errorf("Unexpected Count %d or Contains", Count(a))
+	}
+	if got, want := Lines([][2]int{{4, 5}, {1, 2}, {2, 2}}), []int{1, 2, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %v, want %v", got, want)
+	}
+}
+
+// TestFormatParse round-trips the comment format.
+func TestFormatParse(t *testing.T) {
+	ranges := [][2]int{{3, 3}, {7, 9}}
+	s := Format(ranges)
+	if s != "3, 7-9" {
+		t.Errorf("Format = %q", s)
+	}
+	if got, err := Parse(s); err != nil || !reflect.DeepEqual(got, ranges) {
+		t.Errorf("Parse(%q) = %v, %v", s, got, err)
+	}
+	if Format(nil) != "" {
+		t.Errorf("Format(nil) = %q", Format(nil))
+	}
+	for _, bad := range []string{"x", "5-3", "0", "1-"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/lsp"
+	"github.com/JackShadow/go-new-code-coverage/internal/version"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// lspSeverities maps -severity to diagnostic severities.
+var lspSeverities = map[string]lsp.Severity{
+	"error":       lsp.SeverityError,
+	"warning":     lsp.SeverityWarning,
+	"information": lsp.SeverityInformation,
+	"hint":        lsp.SeverityHint,
+}
+
+// runLSP serves the uncovered changed lines of the working tree as editor
+// diagnostics over the Language Server Protocol on stdin/stdout.
+func runLSP(args []string) int {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	root := fs.String("root", ".", "Module root containing go.mod")
+	base := fs.String("base", "origin/main", "Ref to diff the working tree against")
+	cover := fs.String("cover", "cover.out", "Coverage profile, relative to -root; diagnostics refresh when it changes")
+	configPath := fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present); reloaded when it changes")
+	preset := fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", "))
+	severity := fs.String("severity", "information", "Severity of the diagnostics: error, warning, information or hint")
+	interval := fs.Duration("interval", 2*time.Second, "How often to check the coverage profile and git state for changes")
+	fs.Parse(args)
+
+	// Stdout carries the protocol; log to stderr, which editors show in
+	// their language server output.
+	log.SetOutput(os.Stderr)
+	sev, ok := lspSeverities[*severity]
+	if !ok {
+		log.Printf("unknown severity %q", *severity)
+		return 1
+	}
+	wt, err := newWorktree(*root, *base, *cover, *configPath, *preset)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	l := &lspSession{worktree: wt, severity: sev}
+
+	s := &lsp.Server{
+		Name:        "diffcoverage",
+		Version:     version.Get().Version,
+		Diagnostics: l.diagnostics,
+		Interval:    *interval,
+		Logf:        log.Printf,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := s.Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		log.Print(err)
+		return 1
+	}
+	return 0
+}
+
+// lspSession maps the latest analysis of the working tree to diagnostics.
+type lspSession struct {
+	*worktree
+	severity lsp.Severity
+
+	diags map[string][]lsp.Diagnostic
+}
+
+func (l *lspSession) diagnostics(ctx context.Context) (map[string][]lsp.Diagnostic, error) {
+	_, r, changed, err := l.check()
+	if err != nil || !changed {
+		return l.diags, err
+	}
+	l.diags = map[string][]lsp.Diagnostic{}
+	if r == nil {
+		return l.diags, nil
+	}
+	for _, f := range r.Files {
+		path := filepath.Join(l.root, filepath.FromSlash(f.Path))
+		for _, rng := range f.Uncovered {
+			msg := "Changed line not covered by tests"
+			if n := rng[1] - rng[0] + 1; n > 1 {
+				msg = fmt.Sprintf("%d changed lines not covered by tests", n)
+			}
+			l.diags[path] = append(l.diags[path], lsp.Diagnostic{
+				Range:    lsp.Range{Start: lsp.Position{Line: rng[0] - 1}, End: lsp.Position{Line: rng[1]}},
+				Severity: l.severity,
+				Source:   "diffcoverage",
+				Message:  fmt.Sprintf("%s (diff coverage %.2f%%)", msg, r.Coverage),
+			})
+		}
+	}
+	return l.diags, nil
+}
@@ -1,52 +1,751 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/blame"
+	"github.com/JackShadow/go-new-code-coverage/internal/ci"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
 	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/exceptions"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+	"github.com/JackShadow/go-new-code-coverage/internal/testgen"
+	"github.com/JackShadow/go-new-code-coverage/internal/version"
+	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
+// commands maps subcommand names to their entry points. Each returns the process exit code.
+var commands = map[string]func(args []string) int{
+	"annotate-diff":       runAnnotateDiff,
+	"authors":             runAuthors,
+	"base-cache":          runBaseCache,
+	"archive":             runArchive,
+	"artifact":            runArtifact,
+	"ci":                  runCI,
+	"commits":             runCommits,
+	"compare":             runCompare,
+	"doctor":              runDoctor,
+	"explain":             runExplain,
+	"history":             runHistory,
+	"impact":              runImpact,
+	"init":                runInit,
+	"install-hook":        runInstallHook,
+	"mcp":                 runMCP,
+	"lsp":                 runLSP,
+	"merge":               runMerge,
+	"release-report":      runReleaseReport,
+	"run":                 runRun,
+	"scaffold-tests":      runScaffoldTests,
+	"self-update":         runSelfUpdate,
+	"serve":               runServe,
+	"serve-grpc":          runServeGRPC,
+	"show":                runShow,
+	"uncovered-functions": runUncoveredFunctions,
+	"verify":              runVerify,
+	"watch":               runWatch,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
 	verboseFlag := flag.Bool("vvv", false, "Verbose output: list lines not covered")
 	minCoverageFlag := flag.Float64("min", 0.0, "Minimum coverage percentage (e.g., 80.0)")
+	flag.Float64("min-functions", 0.0, "Minimum percentage of changed functions that must be fully covered")
 	flag.BoolVar(verboseFlag, "verbose", false, "Verbose output: list lines not covered")
+	noPagerFlag := flag.Bool("no-pager", false, "Do not page the verbose output through $PAGER when it does not fit the terminal")
+	formatFlag := flag.String("format", "text", "Output format: text, json (the JSON report), jsonl (JSON Lines), cobertura (Cobertura XML), sarif (SARIF for code scanning) or junit (JUnit XML); unless text, messages go to stderr")
+	outputFlag := flag.String("output", "", "File to write the output to instead of stdout")
+	versionFlag := flag.Bool("version", false, "Print version information and exit")
+	configFlag := flag.String("config", "", "Path to the configuration file (default: <source_root>/"+config.FileName+" if present)")
+	presetFlag := flag.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", "))
+	blameFlag := flag.Bool("blame", false, "Attribute uncovered lines to authors and commits with git blame")
+	skeletonsFlag := flag.Bool("test-skeletons", false, "Generate test skeletons for new, uncovered functions and post them as GitHub review comments")
+	testFilesFlag := flag.Bool("require-test-files", false, "Fail if the diff adds non-generated files to a package without any _test.go file")
+	unreachableFlag := flag.Bool("ignore-unreachable", false, "Do not count changed blocks that only panic, log.Fatal or os.Exit")
+	errReturnsFlag := flag.Bool("ignore-error-returns", false, "Do not count changed if err != nil { return ..., err } statements")
+	wholeFuncsFlag := flag.Bool("whole-functions", false, "Also require the minimum coverage of all lines of the touched functions, changed or not")
+	maxLineAgeFlag := flag.Int("max-line-age", 0, "With -whole-functions, leave out unchanged lines last changed more than this many days ago, according to git blame")
+	coverModeFlag := flag.String("require-covermode", "", "Fail if the profile was generated with a weaker -covermode than this: set, count or atomic")
+	gradeFlag := flag.Bool("grade", false, "Grade the coverage A to F, or by the bands of grading in the configuration")
+	profileCheckFlag := flag.String("profile-check", "", "What an implausible coverage profile does: warn (default), fail or off")
+	minProfileStmtsFlag := flag.Float64("min-profile-statements", 0, "Percentage of the module's statements the coverage profile must have to be plausible (0: not checked)")
+	sortFlag := flag.String("sort", "", "Order of the files in every output: file, coverage (lowest first) or uncovered (most uncovered lines first); ties by path")
+	excludeFuncsFlag := flag.String("exclude-functions", "", "Comma-separated patterns of function names whose changed lines are not counted, e.g. String,DeepCopy*,/^Marshal.*JSON$/")
+	testChangesFlag := flag.String("require-test-changes", "", "Comma-separated glob patterns of files whose changed functions fail the run if their package tests are not changed")
+	profileCommitFlag := flag.String("profile-commit", "", "Commit the coverage profile was generated at; the run fails if it is not the head commit of the diff")
+	headCommitFlag := flag.String("head-commit", "", "Head commit of the diff (default: HEAD of source_root)")
+	covdataFlag := flag.String("covdata", "", "Comma-separated GOCOVERDIR directories of binaries built with go build -cover to merge with cover.out (\"-\" for none)")
+	modulesFlag := flag.Bool("modules", false, "Break the results down by the Go modules under the source roots")
+	addPolicyURLFlags(flag.CommandLine)
+	publish := addPublishFlags(flag.CommandLine)
 
 	flag.Parse()
 
-	if flag.NArg() < 3 {
-		fmt.Println("Usage: diffcoverage [options] <cover.out> <diff.txt> <source_root>")
+	if *versionFlag {
+		fmt.Printf("diffcoverage %s\n", version.Get())
+		return
+	}
+
+	if flag.NArg() < 3 || flag.NArg()%3 != 0 {
+		fmt.Println("Usage: diffcoverage [options] <cover.out> <diff.txt> <source_root> [<cover.out> <diff.txt> <source_root>...]")
 		fmt.Println("Options:")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	coverPath := flag.Arg(0)
-	diffPath := flag.Arg(1)
+	if !slices.Contains(reportFormats, *formatFlag) {
+		fmt.Printf("unknown -format %q: must be one of %s\n", *formatFlag, strings.Join(reportFormats, ", "))
+		os.Exit(1)
+	}
+	log := messageWriter(*formatFlag, *outputFlag)
+
+	// The configuration of the first source root applies to all targets.
 	sourceRoot := flag.Arg(2)
 
-	coveragePercent, uncovered, err := diffcoverage.RunDiffCoverage(coverPath, diffPath, sourceRoot, *minCoverageFlag)
+	var branch string
+	if env := ci.Detect(os.Getenv); env != nil {
+		branch = env.TargetBranch()
+	}
+	cfg, err := loadConfig(log, flag.CommandLine, *configFlag, *presetFlag, sourceRoot, branch, *minCoverageFlag)
+	if err != nil {
+		fmt.Fprintln(log, err.Error())
+		os.Exit(1)
+	}
+	cfg.Blame = cfg.Blame || *blameFlag
+	cfg.TestSkeletons = cfg.TestSkeletons || *skeletonsFlag
+	cfg.RequireTestFiles = cfg.RequireTestFiles || *testFilesFlag
+	cfg.IgnoreUnreachable = cfg.IgnoreUnreachable || *unreachableFlag
+	cfg.IgnoreErrorReturns = cfg.IgnoreErrorReturns || *errReturnsFlag
+	cfg.WholeFunctions = cfg.WholeFunctions || *wholeFuncsFlag
+	setGrade(cfg, *gradeFlag)
+	if err := setMaxLineAge(cfg, *maxLineAgeFlag); err != nil {
+		fmt.Fprintln(log, err.Error())
+		os.Exit(1)
+	}
+	if err := setProfileCheck(cfg, *profileCheckFlag, *minProfileStmtsFlag); err != nil {
+		fmt.Fprintln(log, err.Error())
+		os.Exit(1)
+	}
+	if err := setCoverMode(cfg, *coverModeFlag); err != nil {
+		fmt.Fprintln(log, err.Error())
+		os.Exit(1)
+	}
+	cfg.RequireTestChanges = append(cfg.RequireTestChanges, splitList(*testChangesFlag)...)
+	if err := setExcludeFunctions(cfg, *excludeFuncsFlag); err != nil {
+		fmt.Fprintln(log, err.Error())
+		os.Exit(1)
+	}
+	if err := setSort(cfg, *sortFlag); err != nil {
+		fmt.Fprintln(log, err.Error())
+		os.Exit(1)
+	}
+	applyLabels(log, publish, cfg)
+
+	targets := flag.NArg() / 3
+	if targets > 1 && (*covdataFlag != "" || *profileCommitFlag != "" || *headCommitFlag != "") {
+		fmt.Fprintln(log, "-covdata, -profile-commit and -head-commit take a single target")
+		os.Exit(1)
+	}
+	var names []string
+	var reports []*diffcoverage.Report
+	var modules []diffcoverage.Module
+	var cleanups []func()
+	for i := 0; i < targets; i++ {
+		coverPath, diffPath, root := flag.Arg(3*i), flag.Arg(3*i+1), flag.Arg(3*i+2)
+		r, cleanup, err := evaluateTarget(coverPath, diffPath, root, cfg, *profileCommitFlag, *headCommitFlag, splitList(*covdataFlag))
+		if err != nil {
+			fmt.Fprintln(log, err.Error())
+			os.Exit(1)
+		}
+		cleanups = append(cleanups, cleanup)
+		name := filepath.ToSlash(filepath.Clean(root))
+		names, reports = append(names, name), append(reports, r)
+		if *modulesFlag {
+			found, err := diffcoverage.FindModules(root)
+			if err != nil {
+				fmt.Fprintln(log, err.Error())
+				os.Exit(1)
+			}
+			for _, m := range found {
+				if targets > 1 {
+					m.Dir = path.Join(name, m.Dir)
+				}
+				modules = append(modules, m)
+			}
+		}
+	}
+	r := reports[0]
+	if targets > 1 {
+		r = diffcoverage.Combine(names, reports, cfg.MinCoverage)
+		r.SourceRoot = "."
+	}
+	if *modulesFlag {
+		r.SplitModules(modules)
+	}
+
+	code := finish(r, *formatFlag, *outputFlag, *verboseFlag, !*noPagerFlag, publish, cfg)
+	for _, cleanup := range cleanups {
+		cleanup()
+	}
+	os.Exit(code)
+}
+
+// evaluateTarget verifies the commits of a target (see verifyCommits),
+// merges the GOCOVERDIR directories into its profile and evaluates it. The
+// merged profile, which the report refers to, is removed by cleanup.
+func evaluateTarget(coverPath, diffPath, sourceRoot string, cfg *config.Config, profileCommit, headCommit string, covdata []string) (r *diffcoverage.Report, cleanup func(), err error) {
+	profileCommit, headCommit, err = verifyCommits(sourceRoot, profileCommit, headCommit)
+	if err != nil {
+		return nil, nil, err
+	}
+	coverPath, cleanup, err = withCovData(coverPath, sourceRoot, covdata)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r, err = evaluate(coverPath, diffPath, sourceRoot, cfg); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	r.Commit, r.ProfileCommit = headCommit, profileCommit
+	return r, cleanup, nil
+}
+
+// withCovData returns coverPath merged with the GOCOVERDIR directories in a
+// temporary profile, removed by cleanup. Without directories coverPath is
+// returned as is; "-" stands for no profile besides the directories.
+func withCovData(coverPath, sourceRoot string, dirs []string) (path string, cleanup func(), err error) {
+	if len(dirs) == 0 {
+		return coverPath, func() {}, nil
+	}
+	f, err := os.CreateTemp("", "diffcoverage-*.out")
+	if err != nil {
+		return "", nil, err
+	}
+	f.Close()
+	cleanup = func() { os.Remove(f.Name()) }
+
+	var profiles []string
+	if coverPath != "-" {
+		profiles = append(profiles, coverPath)
+	}
+	if err := mergeProfilesTo(f.Name(), sourceRoot, profiles, dirs); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return f.Name(), cleanup, nil
+}
+
+// loadConfig resolves the configuration (see resolveConfig) and preset and
+// applies the thresholds of the target branch, if known; explicitly set -min
+// and -min-functions flags override all of them.
+// Messages go to log.
+func loadConfig(log io.Writer, fs *flag.FlagSet, path, preset, sourceRoot, branch string, minCoverage float64) (*config.Config, error) {
+	cfg, err := resolveConfig(log, fs, path, preset, sourceRoot)
+	if err != nil {
+		return nil, err
+	}
+	if pattern := cfg.ApplyBranch(branch); pattern != "" {
+		fmt.Fprintf(log, "Using the thresholds of %s for target branch %s\n", pattern, branch)
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "min":
+			cfg.MinCoverage = minCoverage
+		case "min-functions":
+			cfg.MinFunctionCoverage = f.Value.(flag.Getter).Get().(float64)
+		}
+	})
+	return cfg, nil
+}
+
+// evaluate runs the diff coverage analysis with the given configuration.
+func evaluate(coverPath, diffPath, sourceRoot string, cfg *config.Config) (*diffcoverage.Report, error) {
+	_, r, err := analyze(coverPath, diffPath, sourceRoot, cfg)
+	return r, err
+}
+
+// analyze is evaluate, also returning the analysis the report was computed from.
+func analyze(coverPath, diffPath, sourceRoot string, cfg *config.Config) (*diffcoverage.Analysis, *diffcoverage.Report, error) {
+	a, err := diffcoverage.Analyze(coverPath, diffPath, sourceRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.RequireCoverMode != "" {
+		if err := a.RequireCoverMode(cfg.RequireCoverMode); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := excludeCode(a, cfg); err != nil {
+		return nil, nil, err
+	}
+	exempted, err := applyExceptions(a, sourceRoot, cfg.Exceptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := a.Report(cfg.MinCoverage)
+	r.ApplyExceptions(exempted)
+	funcs, err := a.Functions()
+	if err != nil {
+		return nil, nil, err
+	}
+	r.ApplyFunctions(funcs, cfg.MinFunctionCoverage)
+	if cfg.WholeFunctions {
+		if err := applyWholeFunctions(a, r, funcs, cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+	r.ApplyTestFiles(a.FilesWithoutTests(), cfg.RequireTestFiles)
+	untested, err := a.UntestedChanges()
+	if err != nil {
+		return nil, nil, err
+	}
+	r.ApplyUntestedChanges(untested, cfg.RequireTestChanges)
+	if cfg.ProfileCheck != "off" {
+		problems, err := a.ProfileProblems(r, cfg.MinProfileStatements)
+		if err != nil {
+			return nil, nil, err
+		}
+		r.ApplyProfileProblems(problems, cfg.ProfileCheck == "fail")
+	}
+	if cfg.CodeOwners != nil {
+		if err := applyOwners(r, cfg.CodeOwners); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cfg.Blame {
+		if err := blame.Attribute(r); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cfg.TestSkeletons {
+		if err := testgen.Attach(a, r, diffPath); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cfg.Grading != nil {
+		r.ApplyGrades(cfg.Grading.GradeBands())
+	}
+	if len(cfg.Policies) > 0 {
+		if err := applyPolicies(r, cfg.Policies); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(cfg.AppliedLabels) > 0 {
+		r.ApplyOverrides(labelOverrides(cfg.AppliedLabels))
+	}
+	return a, r, nil
+}
+
+// overrideNote describes what the label override o changed.
+func overrideNote(o diffcoverage.LabelOverride) string {
+	var changes []string
+	if o.MinCoverage != nil {
+		changes = append(changes, fmt.Sprintf("minimum %.2f%%", *o.MinCoverage))
+	}
+	if o.MinFunctionCoverage != nil {
+		changes = append(changes, fmt.Sprintf("minimum function coverage %.2f%%", *o.MinFunctionCoverage))
+	}
+	if o.Warn {
+		changes = append(changes, "failures as warnings")
+	}
+	return o.Label + ": " + strings.Join(changes, ", ")
+}
+
+// reportFormats lists the output formats of the default command.
+var reportFormats = []string{"text", "json", "jsonl", "cobertura", "sarif", "junit"}
+
+// writeReport writes the report to w in format, paging verbose text output if
+// paged is set.
+func writeReport(w io.Writer, r *diffcoverage.Report, format string, verbose, paged bool) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "jsonl":
+		return reporter.WriteJSONL(w, r)
+	case "cobertura":
+		return reporter.WriteCobertura(w, r)
+	case "sarif":
+		return reporter.WriteSARIF(w, r)
+	case "junit":
+		return reporter.WriteJUnit(w, r)
+	}
+	if verbose && paged {
+		var buf bytes.Buffer
+		printResult(&buf, r, verbose)
+		page(buf.Bytes())
+		return nil
+	}
+	printResult(w, r, verbose)
+	return nil
+}
+
+// labelOverrides converts the applied labels of the configuration for the report.
+func labelOverrides(labels []config.Label) []diffcoverage.LabelOverride {
+	overrides := make([]diffcoverage.LabelOverride, len(labels))
+	for i, l := range labels {
+		overrides[i] = diffcoverage.LabelOverride{Label: l.Name, MinCoverage: l.MinCoverage, MinFunctionCoverage: l.MinFunctionCoverage, Warn: l.Warn}
+	}
+	return overrides
+}
+
+// applyWholeFunctions holds the touched functions to the minimum coverage as
+// a whole, leaving out the unchanged lines older than cfg.MaxLineAge days.
+func applyWholeFunctions(a *diffcoverage.Analysis, r *diffcoverage.Report, funcs []diffcoverage.FuncReport, cfg *config.Config) error {
+	if cfg.MaxLineAge == 0 {
+		r.ApplyWholeFunctions(funcs, cfg.MinCoverage)
+		return nil
+	}
+	var files []string
+	for _, fn := range funcs {
+		if len(files) == 0 || files[len(files)-1] != fn.File {
+			files = append(files, fn.File)
+		}
+	}
+	recent, err := blame.Since(a.SourceRoot, files, time.Now().AddDate(0, 0, -cfg.MaxLineAge))
 	if err != nil {
-		// Could be coverage below threshold or parse error
-		fmt.Println(err.Error())
+		return err
+	}
+	scoped, err := a.FunctionsWhere(func(file string, line int) bool { return recent[file][line] })
+	if err != nil {
+		return err
+	}
+	r.ApplyWholeFunctions(scoped, cfg.MinCoverage)
+	r.FunctionScope.MaxAgeDays = cfg.MaxLineAge
+	for _, fn := range funcs {
+		r.FunctionScope.OldLines += fn.TotalLines
+	}
+	r.FunctionScope.OldLines -= r.FunctionScope.TotalLines
+	return nil
+}
+
+// setMaxLineAge overrides the maximum line age of cfg with a non-zero age.
+func setMaxLineAge(cfg *config.Config, days int) error {
+	if days < 0 {
+		return fmt.Errorf("invalid -max-line-age %d: must not be negative", days)
+	}
+	if days > 0 {
+		cfg.MaxLineAge = days
+	}
+	return nil
+}
+
+// setGrade enables the grading of cfg, with its bands if it has any.
+func setGrade(cfg *config.Config, grade bool) {
+	if grade && cfg.Grading == nil {
+		cfg.Grading = &config.Grading{}
 	}
+}
 
+// setProfileCheck overrides the profile check of cfg with a non-empty check
+// and its minimum share of the module's statements with a non-zero one.
+func setProfileCheck(cfg *config.Config, check string, minStatements float64) error {
+	if !diffcoverage.ValidProfileCheck(check) {
+		return fmt.Errorf("invalid -profile-check %q: must be warn, fail or off", check)
+	}
+	if minStatements < 0 || minStatements > 100 {
+		return fmt.Errorf("invalid -min-profile-statements %v: must be between 0 and 100", minStatements)
+	}
+	if check != "" {
+		cfg.ProfileCheck = check
+	}
+	if minStatements > 0 {
+		cfg.MinProfileStatements = minStatements
+	}
+	return nil
+}
+
+// setCoverMode overrides the required cover mode of cfg with a non-empty mode.
+func setCoverMode(cfg *config.Config, mode string) error {
+	if mode == "" {
+		return nil
+	}
+	if !diffcoverage.ValidCoverMode(mode) {
+		return fmt.Errorf("invalid -require-covermode %q: must be set, count or atomic", mode)
+	}
+	cfg.RequireCoverMode = mode
+	return nil
+}
+
+// setSort overrides the file order of cfg with a non-empty order.
+func setSort(cfg *config.Config, order string) error {
+	if order == "" {
+		return nil
+	}
+	if !diffcoverage.ValidSortOrder(order) {
+		return fmt.Errorf("invalid -sort %q: must be file, coverage or uncovered", order)
+	}
+	cfg.Sort = order
+	return nil
+}
+
+// setExcludeFunctions adds the comma-separated function name patterns to
+// those of cfg.
+func setExcludeFunctions(cfg *config.Config, patterns string) error {
+	list := splitList(patterns)
+	if _, err := diffcoverage.NewFuncMatcher(list); err != nil {
+		return fmt.Errorf("invalid -exclude-functions: %v", err)
+	}
+	cfg.ExcludeFunctions = append(cfg.ExcludeFunctions, list...)
+	return nil
+}
+
+// exclusion is a step of excludeCode, named by the setting enabling it.
+type exclusion struct {
+	setting string
+	apply   func(a *diffcoverage.Analysis) error
+}
+
+// exclusions returns the steps of excludeCode enabled by cfg, in order.
+func exclusions(cfg *config.Config) []exclusion {
+	steps := []exclusion{
+		{"exclude", func(a *diffcoverage.Analysis) error { a.Exclude(cfg.Exclude); return nil }},
+		{"exclude_functions", func(a *diffcoverage.Analysis) error { return a.ExcludeFunctionNames(cfg.ExcludeFunctions) }},
+	}
+	if cfg.IgnoreUnreachable {
+		steps = append(steps, exclusion{"ignore_unreachable", (*diffcoverage.Analysis).ExcludeUnreachable})
+	}
+	if cfg.IgnoreErrorReturns {
+		steps = append(steps, exclusion{"ignore_error_returns", (*diffcoverage.Analysis).ExcludeErrorReturns})
+	}
+	return steps
+}
+
+// excludeCode drops the changed files and code the configuration does not
+// count from a.
+func excludeCode(a *diffcoverage.Analysis, cfg *config.Config) error {
+	for _, step := range exclusions(cfg) {
+		if err := step.apply(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyExceptions exempts the lines covered by the exceptions file from a.
+func applyExceptions(a *diffcoverage.Analysis, sourceRoot, file string) ([]diffcoverage.Exception, error) {
+	if file == "" {
+		file = exceptions.Find(sourceRoot)
+	} else if !filepath.IsAbs(file) {
+		file = filepath.Join(sourceRoot, file)
+	}
+	if file == "" {
+		return nil, nil
+	}
+	list, err := exceptions.Load(file)
+	if err != nil {
+		return nil, err
+	}
+	return exceptions.Apply(a, list, time.Now())
+}
+
+// finish prints the report in format (see reportFormats) to the output file,
+// or stdout if empty, publishes it and returns the process exit code. Unless
+// the text goes to stdout, messages go to stderr.
+func finish(r *diffcoverage.Report, format, output string, verbose, pager bool, publish *publishFlags, cfg *config.Config) int {
+	exitCode := 0
+	log := messageWriter(format, output)
+	publish.out = log
+	if err := r.SortFiles(cfg.Sort); err != nil {
+		fmt.Fprintln(log, err.Error())
+		return 1
+	}
+	if err := r.Err(); err != nil {
+		fmt.Fprintln(log, err.Error())
+		exitCode = 1
+	}
+
+	// The regressions are part of the report.
+	if err := checkTrend(log, r, publish.env().Branch, cfg); err != nil {
+		fmt.Fprintln(log, err.Error())
+		exitCode = 1
+	}
+	var out io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintln(log, err.Error())
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writeReport(out, r, format, verbose, pager && output == ""); err != nil {
+		fmt.Fprintln(log, err.Error())
+		exitCode = 1
+	}
+	if err := publish.publish(r, cfg); err != nil {
+		fmt.Fprintln(log, err.Error())
+		exitCode = 1
+	}
+	return exitCode
+}
+
+// messageWriter returns where the messages of a run go: stderr if the output
+// in format goes to stdout and is not text, stdout otherwise.
+func messageWriter(format, output string) io.Writer {
+	if format != "text" && output == "" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printResult writes the coverage summary and, in verbose mode, the uncovered line ranges.
+func printResult(w io.Writer, r *diffcoverage.Report, verbose bool) {
 	// If user wants verbose output, show uncovered lines
-	if *verboseFlag && len(uncovered) > 0 {
-		fmt.Println("Uncovered lines:")
-		for file, lines := range uncovered {
-			ranges := diffcoverage.GroupLinesIntoRanges(lines)
-			fmt.Printf("\tFile: %s\n", file)
-			for _, r := range ranges {
+	if verbose && r.CoveredLines < r.TotalLines {
+		fmt.Fprintln(w, "Uncovered lines:")
+		for _, f := range r.Files {
+			if len(f.Uncovered) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "\tFile: %s\n", f.Path)
+			for _, r := range f.Uncovered {
 				if r[0] == r[1] {
-					fmt.Printf("\t- %d\n", r[0])
+					fmt.Fprintf(w, "\t- %d\n", r[0])
 				} else {
-					fmt.Printf("\t- %d-%d\n", r[0], r[1])
+					fmt.Fprintf(w, "\t- %d-%d\n", r[0], r[1])
 				}
 			}
-			fmt.Println()
+			fmt.Fprintln(w)
 		}
 	}
 
-	fmt.Printf("New/Changed lines coverage in functions: %.2f%%\n", coveragePercent)
+	if len(r.ProfileProblems) > 0 {
+		fmt.Fprintln(w, "WARNING: the coverage profile looks implausible; the coverage below may mean nothing:")
+		for _, p := range r.ProfileProblems {
+			fmt.Fprintf(w, "\t%s\n", p)
+		}
+	}
+	fmt.Fprintf(w, "New/Changed lines coverage in functions: %.2f%%\n", r.Coverage)
+	if r.Grade != "" {
+		fmt.Fprintf(w, "Grade: %s\n", r.Grade)
+	}
+	if r.ChangedFunctions > 0 {
+		fmt.Fprintf(w, "Fully covered changed functions: %d of %d (%.2f%%)\n", r.CoveredFunctions, r.ChangedFunctions, r.FunctionCoverage)
+	}
+	if s := r.FunctionScope; s != nil {
+		fmt.Fprintf(w, "Coverage of the touched functions: %.2f%% (%d/%d lines)", s.Coverage, s.CoveredLines, s.TotalLines)
+		if s.MaxAgeDays > 0 {
+			fmt.Fprintf(w, ", not counting %d lines older than %d days", s.OldLines, s.MaxAgeDays)
+		}
+		fmt.Fprintln(w)
+		if verbose {
+			for _, fn := range s.Functions {
+				fmt.Fprintf(w, "\t%s:%d %s: %d/%d lines\n", fn.File, fn.Line, fn.Name, fn.CoveredLines, fn.TotalLines)
+			}
+		}
+	}
+	if t := r.Tests; t != nil {
+		fmt.Fprintf(w, "Tests: %d of %d packages passed in %.1fs\n", len(t.Packages)-t.Failed, len(t.Packages), t.Elapsed)
+		if t.Failed > 0 {
+			fmt.Fprintf(w, "Coverage produced by %d failing packages is ignored:\n", t.Failed)
+			for _, p := range t.Packages {
+				if p.Result != "fail" {
+					continue
+				}
+				fmt.Fprintf(w, "\t%s", p.Package)
+				if len(p.FailedTests) > 0 {
+					fmt.Fprintf(w, " (%s)", strings.Join(p.FailedTests, ", "))
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+	for _, t := range r.Targets {
+		fmt.Fprintf(w, "\t%s: %.2f%% (%d/%d lines)", t.Name, t.Coverage, t.CoveredLines, t.TotalLines)
+		if t.Grade != "" {
+			fmt.Fprintf(w, ", grade %s", t.Grade)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, o := range r.Owners {
+		fmt.Fprintf(w, "\t%s: %.2f%% (%d/%d lines)", o.Owner, o.Coverage, o.CoveredLines, o.TotalLines)
+		if o.MinCoverage > 0 {
+			fmt.Fprintf(w, ", minimum %.2f%%", o.MinCoverage)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(r.Uninstrumented) > 0 {
+		fmt.Fprintln(w, "Packages not instrumented by the profile; run the tests with -coverpkg=./... or include their tests:")
+		for _, p := range r.Uninstrumented {
+			fmt.Fprintf(w, "\t./%s (%d changed lines)\n", p.Package, p.TotalLines)
+		}
+	}
+	if len(r.FilesWithoutTests) > 0 {
+		fmt.Fprintln(w, "New files in packages without tests:")
+		for _, f := range r.FilesWithoutTests {
+			fmt.Fprintf(w, "\t%s\n", f)
+		}
+	}
+	if len(r.Exceptions) > 0 {
+		fmt.Fprintln(w, "Coverage exceptions:")
+		for _, e := range r.Exceptions {
+			state := "until"
+			if e.Expired {
+				state = "EXPIRED on"
+			}
+			fmt.Fprintf(w, "\t%s (%s, %s %s)\n", e, e.Owner, state, e.Expires)
+		}
+	}
+	if len(r.Policies) > 0 {
+		fmt.Fprintln(w, "Policies:")
+		for _, p := range r.Policies {
+			result := "passed"
+			switch {
+			case p.Error != "":
+				result = "error: " + p.Error
+			case !p.Passed:
+				result = "failed"
+			}
+			fmt.Fprintf(w, "\t%s: %s\n", p.Name, result)
+		}
+	}
+	if len(r.Overrides) > 0 {
+		fmt.Fprintln(w, "Overrides of pull request labels:")
+		for _, o := range r.Overrides {
+			fmt.Fprintf(w, "\t%s\n", overrideNote(o))
+		}
+	}
+	if len(r.Warnings) > 0 {
+		fmt.Fprintln(w, "WARNING: failures reported as warnings because of the pull request labels:")
+		for _, msg := range r.Warnings {
+			fmt.Fprintf(w, "\t%s\n", msg)
+		}
+	}
+	if len(r.UntestedChanges) > 0 {
+		fmt.Fprintln(w, "Changed functions whose package tests were not changed:")
+		for _, c := range r.UntestedChanges {
+			fmt.Fprintf(w, "\t%s:%d: %s", c.File, c.Line, c.Func)
+			if c.Required {
+				fmt.Fprint(w, " (required)")
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(r.Survivors) > 0 {
+		fmt.Fprintln(w, "Covered lines no test asserts on (all mutants survived):")
+		for _, s := range r.Survivors {
+			fmt.Fprintf(w, "\t%s:%d: %s\n", s.File, s.Line, strings.Join(s.Mutations, ", "))
+		}
+	}
+	if len(r.Authors) > 0 {
+		fmt.Fprintln(w, "Uncovered lines by author:")
+		for _, a := range r.Authors {
+			fmt.Fprintf(w, "\t%s <%s>: %d lines in %d commits\n", a.Author, a.Email, a.UncoveredLines, len(a.Commits))
+		}
+	}
 }
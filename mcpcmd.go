@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/mcp"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+	"github.com/JackShadow/go-new-code-coverage/internal/testrun"
+	"github.com/JackShadow/go-new-code-coverage/internal/version"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+)
+
+// runMCP serves the analysis of the working tree as MCP tools on stdin/stdout.
+func runMCP(args []string) int {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	root := fs.String("root", ".", "Module root containing go.mod")
+	base := fs.String("base", "origin/main", "Default ref to diff the working tree against")
+	configPath := fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present)")
+	preset := fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", "))
+	fs.Parse(args)
+
+	cfg, err := config.Resolve(*configPath, *root, *preset)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	m := &mcpSession{root: *root, base: *base, cfg: cfg}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := m.server().Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+// mcpSession keeps the latest analysis for the follow-up tools. Stdout
+// carries the protocol, so nothing else may be printed there.
+type mcpSession struct {
+	root string
+	base string
+	cfg  *config.Config
+
+	analysis *diffcoverage.Analysis
+	report   *diffcoverage.Report
+}
+
+func (m *mcpSession) server() *mcp.Server {
+	return &mcp.Server{
+		Name:    "diffcoverage",
+		Version: version.Get().Version,
+		Instructions: "Finds new and changed Go lines in the working tree that no test covers. " +
+			"Call analyze first (it runs the tests of the changed packages), then list-uncovered and explain-line to plan tests.",
+		Tools: []mcp.Tool{
+			{
+				Name:        "analyze",
+				Description: "Run the tests of the packages changed since the base ref and report the diff coverage of the working tree.",
+				InputSchema: schema(map[string]any{
+					"base":    prop("string", "Ref to diff against (default: "+m.base+")"),
+					"profile": prop("string", "Use this existing coverage profile instead of running the tests"),
+				}),
+				Call: m.analyze,
+			},
+			{
+				Name:        "list-uncovered",
+				Description: "List the new/changed lines the tests do not cover, with their source, from the latest analysis.",
+				InputSchema: schema(map[string]any{
+					"file": prop("string", "Only list this file, relative to the module root"),
+				}),
+				Call: m.listUncovered,
+			},
+			{
+				Name:        "explain-line",
+				Description: "Explain whether and why a line counts as covered, uncovered or not counted in the latest analysis.",
+				InputSchema: schema(map[string]any{
+					"file": prop("string", "File relative to the module root"),
+					"line": prop("integer", "Line number"),
+				}, "file", "line"),
+				Call: m.explainLine,
+			},
+		},
+	}
+}
+
+func schema(props map[string]any, required ...string) map[string]any {
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func prop(typ, description string) map[string]any {
+	return map[string]any{"type": typ, "description": description}
+}
+
+func (m *mcpSession) analyze(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Base    string `json:"base"`
+		Profile string `json:"profile"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", err
+	}
+	if in.Base == "" {
+		in.Base = m.base
+	}
+
+	tmpDir, err := os.MkdirTemp("", "diffcoverage-mcp")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+	diffPath := filepath.Join(tmpDir, "diff.txt")
+	if err := testrun.WriteDiff(m.root, in.Base, diffPath); err != nil {
+		return "", fmt.Errorf("error computing diff: %v", err)
+	}
+
+	profile := in.Profile
+	if profile == "" {
+		profile = filepath.Join(tmpDir, "cover.out")
+		changedPkgs, testPkgs, err := impactedPackages(m.root, diffPath, m.cfg)
+		if err != nil {
+			return "", err
+		}
+		if len(testPkgs) == 0 {
+			return fmt.Sprintf("No Go packages changed since %s.", in.Base), nil
+		}
+		var out bytes.Buffer
+		if err := testrun.RunTests(m.root, testPkgs, changedPkgs, profile, &out, &out); err != nil {
+			return "", fmt.Errorf("%v\n\n%s", err, out.String())
+		}
+	}
+
+	a, r, err := analyze(profile, diffPath, m.root, m.cfg)
+	if err != nil {
+		return "", err
+	}
+	m.analysis, m.report = a, r
+	return fmt.Sprintf("Diff against %s.\n\n%s", in.Base, reporter.Markdown(r)), nil
+}
+
+// latest returns the latest analysis, running one against the default base
+// if there is none yet.
+func (m *mcpSession) latest(ctx context.Context) (*diffcoverage.Analysis, *diffcoverage.Report, error) {
+	if m.analysis == nil {
+		if _, err := m.analyze(ctx, json.RawMessage("{}")); err != nil {
+			return nil, nil, err
+		}
+	}
+	if m.analysis == nil {
+		return nil, nil, fmt.Errorf("no Go packages changed since %s", m.base)
+	}
+	return m.analysis, m.report, nil
+}
+
+func (m *mcpSession) listUncovered(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		File string `json:"file"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", err
+	}
+	_, r, err := m.latest(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, f := range r.Files {
+		if len(f.Uncovered) == 0 || (in.File != "" && f.Path != filepath.ToSlash(filepath.Clean(in.File))) {
+			continue
+		}
+		lines, _ := readLines(filepath.Join(m.root, f.Path))
+		for _, rng := range f.Uncovered {
+			for n := rng[0]; n <= rng[1]; n++ {
+				text := ""
+				if n <= len(lines) {
+					text = lines[n-1]
+				}
+				fmt.Fprintf(&sb, "%s:%d: %s\n", f.Path, n, text)
+			}
+		}
+	}
+	if sb.Len() == 0 {
+		return "All new/changed lines are covered.", nil
+	}
+	return sb.String(), nil
+}
+
+func (m *mcpSession) explainLine(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		File string `json:"file"`
+		Line int    `json:"line"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", err
+	}
+	if in.File == "" || in.Line <= 0 {
+		return "", fmt.Errorf("file and a positive line are required")
+	}
+	a, _, err := m.latest(ctx)
+	if err != nil {
+		return "", err
+	}
+	rel := filepath.ToSlash(filepath.Clean(in.File))
+
+	var sb strings.Builder
+	if lines, err := readLines(filepath.Join(m.root, rel)); err == nil && in.Line <= len(lines) {
+		fmt.Fprintf(&sb, "%s:%d: %s\n\n", rel, in.Line, strings.TrimSpace(lines[in.Line-1]))
+	}
+
+	changed := false
+	for file, set := range a.Diff.NewLines {
+		if a.RelPath(file) == rel {
+			changed = set[in.Line]
+		}
+	}
+	switch {
+	case diffcoverage.MatchAny(m.cfg.Exclude, rel):
+		sb.WriteString("Not counted: the file matches an exclude pattern of the configuration.")
+	case !strings.HasSuffix(rel, ".go") || strings.HasSuffix(rel, "_test.go"):
+		sb.WriteString("Not counted: only non-test Go files count towards diff coverage.")
+	case !changed:
+		sb.WriteString("Not counted: the line is not new or changed in the diff against the base.")
+	default:
+		switch a.Status(rel, in.Line) {
+		case diffcoverage.LineIgnored:
+			sb.WriteString("Not counted: the line is outside function bodies (declarations, comments, imports).")
+		case diffcoverage.LineCovered:
+			sb.WriteString("Covered: at least one test executes this line.")
+		case diffcoverage.LineUncovered:
+			sb.WriteString("Uncovered: the line is new or changed, inside a function body, and no test executed it.")
+			if len(a.Coverage.CoveredLines[rel]) == 0 {
+				sb.WriteString(" No line of this file is covered: its package may have no tests, or the tests did not run with -coverpkg including it.")
+			}
+			if fn, ok := enclosingFunc(a, rel, in.Line); ok {
+				fmt.Fprintf(&sb, " The enclosing function body spans lines %d-%d; a test taking this path through it covers the line.", fn[0], fn[1])
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// enclosingFunc returns the line range of the function body containing line.
+func enclosingFunc(a *diffcoverage.Analysis, rel string, line int) ([2]int, bool) {
+	for _, r := range a.Funcs.Functions[rel] {
+		if line >= r[0] && line <= r[1] {
+			return r, true
+		}
+	}
+	return [2]int{}, false
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
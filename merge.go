@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/coverprofile"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"io"
+	"os"
+	"strings"
+)
+
+// runMerge merges coverage profiles of several builds into one.
+func runMerge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	output := fs.String("o", "", "Write the merged profile to this file instead of stdout")
+	root := fs.String("root", ".", "Module root containing go.mod")
+	covdata := fs.String("covdata", "", "Comma-separated GOCOVERDIR directories of binaries built with go build -cover to merge as well")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 && *covdata == "" {
+		fmt.Fprintln(os.Stderr, "Usage: diffcoverage merge [options] <cover.out>...")
+		fmt.Fprintln(os.Stderr, "Options:")
+		fs.PrintDefaults()
+		return 1
+	}
+
+	if *output == "" {
+		if err := mergeProfiles(os.Stdout, *root, fs.Args(), splitList(*covdata)); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		return 0
+	}
+	if err := mergeProfilesTo(*output, *root, fs.Args(), splitList(*covdata)); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+// mergeProfiles writes the merge of the profiles and GOCOVERDIR directories
+// to w, normalized to the module at root.
+func mergeProfiles(w io.Writer, root string, profiles, covDirs []string) error {
+	module, err := diffcoverage.ModulePath(root)
+	if err != nil {
+		return fmt.Errorf("error parsing go.mod: %v", err)
+	}
+	return coverprofile.Merge(w, module, root, profiles, covDirs)
+}
+
+// mergeProfilesTo is mergeProfiles writing to the file at output.
+func mergeProfilesTo(output, root string, profiles, covDirs []string) error {
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := mergeProfiles(f, root, profiles, covDirs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
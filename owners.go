@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/codeowners"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"path"
+	"path/filepath"
+)
+
+// applyOwners adds the per-owner breakdown to r. CODEOWNERS paths are
+// relative to the repository root, report paths to the module root.
+func applyOwners(r *diffcoverage.Report, cfg *config.CodeOwners) error {
+	file, root := codeowners.Find(r.SourceRoot)
+	if cfg.File != "" {
+		file = cfg.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(r.SourceRoot, file)
+		}
+	}
+	if file == "" {
+		return fmt.Errorf("no CODEOWNERS file found (looked in %v of the repository); set codeowners.file in %s", codeowners.Locations, config.FileName)
+	}
+	owners, err := codeowners.Load(file)
+	if err != nil {
+		return err
+	}
+
+	prefix := ""
+	if root != "" {
+		if abs, err := filepath.Abs(r.SourceRoot); err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && rel != "." {
+				prefix = filepath.ToSlash(rel)
+			}
+		}
+	}
+	r.ApplyOwners(func(p string) []string { return owners.Owners(path.Join(prefix, p)) }, cfg.MinCoverage)
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// page writes out to stdout, through $DIFFCOVERAGE_PAGER or $PAGER (default:
+// less) when stdout is a terminal that out does not fit in, like git does.
+func page(out []byte) {
+	pageTo(os.Stdout, out, terminalRows(), os.Getenv)
+}
+
+// pageTo writes out to w, through the pager of getenv (see pagerCommand)
+// when out has at least rows lines; rows is 0 if w is not a terminal.
+func pageTo(w io.Writer, out []byte, rows int, getenv func(string) string) {
+	pager := pagerCommand(getenv)
+	if rows == 0 || bytes.Count(out, []byte("\n")) < rows || pager == "cat" {
+		w.Write(out)
+		return
+	}
+	// The pager may have arguments, e.g. "less -S"; git runs it with sh too.
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = bytes.NewReader(out)
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	if getenv("LESS") == "" {
+		// Quit if the output fits after all, keep colors and the screen.
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := cmd.Start(); err != nil {
+		w.Write(out)
+		return
+	}
+	cmd.Wait()
+}
+
+// pagerCommand returns $DIFFCOVERAGE_PAGER, $PAGER or less, the first set.
+func pagerCommand(getenv func(string) string) string {
+	return firstNonEmpty(getenv("DIFFCOVERAGE_PAGER"), getenv("PAGER"), "less")
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package main
+
+// terminalRows reports that the terminal height is unknown, so output is
+// never paged.
+func terminalRows() int {
+	return 0
+}
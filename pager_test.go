@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPagerCommand prefers $DIFFCOVERAGE_PAGER, then $PAGER, then less.
+func TestPagerCommand(t *testing.T) {
+	cases := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, "less"},
+		{map[string]string{"PAGER": "more"}, "more"},
+		{map[string]string{"PAGER": "more", "DIFFCOVERAGE_PAGER": "less -S"}, "less -S"},
+	}
+	for _, c := range cases {
+		if got := pagerCommand(func(k string) string { return c.env[k] }); got != c.want {
+			t.Errorf("pagerCommand(%v) = %q, want %q", c.env, got, c.want)
+		}
+	}
+}
+
+// TestPageTo pages only output that does not fit a terminal, and never
+// through cat.
+func TestPageTo(t *testing.T) {
+	out := []byte("a\nb\nc\n")
+	pager := map[string]string{"PAGER": "sed 's/^/paged /'"}
+	cases := []struct {
+		name string
+		rows int
+		env  map[string]string
+		want string
+	}{
+		{"not a terminal", 0, pager, "a\nb\nc\n"},
+		{"fits", 10, pager, "a\nb\nc\n"},
+		{"paged", 2, pager, "paged a\npaged b\npaged c\n"},
+		{"cat", 2, map[string]string{"PAGER": "cat"}, "a\nb\nc\n"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		pageTo(&buf, out, c.rows, func(k string) string { return c.env[k] })
+		if buf.String() != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, buf.String(), c.want)
+		}
+	}
+}
+
+// TestTerminalRows reports 0 rows when stdout is not a terminal.
+func TestTerminalRows(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stdout := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+	if rows := terminalRows(); rows != 0 {
+		t.Errorf("terminalRows() = %d, want 0", rows)
+	}
+}
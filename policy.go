@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/policy"
+	"io"
+	"os"
+	"time"
+)
+
+// addPolicyURLFlags registers the flags of a central configuration, which
+// default to the DIFFCOVERAGE_POLICY_URL and DIFFCOVERAGE_POLICY_KEY
+// environment variables so they can be set once for an organization's CI.
+func addPolicyURLFlags(fs *flag.FlagSet) {
+	fs.String("policy-url", os.Getenv("DIFFCOVERAGE_POLICY_URL"), "URL of a central configuration used instead of the repository's "+config.FileName)
+	fs.String("policy-key", os.Getenv("DIFFCOVERAGE_POLICY_KEY"), "Base64 ed25519 public key; when set, <policy-url>.sig must be a valid signature")
+}
+
+// resolveConfig loads the central configuration if -policy-url is set, and
+// the configuration file otherwise (see config.Resolve). Messages go to log.
+func resolveConfig(log io.Writer, fs *flag.FlagSet, path, preset, sourceRoot string) (*config.Config, error) {
+	url := fs.Lookup("policy-url").Value.String()
+	if url == "" {
+		return config.Resolve(path, sourceRoot, preset)
+	}
+	remote := &config.Remote{URL: url, CacheDir: config.DefaultCacheDir()}
+	if key := fs.Lookup("policy-key").Value.String(); key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(decoded) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid -policy-key: expected a base64 encoded ed25519 public key")
+		}
+		remote.PublicKey = decoded
+	}
+	if path != "" || config.Find(sourceRoot) != "" {
+		fmt.Fprintf(log, "Using the central configuration %s; the repository configuration is ignored\n", url)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cfg, stale, err := remote.Load(ctx, preset)
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		fmt.Fprintf(log, "Could not reach %s; using the cached configuration\n", url)
+	}
+	return cfg, nil
+}
+
+// applyPolicies evaluates the policies against the JSON form of r, as
+// computed so far, and records the results.
+func applyPolicies(r *diffcoverage.Report, policies []config.Policy) error {
+	vars, err := policy.Vars(r)
+	if err != nil {
+		return err
+	}
+	results := make([]diffcoverage.PolicyResult, 0, len(policies))
+	for _, p := range policies {
+		res := diffcoverage.PolicyResult{Name: p.Name, Expr: p.Expr}
+		prog, err := policy.Parse(p.Expr)
+		if err == nil {
+			res.Passed, err = prog.Check(vars)
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	r.ApplyPolicies(results)
+	return nil
+}
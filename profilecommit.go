@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+)
+
+// verifyCommits resolves the commit the coverage profile was generated at and
+// the head commit of the diff, HEAD of root unless given, and fails when they
+// differ: lines of a profile of another commit are silently misattributed.
+// Without a profile commit only the head is resolved, if root is a git
+// repository.
+func verifyCommits(root, profileCommit, headCommit string) (profile, head string, err error) {
+	if headCommit == "" {
+		headCommit = "HEAD"
+	}
+	head, err = resolveCommit(root, headCommit)
+	if profileCommit == "" {
+		if err != nil {
+			return "", "", nil
+		}
+		return "", head, nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if profile, err = resolveCommit(root, profileCommit); err != nil {
+		return "", "", err
+	}
+	if profile != head {
+		return "", "", fmt.Errorf("the coverage profile was generated at %s but the diff is at %s; regenerate the profile or the diff at the same commit", profile, head)
+	}
+	return profile, head, nil
+}
+
+// resolveCommit returns the full SHA of a commit-ish.
+func resolveCommit(root, rev string) (string, error) {
+	sha, err := gitutil.Run(root, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown commit %s", rev)
+	}
+	return sha, nil
+}
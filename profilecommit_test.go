@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a git repository with two commits and returns its
+// directory and the SHAs of the commits, oldest first.
+func initRepo(t *testing.T) (dir string, commits []string) {
+	t.Helper()
+	dir = t.TempDir()
+	mustGit(t, dir, "init", "-q")
+	for _, content := range []string{"one\n", "two\n"} {
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mustGit(t, dir, "add", "-A")
+		mustGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "commit "+content)
+		sha, err := gitutil.Run(dir, "rev-parse", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, sha)
+	}
+	return dir, commits
+}
+
+func mustGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := gitutil.Run(dir, args...); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+// TestVerifyCommits resolves both commits and fails on a mismatch or an
+// unresolvable commit; without a profile commit the head is optional.
+func TestVerifyCommits(t *testing.T) {
+	dir, commits := initRepo(t)
+	old, head := commits[0], commits[1]
+	notRepo := t.TempDir()
+	cases := []struct {
+		name, root, profileCommit, headCommit string
+		wantProfile, wantHead, wantErr        string
+	}{
+		{"no profile commit", dir, "", "", "", head, ""},
+		{"no profile commit outside git", notRepo, "", "", "", "", ""},
+		{"no profile commit, unknown head", dir, "", "nope", "", "", ""},
+		{"same commit", dir, "HEAD", "", head, head, ""},
+		{"explicit head", dir, old, old, old, old, ""},
+		{"mismatch", dir, old, "", "", "", "the coverage profile was generated at " + old + " but the diff is at " + head},
+		{"unresolvable head", notRepo, head, "", "", "", "unknown commit HEAD"},
+		{"unknown head commit", dir, head, "nope", "", "", "unknown commit nope"},
+		{"unknown profile commit", dir, "nope", "", "", "", "unknown commit nope"},
+	}
+	for _, c := range cases {
+		profile, gotHead, err := verifyCommits(c.root, c.profileCommit, c.headCommit)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: error %v, want %q", c.name, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil || profile != c.wantProfile || gotHead != c.wantHead {
+			t.Errorf("%s: got %q, %q, %v, want %q, %q", c.name, profile, gotHead, err, c.wantProfile, c.wantHead)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/attest"
+	"github.com/JackShadow/go-new-code-coverage/internal/ci"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/credentials"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/httpclient"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+	"github.com/JackShadow/go-new-code-coverage/internal/storage"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// publishTargets lists the integrations accepted by -publish.
+var publishTargets = []string{"github-comment", "gitlab-note", "bitbucket-insights", "gerrit-review", "gerrit-robot", "gitea",
+	"commit-status", "github-status", "gitlab-status", "bitbucket-status", "webhook", "pushgateway", "otel", "codecov", "coveralls",
+	"archive", "history", "server", "email", "circleci", "parquet", "report-file"}
+
+// codecovServices maps CI providers to Codecov service names.
+var codecovServices = map[string]string{
+	ci.GitHubActions: "github-actions",
+	ci.GitLabCI:      "gitlab",
+	ci.CircleCI:      "circleci",
+	ci.Buildkite:     "buildkite",
+	ci.Jenkins:       "jenkins",
+	ci.Bitbucket:     "bitbucket",
+}
+
+// publishFlags select and configure the integrations that publish the report.
+type publishFlags struct {
+	targets   *string
+	repo      *string
+	pr        *string
+	commit    *string
+	branch    *string
+	apiURL    *string
+	reportURL *string
+	voteLabel *string
+	webhooks  *string
+	pushURL   *string
+	archive   *string
+	circleDir *string
+	parquet   *string
+	report    *string
+	signKey   *string
+	token     *string
+	timeout   *time.Duration
+	retries   *int
+	template  *string
+	// maxComment caps the size of comments; 0 is the provider's limit.
+	maxComment *int
+	// appToken caches the GitHub App installation token across targets.
+	appToken string
+	// out receives progress messages; stdout if nil.
+	out io.Writer
+	// commentTemplate is the parsed -comment-template, if any.
+	commentTemplate *template.Template
+}
+
+// addPublishFlags registers the publishing flags on fs.
+func addPublishFlags(fs *flag.FlagSet) *publishFlags {
+	return &publishFlags{
+		targets:    fs.String("publish", "", "Comma-separated integrations to publish the report to: "+strings.Join(publishTargets, ", ")),
+		repo:       fs.String("repo", "", "Repository (owner/name) for integrations; detected in CI"),
+		pr:         fs.String("pr", "", "Pull/merge request number for integrations; detected in CI"),
+		commit:     fs.String("commit", "", "Commit SHA for integrations that report on commits; detected in CI"),
+		branch:     fs.String("branch", "", "Branch for integrations that label results by branch; detected in CI"),
+		apiURL:     fs.String("api-url", "", "Provider API base URL; detected in CI"),
+		reportURL:  fs.String("report-url", "", "URL of the full report (e.g. an HTML artifact) to link from comments"),
+		webhooks:   fs.String("webhook-url", "", "Comma-separated URLs the webhook integration posts the JSON report to"),
+		pushURL:    fs.String("pushgateway-url", "", "Prometheus Pushgateway URL (default: $PUSHGATEWAY_URL)"),
+		archive:    fs.String("archive-url", "", "s3://bucket/prefix or gs://bucket/prefix the archive integration uploads to"),
+		circleDir:  fs.String("circleci-dir", "diffcoverage-results", "Directory the circleci integration writes test-results/ and artifacts/ to"),
+		parquet:    fs.String("parquet-dir", "diffcoverage-parquet", "Directory the parquet integration writes files.parquet and lines.parquet to"),
+		report:     fs.String("report-file", "diffcoverage-report.json", "File the report-file integration writes the JSON report to"),
+		signKey:    fs.String("signing-key", "", "PEM ed25519 or ECDSA private key signing the JSON report of the report-file and archive integrations (default: $DIFFCOVERAGE_SIGNING_KEY, or HMAC with $DIFFCOVERAGE_SIGNING_SECRET)"),
+		template:   fs.String("comment-template", "", "Go text/template file rendering pull request comments (default: comment_template in the configuration)"),
+		maxComment: fs.Int("max-comment-bytes", 0, "Cap the size of comments and Bitbucket report details, leaving out the files with the fewest uncovered lines (default: the provider's limit)"),
+		voteLabel:  fs.String("vote-label", "", "Label to vote +1/-1 on with the gate result (Gerrit), e.g. Verified"),
+		token:      fs.String("token", "", "API token of the code host; defaults to the provider's environment variables, ~/.netrc or git credential helpers"),
+		timeout:    fs.Duration("http-timeout", httpclient.DefaultTimeout, "Time to wait for each API response"),
+		retries:    fs.Int("http-retries", httpclient.DefaultRetries, "Retries of API requests that fail with network errors, 5xx gateway errors or rate limits"),
+	}
+}
+
+// publish sends the report to the integrations named by -publish, or by the
+// configuration file when the flag is not set.
+func (f *publishFlags) publish(r *diffcoverage.Report, cfg *config.Config) error {
+	targets := cfg.Publish
+	if *f.targets != "" {
+		targets = strings.Split(*f.targets, ",")
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	httpclient.Default = httpclient.New(httpclient.Options{Timeout: *f.timeout, MaxRetries: *f.retries})
+	if path := firstNonEmpty(*f.template, cfg.CommentTemplate); path != "" {
+		if !filepath.IsAbs(path) && *f.template == "" {
+			path = filepath.Join(r.SourceRoot, path)
+		}
+		t, err := reporter.ParseTemplate(path)
+		if err != nil {
+			return err
+		}
+		f.commentTemplate = t
+	}
+	env := f.env()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	for _, name := range targets {
+		name = strings.TrimSpace(name)
+		rep, err := f.newReporter(name, env, cfg)
+		if err != nil {
+			return err
+		}
+		if err := rep.Publish(ctx, r); err != nil {
+			return fmt.Errorf("error publishing to %s: %v", name, err)
+		}
+		out := f.out
+		if out == nil {
+			out = os.Stdout
+		}
+		fmt.Fprintf(out, "Published report to %s\n", name)
+	}
+	return nil
+}
+
+// env returns the detected CI environment with the flag overrides applied.
+func (f *publishFlags) env() *ci.Env {
+	env := ci.Detect(os.Getenv)
+	if env == nil {
+		env = &ci.Env{}
+	}
+	if *f.repo != "" {
+		env.Repo = *f.repo
+	}
+	if *f.pr != "" {
+		env.PRNumber = *f.pr
+	}
+	if *f.commit != "" {
+		env.CommitSHA = *f.commit
+	}
+	if *f.branch != "" {
+		env.Branch = *f.branch
+	}
+	return env
+}
+
+// newReporter builds the named integration from the environment. The detected
+// API URL is only used when it belongs to the integration's provider.
+func (f *publishFlags) newReporter(name string, env *ci.Env, cfg *config.Config) (reporter.Reporter, error) {
+	switch name {
+	case "github-comment":
+		apiURL := f.providerAPIURL(env, ci.GitHubActions)
+		cred, err := f.credential(credentials.GitHub, apiURL, env)
+		if err != nil {
+			return nil, err
+		}
+		return &reporter.GitHub{APIURL: apiURL, Repo: env.Repo, PR: env.PRNumber, Token: cred.Token, ReportURL: *f.reportURL, Template: f.commentTemplate, MaxBytes: *f.maxComment}, nil
+	case "gitlab-note":
+		apiURL := f.providerAPIURL(env, ci.GitLabCI)
+		cred, err := f.credential(credentials.GitLab, apiURL, env)
+		if err != nil {
+			return nil, err
+		}
+		return &reporter.GitLab{APIURL: apiURL, Project: env.Repo, MR: env.PRNumber, Token: cred.Token, ReportURL: *f.reportURL, Template: f.commentTemplate, MaxBytes: *f.maxComment}, nil
+	case "bitbucket-insights":
+		apiURL := f.providerAPIURL(env, ci.Bitbucket)
+		cred, err := f.credential(credentials.Bitbucket, apiURL, env)
+		if err != nil {
+			return nil, err
+		}
+		return &reporter.Bitbucket{
+			APIURL:    apiURL,
+			Repo:      env.Repo,
+			Commit:    env.CommitSHA,
+			Token:     cred.Token,
+			Username:  cred.Username,
+			Password:  cred.Password,
+			ReportURL: *f.reportURL,
+			Template:  f.commentTemplate,
+			MaxBytes:  *f.maxComment,
+		}, nil
+	case "gerrit-review", "gerrit-robot":
+		// Gerrit Trigger and similar Jenkins plugins export the change under review.
+		url := firstNonEmpty(*f.apiURL, os.Getenv("GERRIT_URL"))
+		cred, err := f.credential(credentials.Gerrit, url, env)
+		if err != nil {
+			return nil, err
+		}
+		return &reporter.Gerrit{
+			URL:       url,
+			Change:    firstNonEmpty(*f.pr, os.Getenv("GERRIT_CHANGE_NUMBER")),
+			Revision:  firstNonEmpty(*f.commit, os.Getenv("GERRIT_PATCHSET_REVISION")),
+			Username:  cred.Username,
+			Password:  cred.Password,
+			Label:     *f.voteLabel,
+			Robot:     name == "gerrit-robot",
+			RunID:     os.Getenv("BUILD_URL"),
+			ReportURL: *f.reportURL,
+		}, nil
+	case "gitea":
+		// Gitea and Forgejo Actions export GitHub-compatible variables.
+		apiURL := *f.apiURL
+		cred, err := f.credential(credentials.Gitea, apiURL, env)
+		if os.Getenv("GITEA_ACTIONS") == "true" || os.Getenv("FORGEJO_ACTIONS") == "true" {
+			apiURL = firstNonEmpty(apiURL, env.APIURL)
+			if err != nil && env.Token != "" {
+				cred, err = credentials.Credential{Token: env.Token, Source: "GITHUB_TOKEN"}, nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &reporter.Gitea{
+			APIURL:    apiURL,
+			Repo:      env.Repo,
+			PR:        env.PRNumber,
+			Commit:    env.CommitSHA,
+			Token:     cred.Token,
+			ReportURL: *f.reportURL,
+			Template:  f.commentTemplate,
+			MaxBytes:  *f.maxComment,
+		}, nil
+	case "commit-status":
+		// Pick the provider of the detected CI system.
+		switch env.Provider {
+		case ci.GitHubActions:
+			return f.newReporter("github-status", env, cfg)
+		case ci.GitLabCI:
+			return f.newReporter("gitlab-status", env, cfg)
+		case ci.Bitbucket:
+			return f.newReporter("bitbucket-status", env, cfg)
+		}
+		return nil, fmt.Errorf("commit-status: cannot tell the provider outside GitHub Actions, GitLab CI and Bitbucket Pipelines; use github-status, gitlab-status or bitbucket-status")
+	case "github-status", "gitlab-status", "bitbucket-status":
+		s := &reporter.CommitStatus{Repo: env.Repo, Commit: env.CommitSHA, TargetURL: *f.reportURL}
+		var provider credentials.Provider
+		switch name {
+		case "github-status":
+			s.Provider, s.APIURL, provider = reporter.StatusGitHub, f.providerAPIURL(env, ci.GitHubActions), credentials.GitHub
+		case "gitlab-status":
+			s.Provider, s.APIURL, provider = reporter.StatusGitLab, f.providerAPIURL(env, ci.GitLabCI), credentials.GitLab
+		default:
+			s.Provider, s.APIURL, provider = reporter.StatusBitbucket, f.providerAPIURL(env, ci.Bitbucket), credentials.Bitbucket
+		}
+		cred, err := f.credential(provider, s.APIURL, env)
+		if err != nil {
+			return nil, err
+		}
+		s.Token, s.Username, s.Password = cred.Token, cred.Username, cred.Password
+		return s, nil
+	case "webhook":
+		urls := cfg.Webhooks
+		if *f.webhooks != "" {
+			urls = strings.Split(*f.webhooks, ",")
+		}
+		return &reporter.Webhook{URLs: urls, Secret: os.Getenv("DIFFCOVERAGE_WEBHOOK_SECRET")}, nil
+	case "pushgateway":
+		return &reporter.Pushgateway{
+			URL:    firstNonEmpty(*f.pushURL, os.Getenv("PUSHGATEWAY_URL")),
+			Repo:   env.Repo,
+			Branch: env.Branch,
+			Commit: env.CommitSHA,
+		}, nil
+	case "otel":
+		// Configured with the standard OpenTelemetry exporter variables.
+		return &reporter.OTel{
+			Endpoint:    firstNonEmpty(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "http://localhost:4318"),
+			Headers:     reporter.ParseOTLPHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+			ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+			Attributes:  map[string]string{"repo": env.Repo, "branch": env.Branch, "commit": env.CommitSHA},
+		}, nil
+	case "codecov":
+		return &reporter.Codecov{
+			URL:     os.Getenv("CODECOV_URL"),
+			Token:   os.Getenv("CODECOV_TOKEN"),
+			Service: codecovServices[env.Provider],
+			Slug:    env.Repo,
+			Commit:  env.CommitSHA,
+			Branch:  env.Branch,
+			PR:      env.PRNumber,
+		}, nil
+	case "coveralls":
+		return &reporter.Coveralls{
+			URL:         os.Getenv("COVERALLS_ENDPOINT"),
+			RepoToken:   os.Getenv("COVERALLS_REPO_TOKEN"),
+			ServiceName: firstNonEmpty(os.Getenv("COVERALLS_SERVICE_NAME"), env.Provider),
+			PR:          env.PRNumber,
+			Commit:      env.CommitSHA,
+			Branch:      env.Branch,
+		}, nil
+	case "archive":
+		location := firstNonEmpty(*f.archive, cfg.Archive)
+		if location == "" {
+			return nil, fmt.Errorf("archive: set -archive-url or archive: in %s", config.FileName)
+		}
+		bucket, err := storage.Open(location, os.Getenv)
+		if err != nil {
+			return nil, err
+		}
+		key, err := f.signingKey()
+		if err != nil {
+			return nil, err
+		}
+		return &reporter.Archive{Store: bucket, Repo: env.Repo, Branch: env.Branch, Commit: env.CommitSHA, Key: key}, nil
+	case "history":
+		return &reporter.History{Path: cfg.History, Repo: env.Repo, Branch: env.Branch, Commit: env.CommitSHA, PR: env.PRNumber}, nil
+	case "server":
+		return &reporter.Server{
+			URL:    firstNonEmpty(os.Getenv("DIFFCOVERAGE_SERVER_URL"), cfg.Server),
+			Secret: os.Getenv("DIFFCOVERAGE_SERVER_SECRET"),
+			Repo:   env.Repo,
+			Team:   cfg.Team,
+			Branch: env.Branch,
+			Commit: env.CommitSHA,
+			PR:     env.PRNumber,
+		}, nil
+	case "circleci":
+		return &reporter.CircleCI{Dir: *f.circleDir}, nil
+	case "parquet":
+		return &reporter.Parquet{Dir: *f.parquet, Repo: env.Repo, Branch: env.Branch, Commit: env.CommitSHA}, nil
+	case "report-file":
+		key, err := f.signingKey()
+		if err != nil {
+			return nil, err
+		}
+		return &reporter.ReportFile{Path: *f.report, Key: key}, nil
+	case "email":
+		return &reporter.Email{
+			Addr:      firstNonEmpty(os.Getenv("SMTP_ADDR"), cfg.Email.SMTP),
+			Username:  os.Getenv("SMTP_USERNAME"),
+			Password:  os.Getenv("SMTP_PASSWORD"),
+			From:      firstNonEmpty(os.Getenv("SMTP_FROM"), cfg.Email.From),
+			To:        cfg.Email.To,
+			Author:    cfg.Email.Author,
+			Always:    cfg.Email.Always,
+			Repo:      env.Repo,
+			Branch:    env.Branch,
+			Commit:    env.CommitSHA,
+			ReportURL: *f.reportURL,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown publish target %q (available: %s)", name, strings.Join(publishTargets, ", "))
+}
+
+// credential resolves the credentials of provider for the API at apiURL.
+// Without -token, a configured GitHub App takes precedence for GitHub.
+func (f *publishFlags) credential(provider credentials.Provider, apiURL string, env *ci.Env) (credentials.Credential, error) {
+	if provider.Name == credentials.GitHub.Name && *f.token == "" {
+		if f.appToken == "" {
+			app, err := credentials.GitHubAppFromEnv(os.Getenv)
+			if err != nil {
+				return credentials.Credential{}, err
+			}
+			if app != nil {
+				app.APIURL = apiURL
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				defer cancel()
+				if f.appToken, err = app.InstallationToken(ctx, env.Repo); err != nil {
+					return credentials.Credential{}, err
+				}
+			}
+		}
+		if f.appToken != "" {
+			return credentials.Credential{Token: f.appToken, Source: "GitHub App"}, nil
+		}
+	}
+	r := &credentials.Resolver{Token: *f.token, Getenv: os.Getenv}
+	return r.Resolve(provider, apiURL)
+}
+
+// providerAPIURL returns -api-url, or the detected API URL when the CI system
+// belongs to provider.
+func (f *publishFlags) providerAPIURL(env *ci.Env, provider string) string {
+	if *f.apiURL == "" && env.Provider == provider {
+		return env.APIURL
+	}
+	return *f.apiURL
+}
+
+// signingKey returns the key signing JSON reports: the -signing-key file, the
+// PEM key in $DIFFCOVERAGE_SIGNING_KEY or the HMAC secret in
+// $DIFFCOVERAGE_SIGNING_SECRET; nil if none is set.
+func (f *publishFlags) signingKey() (*attest.Key, error) {
+	data := []byte(os.Getenv("DIFFCOVERAGE_SIGNING_KEY"))
+	if *f.signKey != "" {
+		var err error
+		if data, err = os.ReadFile(*f.signKey); err != nil {
+			return nil, fmt.Errorf("error reading signing key: %v", err)
+		}
+	}
+	if len(data) > 0 {
+		return attest.ParsePrivateKey(data)
+	}
+	if secret := os.Getenv("DIFFCOVERAGE_SIGNING_SECRET"); secret != "" {
+		return attest.Secret(secret), nil
+	}
+	return nil, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// releaseReport summarizes the new code between two releases.
+type releaseReport struct {
+	From         string                       `json:"from"`
+	To           string                       `json:"to"`
+	Date         string                       `json:"date"`
+	Commits      int                          `json:"commits"`
+	Contributors int                          `json:"contributors"`
+	Report       *diffcoverage.Report         `json:"report"`
+	Packages     []diffcoverage.PackageReport `json:"packages"`
+}
+
+// runReleaseReport summarizes the coverage of all new code between two
+// release tags, for release notes and compliance audits.
+func runReleaseReport(args []string) int {
+	fs := flag.NewFlagSet("release-report", flag.ExitOnError)
+	from := fs.String("from", "", "Tag of the previous release (required)")
+	to := fs.String("to", "HEAD", "Tag of the new release; -cover must be its coverage profile")
+	flags := &rangeFlags{
+		cover:  fs.String("cover", "cover.out", "Path to the coverage profile of the new release"),
+		root:   fs.String("root", ".", "Source root containing go.mod"),
+		config: fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present)"),
+		preset: fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", ")),
+		format: fs.String("format", "text", "Output format: text, markdown or json"),
+	}
+	fs.Parse(args)
+
+	if *from == "" {
+		fmt.Println("Usage: diffcoverage release-report -from <tag> [options]")
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		return 1
+	}
+	rng := *from + ".." + *to
+	flags.rng = &rng
+	a, cfg, err := flags.analyze()
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	rr, err := newReleaseReport(a, cfg, *from, *to)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	switch format := *flags.format; format {
+	case "json":
+		return printJSON(rr)
+	case "markdown":
+		printReleaseMarkdown(rr)
+	case "text":
+		printReleaseText(rr)
+	default:
+		fmt.Printf("unknown format %q\n", format)
+		return 1
+	}
+	return 0
+}
+
+// newReleaseReport builds the report of the range from..to analyzed in a.
+func newReleaseReport(a *diffcoverage.Analysis, cfg *config.Config, from, to string) (*releaseReport, error) {
+	rng := from + ".." + to
+	count, err := gitutil.Run(a.SourceRoot, "rev-list", "--count", "--no-merges", rng)
+	if err != nil {
+		return nil, err
+	}
+	commits, _ := strconv.Atoi(count)
+	emails, err := gitutil.Run(a.SourceRoot, "log", "--no-merges", "--format=%aE", rng)
+	if err != nil {
+		return nil, err
+	}
+	contributors := map[string]bool{}
+	for _, email := range strings.Fields(emails) {
+		contributors[strings.ToLower(email)] = true
+	}
+
+	r := a.Report(cfg.MinCoverage)
+	funcs, err := a.Functions()
+	if err != nil {
+		return nil, err
+	}
+	r.ApplyFunctions(funcs, cfg.MinFunctionCoverage)
+	return &releaseReport{
+		From:         from,
+		To:           to,
+		Date:         time.Now().UTC().Format(time.DateOnly),
+		Commits:      commits,
+		Contributors: len(contributors),
+		Report:       r,
+		Packages:     r.Packages(),
+	}, nil
+}
+
+// printReleaseText prints the release report as plain text.
+func printReleaseText(rr *releaseReport) {
+	r := rr.Report
+	fmt.Printf("Release report %s..%s (%s)\n\n", rr.From, rr.To, rr.Date)
+	fmt.Printf("Commits:            %d by %d contributors\n", rr.Commits, rr.Contributors)
+	fmt.Printf("New code:           %d lines in %d files\n", r.TotalLines, len(r.Files))
+	fmt.Printf("New code coverage:  %.2f%% (%d/%d lines)\n", r.Coverage, r.CoveredLines, r.TotalLines)
+	fmt.Printf("Function coverage:  %d of %d changed functions are 100%% covered\n", r.CoveredFunctions, r.ChangedFunctions)
+	fmt.Printf("Project coverage:   %.2f%%\n", r.ProjectCoverage)
+	if len(rr.Packages) == 0 {
+		return
+	}
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tFILES\tLINES\tCOVERAGE")
+	for _, p := range rr.Packages {
+		fmt.Fprintf(tw, "%s\t%d\t%d/%d\t%.2f%%\n", p.Package, p.Files, p.CoveredLines, p.TotalLines, p.Coverage)
+	}
+	tw.Flush()
+
+	if r.CoveredLines < r.TotalLines {
+		fmt.Println("\nUncovered new code:")
+	}
+	for _, f := range r.Files {
+		if len(f.Uncovered) > 0 {
+			fmt.Printf("\t%s: %s\n", f.Path, reporter.FormatRanges(f.Uncovered))
+		}
+	}
+}
+
+// printReleaseMarkdown prints the release report as Markdown for release notes.
+func printReleaseMarkdown(rr *releaseReport) {
+	r := rr.Report
+	fmt.Printf("## Test coverage of %s..%s\n\n", rr.From, rr.To)
+	fmt.Printf("%d commits by %d contributors added or changed %d lines of code in %d files; **%.2f%%** of them are covered by tests (%d/%d lines), and %d of %d changed functions are 100%% covered. Project coverage at %s is %.2f%%.\n",
+		rr.Commits, rr.Contributors, r.TotalLines, len(r.Files), r.Coverage, r.CoveredLines, r.TotalLines, r.CoveredFunctions, r.ChangedFunctions, rr.To, r.ProjectCoverage)
+	if len(rr.Packages) > 0 {
+		fmt.Println()
+		fmt.Println("| Package | Files | Covered | Coverage |")
+		fmt.Println("|---------|------:|--------:|---------:|")
+		for _, p := range rr.Packages {
+			fmt.Printf("| `%s` | %d | %d/%d | %.2f%% |\n", p.Package, p.Files, p.CoveredLines, p.TotalLines, p.Coverage)
+		}
+	}
+	var uncovered []string
+	for _, f := range r.Files {
+		if len(f.Uncovered) > 0 {
+			uncovered = append(uncovered, fmt.Sprintf("- `%s`: %s", f.Path, reporter.FormatRanges(f.Uncovered)))
+		}
+	}
+	if len(uncovered) > 0 {
+		fmt.Printf("\n<details><summary>Uncovered new code</summary>\n\n%s\n\n</details>\n", strings.Join(uncovered, "\n"))
+	}
+	fmt.Printf("\n_Generated on %s by go-new-code-coverage %s._\n", rr.Date, r.ToolVersion)
+}
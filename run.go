@@ -0,0 +1,279 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+	"github.com/JackShadow/go-new-code-coverage/internal/mutation"
+	"github.com/JackShadow/go-new-code-coverage/internal/testrun"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runFlags are the flags shared by run and ci.
+type runFlags struct {
+	base        *string
+	min         *float64
+	root        *string
+	profile     *string
+	verbose     *bool
+	noPager     *bool
+	config      *string
+	preset      *string
+	blame       *bool
+	skeletons   *bool
+	mutate      *bool
+	testFiles   *bool
+	unreachable *bool
+	errReturns  *bool
+	wholeFuncs  *bool
+	grade       *bool
+	maxLineAge  *int
+	coverMode   *string
+	profCheck   *string
+	profStmts   *float64
+	testEdits   *string
+	funcs       *string
+	sort        *string
+	parallel    *int
+	covdata     *string
+	publish     *publishFlags
+	flagSet     *flag.FlagSet
+	// target is the branch the change lands on, if known; otherwise it is
+	// derived from -base.
+	target string
+}
+
+// runOptions are the resolved inputs of the test-and-report pipeline.
+type runOptions struct {
+	base     string
+	root     string
+	profile  string
+	verbose  bool
+	noPager  bool
+	parallel int
+	covdata  []string
+	cfg      *config.Config
+	publish  *publishFlags
+	// diff is a diff against base fetched elsewhere; computed with git if empty.
+	diff string
+	// cover is a coverage profile of the working tree to report on instead
+	// of running the tests.
+	cover string
+}
+
+// addRunFlags registers the run flags on fs.
+func addRunFlags(fs *flag.FlagSet, defaultBase string) *runFlags {
+	f := &runFlags{flagSet: fs}
+	f.base = fs.String("base", defaultBase, "Ref to diff against")
+	f.min = fs.Float64("min", 0.0, "Minimum coverage percentage (e.g., 80.0)")
+	fs.Float64("min-functions", 0.0, "Minimum percentage of changed functions that must be fully covered")
+	f.root = fs.String("root", ".", "Module root containing go.mod")
+	f.profile = fs.String("coverprofile", "", "Keep the coverage profile at this path")
+	f.verbose = fs.Bool("vvv", false, "Verbose output: list lines not covered")
+	fs.BoolVar(f.verbose, "verbose", false, "Verbose output: list lines not covered")
+	f.noPager = fs.Bool("no-pager", false, "Do not page the verbose output through $PAGER when it does not fit the terminal")
+	f.config = fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present)")
+	f.preset = fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", "))
+	f.blame = fs.Bool("blame", false, "Attribute uncovered lines to authors and commits with git blame")
+	f.skeletons = fs.Bool("test-skeletons", false, "Generate test skeletons for new, uncovered functions and post them as GitHub review comments")
+	f.mutate = fs.Bool("mutate", false, "Mutate the covered changed lines and report those whose mutants all pass the tests")
+	f.testFiles = fs.Bool("require-test-files", false, "Fail if the diff adds non-generated files to a package without any _test.go file")
+	f.unreachable = fs.Bool("ignore-unreachable", false, "Do not count changed blocks that only panic, log.Fatal or os.Exit")
+	f.errReturns = fs.Bool("ignore-error-returns", false, "Do not count changed if err != nil { return ..., err } statements")
+	f.wholeFuncs = fs.Bool("whole-functions", false, "Also require the minimum coverage of all lines of the touched functions, changed or not")
+	f.grade = fs.Bool("grade", false, "Grade the coverage A to F, or by the bands of grading in the configuration")
+	f.maxLineAge = fs.Int("max-line-age", 0, "With -whole-functions, leave out unchanged lines last changed more than this many days ago, according to git blame")
+	f.coverMode = fs.String("require-covermode", "", "Fail if the profile was generated with a weaker -covermode than this: set, count or atomic; the tests run with it")
+	f.profCheck = fs.String("profile-check", "", "What an implausible coverage profile does: warn (default), fail or off")
+	f.profStmts = fs.Float64("min-profile-statements", 0, "Percentage of the module's statements the coverage profile must have to be plausible (0: not checked)")
+	f.testEdits = fs.String("require-test-changes", "", "Comma-separated glob patterns of files whose changed functions fail the run if their package tests are not changed")
+	f.funcs = fs.String("exclude-functions", "", "Comma-separated patterns of function names whose changed lines are not counted, e.g. String,DeepCopy*,/^Marshal.*JSON$/")
+	f.sort = fs.String("sort", "", "Order of the files in every output: file, coverage (lowest first) or uncovered (most uncovered lines first); ties by path")
+	f.parallel = fs.Int("p", 1, "Test up to this many packages at once, each in its own go test process (1: a single go test for all packages)")
+	f.covdata = fs.String("covdata", "", "Comma-separated GOCOVERDIR directories of binaries built with go build -cover to merge with the test profile")
+	addPolicyURLFlags(fs)
+	f.publish = addPublishFlags(fs)
+	return f
+}
+
+// options resolves the parsed flags, loading the configuration file.
+func (f *runFlags) options() (runOptions, error) {
+	target := f.target
+	if target == "" {
+		target = refBranch(*f.root, *f.base)
+	}
+	cfg, err := loadConfig(os.Stdout, f.flagSet, *f.config, *f.preset, *f.root, target, *f.min)
+	if err != nil {
+		return runOptions{}, err
+	}
+	cfg.Blame = cfg.Blame || *f.blame
+	cfg.TestSkeletons = cfg.TestSkeletons || *f.skeletons
+	cfg.Mutation = cfg.Mutation || *f.mutate
+	cfg.RequireTestFiles = cfg.RequireTestFiles || *f.testFiles
+	cfg.IgnoreUnreachable = cfg.IgnoreUnreachable || *f.unreachable
+	cfg.IgnoreErrorReturns = cfg.IgnoreErrorReturns || *f.errReturns
+	cfg.WholeFunctions = cfg.WholeFunctions || *f.wholeFuncs
+	setGrade(cfg, *f.grade)
+	if err := setMaxLineAge(cfg, *f.maxLineAge); err != nil {
+		return runOptions{}, err
+	}
+	if err := setProfileCheck(cfg, *f.profCheck, *f.profStmts); err != nil {
+		return runOptions{}, err
+	}
+	if err := setCoverMode(cfg, *f.coverMode); err != nil {
+		return runOptions{}, err
+	}
+	cfg.RequireTestChanges = append(cfg.RequireTestChanges, splitList(*f.testEdits)...)
+	if err := setExcludeFunctions(cfg, *f.funcs); err != nil {
+		return runOptions{}, err
+	}
+	if err := setSort(cfg, *f.sort); err != nil {
+		return runOptions{}, err
+	}
+	applyLabels(os.Stdout, f.publish, cfg)
+	return runOptions{base: *f.base, root: *f.root, profile: *f.profile, verbose: *f.verbose, noPager: *f.noPager, parallel: *f.parallel, covdata: splitList(*f.covdata), cfg: cfg, publish: f.publish}, nil
+}
+
+// refBranch returns the branch of a ref such as origin/main or
+// refs/remotes/origin/main, i.e. without the prefix naming a remote.
+func refBranch(root, ref string) string {
+	ref = strings.TrimPrefix(ref, "refs/heads/")
+	ref = strings.TrimPrefix(ref, "refs/remotes/")
+	remotes, _ := gitutil.Run(root, "remote")
+	for _, remote := range strings.Fields(remotes) {
+		if branch, ok := strings.CutPrefix(ref, remote+"/"); ok {
+			return branch
+		}
+	}
+	return ref
+}
+
+// runRun tests the changed packages and computes diff coverage in one step.
+func runRun(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	flags := addRunFlags(fs, "origin/main")
+	fs.Parse(args)
+
+	opts, err := flags.options()
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	return runPipeline(opts)
+}
+
+// runPipeline diffs against the base, tests the changed packages and reports diff coverage.
+func runPipeline(opts runOptions) int {
+	tmpDir, err := os.MkdirTemp("", "diffcoverage-run")
+	if err != nil {
+		fmt.Printf("error creating temp dir: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(tmpDir)
+
+	diffPath := opts.diff
+	if diffPath == "" {
+		diffPath = filepath.Join(tmpDir, "diff.txt")
+		if err := testrun.WriteDiff(opts.root, opts.base, diffPath); err != nil {
+			fmt.Printf("error computing diff: %v\n", err)
+			return 1
+		}
+	}
+
+	changedPkgs, testPkgs, err := impactedPackages(opts.root, diffPath, opts.cfg)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	if len(testPkgs) == 0 {
+		fmt.Println("No changed Go packages")
+		r := diffcoverage.NewReport(opts.cfg.MinCoverage)
+		r.SourceRoot = opts.root
+		return finish(r, "text", "", opts.verbose, !opts.noPager, opts.publish, opts.cfg)
+	}
+
+	profile := opts.profile
+	if profile == "" {
+		profile = filepath.Join(tmpDir, "cover.out")
+	}
+
+	testProfile := profile
+	var tests []diffcoverage.TestPackage
+	if len(opts.covdata) > 0 {
+		testProfile = filepath.Join(tmpDir, "test.out")
+	}
+	if opts.cover != "" {
+		fmt.Printf("Using the coverage profile %s instead of running the tests\n", opts.cover)
+		testProfile = opts.cover
+		if len(opts.covdata) == 0 {
+			profile = opts.cover
+		}
+	} else {
+		if mode := opts.cfg.RequireCoverMode; mode != "" {
+			// go test reads -covermode from GOFLAGS, as do the per-package runs.
+			os.Setenv("GOFLAGS", strings.TrimSpace(os.Getenv("GOFLAGS")+" -covermode="+mode))
+		}
+		fmt.Printf("Testing %s\n", strings.Join(testPkgs, " "))
+		results, err := testrun.TestPackages(opts.root, testPkgs, changedPkgs, testProfile, opts.parallel, os.Stdout, os.Stderr)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		for _, res := range results {
+			tests = append(tests, diffcoverage.TestPackage{Package: res.Package, Result: res.Action, Elapsed: res.Elapsed.Seconds(), FailedTests: res.FailedTests})
+		}
+	}
+	if len(opts.covdata) > 0 {
+		if err := mergeProfilesTo(profile, opts.root, []string{testProfile}, opts.covdata); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+	}
+
+	a, r, err := analyze(profile, diffPath, opts.root, opts.cfg)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	if tests != nil {
+		r.ApplyTests(tests)
+	}
+	if head, err := resolveCommit(opts.root, "HEAD"); err == nil {
+		r.Commit = head
+		// The profile was just generated from the working tree at HEAD.
+		if opts.cover == "" {
+			r.ProfileCommit = head
+		}
+	}
+	if opts.cfg.Mutation {
+		if err := mutate(a, r, opts); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+	}
+	return finish(r, "text", "", opts.verbose, !opts.noPager, opts.publish, opts.cfg)
+}
+
+// mutate runs the tests impacted by each changed file against mutants of its
+// covered lines, recording the lines no test asserts on in r.
+func mutate(a *diffcoverage.Analysis, r *diffcoverage.Report, opts runOptions) error {
+	pkgs, err := testrun.ListPackages(opts.root)
+	if err != nil {
+		return err
+	}
+	tests := func(file string) []string {
+		_, toTest := testrun.SelectPackages(opts.root, pkgs, []string{file})
+		return toTest
+	}
+	fmt.Println("Mutation testing the covered changed lines")
+	var progress io.Writer
+	if opts.verbose {
+		progress = os.Stdout
+	}
+	return mutation.Attach(a, r, &mutation.Runner{Root: opts.root}, tests, progress)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/testgen"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runScaffoldTests prints or writes test skeletons for changed functions with zero coverage.
+func runScaffoldTests(args []string) int {
+	fs := flag.NewFlagSet("scaffold-tests", flag.ExitOnError)
+	coverFlag := fs.String("cover", "cover.out", "Path to the coverage profile")
+	diffFlag := fs.String("diff", "diff.txt", "Path to the diff (git diff output, with or without context lines)")
+	rootFlag := fs.String("root", ".", "Source root containing go.mod")
+	configFlag := fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present)")
+	presetFlag := fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", "))
+	writeFlag := fs.Bool("write", false, "Create or extend the _test.go files instead of printing the skeletons")
+	fs.Parse(args)
+
+	cfg, err := config.Resolve(*configFlag, *rootFlag, *presetFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	a, err := diffcoverage.Analyze(*coverFlag, *diffFlag, *rootFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	a.Exclude(cfg.Exclude)
+	files, err := testgen.Scaffold(a)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	for _, f := range files {
+		if !*writeFlag {
+			if f.Exists {
+				fmt.Printf("// %s (append)\n\n%s\n", f.Path, f.Tests)
+			} else {
+				fmt.Printf("// %s (new file)\n\n%s\n", f.Path, f.Content)
+			}
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(*rootFlag, f.Path), f.Content, 0644); err != nil {
+			fmt.Printf("error writing %s: %v\n", f.Path, err)
+			return 1
+		}
+		fmt.Printf("%s: %s\n", f.Path, strings.Join(f.Funcs, ", "))
+	}
+	if len(files) == 0 {
+		fmt.Println("No changed functions without coverage")
+	}
+	return 0
+}
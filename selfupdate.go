@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/selfupdate"
+	"github.com/JackShadow/go-new-code-coverage/internal/version"
+	"os"
+	"runtime"
+	"time"
+)
+
+// runSelfUpdate replaces the running binary with the latest verified release.
+func runSelfUpdate(args []string) int {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	checkFlag := fs.Bool("check", false, "Only report whether an update is available")
+	forceFlag := fs.Bool("force", false, "Install the latest release even if it is not newer")
+	keyFlag := fs.String("public-key", "", "Base64 ed25519 public key; when set, checksums.txt must be signed")
+	timeoutFlag := fs.Duration("timeout", 2*time.Minute, "Timeout for the whole update")
+	fs.Parse(args)
+
+	u := &selfupdate.Updater{APIURL: selfupdate.DefaultAPIURL}
+	if *keyFlag != "" {
+		key, err := base64.StdEncoding.DecodeString(*keyFlag)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			fmt.Println("invalid -public-key: expected a base64 encoded ed25519 public key")
+			return 1
+		}
+		u.PublicKey = key
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeoutFlag)
+	defer cancel()
+
+	current := version.Get().Short()
+	rel, err := u.Latest(ctx)
+	if err != nil {
+		fmt.Printf("error checking for updates: %v\n", err)
+		return 1
+	}
+
+	if !*forceFlag && !selfupdate.NewerThan(rel.TagName, current) {
+		fmt.Printf("Already up to date (%s, latest %s)\n", current, rel.TagName)
+		return 0
+	}
+	if *checkFlag {
+		fmt.Printf("Update available: %s -> %s\n", current, rel.TagName)
+		return 0
+	}
+
+	bin, err := u.Download(ctx, rel, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		fmt.Printf("error downloading %s: %v\n", rel.TagName, err)
+		return 1
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Printf("error locating executable: %v\n", err)
+		return 1
+	}
+	if err := selfupdate.Replace(exePath, bin); err != nil {
+		fmt.Printf("error replacing %s: %v\n", exePath, err)
+		return 1
+	}
+
+	fmt.Printf("Updated %s -> %s\n", current, rel.TagName)
+	return 0
+}
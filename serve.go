@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/bot"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/credentials"
+	"github.com/JackShadow/go-new-code-coverage/internal/dashboard"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+	"github.com/JackShadow/go-new-code-coverage/internal/metrics"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// runServe listens for pull/merge request webhooks and publishes the report
+// of every new revision once CI has uploaded its coverage profile.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	workdir := fs.String("workdir", "", "Directory for checkouts (default: the system temporary directory)")
+	artifact := fs.String("artifact", "coverage", "GitHub Actions artifact containing the coverage profile")
+	profile := fs.String("profile", "cover.out", "Coverage profile file name inside the artifact")
+	gitlabJob := fs.String("gitlab-job", "test", "GitLab CI job whose artifacts contain the coverage profile")
+	githubAPIURL := fs.String("github-api-url", "", "GitHub API URL (default: https://api.github.com)")
+	gitlabAPIURL := fs.String("gitlab-api-url", "", "GitLab API URL (default: https://gitlab.com/api/v4)")
+	historyPath := fs.String("history", history.DefaultPath, "History file recording the analyses, shown by the dashboard; empty disables both")
+	workers := fs.Int("workers", 1, "Analyses running at once")
+	queueSize := fs.Int("queue-size", bot.DefaultQueueSize, "Pending analyses before deliveries are rejected")
+	maxPerRepo := fs.Int("max-pending-per-repo", 0, "Pending analyses per repository before its deliveries are rejected; 0 for no limit")
+	timeout := fs.Duration("timeout", 10*time.Minute, "Time limit of each analysis, downloads and checkout included; 0 for no limit")
+	maxDownload := fs.Int64("max-download", bot.DefaultMaxDownload, "Largest API response, artifact or profile in bytes")
+	fs.Parse(args)
+	if *maxDownload <= 0 {
+		fmt.Println("-max-download must be positive")
+		return 1
+	}
+
+	app, err := credentials.GitHubAppFromEnv(os.Getenv)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	if app != nil {
+		app.APIURL = *githubAPIURL
+	}
+	resolver := &credentials.Resolver{Getenv: os.Getenv}
+
+	s := &bot.Server{
+		Sources: map[string]bot.Source{},
+		Analyze: func(coverPath, diffPath, sourceRoot string) (*diffcoverage.Report, error) {
+			cfg, err := config.Resolve("", sourceRoot, "")
+			if err != nil {
+				return nil, err
+			}
+			return evaluate(coverPath, diffPath, sourceRoot, cfg)
+		},
+		Team: func(sourceRoot string) string {
+			if cfg, err := config.Resolve("", sourceRoot, ""); err == nil {
+				return cfg.Team
+			}
+			return ""
+		},
+		Workdir:           *workdir,
+		Logf:              log.Printf,
+		Workers:           *workers,
+		QueueSize:         *queueSize,
+		MaxPendingPerRepo: *maxPerRepo,
+		Timeout:           *timeout,
+	}
+	m := &metrics.Metrics{Ready: s.Ready, QueueDepth: s.QueueDepth}
+	s.Observe = m.ObserveAnalysis
+	mux := http.NewServeMux()
+	mux.Handle("/webhooks/", m.Instrument("webhooks", s))
+	m.Register(mux)
+	ingest := false
+	if *historyPath != "" {
+		s.History = &history.Store{Path: *historyPath}
+		mux.Handle("/", m.Instrument("dashboard", &dashboard.Handler{Store: s.History}))
+		if secret := os.Getenv("DIFFCOVERAGE_SERVER_SECRET"); secret != "" {
+			mux.Handle("/api/runs", m.Instrument("ingest", &dashboard.Ingest{Store: s.History, Secret: secret}))
+			ingest = true
+		}
+	}
+
+	if app != nil && os.Getenv("GITHUB_WEBHOOK_SECRET") == "" {
+		fmt.Println("Set GITHUB_WEBHOOK_SECRET to receive the webhooks of the GitHub App")
+		return 1
+	}
+	if os.Getenv("GITHUB_WEBHOOK_SECRET") != "" {
+		tokens := &appTokens{app: app}
+		s.Sources["/webhooks/github"] = &bot.GitHub{
+			APIURL:      *githubAPIURL,
+			Secret:      os.Getenv("GITHUB_WEBHOOK_SECRET"),
+			Artifact:    *artifact,
+			ProfileName: *profile,
+			MaxDownload: *maxDownload,
+			Token: func(ctx context.Context, repo string) (string, error) {
+				if app != nil {
+					return tokens.get(ctx, repo)
+				}
+				cred, err := resolver.Resolve(credentials.GitHub, *githubAPIURL)
+				return cred.Token, err
+			},
+		}
+	}
+	if os.Getenv("GITLAB_WEBHOOK_TOKEN") != "" {
+		cred, err := resolver.Resolve(credentials.GitLab, *gitlabAPIURL)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		s.Sources["/webhooks/gitlab"] = &bot.GitLab{
+			APIURL:      *gitlabAPIURL,
+			Secret:      os.Getenv("GITLAB_WEBHOOK_TOKEN"),
+			Job:         *gitlabJob,
+			ProfileName: *profile,
+			Token:       cred.Token,
+			MaxDownload: *maxDownload,
+		}
+	}
+	if len(s.Sources) == 0 && !ingest {
+		fmt.Println("Set GITHUB_WEBHOOK_SECRET (or configure a GitHub App) and/or GITLAB_WEBHOOK_TOKEN to receive webhooks, or DIFFCOVERAGE_SERVER_SECRET to receive uploaded runs")
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go s.Run(ctx)
+
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	for path := range s.Sources {
+		log.Printf("listening on %s%s", *addr, path)
+	}
+	if ingest {
+		log.Printf("receiving runs on %s/api/runs", *addr)
+	}
+	if s.History != nil {
+		log.Printf("dashboard on %s/", *addr)
+	}
+	log.Printf("probes on %s/healthz and %s/readyz, metrics on %s/metrics", *addr, *addr, *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println(err.Error())
+		return 1
+	}
+	return 0
+}
+
+// appTokens caches GitHub App installation tokens per repository. Tokens are
+// valid for an hour; they are renewed after 50 minutes.
+type appTokens struct {
+	app *credentials.GitHubApp
+
+	mu     sync.Mutex
+	tokens map[string]appToken
+}
+
+type appToken struct {
+	token   string
+	expires time.Time
+}
+
+func (c *appTokens) get(ctx context.Context, repo string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.tokens[repo]; ok && time.Now().Before(t.expires) {
+		return t.token, nil
+	}
+	token, err := c.app.InstallationToken(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	if c.tokens == nil {
+		c.tokens = map[string]appToken{}
+	}
+	c.tokens[repo] = appToken{token: token, expires: time.Now().Add(50 * time.Minute)}
+	return token, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/grpcapi"
+	"github.com/JackShadow/go-new-code-coverage/internal/metrics"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// runServeGRPC serves the DiffCoverage gRPC API of api/diffcoverage.proto.
+func runServeGRPC(args []string) int {
+	fs := flag.NewFlagSet("serve-grpc", flag.ExitOnError)
+	addr := fs.String("addr", ":9090", "Address to listen on")
+	certFile := fs.String("tls-cert", "", "TLS certificate file; without it the service speaks cleartext HTTP/2 (h2c)")
+	keyFile := fs.String("tls-key", "", "TLS private key file")
+	workdir := fs.String("workdir", "", "Directory for received inputs (default: the system temporary directory)")
+	maxConcurrent := fs.Int("max-concurrent", runtime.GOMAXPROCS(0), "Analyses running at once; further streams wait unread")
+	maxMessageSize := fs.Int("max-message-size", grpcapi.DefaultMaxMessageSize, "Largest accepted request message in bytes")
+	maxRequestSize := fs.Int64("max-request-size", grpcapi.DefaultMaxRequestSize, "Largest accepted total of a stream's messages in bytes")
+	timeout := fs.Duration("timeout", 10*time.Minute, "Time limit of each analysis once it starts, receiving the inputs included; 0 for no limit")
+	fs.Parse(args)
+
+	s := &grpcapi.Server{
+		Analyze: func(coverPath, diffPath, sourceRoot string) (*diffcoverage.Report, error) {
+			cfg, err := config.Resolve("", sourceRoot, "")
+			if err != nil {
+				return nil, err
+			}
+			return evaluate(coverPath, diffPath, sourceRoot, cfg)
+		},
+		Workdir:        *workdir,
+		MaxConcurrent:  *maxConcurrent,
+		MaxMessageSize: *maxMessageSize,
+		MaxRequestSize: *maxRequestSize,
+		Timeout:        *timeout,
+	}
+	m := &metrics.Metrics{QueueDepth: s.Waiting}
+	s.Observe = m.ObserveAnalysis
+	mux := http.NewServeMux()
+	mux.Handle("/", m.Instrument("grpc", s))
+	m.Register(mux)
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	if *certFile == "" && !enableH2C(srv) {
+		fmt.Println("Cleartext HTTP/2 requires a binary built with Go 1.24 or later; set -tls-cert and -tls-key")
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	m.Ready = func() error {
+		if ctx.Err() != nil {
+			return errors.New("shutting down")
+		}
+		return nil
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	log.Printf("serving %s on %s, probes on /healthz and /readyz, metrics on /metrics", grpcapi.Method, *addr)
+	var err error
+	if *certFile != "" {
+		err = srv.ListenAndServeTLS(*certFile, *keyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println(err.Error())
+		return 1
+	}
+	return 0
+}
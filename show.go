@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"os"
+)
+
+// runShow prints a single source file with a coverage gutter.
+func runShow(args []string) int {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	coverFlag := fs.String("cover", "cover.out", "Path to the coverage profile")
+	diffFlag := fs.String("diff", "diff.txt", "Path to the diff (git diff output, with or without context lines)")
+	rootFlag := fs.String("root", ".", "Source root containing go.mod")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fmt.Println("Usage: diffcoverage show [options] <file.go>")
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		return 1
+	}
+
+	a, err := diffcoverage.Analyze(*coverFlag, *diffFlag, *rootFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	for _, file := range fs.Args() {
+		if err := diffcoverage.ShowFile(os.Stdout, *rootFlag, file, a); err != nil {
+			fmt.Printf("error showing %s: %v\n", file, err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/gitutil"
+	"github.com/JackShadow/go-new-code-coverage/internal/history"
+	"io"
+)
+
+// checkTrend compares the report with the branch's run history and records
+// declining coverage in r.Regressions. It returns an error only when the
+// configuration asks for regressions to fail the run. The regressions are
+// also written to w.
+func checkTrend(w io.Writer, r *diffcoverage.Report, branch string, cfg *config.Config) error {
+	if cfg.Trend.Runs == 0 {
+		return nil
+	}
+	if branch == "" {
+		branch, _ = gitutil.Run(r.SourceRoot, "rev-parse", "--abbrev-ref", "HEAD")
+	}
+	store := &history.Store{Path: history.ResolvePath(r.SourceRoot, cfg.History)}
+	previous, err := store.List(branch, cfg.Trend.Runs-1)
+	if err != nil {
+		return fmt.Errorf("error reading run history: %v", err)
+	}
+
+	r.Regressions = history.DetectRegressions(r, previous, cfg.Trend.Runs, cfg.Trend.Tolerance)
+	for _, g := range r.Regressions {
+		fmt.Fprintf(w, "Coverage trend: %s\n", g)
+	}
+	if len(r.Regressions) > 0 && cfg.Trend.Fail {
+		return fmt.Errorf("coverage trend declined on branch %s", branch)
+	}
+	return nil
+}
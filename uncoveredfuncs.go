@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// runUncoveredFunctions lists the functions touched by the diff that are not fully covered.
+func runUncoveredFunctions(args []string) int {
+	fs := flag.NewFlagSet("uncovered-functions", flag.ExitOnError)
+	coverFlag := fs.String("cover", "cover.out", "Path to the coverage profile")
+	diffFlag := fs.String("diff", "diff.txt", "Path to the diff (git diff output, with or without context lines)")
+	rootFlag := fs.String("root", ".", "Source root containing go.mod")
+	configFlag := fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present)")
+	presetFlag := fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", "))
+	below := fs.Float64("below", 100, "List functions whose coverage is below this percentage")
+	format := fs.String("format", "text", "Output format: text or json")
+	fs.Parse(args)
+
+	cfg, err := config.Resolve(*configFlag, *rootFlag, *presetFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	a, err := diffcoverage.Analyze(*coverFlag, *diffFlag, *rootFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	a.Exclude(cfg.Exclude)
+	funcs, err := a.Functions()
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	listed := []diffcoverage.FuncReport{}
+	for _, f := range funcs {
+		if f.Coverage < *below {
+			listed = append(listed, f)
+		}
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(listed); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+	case "text":
+		if len(listed) == 0 {
+			fmt.Printf("All %d changed functions have at least %.2f%% coverage\n", len(funcs), *below)
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "LOCATION\tFUNCTION\tLINES\tCOVERAGE")
+		for _, f := range listed {
+			fmt.Fprintf(tw, "%s:%d\t%s\t%d/%d\t%.2f%%\n", f.File, f.Line, f.Signature, f.CoveredLines, f.TotalLines, f.Coverage)
+		}
+		tw.Flush()
+	default:
+		fmt.Printf("unknown format %q\n", *format)
+		return 1
+	}
+	return 0
+}
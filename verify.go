@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/attest"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"os"
+)
+
+// runVerify checks the detached signature of a JSON report written by the
+// report-file or archive integrations.
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	keyPath := fs.String("key", "", "PEM ed25519 or ECDSA public key of the signer (default: HMAC with $DIFFCOVERAGE_SIGNING_SECRET)")
+	sigPath := fs.String("sig", "", "Signature file (default: <report>"+attest.SignatureSuffix+")")
+	requirePassed := fs.Bool("require-passed", false, "Also fail if the signed report did not pass its gate")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: diffcoverage verify [-key=cosign.pub] [-sig=report.json.sig] [-require-passed] report.json")
+		return 1
+	}
+	path := fs.Arg(0)
+
+	var key *attest.Key
+	if *keyPath != "" {
+		data, err := os.ReadFile(*keyPath)
+		if err != nil {
+			fmt.Printf("error reading key: %v\n", err)
+			return 1
+		}
+		if key, err = attest.ParsePublicKey(data); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+	} else if secret := os.Getenv("DIFFCOVERAGE_SIGNING_SECRET"); secret != "" {
+		key = attest.Secret(secret)
+	} else {
+		fmt.Println("verify: set -key or $DIFFCOVERAGE_SIGNING_SECRET")
+		return 1
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	sig, err := os.ReadFile(firstNonEmpty(*sigPath, path+attest.SignatureSuffix))
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	if err := key.Verify(data, string(sig)); errors.Is(err, attest.ErrInvalid) {
+		fmt.Fprintf(os.Stderr, "%s: signature does not match; the report was altered or signed with another key\n", path)
+		return 1
+	} else if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	var r diffcoverage.Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		fmt.Printf("error parsing %s: %v\n", path, err)
+		return 1
+	}
+	status := "passed"
+	if !r.Passed {
+		status = "failed"
+	}
+	fmt.Printf("Verified %s: %.1f%% diff coverage of commit %s, gate %s\n", path, r.Coverage, firstNonEmpty(r.Commit, "unknown"), status)
+	if *requirePassed && !r.Passed {
+		return 1
+	}
+	return 0
+}
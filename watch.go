@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/reporter"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// watchRange is a run of consecutive counted lines with the same status.
+type watchRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Status    string `json:"status"` // "covered" or "uncovered"
+}
+
+// runWatch re-analyzes the working tree whenever its diff or the coverage
+// profile changes and prints the results, as text or as an NDJSON event
+// stream for editor plugins.
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	root := fs.String("root", ".", "Module root containing go.mod")
+	base := fs.String("base", "origin/main", "Ref to diff the working tree against")
+	cover := fs.String("cover", "cover.out", "Coverage profile, relative to -root")
+	configPath := fs.String("config", "", "Path to the configuration file (default: <root>/"+config.FileName+" if present); reloaded when it changes")
+	preset := fs.String("preset", "", "Policy preset: "+strings.Join(config.PresetNames(), ", "))
+	format := fs.String("format", "text", "Output format: text or ndjson")
+	interval := fs.Duration("interval", 2*time.Second, "How often to check the coverage profile and git state for changes")
+	fs.Parse(args)
+
+	if *format != "text" && *format != "ndjson" {
+		fmt.Printf("unknown format %q\n", *format)
+		return 1
+	}
+	log.SetOutput(os.Stderr)
+	wt, err := newWorktree(*root, *base, *cover, *configPath, *preset)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	w := &watcher{worktree: wt, ndjson: *format == "ndjson", enc: json.NewEncoder(os.Stdout)}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		w.poll()
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// watcher prints the changes of the analysis.
+type watcher struct {
+	*worktree
+	ndjson bool
+	enc    *json.Encoder
+
+	files   map[string][]watchRange
+	lastErr string
+}
+
+// poll checks the working tree and prints what changed. In NDJSON mode each
+// changed file is sent as a "clear" event followed by its "range" events, a
+// file that is no longer counted as a "clear" event only, and every change
+// ends with a "summary" event.
+func (w *watcher) poll() {
+	a, r, changed, err := w.check()
+	if err != nil {
+		if err.Error() != w.lastErr {
+			w.lastErr = err.Error()
+			w.emit(map[string]any{"event": "error", "message": err.Error()}, "error: %v", err)
+		}
+		return
+	}
+	w.lastErr = ""
+	if !changed {
+		return
+	}
+
+	files := map[string][]watchRange{}
+	if a != nil {
+		files = watchRanges(a)
+	}
+	var names []string
+	for name := range w.files {
+		if _, ok := files[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ranges := files[name]
+		if prev, ok := w.files[name]; ok && reflect.DeepEqual(prev, ranges) {
+			continue
+		}
+		if !w.ndjson {
+			continue
+		}
+		w.enc.Encode(map[string]any{"event": "clear", "file": name})
+		for _, rng := range ranges {
+			msg := "covered by tests"
+			if rng.Status == "uncovered" {
+				msg = "not covered by tests"
+			}
+			w.enc.Encode(map[string]any{"event": "range", "file": name, "startLine": rng.StartLine, "endLine": rng.EndLine, "status": rng.Status, "message": msg})
+		}
+	}
+	w.files = files
+
+	if r == nil {
+		w.emit(map[string]any{"event": "summary", "profile": false}, "%s waiting for %s", time.Now().Format(time.TimeOnly), w.profile)
+		return
+	}
+	w.emit(map[string]any{"event": "summary", "profile": true, "coverage": r.Coverage, "totalLines": r.TotalLines, "coveredLines": r.CoveredLines, "passed": r.Passed},
+		"%s diff coverage %.2f%% (%d/%d lines)", time.Now().Format(time.TimeOnly), r.Coverage, r.CoveredLines, r.TotalLines)
+	if !w.ndjson {
+		for _, f := range r.Files {
+			if len(f.Uncovered) > 0 {
+				fmt.Printf("\t%s: %s\n", f.Path, reporter.FormatRanges(f.Uncovered))
+			}
+		}
+	}
+}
+
+// emit prints event in NDJSON mode and the formatted line otherwise.
+func (w *watcher) emit(event map[string]any, format string, args ...any) {
+	if w.ndjson {
+		w.enc.Encode(event)
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// watchRanges groups the counted changed lines of each file by status.
+func watchRanges(a *diffcoverage.Analysis) map[string][]watchRange {
+	files := map[string][]watchRange{}
+	for file, set := range a.Diff.NewLines {
+		rel := a.RelPath(file)
+		lines := make([]int, 0, len(set))
+		for line := range set {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		var ranges []watchRange
+		for _, line := range lines {
+			var status string
+			switch a.Status(rel, line) {
+			case diffcoverage.LineCovered:
+				status = "covered"
+			case diffcoverage.LineUncovered:
+				status = "uncovered"
+			default:
+				continue
+			}
+			if n := len(ranges); n > 0 && ranges[n-1].EndLine == line-1 && ranges[n-1].Status == status {
+				ranges[n-1].EndLine = line
+				continue
+			}
+			ranges = append(ranges, watchRange{StartLine: line, EndLine: line, Status: status})
+		}
+		if len(ranges) > 0 {
+			files[rel] = ranges
+		}
+	}
+	return files
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWatcher_Poll decodes the NDJSON events of a poll: a clear event and
+// the range events of each changed file, then a summary. Polling again
+// without changes emits nothing.
+func TestWatcher_Poll(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":   "module example.com/m\n\ngo 1.21\n",
+		"pkg/a.go": "package pkg\n\nfunc A(x int) int {\n\treturn 0\n}\n",
+	})
+	mustGit(t, dir, "init", "-q")
+	mustGit(t, dir, "add", "-A")
+	mustGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "base")
+	writeFiles(t, dir, map[string]string{
+		"pkg/a.go":  "package pkg\n\nfunc A(x int) int {\n\tif x > 0 {\n\t\treturn 1\n\t}\n\treturn 0\n}\n",
+		"cover.out": "mode: set\nexample.com/m/pkg/a.go:3.19,4.11 1 1\nexample.com/m/pkg/a.go:4.11,6.3 1 0\nexample.com/m/pkg/a.go:7.2,7.10 1 1\n",
+	})
+
+	wt, err := newWorktree(dir, "HEAD", "cover.out", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	w := &watcher{worktree: wt, ndjson: true, enc: json.NewEncoder(&out)}
+	w.poll()
+
+	var events []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var ev map[string]any
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatalf("invalid event: %v", err)
+		}
+		events = append(events, ev)
+	}
+	want := []map[string]any{
+		{"event": "clear", "file": "pkg/a.go"},
+		{"event": "range", "file": "pkg/a.go", "startLine": 4.0, "endLine": 4.0, "status": "covered", "message": "covered by tests"},
+		{"event": "range", "file": "pkg/a.go", "startLine": 5.0, "endLine": 6.0, "status": "uncovered", "message": "not covered by tests"},
+		{"event": "summary", "profile": true, "coverage": 100.0 / 3, "totalLines": 3.0, "coveredLines": 1.0, "passed": true},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+
+	w.poll()
+	if out.Len() != 0 {
+		t.Errorf("Expected no events without changes, got %s", out.String())
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "cover.out"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	w.poll()
+	if got := out.String(); !strings.Contains(got, `"event":"summary"`) || strings.Contains(got, `"event":"clear"`) {
+		t.Errorf("Expected only a summary after touching the profile, got %s", got)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/JackShadow/go-new-code-coverage/internal/config"
+	"github.com/JackShadow/go-new-code-coverage/internal/diffcoverage"
+	"github.com/JackShadow/go-new-code-coverage/internal/testrun"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// worktree analyzes the working tree against a coverage profile for the
+// long-running commands, again only when its diff against base, the profile
+// or the configuration file changed.
+type worktree struct {
+	root    string // absolute
+	base    string
+	profile string
+	cfg     *config.Config
+
+	// configPath and preset are the -config and -preset flags the
+	// configuration is reloaded with; configStamp identifies the file loaded.
+	configPath  string
+	preset      string
+	configStamp string
+
+	fingerprint string
+	analysis    *diffcoverage.Analysis
+	report      *diffcoverage.Report
+}
+
+// newWorktree loads the configuration of the working tree at root, with the
+// profile cover relative to it.
+func newWorktree(root, base, cover, configPath, preset string) (*worktree, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(cover) {
+		cover = filepath.Join(absRoot, cover)
+	}
+	w := &worktree{root: absRoot, base: base, profile: cover, configPath: configPath, preset: preset}
+	w.configStamp = w.configFile()
+	if w.cfg, err = config.Resolve(configPath, absRoot, preset); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// configFile identifies the configuration file by path, modification time
+// and size; it is empty without a file.
+func (w *worktree) configFile() string {
+	path := w.configPath
+	if path == "" {
+		path = config.Find(w.root)
+	}
+	info, err := os.Stat(path)
+	if path == "" || err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %d %d", path, info.ModTime().UnixNano(), info.Size())
+}
+
+// reloadConfig loads the configuration again if its file changed and logs
+// the settings that changed. An invalid file keeps the previous configuration.
+func (w *worktree) reloadConfig() {
+	stamp := w.configFile()
+	if stamp == w.configStamp {
+		return
+	}
+	w.configStamp = stamp
+	cfg, err := config.Resolve(w.configPath, w.root, w.preset)
+	if err != nil {
+		log.Printf("configuration not reloaded, keeping the previous one: %v", err)
+		return
+	}
+	changes := config.Diff(w.cfg, cfg)
+	w.cfg = cfg
+	if len(changes) == 0 {
+		log.Printf("configuration reloaded, no settings changed")
+		return
+	}
+	log.Printf("configuration reloaded: %s", strings.Join(changes, "; "))
+	// Analyze again with the new settings.
+	w.fingerprint = ""
+}
+
+// check returns the latest analysis and whether it changed since the
+// previous call. Both are nil while there is no profile.
+func (w *worktree) check() (*diffcoverage.Analysis, *diffcoverage.Report, bool, error) {
+	w.reloadConfig()
+	tmpDir, err := os.MkdirTemp("", "diffcoverage-worktree")
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer os.RemoveAll(tmpDir)
+	diffPath := filepath.Join(tmpDir, "diff.txt")
+	if err := testrun.WriteDiff(w.root, w.base, diffPath); err != nil {
+		return nil, nil, false, fmt.Errorf("error computing diff: %v", err)
+	}
+	diff, err := os.ReadFile(diffPath)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	h := sha256.New()
+	h.Write(diff)
+	info, statErr := os.Stat(w.profile)
+	if statErr == nil {
+		fmt.Fprintf(h, "%d %d", info.ModTime().UnixNano(), info.Size())
+	}
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+	if fingerprint == w.fingerprint {
+		return w.analysis, w.report, false, nil
+	}
+	if statErr != nil {
+		log.Printf("no coverage profile at %s yet; run go test -coverprofile", w.profile)
+		w.fingerprint, w.analysis, w.report = fingerprint, nil, nil
+		return nil, nil, true, nil
+	}
+
+	a, err := diffcoverage.Analyze(w.profile, diffPath, w.root)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if err := excludeCode(a, w.cfg); err != nil {
+		return nil, nil, false, err
+	}
+	if _, err := applyExceptions(a, w.root, w.cfg.Exceptions); err != nil {
+		return nil, nil, false, err
+	}
+	w.fingerprint, w.analysis, w.report = fingerprint, a, a.Report(w.cfg.MinCoverage)
+	return w.analysis, w.report, true, nil
+}